@@ -2,12 +2,16 @@ package cmd
 
 import (
 	"context"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/database"
 	"github.com/badno/badops/internal/database/clickhouse"
 	"github.com/badno/badops/internal/database/postgres"
@@ -44,6 +48,27 @@ var analyticsAlertsCmd = &cobra.Command{
 	RunE:  runAnalyticsAlerts,
 }
 
+var analyticsVolatilityCmd = &cobra.Command{
+	Use:   "volatility",
+	Short: "Show price volatility",
+	Long:  "Lists products whose competitor prices vary the most (by coefficient of variation)",
+	RunE:  runAnalyticsVolatility,
+}
+
+var analyticsDropsCmd = &cobra.Command{
+	Use:   "drops",
+	Short: "Show competitor price drops",
+	Long:  "Lists competitor price drops between consecutive days",
+	RunE:  runAnalyticsDrops,
+}
+
+var analyticsStockCmd = &cobra.Command{
+	Use:   "stock",
+	Short: "Show competitor stock availability",
+	Long:  "Shows how often each competitor had a product in stock over a period",
+	RunE:  runAnalyticsStock,
+}
+
 var analyticsSyncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync data to ClickHouse",
@@ -65,12 +90,24 @@ var (
 	analyticsThreshold float64
 	analyticsSyncDays  int
 	analyticsSyncAll   bool
+	analyticsRefresh   bool
+	analyticsMinObs    int
+	analyticsMinDrop   float64
+	analyticsOutput    string
+
+	// Separate from analyticsPeriod, whose default is shared by several commands
+	analyticsDropsPeriod string
 )
 
 func init() {
+	analyticsCmd.PersistentFlags().StringVarP(&analyticsOutput, "output", "o", "table", "Output format: table, json, csv")
+
 	analyticsCmd.AddCommand(analyticsTrendsCmd)
 	analyticsCmd.AddCommand(analyticsPositionCmd)
 	analyticsCmd.AddCommand(analyticsAlertsCmd)
+	analyticsCmd.AddCommand(analyticsVolatilityCmd)
+	analyticsCmd.AddCommand(analyticsDropsCmd)
+	analyticsCmd.AddCommand(analyticsStockCmd)
 	analyticsCmd.AddCommand(analyticsSyncCmd)
 	analyticsCmd.AddCommand(analyticsInitCmd)
 
@@ -84,8 +121,19 @@ func init() {
 	analyticsAlertsCmd.Flags().Float64Var(&analyticsThreshold, "threshold", 10.0, "Price difference threshold in percent")
 	analyticsAlertsCmd.Flags().StringVar(&analyticsVendor, "vendor", "", "Filter by vendor")
 
+	analyticsVolatilityCmd.Flags().StringVar(&analyticsPeriod, "period", "30d", "Time period (e.g., 7d, 30d, 90d)")
+	analyticsVolatilityCmd.Flags().IntVar(&analyticsMinObs, "min-obs", 5, "Minimum observations per product")
+
+	analyticsDropsCmd.Flags().StringVar(&analyticsDropsPeriod, "period", "14d", "Time period (e.g., 7d, 14d, 30d)")
+	analyticsDropsCmd.Flags().Float64Var(&analyticsMinDrop, "min-drop", 5.0, "Minimum price drop in percent")
+
+	analyticsStockCmd.Flags().StringVar(&analyticsSKU, "sku", "", "Product SKU to analyze (required)")
+	analyticsStockCmd.Flags().StringVar(&analyticsPeriod, "period", "30d", "Time period (e.g., 7d, 30d, 90d)")
+	analyticsStockCmd.MarkFlagRequired("sku")
+
 	analyticsSyncCmd.Flags().IntVar(&analyticsSyncDays, "days", 0, "Sync last N days (0 = incremental)")
 	analyticsSyncCmd.Flags().BoolVar(&analyticsSyncAll, "all", false, "Sync all historical data")
+	analyticsSyncCmd.Flags().BoolVar(&analyticsRefresh, "refresh-aggregates", false, "Refresh the market_daily aggregate for the synced period")
 }
 
 // getClickHouseClient creates a ClickHouse client from configuration
@@ -95,18 +143,61 @@ func getClickHouseClient() (*clickhouse.Client, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	username, err := credentials.Resolve(nil, cfg.Database.ClickHouse.UsernameEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ClickHouse username: %w", err)
+	}
+	password, err := credentials.Resolve(nil, cfg.Database.ClickHouse.PasswordEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ClickHouse password: %w", err)
+	}
+
 	chConfig := &clickhouse.Config{
 		Host:     cfg.Database.ClickHouse.Host,
 		Port:     cfg.Database.ClickHouse.Port,
 		Database: cfg.Database.ClickHouse.Database,
-		Username: os.Getenv(cfg.Database.ClickHouse.UsernameEnv),
-		Password: os.Getenv(cfg.Database.ClickHouse.PasswordEnv),
+		Username: username,
+		Password: password,
 		Secure:   cfg.Database.ClickHouse.Secure,
 	}
 
 	return clickhouse.NewClient(chConfig), nil
 }
 
+// checkAnalyticsOutput validates the --output flag
+func checkAnalyticsOutput() error {
+	switch analyticsOutput {
+	case "table", "json", "csv":
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q (use table, json or csv)", analyticsOutput)
+	}
+}
+
+// writeAnalyticsJSON writes a result set to stdout as indented JSON
+func writeAnalyticsJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeAnalyticsCSV writes a header and rows to stdout as CSV
+func writeAnalyticsCSV(header []string, rows [][]string) error {
+	w := csv.NewWriter(os.Stdout)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
+
+// formatFloat formats a number for CSV output
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
 func parsePeriod(period string) int {
 	// Parse period like "7d", "30d", "90d"
 	var days int
@@ -118,6 +209,10 @@ func parsePeriod(period string) int {
 }
 
 func runAnalyticsTrends(cmd *cobra.Command, args []string) error {
+	if err := checkAnalyticsOutput(); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
@@ -134,9 +229,12 @@ func runAnalyticsTrends(cmd *cobra.Command, args []string) error {
 	}
 	defer client.Close()
 
-	color.Green("✓ Connected to ClickHouse")
+	if analyticsOutput == "table" {
+		color.Green("✓ Connected to ClickHouse")
+	}
 
 	var trends []clickhouse.PriceTrend
+	var title string
 
 	if analyticsSKU != "" {
 		// Get trends for specific SKU
@@ -144,25 +242,57 @@ func runAnalyticsTrends(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("failed to get trends: %w", err)
 		}
-		fmt.Printf("\nPrice trends for %s (last %d days):\n\n", analyticsSKU, days)
+		title = fmt.Sprintf("Price trends for %s (last %d days):", analyticsSKU, days)
 	} else {
 		// Get trends for vendor or all
 		trends, err = client.GetVendorTrends(ctx, analyticsVendor, days)
 		if err != nil {
 			return fmt.Errorf("failed to get vendor trends: %w", err)
 		}
-		fmt.Printf("\nPrice trends (last %d days):\n\n", days)
+		title = fmt.Sprintf("Price trends (last %d days):", days)
 	}
 
-	if len(trends) == 0 {
-		color.Yellow("No trend data found")
-		fmt.Println("\nEnsure data is synced to ClickHouse:")
-		fmt.Println("  badops analytics sync --all")
-		return nil
+	if analyticsOutput == "table" {
+		fmt.Printf("\n%s\n\n", title)
+
+		if len(trends) == 0 {
+			color.Yellow("No trend data found")
+			fmt.Println("\nEnsure data is synced to ClickHouse:")
+			fmt.Println("  badops analytics sync --all")
+			return nil
+		}
+	}
+
+	return writePriceTrends(analyticsOutput, trends)
+}
+
+// writePriceTrends writes daily per-competitor trends as JSON, CSV, or one
+// min/max/change table per product
+func writePriceTrends(format string, trends []database.PriceTrend) error {
+	switch format {
+	case "json":
+		if trends == nil {
+			trends = []database.PriceTrend{}
+		}
+		return writeAnalyticsJSON(trends)
+	case "csv":
+		rows := make([][]string, 0, len(trends))
+		for _, t := range trends {
+			rows = append(rows, []string{
+				t.ProductSKU,
+				t.CompetitorName,
+				t.Date.Format("2006-01-02"),
+				formatFloat(t.MinPrice),
+				formatFloat(t.MaxPrice),
+				formatFloat(t.AvgPrice),
+				strconv.FormatInt(t.Count, 10),
+			})
+		}
+		return writeAnalyticsCSV([]string{"sku", "competitor", "date", "min_price", "max_price", "avg_price", "count"}, rows)
 	}
 
 	// Group by product
-	productTrends := make(map[string][]clickhouse.PriceTrend)
+	productTrends := make(map[string][]database.PriceTrend)
 	for _, t := range trends {
 		productTrends[t.ProductSKU] = append(productTrends[t.ProductSKU], t)
 	}
@@ -228,6 +358,10 @@ func runAnalyticsTrends(cmd *cobra.Command, args []string) error {
 }
 
 func runAnalyticsPosition(cmd *cobra.Command, args []string) error {
+	if err := checkAnalyticsOutput(); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
@@ -260,50 +394,70 @@ func runAnalyticsPosition(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("product not found: %s", analyticsSKU)
 	}
 
-	fmt.Printf("Product: %s\n", product.Title)
-	fmt.Printf("SKU: %s\n", product.SKU)
-	if product.Price != nil {
-		fmt.Printf("Our Price: %.2f %s\n", product.Price.Amount, product.Price.Currency)
-	}
-
 	// Get price distribution from ClickHouse
 	distribution, err := chClient.GetPriceDistribution(ctx, analyticsSKU)
 	if err != nil {
 		return fmt.Errorf("failed to get distribution: %w", err)
 	}
 
-	if len(distribution) == 0 {
-		color.Yellow("\nNo competitor price data found")
-		return nil
-	}
-
 	// Sort competitors by price
 	type competitorPrice struct {
-		name  string
-		price float64
+		Name  string  `json:"name"`
+		Price float64 `json:"price"`
 	}
-	var sortedPrices []competitorPrice
+	sortedPrices := []competitorPrice{}
 	for name, price := range distribution {
 		sortedPrices = append(sortedPrices, competitorPrice{name, price})
 	}
 	sort.Slice(sortedPrices, func(i, j int) bool {
-		return sortedPrices[i].price < sortedPrices[j].price
+		return sortedPrices[i].Price < sortedPrices[j].Price
 	})
 
+	switch analyticsOutput {
+	case "json":
+		report := struct {
+			SKU         string            `json:"sku"`
+			Title       string            `json:"title"`
+			OurPrice    *float64          `json:"our_price,omitempty"`
+			Competitors []competitorPrice `json:"competitors"`
+		}{SKU: product.SKU, Title: product.Title, Competitors: sortedPrices}
+		if product.Price != nil {
+			report.OurPrice = &product.Price.Amount
+		}
+		return writeAnalyticsJSON(report)
+	case "csv":
+		rows := make([][]string, 0, len(sortedPrices))
+		for _, cp := range sortedPrices {
+			rows = append(rows, []string{product.SKU, cp.Name, formatFloat(cp.Price)})
+		}
+		return writeAnalyticsCSV([]string{"sku", "competitor", "price"}, rows)
+	}
+
+	fmt.Printf("Product: %s\n", product.Title)
+	fmt.Printf("SKU: %s\n", product.SKU)
+	if product.Price != nil {
+		fmt.Printf("Our Price: %.2f %s\n", product.Price.Amount, product.Price.Currency)
+	}
+
+	if len(sortedPrices) == 0 {
+		color.Yellow("\nNo competitor price data found")
+		return nil
+	}
+
 	fmt.Println("\n" + color.CyanString("Competitor Prices (Sorted)"))
 
 	var minPrice, maxPrice, sumPrice float64
-	minPrice = sortedPrices[0].price
+	minPrice = sortedPrices[0].Price
 	for i, cp := range sortedPrices {
-		if cp.price < minPrice {
-			minPrice = cp.price
+		if cp.Price < minPrice {
+			minPrice = cp.Price
 		}
-		if cp.price > maxPrice {
-			maxPrice = cp.price
+		if cp.Price > maxPrice {
+			maxPrice = cp.Price
 		}
-		sumPrice += cp.price
+		sumPrice += cp.Price
 
-		fmt.Printf("  %d. %s: %.2f\n", i+1, cp.name, cp.price)
+		fmt.Printf("  %d. %s: %.2f\n", i+1, cp.Name, cp.Price)
 	}
 
 	avgPrice := sumPrice / float64(len(sortedPrices))
@@ -330,7 +484,7 @@ func runAnalyticsPosition(cmd *cobra.Command, args []string) error {
 		// Find rank
 		rank := 1
 		for _, cp := range sortedPrices {
-			if ownPrice > cp.price {
+			if ownPrice > cp.Price {
 				rank++
 			}
 		}
@@ -341,6 +495,10 @@ func runAnalyticsPosition(cmd *cobra.Command, args []string) error {
 }
 
 func runAnalyticsAlerts(cmd *cobra.Command, args []string) error {
+	if err := checkAnalyticsOutput(); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
@@ -373,7 +531,7 @@ func runAnalyticsAlerts(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	if len(ownPrices) == 0 {
+	if len(ownPrices) == 0 && analyticsOutput == "table" {
 		color.Yellow("No products with prices found")
 		return nil
 	}
@@ -394,16 +552,36 @@ func runAnalyticsAlerts(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get alerts: %w", err)
 	}
 
-	if len(alerts) == 0 {
-		color.Green("✓ No price alerts (all products within %.0f%% of market average)", analyticsThreshold)
-		return nil
-	}
-
 	// Sort by difference percentage
 	sort.Slice(alerts, func(i, j int) bool {
 		return alerts[i].DiffPercent > alerts[j].DiffPercent
 	})
 
+	switch analyticsOutput {
+	case "json":
+		if alerts == nil {
+			alerts = []clickhouse.PriceAlert{}
+		}
+		return writeAnalyticsJSON(alerts)
+	case "csv":
+		rows := make([][]string, 0, len(alerts))
+		for _, a := range alerts {
+			rows = append(rows, []string{
+				a.ProductSKU,
+				formatFloat(a.CurrentPrice),
+				formatFloat(a.MarketAvg),
+				formatFloat(a.Difference),
+				formatFloat(a.DiffPercent),
+			})
+		}
+		return writeAnalyticsCSV([]string{"sku", "our_price", "market_avg", "difference", "diff_percent"}, rows)
+	}
+
+	if len(alerts) == 0 {
+		color.Green("✓ No price alerts (all products within %.0f%% of market average)", analyticsThreshold)
+		return nil
+	}
+
 	fmt.Printf("Found %d products with price difference > %.0f%%:\n\n", len(alerts), analyticsThreshold)
 
 	table := tablewriter.NewWriter(os.Stdout)
@@ -438,6 +616,242 @@ func runAnalyticsAlerts(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runAnalyticsVolatility(cmd *cobra.Command, args []string) error {
+	if err := checkAnalyticsOutput(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	days := parsePeriod(analyticsPeriod)
+
+	// Connect to ClickHouse
+	client, err := getClickHouseClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+	defer client.Close()
+
+	rows, err := client.GetPriceVolatility(ctx, days, analyticsMinObs)
+	if err != nil {
+		return fmt.Errorf("failed to get volatility: %w", err)
+	}
+
+	switch analyticsOutput {
+	case "json":
+		if rows == nil {
+			rows = []clickhouse.VolatilityRow{}
+		}
+		return writeAnalyticsJSON(rows)
+	case "csv":
+		records := make([][]string, 0, len(rows))
+		for _, r := range rows {
+			records = append(records, []string{
+				r.ProductSKU,
+				formatFloat(r.AvgPrice),
+				formatFloat(r.StdDev),
+				formatFloat(r.CoV),
+				strconv.FormatUint(r.Observations, 10),
+			})
+		}
+		return writeAnalyticsCSV([]string{"sku", "avg_price", "stddev", "cov", "observations"}, records)
+	}
+
+	if len(rows) == 0 {
+		color.Yellow("No products with at least %d observations in the last %d days", analyticsMinObs, days)
+		return nil
+	}
+
+	fmt.Printf("Price volatility (last %d days, min %d observations):\n\n", days, analyticsMinObs)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SKU", "Avg Price", "Std Dev", "CoV", "Observations"})
+	table.SetBorder(false)
+
+	for _, r := range rows {
+		table.Append([]string{
+			r.ProductSKU,
+			fmt.Sprintf("%.2f", r.AvgPrice),
+			fmt.Sprintf("%.2f", r.StdDev),
+			fmt.Sprintf("%.1f%%", r.CoV*100),
+			fmt.Sprintf("%d", r.Observations),
+		})
+	}
+
+	table.Render()
+
+	return nil
+}
+
+func runAnalyticsDrops(cmd *cobra.Command, args []string) error {
+	if err := checkAnalyticsOutput(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	days := parsePeriod(analyticsDropsPeriod)
+
+	// Connect to ClickHouse
+	client, err := getClickHouseClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+	defer client.Close()
+
+	changes, err := client.GetPriceChanges(ctx, days, analyticsMinDrop)
+	if err != nil {
+		return fmt.Errorf("failed to get price changes: %w", err)
+	}
+
+	switch analyticsOutput {
+	case "json":
+		if changes == nil {
+			changes = []clickhouse.PriceChange{}
+		}
+		return writeAnalyticsJSON(changes)
+	case "csv":
+		rows := make([][]string, 0, len(changes))
+		for _, ch := range changes {
+			rows = append(rows, []string{
+				ch.ProductSKU,
+				ch.CompetitorName,
+				ch.Date.Format("2006-01-02"),
+				formatFloat(ch.OldPrice),
+				formatFloat(ch.NewPrice),
+				formatFloat(ch.DropPercent),
+			})
+		}
+		return writeAnalyticsCSV([]string{"sku", "competitor", "date", "old_price", "new_price", "drop_percent"}, rows)
+	}
+
+	if len(changes) == 0 {
+		color.Green("✓ No price drops of %.0f%% or more in the last %d days", analyticsMinDrop, days)
+		return nil
+	}
+
+	fmt.Printf("Found %d price drops of %.0f%% or more (last %d days):\n\n", len(changes), analyticsMinDrop, days)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SKU", "Competitor", "Date", "Old Price", "New Price", "Drop"})
+	table.SetBorder(false)
+
+	for _, ch := range changes {
+		table.Append([]string{
+			ch.ProductSKU,
+			ch.CompetitorName,
+			ch.Date.Format("2006-01-02"),
+			fmt.Sprintf("%.2f", ch.OldPrice),
+			fmt.Sprintf("%.2f", ch.NewPrice),
+			color.GreenString("-%.1f%%", ch.DropPercent),
+		})
+	}
+
+	table.Render()
+
+	return nil
+}
+
+func runAnalyticsStock(cmd *cobra.Command, args []string) error {
+	if err := checkAnalyticsOutput(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	days := parsePeriod(analyticsPeriod)
+
+	// Connect to ClickHouse
+	client, err := getClickHouseClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+	defer client.Close()
+
+	availability, err := client.GetStockAvailability(ctx, analyticsSKU, days)
+	if err != nil {
+		return fmt.Errorf("failed to get stock availability: %w", err)
+	}
+
+	switch analyticsOutput {
+	case "json":
+		if availability == nil {
+			availability = []clickhouse.StockAvailability{}
+		}
+		return writeAnalyticsJSON(availability)
+	case "csv":
+		rows := make([][]string, 0, len(availability))
+		for _, a := range availability {
+			avgQty := ""
+			if a.AvgQuantity != nil {
+				avgQty = formatFloat(*a.AvgQuantity)
+			}
+			rows = append(rows, []string{
+				analyticsSKU,
+				a.CompetitorName,
+				strconv.FormatUint(a.Observations, 10),
+				strconv.FormatUint(a.InStockCount, 10),
+				formatFloat(a.InStockRate),
+				avgQty,
+			})
+		}
+		return writeAnalyticsCSV([]string{"sku", "competitor", "observations", "in_stock_count", "in_stock_rate", "avg_quantity"}, rows)
+	}
+
+	if len(availability) == 0 {
+		color.Yellow("No stock data found for %s in the last %d days", analyticsSKU, days)
+		return nil
+	}
+
+	fmt.Printf("Stock availability for %s (last %d days):\n\n", analyticsSKU, days)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Competitor", "Observations", "In Stock", "Avg Qty"})
+	table.SetBorder(false)
+
+	for _, a := range availability {
+		rate := fmt.Sprintf("%.0f%%", a.InStockRate*100)
+		if a.InStockRate < 0.5 {
+			rate = color.RedString(rate)
+		} else if a.InStockRate < 1 {
+			rate = color.YellowString(rate)
+		} else {
+			rate = color.GreenString(rate)
+		}
+
+		avgQty := "-"
+		if a.AvgQuantity != nil {
+			avgQty = fmt.Sprintf("%.1f", *a.AvgQuantity)
+		}
+
+		table.Append([]string{
+			a.CompetitorName,
+			fmt.Sprintf("%d", a.Observations),
+			rate,
+			avgQty,
+		})
+	}
+
+	table.Render()
+
+	return nil
+}
+
 func runAnalyticsSync(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
@@ -476,6 +890,7 @@ func runAnalyticsSync(cmd *cobra.Command, args []string) error {
 
 	// Determine sync mode
 	var result *clickhouse.SyncResult
+	var refreshSince time.Time
 	fmt.Println("\nSyncing...")
 
 	bar := progressbar.NewOptions(-1,
@@ -489,8 +904,10 @@ func runAnalyticsSync(cmd *cobra.Command, args []string) error {
 	} else if analyticsSyncDays > 0 {
 		bar.Describe(fmt.Sprintf("Syncing last %d days", analyticsSyncDays))
 		result, err = syncer.SyncRecent(ctx, analyticsSyncDays)
+		refreshSince = time.Now().AddDate(0, 0, -analyticsSyncDays)
 	} else {
 		bar.Describe("Incremental sync")
+		refreshSince, _ = syncer.GetLastSyncTime(ctx)
 		result, err = syncer.SyncIncremental(ctx)
 	}
 
@@ -515,6 +932,14 @@ func runAnalyticsSync(cmd *cobra.Command, args []string) error {
 	statsAfter, _ := syncer.GetSyncStats(ctx)
 	fmt.Printf("\nClickHouse records: %d (was %d)\n", statsAfter.TotalCHRecords, statsBefore.TotalCHRecords)
 
+	// Refresh daily aggregates for the synced period
+	if analyticsRefresh {
+		if err := chClient.RefreshMarketDaily(ctx, refreshSince); err != nil {
+			return fmt.Errorf("failed to refresh aggregates: %w", err)
+		}
+		color.Green("✓ Refreshed market_daily aggregates")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/internal/source/nobb"
+	"github.com/badno/badops/internal/source/tiger"
+	"github.com/badno/badops/internal/state"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cacheSource string
+	cacheSKUs   []string
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage source lookup caches",
+	Long: `Inspect and clear the lookup caches kept by Tiger.nl
+(output/.tiger-cache.json) and NOBB (output/.nobb-cache.json).
+
+Tiger.nl cache lifetimes are set with sources.tiger_nl.cache_ttl and
+sources.tiger_nl.negative_cache_ttl (for "not found" results).`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear or invalidate cached lookups",
+	Long: `Delete every entry in a source's cache, or only the entries for the given
+SKUs. For NOBB, a SKU is resolved to its NOBB number from the state; values
+that are not in the state are treated as NOBB numbers.`,
+	Example: `  badops cache clear --source tiger
+  badops cache clear --source tiger --sku CO-T309012
+  badops cache clear --source nobb --sku CO-T309012 --sku CO-T309013`,
+	SilenceUsage: true,
+	RunE:         runCacheClear,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache entry counts and ages",
+	Long: `Show the number of cached entries per source, how many cache a "not found"
+result or have expired, and how old they are. Cache hit rates are tracked per
+run; see 'badops sources status' and the summary of 'badops enhance run'.`,
+	SilenceUsage: true,
+	RunE:         runCacheStats,
+}
+
+func init() {
+	cacheClearCmd.Flags().StringVar(&cacheSource, "source", "", "Cache to clear (tiger, nobb)")
+	cacheClearCmd.Flags().StringSliceVar(&cacheSKUs, "sku", nil, "Only invalidate these SKUs (repeatable)")
+	cacheClearCmd.MarkFlagRequired("source")
+	cacheStatsCmd.Flags().StringVar(&cacheSource, "source", "", "Only show this cache (tiger, nobb)")
+
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+}
+
+// cachedSource is a connector with a persistent lookup cache
+type cachedSource interface {
+	source.Connector
+	CacheSummary() source.CacheSummary
+	ClearCache() int
+	InvalidateCache(keys ...string) int
+}
+
+// cacheSources returns the connectors whose caches match name (tiger, nobb,
+// or "" for all), ready for cache operations
+func cacheSources(ctx context.Context, name string) ([]cachedSource, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+
+	var sources []cachedSource
+	if name == "" || name == "tiger" || name == "tiger_nl" {
+		conn := tiger.NewConnector(tiger.Config{
+			RateLimitMs:      cfg.Sources.TigerNL.RateLimitMs,
+			MappingsFile:     cfg.Sources.TigerNL.MappingsFile,
+			CacheTTL:         cfg.Sources.TigerNL.CacheDuration(),
+			NegativeCacheTTL: cfg.Sources.TigerNL.NegativeCacheDuration(),
+			UserAgent:        cfg.Sources.TigerNL.UserAgent,
+			AcceptLanguage:   cfg.Sources.TigerNL.AcceptLanguage,
+			Cache:            tigerMatchCache(ctx, cfg),
+		})
+		// Connecting only loads the scraper's cache and mappings
+		if err := conn.Connect(ctx); err != nil {
+			return nil, err
+		}
+		sources = append(sources, conn)
+	}
+	if name == "" || name == "nobb" {
+		// The cache is loaded on creation; no credentials are needed
+		sources = append(sources, nobb.NewConnector(nobb.Config{}))
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("unknown cache: %s (use tiger or nobb)", name)
+	}
+	return sources, nil
+}
+
+// cacheKeys returns the cache keys to invalidate for a SKU in a source's cache
+func cacheKeys(store state.Backend, sourceName, sku string) []string {
+	p, inState := store.GetProduct(sku)
+	switch sourceName {
+	case tiger.ConnectorName:
+		keys := []string{sku}
+		if inState {
+			if key := matcher.BarcodeCacheKey(p.Barcode); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		return keys
+	case nobb.ConnectorName:
+		if !inState {
+			return []string{sku} // Not a known SKU; assume a NOBB number
+		}
+		if p.NOBBNumber != "" {
+			return []string{p.NOBBNumber}
+		}
+	}
+	return nil
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	sources, err := cacheSources(cmd.Context(), cacheSource)
+	if err != nil {
+		return err
+	}
+
+	// Resolve barcodes and NOBB numbers when there is a state
+	var store state.Backend = state.NewStore("")
+	if len(cacheSKUs) > 0 {
+		loaded, err := openStore(cmd.Context(), false)
+		if errors.Is(err, state.ErrLocked) {
+			return err
+		}
+		if err == nil {
+			defer loaded.Close()
+			store = loaded
+		}
+	}
+
+	for _, src := range sources {
+		if len(cacheSKUs) == 0 {
+			n := src.ClearCache()
+			// Closing writes the cache file
+			if err := src.Close(); err != nil {
+				return err
+			}
+			color.Green("  ✓ Cleared %d %s cache entries", n, src.Name())
+			continue
+		}
+
+		var keys []string
+		for _, sku := range cacheSKUs {
+			keys = append(keys, cacheKeys(store, src.Name(), sku)...)
+		}
+		n := src.InvalidateCache(keys...)
+		if err := src.Close(); err != nil {
+			return err
+		}
+		color.Green("  ✓ Invalidated %d %s cache entries for %d SKUs", n, src.Name(), len(cacheSKUs))
+	}
+	return nil
+}
+
+// cacheAgeBuckets groups cache entries by age for cache stats
+var cacheAgeBuckets = []struct {
+	label string
+	max   time.Duration
+}{
+	{"< 1h", time.Hour},
+	{"1h-24h", 24 * time.Hour},
+	{"1d-7d", 7 * 24 * time.Hour},
+	{"> 7d", 0}, // Everything older
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	sources, err := cacheSources(cmd.Context(), cacheSource)
+	if err != nil {
+		return err
+	}
+
+	header.Println("\n  CACHE STATISTICS")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	columns := []string{"Source", "Entries", "Not Found", "Expired"}
+	for _, b := range cacheAgeBuckets {
+		columns = append(columns, b.label)
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(columns)
+	table.SetBorder(false)
+	headerColors := make([]tablewriter.Colors, len(columns))
+	for i := range headerColors {
+		headerColors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor}
+	}
+	table.SetHeaderColor(headerColors...)
+
+	for _, src := range sources {
+		summary := src.CacheSummary()
+		counts := make([]int, len(cacheAgeBuckets))
+		for _, age := range summary.Ages {
+			for i, b := range cacheAgeBuckets {
+				if b.max == 0 || age < b.max {
+					counts[i]++
+					break
+				}
+			}
+		}
+
+		row := []string{
+			src.Name(),
+			fmt.Sprintf("%d", summary.Entries),
+			fmt.Sprintf("%d", summary.NotFound),
+			fmt.Sprintf("%d", summary.Expired),
+		}
+		for _, n := range counts {
+			row = append(row, fmt.Sprintf("%d", n))
+		}
+		table.Append(row)
+	}
+
+	table.Render()
+	fmt.Println()
+	return nil
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/badno/badops/internal/database"
 	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/prices"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -49,8 +53,38 @@ var competitorsRemoveCmd = &cobra.Command{
 	RunE:  runCompetitorsRemove,
 }
 
+var competitorsScrapeConfigCmd = &cobra.Command{
+	Use:   "scrape-config <name> [key=value...]",
+	Short: "Show or set a competitor's scrape config",
+	Long: `Shows a competitor's scrape_config, or sets keys in it. Used by
+'badops prices scrape'.
+
+Keys:
+  base_url        Site root for {base_url} and relative URLs (default: website)
+  product_url     URL template for products without a stored URL, with
+                  {base_url}, {sku}, {barcode} and {competitor_sku}
+  price_selector  CSS selector of the price (required); tag, .class, #id,
+                  [attr] and [attr=value], joined by spaces or commas
+  stock_selector  CSS selector of the stock element (in stock when present)
+  in_stock_text   Text the stock element contains when in stock
+  currency        Price currency (default NOK)
+  rate_limit_ms   Milliseconds between requests (default 1000)
+
+An empty value (key=) removes the key.`,
+	Example: `  badops competitors scrape-config Megaflis
+  badops competitors scrape-config Megaflis --enable \
+    price_selector="meta[itemprop=price], .product-price" \
+    product_url="{base_url}/search?q={barcode}" rate_limit_ms=2000`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runCompetitorsScrapeConfig,
+}
+
 var (
 	competitorWebsite string
+
+	competitorScrapeEnable  bool
+	competitorScrapeDisable bool
 )
 
 func init() {
@@ -58,8 +92,14 @@ func init() {
 	competitorsCmd.AddCommand(competitorsAddCmd)
 	competitorsCmd.AddCommand(competitorsStatsCmd)
 	competitorsCmd.AddCommand(competitorsRemoveCmd)
+	competitorsCmd.AddCommand(competitorsScrapeConfigCmd)
 
 	competitorsAddCmd.Flags().StringVar(&competitorWebsite, "website", "", "Competitor website URL")
+
+	competitorsScrapeConfigCmd.Flags().BoolVar(&competitorScrapeEnable, "enable", false, "Enable scraping for the competitor")
+	competitorsScrapeConfigCmd.Flags().BoolVar(&competitorScrapeDisable, "disable", false, "Disable scraping for the competitor")
+	competitorsScrapeConfigCmd.Flags().StringVar(&competitorWebsite, "website", "", "Set the competitor website URL")
+	competitorsScrapeConfigCmd.MarkFlagsMutuallyExclusive("enable", "disable")
 }
 
 func runCompetitorsList(cmd *cobra.Command, args []string) error {
@@ -373,6 +413,103 @@ func runCompetitorsRemove(cmd *cobra.Command, args []string) error {
 }
 
 // Helper functions
+// scrapeConfigKeys are the keys prices.ParseScrapeConfig reads
+var scrapeConfigKeys = []string{"base_url", "product_url", "price_selector", "stock_selector", "in_stock_text", "currency", "rate_limit_ms"}
+
+func runCompetitorsScrapeConfig(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	name := args[0]
+	updates := make(map[string]string)
+	for _, arg := range args[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return fmt.Errorf("invalid setting %q (use key=value)", arg)
+		}
+		if !slices.Contains(scrapeConfigKeys, key) {
+			return fmt.Errorf("unknown scrape_config key %q (valid: %s)", key, strings.Join(scrapeConfigKeys, ", "))
+		}
+		updates[key] = value
+	}
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	repo := postgres.NewCompetitorRepo(client)
+	competitor, err := repo.GetByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to get competitor: %w", err)
+	}
+	if competitor == nil {
+		return fmt.Errorf("competitor not found: %s", name)
+	}
+
+	changed := len(updates) > 0 || competitorScrapeEnable || competitorScrapeDisable || competitorWebsite != ""
+	if changed {
+		if competitor.ScrapeConfig == nil {
+			competitor.ScrapeConfig = make(map[string]string)
+		}
+		for key, value := range updates {
+			if value == "" {
+				delete(competitor.ScrapeConfig, key)
+			} else {
+				competitor.ScrapeConfig[key] = value
+			}
+		}
+		if competitorWebsite != "" {
+			competitor.Website = competitorWebsite
+		}
+		if competitorScrapeEnable {
+			competitor.ScrapeEnabled = true
+		} else if competitorScrapeDisable {
+			competitor.ScrapeEnabled = false
+		}
+
+		// Refuse to enable a config the scraper cannot use
+		if competitor.ScrapeEnabled {
+			if _, err := prices.ParseScrapeConfig(competitor.ScrapeConfig, competitor.Website); err != nil {
+				return fmt.Errorf("invalid scrape_config for %s: %w", competitor.Name, err)
+			}
+		}
+
+		if err := repo.Update(ctx, competitor); err != nil {
+			return err
+		}
+		color.Green("✓ Updated scrape config for %s", competitor.Name)
+	}
+
+	fmt.Printf("\n%s\n", color.CyanString(competitor.Name))
+	enabled := color.YellowString("disabled")
+	if competitor.ScrapeEnabled {
+		enabled = color.GreenString("enabled")
+	}
+	fmt.Printf("  Scraping: %s\n", enabled)
+	if competitor.Website != "" {
+		fmt.Printf("  Website:  %s\n", competitor.Website)
+	}
+	if len(competitor.ScrapeConfig) == 0 {
+		fmt.Println("  No scrape_config set")
+		return nil
+	}
+
+	keys := make([]string, 0, len(competitor.ScrapeConfig))
+	for key := range competitor.ScrapeConfig {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  %-15s %s\n", key+":", competitor.ScrapeConfig[key])
+	}
+	return nil
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
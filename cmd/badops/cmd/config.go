@@ -1,11 +1,23 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/output/file"
+	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/internal/source/nobb"
+	"github.com/badno/badops/internal/source/rest"
+	"github.com/badno/badops/internal/source/shopify"
+	"github.com/badno/badops/internal/source/tiger"
+	"github.com/badno/badops/internal/source/woocommerce"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -28,10 +40,21 @@ var configInitCmd = &cobra.Command{
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
-	Long:  `Display all configuration settings.`,
-	RunE:  runConfigShow,
+	Long: `Display the configuration in effect, with defaults applied and environment
+references expanded. Secret values are shown as the variable they came from,
+and the commands of cmd: credential references are hidden.
+
+With --show-resolved, each credential reference is checked. cmd: references
+run a shell command, so they are only checked with --run-commands.`,
+	RunE: runConfigShow,
 }
 
+var (
+	configShowFormat   string
+	configShowResolved bool
+	configRunCommands  bool // Resolve cmd: credential references in show and validate
+)
+
 var configSetCmd = &cobra.Command{
 	Use:   "set [key] [value]",
 	Short: "Set a configuration value",
@@ -48,11 +71,33 @@ var configGetCmd = &cobra.Command{
 	RunE:  runConfigGet,
 }
 
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check environment variables and connectivity",
+	Long: `Check that every environment variable referenced by the configuration is set.
+With --connect, also test each source connector and ping PostgreSQL and ClickHouse.
+Exits non-zero if a required check fails, for use in CI and deploy scripts.
+
+cmd: credential references run a shell command, so they are skipped, along with
+the connectors that need them, unless --run-commands is given.`,
+	RunE:         runConfigValidate,
+	SilenceUsage: true, // A failed check is not a usage error
+}
+
+var configValidateConnect bool
+
 func init() {
+	configShowCmd.Flags().StringVar(&configShowFormat, "format", "yaml", "Output format (yaml, json)")
+	configShowCmd.Flags().BoolVar(&configShowResolved, "show-resolved", false, "Also show whether each referenced environment variable is set")
+	configShowCmd.Flags().BoolVar(&configRunCommands, "run-commands", false, "With --show-resolved, also run cmd: credential references")
+	configValidateCmd.Flags().BoolVar(&configValidateConnect, "connect", false, "Also test connectors and database connectivity")
+	configValidateCmd.Flags().BoolVar(&configRunCommands, "run-commands", false, "Also run cmd: credential references to check them")
+
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configValidateCmd)
 }
 
 func runConfigInit(cmd *cobra.Command, args []string) error {
@@ -100,15 +145,38 @@ func runConfigInit(cmd *cobra.Command, args []string) error {
 func runConfigShow(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 
-	header.Println("\n  CURRENT CONFIGURATION")
-	fmt.Println("  " + strings.Repeat("─", 40))
-	fmt.Println()
+	if configShowFormat != "yaml" && configShowFormat != "json" {
+		return fmt.Errorf("unsupported format %q (use yaml or json)", configShowFormat)
+	}
 
+	// Load applies defaults, so this is the configuration actually in effect
 	cfg, err := config.Load()
 	if err != nil {
 		color.Red("  Error loading configuration: %v", err)
 		return err
 	}
+	data, err := yaml.Marshal(config.Redacted(cfg))
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	// JSON is printed bare so it can be piped to jq
+	if configShowFormat == "json" {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("failed to serialize config: %w", err)
+		}
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to serialize config: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	header.Println("\n  CURRENT CONFIGURATION")
+	fmt.Println("  " + strings.Repeat("─", 40))
+	fmt.Println()
 
 	configPath, _ := config.GetConfigPath()
 	if config.Exists() {
@@ -117,39 +185,38 @@ func runConfigShow(cmd *cobra.Command, args []string) error {
 		color.Yellow("  Using default configuration (no config file)\n\n")
 	}
 
-	// Format as YAML
-	data, _ := yaml.Marshal(cfg)
 	fmt.Println("  " + strings.ReplaceAll(string(data), "\n", "\n  "))
 	fmt.Println()
 
-	// Show environment variable status
-	header.Println("  ENVIRONMENT VARIABLES")
+	if !configShowResolved {
+		return nil
+	}
+
+	// Show credential status
+	header.Println("  CREDENTIALS")
 	fmt.Println("  " + strings.Repeat("─", 40))
 	fmt.Println()
 
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Variable", "Status"})
+	table.SetHeader([]string{"Setting", "Reference", "Status"})
 	table.SetBorder(false)
 	table.SetHeaderColor(
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 	)
 
-	envVars := []struct {
-		name     string
-		envName  string
-	}{
-		{"Shopify API Key", cfg.Sources.Shopify.APIKeyEnv},
-		{"NOBB Username", cfg.Sources.NOBB.UsernameEnv},
-		{"NOBB Password", cfg.Sources.NOBB.PasswordEnv},
-	}
-
-	for _, ev := range envVars {
-		status := color.RedString("not set")
-		if os.Getenv(ev.envName) != "" {
-			status = color.GreenString("set")
+	for _, ref := range config.CredentialRefs(cfg) {
+		status := color.GreenString("set")
+		value, err := checkCredential(ref.Ref, configRunCommands)
+		if errors.Is(err, errCommandNotRun) {
+			status = color.YellowString("not run (--run-commands)")
+		} else if err != nil {
+			status = color.RedString(truncate(err.Error(), 50))
+		} else if value == "" {
+			status = color.RedString("not set")
 		}
-		table.Append([]string{ev.name + " (" + ev.envName + ")", status})
+		table.Append([]string{ref.Key, credentials.RedactRef(ref.Ref), status})
 	}
 
 	table.Render()
@@ -185,3 +252,304 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	return nil
 }
+
+// credentialCheck is a credential referenced by the configuration
+type credentialCheck struct {
+	name     string
+	ref      string
+	required bool
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	header.Println("\n  VALIDATING CONFIGURATION")
+	fmt.Println("  " + strings.Repeat("─", 40))
+	fmt.Println()
+
+	cfg, err := config.Load()
+	if err != nil {
+		color.Red("  ✗ Config file: %v", err)
+		return err
+	}
+
+	failures := 0
+	pass := func(name string) {
+		color.Green("  ✓ %s", name)
+	}
+	fail := func(name string, reason string) {
+		color.Red("  ✗ %s: %s", name, reason)
+		failures++
+	}
+	skip := func(name string, reason string) {
+		color.Yellow("  - %s: %s", name, reason)
+	}
+
+	configPath, _ := config.GetConfigPath()
+	if config.Exists() {
+		pass("Config file " + configPath)
+	} else {
+		skip("Config file", "not found, using defaults")
+	}
+	fmt.Println()
+
+	// Credentials
+	header.Println("  CREDENTIALS")
+	for _, c := range credentialChecks(cfg) {
+		name := c.name + " (" + credentials.RedactRef(c.ref) + ")"
+		if c.ref == "" {
+			if c.required {
+				fail(c.name, "no credential configured")
+			} else {
+				skip(c.name, "no credential configured")
+			}
+			continue
+		}
+		value, err := checkCredential(c.ref, configRunCommands)
+		switch {
+		case errors.Is(err, errCommandNotRun):
+			skip(name, err.Error())
+		case err != nil:
+			fail(name, err.Error())
+		case value != "":
+			pass(name)
+		case c.required:
+			fail(name, "not set")
+		default:
+			skip(name, "not set (optional)")
+		}
+	}
+	fmt.Println()
+
+	// Durations, reported only when set
+	durations := []struct{ key, value string }{
+		{"sources.tiger_nl.cache_ttl", cfg.Sources.TigerNL.CacheTTL},
+		{"sources.tiger_nl.negative_cache_ttl", cfg.Sources.TigerNL.NegativeCacheTTL},
+		{"database.postgres.max_conn_lifetime", cfg.Database.Postgres.MaxConnLifetime},
+		{"database.postgres.max_conn_idle_time", cfg.Database.Postgres.MaxConnIdleTime},
+	}
+	durationSet := false
+	for _, d := range durations {
+		durationSet = durationSet || d.value != ""
+	}
+	if durationSet {
+		header.Println("  SETTINGS")
+		for _, d := range durations {
+			if d.value == "" {
+				continue
+			}
+			if _, err := config.ParseDuration(d.value); err != nil {
+				fail(d.key, err.Error())
+			} else {
+				pass(d.key + " = " + d.value)
+			}
+		}
+		fmt.Println()
+	}
+
+	// Files referenced by the configuration
+	if cfg.Outputs.File.ColumnMapFile != "" {
+		header.Println("  FILES")
+		if _, err := file.LoadColumnMap(cfg.Outputs.File.ColumnMapFile); err != nil {
+			fail("Column map", err.Error())
+		} else {
+			pass("Column map " + cfg.Outputs.File.ColumnMapFile)
+		}
+		fmt.Println()
+	}
+
+	// REST source endpoint, pagination and field mapping
+	if cfg.Sources.REST.URL != "" {
+		header.Println("  REST SOURCE")
+		if err := cfg.Sources.REST.Validate(); err != nil {
+			fail("Mapping", err.Error())
+		} else {
+			pass("Mapping for " + cfg.Sources.REST.URL)
+		}
+		fmt.Println()
+	}
+
+	if configValidateConnect {
+		header.Println("  CONNECTIVITY")
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+
+		// Connect validates credentials and runs the connector's Test
+		for _, c := range connectorChecks(cfg, configRunCommands) {
+			if !c.enabled {
+				skip(c.name, "credentials not set")
+				continue
+			}
+			if err := c.connector.Connect(ctx); err != nil {
+				fail(c.name, err.Error())
+			} else {
+				pass(c.name)
+			}
+			c.connector.Close()
+		}
+
+		pg := cfg.Database.Postgres
+		ch := cfg.Database.ClickHouse
+		if !commandsAllowed(configRunCommands, pg.UsernameEnv, pg.PasswordEnv) {
+			skip("PostgreSQL", errCommandNotRun.Error())
+		} else if cfg.Database.UseDB || credentialResolves(pg.UsernameEnv, configRunCommands) {
+			if err := pingPostgres(ctx); err != nil {
+				fail("PostgreSQL", err.Error())
+			} else {
+				pass("PostgreSQL")
+			}
+		} else {
+			skip("PostgreSQL", "not enabled")
+		}
+
+		if !commandsAllowed(configRunCommands, ch.UsernameEnv, ch.PasswordEnv) {
+			skip("ClickHouse", errCommandNotRun.Error())
+		} else if credentialResolves(ch.UsernameEnv, configRunCommands) {
+			if err := pingClickHouse(ctx); err != nil {
+				fail("ClickHouse", err.Error())
+			} else {
+				pass("ClickHouse")
+			}
+		} else {
+			skip("ClickHouse", "credentials not set")
+		}
+		fmt.Println()
+	}
+
+	if failures > 0 {
+		color.Red("  %d check(s) failed", failures)
+		fmt.Println()
+		return fmt.Errorf("configuration validation failed: %d check(s) failed", failures)
+	}
+
+	color.Green("  All checks passed")
+	fmt.Println()
+	return nil
+}
+
+// credentialChecks lists the credentials referenced by the configuration.
+// Credentials for configured sources are required.
+func credentialChecks(cfg *config.Config) []credentialCheck {
+	return []credentialCheck{
+		{"Shopify API key", cfg.Sources.Shopify.APIKeyEnv, true},
+		{"Shopify webhook secret", cfg.Sources.Shopify.WebhookSecretEnv, cfg.Sources.Shopify.WebhookSecretEnv != ""},
+		{"NOBB username", cfg.Sources.NOBB.UsernameEnv, false},
+		{"NOBB password", cfg.Sources.NOBB.PasswordEnv, false},
+		{"Shopify output API key", cfg.Outputs.Shopify.APIKeyEnv, false},
+		{"PostgreSQL username", cfg.Database.Postgres.UsernameEnv, cfg.Database.UseDB},
+		{"PostgreSQL password", cfg.Database.Postgres.PasswordEnv, cfg.Database.UseDB},
+		{"ClickHouse username", cfg.Database.ClickHouse.UsernameEnv, false},
+		{"ClickHouse password", cfg.Database.ClickHouse.PasswordEnv, false},
+		{"Notify webhook URL", cfg.Notify.WebhookURLEnv, false},
+		{"WooCommerce consumer key", cfg.Sources.WooCommerce.ConsumerKeyEnv, cfg.Sources.WooCommerce.URL != ""},
+		{"WooCommerce consumer secret", cfg.Sources.WooCommerce.ConsumerSecretEnv, cfg.Sources.WooCommerce.URL != ""},
+		{"REST source token", cfg.Sources.REST.TokenEnv, cfg.Sources.REST.URL != "" && cfg.Sources.REST.TokenEnv != ""},
+		{"Image upload access key", cfg.Images.Upload.AccessKeyEnv, cfg.Images.Upload.Configured()},
+		{"Image upload secret key", cfg.Images.Upload.SecretKeyEnv, cfg.Images.Upload.Configured()},
+	}
+}
+
+// errCommandNotRun is returned by checkCredential for a cmd: reference it
+// was not allowed to run
+var errCommandNotRun = errors.New("cmd: reference not run (use --run-commands)")
+
+// checkCredential resolves a credential reference for a check. cmd:
+// references run a shell command, so they are only resolved with runCommands.
+func checkCredential(ref string, runCommands bool) (string, error) {
+	if credentials.RunsCommand(ref) && !runCommands {
+		return "", errCommandNotRun
+	}
+	return credentials.Resolve(nil, ref)
+}
+
+// credentialResolves reports whether a credential reference resolves to a
+// value. Without runCommands, cmd: references count as unresolved.
+func credentialResolves(ref string, runCommands bool) bool {
+	value, err := checkCredential(ref, runCommands)
+	return err == nil && value != ""
+}
+
+// commandsAllowed reports whether refs may be resolved: with runCommands
+// always, otherwise only when none of them is a cmd: reference
+func commandsAllowed(runCommands bool, refs ...string) bool {
+	if runCommands {
+		return true
+	}
+	for _, ref := range refs {
+		if credentials.RunsCommand(ref) {
+			return false
+		}
+	}
+	return true
+}
+
+// connectorCheck is a source connector to test, enabled when its
+// credentials or settings are configured
+type connectorCheck struct {
+	name      string
+	connector source.Connector
+	enabled   bool
+}
+
+// connectorChecks lists the source connectors whose connectivity can be
+// tested with the configuration. Without runCommands, connectors whose
+// credentials are cmd: references are not enabled.
+func connectorChecks(cfg *config.Config, runCommands bool) []connectorCheck {
+	return []connectorCheck{
+		{"Shopify", shopify.NewConnector(shopify.Config{
+			Store:     cfg.Sources.Shopify.Store,
+			APIKeyEnv: cfg.Sources.Shopify.APIKeyEnv,
+		}), credentialResolves(cfg.Sources.Shopify.APIKeyEnv, runCommands)},
+		{"NOBB", nobb.NewConnector(nobb.Config{
+			UsernameEnv:   cfg.Sources.NOBB.UsernameEnv,
+			PasswordEnv:   cfg.Sources.NOBB.PasswordEnv,
+			DimensionUnit: cfg.Defaults.DimensionUnit,
+			WeightUnit:    cfg.Defaults.WeightUnit,
+			SKURulesFile:  cfg.Defaults.SKURulesFile,
+		}), credentialResolves(cfg.Sources.NOBB.UsernameEnv, runCommands) && credentialResolves(cfg.Sources.NOBB.PasswordEnv, runCommands)},
+		{"Tiger.nl", tiger.NewConnector(tiger.Config{
+			RateLimitMs:      cfg.Sources.TigerNL.RateLimitMs,
+			MappingsFile:     cfg.Sources.TigerNL.MappingsFile,
+			SKURulesFile:     cfg.Defaults.SKURulesFile,
+			CacheTTL:         cfg.Sources.TigerNL.CacheDuration(),
+			NegativeCacheTTL: cfg.Sources.TigerNL.NegativeCacheDuration(),
+			UserAgent:        cfg.Sources.TigerNL.UserAgent,
+			AcceptLanguage:   cfg.Sources.TigerNL.AcceptLanguage,
+		}), true},
+		{"WooCommerce", woocommerce.NewConnector(woocommerce.Config{
+			URL:               cfg.Sources.WooCommerce.URL,
+			ConsumerKeyEnv:    cfg.Sources.WooCommerce.ConsumerKeyEnv,
+			ConsumerSecretEnv: cfg.Sources.WooCommerce.ConsumerSecretEnv,
+			Currency:          cfg.Sources.WooCommerce.Currency,
+		}), cfg.Sources.WooCommerce.URL != "" &&
+			commandsAllowed(runCommands, cfg.Sources.WooCommerce.ConsumerKeyEnv, cfg.Sources.WooCommerce.ConsumerSecretEnv)},
+		{"REST", rest.NewConnector(cfg.Sources.REST), cfg.Sources.REST.URL != "" &&
+			commandsAllowed(runCommands, cfg.Sources.REST.TokenEnv)},
+	}
+}
+
+// pingPostgres connects to PostgreSQL and pings it
+func pingPostgres(ctx context.Context) error {
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Ping(ctx)
+}
+
+// pingClickHouse connects to ClickHouse, which pings it
+func pingClickHouse(ctx context.Context) error {
+	client, err := getClickHouseClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	return client.Close()
+}
@@ -4,16 +4,22 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/database"
 	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/matcher"
 	"github.com/badno/badops/internal/state"
 	"github.com/badno/badops/pkg/models"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
 	"github.com/olekukonko/tablewriter"
+	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
@@ -40,22 +46,92 @@ var dbStatusCmd = &cobra.Command{
 var dbMigrateCmd = &cobra.Command{
 	Use:   "migrate",
 	Short: "Migrate data from JSON state to database",
-	Long:  "Imports products and history from the JSON state file into the database",
-	RunE:  runDBMigrate,
+	Long: `Imports products and history from the JSON state file into the database.
+
+--force merges the state into a database that already has products; run
+'badops db backup' first to be able to undo it.`,
+	RunE: runDBMigrate,
+}
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup <file>",
+	Short: "Back up the database to a file",
+	Long: `Writes a backup of the database to a file, e.g. before 'db migrate --force'
+or purging products.
+
+When pg_dump is installed the backup is a pg_dump custom-format archive of
+the whole database. Otherwise, or with --logical, the core tables are
+exported with COPY into a plain-text file that 'db restore' can load
+without any PostgreSQL client tools.
+
+Examples:
+  badops db backup backups/badops-$(date +%F).dump
+  badops db backup --logical badops.sql`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runDBBackup,
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore <file>",
+	Short: "Restore the database from a backup",
+	Long: `Restores a backup written by 'db backup'. pg_dump archives are restored
+with pg_restore; logical backups are loaded with COPY after applying the
+schema migrations.
+
+The restore refuses to run when the database already contains data unless
+--force is given, in which case the existing data is replaced.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runDBRestore,
+}
+
+var dbPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old data to keep the database size in check",
+	Long: `Deletes price observations older than the given age and reports how many
+rows were removed. --vacuum runs VACUUM ANALYZE on the pruned tables
+afterwards to reclaim space and refresh planner statistics. --dry-run only
+counts what would be deleted.
+
+Examples:
+  badops db prune --observations-older-than 180d --dry-run
+  badops db prune --observations-older-than 365d --vacuum`,
+	SilenceUsage: true,
+	RunE:         runDBPrune,
 }
 
 var (
 	migrateFromState string
 	migrateForce     bool
+	migrateBatchSize int
+	backupLogical    bool
+	restoreForce     bool
+
+	pruneObservationsAge string
+	pruneVacuum          bool
+	pruneDryRun          bool
 )
 
 func init() {
 	dbCmd.AddCommand(dbInitCmd)
 	dbCmd.AddCommand(dbStatusCmd)
 	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbBackupCmd)
+	dbCmd.AddCommand(dbRestoreCmd)
+	dbCmd.AddCommand(dbPruneCmd)
 
 	dbMigrateCmd.Flags().StringVar(&migrateFromState, "from-state", "", "Path to JSON state file (default: output/.badops-state.json)")
 	dbMigrateCmd.Flags().BoolVar(&migrateForce, "force", false, "Force migration even if products already exist in database")
+	dbMigrateCmd.Flags().IntVar(&migrateBatchSize, "batch-size", database.DefaultBulkBatchSize, "Products to write per transaction")
+
+	dbBackupCmd.Flags().BoolVar(&backupLogical, "logical", false, "Write a COPY-based backup even if pg_dump is installed")
+
+	dbRestoreCmd.Flags().BoolVar(&restoreForce, "force", false, "Replace the data in a non-empty database")
+
+	dbPruneCmd.Flags().StringVar(&pruneObservationsAge, "observations-older-than", "", "Delete price observations older than this (e.g. 180d, 4320h)")
+	dbPruneCmd.Flags().BoolVar(&pruneVacuum, "vacuum", false, "Run VACUUM ANALYZE on the pruned tables")
+	dbPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Count the rows that would be deleted without deleting them")
 }
 
 // getDBClient creates a PostgreSQL client from configuration
@@ -65,17 +141,32 @@ func getDBClient() (*postgres.Client, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
+	username, err := credentials.Resolve(nil, cfg.Database.Postgres.UsernameEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve PostgreSQL username: %w", err)
+	}
+	password, err := credentials.Resolve(nil, cfg.Database.Postgres.PasswordEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve PostgreSQL password: %w", err)
+	}
+
 	pgConfig := &postgres.Config{
 		Host:     cfg.Database.Postgres.Host,
 		Port:     cfg.Database.Postgres.Port,
 		Database: cfg.Database.Postgres.Database,
-		Username: os.Getenv(cfg.Database.Postgres.UsernameEnv),
-		Password: os.Getenv(cfg.Database.Postgres.PasswordEnv),
+		Username: username,
+		Password: password,
 		SSLMode:  cfg.Database.Postgres.SSLMode,
+
+		MaxConns:       int32(cfg.Database.Postgres.MaxConns),
+		MinConns:       int32(cfg.Database.Postgres.MinConns),
+		MaxConnLife:    cfg.Database.Postgres.ConnLifetime(),
+		MaxConnIdle:    cfg.Database.Postgres.ConnIdleTime(),
+		ConnectRetries: cfg.Database.Postgres.ConnectRetries,
 	}
 
 	if pgConfig.Username == "" {
-		return nil, fmt.Errorf("PostgreSQL username not set. Set the %s environment variable", cfg.Database.Postgres.UsernameEnv)
+		return nil, fmt.Errorf("PostgreSQL username not set. Set %s", cfg.Database.Postgres.UsernameEnv)
 	}
 
 	return postgres.NewClient(pgConfig), nil
@@ -221,6 +312,7 @@ func runDBMigrate(cmd *cobra.Command, args []string) error {
 	if err := store.Load(); err != nil {
 		return fmt.Errorf("failed to load state file: %w", err)
 	}
+	defer store.Close()
 
 	products := store.GetAllProducts()
 	if len(products) == 0 {
@@ -254,17 +346,36 @@ func runDBMigrate(cmd *cobra.Command, args []string) error {
 	if existingCount > 0 && !migrateForce {
 		color.Yellow("Database already contains %d products", existingCount)
 		fmt.Println("Use --force to migrate anyway (will merge with existing data)")
+		fmt.Println("Back up the database first with: badops db backup <file>")
 		return nil
 	}
 
 	// Migrate products
 	fmt.Println("\nMigrating products...")
-	count, err := productRepo.BulkUpsert(ctx, products)
+	bar := progressbar.NewOptions(len(products),
+		progressbar.OptionSetDescription("Migrating"),
+		progressbar.OptionSetWidth(40),
+		progressbar.OptionShowCount(),
+		progressbar.OptionClearOnFinish(),
+	)
+	count, err := productRepo.BulkUpsertWithOptions(ctx, products, database.BulkOptions{
+		BatchSize: migrateBatchSize,
+		Progress: func(done, total int) {
+			bar.Set(done)
+		},
+	})
+	bar.Finish()
 	if err != nil {
+		if count > 0 {
+			color.Yellow("%d products were migrated before the failure", count)
+		}
 		return fmt.Errorf("failed to migrate products: %w", err)
 	}
 
 	color.Green("✓ Migrated %d products", count)
+	if skipped := len(products) - count; skipped > 0 {
+		color.Yellow("Skipped %d invalid products (run 'badops products validate' for details)", skipped)
+	}
 
 	// Migrate images
 	imageRepo := postgres.NewImageRepo(client)
@@ -310,6 +421,71 @@ func runDBMigrate(cmd *cobra.Command, args []string) error {
 		color.Green("✓ Migrated %d properties", totalProps)
 	}
 
+	// Migrate suppliers and their article numbers
+	supplierRepo := postgres.NewSupplierRepo(client)
+	suppliers := make(map[string]*database.Supplier)
+	var supplierLinks []*database.ProductSupplier
+	for _, p := range products {
+		productID, err := uuid.Parse(p.ID)
+		if err != nil {
+			continue
+		}
+		for _, s := range p.Suppliers {
+			if s.ID == "" {
+				continue
+			}
+			if _, ok := suppliers[s.ID]; !ok || suppliers[s.ID].GLN == "" {
+				suppliers[s.ID] = &database.Supplier{ID: s.ID, Name: s.Name, GLN: s.GLN}
+			}
+			supplierLinks = append(supplierLinks, &database.ProductSupplier{
+				ProductID:     productID,
+				SupplierID:    s.ID,
+				ArticleNumber: s.ArticleNo,
+				IsPrimary:     s.IsPrimary,
+			})
+		}
+	}
+
+	var totalSuppliers, totalSupplierLinks int
+	if len(suppliers) > 0 {
+		dbSuppliers := make([]*database.Supplier, 0, len(suppliers))
+		for _, s := range suppliers {
+			dbSuppliers = append(dbSuppliers, s)
+		}
+		totalSuppliers, err = supplierRepo.BulkUpsert(ctx, dbSuppliers)
+		if err != nil {
+			color.Yellow("Warning: failed to migrate suppliers: %v", err)
+		} else {
+			color.Green("✓ Migrated %d suppliers", totalSuppliers)
+
+			totalSupplierLinks, err = supplierRepo.BulkUpsertProductSuppliers(ctx, supplierLinks)
+			if err != nil {
+				color.Yellow("Warning: failed to migrate product suppliers: %v", err)
+			} else {
+				color.Green("✓ Migrated %d product supplier links", totalSupplierLinks)
+			}
+		}
+	}
+
+	// Migrate enhancement records to the enhancement log
+	enhancements := make(map[uuid.UUID][]models.Enhancement)
+	for _, p := range products {
+		productID, err := uuid.Parse(p.ID)
+		if err != nil || len(p.Enhancements) == 0 {
+			continue
+		}
+		enhancements[productID] = p.Enhancements
+	}
+	var totalEnhancements int
+	if len(enhancements) > 0 {
+		totalEnhancements, err = postgres.NewEnhancementLogRepo(client).ImportEnhancements(ctx, enhancements)
+		if err != nil {
+			color.Yellow("Warning: failed to migrate enhancement log: %v", err)
+		} else {
+			color.Green("✓ Migrated %d enhancement log entries", totalEnhancements)
+		}
+	}
+
 	// Migrate history
 	historyRepo := postgres.NewHistoryRepo(client)
 	history := store.GetHistory()
@@ -331,12 +507,26 @@ func runDBMigrate(cmd *cobra.Command, args []string) error {
 		color.Green("✓ Migrated %d history entries", len(history))
 	}
 
+	// Migrate the Tiger.nl lookup cache so the shared cache starts warm
+	var totalCached int
+	if entries, _ := matcher.NewFileCache(matcher.DefaultCacheFile).Entries(); len(entries) > 0 {
+		totalCached, err = postgres.NewMatchCacheRepo(client).Import(ctx, entries)
+		if err != nil {
+			color.Yellow("Warning: failed to migrate match cache: %v", err)
+		} else {
+			color.Green("✓ Migrated %d match cache entries", totalCached)
+		}
+	}
+
 	// Summary
 	fmt.Println("\n" + color.CyanString("Migration Summary"))
-	fmt.Printf("  Products:   %d\n", count)
-	fmt.Printf("  Images:     %d\n", totalImages)
-	fmt.Printf("  Properties: %d\n", totalProps)
-	fmt.Printf("  History:    %d\n", len(history))
+	fmt.Printf("  Products:     %d\n", count)
+	fmt.Printf("  Images:       %d\n", totalImages)
+	fmt.Printf("  Properties:   %d\n", totalProps)
+	fmt.Printf("  Suppliers:    %d\n", totalSuppliers)
+	fmt.Printf("  Enhancements: %d\n", totalEnhancements)
+	fmt.Printf("  History:      %d\n", len(history))
+	fmt.Printf("  Match cache:  %d\n", totalCached)
 
 	color.Green("\n✓ Migration complete")
 	fmt.Println("\nTo enable database backend, run:")
@@ -345,6 +535,231 @@ func runDBMigrate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runDBBackup(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Minute)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Connecting to PostgreSQL...")
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	color.Green("✓ Connected")
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create backup directory: %w", err)
+		}
+	}
+
+	// Write to a temporary file so a failed backup never replaces a good one
+	tmp := path + ".tmp"
+	defer os.Remove(tmp)
+
+	pgDump, lookErr := exec.LookPath("pg_dump")
+	if !backupLogical && lookErr == nil {
+		fmt.Printf("Running %s...\n", pgDump)
+		if err := client.PgDump(ctx, pgDump, tmp); err != nil {
+			return err
+		}
+	} else {
+		if !backupLogical {
+			color.Yellow("pg_dump not found, writing a logical backup of the core tables")
+		}
+		fmt.Println("Exporting tables...")
+		f, err := os.Create(tmp)
+		if err != nil {
+			return fmt.Errorf("failed to create backup file: %w", err)
+		}
+		counts, err := client.ExportTables(ctx, f)
+		if closeErr := f.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write backup file: %w", closeErr)
+		}
+		if err != nil {
+			return err
+		}
+		showBackupCounts(counts)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	size := ""
+	if info, err := os.Stat(path); err == nil {
+		size = fmt.Sprintf(" (%s)", formatBytes(info.Size()))
+	}
+	color.Green("\n✓ Backup written to %s%s", path, size)
+	return nil
+}
+
+func runDBRestore(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	format, err := postgres.DetectBackupFormat(path)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Minute)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Connecting to PostgreSQL...")
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	color.Green("✓ Connected")
+
+	hasData, err := client.HasData(ctx)
+	if err != nil {
+		return err
+	}
+	if hasData {
+		if !restoreForce {
+			color.Yellow("Database already contains data")
+			fmt.Println("Use --force to replace it (back it up first with: badops db backup <file>)")
+			return fmt.Errorf("refusing to restore over a non-empty database")
+		}
+		color.Yellow("Replacing existing data (--force)")
+	}
+
+	switch format {
+	case postgres.BackupFormatPgDump:
+		pgRestore, err := exec.LookPath("pg_restore")
+		if err != nil {
+			return fmt.Errorf("%s is a pg_dump archive, which needs pg_restore: install the PostgreSQL client tools", path)
+		}
+		fmt.Printf("Running %s...\n", pgRestore)
+		if err := client.PgRestore(ctx, pgRestore, path); err != nil {
+			return err
+		}
+
+	case postgres.BackupFormatLogical:
+		fmt.Println("Running migrations...")
+		if err := client.RunMigrations(); err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+
+		fmt.Println("Restoring tables...")
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		counts, err := client.ImportTables(ctx, f, hasData)
+		if err != nil {
+			return err
+		}
+		showBackupCounts(counts)
+	}
+
+	color.Green("\n✓ Database restored from %s", path)
+	return nil
+}
+
+func runDBPrune(cmd *cobra.Command, args []string) error {
+	if pruneObservationsAge == "" {
+		return fmt.Errorf("nothing to prune: pass --observations-older-than (e.g. 180d)")
+	}
+	age, err := config.ParseDuration(pruneObservationsAge)
+	if err != nil {
+		return fmt.Errorf("invalid --observations-older-than: %w", err)
+	}
+	cutoff := time.Now().Add(-age)
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Minute)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Connecting to PostgreSQL...")
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	color.Green("✓ Connected")
+
+	priceRepo := postgres.NewPriceObservationRepo(client)
+	cutoffLabel := cutoff.Format(time.DateOnly)
+
+	if pruneDryRun {
+		count, err := priceRepo.CountOlderThan(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		color.Yellow("Dry run: %d price observations from before %s would be deleted", count, cutoffLabel)
+		return nil
+	}
+
+	fmt.Printf("Deleting price observations from before %s...\n", cutoffLabel)
+	start := time.Now()
+	deleted, err := priceRepo.DeleteOlderThan(ctx, cutoff)
+	if err != nil {
+		return err
+	}
+	color.Green("✓ Deleted %d price observations", deleted)
+
+	if pruneVacuum {
+		fmt.Println("Running VACUUM ANALYZE...")
+		if err := client.VacuumAnalyze(ctx, "price_observations"); err != nil {
+			return err
+		}
+		color.Green("✓ Vacuumed price_observations")
+	}
+
+	historyRepo := postgres.NewHistoryRepo(client)
+	completed := time.Now()
+	historyRepo.Add(ctx, &database.OperationHistory{
+		Action:      "db_prune",
+		Source:      "price_observations",
+		Count:       int(deleted),
+		Details:     fmt.Sprintf("Deleted %d price observations older than %s (before %s)", deleted, pruneObservationsAge, cutoffLabel),
+		StartedAt:   start,
+		CompletedAt: &completed,
+	})
+
+	return nil
+}
+
+// showBackupCounts prints the rows exported or restored per table
+func showBackupCounts(counts map[string]int64) {
+	tables := make([]string, 0, len(counts))
+	for table := range counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Table", "Rows"})
+	table.SetBorder(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, name := range tables {
+		table.Append([]string{name, fmt.Sprintf("%d", counts[name])})
+	}
+	table.Render()
+}
+
 // Helper functions for converting models
 func convertToDBImage(productID string, img *models.ProductImage) *database.ProductImage {
 	dbImg := &database.ProductImage{
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/fatih/color"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that every external dependency is reachable",
+	Long: `Run every health check before a scheduled run: the config file parses,
+required credentials are set, PostgreSQL connects and is fully migrated,
+ClickHouse connects, the configured sources pass their connection test and
+the output directories are writable.
+
+Checks for services that are not configured are skipped. Credentials are
+resolved as a real run would, including cmd: references. The command exits
+non-zero when any check fails.`,
+	SilenceUsage: true,
+	RunE:         runDoctor,
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	header.Println("\n  BADOPS DOCTOR")
+	fmt.Println("  " + strings.Repeat("─", 40))
+	fmt.Println()
+
+	failures := 0
+	pass := func(name string) {
+		color.Green("  ✓ %s", name)
+	}
+	fail := func(name string, reason string) {
+		color.Red("  ✗ %s: %s", name, reason)
+		failures++
+	}
+	skip := func(name string, reason string) {
+		color.Yellow("  - %s: %s", name, reason)
+	}
+
+	// Config
+	header.Println("  CONFIG")
+	configPath, _ := config.GetConfigPath()
+	cfg, err := config.Load()
+	switch {
+	case err != nil:
+		fail("Config file "+configPath, err.Error())
+		cfg = config.DefaultConfig()
+	case config.Exists():
+		pass("Config file " + configPath)
+	default:
+		skip("Config file", "not found, using defaults")
+	}
+	fmt.Println()
+
+	// Credentials
+	header.Println("  CREDENTIALS")
+	for _, c := range credentialChecks(cfg) {
+		if c.ref == "" {
+			if c.required {
+				fail(c.name, "no credential configured")
+			}
+			continue
+		}
+		name := c.name + " (" + credentials.RedactRef(c.ref) + ")"
+		value, err := credentials.Resolve(nil, c.ref)
+		switch {
+		case err != nil:
+			fail(name, err.Error())
+		case value != "":
+			pass(name)
+		case c.required:
+			fail(name, "not set")
+		default:
+			skip(name, "not set (optional)")
+		}
+	}
+	fmt.Println()
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+	defer cancel()
+
+	// Databases
+	header.Println("  DATABASES")
+	if cfg.Database.UseDB || credentialResolves(cfg.Database.Postgres.UsernameEnv, true) {
+		if version, err := checkPostgres(ctx); err != nil {
+			fail("PostgreSQL", err.Error())
+		} else {
+			pass(fmt.Sprintf("PostgreSQL (migration v%d)", version))
+		}
+	} else {
+		skip("PostgreSQL", "not enabled")
+	}
+	if credentialResolves(cfg.Database.ClickHouse.UsernameEnv, true) {
+		if err := pingClickHouse(ctx); err != nil {
+			fail("ClickHouse", err.Error())
+		} else {
+			pass("ClickHouse")
+		}
+	} else {
+		skip("ClickHouse", "credentials not set")
+	}
+	fmt.Println()
+
+	// Sources
+	header.Println("  SOURCES")
+	for _, c := range connectorChecks(cfg, true) {
+		if !c.enabled {
+			skip(c.name, "not configured")
+			continue
+		}
+		// Connect resolves credentials and runs the connector's Test
+		if err := c.connector.Connect(ctx); err != nil {
+			fail(c.name, err.Error())
+		} else {
+			pass(c.name)
+		}
+		c.connector.Close()
+	}
+	fmt.Println()
+
+	// Directories
+	header.Println("  FILES")
+	dirs := []string{filepath.Dir(stateFile)}
+	if dir := cfg.Outputs.File.OutputDir; dir != "" && filepath.Clean(dir) != dirs[0] {
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	for _, dir := range dirs {
+		if err := checkWritable(dir); err != nil {
+			fail("Directory "+dir, err.Error())
+		} else {
+			pass("Directory " + dir + " is writable")
+		}
+	}
+	fmt.Println()
+
+	if failures > 0 {
+		color.Red("  %d check(s) failed", failures)
+		fmt.Println()
+		return fmt.Errorf("doctor found %d failing check(s)", failures)
+	}
+
+	color.Green("  All checks passed")
+	fmt.Println()
+	return nil
+}
+
+// checkPostgres connects to PostgreSQL and checks that every embedded
+// migration has been applied, returning the database's migration version
+func checkPostgres(ctx context.Context) (uint, error) {
+	if err := pingPostgres(ctx); err != nil {
+		return 0, err
+	}
+
+	client, err := getDBClient()
+	if err != nil {
+		return 0, err
+	}
+	version, dirty, err := client.MigrationVersion()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, fmt.Errorf("schema not initialized (run 'badops db init')")
+	}
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return version, fmt.Errorf("migration v%d is dirty (repair it, then run 'badops db init')", version)
+	}
+
+	latest, err := postgres.LatestMigrationVersion()
+	if err != nil {
+		return version, err
+	}
+	if version < latest {
+		return version, fmt.Errorf("migration v%d, expected v%d (run 'badops db init' to apply pending migrations)", version, latest)
+	}
+	return version, nil
+}
+
+// checkWritable creates dir if needed and writes and removes a temporary
+// file in it
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".badops-doctor-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -3,29 +3,54 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/metrics"
+	"github.com/badno/badops/internal/orchestrator"
 	"github.com/badno/badops/internal/source"
 	"github.com/badno/badops/internal/source/nobb"
 	"github.com/badno/badops/internal/source/tiger"
 	"github.com/badno/badops/internal/state"
 	"github.com/badno/badops/pkg/models"
 	"github.com/fatih/color"
+	"github.com/google/uuid"
 	"github.com/olekukonko/tablewriter"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
 )
 
 var (
-	enhanceSources  []string
-	enhanceLimit    int
-	enhanceDryRun   bool
-	enhanceVendor   string
+	enhanceSources     []string
+	enhanceLimit       int
+	enhanceDryRun      bool
+	enhanceVendor      string
+	enhanceConcurrency int
+	enhanceSkipFresh   string
+	enhanceSaveEvery   int
+	enhanceDiffSource  string
+	enhanceRollbackRun string
+	enhanceRollbackLs  bool
+	enhanceLogSKU      string
+	enhanceLogSource   string
+	enhanceLogLimit    int
+	enhanceLogFailed   bool
+	enhanceRefresh     bool
+	enhancePersist     bool
 )
 
+// enhanceRow is one product/source line of the enhance run results table
+type enhanceRow struct {
+	sku, source, status, details string
+}
+
 var enhanceCmd = &cobra.Command{
 	Use:   "enhance",
 	Short: "Enhance products with additional data",
@@ -35,8 +60,16 @@ var enhanceCmd = &cobra.Command{
 var enhanceRunCmd = &cobra.Command{
 	Use:   "run",
 	Short: "Run enhancements on products",
-	Long:  `Enhance products using specified sources (nobb, tiger_nl).`,
-	RunE:  runEnhance,
+	Long: `Enhance products using specified sources (nobb, tiger_nl).
+
+Ctrl-C (or SIGTERM) stops the run after the products in flight finish and
+saves what was enhanced; press it again to exit immediately.
+
+--persist also saves each enhanced product to PostgreSQL in its own
+transaction while the JSON state file is used; with database.use_db the
+state already lives there.`,
+	SilenceUsage: true,
+	RunE:         runEnhance,
 }
 
 var enhanceReviewCmd = &cobra.Command{
@@ -46,6 +79,40 @@ var enhanceReviewCmd = &cobra.Command{
 	RunE:  runEnhanceReview,
 }
 
+var enhanceDiffCmd = &cobra.Command{
+	Use:   "diff <sku>",
+	Short: "Show what an enhancement would change for one product",
+	Long: `Run a source against a copy of one product and print a field-by-field
+diff, including added images and properties. The state is not modified.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runEnhanceDiff,
+}
+
+var enhanceRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Undo an enhance run",
+	Long: `Restore the products changed by the most recent enhance run, or the run
+given with --run, and remove that run's enhancement records. The last
+defaults.journal_runs runs (default 5) can be rolled back.`,
+	SilenceUsage: true,
+	RunE:         runEnhanceRollback,
+}
+
+var enhanceLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show the enhancement log from PostgreSQL",
+	Long: `Show the enhancement audit log: each source's outcome per product, with
+the fields it added or the error it failed with, newest first. Runs write the
+log when database.use_db is enabled, and 'db migrate' copies the enhancements
+recorded in the state file.`,
+	Example: `  badops enhance log --sku CO-T309012
+  badops enhance log --source nobb --limit 100
+  badops enhance log --sku CO-T309012 --source tiger_nl`,
+	SilenceUsage: true,
+	RunE:         runEnhanceLog,
+}
+
 var enhanceApplyCmd = &cobra.Command{
 	Use:   "apply",
 	Short: "Apply approved enhancements",
@@ -58,26 +125,53 @@ func init() {
 	enhanceRunCmd.Flags().IntVar(&enhanceLimit, "limit", 0, "Maximum products to enhance (0 = all)")
 	enhanceRunCmd.Flags().BoolVar(&enhanceDryRun, "dry-run", false, "Preview without making changes")
 	enhanceRunCmd.Flags().StringVar(&enhanceVendor, "vendor", "", "Only enhance products from this vendor")
+	enhanceRunCmd.Flags().IntVar(&enhanceConcurrency, "concurrency", 1, "Products to enhance in parallel (sources keep their own rate limits)")
+	enhanceRunCmd.Flags().StringVar(&enhanceSkipFresh, "skip-fresh", "", "Skip sources that enhanced a product within this window (e.g., 7d, 12h)")
+	enhanceRunCmd.Flags().IntVar(&enhanceSaveEvery, "save-every", 25, "Save state after every N products (0 = only at the end)")
+	enhanceRunCmd.Flags().BoolVar(&enhanceRefresh, "force-refresh", false, "Ignore cached Tiger.nl and NOBB lookups and fetch them again (fresh results are re-cached)")
+	enhanceRunCmd.Flags().BoolVar(&enhancePersist, "persist", false, "Also save enhanced products to PostgreSQL (JSON state only)")
+
+	enhanceDiffCmd.Flags().StringVar(&enhanceDiffSource, "source", "tiger_nl", "Enhancement source to diff (tiger_nl, nobb)")
 
 	enhanceCmd.AddCommand(enhanceRunCmd)
 	enhanceCmd.AddCommand(enhanceReviewCmd)
+	enhanceRollbackCmd.Flags().StringVar(&enhanceRollbackRun, "run", "", "Run ID to roll back (default: most recent)")
+	enhanceRollbackCmd.Flags().BoolVar(&enhanceRollbackLs, "list", false, "List the runs that can be rolled back")
+
+	enhanceCmd.AddCommand(enhanceDiffCmd)
+	enhanceCmd.AddCommand(enhanceRollbackCmd)
 	enhanceCmd.AddCommand(enhanceApplyCmd)
+
+	enhanceLogCmd.Flags().StringVar(&enhanceLogSKU, "sku", "", "Show the log for one product")
+	enhanceLogCmd.Flags().StringVar(&enhanceLogSource, "source", "", "Show the log for one source (tiger_nl, nobb)")
+	enhanceLogCmd.Flags().IntVar(&enhanceLogLimit, "limit", 50, "Maximum entries to show")
+	enhanceCmd.AddCommand(enhanceLogCmd)
 }
 
 func runEnhance(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 	success := color.New(color.FgGreen)
 
+	var freshWindow time.Duration
+	if enhanceSkipFresh != "" {
+		window, err := parseFreshWindow(enhanceSkipFresh)
+		if err != nil {
+			return err
+		}
+		freshWindow = window
+	}
+
 	header.Println("\n  ENHANCING PRODUCTS")
 	fmt.Println("  " + strings.Repeat("─", 50))
 	fmt.Println()
 
 	// Load state
-	store := state.NewStore("")
-	if err := store.Load(); err != nil {
+	store, err := openStore(cmd.Context(), !enhanceDryRun)
+	if err != nil {
 		color.Red("  Error loading state: %v", err)
 		return err
 	}
+	defer store.Close()
 
 	// Get products to enhance
 	products := store.GetAllProducts()
@@ -116,35 +210,29 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 		cfg = config.DefaultConfig()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	ctx, cancel := context.WithTimeout(cmd.Context(), 10*time.Minute)
 	defer cancel()
 
+	var persister orchestrator.EnhancedPersister
+	if enhancePersist && !enhanceDryRun {
+		p, closeDB, err := enhancePersister(ctx, cfg)
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return err
+		}
+		defer closeDB()
+		persister = p
+	}
+
 	// Initialize connectors based on requested sources
 	enhancers := make(map[string]source.Connector)
 	for _, src := range enhanceSources {
-		switch src {
-		case "tiger_nl":
-			conn := tiger.NewConnector(tiger.Config{
-				RateLimitMs: cfg.Sources.TigerNL.RateLimitMs,
-			})
-			if err := conn.Connect(ctx); err != nil {
-				color.Yellow("  Warning: Could not connect to Tiger.nl: %v", err)
-				continue
-			}
-			enhancers[src] = conn
-		case "nobb":
-			conn := nobb.NewConnector(nobb.Config{
-				UsernameEnv: cfg.Sources.NOBB.UsernameEnv,
-				PasswordEnv: cfg.Sources.NOBB.PasswordEnv,
-			})
-			if err := conn.Connect(ctx); err != nil {
-				color.Yellow("  Warning: Could not connect to NOBB: %v", err)
-				continue
-			}
-			enhancers[src] = conn
-		default:
-			color.Yellow("  Warning: Unknown source: %s", src)
+		conn, err := newEnhancer(ctx, cfg, src, enhanceRefresh)
+		if err != nil {
+			color.Yellow("  Warning: %v", err)
+			continue
 		}
+		enhancers[src] = conn
 	}
 
 	if len(enhancers) == 0 {
@@ -152,6 +240,26 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no enhancement sources available")
 	}
 
+	// Skip products every source enhanced within the freshness window, so an
+	// interrupted run picks up where it stopped
+	var freshSince time.Time
+	skippedFresh := 0
+	if freshWindow > 0 {
+		freshSince = time.Now().Add(-freshWindow)
+		pending := make([]*models.EnhancedProduct, 0, len(products))
+		for _, p := range products {
+			if enhancedByAll(p, enhancers, freshSince) {
+				skippedFresh++
+				continue
+			}
+			pending = append(pending, p)
+		}
+		products = pending
+
+		color.Yellow("  Skipping %d products enhanced within %s\n", skippedFresh, enhanceSkipFresh)
+		fmt.Println()
+	}
+
 	// Progress bar
 	bar := progressbar.NewOptions(len(products),
 		progressbar.OptionSetDescription("  Enhancing products"),
@@ -165,41 +273,83 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 		progressbar.OptionShowCount(),
 	)
 
-	// Enhance each product
-	results := make([]struct {
-		sku      string
-		source   string
-		status   string
-		details  string
-	}, 0)
+	// Enhance products on a worker pool. Each source rate-limits its own
+	// requests, so workers only overlap the waiting.
+	var mu sync.Mutex
+	rowsBySKU := make(map[string][]enhanceRow, len(products))
+	var logEntries []*database.EnhancementLog
 
 	enhanced := 0
 	imagesAdded := 0
 	fieldsAdded := 0
 
-	for _, p := range products {
-		bar.Add(1)
+	// Workers hold saveMu for reading while they modify a product, so a
+	// periodic save waits for products in flight and never writes one half done
+	var saveMu sync.RWMutex
+	var saveErr error
+	completed := 0
+
+	// The journal records what each product looked like before the run, so
+	// 'enhance rollback' can undo it. PostgreSQL doesn't keep the journal and
+	// rollback needs the state file, so DB-backed runs aren't journaled.
+	journal := state.NewJournalEntry(enhanceSources)
+	_, dbBacked := store.(*dbStateStore)
+	journaling := !enhanceDryRun && !dbBacked
+
+	enhanceProduct := func(ctx context.Context, p *models.EnhancedProduct) []enhanceRow {
+		saveMu.RLock()
+		defer saveMu.RUnlock()
+
+		var before *models.EnhancedProduct
+		if journaling {
+			before = p.Clone()
+		}
+
+		rows := make([]enhanceRow, 0, len(enhancers))
 
 		for srcName, enhancer := range enhancers {
+			if !freshSince.IsZero() && p.EnhancedSince(srcName, freshSince) {
+				rows = append(rows, enhanceRow{p.SKU, srcName, "fresh", "enhanced within " + enhanceSkipFresh})
+				continue
+			}
+
 			if enhanceDryRun {
-				results = append(results, struct {
-					sku, source, status, details string
-				}{p.SKU, srcName, "dry-run", "Would enhance"})
+				details := "Would enhance"
+				if previewer, ok := enhancer.(source.Previewer); ok {
+					preview, err := previewer.PreviewEnhancement(ctx, p)
+					switch {
+					case err != nil:
+						details = err.Error()
+					case !preview.Success && preview.Error != nil:
+						details = truncate(preview.Error.Error(), 30)
+					default:
+						details = describePreview(preview)
+					}
+				}
+				rows = append(rows, enhanceRow{p.SKU, srcName, "dry-run", details})
 				continue
 			}
 
 			result, err := enhancer.EnhanceProduct(ctx, p)
+			if entry := enhancementFailure(p.SKU, srcName, result, err); entry != nil {
+				mu.Lock()
+				logEntries = append(logEntries, entry)
+				mu.Unlock()
+			}
 			if err != nil {
-				results = append(results, struct {
-					sku, source, status, details string
-				}{p.SKU, srcName, "error", err.Error()})
+				slog.Error("enhancement failed", "sku", p.SKU, "source", srcName, "error", err)
+				metrics.Enhancement(srcName, metrics.ResultError)
+				rows = append(rows, enhanceRow{p.SKU, srcName, "error", err.Error()})
 				continue
 			}
 
 			if result.Success {
+				metrics.Enhancement(srcName, metrics.ResultSuccess)
+				mu.Lock()
 				enhanced++
 				imagesAdded += result.ImagesAdded
 				fieldsAdded += len(result.FieldsUpdated)
+				mu.Unlock()
 
 				details := ""
 				if result.ImagesAdded > 0 {
@@ -215,24 +365,90 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 					details = "no changes"
 				}
 
-				results = append(results, struct {
-					sku, source, status, details string
-				}{p.SKU, srcName, "ok", details})
+				rows = append(rows, enhanceRow{p.SKU, srcName, "ok", details})
 			} else {
 				errMsg := "failed"
 				if result.Error != nil {
 					errMsg = result.Error.Error()
 				}
-				results = append(results, struct {
-					sku, source, status, details string
-				}{p.SKU, srcName, "failed", truncate(errMsg, 30)})
+				slog.Debug("product not enhanced", "sku", p.SKU, "source", srcName, "reason", errMsg)
+				metrics.Enhancement(srcName, metrics.ResultFailure)
+				rows = append(rows, enhanceRow{p.SKU, srcName, "failed", truncate(errMsg, 30)})
+			}
+		}
+
+		if before != nil {
+			if snap, err := state.Snapshot(before, p); err == nil && snap != nil {
+				mu.Lock()
+				journal.Products = append(journal.Products, *snap)
+				mu.Unlock()
+			}
+		}
+
+		return rows
+	}
+
+	start := time.Now()
+	poolErr := orchestrator.EnhanceConcurrent(ctx, products, enhanceConcurrency, func(ctx context.Context, p *models.EnhancedProduct) {
+		// A product that was started is finished even if the run is
+		// interrupted, so it is never saved half enhanced
+		rows := enhanceProduct(context.WithoutCancel(ctx), p)
+		// Counted as each product finishes, so a scrape during a long run
+		// shows its progress
+		metrics.ProductsProcessed("enhance", 1)
+
+		mu.Lock()
+		rowsBySKU[p.SKU] = rows
+		bar.Add(1)
+		completed++
+		checkpoint := !enhanceDryRun && enhanceSaveEvery > 0 && completed%enhanceSaveEvery == 0
+		mu.Unlock()
+
+		// Persist progress so a crash doesn't lose the products done so far
+		if checkpoint {
+			saveMu.Lock()
+			store.RecordJournal(*journal, cfg.Defaults.JournalRuns)
+			err := store.Save()
+			saveMu.Unlock()
+			if err != nil {
+				mu.Lock()
+				saveErr = err
+				mu.Unlock()
 			}
 		}
+	})
+
+	// Closing writes the lookups the sources cached during the run
+	for srcName, enhancer := range enhancers {
+		if err := enhancer.Close(); err != nil {
+			color.Yellow("  Warning: Could not close %s: %v", srcName, err)
+		}
+	}
+
+	// List results in product order regardless of which worker finished first
+	results := make([]enhanceRow, 0, len(rowsBySKU))
+	for _, p := range products {
+		results = append(results, rowsBySKU[p.SKU]...)
 	}
 
 	fmt.Println()
 	fmt.Println()
 
+	interrupted := cmd.Context().Err() != nil
+	if poolErr != nil {
+		if interrupted {
+			color.Yellow("  Interrupted, stopping after the products in flight")
+		} else {
+			color.Yellow("  Warning: Enhancement stopped early: %v", poolErr)
+		}
+		color.Yellow("  Processed %d of %d products\n", len(rowsBySKU), len(products))
+		fmt.Println()
+	}
+	if saveErr != nil {
+		color.Yellow("  Warning: Could not save progress during the run: %v", saveErr)
+		fmt.Println()
+	}
+
 	// Show results table
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader([]string{"SKU", "Source", "Status", "Details"})
@@ -255,7 +471,7 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 		statusColor := color.GreenString(r.status)
 		if r.status == "error" || r.status == "failed" {
 			statusColor = color.RedString(r.status)
-		} else if r.status == "dry-run" {
+		} else if r.status == "dry-run" || r.status == "fresh" {
 			statusColor = color.YellowString(r.status)
 		}
 		table.Append([]string{r.sku, r.source, statusColor, r.details})
@@ -268,9 +484,29 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 	table.Render()
 	fmt.Println()
 
+	slog.Info("enhance run finished", "sources", enhanceSources, "products", len(products),
+		"enhanced", enhanced, "skipped_fresh", skippedFresh, "images_added", imagesAdded,
+		"fields_updated", fieldsAdded, "dry_run", enhanceDryRun, "duration", time.Since(start))
+	if poolErr == nil && !enhanceDryRun {
+		metrics.Success("enhance")
+	}
+
+	// Source statistics
+	used := make([]source.Connector, 0, len(enhancers))
+	for _, src := range enhanceSources {
+		if conn, ok := enhancers[src]; ok {
+			used = append(used, conn)
+		}
+	}
+	renderConnectorStats(used, nil)
+
 	// Summary
+	var persistErr error
 	if !enhanceDryRun {
 		success.Printf("  ✓ Enhanced %d products\n", enhanced)
+		if skippedFresh > 0 {
+			success.Printf("  ✓ Skipped %d fresh products\n", skippedFresh)
+		}
 		if imagesAdded > 0 {
 			success.Printf("  ✓ Added %d images\n", imagesAdded)
 		}
@@ -278,22 +514,342 @@ func runEnhance(cmd *cobra.Command, args []string) error {
 			success.Printf("  ✓ Updated %d fields\n", fieldsAdded)
 		}
 
+		// Persist before saving state so the database IDs the products get
+		// are saved with them
+		if persister != nil {
+			done := make([]*models.EnhancedProduct, 0, len(rowsBySKU))
+			for _, p := range products {
+				if _, ok := rowsBySKU[p.SKU]; ok {
+					done = append(done, p)
+				}
+			}
+			persistCtx, persistCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Minute)
+			saved, err := orchestrator.PersistEnhanced(persistCtx, persister, done, slog.Default())
+			persistCancel()
+			if err != nil {
+				color.Red("  Error: %v", err)
+				persistErr = err
+			}
+			if saved > 0 {
+				success.Printf("  ✓ Saved %d products to PostgreSQL\n", saved)
+			}
+		}
+
 		// Save state
 		store.AddHistory("enhance", strings.Join(enhanceSources, ","), enhanced,
-			fmt.Sprintf("Enhanced %d products with %d images", enhanced, imagesAdded))
+			fmt.Sprintf("Enhanced %d products with %d images, skipped %d fresh", enhanced, imagesAdded, skippedFresh))
+		if len(journal.Products) > 0 {
+			store.RecordJournal(*journal, cfg.Defaults.JournalRuns)
+		}
 		if err := store.Save(); err != nil {
 			color.Red("  Warning: Could not save state: %v", err)
 		} else {
 			success.Println("  ✓ State saved")
+			if len(journal.Products) > 0 {
+				success.Printf("  ✓ Run %s journaled (undo with 'badops enhance rollback')\n", journal.ID)
+			}
+			if interrupted {
+				color.Yellow("  Interrupted, saved %d products", completed)
+			}
+		}
+
+		// Failures are logged even when the run was interrupted; successes were
+		// logged by store.Save
+		if cfg.Database.UseDB && len(logEntries) > 0 {
+			logCtx, logCancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+			logged, err := writeEnhancementLogs(logCtx, logEntries)
+			logCancel()
+			if err != nil {
+				color.Yellow("  Warning: Could not write the enhancement log: %v", err)
+			} else {
+				success.Printf("  ✓ Logged %d failed enhancements\n", logged)
+			}
 		}
 	} else {
 		color.Yellow("  Dry run complete. No changes made.")
 	}
 	fmt.Println()
 
+	if persistErr != nil {
+		return persistErr
+	}
+	if interrupted {
+		return errInterrupted
+	}
+	return nil
+}
+
+// enhancePersister connects to PostgreSQL for --persist and returns the repo
+// that saves enhanced products and a func that closes the connection
+func enhancePersister(ctx context.Context, cfg *config.Config) (orchestrator.EnhancedPersister, func(), error) {
+	if cfg.Database.UseDB {
+		return nil, nil, fmt.Errorf("--persist is for the JSON state; with database.use_db products are already saved to PostgreSQL")
+	}
+	client, err := getDBClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return postgres.NewProductRepo(client), client.Close, nil
+}
+
+func runEnhanceLog(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	if enhanceLogSKU == "" && enhanceLogSource == "" {
+		return fmt.Errorf("specify --sku or --source")
+	}
+	if enhanceLogLimit <= 0 {
+		return fmt.Errorf("--limit must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	logRepo := postgres.NewEnhancementLogRepo(client)
+	var entries []*database.EnhancementLog
+	if enhanceLogSKU != "" {
+		product, err := postgres.NewProductRepo(client).GetBySKU(ctx, enhanceLogSKU)
+		if err != nil {
+			return err
+		}
+		if product == nil {
+			return fmt.Errorf("product %s not found in the database", enhanceLogSKU)
+		}
+		id, err := uuid.Parse(product.ID)
+		if err != nil {
+			return fmt.Errorf("invalid product ID %q", product.ID)
+		}
+		all, err := logRepo.GetByProduct(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, e := range all {
+			if enhanceLogSource == "" || e.Source == enhanceLogSource {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) > enhanceLogLimit {
+			entries = entries[:enhanceLogLimit]
+		}
+	} else {
+		entries, err = logRepo.GetBySource(ctx, enhanceLogSource, enhanceLogLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	var scope []string
+	if enhanceLogSKU != "" {
+		scope = append(scope, enhanceLogSKU)
+	}
+	if enhanceLogSource != "" {
+		scope = append(scope, enhanceLogSource)
+	}
+	header.Printf("\n  ENHANCEMENT LOG (%s)\n", strings.Join(scope, ", "))
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	if len(entries) == 0 {
+		color.Yellow("  No enhancements logged")
+		fmt.Println()
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Time", "SKU", "Source", "Action", "Fields", "Result"})
+	table.SetBorder(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	failed := 0
+	for _, e := range entries {
+		result := color.GreenString("ok")
+		if !e.Success {
+			failed++
+			msg := "failed"
+			if e.Error != "" {
+				msg = truncate(e.Error, 40)
+			}
+			result = color.RedString(msg)
+		}
+		table.Append([]string{
+			e.CreatedAt.Local().Format("2006-01-02 15:04"),
+			e.ProductSKU,
+			e.Source,
+			e.Action,
+			truncate(strings.Join(e.FieldsAdded, ", "), 30),
+			result,
+		})
+	}
+	table.Render()
+	fmt.Println()
+
+	fmt.Printf("  %d entries, %d failed\n\n", len(entries), failed)
 	return nil
 }
 
+// enhancementFailure returns the log entry for a failed enhancement, or nil
+// when the source succeeded. Successful ones are recorded in
+// product.Enhancements and logged when the store saves the product, as in
+// the orchestrator's appendFailure. The product ID is resolved from the SKU
+// when the log is written.
+func enhancementFailure(sku, srcName string, result *source.EnhancementResult, err error) *database.EnhancementLog {
+	if err == nil && result.Success {
+		return nil
+	}
+
+	entry := &database.EnhancementLog{
+		ProductSKU: sku,
+		Source:     srcName,
+		Action:     "enhance_failed",
+		CreatedAt:  time.Now(),
+	}
+	switch {
+	case err != nil:
+		entry.Error = err.Error()
+	case result.Error != nil:
+		entry.Error = result.Error.Error()
+	}
+	return entry
+}
+
+// writeEnhancementLogs adds failure entries to the PostgreSQL enhancement log.
+// Products not in the database are skipped. Returns the number written.
+func writeEnhancementLogs(ctx context.Context, entries []*database.EnhancementLog) (int, error) {
+	client, err := getDBClient()
+	if err != nil {
+		return 0, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return 0, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	products, err := postgres.NewProductRepo(client).GetAll(ctx, database.QueryOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get products: %w", err)
+	}
+	productIDs := make(map[string]uuid.UUID, len(products))
+	for _, p := range products {
+		if id, err := uuid.Parse(p.ID); err == nil {
+			productIDs[p.SKU] = id
+		}
+	}
+
+	logRepo := postgres.NewEnhancementLogRepo(client)
+	written := 0
+	for _, entry := range entries {
+		id, ok := productIDs[entry.ProductSKU]
+		if !ok {
+			continue
+		}
+		entry.ProductID = id
+		if err := logRepo.Add(ctx, entry); err != nil {
+			return written, err
+		}
+		written++
+	}
+
+	return written, nil
+}
+
+// newEnhancer creates and connects the enhancement connector for a source name.
+// forceRefresh makes it ignore cached lookups for this run.
+func newEnhancer(ctx context.Context, cfg *config.Config, name string, forceRefresh bool) (source.Connector, error) {
+	switch name {
+	case "tiger_nl":
+		conn := tiger.NewConnector(tiger.Config{
+			RateLimitMs:      cfg.Sources.TigerNL.RateLimitMs,
+			MappingsFile:     cfg.Sources.TigerNL.MappingsFile,
+			SKURulesFile:     cfg.Defaults.SKURulesFile,
+			CacheTTL:         cfg.Sources.TigerNL.CacheDuration(),
+			NegativeCacheTTL: cfg.Sources.TigerNL.NegativeCacheDuration(),
+			UserAgent:        cfg.Sources.TigerNL.UserAgent,
+			AcceptLanguage:   cfg.Sources.TigerNL.AcceptLanguage,
+			Cache:            tigerMatchCache(ctx, cfg),
+			ForceRefresh:     forceRefresh,
+			Merge:            source.NewMergePolicy(cfg.Defaults.SourcePriority, cfg.Defaults.OverwritePolicy),
+		})
+		if err := conn.Connect(ctx); err != nil {
+			return nil, fmt.Errorf("could not connect to Tiger.nl: %w", err)
+		}
+		return conn, nil
+	case "nobb":
+		conn := nobb.NewConnector(nobb.Config{
+			UsernameEnv:   cfg.Sources.NOBB.UsernameEnv,
+			PasswordEnv:   cfg.Sources.NOBB.PasswordEnv,
+			DimensionUnit: cfg.Defaults.DimensionUnit,
+			WeightUnit:    cfg.Defaults.WeightUnit,
+			RateLimitMs:   cfg.Sources.NOBB.RateLimitMs,
+			Merge:         source.NewMergePolicy(cfg.Defaults.SourcePriority, cfg.Defaults.OverwritePolicy),
+			SKURulesFile:  cfg.Defaults.SKURulesFile,
+			ForceRefresh:  forceRefresh,
+		})
+		if err := conn.Connect(ctx); err != nil {
+			return nil, fmt.Errorf("could not connect to NOBB: %w", err)
+		}
+		return conn, nil
+	default:
+		return nil, fmt.Errorf("unknown source: %s", name)
+	}
+}
+
+// parseFreshWindow parses a --skip-fresh value: a number of days such as "7d",
+// or any Go duration such as "12h"
+func parseFreshWindow(value string) (time.Duration, error) {
+	d, err := config.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --skip-fresh value %q (use e.g. 7d or 12h)", value)
+	}
+	return d, nil
+}
+
+// enhancedByAll reports whether every source enhanced the product since t
+func enhancedByAll(p *models.EnhancedProduct, enhancers map[string]source.Connector, t time.Time) bool {
+	for srcName := range enhancers {
+		if !p.EnhancedSince(srcName, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// describePreview summarizes a dry-run result, e.g. "would add: dimensions, weight, 3 images"
+func describePreview(preview *source.EnhancementResult) string {
+	changes := make([]string, 0, len(preview.FieldsUpdated)+1)
+	for _, field := range preview.FieldsUpdated {
+		if field == "images" {
+			continue
+		}
+		changes = append(changes, field)
+	}
+	if preview.ImagesAdded > 0 {
+		changes = append(changes, fmt.Sprintf("%d images", preview.ImagesAdded))
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return "would add: " + strings.Join(changes, ", ")
+}
+
 func runEnhanceReview(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 
@@ -302,11 +858,12 @@ func runEnhanceReview(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Load state
-	store := state.NewStore("")
-	if err := store.Load(); err != nil {
+	store, err := openStore(cmd.Context(), false)
+	if err != nil {
 		color.Red("  Error loading state: %v", err)
 		return err
 	}
+	defer store.Close()
 
 	// Get products with enhancements
 	products := store.GetAllProducts()
@@ -389,11 +946,12 @@ func runEnhanceApply(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	// Load state
-	store := state.NewStore("")
-	if err := store.Load(); err != nil {
+	store, err := openStore(cmd.Context(), true)
+	if err != nil {
 		color.Red("  Error loading state: %v", err)
 		return err
 	}
+	defer store.Close()
 
 	// Get products with pending enhancements
 	products := store.GetAllProducts()
@@ -427,3 +985,282 @@ func runEnhanceApply(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runEnhanceDiff(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	sku := args[0]
+
+	store, err := openStore(cmd.Context(), false)
+	if err != nil {
+		color.Red("  Error loading state: %v", err)
+		return err
+	}
+	defer store.Close()
+
+	product, ok := store.GetProduct(sku)
+	if !ok {
+		return fmt.Errorf("product not found: %s", sku)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		color.Yellow("  Warning: Could not load config, using defaults")
+		cfg = config.DefaultConfig()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	enhancer, err := newEnhancer(ctx, cfg, enhanceDiffSource, false)
+	if err != nil {
+		return err
+	}
+	defer enhancer.Close()
+
+	header.Println("\n  ENHANCEMENT DIFF")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Printf("  SKU:    %s\n", product.SKU)
+	fmt.Printf("  Title:  %s\n", product.Title)
+	fmt.Printf("  Source: %s\n", enhanceDiffSource)
+	fmt.Println()
+
+	// Enhance a copy so the stored product is untouched
+	after := product.Clone()
+	result, err := enhancer.EnhanceProduct(ctx, after)
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		errMsg := "enhancement failed"
+		if result.Error != nil {
+			errMsg = result.Error.Error()
+		}
+		color.Red("  ✗ %s", errMsg)
+		fmt.Println()
+		return nil
+	}
+
+	changes := diffProducts(product, after)
+	if len(changes) == 0 {
+		color.Yellow("  No changes")
+		fmt.Println()
+		return nil
+	}
+
+	added, changed, removed := 0, 0, 0
+	for _, c := range changes {
+		switch {
+		case c.old == "":
+			added++
+			color.Green("  + %-14s %s", c.field, c.new)
+		case c.new == "":
+			removed++
+			color.Red("  - %-14s %s", c.field, c.old)
+		default:
+			changed++
+			color.Yellow("  ~ %-14s %s → %s", c.field, c.old, c.new)
+		}
+	}
+	fmt.Println()
+
+	// The enhancement record notes any values the overwrite policy replaced
+	if n := len(after.Enhancements); n > len(product.Enhancements) {
+		fmt.Printf("  %s\n", after.Enhancements[n-1].Details)
+	}
+	fmt.Printf("  %d changes: %d added, %d changed, %d removed\n", len(changes), added, changed, removed)
+	fmt.Println()
+
+	return nil
+}
+
+func runEnhanceRollback(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	// The journal is kept in the state file only
+	if err := requireJSONState("enhance rollback"); err != nil {
+		return err
+	}
+	store := state.NewStore("")
+	load := store.LoadForUpdate
+	if enhanceRollbackLs {
+		load = store.Load
+	}
+	if err := load(); err != nil {
+		color.Red("  Error loading state: %v", err)
+		return err
+	}
+	defer store.Close()
+
+	if enhanceRollbackLs {
+		header.Println("\n  ENHANCE RUNS")
+		fmt.Println("  " + strings.Repeat("─", 50))
+
+		runs := store.GetJournal()
+		if len(runs) == 0 {
+			color.Yellow("  No enhance runs to roll back")
+			fmt.Println()
+			return nil
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Run", "Started", "Sources", "Products"})
+		table.SetBorder(false)
+		for i := len(runs) - 1; i >= 0; i-- {
+			r := runs[i]
+			table.Append([]string{
+				r.ID,
+				r.Timestamp.Format("2006-01-02 15:04"),
+				strings.Join(r.Sources, ", "),
+				fmt.Sprintf("%d", len(r.Products)),
+			})
+		}
+		table.Render()
+		fmt.Println()
+		return nil
+	}
+
+	header.Println("\n  ROLLING BACK ENHANCE RUN")
+	fmt.Println("  " + strings.Repeat("─", 50))
+
+	entry, restored, err := store.Rollback(enhanceRollbackRun)
+	if err != nil {
+		return err
+	}
+
+	store.AddHistory("rollback", strings.Join(entry.Sources, ","), restored,
+		fmt.Sprintf("Rolled back enhance run %s", entry.ID))
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	success.Printf("  ✓ Rolled back run %s (%s)\n", entry.ID, strings.Join(entry.Sources, ", "))
+	success.Printf("  ✓ Restored %d products\n", restored)
+	if skipped := len(entry.Products) - restored; skipped > 0 {
+		color.Yellow("  Skipped %d products no longer in state", skipped)
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// fieldChange is one line of an enhance diff. old is empty for additions
+// and new is empty for removals.
+type fieldChange struct {
+	field string
+	old   string
+	new   string
+}
+
+// diffProducts lists the differences between a product before and after an
+// enhancement. Images, properties and suppliers are listed per item added or
+// removed; specifications per key.
+func diffProducts(before, after *models.EnhancedProduct) []fieldChange {
+	var changes []fieldChange
+	add := func(field, old, new string) {
+		if old != new {
+			changes = append(changes, fieldChange{field, truncate(old, 60), truncate(new, 60)})
+		}
+	}
+
+	add("nobb_number", before.NOBBNumber, after.NOBBNumber)
+	add("barcode", before.Barcode, after.Barcode)
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("vendor", before.Vendor, after.Vendor)
+	add("product_type", before.ProductType, after.ProductType)
+	add("tags", strings.Join(before.Tags, ", "), strings.Join(after.Tags, ", "))
+	add("dimensions", formatDimensions(before.Dimensions), formatDimensions(after.Dimensions))
+	add("weight", formatWeight(before.Weight), formatWeight(after.Weight))
+
+	keys := make(map[string]bool)
+	for k := range before.Specifications {
+		keys[k] = true
+	}
+	for k := range after.Specifications {
+		keys[k] = true
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	for _, k := range sortedKeys {
+		add("spec "+k, before.Specifications[k], after.Specifications[k])
+	}
+
+	changes = append(changes, diffItems("image", imageItems(before), imageItems(after))...)
+	changes = append(changes, diffItems("property", propertyItems(before), propertyItems(after))...)
+	changes = append(changes, diffItems("supplier", supplierItems(before), supplierItems(after))...)
+
+	return changes
+}
+
+// diffItems lists the items added to or removed from a list
+func diffItems(field string, before, after []string) []fieldChange {
+	inBefore := make(map[string]bool, len(before))
+	for _, item := range before {
+		inBefore[item] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, item := range after {
+		inAfter[item] = true
+	}
+
+	var changes []fieldChange
+	for _, item := range before {
+		if !inAfter[item] {
+			changes = append(changes, fieldChange{field: field, old: item})
+		}
+	}
+	for _, item := range after {
+		if !inBefore[item] {
+			changes = append(changes, fieldChange{field: field, new: item})
+		}
+	}
+	return changes
+}
+
+func imageItems(p *models.EnhancedProduct) []string {
+	items := make([]string, 0, len(p.Images))
+	for _, img := range p.Images {
+		items = append(items, img.SourceURL)
+	}
+	return items
+}
+
+func propertyItems(p *models.EnhancedProduct) []string {
+	items := make([]string, 0, len(p.Properties))
+	for _, prop := range p.Properties {
+		item := fmt.Sprintf("%s: %s", prop.Name, prop.Value)
+		if prop.Unit != "" {
+			item += " " + prop.Unit
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func supplierItems(p *models.EnhancedProduct) []string {
+	items := make([]string, 0, len(p.Suppliers))
+	for _, sup := range p.Suppliers {
+		items = append(items, fmt.Sprintf("%s (%s)", sup.Name, sup.ID))
+	}
+	return items
+}
+
+// formatDimensions formats dimensions as "L×W×H unit", or "" when unset
+func formatDimensions(d *models.Dimensions) string {
+	if d == nil {
+		return ""
+	}
+	return fmt.Sprintf("%g×%g×%g %s", d.Length, d.Width, d.Height, d.Unit)
+}
+
+// formatWeight formats a weight as "value unit", or "" when unset
+func formatWeight(w *models.Weight) string {
+	if w == nil {
+		return ""
+	}
+	return fmt.Sprintf("%g %s", w.Value, w.Unit)
+}
@@ -8,9 +8,11 @@ import (
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/metrics"
 	"github.com/badno/badops/internal/output"
+	clickhouseout "github.com/badno/badops/internal/output/clickhouse"
 	"github.com/badno/badops/internal/output/file"
-	"github.com/badno/badops/internal/state"
+	shopifyout "github.com/badno/badops/internal/output/shopify"
 	"github.com/badno/badops/pkg/models"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
@@ -24,12 +26,17 @@ var (
 	exportOnlyEnhanced bool
 	exportDryRun      bool
 	exportIncludeImages bool
+	exportImageRowsOnly bool
+	exportColumnMap     string
+	exportStatus        string
+	exportVendor        string
+	exportSKUs          []string
 )
 
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "Export products to various destinations",
-	Long:  `Export enhanced products to CSV, JSON, Shopify, or ClickHouse.`,
+	Long:  `Export enhanced products to CSV, JSON, a Google Merchant feed, Shopify, or ClickHouse.`,
 }
 
 var exportRunCmd = &cobra.Command{
@@ -47,12 +54,17 @@ var exportListCmd = &cobra.Command{
 }
 
 func init() {
-	exportRunCmd.Flags().StringVar(&exportDest, "dest", "csv", "Export destination (csv, json, shopify, clickhouse)")
-	exportRunCmd.Flags().StringVar(&exportFormat, "format", "matrixify", "Output format (matrixify, shopify, json, jsonl)")
+	exportRunCmd.Flags().StringVar(&exportDest, "dest", "csv", "Export destination (csv, json, google, shopify, clickhouse)")
+	exportRunCmd.Flags().StringVar(&exportFormat, "format", "matrixify", "Output format (matrixify, shopify, metafields, json, jsonl, google)")
 	exportRunCmd.Flags().StringVarP(&exportOutputPath, "output", "o", "", "Output file path (for file exports)")
 	exportRunCmd.Flags().BoolVar(&exportOnlyEnhanced, "enhanced-only", false, "Only export enhanced products")
 	exportRunCmd.Flags().BoolVar(&exportDryRun, "dry-run", false, "Preview without exporting")
 	exportRunCmd.Flags().BoolVar(&exportIncludeImages, "images", true, "Include image URLs in export")
+	exportRunCmd.Flags().BoolVar(&exportImageRowsOnly, "image-rows", false, "Matrixify: put every image on its own row")
+	exportRunCmd.Flags().StringVar(&exportStatus, "status", "", "Only export products with this status (e.g. approved, enhanced)")
+	exportRunCmd.Flags().StringVar(&exportVendor, "vendor", "", "Only export products from this vendor (case-insensitive)")
+	exportRunCmd.Flags().StringSliceVar(&exportSKUs, "sku", nil, "Only export these SKUs (repeatable)")
+	exportRunCmd.Flags().StringVar(&exportColumnMap, "column-map", "", "YAML column map for Matrixify CSV (default: outputs.file.column_map_file)")
 
 	exportCmd.AddCommand(exportRunCmd)
 	exportCmd.AddCommand(exportListCmd)
@@ -62,16 +74,25 @@ func runExport(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 	success := color.New(color.FgGreen)
 
+	status := models.ProductStatus(strings.ToLower(exportStatus))
+	switch status {
+	case "", models.StatusPending, models.StatusProcessing, models.StatusEnhanced, models.StatusReview,
+		models.StatusApproved, models.StatusExported, models.StatusFailed, models.StatusArchived:
+	default:
+		return fmt.Errorf("invalid status %q (use pending, processing, enhanced, review, approved, exported, failed, or archived)", exportStatus)
+	}
+
 	header.Println("\n  EXPORTING PRODUCTS")
 	fmt.Println("  " + strings.Repeat("─", 50))
 	fmt.Println()
 
 	// Load state
-	store := state.NewStore("")
-	if err := store.Load(); err != nil {
+	store, err := openStore(cmd.Context(), !exportDryRun)
+	if err != nil {
 		color.Red("  Error loading state: %v", err)
 		return err
 	}
+	defer store.Close()
 
 	// Get products
 	products := store.GetAllProducts()
@@ -86,9 +107,31 @@ func runExport(cmd *cobra.Command, args []string) error {
 		productValues = append(productValues, *p)
 	}
 
+	// Non-CSV file formats pick their adapter unless a destination was given
+	format := output.Format(exportFormat)
+	if !cmd.Flags().Changed("dest") {
+		switch format {
+		case output.FormatJSON, output.FormatJSONL:
+			exportDest = "json"
+		case output.FormatGoogleMerchant:
+			exportDest = "google"
+		}
+	}
+	if !cmd.Flags().Changed("format") {
+		switch exportDest {
+		case "json":
+			format = output.FormatJSON
+		case "google":
+			format = output.FormatGoogleMerchant
+		}
+	}
+
 	color.Yellow("  Found %d products\n", len(productValues))
 	color.Yellow("  Destination: %s\n", exportDest)
-	color.Yellow("  Format: %s\n", exportFormat)
+	color.Yellow("  Format: %s\n", format)
+	if filters := exportFilterSummary(status); filters != "" {
+		color.Yellow("  Filters: %s\n", filters)
+	}
 	if exportDryRun {
 		color.Yellow("  Mode: DRY RUN\n")
 	}
@@ -108,19 +151,55 @@ func runExport(cmd *cobra.Command, args []string) error {
 	var adapter output.Adapter
 	switch exportDest {
 	case "csv":
+		columnMap := exportColumnMap
+		if columnMap == "" {
+			columnMap = cfg.Outputs.File.ColumnMapFile
+		}
 		adapter = file.NewCSVAdapter(file.CSVConfig{
-			OutputDir: cfg.Outputs.File.OutputDir,
+			OutputDir:     cfg.Outputs.File.OutputDir,
+			ColumnMapFile: columnMap,
 		})
 	case "json":
 		adapter = file.NewJSONAdapter(file.JSONConfig{
 			OutputDir: cfg.Outputs.File.OutputDir,
 			Pretty:    cfg.Outputs.File.Pretty,
 		})
+	case "google":
+		storeURL := cfg.Outputs.File.StoreURL
+		if storeURL == "" && cfg.Outputs.Shopify.Store != "" {
+			storeURL = fmt.Sprintf("https://%s.myshopify.com", cfg.Outputs.Shopify.Store)
+		}
+		adapter = file.NewGoogleAdapter(file.GoogleConfig{
+			OutputDir: cfg.Outputs.File.OutputDir,
+			StoreURL:  storeURL,
+			Title:     cfg.Outputs.Shopify.Store,
+		})
+	case "shopify":
+		adapter = shopifyout.NewAdapter(shopifyout.Config{
+			Store:     cfg.Outputs.Shopify.Store,
+			APIKeyEnv: cfg.Outputs.Shopify.APIKeyEnv,
+		})
+	case "clickhouse":
+		adapter = clickhouseout.NewAdapter(clickhouseout.Config{
+			Host:        cfg.Outputs.ClickHouse.Host,
+			Port:        cfg.Outputs.ClickHouse.Port,
+			Database:    cfg.Outputs.ClickHouse.Database,
+			UsernameEnv: cfg.Outputs.ClickHouse.UsernameEnv,
+			PasswordEnv: cfg.Outputs.ClickHouse.PasswordEnv,
+			Table:       cfg.Outputs.ClickHouse.Table,
+			Secure:      cfg.Outputs.ClickHouse.Secure,
+		})
 	default:
 		color.Red("  Error: Unsupported destination: %s", exportDest)
 		return fmt.Errorf("unsupported destination: %s", exportDest)
 	}
 
+	// File adapters write a fixed set of formats
+	if (exportDest == "csv" || exportDest == "json" || exportDest == "google") && !adapter.SupportsFormat(format) {
+		color.Red("  Error: %s export does not support format %s", exportDest, format)
+		return fmt.Errorf("unsupported format for %s: %s", exportDest, format)
+	}
+
 	// Connect
 	if err := adapter.Connect(ctx); err != nil {
 		color.Red("  Error connecting to destination: %v", err)
@@ -130,11 +209,15 @@ func runExport(cmd *cobra.Command, args []string) error {
 
 	// Build export options
 	opts := output.ExportOptions{
-		Format:        output.Format(exportFormat),
+		Format:        format,
 		OutputPath:    exportOutputPath,
 		IncludeImages: exportIncludeImages,
 		OnlyEnhanced:  exportOnlyEnhanced,
+		SKUs:          exportSKUs,
+		Status:        status,
+		Vendor:        exportVendor,
 		DryRun:        exportDryRun,
+		ImageRowsOnly: exportImageRowsOnly,
 	}
 
 	// Export
@@ -147,6 +230,9 @@ func runExport(cmd *cobra.Command, args []string) error {
 	// Show result
 	if result.Success {
 		success.Printf("  ✓ Exported %d products\n", result.ProductsExported)
+		if result.ProductsCreated > 0 || result.ProductsUpdated > 0 {
+			success.Printf("  ✓ Created %d, updated %d\n", result.ProductsCreated, result.ProductsUpdated)
+		}
 		if result.ImagesExported > 0 {
 			success.Printf("  ✓ Exported %d images\n", result.ImagesExported)
 		}
@@ -154,9 +240,14 @@ func runExport(cmd *cobra.Command, args []string) error {
 			success.Printf("  ✓ Output: %s\n", result.Destination)
 		}
 		success.Printf("  ✓ %s\n", result.Details)
+		if result.ProductsInvalid > 0 {
+			color.Yellow("  Skipped %d invalid products (run 'badops products validate' for details)", result.ProductsInvalid)
+		}
 
 		// Update history
 		if !exportDryRun {
+			metrics.ProductsProcessed("export", result.ProductsExported)
+			metrics.Success("export")
 			store.AddHistory("export", exportDest, result.ProductsExported,
 				fmt.Sprintf("Exported to %s", result.Destination))
 			store.Save()
@@ -169,6 +260,25 @@ func runExport(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// exportFilterSummary describes the active product filters, or returns ""
+// when every product is exported
+func exportFilterSummary(status models.ProductStatus) string {
+	var parts []string
+	if exportOnlyEnhanced {
+		parts = append(parts, "enhanced only")
+	}
+	if status != "" {
+		parts = append(parts, "status="+string(status))
+	}
+	if exportVendor != "" {
+		parts = append(parts, "vendor="+exportVendor)
+	}
+	if len(exportSKUs) > 0 {
+		parts = append(parts, fmt.Sprintf("%d SKUs", len(exportSKUs)))
+	}
+	return strings.Join(parts, ", ")
+}
+
 func runExportList(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 
@@ -190,10 +300,11 @@ func runExportList(cmd *cobra.Command, args []string) error {
 		formats string
 		desc    string
 	}{
-		{"csv", "matrixify, shopify", "CSV file export (Matrixify/Shopify format)"},
+		{"csv", "matrixify, shopify, metafields", "CSV file export (Matrixify/Shopify format, property metafields)"},
 		{"json", "json, jsonl", "JSON file export"},
-		{"shopify", "-", "Direct Shopify API update (requires API key)"},
-		{"clickhouse", "-", "ClickHouse data warehouse"},
+		{"google", "google", "Google Merchant Center XML product feed"},
+		{"shopify", "-", "Direct Shopify API upsert by SKU (requires API key)"},
+		{"clickhouse", "-", "ClickHouse enhanced_products table (batched inserts)"},
 	}
 
 	for _, d := range destinations {
@@ -206,7 +317,12 @@ func runExportList(cmd *cobra.Command, args []string) error {
 	color.Yellow("  Example usage:")
 	fmt.Println("    badops export run --dest csv --format matrixify")
 	fmt.Println("    badops export run --dest json --enhanced-only")
+	fmt.Println("    badops export run --format metafields")
+	fmt.Println("    badops export run --format jsonl")
+	fmt.Println("    badops export run --format google")
 	fmt.Println("    badops export run --dest csv -o my-export.csv")
+	fmt.Println("    badops export run --status approved --vendor Tiger")
+	fmt.Println("    badops export run --dest clickhouse")
 	fmt.Println()
 
 	return nil
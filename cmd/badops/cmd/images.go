@@ -1,13 +1,23 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/images"
-	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/images/uploader"
+	"github.com/badno/badops/internal/source/tiger"
+	"github.com/badno/badops/pkg/models"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/schollz/progressbar/v3"
@@ -15,9 +25,21 @@ import (
 )
 
 var (
-	fetchLimit  int
-	resizeSize  int
-	downloadNew bool
+	fetchLimit       int
+	fetchConcurrency int
+	fetchRateLimitMs int
+	resizeSize       int
+	dedupThreshold   int
+	resizeFormat     string
+	resizeQuality    int
+	resizeFit        string
+	resizeBackground string
+	downloadNew      bool
+	fetchFromCompare string
+	compareRefresh   bool
+	uploadSize       int
+	uploadForce      bool
+	uploadDryRun     bool
 )
 
 var imagesCmd = &cobra.Command{
@@ -30,7 +52,11 @@ var fetchCmd = &cobra.Command{
 	Use:   "fetch",
 	Short: "Fetch images from Tiger.nl",
 	Long:  `Download product images from matched Tiger.nl product pages.`,
-	RunE:  runFetch,
+	Example: `  badops images fetch --new-only
+  badops images compare
+  badops images fetch --from-compare output/image-compare.json`,
+	SilenceUsage: true,
+	RunE:         runFetch,
 }
 
 var resizeCmd = &cobra.Command{
@@ -43,26 +69,68 @@ var resizeCmd = &cobra.Command{
 var compareCmd = &cobra.Command{
 	Use:   "compare",
 	Short: "Compare images between bad.no and Tiger.nl",
-	Long:  `Check how many images each product has on bad.no vs Tiger.nl and identify new images to download.`,
-	RunE:  runCompare,
+	Long: `Check how many images each product has on bad.no vs Tiger.nl and identify new images to download.
+
+The result is also written to ` + compareReportFile + ` with per-SKU counts and
+the new image URLs, which 'images fetch --from-compare' downloads without
+scanning Tiger.nl again.`,
+	RunE: runCompare,
+}
+
+var uploadCmd = &cobra.Command{
+	Use:   "upload",
+	Short: "Upload resized images to S3-compatible storage",
+	Long: `Upload the images in output/resized/<size>/ to the bucket configured under
+images.upload and point each product image at its public URL, so exports
+reference hosted copies instead of scraped source URLs. The fetched URL is
+kept as the image's original_url.
+
+Files are matched to product images by the name they were downloaded under.
+Images already uploaded are skipped unless --force is given.`,
+	Example: `  badops config set images.upload.endpoint https://s3.eu-north-1.amazonaws.com
+  badops config set images.upload.bucket badno-images
+  badops config set images.upload.public_url https://cdn.bad.no
+  badops config set images.upload.access_key_env env:S3_ACCESS_KEY
+  badops config set images.upload.secret_key_env env:S3_SECRET_KEY
+  badops images upload --size 800`,
+	SilenceUsage: true,
+	RunE:         runUpload,
 }
 
 func init() {
 	fetchCmd.Flags().IntVarP(&fetchLimit, "limit", "l", 0, "Limit number of images to fetch (0 = all)")
 	fetchCmd.Flags().BoolVar(&downloadNew, "new-only", false, "Only download new images not already on bad.no")
+	fetchCmd.Flags().StringVar(&fetchFromCompare, "from-compare", "", "Download the new images listed in an images compare report (e.g. "+compareReportFile+")")
+	fetchCmd.Flags().IntVarP(&fetchConcurrency, "concurrency", "c", 4, "Number of parallel downloads")
+	fetchCmd.Flags().IntVar(&fetchRateLimitMs, "rate-limit", 100, "Minimum milliseconds between requests to the same host")
 	resizeCmd.Flags().IntVarP(&resizeSize, "size", "s", 800, "Target size for square images")
+	resizeCmd.Flags().StringVar(&resizeFormat, "format", "jpg", "Output format: jpg, png, webp, avif")
+	resizeCmd.Flags().IntVar(&resizeQuality, "quality", 0, "Encoder quality 1-100 for jpg/webp/avif (0 = format default)")
+	resizeCmd.Flags().StringVar(&resizeFit, "fit", "crop", "How to make images square: crop (center-crop) or pad (fit and pad)")
+	resizeCmd.Flags().StringVar(&resizeBackground, "bg", "#ffffff", "Padding color for --fit pad (#RRGGBB or #RRGGBBAA)")
+	resizeCmd.Flags().IntVar(&dedupThreshold, "dedup-threshold", images.DefaultDedupThreshold, "Max perceptual hash distance for duplicate images (0 = keep duplicates)")
+
+	compareCmd.Flags().BoolVar(&compareRefresh, "force-refresh", false, "Ignore cached Tiger.nl lookups and fetch them again (fresh results are re-cached)")
 
 	imagesCmd.AddCommand(fetchCmd)
 	imagesCmd.AddCommand(resizeCmd)
 	imagesCmd.AddCommand(compareCmd)
+
+	uploadCmd.Flags().IntVarP(&uploadSize, "size", "s", 800, "Upload images resized to this size")
+	uploadCmd.Flags().BoolVar(&uploadForce, "force", false, "Upload images that were already uploaded again")
+	uploadCmd.Flags().BoolVar(&uploadDryRun, "dry-run", false, "List the images that would be uploaded")
+	imagesCmd.AddCommand(uploadCmd)
 }
 
 func runFetch(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 	success := color.New(color.FgGreen)
 
+	if fetchFromCompare != "" {
+		return runFetchFromCompare(fetchFromCompare)
+	}
 	if downloadNew {
-		return runFetchNewOnly()
+		return runFetchNewOnly(cmd.Context())
 	}
 
 	header.Println("\n  FETCHING IMAGES FROM TIGER.NL")
@@ -95,40 +163,33 @@ func runFetch(cmd *cobra.Command, args []string) error {
 		progressbar.OptionShowBytes(true),
 	)
 
-	downloaded := 0
-	failed := 0
-	results := make([]struct {
-		url    string
-		path   string
-		status string
-		size   string
-	}, 0)
+	downloads := make([]images.ImageDownload, len(imageURLs))
+	for i, img := range imageURLs {
+		downloads[i] = images.ImageDownload{URL: img.URL, Filename: img.SKU, Source: tiger.ConnectorName}
+	}
 
-	for _, img := range imageURLs {
-		path, size, err := fetcher.Download(img.URL, img.SKU)
-		bar.Add(1)
+	fetcher.SetRateLimit(time.Duration(fetchRateLimitMs) * time.Millisecond)
+	fetcher.SetProgressFunc(func(images.DownloadResult) { bar.Add(1) })
+	results := fetcher.DownloadBatch(downloads, fetchConcurrency)
+	fmt.Println()
+	fmt.Println()
 
-		if err != nil {
-			results = append(results, struct {
-				url    string
-				path   string
-				status string
-				size   string
-			}{img.URL, "", "failed", "-"})
+	downloaded := 0
+	unchanged := 0
+	invalid := 0
+	failed := 0
+	for _, r := range results {
+		switch {
+		case errors.Is(r.Error, images.ErrInvalidImage):
+			invalid++
+		case r.Error != nil:
 			failed++
-		} else {
-			results = append(results, struct {
-				url    string
-				path   string
-				status string
-				size   string
-			}{img.URL, path, "success", size})
+		case r.Unchanged:
+			unchanged++
+		default:
 			downloaded++
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
-	fmt.Println()
-	fmt.Println()
 
 	// Display results table
 	table := tablewriter.NewWriter(os.Stdout)
@@ -141,18 +202,16 @@ func runFetch(cmd *cobra.Command, args []string) error {
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 	)
 
-	for i, r := range results {
-		sku := imageURLs[i].SKU
+	for _, r := range results {
 		filename := "-"
-		if r.path != "" {
-			parts := strings.Split(r.path, "/")
+		size := "-"
+		if r.Error == nil {
+			parts := strings.Split(r.Path, "/")
 			filename = parts[len(parts)-1]
+			size = r.Size
 		}
-		status := color.GreenString("downloaded")
-		if r.status == "failed" {
-			status = color.RedString("failed")
-		}
-		table.Append([]string{sku, filename, r.size, status})
+		status := downloadStatus(r)
+		table.Append([]string{r.Filename, filename, size, status})
 	}
 	table.Render()
 	fmt.Println()
@@ -161,6 +220,19 @@ func runFetch(cmd *cobra.Command, args []string) error {
 	if downloaded > 0 {
 		success.Printf("  ✓ Downloaded %d images to output/originals/\n", downloaded)
 	}
+	if unchanged > 0 {
+		color.Cyan("  ✓ %d images unchanged since the last download\n", unchanged)
+	}
+	if downloaded > 0 {
+		if updated, err := recordDownloads(results); err != nil {
+			color.Yellow("  Warning: failed to update image metadata in state: %v", err)
+		} else if updated > 0 {
+			success.Printf("  ✓ Updated image metadata for %d images in state\n", updated)
+		}
+	}
+	if invalid > 0 {
+		color.Yellow("  ⚠ Skipped %d URLs that did not return an image\n", invalid)
+	}
 	if failed > 0 {
 		color.Red("  ✗ Failed to download %d images\n", failed)
 	}
@@ -169,9 +241,8 @@ func runFetch(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runFetchNewOnly() error {
+func runFetchNewOnly(ctx context.Context) error {
 	header := color.New(color.FgCyan, color.Bold)
-	success := color.New(color.FgGreen)
 
 	header.Println("\n  FETCHING NEW IMAGES FROM TIGER.NL")
 	fmt.Println("  " + strings.Repeat("─", 45))
@@ -186,16 +257,13 @@ func runFetchNewOnly() error {
 
 	color.Yellow("  Scanning %d products for new images...\n\n", len(products))
 
-	// Create matcher and fetcher (uses new SKU-based lookup)
-	tigerMatcher := matcher.NewTigerMatcher()
-	fetcher := images.NewFetcher()
+	// Create matcher (uses new SKU-based lookup)
+	tigerMatcher, err := newTigerMatcher(ctx)
+	if err != nil {
+		return err
+	}
 
 	// First pass: find all new images
-	type newImage struct {
-		sku string
-		url string
-		idx int
-	}
 	var newImages []newImage
 
 	// Progress bar for scanning
@@ -215,25 +283,74 @@ func runFetchNewOnly() error {
 		existingCount := len(p.ExistingImages)
 
 		// Find product on Tiger.nl using SKU-based lookup (new method)
-		tigerProduct, err := tigerMatcher.LookupBySKU(p.SKU, p.Name)
+		tigerProduct, err := tigerMatcher.LookupBySKU(ctx, p.SKU, p.Name)
+		if ctx.Err() != nil {
+			fmt.Println()
+			return errInterrupted
+		}
 		if err == nil && tigerProduct != nil {
 			// Skip first N images (already on bad.no), take the rest
 			for i, imgURL := range tigerProduct.ImageURLs {
 				if i >= existingCount {
 					newImages = append(newImages, newImage{
-						sku: p.SKU,
-						url: imgURL,
-						idx: i - existingCount + 1,
+						sku:    p.SKU,
+						url:    imgURL,
+						idx:    i - existingCount + 1,
+						source: tiger.ConnectorName,
 					})
 				}
 			}
 		}
 		scanBar.Add(1)
-		time.Sleep(100 * time.Millisecond)
 	}
 	fmt.Println()
 	fmt.Println()
 
+	return downloadNewImages(newImages)
+}
+
+// runFetchFromCompare downloads the new images listed in a compare report.
+// Filenames are numbered the same way as --new-only, so running it again
+// re-validates the same files instead of downloading duplicates.
+func runFetchFromCompare(path string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	header.Println("\n  FETCHING NEW IMAGES FROM COMPARE REPORT")
+	fmt.Println("  " + strings.Repeat("─", 45))
+	fmt.Println()
+
+	report, err := loadCompareReport(path)
+	if err != nil {
+		return err
+	}
+
+	color.Yellow("  Report %s from %s (%d products)\n\n", path, report.GeneratedAt.Local().Format("2006-01-02 15:04"), report.TotalProducts)
+
+	var newImages []newImage
+	for _, c := range report.Products {
+		for i, imgURL := range c.NewImages {
+			newImages = append(newImages, newImage{sku: c.SKU, url: imgURL, idx: i + 1, source: tiger.ConnectorName})
+		}
+	}
+
+	return downloadNewImages(newImages)
+}
+
+// newImage is a Tiger.nl image not yet on bad.no; idx numbers a product's
+// new images from 1 and names the downloaded file SKU_new_<idx>, source is
+// the connector the URL came from
+type newImage struct {
+	sku    string
+	url    string
+	idx    int
+	source string
+}
+
+// downloadNewImages downloads new images, records them in state and prints
+// the results
+func downloadNewImages(newImages []newImage) error {
+	success := color.New(color.FgGreen)
+
 	if len(newImages) == 0 {
 		color.Yellow("  No new images found. All products are up to date.")
 		fmt.Println()
@@ -261,48 +378,40 @@ func runFetchNewOnly() error {
 		progressbar.OptionShowBytes(true),
 	)
 
-	downloaded := 0
-	failed := 0
-	results := make([]struct {
-		sku    string
-		url    string
-		path   string
-		status string
-		size   string
-		idx    int
-	}, 0)
+	fetcher := images.NewFetcher()
 
-	for _, img := range newImages {
-		// Create filename with index for new images
-		filename := fmt.Sprintf("%s_new_%d", img.sku, img.idx)
-		path, size, err := fetcher.Download(img.url, filename)
-		downloadBar.Add(1)
+	// Create filenames with index for new images
+	downloads := make([]images.ImageDownload, len(newImages))
+	for i, img := range newImages {
+		downloads[i] = images.ImageDownload{
+			URL:      img.url,
+			Filename: fmt.Sprintf("%s_new_%d", img.sku, img.idx),
+			Source:   img.source,
+		}
+	}
 
-		if err != nil {
-			results = append(results, struct {
-				sku    string
-				url    string
-				path   string
-				status string
-				size   string
-				idx    int
-			}{img.sku, img.url, "", "failed", "-", img.idx})
+	fetcher.SetRateLimit(time.Duration(fetchRateLimitMs) * time.Millisecond)
+	fetcher.SetProgressFunc(func(images.DownloadResult) { downloadBar.Add(1) })
+	results := fetcher.DownloadBatch(downloads, fetchConcurrency)
+	fmt.Println()
+	fmt.Println()
+
+	downloaded := 0
+	unchanged := 0
+	invalid := 0
+	failed := 0
+	for _, r := range results {
+		switch {
+		case errors.Is(r.Error, images.ErrInvalidImage):
+			invalid++
+		case r.Error != nil:
 			failed++
-		} else {
-			results = append(results, struct {
-				sku    string
-				url    string
-				path   string
-				status string
-				size   string
-				idx    int
-			}{img.sku, img.url, path, "success", size, img.idx})
+		case r.Unchanged:
+			unchanged++
+		default:
 			downloaded++
 		}
-		time.Sleep(50 * time.Millisecond)
 	}
-	fmt.Println()
-	fmt.Println()
 
 	// Display results table
 	table := tablewriter.NewWriter(os.Stdout)
@@ -316,17 +425,17 @@ func runFetchNewOnly() error {
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 	)
 
-	for _, r := range results {
+	for i, r := range results {
+		img := newImages[i]
 		filename := "-"
-		if r.path != "" {
-			parts := strings.Split(r.path, "/")
+		size := "-"
+		if r.Error == nil {
+			parts := strings.Split(r.Path, "/")
 			filename = parts[len(parts)-1]
+			size = r.Size
 		}
-		status := color.GreenString("downloaded")
-		if r.status == "failed" {
-			status = color.RedString("failed")
-		}
-		table.Append([]string{r.sku, fmt.Sprintf("+%d", r.idx), filename, r.size, status})
+		status := downloadStatus(r)
+		table.Append([]string{img.sku, fmt.Sprintf("+%d", img.idx), filename, size, status})
 	}
 	table.Render()
 	fmt.Println()
@@ -335,6 +444,19 @@ func runFetchNewOnly() error {
 	if downloaded > 0 {
 		success.Printf("  ✓ Downloaded %d NEW images to output/originals/\n", downloaded)
 	}
+	if unchanged > 0 {
+		color.Cyan("  ✓ %d images unchanged since the last download\n", unchanged)
+	}
+	if downloaded > 0 {
+		if updated, err := recordDownloads(results); err != nil {
+			color.Yellow("  Warning: failed to update image metadata in state: %v", err)
+		} else if updated > 0 {
+			success.Printf("  ✓ Updated image metadata for %d images in state\n", updated)
+		}
+	}
+	if invalid > 0 {
+		color.Yellow("  ⚠ Skipped %d URLs that did not return an image\n", invalid)
+	}
 	if failed > 0 {
 		color.Red("  ✗ Failed to download %d images\n", failed)
 	}
@@ -347,11 +469,28 @@ func runResize(cmd *cobra.Command, args []string) error {
 	header := color.New(color.FgCyan, color.Bold)
 	success := color.New(color.FgGreen)
 
+	if !images.IsOutputFormat(resizeFormat) {
+		return fmt.Errorf("unsupported format %q (use jpg, png, webp or avif)", resizeFormat)
+	}
+	if resizeQuality < 0 || resizeQuality > 100 {
+		return fmt.Errorf("quality must be between 0 and 100")
+	}
+	bg, err := images.ParseHexColor(resizeBackground)
+	if err != nil {
+		return err
+	}
+
 	header.Println("\n  RESIZING IMAGES TO SQUARE FORMAT")
 	fmt.Println("  " + strings.Repeat("─", 40))
 	fmt.Println()
 
 	resizer := images.NewResizer()
+	resizer.SetQuality(resizeQuality)
+	resizer.SetBackground(bg)
+	if err := resizer.SetFitMode(images.FitMode(resizeFit)); err != nil {
+		return err
+	}
+
 	imagesToResize, err := resizer.FindOriginals()
 	if err != nil || len(imagesToResize) == 0 {
 		color.Yellow("  No images found in output/originals/")
@@ -359,7 +498,14 @@ func runResize(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	color.Yellow("  Found %d images to resize to %dx%d\n\n", len(imagesToResize), resizeSize, resizeSize)
+	color.Yellow("  Found %d images to resize to %dx%d\n", len(imagesToResize), resizeSize, resizeSize)
+
+	// Drop near-duplicates (same shot from different sources) before resizing
+	imagesToResize, duplicates := images.DedupWithThreshold(imagesToResize, dedupThreshold)
+	if len(duplicates) > 0 {
+		color.Yellow("  Skipping %d duplicate images\n", len(duplicates))
+	}
+	fmt.Println()
 
 	// Progress bar
 	bar := progressbar.NewOptions(len(imagesToResize),
@@ -383,7 +529,7 @@ func runResize(cmd *cobra.Command, args []string) error {
 	}, 0)
 
 	for _, imgPath := range imagesToResize {
-		destPath, err := resizer.ResizeSquare(imgPath, resizeSize)
+		destPath, err := resizer.ResizeSquareFormat(imgPath, resizeSize, resizeFormat)
 		bar.Add(1)
 
 		if err != nil {
@@ -432,6 +578,15 @@ func runResize(cmd *cobra.Command, args []string) error {
 		}
 		table.Append([]string{sourceFile, destFile, status})
 	}
+	removedPaths := make([]string, 0, len(duplicates))
+	for removedPath := range duplicates {
+		removedPaths = append(removedPaths, removedPath)
+	}
+	sort.Strings(removedPaths)
+	for _, removedPath := range removedPaths {
+		keptFile := filepath.Base(duplicates[removedPath])
+		table.Append([]string{filepath.Base(removedPath), "-", color.YellowString("duplicate of %s", keptFile)})
+	}
 	table.Render()
 	fmt.Println()
 
@@ -465,7 +620,11 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	color.Yellow("  Checking %d products...\n\n", len(products))
 
 	// Create matcher (uses new SKU-based lookup)
-	tigerMatcher := matcher.NewTigerMatcher()
+	tigerMatcher, err := newTigerMatcher(cmd.Context())
+	if err != nil {
+		return err
+	}
+	tigerMatcher.GetScraper().SetForceRefresh(compareRefresh)
 
 	// Progress bar
 	bar := progressbar.NewOptions(len(products),
@@ -480,17 +639,7 @@ func runCompare(cmd *cobra.Command, args []string) error {
 		progressbar.OptionShowCount(),
 	)
 
-	type comparison struct {
-		sku         string
-		name        string
-		badnoCount  int
-		tigerCount  int
-		newCount    int
-		tigerImages []string
-		tigerURL    string
-	}
-
-	var comparisons []comparison
+	var comparisons []models.ImageComparison
 	totalNew := 0
 
 	for _, p := range products {
@@ -501,27 +650,32 @@ func runCompare(cmd *cobra.Command, args []string) error {
 		var tigerImages []string
 		var tigerURL string
 
-		tigerProduct, err := tigerMatcher.LookupBySKU(p.SKU, p.Name)
+		tigerProduct, err := tigerMatcher.LookupBySKU(cmd.Context(), p.SKU, p.Name)
+		if cmd.Context().Err() != nil {
+			fmt.Println() // A partial scan would report missing images as new
+			return errInterrupted
+		}
 		if err == nil && tigerProduct != nil {
 			tigerCount = len(tigerProduct.ImageURLs)
 			tigerImages = tigerProduct.ImageURLs
 			tigerURL = tigerProduct.URL
 		}
 
-		newCount := 0
+		// Tiger.nl images beyond the ones already on bad.no are new
+		var newImages []string
 		if tigerCount > badnoCount {
-			newCount = tigerCount - badnoCount
-		}
-		totalNew += newCount
-
-		comparisons = append(comparisons, comparison{
-			sku:         p.SKU,
-			name:        p.Name,
-			badnoCount:  badnoCount,
-			tigerCount:  tigerCount,
-			newCount:    newCount,
-			tigerImages: tigerImages,
-			tigerURL:    tigerURL,
+			newImages = tigerImages[badnoCount:]
+		}
+		totalNew += len(newImages)
+
+		comparisons = append(comparisons, models.ImageComparison{
+			SKU:        p.SKU,
+			Name:       p.Name,
+			BadnoCount: badnoCount,
+			TigerCount: tigerCount,
+			NewCount:   len(newImages),
+			TigerURL:   tigerURL,
+			NewImages:  newImages,
 		})
 
 		bar.Add(1)
@@ -543,27 +697,27 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	)
 
 	for _, c := range comparisons {
-		name := c.name
+		name := c.Name
 		if len(name) > 25 {
 			name = name[:22] + "..."
 		}
 
-		badnoStr := fmt.Sprintf("%d", c.badnoCount)
-		if c.badnoCount == 0 {
+		badnoStr := fmt.Sprintf("%d", c.BadnoCount)
+		if c.BadnoCount == 0 {
 			badnoStr = color.RedString("0")
 		}
 
-		tigerStr := fmt.Sprintf("%d", c.tigerCount)
-		if c.tigerCount == 0 {
+		tigerStr := fmt.Sprintf("%d", c.TigerCount)
+		if c.TigerCount == 0 {
 			tigerStr = color.YellowString("?")
 		}
 
-		newStr := fmt.Sprintf("%d", c.newCount)
-		if c.newCount > 0 {
-			newStr = color.GreenString("+%d", c.newCount)
+		newStr := fmt.Sprintf("%d", c.NewCount)
+		if c.NewCount > 0 {
+			newStr = color.GreenString("+%d", c.NewCount)
 		}
 
-		table.Append([]string{c.sku, name, badnoStr, tigerStr, newStr})
+		table.Append([]string{c.SKU, name, badnoStr, tigerStr, newStr})
 	}
 	table.Render()
 	fmt.Println()
@@ -571,18 +725,273 @@ func runCompare(cmd *cobra.Command, args []string) error {
 	// Summary
 	productsWithNew := 0
 	for _, c := range comparisons {
-		if c.newCount > 0 {
+		if c.NewCount > 0 {
 			productsWithNew++
 		}
 	}
 
 	if totalNew > 0 {
 		success.Printf("  ✓ Found %d new images across %d products\n", totalNew, productsWithNew)
-		color.Yellow("  → Run 'badops images fetch --new-only' to download them\n")
 	} else {
 		color.Yellow("  All products have matching images\n")
 	}
+
+	report := models.ImageCompareReport{
+		GeneratedAt:     time.Now(),
+		TotalProducts:   len(comparisons),
+		ProductsWithNew: productsWithNew,
+		TotalNew:        totalNew,
+		Products:        comparisons,
+	}
+	if err := saveCompareReport(compareReportFile, report); err != nil {
+		color.Red("  Warning: Could not save report: %v", err)
+	} else {
+		color.Green("  ✓ Report saved to %s", compareReportFile)
+		if totalNew > 0 {
+			color.Yellow("  → Run 'badops images fetch --from-compare %s' to download them\n", compareReportFile)
+		}
+	}
 	fmt.Println()
 
 	return nil
 }
+
+// compareReportFile is where images compare writes its report
+const compareReportFile = "output/image-compare.json"
+
+func saveCompareReport(path string, report models.ImageCompareReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func loadCompareReport(path string) (*models.ImageCompareReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compare report (run 'badops images compare' first): %w", err)
+	}
+	var report models.ImageCompareReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid compare report %s: %w", path, err)
+	}
+	return &report, nil
+}
+
+// downloadStatus returns the colored status of a batch download for tables
+func downloadStatus(r images.DownloadResult) string {
+	switch {
+	case errors.Is(r.Error, images.ErrInvalidImage):
+		return color.YellowString("invalid")
+	case r.Error != nil:
+		return color.RedString("failed")
+	case r.Unchanged:
+		return color.CyanString("unchanged")
+	default:
+		return color.GreenString("downloaded")
+	}
+}
+
+// recordDownloads reads the dimensions of each downloaded file and stores them
+// on the matching product image in the state store. Products are matched by
+// the SKU-derived filename; each image keeps the source of its download.
+// Returns the number of images updated.
+func recordDownloads(results []images.DownloadResult) (int, error) {
+	store, err := openStore(context.Background(), true)
+	if err != nil {
+		return 0, err
+	}
+	defer store.Close()
+
+	updated := 0
+	for _, r := range results {
+		if r.Error != nil || r.Unchanged {
+			continue // Unchanged images keep the metadata of their last download
+		}
+
+		width, height, size, err := images.ReadImageInfo(r.Path)
+		if err != nil {
+			continue
+		}
+
+		if store.RecordImageDownload(images.SKUFromFilename(r.Path), models.ProductImage{
+			SourceURL:    r.URL,
+			LocalPath:    r.Path,
+			Width:        width,
+			Height:       height,
+			Bytes:        size,
+			Status:       "downloaded",
+			Source:       r.Source,
+			DownloadedAt: time.Now(),
+		}) {
+			updated++
+		}
+	}
+
+	if updated == 0 {
+		return 0, nil
+	}
+	return updated, store.Save()
+}
+
+// uploadJob is a resized image to upload for a product image
+type uploadJob struct {
+	path string
+	sku  string
+	stem string
+}
+
+func runUpload(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	up := cfg.Images.Upload
+	if !up.Configured() {
+		return fmt.Errorf("no upload bucket configured (set images.upload.endpoint and images.upload.bucket)")
+	}
+	accessKey, err := credentials.Resolve(nil, up.AccessKeyEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve upload access key: %w", err)
+	}
+	secretKey, err := credentials.Resolve(nil, up.SecretKeyEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve upload secret key: %w", err)
+	}
+	s3, err := uploader.NewS3Uploader(uploader.S3Config{
+		Endpoint:  up.Endpoint,
+		Region:    up.Region,
+		Bucket:    up.Bucket,
+		Prefix:    up.Prefix,
+		PublicURL: up.PublicURL,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		ACL:       up.ACL,
+	})
+	if err != nil {
+		return err
+	}
+
+	header.Println("\n  UPLOADING IMAGES")
+	fmt.Println("  " + strings.Repeat("─", 40))
+	fmt.Println()
+
+	dir := filepath.Join("output", "resized", strconv.Itoa(uploadSize))
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) == 0 {
+		color.Yellow("  No images found in %s/", dir)
+		color.Yellow("  Run 'badops images resize --size %d' first.", uploadSize)
+		return nil
+	}
+
+	store, err := openStore(cmd.Context(), !uploadDryRun)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var jobs []uploadJob
+	unmatched, alreadyUploaded := 0, 0
+	for _, e := range entries {
+		if e.IsDir() || !images.IsOutputFormat(strings.TrimPrefix(strings.ToLower(filepath.Ext(e.Name())), ".")) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		stem := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		sku := images.SKUFromFilename(path)
+
+		var img *models.ProductImage
+		if product, ok := store.GetProduct(sku); ok {
+			img = product.ImageByFile(stem)
+		}
+		if img == nil {
+			unmatched++
+			continue
+		}
+		if img.Status == "uploaded" && !uploadForce {
+			alreadyUploaded++
+			continue
+		}
+		jobs = append(jobs, uploadJob{path: path, sku: sku, stem: stem})
+	}
+
+	color.Yellow("  Found %d images to upload to %s\n", len(jobs), up.Bucket)
+	if alreadyUploaded > 0 {
+		color.Yellow("  Skipping %d images already uploaded (use --force to upload again)\n", alreadyUploaded)
+	}
+	if unmatched > 0 {
+		color.Yellow("  Skipping %d files that match no downloaded product image\n", unmatched)
+	}
+	fmt.Println()
+
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	if uploadDryRun {
+		for _, job := range jobs {
+			fmt.Printf("  %s → %s\n", filepath.Base(job.path), s3.PublicURL(s3.Key(job.path)))
+		}
+		fmt.Println()
+		color.Yellow("  Dry run complete. No images uploaded.")
+		fmt.Println()
+		return nil
+	}
+
+	bar := progressbar.NewOptions(len(jobs),
+		progressbar.OptionSetDescription("  Uploading images"),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        color.GreenString("█"),
+			SaucerHead:    color.GreenString("█"),
+			SaucerPadding: "░",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}),
+		progressbar.OptionShowCount(),
+	)
+
+	uploaded := 0
+	var failures []string
+	for _, job := range jobs {
+		publicURL, err := s3.Upload(job.path)
+		bar.Add(1)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", filepath.Base(job.path), err))
+			continue
+		}
+		if store.RecordImageUpload(job.sku, job.stem, strconv.Itoa(uploadSize), job.path, publicURL) {
+			uploaded++
+		}
+	}
+	fmt.Println()
+	fmt.Println()
+
+	if uploaded > 0 {
+		store.AddHistory("images_upload", up.Bucket, uploaded,
+			fmt.Sprintf("Uploaded %d images resized to %d", uploaded, uploadSize))
+		if err := store.Save(); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
+		}
+		success.Printf("  ✓ Uploaded %d images; product images now use their public URLs\n", uploaded)
+	}
+	for i, f := range failures {
+		if i == 10 {
+			color.Red("  ... and %d more", len(failures)-10)
+			break
+		}
+		color.Red("  ✗ %s", f)
+	}
+	fmt.Println()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to upload %d of %d images", len(failures), len(jobs))
+	}
+	return nil
+}
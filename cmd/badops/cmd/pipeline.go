@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/orchestrator"
+	"github.com/badno/badops/internal/output"
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pipelineImportSource   string
+	pipelineImportFile     string
+	pipelineEnhanceSources []string
+	pipelineExportDest     string
+	pipelineExportFormat   string
+	pipelineExportPath     string
+	pipelineVendor         string
+	pipelineLimit          int
+	pipelineConcurrency    int
+	pipelineDryRun         bool
+	pipelineIncludeImages  bool
+	pipelinePersist        bool
+)
+
+var pipelineCmd = &cobra.Command{
+	Use:   "pipeline",
+	Short: "Run import, enhance and export together",
+	Long:  `Run the full import → enhance → export pipeline in one command.`,
+}
+
+var pipelineRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Import, enhance and export products in one shot",
+	Long: `Import products from a source, enhance them and export the result, then
+print the counts and duration of each stage.
+
+--vendor and --limit scope every stage. Flags left unset fall back to
+defaults.vendor, defaults.enhance_sources and defaults.export_format. Pass
+an empty value (e.g. --import-source "" or --export-dest "") to skip a stage.
+
+With --dry-run products are fetched but not imported, enhancement sources
+are connected without looking anything up and the export is previewed.
+
+--persist also saves each enhanced product to PostgreSQL in its own
+transaction while the JSON state file is used; with database.use_db the
+state already lives there.
+
+Examples:
+  badops pipeline run --vendor Tiger --limit 50
+  badops pipeline run --import-source matrixify --file export.csv --export-format json
+  badops pipeline run --enhance-source tiger_nl --enhance-source nobb --export-dest shopify --dry-run`,
+	SilenceUsage: true,
+	RunE:         runPipeline,
+}
+
+func init() {
+	pipelineRunCmd.Flags().StringVar(&pipelineImportSource, "import-source", "shopify", "Source to import from (shopify, matrixify, woocommerce, rest)")
+	pipelineRunCmd.Flags().StringVar(&pipelineImportFile, "file", "", "Export CSV for --import-source matrixify (default: sources.matrixify.file)")
+	pipelineRunCmd.Flags().StringSliceVar(&pipelineEnhanceSources, "enhance-source", nil, "Enhancement sources to use (repeatable; default: defaults.enhance_sources or tiger_nl)")
+	pipelineRunCmd.Flags().StringVar(&pipelineExportDest, "export-dest", "csv", "Export destination (csv, json, google, shopify, clickhouse)")
+	pipelineRunCmd.Flags().StringVar(&pipelineExportFormat, "export-format", "", "Export format (default: defaults.export_format or matrixify)")
+	pipelineRunCmd.Flags().StringVarP(&pipelineExportPath, "output", "o", "", "Output file path (for file exports)")
+	pipelineRunCmd.Flags().StringVar(&pipelineVendor, "vendor", "", "Only process products from this vendor (default: defaults.vendor)")
+	pipelineRunCmd.Flags().IntVar(&pipelineLimit, "limit", 0, "Maximum products to import and enhance (0 = all)")
+	pipelineRunCmd.Flags().IntVar(&pipelineConcurrency, "concurrency", 1, "Products to enhance in parallel")
+	pipelineRunCmd.Flags().BoolVar(&pipelineDryRun, "dry-run", false, "Preview without changing state or exporting")
+	pipelineRunCmd.Flags().BoolVar(&pipelineIncludeImages, "include-images", true, "Include image URLs in the export")
+	pipelineRunCmd.Flags().BoolVar(&pipelinePersist, "persist", false, "Also save enhanced products to PostgreSQL (JSON state only)")
+
+	pipelineCmd.AddCommand(pipelineRunCmd)
+}
+
+func runPipeline(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	cfg, err := config.Load()
+	if err != nil {
+		color.Yellow("  Warning: Could not load config, using defaults")
+		cfg = config.DefaultConfig()
+	}
+
+	opts := pipelineOptions(cmd, cfg)
+
+	header.Println("\n  RUNNING PIPELINE")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	color.Yellow("  Import: %s\n", stageLabel(opts.ImportSource))
+	color.Yellow("  Enhance: %s\n", stageLabel(strings.Join(opts.EnhanceSources, ", ")))
+	if opts.ExportDest != "" {
+		color.Yellow("  Export: %s (%s)\n", opts.ExportDest, opts.ExportFormat)
+	} else {
+		color.Yellow("  Export: %s\n", stageLabel(""))
+	}
+	if opts.ImportVendor != "" {
+		color.Yellow("  Vendor filter: %s\n", opts.ImportVendor)
+	}
+	if opts.ImportLimit > 0 {
+		color.Yellow("  Limit: %d\n", opts.ImportLimit)
+	}
+	if opts.DryRun {
+		color.Yellow("  Mode: DRY RUN (no changes will be made)\n")
+	}
+	fmt.Println()
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Minute)
+	defer cancel()
+
+	orch := orchestrator.New(cfg)
+	if err := orch.Initialize(ctx); err != nil {
+		color.Red("  Error: %v", err)
+		return err
+	}
+	defer orch.Close()
+
+	if pipelinePersist && !opts.DryRun {
+		persister, closeDB, err := enhancePersister(ctx, cfg)
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return err
+		}
+		defer closeDB()
+		orch.SetPersister(persister)
+	}
+
+	result, runErr := orch.RunPipeline(ctx, opts)
+	showPipelineResult(result, opts)
+
+	if runErr != nil {
+		if cmd.Context().Err() != nil {
+			color.Yellow("  Interrupted, saved the stages completed so far")
+			fmt.Println()
+			return errInterrupted
+		}
+		color.Red("  Error: %v", runErr)
+		fmt.Println()
+		return runErr
+	}
+
+	success.Printf("  ✓ Pipeline finished in %s\n", result.CompletedAt.Sub(result.StartedAt).Round(time.Millisecond))
+	fmt.Println()
+	return nil
+}
+
+// pipelineOptions builds the pipeline options from flags, falling back to
+// the config defaults for flags that were not set
+func pipelineOptions(cmd *cobra.Command, cfg *config.Config) orchestrator.PipelineOptions {
+	vendor := pipelineVendor
+	if !cmd.Flags().Changed("vendor") {
+		vendor = cfg.Defaults.Vendor
+	}
+
+	enhanceSources := pipelineEnhanceSources
+	if !cmd.Flags().Changed("enhance-source") {
+		enhanceSources = cfg.Defaults.EnhanceSources
+		if len(enhanceSources) == 0 {
+			enhanceSources = []string{"tiger_nl"}
+		}
+	}
+	// --enhance-source "" skips the stage
+	sources := make([]string, 0, len(enhanceSources))
+	for _, s := range enhanceSources {
+		if s = strings.TrimSpace(s); s != "" {
+			sources = append(sources, s)
+		}
+	}
+
+	format := output.Format(pipelineExportFormat)
+	if format == "" {
+		format = output.Format(cfg.Defaults.ExportFormat)
+	}
+	dest := pipelineExportDest
+	// Non-CSV file formats pick their adapter unless a destination was given,
+	// as in 'export run'
+	if !cmd.Flags().Changed("export-dest") {
+		switch format {
+		case output.FormatJSON, output.FormatJSONL:
+			dest = "json"
+		case output.FormatGoogleMerchant:
+			dest = "google"
+		}
+	}
+	if format == "" {
+		switch dest {
+		case "json":
+			format = output.FormatJSON
+		case "google":
+			format = output.FormatGoogleMerchant
+		default:
+			format = output.FormatMatrixify
+		}
+	}
+
+	return orchestrator.PipelineOptions{
+		ImportSource:   pipelineImportSource,
+		ImportVendor:   vendor,
+		ImportLimit:    pipelineLimit,
+		ImportFile:     pipelineImportFile,
+		EnhanceSources: sources,
+		Concurrency:    pipelineConcurrency,
+		ExportDest:     dest,
+		ExportFormat:   format,
+		ExportPath:     pipelineExportPath,
+		IncludeImages:  pipelineIncludeImages,
+		DryRun:         pipelineDryRun,
+	}
+}
+
+// showPipelineResult prints one row per stage with its counts and duration
+func showPipelineResult(result *orchestrator.PipelineResult, opts orchestrator.PipelineOptions) {
+	if result == nil {
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Stage", "Status", "Products", "Details", "Duration"})
+	table.SetBorder(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	// A stage that was configured but has no result never ran
+	notRun := func(stage string) {
+		table.Append([]string{stage, color.YellowString("not run"), "-", "", ""})
+	}
+
+	switch {
+	case opts.ImportSource == "":
+		table.Append([]string{"Import", "skipped", "-", "", ""})
+	case result.Import == nil:
+		notRun("Import")
+	default:
+		r := result.Import
+		details := "from " + opts.ImportSource
+		if opts.DryRun {
+			details += " (fetched only)"
+		}
+		table.Append([]string{"Import", stageStatus(r.Success, r.Error), strconv.Itoa(r.ProductsImported),
+			details, stageDuration(r.StartedAt, r.CompletedAt)})
+	}
+
+	switch {
+	case len(opts.EnhanceSources) == 0:
+		table.Append([]string{"Enhance", "skipped", "-", "", ""})
+	case result.Enhance == nil:
+		notRun("Enhance")
+	default:
+		r := result.Enhance
+		details := fmt.Sprintf("%d processed, %d images, %d fields", r.ProductsProcessed, r.ImagesAdded, r.FieldsUpdated)
+		table.Append([]string{"Enhance", stageStatus(r.Success, r.Error), strconv.Itoa(r.ProductsEnhanced),
+			details, stageDuration(r.StartedAt, r.CompletedAt)})
+	}
+
+	switch {
+	case opts.ExportDest == "":
+		table.Append([]string{"Export", "skipped", "-", "", ""})
+	case result.Export == nil:
+		notRun("Export")
+	default:
+		r := result.Export
+		details := r.Destination
+		if details == "" {
+			details = r.Details
+		}
+		table.Append([]string{"Export", stageStatus(r.Success, r.Error), strconv.Itoa(r.ProductsExported),
+			truncate(details, 50), stageDuration(r.StartedAt, r.CompletedAt)})
+	}
+
+	table.Render()
+	fmt.Println()
+}
+
+// stageLabel names a stage's source, or "skipped" when it has none
+func stageLabel(name string) string {
+	if name == "" {
+		return "skipped"
+	}
+	return name
+}
+
+// stageStatus reports a stage that succeeded with an error, such as an
+// enhance run that was cancelled or failed to persist some products, as partial
+func stageStatus(ok bool, err error) string {
+	switch {
+	case ok && err == nil:
+		return color.GreenString("ok")
+	case ok:
+		return color.YellowString("partial")
+	default:
+		return color.RedString("failed")
+	}
+}
+
+func stageDuration(start, end time.Time) string {
+	if start.IsZero() || end.IsZero() {
+		return "-"
+	}
+	return end.Sub(start).Round(time.Millisecond).String()
+}
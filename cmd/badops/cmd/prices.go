@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/database"
 	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/notify"
 	"github.com/badno/badops/internal/prices"
 	"github.com/badno/badops/pkg/models"
 	"github.com/fatih/color"
@@ -25,11 +32,22 @@ var pricesCmd = &cobra.Command{
 }
 
 var pricesImportCmd = &cobra.Command{
-	Use:   "import <csv-file>",
-	Short: "Import prices from Reprice CSV export",
-	Long:  "Parses a Reprice CSV file and imports competitor prices into the database",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runPricesImport,
+	Use:   "import <file>",
+	Short: "Import prices from Reprice CSV export or a JSON feed",
+	Long: `Parses a Reprice CSV file, or a JSON price feed, and imports competitor
+prices into the database.
+
+A JSON feed is an array of objects:
+  [{"sku": "CO-T309012", "barcode": "8711559...", "competitor": "Megaflis",
+    "price": 1299.0, "in_stock": true, "url": "https://...",
+    "observed_at": "2024-05-01T10:00:00Z"}]
+
+title, vendor and stock_quantity are optional; observed_at defaults to the
+import time and in_stock to true.`,
+	Example: `  badops prices import reprice-export.csv
+  badops prices import feed.json --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPricesImport,
 }
 
 var pricesCheckCmd = &cobra.Command{
@@ -46,20 +64,126 @@ var pricesSummaryCmd = &cobra.Command{
 	RunE:  runPricesSummary,
 }
 
+var pricesAlertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Show price alerts without ClickHouse",
+	Long:  "Lists products whose price differs from the competitor average by more than a threshold, computed in PostgreSQL",
+	RunE:  runPricesAlerts,
+}
+
+var pricesTrendsCmd = &cobra.Command{
+	Use:   "trends",
+	Short: "Show price trends without ClickHouse",
+	Long: `Shows a product's competitor prices over time from PostgreSQL, bucketed by
+day and competitor. Renders the same table as 'analytics trends', which needs
+ClickHouse.`,
+	Example: `  badops prices trends --sku CO-T309012
+  badops prices trends --sku CO-T309012 --days 90 -o csv`,
+	SilenceUsage: true,
+	RunE:         runPricesTrends,
+}
+
+var pricesScrapeCmd = &cobra.Command{
+	Use:   "scrape",
+	Short: "Scrape competitor prices",
+	Long: `Fetches current prices for the products linked to each competitor with
+scraping enabled, using the competitor's scrape_config (see
+'badops competitors scrape-config'). Observations are stored with source
+"scraper" and the competitor's last scraped time is updated. Requests to a
+competitor are spaced by its rate_limit_ms (default 1000).
+
+Ctrl-C stops after the current request and saves the prices scraped so far.`,
+	Example: `  badops prices scrape
+  badops prices scrape --competitor Megaflis --limit 20 --dry-run`,
+	SilenceUsage: true,
+	RunE:         runPricesScrape,
+}
+
+var (
+	pricesScrapeCompetitor string
+	pricesScrapeLimit      int
+	pricesScrapeDryRun     bool
+)
+
+var pricesWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Send undercut alerts to the notify webhook",
+	Long: `Finds products priced more than notify.min_diff_percent (default 10%) above
+the cheapest competitor's latest price and POSTs one alert per product to the
+webhook in notify.webhook_url_env. Slack incoming webhooks get a Slack
+message; other URLs get a JSON object with sku, our_price, market_min,
+competitor and diff_percent.
+
+Runs once, or every --interval until interrupted. The last alert sent for each
+product is kept in PostgreSQL, so an undercut is only sent again when our
+price, the market minimum or the cheapest competitor changes, or after the
+product stopped being undercut. 'prices import' sends the same alerts for the
+products it imported.`,
+	Example: `  badops config set notify.webhook_url_env SLACK_WEBHOOK_URL
+  badops prices watch --dry-run
+  badops prices watch --interval 1h`,
+	SilenceUsage: true,
+	RunE:         runPricesWatch,
+}
+
 var (
-	pricesSKU     string
-	pricesBarcode string
-	pricesDays    int
+	pricesWatchInterval time.Duration
+	pricesWatchMinDiff  float64
+	pricesWatchDryRun   bool
+	pricesNoNotify      bool
+)
+
+var (
+	pricesSKU       string
+	pricesBarcode   string
+	pricesDays      int
+	pricesVendor    string
+	pricesThreshold float64
+	pricesAlertDays int
+	pricesOutput    string
+
+	pricesFormat         string
+	pricesDelimiter      string
+	pricesFuzzy          bool
+	pricesFuzzyThreshold float64
 )
 
 func init() {
 	pricesCmd.AddCommand(pricesImportCmd)
 	pricesCmd.AddCommand(pricesCheckCmd)
 	pricesCmd.AddCommand(pricesSummaryCmd)
+	pricesCmd.AddCommand(pricesAlertsCmd)
+	pricesCmd.AddCommand(pricesTrendsCmd)
+	pricesCmd.AddCommand(pricesScrapeCmd)
+	pricesCmd.AddCommand(pricesWatchCmd)
+
+	pricesImportCmd.Flags().StringVar(&pricesFormat, "format", "", "Input format: csv or json (default: json for .json files, else csv)")
+	pricesImportCmd.Flags().StringVar(&pricesDelimiter, "delimiter", "", "Field delimiter: , ; or tab (default: detect)")
+	pricesImportCmd.Flags().BoolVar(&pricesFuzzy, "fuzzy", false, "Match products by title when SKU and barcode are not found")
+	pricesImportCmd.Flags().Float64Var(&pricesFuzzyThreshold, "fuzzy-threshold", prices.DefaultFuzzyThreshold, "Minimum title similarity (0-1) for --fuzzy")
+	pricesImportCmd.Flags().BoolVar(&pricesNoNotify, "no-notify", false, "Do not send undercut alerts to the notify webhook")
 
 	pricesCheckCmd.Flags().StringVar(&pricesSKU, "sku", "", "Product SKU to check")
 	pricesCheckCmd.Flags().StringVar(&pricesBarcode, "barcode", "", "Product barcode to check")
 	pricesCheckCmd.Flags().IntVar(&pricesDays, "days", 30, "Number of days of history to show")
+
+	pricesAlertsCmd.Flags().Float64Var(&pricesThreshold, "threshold", 10.0, "Price difference threshold in percent")
+	pricesAlertsCmd.Flags().StringVar(&pricesVendor, "vendor", "", "Filter by vendor")
+	pricesAlertsCmd.Flags().IntVar(&pricesAlertDays, "days", 7, "Only use competitor prices observed in the last N days")
+
+	pricesTrendsCmd.Flags().StringVar(&pricesSKU, "sku", "", "Product SKU (required)")
+	pricesTrendsCmd.Flags().IntVar(&pricesDays, "days", 30, "Number of days of history")
+	pricesTrendsCmd.Flags().StringVarP(&pricesOutput, "output", "o", "table", "Output format: table, json, csv")
+	pricesTrendsCmd.MarkFlagRequired("sku")
+
+	pricesScrapeCmd.Flags().StringVar(&pricesScrapeCompetitor, "competitor", "", "Only scrape this competitor")
+	pricesScrapeCmd.Flags().IntVar(&pricesScrapeLimit, "limit", 0, "Maximum products per competitor (0 = all)")
+	pricesScrapeCmd.Flags().BoolVar(&pricesScrapeDryRun, "dry-run", false, "Scrape and show prices without saving them")
+
+	pricesWatchCmd.Flags().DurationVar(&pricesWatchInterval, "interval", 0, "Check again at this interval until interrupted (0 = once)")
+	pricesWatchCmd.Flags().Float64Var(&pricesWatchMinDiff, "min-diff", 0, "Minimum percent above the market min (default: notify.min_diff_percent)")
+	pricesWatchCmd.Flags().IntVar(&pricesAlertDays, "days", 7, "Only use competitor prices observed in the last N days")
+	pricesWatchCmd.Flags().BoolVar(&pricesWatchDryRun, "dry-run", false, "Show the alerts without sending them")
 }
 
 func runPricesImport(cmd *cobra.Command, args []string) error {
@@ -73,13 +197,47 @@ func runPricesImport(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("file not found: %s", csvFile)
 	}
 
+	format, err := priceImportFormat(pricesFormat, csvFile)
+	if err != nil {
+		return err
+	}
+
+	delimiter, err := parseDelimiter(pricesDelimiter)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Parsing: %s\n", filepath.Base(csvFile))
 
-	// Parse CSV
-	parser := prices.NewParser()
-	result, err := parser.ParseFile(csvFile)
+	var result *prices.ParseResult
+	if format == "json" {
+		result, err = prices.NewJSONParser().ParseFile(csvFile)
+		if err != nil {
+			return fmt.Errorf("failed to parse JSON: %w", err)
+		}
+	} else {
+		parser := prices.NewParser()
+		parser.SetDelimiter(delimiter)
+		result, err = parser.ParseFile(csvFile)
+		if err != nil {
+			return fmt.Errorf("failed to parse CSV: %w", err)
+		}
+	}
+
+	// Normalize foreign currency prices before they reach market stats
+	cfg, err := config.Load()
 	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %w", err)
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	converter := newCurrencyConverter(cfg)
+	var currencyWarnings []string
+	result.Records, currencyWarnings = converter.Normalize(ctx, result.Records)
+	result.Errors = append(result.Errors, currencyWarnings...)
+	converted := 0
+	for _, rec := range result.Records {
+		if rec.OriginalCurrency != "" {
+			converted++
+		}
 	}
 
 	// Show parse summary
@@ -87,6 +245,14 @@ func runPricesImport(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Products:     %d\n", result.ProductCount)
 	fmt.Printf("  Competitors:  %d\n", len(result.Competitors))
 	fmt.Printf("  Observations: %d\n", len(result.Records))
+	if format == "json" {
+		fmt.Printf("  Format:       JSON, %s\n", result.Encoding)
+	} else {
+		fmt.Printf("  Format:       %s, %s\n", delimiterName(result.Delimiter), result.Encoding)
+	}
+	if converted > 0 {
+		fmt.Printf("  Converted:    %d prices to %s\n", converted, converter.Base)
+	}
 
 	if len(result.Errors) > 0 {
 		color.Yellow("  Warnings:     %d", len(result.Errors))
@@ -146,22 +312,47 @@ func runPricesImport(cmd *cobra.Command, args []string) error {
 	}
 
 	productMap := make(map[string]uuid.UUID)
+	titles := make(map[uuid.UUID]string)
 	for _, p := range allProducts {
 		if p.ID != "" {
 			id, err := uuid.Parse(p.ID)
 			if err == nil {
 				productMap[p.SKU] = id
 				if p.Barcode != "" {
-					productMap[p.Barcode] = id
+					productMap[prices.BarcodeKey(p.Barcode)] = id
+				}
+				if p.Title != "" {
+					titles[id] = p.Title
 				}
 			}
 		}
 	}
 	fmt.Printf("  Found %d products in database\n", len(allProducts))
 
+	// Fall back to title matching for records without a SKU/barcode match
+	var fuzzyMatches map[string]prices.FuzzyMatch
+	var unmatched []string
+	if pricesFuzzy {
+		index := prices.NewTitleIndex(titles, pricesFuzzyThreshold)
+		fuzzyMatches, unmatched = index.MatchRecords(result.Records, productMap)
+		fmt.Printf("  Fuzzy title matches: %d (threshold %.2f)\n", len(fuzzyMatches), pricesFuzzyThreshold)
+	} else {
+		unmatched = unmatchedSKUs(result.Records, productMap)
+	}
+
+	if len(unmatched) > 0 {
+		color.Yellow("  %d products not found in catalog:", len(unmatched))
+		for _, sku := range unmatched[:min(10, len(unmatched))] {
+			fmt.Printf("    • %s\n", sku)
+		}
+		if len(unmatched) > 10 {
+			fmt.Printf("    ... and %d more\n", len(unmatched)-10)
+		}
+	}
+
 	// Create competitor product links
 	fmt.Println("\nCreating competitor product links...")
-	links := prices.ConvertToCompetitorProducts(result.Records, productMap, competitorMap)
+	links := prices.ConvertToCompetitorProducts(result.Records, productMap, fuzzyMatches, competitorMap)
 	if len(links) > 0 {
 		linkRepo := postgres.NewCompetitorProductRepo(client)
 		linkCount, err := linkRepo.BulkUpsert(ctx, links)
@@ -178,7 +369,8 @@ func runPricesImport(cmd *cobra.Command, args []string) error {
 
 	// Import price observations
 	fmt.Println("\nImporting price observations...")
-	observations := prices.ConvertToPriceObservations(result.Records, productMap, competitorMap)
+	observations := prices.ConvertToPriceObservations(result.Records, productMap, fuzzyMatches, competitorMap)
+	var newObservations, refreshedObservations int
 
 	if len(observations) > 0 {
 		priceRepo := postgres.NewPriceObservationRepo(client)
@@ -191,23 +383,23 @@ func runPricesImport(cmd *cobra.Command, args []string) error {
 			progressbar.OptionClearOnFinish(),
 		)
 
-		// Batch insert
+		// Batch upsert
 		batchSize := 1000
-		totalInserted := 0
 		for i := 0; i < len(observations); i += batchSize {
 			end := min(i+batchSize, len(observations))
 			batch := observations[i:end]
 
-			count, err := priceRepo.BulkCreate(ctx, batch)
+			inserted, updated, err := priceRepo.BulkUpsert(ctx, batch)
 			if err != nil {
-				return fmt.Errorf("failed to insert observations: %w", err)
+				return fmt.Errorf("failed to import observations: %w", err)
 			}
-			totalInserted += count
+			newObservations += inserted
+			refreshedObservations += updated
 			bar.Add(len(batch))
 		}
 
 		bar.Finish()
-		color.Green("✓ %d price observations imported", totalInserted)
+		color.Green("✓ %d price observations imported (%d new, %d refreshed)", newObservations+refreshedObservations, newObservations, refreshedObservations)
 	} else {
 		color.Yellow("No matching products found for price import")
 		fmt.Println("Ensure products are imported before importing prices")
@@ -219,20 +411,74 @@ func runPricesImport(cmd *cobra.Command, args []string) error {
 		Action:    "prices_import",
 		Source:    filepath.Base(csvFile),
 		Count:     len(observations),
-		Details:   fmt.Sprintf("Imported %d observations from %d products across %d competitors", len(observations), result.ProductCount, len(result.Competitors)),
+		Details:   fmt.Sprintf("Imported %d observations (%d new, %d refreshed) from %d products across %d competitors", len(observations), newObservations, refreshedObservations, result.ProductCount, len(result.Competitors)),
 		StartedAt: time.Now(),
 	})
 
 	// Summary
 	fmt.Println("\n" + color.CyanString("Import Summary"))
-	fmt.Printf("  Products matched: %d/%d\n", countMatchedProducts(result.Records, productMap), result.ProductCount)
+	exactMatched := countMatchedProducts(result.Records, productMap)
+	fmt.Printf("  Products matched: %d/%d\n", exactMatched+len(fuzzyMatches), result.ProductCount)
+	if pricesFuzzy {
+		fmt.Printf("    Exact:          %d\n", exactMatched)
+		fmt.Printf("    Fuzzy title:    %d\n", len(fuzzyMatches))
+	}
 	fmt.Printf("  Competitors:      %d\n", len(competitorMap))
 	fmt.Printf("  Links created:    %d\n", len(links))
 	fmt.Printf("  Observations:     %d\n", len(observations))
+	fmt.Printf("    New:            %d\n", newObservations)
+	fmt.Printf("    Refreshed:      %d\n", refreshedObservations)
+
+	if !pricesNoNotify && len(observations) > 0 {
+		imported := make(map[uuid.UUID]bool, len(observations))
+		for _, obs := range observations {
+			imported[obs.ProductID] = true
+		}
+		if err := notifyImportUndercuts(ctx, client, imported); err != nil {
+			color.Yellow("\nWarning: undercut alerts failed: %v", err)
+		}
+	}
 
 	return nil
 }
 
+// notifyImportUndercuts sends undercut alerts for imported products when a
+// notify webhook is configured. Undercuts already alerted at the same prices
+// are not sent again.
+func notifyImportUndercuts(ctx context.Context, client *postgres.Client, productIDs map[uuid.UUID]bool) error {
+	cfg, err := config.Load()
+	if err != nil || cfg.Notify.WebhookURLEnv == "" {
+		return err
+	}
+	webhook, err := newNotifyWebhook(cfg)
+	if err != nil {
+		return err
+	}
+
+	undercuts, checked, err := findUndercuts(ctx, client, cfg.Notify.MinDiff(), 7, productIDs)
+	if err != nil {
+		return err
+	}
+
+	alerts := postgres.NewUndercutAlertRepo(client)
+	if _, err := alerts.Resolve(ctx, checked, undercuts); err != nil {
+		return err
+	}
+	fresh, err := alerts.Unsent(ctx, undercuts)
+	if err != nil || len(fresh) == 0 {
+		return err
+	}
+
+	sent, err := sendUndercuts(ctx, webhook, fresh)
+	if sent > 0 {
+		color.Green("✓ %d undercut alerts sent", sent)
+	}
+	if recordErr := alerts.Record(ctx, fresh[:sent]); recordErr != nil {
+		return errors.Join(err, recordErr)
+	}
+	return err
+}
+
 func runPricesCheck(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -330,26 +576,19 @@ func runPricesCheck(cmd *cobra.Command, args []string) error {
 	table.Render()
 
 	// Calculate market position
-	if product.Price != nil && len(latestPrices) > 0 {
-		var minPrice, maxPrice, sumPrice float64
-		minPrice = latestPrices[0].Price
-		for _, p := range latestPrices {
-			if p.Price < minPrice {
-				minPrice = p.Price
-			}
-			if p.Price > maxPrice {
-				maxPrice = p.Price
-			}
-			sumPrice += p.Price
-		}
-		avgPrice := sumPrice / float64(len(latestPrices))
+	stats, err := priceRepo.GetMarketStats(ctx, productID, pricesDays)
+	if err != nil {
+		return fmt.Errorf("failed to get market stats: %w", err)
+	}
 
-		fmt.Println("\n" + color.CyanString("Market Position"))
-		fmt.Printf("  Market Min: %.2f\n", minPrice)
-		fmt.Printf("  Market Max: %.2f\n", maxPrice)
-		fmt.Printf("  Market Avg: %.2f\n", avgPrice)
+	if product.Price != nil && stats != nil && stats.AvgPrice > 0 {
+		fmt.Println("\n" + color.CyanString("Market Position") + fmt.Sprintf(" (last %d days)", pricesDays))
+		fmt.Printf("  Market Min: %.2f\n", stats.MinPrice)
+		fmt.Printf("  Market Max: %.2f\n", stats.MaxPrice)
+		fmt.Printf("  Market Avg: %.2f\n", stats.AvgPrice)
+		fmt.Printf("  Competitors: %d (%d observations)\n", stats.CompetitorCount, stats.Observations)
 
-		diff := ((product.Price.Amount - avgPrice) / avgPrice) * 100
+		diff := ((product.Price.Amount - stats.AvgPrice) / stats.AvgPrice) * 100
 		if diff > 0 {
 			color.Yellow("  Our Price: %.1f%% above market average", diff)
 		} else {
@@ -360,6 +599,105 @@ func runPricesCheck(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runPricesAlerts(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// Connect to database
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	productRepo := postgres.NewProductRepo(client)
+	products, err := productRepo.GetAll(ctx, database.QueryOptions{Vendor: pricesVendor})
+	if err != nil {
+		return fmt.Errorf("failed to get products: %w", err)
+	}
+
+	priceRepo := postgres.NewPriceObservationRepo(client)
+	allStats, err := priceRepo.GetAllMarketStats(ctx, pricesAlertDays)
+	if err != nil {
+		return fmt.Errorf("failed to get market stats: %w", err)
+	}
+
+	type priceAlert struct {
+		sku         string
+		ownPrice    float64
+		stats       *database.MarketStats
+		diffPercent float64
+	}
+
+	var alerts []priceAlert
+	for _, p := range products {
+		if p.Price == nil || p.Price.Amount <= 0 {
+			continue
+		}
+		id, err := uuid.Parse(p.ID)
+		if err != nil {
+			continue
+		}
+		stats := allStats[id]
+		if stats == nil || stats.AvgPrice <= 0 {
+			continue
+		}
+
+		diffPercent := (p.Price.Amount - stats.AvgPrice) / stats.AvgPrice * 100
+		if diffPercent > pricesThreshold || diffPercent < -pricesThreshold {
+			alerts = append(alerts, priceAlert{sku: p.SKU, ownPrice: p.Price.Amount, stats: stats, diffPercent: diffPercent})
+		}
+	}
+
+	if len(alerts) == 0 {
+		color.Green("✓ No price alerts (all products within %.0f%% of market average)", pricesThreshold)
+		return nil
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].diffPercent > alerts[j].diffPercent
+	})
+
+	fmt.Printf("Found %d products with price difference > %.0f%% (last %d days):\n\n", len(alerts), pricesThreshold, pricesAlertDays)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SKU", "Our Price", "Market Min", "Market Avg", "Competitors", "Difference"})
+	table.SetBorder(false)
+
+	aboveMarket := 0
+	belowMarket := 0
+
+	for _, a := range alerts {
+		var diffStr string
+		if a.diffPercent > 0 {
+			diffStr = color.RedString("+%.1f%%", a.diffPercent)
+			aboveMarket++
+		} else {
+			diffStr = color.GreenString("%.1f%%", a.diffPercent)
+			belowMarket++
+		}
+
+		table.Append([]string{
+			a.sku,
+			fmt.Sprintf("%.2f", a.ownPrice),
+			fmt.Sprintf("%.2f", a.stats.MinPrice),
+			fmt.Sprintf("%.2f", a.stats.AvgPrice),
+			fmt.Sprintf("%d", a.stats.CompetitorCount),
+			diffStr,
+		})
+	}
+
+	table.Render()
+
+	fmt.Printf("\nSummary: %d above market, %d below market\n", aboveMarket, belowMarket)
+
+	return nil
+}
+
 func runPricesSummary(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -443,9 +781,538 @@ func countMatchedProducts(records []prices.CSVRecord, productMap map[string]uuid
 	return len(matched)
 }
 
+// unmatchedSKUs returns the SKUs of records with no SKU or barcode match
+func unmatchedSKUs(records []prices.CSVRecord, productMap map[string]uuid.UUID) []string {
+	var skus []string
+	seen := make(map[string]bool)
+	for _, rec := range records {
+		if seen[rec.SKU] {
+			continue
+		}
+		seen[rec.SKU] = true
+
+		if _, ok := productMap[rec.SKU]; ok {
+			continue
+		}
+		if _, ok := productMap[rec.Barcode]; ok && rec.Barcode != "" {
+			continue
+		}
+		skus = append(skus, rec.SKU)
+	}
+	return skus
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
 	}
 	return b
 }
+
+// parseDelimiter converts the --delimiter flag to a rune; "" means detect
+func parseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case ",", ";":
+		return rune(s[0]), nil
+	case "tab", "\\t", "\t":
+		return '\t', nil
+	default:
+		return 0, fmt.Errorf("invalid delimiter %q (use , ; or tab)", s)
+	}
+}
+
+// priceImportFormat resolves the --format flag, detecting JSON from a .json
+// extension when it is unset
+func priceImportFormat(format, path string) (string, error) {
+	switch strings.ToLower(format) {
+	case "":
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			return "json", nil
+		}
+		return "csv", nil
+	case "csv":
+		return "csv", nil
+	case "json":
+		return "json", nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (use csv or json)", format)
+	}
+}
+
+// delimiterName returns a printable name for a CSV delimiter
+func delimiterName(r rune) string {
+	if r == '\t' {
+		return "tab-separated"
+	}
+	return fmt.Sprintf("'%c'-separated", r)
+}
+
+func runPricesTrends(cmd *cobra.Command, args []string) error {
+	switch pricesOutput {
+	case "table", "json", "csv":
+	default:
+		return fmt.Errorf("unsupported output format %q (use table, json or csv)", pricesOutput)
+	}
+	if pricesDays <= 0 {
+		return fmt.Errorf("--days must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	product, err := postgres.NewProductRepo(client).GetBySKU(ctx, pricesSKU)
+	if err != nil {
+		return fmt.Errorf("failed to find product: %w", err)
+	}
+	if product == nil {
+		return fmt.Errorf("product not found: %s", pricesSKU)
+	}
+
+	productID, _ := uuid.Parse(product.ID)
+	trends, err := postgres.NewPriceObservationRepo(client).GetDailyTrends(ctx, productID, pricesDays)
+	if err != nil {
+		return fmt.Errorf("failed to get trends: %w", err)
+	}
+
+	if pricesOutput == "table" {
+		fmt.Printf("\nPrice trends for %s (last %d days):\n\n", product.SKU, pricesDays)
+
+		if len(trends) == 0 {
+			color.Yellow("No price observations found")
+			fmt.Println("\nImport competitor prices first:")
+			fmt.Println("  badops prices import <csv-file>")
+			return nil
+		}
+	}
+
+	return writePriceTrends(pricesOutput, trends)
+}
+
+func runPricesScrape(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	competitorRepo := postgres.NewCompetitorRepo(client)
+	competitors, err := competitorRepo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get competitors: %w", err)
+	}
+
+	var enabled []*database.Competitor
+	for _, c := range competitors {
+		if pricesScrapeCompetitor != "" && !strings.EqualFold(c.Name, pricesScrapeCompetitor) {
+			continue
+		}
+		if c.ScrapeEnabled {
+			enabled = append(enabled, c)
+		} else if pricesScrapeCompetitor != "" {
+			return fmt.Errorf("scraping is not enabled for %s (see 'badops competitors scrape-config')", c.Name)
+		}
+	}
+	if len(enabled) == 0 {
+		if pricesScrapeCompetitor != "" {
+			return fmt.Errorf("competitor not found: %s", pricesScrapeCompetitor)
+		}
+		color.Yellow("No competitors have scraping enabled")
+		fmt.Println("\nConfigure one with:")
+		fmt.Println("  badops competitors scrape-config \"Competitor Name\" --enable price_selector=.price")
+		return nil
+	}
+
+	// Products by ID, for the SKU and barcode in product URL templates
+	allProducts, err := postgres.NewProductRepo(client).GetAll(ctx, database.QueryOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get products: %w", err)
+	}
+	products := make(map[uuid.UUID]*models.EnhancedProduct, len(allProducts))
+	for _, p := range allProducts {
+		if id, err := uuid.Parse(p.ID); err == nil {
+			products[id] = p
+		}
+	}
+
+	linkRepo := postgres.NewCompetitorProductRepo(client)
+	priceRepo := postgres.NewPriceObservationRepo(client)
+	scraper := prices.NewScraper()
+	var totalScraped, totalFailed int
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	converter := newCurrencyConverter(cfg)
+
+	for _, competitor := range enabled {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("\n%s\n", color.CyanString(competitor.Name))
+
+		scrapeCfg, err := prices.ParseScrapeConfig(competitor.ScrapeConfig, competitor.Website)
+		if err != nil {
+			color.Yellow("  ⚠ Skipped: invalid scrape_config: %v", err)
+			continue
+		}
+
+		links, err := linkRepo.GetByCompetitor(ctx, competitor.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get products for %s: %w", competitor.Name, err)
+		}
+
+		type target struct {
+			productID uuid.UUID
+			sku       string
+			url       string
+		}
+		var targets []target
+		noURL := 0
+		for _, link := range links {
+			p, ok := products[link.ProductID]
+			if !link.IsActive || !ok {
+				continue
+			}
+			pageURL := scrapeCfg.ProductPageURL(link.URL, p.SKU, p.Barcode, link.CompetitorSKU)
+			if pageURL == "" {
+				noURL++
+				continue
+			}
+			targets = append(targets, target{productID: link.ProductID, sku: p.SKU, url: pageURL})
+			if pricesScrapeLimit > 0 && len(targets) >= pricesScrapeLimit {
+				break
+			}
+		}
+		if noURL > 0 {
+			color.Yellow("  %d linked products have no URL and scrape_config has no product_url for them", noURL)
+		}
+		if len(targets) == 0 {
+			fmt.Println("  No products to scrape")
+			continue
+		}
+		fmt.Printf("  Scraping %d products (%s between requests)\n", len(targets), scrapeCfg.RateLimit)
+
+		bar := progressbar.NewOptions(len(targets),
+			progressbar.OptionSetDescription("  Scraping"),
+			progressbar.OptionSetWidth(40),
+			progressbar.OptionShowCount(),
+			progressbar.OptionClearOnFinish(),
+		)
+
+		var observations []*database.PriceObservation
+		var failures []string
+		var rows [][]string
+		for _, t := range targets {
+			result, err := scraper.Scrape(ctx, scrapeCfg, t.url)
+			if ctx.Err() != nil {
+				break
+			}
+			bar.Add(1)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", t.sku, err))
+				continue
+			}
+			price, err := converter.Convert(ctx, result.Price, scrapeCfg.Currency)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", t.sku, err))
+				continue
+			}
+
+			obs := &database.PriceObservation{
+				ProductID:    t.productID,
+				CompetitorID: competitor.ID,
+				Price:        price,
+				Currency:     converter.Base,
+				InStock:      result.InStock,
+				ObservedAt:   time.Now(),
+				Source:       prices.SourceScraper,
+			}
+			if scrapeCfg.Currency != converter.Base {
+				obs.OriginalPrice = &result.Price
+				obs.OriginalCurrency = scrapeCfg.Currency
+			}
+			observations = append(observations, obs)
+			stock := color.GreenString("yes")
+			if !result.InStock {
+				stock = color.RedString("no")
+			}
+			rows = append(rows, []string{t.sku, fmt.Sprintf("%.2f %s", result.Price, scrapeCfg.Currency), stock, truncateString(t.url, 60)})
+		}
+		bar.Finish()
+
+		totalScraped += len(observations)
+		totalFailed += len(failures)
+		if ctx.Err() != nil {
+			color.Yellow("  Interrupted after %d products", len(observations)+len(failures))
+		}
+
+		if pricesScrapeDryRun && len(rows) > 0 {
+			table := tablewriter.NewWriter(os.Stdout)
+			table.SetHeader([]string{"SKU", "Price", "In Stock", "URL"})
+			table.SetBorder(false)
+			table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+			table.SetAlignment(tablewriter.ALIGN_LEFT)
+			table.AppendBulk(rows)
+			table.Render()
+		}
+
+		if len(failures) > 0 {
+			color.Yellow("  %d products failed:", len(failures))
+			for _, f := range failures[:min(5, len(failures))] {
+				fmt.Printf("    • %s\n", f)
+			}
+			if len(failures) > 5 {
+				fmt.Printf("    ... and %d more\n", len(failures)-5)
+			}
+		}
+
+		if pricesScrapeDryRun {
+			fmt.Printf("  Dry run: %d prices not saved\n", len(observations))
+			continue
+		}
+
+		// Save with a fresh context so an interrupted run keeps what it scraped
+		saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		if len(observations) > 0 {
+			inserted, updated, err := priceRepo.BulkUpsert(saveCtx, observations)
+			if err != nil {
+				cancel()
+				return fmt.Errorf("failed to save prices for %s: %w", competitor.Name, err)
+			}
+			color.Green("  ✓ %d prices saved (%d new, %d refreshed)", inserted+updated, inserted, updated)
+		}
+
+		now := time.Now()
+		competitor.LastScraped = &now
+		if err := competitorRepo.Update(saveCtx, competitor); err != nil {
+			color.Yellow("  Warning: failed to update last scraped time: %v", err)
+		}
+
+		postgres.NewHistoryRepo(client).Add(saveCtx, &database.OperationHistory{
+			Action:    "prices_scrape",
+			Source:    competitor.Name,
+			Count:     len(observations),
+			Details:   fmt.Sprintf("Scraped %d prices, %d failed", len(observations), len(failures)),
+			StartedAt: now,
+		})
+		cancel()
+	}
+
+	fmt.Println("\n" + color.CyanString("Scrape Summary"))
+	fmt.Printf("  Competitors: %d\n", len(enabled))
+	fmt.Printf("  Scraped:     %d\n", totalScraped)
+	fmt.Printf("  Failed:      %d\n", totalFailed)
+	return nil
+}
+
+func runPricesWatch(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	minDiff := pricesWatchMinDiff
+	if minDiff <= 0 {
+		minDiff = cfg.Notify.MinDiff()
+	}
+
+	var webhook *notify.Webhook
+	if !pricesWatchDryRun {
+		if webhook, err = newNotifyWebhook(cfg); err != nil {
+			return err
+		}
+	}
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	// The last alert per product is kept in PostgreSQL, so an undercut is
+	// only sent again when its prices change, across runs and with prices
+	// import. A dry run records nothing and only remembers what it printed.
+	alerts := postgres.NewUndercutAlertRepo(client)
+	shown := make(map[string]bool)
+	for {
+		undercuts, _, err := findUndercuts(ctx, client, minDiff, pricesAlertDays, nil)
+		if err != nil {
+			return err
+		}
+
+		if !pricesWatchDryRun {
+			if _, err := alerts.Resolve(ctx, nil, undercuts); err != nil {
+				return err
+			}
+		}
+		fresh, err := alerts.Unsent(ctx, undercuts)
+		if err != nil {
+			return err
+		}
+		if pricesWatchDryRun {
+			key := func(u notify.Undercut) string {
+				return fmt.Sprintf("%s|%s|%.2f|%.2f", u.SKU, u.Competitor, u.OurPrice, u.MarketMin)
+			}
+			fresh = slices.DeleteFunc(fresh, func(u notify.Undercut) bool { return shown[key(u)] })
+			for _, u := range fresh {
+				shown[key(u)] = true
+			}
+		}
+
+		fmt.Printf("%s  %d products more than %.0f%% above the market min, %d new\n",
+			time.Now().Format("2006-01-02 15:04"), len(undercuts), minDiff, len(fresh))
+
+		if pricesWatchDryRun {
+			for _, u := range fresh {
+				fmt.Printf("  • %s: %.2f vs %.2f at %s (%s)\n", u.SKU, u.OurPrice, u.MarketMin, u.Competitor, color.RedString("+%.1f%%", u.DiffPercent))
+			}
+		} else if len(fresh) > 0 {
+			// Alerts that failed to send are retried on the next check
+			n, err := sendUndercuts(ctx, webhook, fresh)
+			if n > 0 {
+				color.Green("  ✓ %d alerts sent (%s)", n, webhook.Format())
+			}
+			if err != nil {
+				color.Yellow("  Warning: undercut alerts failed: %v", err)
+			}
+			if err := alerts.Record(ctx, fresh[:n]); err != nil {
+				return err
+			}
+		}
+
+		if pricesWatchInterval <= 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pricesWatchInterval):
+		}
+	}
+}
+
+// newCurrencyConverter creates the converter that normalizes competitor
+// prices to the configured base currency
+func newCurrencyConverter(cfg *config.Config) *prices.Converter {
+	var provider prices.RateProvider
+	if cfg.Currency.RatesURL != "" {
+		provider = prices.NewHTTPRates(cfg.Currency.RatesURL)
+	}
+	return prices.NewConverter(cfg.Currency.BaseCurrency(), cfg.Currency.Rates, provider)
+}
+
+// newNotifyWebhook creates the webhook notifier from the notify config
+func newNotifyWebhook(cfg *config.Config) (*notify.Webhook, error) {
+	if cfg.Notify.WebhookURLEnv == "" {
+		return nil, fmt.Errorf("no webhook configured (set notify.webhook_url_env)")
+	}
+	url, err := credentials.Resolve(nil, cfg.Notify.WebhookURLEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve webhook URL: %w", err)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL %s is not set", cfg.Notify.WebhookURLEnv)
+	}
+	return notify.NewWebhook(url, cfg.Notify.Format)
+}
+
+// findUndercuts returns the products priced more than minDiff percent above
+// the cheapest competitor's latest price in the last days, most undercut
+// first. A non-nil productIDs limits the check to those products, whose SKUs
+// are returned as checked; otherwise checked is nil.
+func findUndercuts(ctx context.Context, client *postgres.Client, minDiff float64, days int, productIDs map[uuid.UUID]bool) (undercuts []notify.Undercut, checked []string, err error) {
+	allStats, err := postgres.NewPriceObservationRepo(client).GetAllMarketStats(ctx, days)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get market stats: %w", err)
+	}
+
+	competitors, err := postgres.NewCompetitorRepo(client).GetAll(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get competitors: %w", err)
+	}
+	competitorNames := make(map[int]string, len(competitors))
+	for _, c := range competitors {
+		competitorNames[c.ID] = c.Name
+	}
+
+	products, err := postgres.NewProductRepo(client).GetAll(ctx, database.QueryOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	if productIDs != nil {
+		checked = []string{}
+	}
+	for _, p := range products {
+		id, err := uuid.Parse(p.ID)
+		if err != nil || productIDs != nil && !productIDs[id] {
+			continue
+		}
+		if productIDs != nil {
+			checked = append(checked, p.SKU)
+		}
+		if p.Price == nil || p.Price.Amount <= 0 {
+			continue
+		}
+		stats := allStats[id]
+		if stats == nil || stats.MinPrice <= 0 {
+			continue
+		}
+
+		diffPercent := (p.Price.Amount - stats.MinPrice) / stats.MinPrice * 100
+		if diffPercent <= minDiff {
+			continue
+		}
+		currency := p.Price.Currency
+		if currency == "" {
+			currency = "NOK"
+		}
+		undercuts = append(undercuts, notify.Undercut{
+			SKU:         p.SKU,
+			Title:       p.Title,
+			OurPrice:    p.Price.Amount,
+			MarketMin:   stats.MinPrice,
+			Competitor:  competitorNames[stats.MinCompetitorID],
+			DiffPercent: diffPercent,
+			Currency:    currency,
+			DetectedAt:  time.Now(),
+		})
+	}
+
+	sort.Slice(undercuts, func(i, j int) bool {
+		return undercuts[i].DiffPercent > undercuts[j].DiffPercent
+	})
+	return undercuts, checked, nil
+}
+
+// sendUndercuts posts each undercut, stopping at the first failure. Returns
+// the number sent.
+func sendUndercuts(ctx context.Context, webhook *notify.Webhook, undercuts []notify.Undercut) (int, error) {
+	for i, u := range undercuts {
+		if err := webhook.SendUndercut(ctx, u); err != nil {
+			return i, fmt.Errorf("%s: %w", u.SKU, err)
+		}
+	}
+	return len(undercuts), nil
+}
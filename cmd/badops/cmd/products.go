@@ -3,19 +3,36 @@ package cmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/internal/database/postgres"
 	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/metrics"
 	"github.com/badno/badops/internal/parser"
+	"github.com/badno/badops/internal/prices"
+	"github.com/badno/badops/internal/skurules"
 	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/internal/source/matrixify"
+	"github.com/badno/badops/internal/source/rest"
 	"github.com/badno/badops/internal/source/shopify"
+	"github.com/badno/badops/internal/source/woocommerce"
 	"github.com/badno/badops/internal/state"
 	"github.com/badno/badops/pkg/models"
 	"github.com/fatih/color"
+	"github.com/google/uuid"
 	"github.com/olekukonko/tablewriter"
 	"github.com/schollz/progressbar/v3"
 	"github.com/spf13/cobra"
@@ -27,6 +44,9 @@ var (
 	importSource     string
 	importLimit      int
 	importVendor     string
+	importSince      string
+	importFile       string
+	importIncremental bool
 )
 
 var productsCmd = &cobra.Command{
@@ -46,42 +66,369 @@ var parseCmd = &cobra.Command{
 var matchCmd = &cobra.Command{
 	Use:   "match",
 	Short: "Match products against Tiger.nl",
-	Long:  `Match parsed products against the Tiger.nl product catalog.`,
-	RunE:  runMatch,
+	Long: `Match parsed products against the Tiger.nl product catalog.
+
+Each match shows how it was found: barcode (the Tiger.nl page declares the
+product's GTIN), id (a Tiger.nl ID derived from the SKU) or name (keywords in
+the product name). Use --review to pick one of the alternate candidates for
+name matches and products scoring below 70%.`,
+	RunE: runMatch,
 }
 
 var lookupCmd = &cobra.Command{
 	Use:   "lookup [sku]",
 	Short: "Look up a single SKU on Tiger.nl",
-	Long:  `Look up a single SKU directly on Tiger.nl using ID-based matching.`,
-	Args:  cobra.ExactArgs(1),
-	RunE:  runLookup,
+	Long: `Look up a single SKU directly on Tiger.nl using ID-based matching.
+
+The match method, score and alternate candidates are shown. Use --pick to save
+one of the alternates as the product's match.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLookup,
+}
+
+var matchOverrideCmd = &cobra.Command{
+	Use:   "match-override <sku> [url]",
+	Short: "Pin a SKU to a Tiger.nl product page",
+	Long: `Pin a SKU to a specific Tiger.nl product page when the automatic match is
+wrong. Overrides are stored in output/.tiger-overrides.json and take precedence
+over every automated lookup (match, lookup, images compare/fetch, enhance),
+scoring 100% with method "manual". Without a URL the current override is shown.`,
+	Example: `  badops products match-override CO-T309012 https://tiger.nl/producten/badkameraccessoires/haak/309012-boston-haak/
+  badops products match-override CO-T309012 --clear`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runMatchOverride,
 }
 
+var (
+	matchReview        bool
+	lookupPick         int
+	matchOverrideClear bool
+	matchRefresh       bool
+	lookupRefresh      bool
+)
+
 var importCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import products from a source",
-	Long:  `Import products from Shopify or other configured sources.`,
-	RunE:  runImport,
+	Long: `Import products from Shopify or other configured sources.
+
+--source matrixify reads a Matrixify or Shopify export CSV (--file),
+--source woocommerce reads the WooCommerce REST API (sources.woocommerce)
+and --source rest reads any JSON API mapped in sources.rest; all merge into
+state the same way as a Shopify import.
+
+Ctrl-C (or SIGTERM) stops fetching and saves the products fetched so far.`,
+	SilenceUsage: true,
+	RunE:         runImport,
 }
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List products in state",
-	Long:  `Display all products currently in the state file.`,
+	Long: `Display all products currently in the state file, or in PostgreSQL with --db
+or database.use_db.
+
+Use --missing-images and --missing-description to build an enhancement worklist.`,
 	RunE:  runList,
 }
 
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search products in the database",
+	Long: `Full-text search over product titles, tags and descriptions in PostgreSQL.
+Words match as prefixes and results are ranked by relevance. SKUs and barcodes
+containing the query are listed first.`,
+	Example: `  badops products search "boston hook"
+  badops products search CO-T3090 --limit 10`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runSearch,
+}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive <sku>...",
+	Short: "Archive products dropped from the catalog",
+	Long: `Soft-delete products in the database. Archived products are hidden from
+listings and lookups but keep their price history, so analytics stay continuous.
+Re-importing an archived SKU restores it.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runArchive,
+}
+
+var dedupeCmd = &cobra.Command{
+	Use:   "dedupe",
+	Short: "Find and merge duplicate products",
+	Long: `Group products in the state whose SKUs differ only in case or surrounding
+whitespace, or that share a barcode, and list the merges. With --apply each
+group is merged into its richest record: that record's fields are kept and the
+images, properties, specifications and enhancements of the others are added.`,
+	Example: `  badops products dedupe
+  badops products dedupe --apply`,
+	SilenceUsage: true,
+	RunE:         runDedupe,
+}
+
+var dedupeApply bool
+
+var skuCandidatesCmd = &cobra.Command{
+	Use:   "sku-candidates <sku>",
+	Short: "Preview the IDs the SKU rules generate",
+	Long: `Show the Tiger.nl product IDs and NOBB numbers tried for a SKU. They are
+generated by the ordered rules in ~/.badops/sku-rules.yaml (or the file set in
+defaults.sku_rules_file), falling back to the built-in rules.
+
+Example file:
+  tiger:
+    - name: strip store prefix
+      strip_prefix: [CO-]
+    - name: new supplier prefix
+      match: ^NS
+      replace: ^NS(\d+)$
+      with: ["${1}"]
+  nobb:
+    - strip_prefix: [CO-T, CO-, T]
+      keep: true
+    - match: ^\d{6,7}$
+      pad: 8
+    - filter: ^\d{8}$
+
+Each rule applies to the candidates matching 'match' (all when unset): it
+strips the first matching prefix, applies the regex replace once per 'with'
+value, zero-pads numbers to 'pad' digits, and drops candidates not matching
+'filter'. With 'keep' the rule's input stays a candidate too. A section
+missing from the file keeps the built-in rules.`,
+	Example: `  badops products sku-candidates CO-T309012
+  badops products sku-candidates CO-T309012 --rules ./sku-rules.yaml`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runSKUCandidates,
+}
+
+var skuCandidatesRules string
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Report products with invalid fields",
+	Long: `Check every product in the state file, or in PostgreSQL with --db, for the
+problems that make exports and database writes skip it: an empty SKU, a
+negative price, a currency that is not a 3-letter code, a barcode that is not
+a numeric GTIN, and image URLs that do not parse. Barcodes with a wrong check
+digit are listed as warnings; they are still exported and stored.
+
+Exits non-zero when any product is invalid.`,
+	Example: `  badops products validate
+  badops products validate --db`,
+	SilenceUsage: true,
+	RunE:         runValidate,
+}
+
+var validateFromDB bool
+
+var marginsCmd = &cobra.Command{
+	Use:   "margins",
+	Short: "List products with thin or negative profit margins",
+	Long: `List products whose profit margin, (price - cost) / price, is below a
+percentage, lowest first. Products without a price or cost are left out.
+Reads the state file, or the stored profit_margin column in PostgreSQL with
+--db.`,
+	Example: `  badops products margins
+  badops products margins --below 0 --vendor Tiger
+  badops products margins --below 20 --db`,
+	SilenceUsage: true,
+	RunE:         runMargins,
+}
+
+var (
+	marginsBelow  float64
+	marginsVendor string
+	marginsFromDB bool
+)
+
+var priceSetCmd = &cobra.Command{
+	Use:   "price-set",
+	Short: "Reprice products from competitor prices by a rule",
+	Long: `Compute a target price per product from the latest competitor prices in
+PostgreSQL and show the changes. Nothing is written unless --apply is given;
+--apply updates the products table and, unless database.use_db is set, the
+JSON state file as well.
+
+A rule starts from market_min, market_avg, market_max, cost, price or a fixed
+number and applies +, -, * and / adjustments left to right; + and - also take
+percentages:
+
+  market_min+2%    match the cheapest competitor plus 2%
+  market_min-1     undercut the cheapest competitor by 1 NOK
+  market_avg*0.95  5% below the market average
+
+--floor is a rule of the same form; target prices below it are raised to it.
+Products the rule or floor cannot be computed for (no competitor prices in
+the last --days, or no cost) are left unchanged.`,
+	Example: `  badops products price-set --rule "market_min+2%"
+  badops products price-set --rule "market_min-1" --floor "cost*1.1"
+  badops products price-set --rule "market_min-1" --floor "cost*1.1" --vendor Tiger --apply`,
+	SilenceUsage: true,
+	RunE:         runPriceSet,
+}
+
+var (
+	priceSetRule   string
+	priceSetFloor  string
+	priceSetDays   int
+	priceSetVendor string
+	priceSetApply  bool
+)
+
+var showCmd = &cobra.Command{
+	Use:   "show <sku>",
+	Short: "Show everything known about one product",
+	Long: `Print a product's complete record from the state file, or from PostgreSQL
+with --db or database.use_db: basic fields, price and margin, dimensions and weight,
+specifications, properties grouped by source, images with their status,
+suppliers, packaging and the enhancement history, oldest first.
+
+With --db, suppliers come from product_suppliers and the history from the
+enhancement log. --json prints the record as JSON instead.`,
+	Example: `  badops products show CO-T309012
+  badops products show CO-T309012 --db
+  badops products show CO-T309012 --json | jq .images`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runShow,
+}
+
+var (
+	showFromDB bool
+	showJSON   bool
+)
+
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Add, remove or rename tags across products",
+	Long: `Bulk-edit product tags in the state file, or in PostgreSQL with --db or
+database.use_db, where only the tags column is written. Products are selected
+with --vendor, --status and --sku; remove and rename act on every product
+carrying the tag unless filtered. Tags match case-insensitively, like
+Shopify's.
+
+Changes are listed before they are saved; --dry-run only lists them.`,
+	Example: `  badops products tag add clearance --vendor Tiger --dry-run
+  badops products tag add clearance --vendor Tiger
+  badops products tag remove clearance --db
+  badops products tag rename "Bad & Spa" bad-og-spa`,
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add <tag>",
+	Short: "Add a tag to the selected products",
+	Long: `Add a tag to the selected products that don't have it yet. Without
+--vendor, --status or --sku, pass --all to tag every product.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagOp(cmd, tagOp{action: "add", tag: args[0]})
+	},
+}
+
+var tagRemoveCmd = &cobra.Command{
+	Use:          "remove <tag>",
+	Short:        "Remove a tag from the products carrying it",
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagOp(cmd, tagOp{action: "remove", tag: args[0]})
+	},
+}
+
+var tagRenameCmd = &cobra.Command{
+	Use:   "rename <old> <new>",
+	Short: "Rename a tag on the products carrying it",
+	Long: `Replace a tag with another on every selected product carrying it. A
+product that already has the new tag just loses the old one.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTagOp(cmd, tagOp{action: "rename", tag: args[0], newTag: args[1]})
+	},
+}
+
+var (
+	tagVendor string
+	tagStatus string
+	tagSKUs   []string
+	tagAll    bool
+	tagFromDB bool
+	tagDryRun bool
+)
+
+var (
+	listFromDB             bool
+	listMissingImages      bool
+	listMissingDescription bool
+)
+
+var (
+	searchLimit  int
+	searchVendor string
+)
+
 func init() {
-	importCmd.Flags().StringVar(&importSource, "source", "shopify", "Source to import from (shopify)")
+	listCmd.Flags().BoolVar(&listFromDB, "db", false, "List products from PostgreSQL instead of the state file")
+	listCmd.Flags().BoolVar(&listMissingImages, "missing-images", false, "Only list products without images")
+	listCmd.Flags().BoolVar(&listMissingDescription, "missing-description", false, "Only list products without a description")
+
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 25, "Maximum results")
+	searchCmd.Flags().StringVar(&searchVendor, "vendor", "", "Only search products from this vendor")
+
+	matchCmd.Flags().BoolVar(&matchReview, "review", false, "Prompt to pick an alternate for name matches and products scoring below 70%")
+	lookupCmd.Flags().IntVar(&lookupPick, "pick", 0, "Save alternate n as the product's match")
+	matchCmd.Flags().BoolVar(&matchRefresh, "force-refresh", false, "Ignore cached Tiger.nl lookups and fetch them again (fresh results are re-cached)")
+	lookupCmd.Flags().BoolVar(&lookupRefresh, "force-refresh", false, "Ignore the cached Tiger.nl lookup and fetch it again")
+	matchOverrideCmd.Flags().BoolVar(&matchOverrideClear, "clear", false, "Remove the SKU's override")
+	dedupeCmd.Flags().BoolVar(&dedupeApply, "apply", false, "Merge the duplicates (default is a dry run)")
+	marginsCmd.Flags().Float64Var(&marginsBelow, "below", 15, "List products with a margin below this percentage")
+	marginsCmd.Flags().StringVar(&marginsVendor, "vendor", "", "Only list products from this vendor")
+	marginsCmd.Flags().BoolVar(&marginsFromDB, "db", false, "List products from PostgreSQL instead of the state file")
+	priceSetCmd.Flags().StringVar(&priceSetRule, "rule", "", "Price rule, e.g. \"market_min+2%\" or \"market_min-1\"")
+	priceSetCmd.Flags().StringVar(&priceSetFloor, "floor", "", "Minimum price rule, e.g. \"cost*1.1\"")
+	priceSetCmd.Flags().IntVar(&priceSetDays, "days", 7, "Only use competitor prices observed in the last N days")
+	priceSetCmd.Flags().StringVar(&priceSetVendor, "vendor", "", "Only reprice products from this vendor")
+	priceSetCmd.Flags().BoolVar(&priceSetApply, "apply", false, "Update the prices (default is a dry run)")
+	priceSetCmd.MarkFlagRequired("rule")
+	showCmd.Flags().BoolVar(&showFromDB, "db", false, "Read the product from PostgreSQL instead of the state file")
+	showCmd.Flags().BoolVar(&showJSON, "json", false, "Print the product as JSON")
+	tagCmd.PersistentFlags().StringVar(&tagVendor, "vendor", "", "Only change products from this vendor (case-insensitive)")
+	tagCmd.PersistentFlags().StringVar(&tagStatus, "status", "", "Only change products with this status")
+	tagCmd.PersistentFlags().StringSliceVar(&tagSKUs, "sku", nil, "Only change this SKU (repeatable)")
+	tagCmd.PersistentFlags().BoolVar(&tagFromDB, "db", false, "Change products in PostgreSQL instead of the state file")
+	tagCmd.PersistentFlags().BoolVar(&tagDryRun, "dry-run", false, "List the changes without saving them")
+	tagAddCmd.Flags().BoolVar(&tagAll, "all", false, "Tag every product when no filter is given")
+	tagCmd.AddCommand(tagAddCmd)
+	tagCmd.AddCommand(tagRemoveCmd)
+	tagCmd.AddCommand(tagRenameCmd)
+	validateCmd.Flags().BoolVar(&validateFromDB, "db", false, "Validate products in PostgreSQL instead of the state file")
+	skuCandidatesCmd.Flags().StringVar(&skuCandidatesRules, "rules", "", "Rules file to preview (default: configured or ~/.badops/sku-rules.yaml)")
+
+	importCmd.Flags().StringVar(&importSource, "source", "shopify", "Source to import from (shopify, matrixify, woocommerce, rest)")
+	importCmd.Flags().StringVar(&importFile, "file", "", "Export CSV for --source matrixify (default: sources.matrixify.file)")
 	importCmd.Flags().IntVar(&importLimit, "limit", 0, "Maximum products to import (0 = all)")
 	importCmd.Flags().StringVar(&importVendor, "vendor", "", "Only import products from this vendor")
+	importCmd.Flags().StringVar(&importSince, "since", "", "Only import products updated since this time (RFC3339 or YYYY-MM-DD)")
+	importCmd.Flags().BoolVar(&importIncremental, "incremental", false, "Only import products updated since the last import")
 
 	productsCmd.AddCommand(parseCmd)
 	productsCmd.AddCommand(matchCmd)
 	productsCmd.AddCommand(lookupCmd)
+	productsCmd.AddCommand(matchOverrideCmd)
 	productsCmd.AddCommand(importCmd)
 	productsCmd.AddCommand(listCmd)
+	productsCmd.AddCommand(showCmd)
+	productsCmd.AddCommand(tagCmd)
+	productsCmd.AddCommand(searchCmd)
+	productsCmd.AddCommand(archiveCmd)
+	productsCmd.AddCommand(dedupeCmd)
+	productsCmd.AddCommand(skuCandidatesCmd)
+	productsCmd.AddCommand(validateCmd)
+	productsCmd.AddCommand(marginsCmd)
+	productsCmd.AddCommand(priceSetCmd)
 }
 
 func runParse(cmd *cobra.Command, args []string) error {
@@ -206,7 +553,11 @@ func runMatch(cmd *cobra.Command, args []string) error {
 	color.Yellow("  Found %d products to match\n\n", len(products))
 
 	// Create matcher
-	m := matcher.NewTigerMatcher()
+	m, err := newTigerMatcher(cmd.Context())
+	if err != nil {
+		return err
+	}
+	m.GetScraper().SetForceRefresh(matchRefresh)
 
 	// Progress bar
 	bar := progressbar.NewOptions(len(products),
@@ -222,48 +573,76 @@ func runMatch(cmd *cobra.Command, args []string) error {
 	)
 
 	matched := 0
+	processed := 0
+	results := make([]matcher.MatchResult, len(products))
 	for i := range products {
-		url, score := m.Match(products[i])
-		products[i].MatchedURL = url
-		products[i].MatchScore = score
-		if score > 0.7 {
+		// Stop between products on Ctrl-C; the results so far are saved below
+		if cmd.Context().Err() != nil {
+			break
+		}
+		results[i] = m.Match(cmd.Context(), products[i])
+		if cmd.Context().Err() != nil {
+			break // The lookups were cut short; don't record a name-only match
+		}
+		products[i].MatchedURL = results[i].URL
+		products[i].MatchScore = results[i].Score
+		products[i].MatchMethod = results[i].Method
+		if results[i].Score > 0.7 {
 			matched++
 		}
+		processed++
 		bar.Add(1)
-		time.Sleep(150 * time.Millisecond) // Simulate API call
 	}
 	fmt.Println()
 	fmt.Println()
 
 	// Display results table
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"SKU", "Product Name", "Match Score", "Status"})
+	table.SetHeader([]string{"SKU", "Product Name", "Match Score", "Method", "Alternates", "Status"})
 	table.SetBorder(false)
 	table.SetHeaderColor(
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
 	)
 
-	for _, p := range products {
+	for i, p := range products {
 		name := p.Name
 		if len(name) > 30 {
 			name = name[:27] + "..."
 		}
 		scoreStr := fmt.Sprintf("%.0f%%", p.MatchScore*100)
-		status := color.GreenString("matched")
-		if p.MatchScore < 0.7 {
-			status = color.YellowString("review")
-		}
-		if p.MatchScore < 0.5 {
-			status = color.RedString("no match")
-		}
-		table.Append([]string{p.SKU, name, scoreStr, status})
+		table.Append([]string{p.SKU, name, scoreStr, p.MatchMethod,
+			fmt.Sprintf("%d", len(results[i].Alternates)), matchStatus(p.MatchScore)})
 	}
 	table.Render()
 	fmt.Println()
 
+	// Let the user resolve uncertain matches
+	if matchReview && processed == len(products) {
+		for i := range products {
+			// Barcode and ID matches were confirmed on a product page
+			confident := products[i].MatchScore >= 0.7 && results[i].Method != matcher.MethodName
+			if confident || len(results[i].Alternates) == 0 {
+				continue
+			}
+			if c, ok := pickAlternate(products[i], results[i]); ok {
+				if products[i].MatchScore > 0.7 {
+					matched--
+				}
+				if c.Score > 0.7 {
+					matched++
+				}
+				products[i].MatchedURL = c.URL
+				products[i].MatchScore = c.Score
+				products[i].MatchMethod = c.Method
+			}
+		}
+	}
+
 	// Summary
 	success.Printf("  ✓ Matched %d/%d products (%.0f%%)\n", matched, len(products), float64(matched)/float64(len(products))*100)
 	fmt.Println()
@@ -271,6 +650,10 @@ func runMatch(cmd *cobra.Command, args []string) error {
 	// Save updated state
 	if err := saveState(products); err != nil {
 		color.Red("  Warning: Could not save state: %v", err)
+	} else if processed < len(products) {
+		color.Yellow("  Interrupted, saved matches for %d products", processed)
+		fmt.Println()
+		return errInterrupted
 	}
 
 	// Save report
@@ -284,20 +667,102 @@ func runMatch(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runMatchOverride(cmd *cobra.Command, args []string) error {
+	sku := args[0]
+	m, err := newTigerMatcher(cmd.Context())
+	if err != nil {
+		return err
+	}
+	scraper := m.GetScraper()
+
+	switch {
+	case matchOverrideClear:
+		if len(args) > 1 {
+			return fmt.Errorf("--clear takes only a SKU")
+		}
+		cleared, err := scraper.ClearOverride(sku)
+		if err != nil {
+			return err
+		}
+		if !cleared {
+			color.Yellow("  No override for %s", sku)
+			return nil
+		}
+		color.Green("  ✓ Cleared override for %s", sku)
+	case len(args) == 1:
+		productURL, ok := scraper.Override(sku)
+		if !ok {
+			color.Yellow("  No override for %s", sku)
+			return nil
+		}
+		fmt.Printf("  %s → %s\n", sku, productURL)
+	default:
+		if err := scraper.SetOverride(sku, args[1]); err != nil {
+			return err
+		}
+		color.Green("  ✓ %s pinned to %s", sku, args[1])
+		color.Yellow("  Run 'badops products match' to update the stored match")
+	}
+	return nil
+}
+
+// matchStatus labels a match score as matched, review or no match
+func matchStatus(score float64) string {
+	switch {
+	case score < 0.5:
+		return color.RedString("no match")
+	case score < 0.7:
+		return color.YellowString("review")
+	}
+	return color.GreenString("matched")
+}
+
+// printAlternates lists a match's alternate candidates, numbered from 1
+func printAlternates(alternates []matcher.MatchCandidate) {
+	for i, c := range alternates {
+		fmt.Printf("    %d. %s (%.0f%%, %s)\n", i+1, c.URL, c.Score*100, c.Method)
+	}
+}
+
+// pickAlternate shows a product's match and alternates and asks which to
+// use. It returns false when the user keeps the current match.
+func pickAlternate(p models.Product, result matcher.MatchResult) (matcher.MatchCandidate, bool) {
+	fmt.Printf("  %s  %s\n", p.SKU, p.Name)
+	fmt.Printf("    Current: %s (%.0f%%, %s)\n", result.URL, result.Score*100, result.Method)
+	printAlternates(result.Alternates)
+	fmt.Printf("  Pick an alternate [1-%d], or Enter to keep: ", len(result.Alternates))
+
+	var choice string
+	fmt.Scanln(&choice)
+	fmt.Println()
+	n, err := strconv.Atoi(strings.TrimSpace(choice))
+	if err != nil || n < 1 || n > len(result.Alternates) {
+		return matcher.MatchCandidate{}, false
+	}
+	return result.Alternates[n-1], true
+}
+
 func saveState(products []models.Product) error {
 	// Use the new v2 state store
-	store := state.NewStore("")
-	store.Load() // Load existing state if any
+	store, err := openStore(context.Background(), true)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
 	store.ImportLegacyProducts(products, "csv")
 	return store.Save()
 }
 
 func loadState() ([]models.Product, error) {
 	// Try to load from v2 state store first
-	store := state.NewStore("")
-	if err := store.Load(); err == nil {
+	store, err := openStore(context.Background(), false)
+	if err == nil {
+		defer store.Close()
 		return store.ExportLegacyProducts(), nil
 	}
+	if errors.Is(err, state.ErrLocked) {
+		return nil, err
+	}
 
 	// Fall back to legacy v1 format
 	data, err := os.ReadFile(stateFile)
@@ -349,7 +814,11 @@ func runLookup(cmd *cobra.Command, args []string) error {
 	info.Printf("  SKU: %s\n\n", sku)
 
 	// Create matcher
-	m := matcher.NewTigerMatcher()
+	m, err := newTigerMatcher(cmd.Context())
+	if err != nil {
+		return err
+	}
+	m.GetScraper().SetForceRefresh(lookupRefresh)
 	skuMapper := m.GetSKUMapper()
 
 	// Get candidate IDs
@@ -360,40 +829,84 @@ func runLookup(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
+	// Match with the product's name and barcode when it is in the state
+	store, storeErr := openStore(cmd.Context(), lookupPick != 0)
+	if errors.Is(storeErr, state.ErrLocked) {
+		return storeErr
+	}
+	if storeErr == nil {
+		defer store.Close()
+	}
+	product := models.Product{SKU: sku}
+	if storeErr == nil {
+		if ep, ok := store.GetProduct(sku); ok {
+			product = *ep.ToLegacyProduct()
+		}
+	}
+
 	// Try to find the product
 	info.Println("  Searching Tiger.nl...")
 	fmt.Println()
 
-	product, err := m.LookupBySKU(sku, "")
-	if err != nil {
-		color.Red("  Error: %v", err)
-		return err
+	result := m.Match(cmd.Context(), product)
+
+	if result.Method == matcher.MethodName {
+		color.Yellow("  Product not found on Tiger.nl by barcode or ID")
+		color.Yellow("  Best guess from the product name: %s (%.0f%%)", result.URL, result.Score*100)
+	} else {
+		success.Printf("  ✓ Found: %s\n", result.URL)
+		success.Printf("  ✓ Method: %s (%.0f%%)\n", result.Method, result.Score*100)
+		if result.Product != nil {
+			success.Printf("  ✓ Images: %d (validated)\n", len(result.Product.ImageURLs))
+		}
 	}
+	fmt.Println()
 
-	if product == nil {
-		color.Yellow("  Product not found on Tiger.nl")
-		color.Yellow("  Try with a product name for better category matching:")
-		color.Yellow("    badops products lookup %s --name \"Tiger Boston Hook\"", sku)
-		return nil
+	if len(result.Alternates) > 0 {
+		info.Println("  Alternates:")
+		printAlternates(result.Alternates)
+		fmt.Println()
 	}
 
-	// Display results
-	success.Printf("  ✓ Found: %s\n", product.URL)
-	success.Printf("  ✓ Images: %d (validated)\n", len(product.ImageURLs))
-	fmt.Println()
+	if lookupPick != 0 {
+		if lookupPick < 1 || lookupPick > len(result.Alternates) {
+			return fmt.Errorf("no alternate %d (there are %d)", lookupPick, len(result.Alternates))
+		}
+		if storeErr != nil {
+			return storeErr
+		}
+		ep, ok := store.GetProduct(sku)
+		if !ok {
+			return fmt.Errorf("product not in state: %s", sku)
+		}
+		c := result.Alternates[lookupPick-1]
+		ep.LegacyMatchedURL = c.URL
+		ep.LegacyMatchScore = c.Score
+		ep.LegacyMatchMethod = c.Method
+		store.SetProduct(ep)
+		if err := store.Save(); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
+		}
+		success.Printf("  ✓ Saved match: %s\n\n", c.URL)
+	}
+
+	if result.Product == nil {
+		return nil
+	}
+	tigerProduct := result.Product
 
 	// Show first few image URLs
-	if len(product.ImageURLs) > 0 {
+	if len(tigerProduct.ImageURLs) > 0 {
 		info.Println("  Image URLs:")
 		maxShow := 5
-		if len(product.ImageURLs) < maxShow {
-			maxShow = len(product.ImageURLs)
+		if len(tigerProduct.ImageURLs) < maxShow {
+			maxShow = len(tigerProduct.ImageURLs)
 		}
 		for i := 0; i < maxShow; i++ {
-			fmt.Printf("    %d. %s\n", i+1, product.ImageURLs[i])
+			fmt.Printf("    %d. %s\n", i+1, tigerProduct.ImageURLs[i])
 		}
-		if len(product.ImageURLs) > maxShow {
-			fmt.Printf("    ... and %d more\n", len(product.ImageURLs)-maxShow)
+		if len(tigerProduct.ImageURLs) > maxShow {
+			fmt.Printf("    ... and %d more\n", len(tigerProduct.ImageURLs)-maxShow)
 		}
 	}
 	fmt.Println()
@@ -425,19 +938,49 @@ func runImport(cmd *cobra.Command, args []string) error {
 		cfg = config.DefaultConfig()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
 	defer cancel()
 
 	switch importSource {
 	case "shopify":
 		return importFromShopify(ctx, cfg, header, success)
+	case "matrixify":
+		return importFromMatrixify(ctx, cfg, success)
+	case "woocommerce":
+		return importFromWooCommerce(ctx, cfg, success)
+	case "rest":
+		return importFromREST(ctx, cfg, success)
 	default:
 		color.Red("  Error: Unsupported source: %s", importSource)
 		return fmt.Errorf("unsupported source: %s", importSource)
 	}
 }
 
+// parseSince parses a --since value as RFC3339 or a plain date
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %q (use RFC3339 or YYYY-MM-DD)", value)
+}
+
+// latestUpdatedAt returns the most recent source updated_at among products
+func latestUpdatedAt(products []models.EnhancedProduct) time.Time {
+	var latest time.Time
+	for _, p := range products {
+		if p.UpdatedAt.After(latest) {
+			latest = p.UpdatedAt
+		}
+	}
+	return latest
+}
+
 func importFromShopify(ctx context.Context, cfg *config.Config, header, success *color.Color) error {
+	start := time.Now()
+
 	// Create Shopify connector
 	conn := shopify.NewConnector(shopify.Config{
 		Store:     cfg.Sources.Shopify.Store,
@@ -448,7 +991,7 @@ func importFromShopify(ctx context.Context, cfg *config.Config, header, success
 
 	if err := conn.Connect(ctx); err != nil {
 		color.Red("  Error connecting to Shopify: %v", err)
-		color.Yellow("  Make sure %s environment variable is set", cfg.Sources.Shopify.APIKeyEnv)
+		color.Yellow("  Make sure %s is set", cfg.Sources.Shopify.APIKeyEnv)
 		return err
 	}
 	defer conn.Close()
@@ -456,19 +999,188 @@ func importFromShopify(ctx context.Context, cfg *config.Config, header, success
 	success.Println("  ✓ Connected to Shopify")
 	fmt.Println()
 
+	// Load state (needed for the incremental cursor)
+	store, err := openStore(ctx, true)
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return err
+	}
+	defer store.Close()
+
+	// Resolve the updated_at_min cursor
+	cursorKey := "shopify"
+	if importVendor != "" {
+		cursorKey += ":" + importVendor
+	}
+	var since time.Time
+	if importSince != "" {
+		var err error
+		since, err = parseSince(importSince)
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return err
+		}
+	} else if importIncremental {
+		if cursor, ok := store.GetImportCursor(cursorKey); ok {
+			since = cursor
+		} else {
+			color.Yellow("  No previous import cursor found, importing everything")
+		}
+	}
+	if !since.IsZero() {
+		color.Yellow("  Updated since: %s\n", since.Format(time.RFC3339))
+	}
+
 	// Fetch products
 	color.Yellow("  Fetching products...")
 
-	result, err := conn.FetchProducts(ctx, source.FetchOptions{
-		Limit:  importLimit,
-		Vendor: importVendor,
+	var products []models.EnhancedProduct
+	err = conn.FetchProductsStream(ctx, source.FetchOptions{
+		Limit:        importLimit,
+		Vendor:       importVendor,
+		UpdatedSince: since,
+	}, func(p models.EnhancedProduct) error {
+		products = append(products, p)
+		return nil
 	})
 
-	if err != nil {
+	// An interrupted import keeps the pages fetched so far
+	interrupted := errors.Is(ctx.Err(), context.Canceled)
+	if err != nil && !(interrupted && len(products) > 0) {
 		color.Red("  Error fetching products: %v", err)
 		return err
 	}
+	fmt.Println()
+	if interrupted {
+		color.Yellow("  Interrupted after fetching %d products", len(products))
+	}
+
+	if len(products) == 0 {
+		color.Yellow("  No products found matching criteria")
+		return nil
+	}
+
+	showImportedProducts(products)
+
+	// Save to state. The cursor only advances on complete runs, since a
+	// limited fetch may skip products updated before the newest one seen.
+	latest := latestUpdatedAt(products)
+	count := store.ImportProducts(products, "shopify")
+	if importLimit == 0 && !interrupted && !latest.IsZero() {
+		store.SetImportCursor(cursorKey, latest)
+	}
+	if err := store.Save(); err != nil {
+		color.Red("  Error saving state: %v", err)
+		return err
+	}
+	slog.Info("import finished", "source", "shopify", "products", count, "duration", time.Since(start))
+	metrics.ProductsProcessed("import", count)
+	metrics.Success("import")
+
+	success.Printf("  ✓ Imported %d products from Shopify\n", count)
+	success.Printf("  ✓ State saved to %s\n", storeLocation(store))
+	if interrupted {
+		color.Yellow("  Interrupted, saved %d products", count)
+		fmt.Println()
+		return errInterrupted
+	}
+	color.Yellow("  → Run 'badops enhance run' to enhance products")
+	fmt.Println()
+
+	return nil
+}
+
+// importFromMatrixify imports products from a Matrixify or Shopify export CSV
+// through the matrixify connector
+func importFromMatrixify(ctx context.Context, cfg *config.Config, success *color.Color) error {
+	file := importFile
+	if file == "" {
+		file = cfg.Sources.Matrixify.File
+	}
+	color.Yellow("  Reading %s...\n", file)
+	conn := matrixify.NewConnector(matrixify.Config{File: file})
+	return importFromConnector(ctx, conn, filepath.Base(file), success)
+}
+
+// importFromWooCommerce imports products from the configured WooCommerce store
+func importFromWooCommerce(ctx context.Context, cfg *config.Config, success *color.Color) error {
+	wc := cfg.Sources.WooCommerce
+	color.Yellow("  Connecting to %s...\n", wc.URL)
+	conn := woocommerce.NewConnector(woocommerce.Config{
+		URL:               wc.URL,
+		ConsumerKeyEnv:    wc.ConsumerKeyEnv,
+		ConsumerSecretEnv: wc.ConsumerSecretEnv,
+		Currency:          wc.Currency,
+	})
+	return importFromConnector(ctx, conn, "WooCommerce", success)
+}
+
+// importFromREST imports products from the JSON API mapped in sources.rest
+func importFromREST(ctx context.Context, cfg *config.Config, success *color.Color) error {
+	if err := cfg.Sources.REST.Validate(); err != nil {
+		color.Red("  Error: %v", err)
+		return err
+	}
+	label := cfg.Sources.REST.URL
+	if u, err := url.Parse(label); err == nil {
+		label = u.Host
+	}
+	color.Yellow("  Connecting to %s...\n", cfg.Sources.REST.URL)
+	return importFromConnector(ctx, rest.NewConnector(cfg.Sources.REST), label, success)
+}
+
+// importFromConnector fetches products from a source connector and merges
+// them into state. label names the source in the summary. The incremental
+// cursor is kept per connector and vendor, as for Shopify.
+func importFromConnector(ctx context.Context, conn source.Connector, label string, success *color.Color) error {
+	start := time.Now()
+
+	if err := conn.Connect(ctx); err != nil {
+		color.Red("  Error: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	store, err := openStore(ctx, true)
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return err
+	}
+	defer store.Close()
+
+	cursorKey := conn.Name()
+	if importVendor != "" {
+		cursorKey += ":" + importVendor
+	}
+	var since time.Time
+	if importSince != "" {
+		var err error
+		since, err = parseSince(importSince)
+		if err != nil {
+			color.Red("  Error: %v", err)
+			return err
+		}
+	} else if importIncremental {
+		if cursor, ok := store.GetImportCursor(cursorKey); ok {
+			since = cursor
+		} else {
+			color.Yellow("  No previous import cursor found, importing everything")
+		}
+	}
+	if !since.IsZero() {
+		color.Yellow("  Updated since: %s\n", since.Format(time.RFC3339))
+	}
 
+	color.Yellow("  Fetching products...")
+	result, err := conn.FetchProducts(ctx, source.FetchOptions{
+		Limit:        importLimit,
+		Vendor:       importVendor,
+		UpdatedSince: since,
+	})
+	if err != nil {
+		color.Red("  Error fetching products: %v", err)
+		return err
+	}
 	products := result.Products
 	fmt.Println()
 
@@ -477,6 +1189,31 @@ func importFromShopify(ctx context.Context, cfg *config.Config, header, success
 		return nil
 	}
 
+	showImportedProducts(products)
+
+	latest := latestUpdatedAt(products)
+	count := store.ImportProducts(products, conn.Name())
+	if importLimit == 0 && !latest.IsZero() {
+		store.SetImportCursor(cursorKey, latest)
+	}
+	if err := store.Save(); err != nil {
+		color.Red("  Error saving state: %v", err)
+		return err
+	}
+	slog.Info("import finished", "source", conn.Name(), "products", count, "duration", time.Since(start))
+	metrics.ProductsProcessed("import", count)
+	metrics.Success("import")
+
+	success.Printf("  ✓ Imported %d products from %s\n", count, label)
+	success.Printf("  ✓ State saved to %s\n", storeLocation(store))
+	color.Yellow("  → Run 'badops enhance run' to enhance products")
+	fmt.Println()
+
+	return nil
+}
+
+// showImportedProducts prints the fetched products, up to 20 of them
+func showImportedProducts(products []models.EnhancedProduct) {
 	// Progress bar for processing
 	bar := progressbar.NewOptions(len(products),
 		progressbar.OptionSetDescription("  Processing products"),
@@ -530,44 +1267,50 @@ func importFromShopify(ctx context.Context, cfg *config.Config, header, success
 
 	table.Render()
 	fmt.Println()
+}
 
-	// Save to state
-	store := state.NewStore("")
-	if err := store.Load(); err != nil {
-		color.Yellow("  Warning: Could not load existing state, creating new")
-	}
+func runList(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
 
-	count := store.ImportProducts(products, "shopify")
-	if err := store.Save(); err != nil {
-		color.Red("  Error saving state: %v", err)
+	var products []*models.EnhancedProduct
+	var store *state.Store
+	var dbImageCounts map[uuid.UUID]int
+
+	useDB, err := useDBState()
+	if err != nil {
 		return err
 	}
+	fromDB := listFromDB || useDB
+	if fromDB {
+		header.Println("\n  PRODUCTS IN DATABASE")
+		fmt.Println("  " + strings.Repeat("─", 50))
+		fmt.Println()
 
-	success.Printf("  ✓ Imported %d products from Shopify\n", count)
-	success.Println("  ✓ State saved to output/.badops-state.json")
-	color.Yellow("  → Run 'badops enhance run' to enhance products")
-	fmt.Println()
-
-	return nil
-}
-
-func runList(cmd *cobra.Command, args []string) error {
-	header := color.New(color.FgCyan, color.Bold)
+		products, dbImageCounts, err = listDBProducts()
+		if err != nil {
+			return err
+		}
+	} else {
+		header.Println("\n  PRODUCTS IN STATE")
+		fmt.Println("  " + strings.Repeat("─", 50))
+		fmt.Println()
 
-	header.Println("\n  PRODUCTS IN STATE")
-	fmt.Println("  " + strings.Repeat("─", 50))
-	fmt.Println()
+		// Load state
+		store = state.NewStore("")
+		if err := store.Load(); err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+		defer store.Close()
 
-	// Load state
-	store := state.NewStore("")
-	if err := store.Load(); err != nil {
-		color.Yellow("  No state file found. Run 'badops products parse' or 'badops products import' first.")
-		return nil
+		products = filterListProducts(store.GetAllProducts())
 	}
 
-	products := store.GetAllProducts()
 	if len(products) == 0 {
-		color.Yellow("  No products in state.")
+		if listMissingImages || listMissingDescription {
+			color.Green("  No products match the filters.")
+		} else {
+			color.Yellow("  No products found.")
+		}
 		return nil
 	}
 
@@ -598,6 +1341,11 @@ func runList(cmd *cobra.Command, args []string) error {
 			title = title[:22] + "..."
 		}
 		imgCount := fmt.Sprintf("%d", len(p.Images))
+		if fromDB {
+			// Images are not loaded with database listings, only counted
+			id, _ := uuid.Parse(p.ID)
+			imgCount = fmt.Sprintf("%d", dbImageCounts[id])
+		}
 		status := string(p.Status)
 		if p.Status == models.StatusEnhanced || p.Status == models.StatusApproved {
 			status = color.GreenString(status)
@@ -629,6 +1377,9 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Show recent history
+	if store == nil {
+		return nil
+	}
 	history := store.GetRecentHistory(5)
 	if len(history) > 0 {
 		header.Println("  RECENT HISTORY")
@@ -642,3 +1393,1186 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// listDBProducts loads products from PostgreSQL using the list filters,
+// along with the number of images of each product
+func listDBProducts() ([]*models.EnhancedProduct, map[uuid.UUID]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	productRepo := postgres.NewProductRepo(client)
+	products, err := productRepo.GetAll(ctx, database.QueryOptions{
+		OrderBy:            "sku",
+		OrderDir:           "ASC",
+		MissingImages:      listMissingImages,
+		MissingDescription: listMissingDescription,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imageCounts, err := postgres.NewImageRepo(client).CountByProduct(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return products, imageCounts, nil
+}
+
+// filterListProducts applies the list filters to products from state
+func filterListProducts(products []*models.EnhancedProduct) []*models.EnhancedProduct {
+	if !listMissingImages && !listMissingDescription {
+		return products
+	}
+
+	var filtered []*models.EnhancedProduct
+	for _, p := range products {
+		if listMissingImages && len(p.Images) > 0 {
+			continue
+		}
+		if listMissingDescription && strings.TrimSpace(p.Description) != "" {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
+
+func runSearch(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := strings.Join(args, " ")
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	productRepo := postgres.NewProductRepo(client)
+	products, err := productRepo.Search(ctx, query, database.QueryOptions{
+		Vendor: searchVendor,
+		Limit:  searchLimit,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(products) == 0 {
+		color.Yellow("No products match %q", query)
+		return nil
+	}
+
+	fmt.Printf("Found %d products matching %q:\n\n", len(products), query)
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"#", "SKU", "Title", "Vendor", "Price", "Status"})
+	table.SetBorder(false)
+
+	for i, p := range products {
+		title := p.Title
+		if len(title) > 40 {
+			title = title[:37] + "..."
+		}
+		price := "-"
+		if p.Price != nil && p.Price.Amount > 0 {
+			price = fmt.Sprintf("%.2f", p.Price.Amount)
+		}
+		table.Append([]string{fmt.Sprintf("%d", i+1), p.SKU, title, p.Vendor, price, string(p.Status)})
+	}
+
+	table.Render()
+	return nil
+}
+
+func runArchive(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	productRepo := postgres.NewProductRepo(client)
+	archived := 0
+	for _, sku := range args {
+		product, err := productRepo.GetBySKU(ctx, sku)
+		if err != nil {
+			color.Red("✗ %s: %v", sku, err)
+			continue
+		}
+		if product == nil {
+			color.Yellow("- %s: not found (or already archived)", sku)
+			continue
+		}
+
+		id, err := uuid.Parse(product.ID)
+		if err != nil {
+			color.Red("✗ %s: invalid product ID %q", sku, product.ID)
+			continue
+		}
+		if err := productRepo.Delete(ctx, id); err != nil {
+			color.Red("✗ %s: %v", sku, err)
+			continue
+		}
+
+		color.Green("✓ %s archived", sku)
+		archived++
+	}
+
+	if archived < len(args) {
+		return fmt.Errorf("archived %d of %d products", archived, len(args))
+	}
+	return nil
+}
+
+func runDedupe(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	// Merged duplicates are removed from the state file; the database store
+	// only writes products back
+	if err := requireJSONState("products dedupe"); err != nil {
+		return err
+	}
+	store := state.NewStore("")
+	if err := store.LoadForUpdate(); err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	defer store.Close()
+
+	header.Println("\n  DUPLICATE PRODUCTS")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	groups := store.FindDuplicates()
+	if len(groups) == 0 {
+		success.Printf("  ✓ No duplicates among %d products\n\n", store.Count())
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Key", "Keep", "Merge"})
+	table.SetBorder(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	duplicates := 0
+	for _, g := range groups {
+		quoted := make([]string, len(g.Duplicates))
+		for i, sku := range g.Duplicates {
+			quoted[i] = fmt.Sprintf("%q", sku)
+		}
+		table.Append([]string{g.Key, fmt.Sprintf("%q", g.Keep), strings.Join(quoted, ", ")})
+		duplicates += len(g.Duplicates)
+	}
+	table.Render()
+	fmt.Println()
+
+	if !dedupeApply {
+		color.Yellow("  Dry run: %d duplicates in %d groups. Run with --apply to merge them.\n\n", duplicates, len(groups))
+		return nil
+	}
+
+	removed := store.MergeDuplicates(groups)
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	success.Printf("  ✓ Merged %d duplicates into %d products (%d products in state)\n\n", removed, len(groups), store.Count())
+	return nil
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	var products []*models.EnhancedProduct
+	if validateFromDB {
+		ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+		defer cancel()
+
+		client, err := getDBClient()
+		if err != nil {
+			return err
+		}
+		if err := client.Connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Close()
+
+		// The Postgres state store loads images, whose URLs are checked too
+		store := state.NewPostgresStore(client)
+		if err := store.Load(); err != nil {
+			return fmt.Errorf("failed to load products: %w", err)
+		}
+		products = store.GetAllProducts()
+	} else {
+		store, err := openStore(cmd.Context(), false)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		products = store.GetAllProducts()
+	}
+
+	header.Println("\n  PRODUCT VALIDATION")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SKU", "Field", "Level", "Problem"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	const maxRows = 50
+	invalid, warned, problems := 0, 0, 0
+	byField := make(map[string]int)
+	for _, p := range products {
+		errs := p.Validate()
+		warnings := p.Warnings()
+		if len(errs) == 0 && len(warnings) == 0 {
+			continue
+		}
+		if len(errs) > 0 {
+			invalid++
+		} else {
+			warned++
+		}
+		sku := p.SKU
+		if strings.TrimSpace(sku) == "" {
+			sku = "(no SKU)"
+		}
+		for i, err := range append(errs, warnings...) {
+			level := color.RedString("error")
+			if i >= len(errs) {
+				level = color.YellowString("warning")
+			}
+			field, message := "", err.Error()
+			var verr *models.ValidationError
+			if errors.As(err, &verr) {
+				field, message = verr.Field, verr.Message
+			}
+			byField[field]++
+			problems++
+			if problems <= maxRows {
+				table.Append([]string{sku, field, level, truncate(message, 60)})
+			}
+		}
+	}
+
+	if problems == 0 {
+		success.Printf("  ✓ All %d products are valid\n\n", len(products))
+		return nil
+	}
+
+	table.Render()
+	if problems > maxRows {
+		fmt.Printf("  ... and %d more problems\n", problems-maxRows)
+	}
+	fmt.Println()
+
+	header.Println("  BY FIELD")
+	fields := make([]string, 0, len(byField))
+	for field := range byField {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		fmt.Printf("    %s: %d\n", field, byField[field])
+	}
+	fmt.Println()
+
+	if warned > 0 {
+		color.Yellow("  %d of %d products have warnings only and are still exported", warned, len(products))
+	}
+	if invalid == 0 {
+		fmt.Println()
+		return nil
+	}
+	color.Red("  %d of %d products are invalid and will be skipped by exports and database writes", invalid, len(products))
+	fmt.Println()
+	return fmt.Errorf("%d invalid products", invalid)
+}
+
+func runMargins(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	var products []*models.EnhancedProduct
+	if marginsFromDB {
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Minute)
+		defer cancel()
+
+		client, err := getDBClient()
+		if err != nil {
+			return err
+		}
+		if err := client.Connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Close()
+
+		below := marginsBelow
+		products, err = postgres.NewProductRepo(client).GetAll(ctx, database.QueryOptions{
+			Vendor:      marginsVendor,
+			MarginBelow: &below,
+			OrderBy:     "profit_margin",
+			OrderDir:    "ASC",
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		store, err := openStore(cmd.Context(), false)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		for _, p := range store.GetAllProducts() {
+			if marginsVendor != "" && !strings.EqualFold(p.Vendor, marginsVendor) {
+				continue
+			}
+			if p.Price.HasMargin() && p.Price.Margin() < marginsBelow {
+				products = append(products, p)
+			}
+		}
+		sort.SliceStable(products, func(i, j int) bool {
+			return products[i].Price.Margin() < products[j].Price.Margin()
+		})
+	}
+
+	header.Printf("\n  MARGINS BELOW %g%%\n", marginsBelow)
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	if len(products) == 0 {
+		success.Printf("  ✓ No products with a margin below %g%%\n\n", marginsBelow)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SKU", "Title", "Vendor", "Price", "Cost", "Margin"})
+	table.SetBorder(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	negative := 0
+	for _, p := range products {
+		margin := p.Price.Margin()
+		marginText := fmt.Sprintf("%.1f%%", margin)
+		if margin < 0 {
+			negative++
+			marginText = color.RedString(marginText)
+		} else {
+			marginText = color.YellowString(marginText)
+		}
+		table.Append([]string{
+			p.SKU,
+			truncate(p.Title, 30),
+			p.Vendor,
+			fmt.Sprintf("%.2f %s", p.Price.Amount, p.Price.Currency),
+			fmt.Sprintf("%.2f", p.Price.CostPerItem),
+			marginText,
+		})
+	}
+	table.Render()
+	fmt.Println()
+
+	color.Yellow("  %d products below %g%% (%d negative)", len(products), marginsBelow, negative)
+	fmt.Println()
+	return nil
+}
+
+// priceChange is a repricing computed by products price-set
+type priceChange struct {
+	product   *models.EnhancedProduct
+	oldPrice  float64
+	newPrice  float64
+	marketMin float64
+	floored   bool // Raised to the floor
+}
+
+func runPriceSet(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	rule, err := prices.ParsePriceRule(priceSetRule)
+	if err != nil {
+		return err
+	}
+	var floor *prices.PriceRule
+	if priceSetFloor != "" {
+		if floor, err = prices.ParsePriceRule(priceSetFloor); err != nil {
+			return fmt.Errorf("invalid --floor: %w", err)
+		}
+	}
+	if priceSetDays <= 0 {
+		return fmt.Errorf("--days must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	allStats, err := postgres.NewPriceObservationRepo(client).GetAllMarketStats(ctx, priceSetDays)
+	if err != nil {
+		return fmt.Errorf("failed to get market stats: %w", err)
+	}
+
+	productRepo := postgres.NewProductRepo(client)
+	products, err := productRepo.GetAll(ctx, database.QueryOptions{Vendor: priceSetVendor})
+	if err != nil {
+		return fmt.Errorf("failed to get products: %w", err)
+	}
+
+	var changes []priceChange
+	unchanged, missing := 0, 0
+	for _, p := range products {
+		if p.Price == nil || p.Price.Amount <= 0 {
+			missing++
+			continue
+		}
+		in := prices.PriceInputs{Cost: p.Price.CostPerItem, Price: p.Price.Amount}
+		if id, err := uuid.Parse(p.ID); err == nil {
+			if stats := allStats[id]; stats != nil {
+				in.MarketMin = stats.MinPrice
+				in.MarketAvg = stats.AvgPrice
+				in.MarketMax = stats.MaxPrice
+			}
+		}
+
+		target, ok := rule.Eval(in)
+		if !ok || target <= 0 {
+			missing++
+			continue
+		}
+		floored := false
+		if floor != nil {
+			min, ok := floor.Eval(in)
+			if !ok {
+				missing++
+				continue
+			}
+			if target < min {
+				target, floored = min, true
+			}
+		}
+
+		if math.Abs(target-p.Price.Amount) < 0.005 {
+			unchanged++
+			continue
+		}
+		changes = append(changes, priceChange{
+			product:   p,
+			oldPrice:  p.Price.Amount,
+			newPrice:  target,
+			marketMin: in.MarketMin,
+			floored:   floored,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].product.SKU < changes[j].product.SKU
+	})
+
+	title := "PRICE CHANGES (DRY RUN)"
+	if priceSetApply {
+		title = "PRICE CHANGES"
+	}
+	header.Printf("\n  %s\n", title)
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Printf("  Rule: %s", rule)
+	if floor != nil {
+		fmt.Printf("  Floor: %s", floor)
+	}
+	fmt.Println()
+	fmt.Println()
+
+	if len(changes) == 0 {
+		success.Println("  ✓ All prices already match the rule")
+		fmt.Printf("  Unchanged: %d, without data: %d\n\n", unchanged, missing)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SKU", "Title", "Market Min", "Current", "New", "Change"})
+	table.SetBorder(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	for _, c := range changes {
+		marketMin := "-"
+		if c.marketMin > 0 {
+			marketMin = fmt.Sprintf("%.2f", c.marketMin)
+		}
+		newPrice := fmt.Sprintf("%.2f", c.newPrice)
+		if c.floored {
+			newPrice += " (floor)"
+		}
+		diff := (c.newPrice - c.oldPrice) / c.oldPrice * 100
+		change := color.GreenString("+%.1f%%", diff)
+		if diff < 0 {
+			change = color.RedString("%.1f%%", diff)
+		}
+		table.Append([]string{
+			c.product.SKU,
+			truncate(c.product.Title, 30),
+			marketMin,
+			fmt.Sprintf("%.2f", c.oldPrice),
+			newPrice,
+			change,
+		})
+	}
+	table.Render()
+	fmt.Println()
+
+	fmt.Printf("  Changes: %d, unchanged: %d, without data: %d\n", len(changes), unchanged, missing)
+	if !priceSetApply {
+		color.Yellow("\n  Dry run: re-run with --apply to update these prices")
+		fmt.Println()
+		return nil
+	}
+
+	// Without database.use_db the JSON state file is what enhance and export
+	// read, so it gets the new prices too; take its lock before touching the
+	// database so a concurrent writer stops the run up front.
+	useDB, err := useDBState()
+	if err != nil {
+		return err
+	}
+	var store stateStore
+	if !useDB {
+		if store, err = openStore(ctx, true); err != nil {
+			return err
+		}
+		defer store.Close()
+	}
+
+	start := time.Now()
+	updated := 0
+	for _, c := range changes {
+		c.product.Price.Amount = c.newPrice
+		if err := productRepo.Update(ctx, c.product); err != nil {
+			color.Red("  ✗ %s: %v", c.product.SKU, err)
+			continue
+		}
+		updated++
+		if store == nil {
+			continue
+		}
+		if p, ok := store.GetProduct(c.product.SKU); ok && p.Price != nil {
+			p.Price.Amount = c.newPrice
+			store.SetProduct(p)
+		}
+	}
+
+	details := fmt.Sprintf("Repriced %d products with rule %q", updated, rule.String())
+	if floor != nil {
+		details += fmt.Sprintf(" and floor %q", floor.String())
+	}
+	completed := time.Now()
+	postgres.NewHistoryRepo(client).Add(ctx, &database.OperationHistory{
+		Action:      "price_set",
+		Source:      rule.String(),
+		Count:       updated,
+		Details:     details,
+		StartedAt:   start,
+		CompletedAt: &completed,
+	})
+
+	if store != nil && updated > 0 {
+		store.AddHistory("price_set", rule.String(), updated, details)
+		if err := store.Save(); err != nil {
+			return fmt.Errorf("updated %d prices in the database but failed to save state: %w", updated, err)
+		}
+	}
+
+	fmt.Println()
+	success.Printf("  ✓ Updated %d prices\n\n", updated)
+	if updated < len(changes) {
+		return fmt.Errorf("updated %d of %d prices", updated, len(changes))
+	}
+	return nil
+}
+
+func runSKUCandidates(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	info := color.New(color.FgYellow)
+	sku := args[0]
+
+	path := skuCandidatesRules
+	if path == "" {
+		if cfg, err := config.Load(); err == nil {
+			path = cfg.Defaults.SKURulesFile
+		}
+	}
+	rules, err := skurules.LoadOrDefault(path)
+	if err != nil {
+		return err
+	}
+	if problems := rules.Validate(); len(problems) > 0 {
+		for _, p := range problems {
+			color.Yellow("  ⚠ %s", p)
+		}
+	}
+
+	header.Println("\n  SKU CANDIDATES")
+	fmt.Println("  " + strings.Repeat("─", 40))
+	fmt.Println()
+	info.Printf("  SKU: %s\n\n", sku)
+
+	for _, section := range []struct {
+		name       string
+		candidates []string
+	}{
+		{"Tiger.nl product IDs", rules.TigerCandidates(sku)},
+		{"NOBB numbers", rules.NOBBCandidates(sku)},
+	} {
+		fmt.Printf("  %s:\n", section.name)
+		if len(section.candidates) == 0 {
+			color.New(color.Faint).Println("    (none)")
+		}
+		for _, c := range section.candidates {
+			fmt.Printf("    - %s\n", c)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	sku := args[0]
+
+	useDB, err := useDBState()
+	if err != nil {
+		return err
+	}
+
+	var product *models.EnhancedProduct
+	if showFromDB || useDB {
+		product, err = loadDBProductDetail(cmd.Context(), sku)
+		if err != nil {
+			return err
+		}
+		if product == nil {
+			return fmt.Errorf("product %s not found in the database", sku)
+		}
+	} else {
+		store, err := openStore(cmd.Context(), false)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		p, ok := store.GetProduct(sku)
+		if !ok {
+			return fmt.Errorf("product %s not found in state", sku)
+		}
+		product = p
+	}
+
+	if showJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(product)
+	}
+
+	printProductDetail(product)
+	return nil
+}
+
+// loadDBProductDetail reads a product with its images, properties, suppliers
+// and enhancement log from PostgreSQL. It returns nil when the SKU is not in
+// the database.
+func loadDBProductDetail(ctx context.Context, sku string) (*models.EnhancedProduct, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client, err := getDBClient()
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Close()
+
+	product, err := state.NewPostgresStore(client).LoadProduct(ctx, sku)
+	if err != nil || product == nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// printProductDetail prints every section of a product that has data
+func printProductDetail(p *models.EnhancedProduct) {
+	header := color.New(color.FgCyan, color.Bold)
+	section := func(title string) {
+		fmt.Println()
+		header.Printf("  %s\n", title)
+		fmt.Println("  " + strings.Repeat("─", 50))
+	}
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Printf("  %-13s %s\n", name+":", value)
+		}
+	}
+
+	header.Printf("\n  PRODUCT: %s\n", p.SKU)
+	fmt.Println("  " + strings.Repeat("─", 50))
+	field("Title", p.Title)
+	field("Status", string(p.Status))
+	field("Vendor", p.Vendor)
+	field("Type", p.ProductType)
+	field("Handle", p.Handle)
+	field("Barcode", p.Barcode)
+	field("NOBB number", p.NOBBNumber)
+	field("Tags", strings.Join(p.Tags, ", "))
+	if !p.CreatedAt.IsZero() {
+		field("Created", p.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	if !p.UpdatedAt.IsZero() {
+		field("Updated", p.UpdatedAt.Format("2006-01-02 15:04"))
+	}
+	if p.LegacyMatchedURL != "" {
+		field("Tiger.nl", fmt.Sprintf("%s (%.0f%%, %s)", p.LegacyMatchedURL, p.LegacyMatchScore*100, p.LegacyMatchMethod))
+	}
+	if p.Description != "" {
+		fmt.Println("  Description:")
+		for _, line := range strings.Split(strings.TrimSpace(p.Description), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+
+	if p.Price != nil {
+		section("PRICE")
+		field("Price", fmt.Sprintf("%.2f %s", p.Price.Amount, p.Price.Currency))
+		if p.Price.CompareAt > 0 {
+			field("Compare at", fmt.Sprintf("%.2f %s", p.Price.CompareAt, p.Price.Currency))
+		}
+		if p.Price.CostPerItem > 0 {
+			field("Cost", fmt.Sprintf("%.2f %s", p.Price.CostPerItem, p.Price.Currency))
+		}
+		if p.Price.HasMargin() {
+			margin := fmt.Sprintf("%.1f%%", p.Price.Margin())
+			if p.Price.Margin() < 0 {
+				margin = color.RedString(margin)
+			}
+			field("Margin", margin)
+		}
+	}
+
+	if p.Dimensions != nil || p.Weight != nil {
+		section("DIMENSIONS")
+		if d := p.Dimensions; d != nil {
+			field("Size", fmt.Sprintf("%s × %s × %s %s (L × W × H)",
+				formatFloat(d.Length), formatFloat(d.Width), formatFloat(d.Height), d.Unit))
+		}
+		if w := p.Weight; w != nil {
+			field("Weight", fmt.Sprintf("%s %s", formatFloat(w.Value), w.Unit))
+		}
+	}
+
+	if len(p.Specifications) > 0 {
+		section(fmt.Sprintf("SPECIFICATIONS (%d)", len(p.Specifications)))
+		keys := make([]string, 0, len(p.Specifications))
+		for k := range p.Specifications {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %s: %s\n", k, p.Specifications[k])
+		}
+	}
+
+	if len(p.Properties) > 0 {
+		bySource := make(map[string][]models.Property)
+		for _, prop := range p.Properties {
+			bySource[prop.Source] = append(bySource[prop.Source], prop)
+		}
+		sources := make([]string, 0, len(bySource))
+		for src := range bySource {
+			sources = append(sources, src)
+		}
+		sort.Strings(sources)
+
+		section(fmt.Sprintf("PROPERTIES (%d)", len(p.Properties)))
+		for _, src := range sources {
+			name := src
+			if name == "" {
+				name = "unknown source"
+			}
+			color.Yellow("\n  %s\n", name)
+			table := newDetailTable("Code", "Name", "Value")
+			for _, prop := range bySource[src] {
+				value := prop.Value
+				if prop.Unit != "" {
+					value += " " + prop.Unit
+				}
+				table.Append([]string{prop.Code, prop.Name, value})
+			}
+			table.Render()
+		}
+	}
+
+	if len(p.Images) > 0 {
+		section(fmt.Sprintf("IMAGES (%d)", len(p.Images)))
+		table := newDetailTable("#", "Status", "Source", "Size", "URL / File")
+		for _, img := range p.Images {
+			size := "-"
+			if img.Width > 0 && img.Height > 0 {
+				size = fmt.Sprintf("%d×%d", img.Width, img.Height)
+			}
+			if img.Bytes > 0 {
+				size += " " + formatBytes(img.Bytes)
+			}
+			location := img.SourceURL
+			if img.LocalPath != "" {
+				location += "\n" + img.LocalPath
+			}
+			table.Append([]string{strconv.Itoa(img.Position), img.Status, img.Source, size, location})
+		}
+		table.Render()
+	}
+
+	if len(p.Suppliers) > 0 {
+		section(fmt.Sprintf("SUPPLIERS (%d)", len(p.Suppliers)))
+		table := newDetailTable("Name", "ID", "GLN", "Article No", "Primary")
+		for _, s := range p.Suppliers {
+			primary := ""
+			if s.IsPrimary {
+				primary = "✓"
+			}
+			table.Append([]string{s.Name, s.ID, s.GLN, s.ArticleNo, primary})
+		}
+		table.Render()
+	}
+
+	if len(p.PackageInfo) > 0 {
+		section(fmt.Sprintf("PACKAGING (%d)", len(p.PackageInfo)))
+		table := newDetailTable("Type", "Qty", "GTIN", "Weight", "Size")
+		for _, pkg := range p.PackageInfo {
+			weight, size := "-", "-"
+			if pkg.Weight > 0 {
+				weight = fmt.Sprintf("%s %s", formatFloat(pkg.Weight), pkg.WeightUnit)
+			}
+			if pkg.Length > 0 || pkg.Width > 0 || pkg.Height > 0 {
+				size = fmt.Sprintf("%s × %s × %s %s", formatFloat(pkg.Length), formatFloat(pkg.Width), formatFloat(pkg.Height), pkg.DimUnit)
+			}
+			table.Append([]string{pkg.Type, strconv.Itoa(pkg.Quantity), pkg.GTIN, weight, size})
+		}
+		table.Render()
+	}
+
+	if len(p.Enhancements) > 0 {
+		section(fmt.Sprintf("ENHANCEMENT HISTORY (%d)", len(p.Enhancements)))
+		history := append([]models.Enhancement(nil), p.Enhancements...)
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].Timestamp.Before(history[j].Timestamp)
+		})
+		for _, e := range history {
+			mark := color.GreenString("✓")
+			if !e.Success {
+				mark = color.RedString("✗")
+			}
+			fmt.Printf("  %s %s %-10s %s\n", e.Timestamp.Format("2006-01-02 15:04"), mark, e.Source, e.Action)
+			switch {
+			case e.Error != "":
+				color.Red("      %s", e.Error)
+			case e.Details != "":
+				fmt.Printf("      %s\n", e.Details)
+			case len(e.FieldsAdded) > 0:
+				fmt.Printf("      fields: %s\n", strings.Join(e.FieldsAdded, ", "))
+			}
+		}
+	}
+	fmt.Println()
+}
+
+// newDetailTable returns a borderless table for a products show section
+func newDetailTable(columns ...string) *tablewriter.Table {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(columns)
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	colors := make([]tablewriter.Colors, len(columns))
+	for i := range colors {
+		colors[i] = tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor}
+	}
+	table.SetHeaderColor(colors...)
+	return table
+}
+
+// tagOp is a bulk tag edit: add or remove tag, or rename tag to newTag
+type tagOp struct {
+	action string
+	tag    string
+	newTag string
+}
+
+// String describes the edit for headers and history
+func (op tagOp) String() string {
+	if op.action == "rename" {
+		return fmt.Sprintf("rename %q → %q", op.tag, op.newTag)
+	}
+	return fmt.Sprintf("%s %q", op.action, op.tag)
+}
+
+// apply returns tags after the edit and whether it changed anything. Tags
+// compare case-insensitively; the input slice is not modified.
+func (op tagOp) apply(tags []string) ([]string, bool) {
+	has := func(tag string) bool {
+		return slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, tag) })
+	}
+	without := func(tags []string, tag string) []string {
+		return slices.DeleteFunc(slices.Clone(tags), func(t string) bool { return strings.EqualFold(t, tag) })
+	}
+
+	switch op.action {
+	case "add":
+		if has(op.tag) {
+			return tags, false
+		}
+		return append(slices.Clone(tags), op.tag), true
+	case "remove":
+		if !has(op.tag) {
+			return tags, false
+		}
+		return without(tags, op.tag), true
+	case "rename":
+		if !has(op.tag) {
+			return tags, false
+		}
+		i := slices.IndexFunc(tags, func(t string) bool { return strings.EqualFold(t, op.tag) })
+		result := slices.Clone(tags)
+		result[i] = op.newTag
+		// Drop other spellings of either tag so the result has one newTag
+		result = slices.DeleteFunc(result, func(t string) bool {
+			return t != op.newTag && (strings.EqualFold(t, op.tag) || strings.EqualFold(t, op.newTag))
+		})
+		return result, !slices.Equal(result, tags)
+	}
+	return tags, false
+}
+
+// validateTag rejects tags Shopify would split or drop
+func validateTag(tag string) error {
+	if strings.TrimSpace(tag) == "" {
+		return fmt.Errorf("tag must not be empty")
+	}
+	if strings.Contains(tag, ",") {
+		return fmt.Errorf("tag %q must not contain a comma", tag)
+	}
+	if tag != strings.TrimSpace(tag) {
+		return fmt.Errorf("tag %q must not start or end with spaces", tag)
+	}
+	return nil
+}
+
+// tagChange is a product whose tags an edit changes
+type tagChange struct {
+	product *models.EnhancedProduct
+	tags    []string
+}
+
+func runTagOp(cmd *cobra.Command, op tagOp) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	if err := validateTag(op.tag); err != nil {
+		return err
+	}
+	if op.action == "rename" {
+		if err := validateTag(op.newTag); err != nil {
+			return err
+		}
+		if op.tag == op.newTag {
+			return fmt.Errorf("old and new tag are the same")
+		}
+	}
+	filtered := tagVendor != "" || tagStatus != "" || len(tagSKUs) > 0
+	if op.action == "add" && !filtered && !tagAll {
+		return fmt.Errorf("select products with --vendor, --status or --sku, or pass --all to tag every product")
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), 5*time.Minute)
+	defer cancel()
+
+	useDB, err := useDBState()
+	if err != nil {
+		return err
+	}
+	fromDB := tagFromDB || useDB
+
+	var products []*models.EnhancedProduct
+	var store *state.Store
+	var productRepo *postgres.ProductRepo
+	var historyRepo *postgres.HistoryRepo
+	if fromDB {
+		client, err := getDBClient()
+		if err != nil {
+			return err
+		}
+		if err := client.Connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Close()
+
+		productRepo = postgres.NewProductRepo(client)
+		historyRepo = postgres.NewHistoryRepo(client)
+		// The vendor is matched below, case-insensitively as for the state file
+		products, err = productRepo.GetAll(ctx, database.QueryOptions{
+			Status:   models.ProductStatus(tagStatus),
+			OrderBy:  "sku",
+			OrderDir: "ASC",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get products: %w", err)
+		}
+	} else {
+		store = state.NewStore("")
+		load := store.LoadForUpdate
+		if tagDryRun {
+			load = store.Load
+		}
+		if err := load(); err != nil {
+			return fmt.Errorf("failed to load state: %w", err)
+		}
+		defer store.Close()
+		products = store.GetAllProducts()
+	}
+
+	var changes []tagChange
+	for _, p := range products {
+		if tagVendor != "" && !strings.EqualFold(p.Vendor, tagVendor) {
+			continue
+		}
+		if tagStatus != "" && string(p.Status) != tagStatus {
+			continue
+		}
+		if len(tagSKUs) > 0 && !slices.Contains(tagSKUs, p.SKU) {
+			continue
+		}
+		if tags, changed := op.apply(p.Tags); changed {
+			changes = append(changes, tagChange{product: p, tags: tags})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].product.SKU < changes[j].product.SKU
+	})
+
+	title := "TAG CHANGES"
+	if tagDryRun {
+		title += " (DRY RUN)"
+	}
+	header.Printf("\n  %s\n", title)
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Printf("  %s\n\n", strings.ToUpper(op.String()[:1])+op.String()[1:])
+
+	if len(changes) == 0 {
+		success.Println("  ✓ No products to change")
+		fmt.Println()
+		return nil
+	}
+
+	table := newDetailTable("SKU", "Title", "Tags")
+	const maxRows = 50
+	for i, c := range changes {
+		if i == maxRows {
+			break
+		}
+		table.Append([]string{c.product.SKU, truncate(c.product.Title, 40), truncate(strings.Join(c.tags, ", "), 60)})
+	}
+	table.Render()
+	if len(changes) > maxRows {
+		fmt.Printf("  ... and %d more\n", len(changes)-maxRows)
+	}
+	fmt.Println()
+
+	if tagDryRun {
+		color.Yellow("  Dry run: %d products would change. Re-run without --dry-run to save.", len(changes))
+		fmt.Println()
+		return nil
+	}
+
+	start := time.Now()
+	details := fmt.Sprintf("Tag %s on %d products", op, len(changes))
+	updated := len(changes)
+	if fromDB {
+		tags := make(map[string][]string, len(changes))
+		for _, c := range changes {
+			tags[c.product.SKU] = c.tags
+		}
+		n, err := productRepo.UpdateTags(ctx, tags)
+		if err != nil {
+			return err
+		}
+		updated = n
+		completed := time.Now()
+		historyRepo.Add(ctx, &database.OperationHistory{
+			Action:      "tag_" + op.action,
+			Source:      op.tag,
+			Count:       updated,
+			Details:     details,
+			StartedAt:   start,
+			CompletedAt: &completed,
+		})
+	} else {
+		for _, c := range changes {
+			c.product.Tags = c.tags
+			c.product.UpdatedAt = start
+		}
+		store.AddHistory("tag_"+op.action, op.tag, updated, details)
+		if err := store.Save(); err != nil {
+			return fmt.Errorf("failed to save state: %w", err)
+		}
+	}
+
+	success.Printf("  ✓ Updated tags on %d products\n\n", updated)
+	return nil
+}
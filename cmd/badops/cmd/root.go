@@ -1,10 +1,34 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/logging"
+	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/metrics"
+	"github.com/badno/badops/internal/state"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
+// errInterrupted is returned by commands stopped by SIGINT/SIGTERM after
+// saving their progress
+var errInterrupted = errors.New("interrupted")
+
+var (
+	logLevel    string
+	logFormat   string
+	metricsAddr string
+	lockTimeout time.Duration
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "badops",
 	Short: "Bad.no Operations Terminal",
@@ -20,21 +44,87 @@ Bad.no Operations Terminal - Product image enhancement toolkit
 
 Streamline your product operations with automated image fetching,
 matching, and processing from supplier catalogs.`,
+	PersistentPreRunE: setupGlobals,
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	// SIGINT/SIGTERM cancel the command's context so long-running commands
+	// can stop between products and save their progress. Once cancelled the
+	// handler is removed, so a second Ctrl-C exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if matchCacheDB != nil {
+		matchCacheDB.Close()
+	}
+	if err != nil && logFormat == logging.FormatJSON {
+		// Cobra's "Error:" line is silenced in JSON mode; log it instead
+		slog.Error("command failed", "command", cmd.CommandPath(), "error", err)
+	}
+	return err
+}
+
+// setupGlobals applies the persistent flags shared by every command
+func setupGlobals(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(cmd); err != nil {
+		return err
+	}
+	state.LockTimeout = lockTimeout
+	if cfg, err := config.Load(); err == nil && cfg.Defaults.BrandPrefixes != nil {
+		matcher.SetBrandPrefixes(cfg.Defaults.BrandPrefixes)
+	}
+	if metricsAddr != "" {
+		if _, err := metrics.Serve(metricsAddr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupLogging installs the logger selected by --log-level and --log-format
+// as the slog default. Logs go to stderr so they never mix with command
+// output that may be piped.
+func setupLogging(cmd *cobra.Command) error {
+	logger, err := logging.New(os.Stderr, logLevel, logFormat)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+
+	if logFormat == logging.FormatJSON {
+		// Machine-readable runs (cron, systemd) don't want ANSI colors
+		color.NoColor = true
+		cmd.Root().SilenceErrors = true
+	}
+	return nil
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", logging.DefaultLevel, "Log level: debug, info, warn, error")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "Log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address while the command runs (e.g., :9090)")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", state.LockTimeout, "How long to wait for another badops process to release the state (0 fails immediately)")
+
 	rootCmd.AddCommand(productsCmd)
 	rootCmd.AddCommand(imagesCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(sourcesCmd)
 	rootCmd.AddCommand(enhanceCmd)
 	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(pipelineCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(doctorCmd)
 	rootCmd.AddCommand(dbCmd)
 	rootCmd.AddCommand(pricesCmd)
 	rootCmd.AddCommand(competitorsCmd)
 	rootCmd.AddCommand(analyticsCmd)
+	rootCmd.AddCommand(tigerCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(stateCmd)
 }
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleCron string
+	scheduleOnce bool
+)
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule --cron <expr> -- <command> [args...]",
+	Short: "Run a badops command on a cron schedule",
+	Long: `Run a badops command on a cron schedule in a long-lived process, for
+unattended runs under systemd without a crontab.
+
+The expression has five fields (minute hour day-of-month month day-of-week)
+or a descriptor such as @daily or @every 6h, in local time unless prefixed
+with CRON_TZ=<zone>. Each run starts the command as a separate badops
+process with the same --log-level, --log-format and --lock-timeout, and its
+outcome and duration are logged. A run that is due while the previous one is
+still going is skipped.
+
+--once runs the command immediately and exits with its result, to test the
+command line before scheduling it.
+
+Ctrl-C or SIGTERM stops the schedule; a running command is interrupted and
+saves its progress first.
+
+Examples:
+  badops schedule --cron "0 3 * * *" -- pipeline run --vendor Tiger
+  badops schedule --cron "CRON_TZ=Europe/Oslo 30 6 * * 1-5" -- prices scrape
+  badops schedule --cron "@every 6h" --once -- products import --incremental`,
+	Args:         cobra.MinimumNArgs(1),
+	SilenceUsage: true,
+	RunE:         runSchedule,
+}
+
+func init() {
+	scheduleCmd.Flags().StringVar(&scheduleCron, "cron", "", "Cron expression (e.g. \"0 3 * * *\", @daily, @every 6h)")
+	scheduleCmd.Flags().BoolVar(&scheduleOnce, "once", false, "Run the command once now and exit")
+}
+
+func runSchedule(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	if args[0] == "schedule" {
+		return fmt.Errorf("cannot schedule the schedule command")
+	}
+	if scheduleCron == "" && !scheduleOnce {
+		return fmt.Errorf("--cron is required (or pass --once)")
+	}
+
+	var schedule cron.Schedule
+	if scheduleCron != "" {
+		s, err := cron.ParseStandard(scheduleCron)
+		if err != nil {
+			return fmt.Errorf("invalid cron expression %q: %w", scheduleCron, err)
+		}
+		schedule = s
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the badops executable: %w", err)
+	}
+	command := strings.Join(args, " ")
+	job := &scheduledJob{
+		exe: exe,
+		args: append([]string{
+			"--log-level", logLevel,
+			"--log-format", logFormat,
+			"--lock-timeout", lockTimeout.String(),
+		}, args...),
+		command: command,
+	}
+
+	ctx := cmd.Context()
+
+	if scheduleOnce {
+		return job.run(ctx)
+	}
+
+	header.Println("\n  SCHEDULE")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+	color.Yellow("  Command: badops %s\n", command)
+	color.Yellow("  Cron: %s\n", scheduleCron)
+	color.Yellow("  Next run: %s\n", schedule.Next(time.Now()).Format(time.RFC3339))
+	fmt.Println()
+
+	c := cron.New()
+	c.Schedule(schedule, cron.FuncJob(func() {
+		// Errors are logged by run; the schedule keeps going
+		job.run(ctx)
+		if ctx.Err() == nil {
+			fmt.Printf("  Next run: %s\n\n", schedule.Next(time.Now()).Format(time.RFC3339))
+		}
+	}))
+	c.Start()
+	slog.Info("schedule started", "command", command, "cron", scheduleCron)
+
+	<-ctx.Done()
+	slog.Info("stopping schedule", "command", command)
+	<-c.Stop().Done() // Waits for a running command to exit
+	return nil
+}
+
+// scheduledJob runs a badops command as a child process, skipping runs that
+// overlap a previous one
+type scheduledJob struct {
+	exe     string
+	args    []string
+	command string
+	running atomic.Bool
+}
+
+// run starts the command and waits for it. Cancelling ctx interrupts the
+// command so it can save its progress, and kills it after a minute.
+func (j *scheduledJob) run(ctx context.Context) error {
+	if !j.running.CompareAndSwap(false, true) {
+		color.Yellow("  %s skipped: previous run still going\n", time.Now().Format(time.DateTime))
+		slog.Warn("skipping scheduled run, previous run still going", "command", j.command)
+		return nil
+	}
+	defer j.running.Store(false)
+
+	proc := exec.CommandContext(ctx, j.exe, j.args...)
+	proc.Stdout = os.Stdout
+	proc.Stderr = os.Stderr
+	proc.Cancel = func() error {
+		return proc.Process.Signal(os.Interrupt)
+	}
+	proc.WaitDelay = time.Minute
+
+	start := time.Now()
+	color.Cyan("  %s running badops %s\n", start.Format(time.DateTime), j.command)
+	slog.Info("scheduled run started", "command", j.command)
+	err := proc.Run()
+	duration := time.Since(start).Round(time.Millisecond)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		color.Green("  ✓ Finished in %s\n", duration)
+		slog.Info("scheduled run finished", "command", j.command, "duration", duration)
+	case errors.As(err, &exitErr):
+		color.Red("  ✗ Failed with exit code %d after %s\n", exitErr.ExitCode(), duration)
+		slog.Error("scheduled run failed", "command", j.command, "duration", duration, "exit_code", exitErr.ExitCode())
+	default:
+		color.Red("  ✗ Failed after %s: %v\n", duration, err)
+		slog.Error("scheduled run failed", "command", j.command, "duration", duration, "error", err)
+	}
+	return err
+}
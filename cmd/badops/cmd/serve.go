@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/api"
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr     string
+	serveReadOnly bool
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve products and price data over a JSON HTTP API",
+	Long: `Serve products, competitor prices and price alerts from the database
+over a JSON HTTP API, so other services can read them without the CLI, and
+receive Shopify product webhooks.
+
+Endpoints (all GET):
+  /products                 Products by SKU (?vendor=, ?status=)
+  /products/{sku}           One product
+  /products/{sku}/prices    Competitor prices and market stats (?days=30)
+  /competitors              Competitors
+  /analytics/alerts         Products priced away from the market (?threshold=10, ?vendor=)
+
+List endpoints take ?limit= (default 50, max 500) and ?offset=, and return
+total_count and has_more for paging. Alerts need ClickHouse; when it can't
+be reached, /analytics/alerts responds 503 and the rest keeps working.
+
+--read-only (the default) opens every PostgreSQL session read-only, so the
+server cannot modify data.
+
+With --read-only=false and sources.shopify.webhook_secret_env set, POST
+/webhooks/shopify/products accepts products/create and products/update
+webhooks. The X-Shopify-Hmac-Sha256 signature is checked against the
+secret, and the product is upserted by SKU, keeping its status and cost.
+
+Ctrl-C or SIGTERM stops the server after in-flight requests finish.`,
+	Example: `  badops serve
+  badops serve --addr 127.0.0.1:9090
+  badops serve --read-only=false   # also accept Shopify product webhooks
+  curl 'localhost:8080/products?vendor=Tiger&limit=10'
+  curl localhost:8080/products/TIG-12345/prices?days=90`,
+	SilenceUsage: true,
+	RunE:         runServe,
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().BoolVar(&serveReadOnly, "read-only", true, "Open database sessions read-only (disables webhooks)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	ctx := cmd.Context()
+
+	connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	appCfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	webhookSecret, err := credentials.Resolve(nil, appCfg.Sources.Shopify.WebhookSecretEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve Shopify webhook secret: %w", err)
+	}
+
+	client, err := getDBClient()
+	if err != nil {
+		return err
+	}
+	client.SetReadOnly(serveReadOnly)
+	if err := client.Connect(connectCtx); err != nil {
+		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	defer client.Close()
+
+	cfg := api.Config{
+		Products:    postgres.NewProductRepo(client),
+		Competitors: postgres.NewCompetitorRepo(client),
+		Prices:      postgres.NewPriceObservationRepo(client),
+
+		ShopifyWebhookSecret: webhookSecret,
+		ReadOnly:             serveReadOnly,
+	}
+
+	// Alerts are optional; the product and price endpoints don't need ClickHouse
+	alerts := "disabled (ClickHouse not connected)"
+	chClient, err := getClickHouseClient()
+	if err == nil {
+		err = chClient.Connect(connectCtx)
+	}
+	if err != nil {
+		slog.Warn("analytics alerts disabled", "error", err)
+	} else {
+		defer chClient.Close()
+		cfg.Alerts = chClient
+		alerts = "enabled"
+	}
+
+	server := api.New(cfg)
+	webhooks := "disabled (no sources.shopify.webhook_secret_env)"
+	switch {
+	case server.WebhooksEnabled():
+		webhooks = "POST /webhooks/shopify/products"
+	case serveReadOnly && webhookSecret != "":
+		webhooks = "disabled (--read-only)"
+	}
+
+	header.Println("\n  API SERVER")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+	color.Yellow("  Address: %s\n", serveAddr)
+	color.Yellow("  Read-only: %v\n", serveReadOnly)
+	color.Yellow("  Alerts: %s\n", alerts)
+	color.Yellow("  Webhooks: %s\n", webhooks)
+	fmt.Println()
+
+	return server.ListenAndServe(ctx, serveAddr)
+}
@@ -4,14 +4,19 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/matcher"
 	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/internal/source/matrixify"
 	"github.com/badno/badops/internal/source/nobb"
+	"github.com/badno/badops/internal/source/rest"
 	"github.com/badno/badops/internal/source/shopify"
 	"github.com/badno/badops/internal/source/tiger"
+	"github.com/badno/badops/internal/source/woocommerce"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -46,10 +51,21 @@ var sourcesInfoCmd = &cobra.Command{
 	RunE:  runSourcesInfo,
 }
 
+var sourcesStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show source status and request statistics",
+	Long: `Test every configured source and show its request statistics: requests
+made, data transferred, cache hits and misses, errors by type, and the time of
+the last request. Statistics cover this run only; NOBB and Tiger.nl also show
+their cache hit rate and the number of cached entries.`,
+	RunE: runSourcesStatus,
+}
+
 func init() {
 	sourcesCmd.AddCommand(sourcesListCmd)
 	sourcesCmd.AddCommand(sourcesTestCmd)
 	sourcesCmd.AddCommand(sourcesInfoCmd)
+	sourcesCmd.AddCommand(sourcesStatusCmd)
 }
 
 // initSources initializes all source connectors from config
@@ -66,16 +82,41 @@ func initSources() error {
 	})
 	source.Register(shopifyConn)
 
+	// Register Matrixify export file connector
+	matrixifyConn := matrixify.NewConnector(matrixify.Config{
+		File: cfg.Sources.Matrixify.File,
+	})
+	source.Register(matrixifyConn)
+
+	// Register WooCommerce connector
+	wooConn := woocommerce.NewConnector(woocommerce.Config{
+		URL:               cfg.Sources.WooCommerce.URL,
+		ConsumerKeyEnv:    cfg.Sources.WooCommerce.ConsumerKeyEnv,
+		ConsumerSecretEnv: cfg.Sources.WooCommerce.ConsumerSecretEnv,
+		Currency:          cfg.Sources.WooCommerce.Currency,
+	})
+	source.Register(wooConn)
+
+	// Register generic REST connector
+	source.Register(rest.NewConnector(cfg.Sources.REST))
+
 	// Register NOBB connector
 	nobbConn := nobb.NewConnector(nobb.Config{
-		UsernameEnv: cfg.Sources.NOBB.UsernameEnv,
-		PasswordEnv: cfg.Sources.NOBB.PasswordEnv,
+		UsernameEnv:  cfg.Sources.NOBB.UsernameEnv,
+		PasswordEnv:  cfg.Sources.NOBB.PasswordEnv,
+		SKURulesFile: cfg.Defaults.SKURulesFile,
 	})
 	source.Register(nobbConn)
 
 	// Register Tiger.nl connector
 	tigerConn := tiger.NewConnector(tiger.Config{
-		RateLimitMs: cfg.Sources.TigerNL.RateLimitMs,
+		RateLimitMs:      cfg.Sources.TigerNL.RateLimitMs,
+		MappingsFile:     cfg.Sources.TigerNL.MappingsFile,
+		SKURulesFile:     cfg.Defaults.SKURulesFile,
+		CacheTTL:         cfg.Sources.TigerNL.CacheDuration(),
+		NegativeCacheTTL: cfg.Sources.TigerNL.NegativeCacheDuration(),
+		UserAgent:        cfg.Sources.TigerNL.UserAgent,
+		AcceptLanguage:   cfg.Sources.TigerNL.AcceptLanguage,
 	})
 	source.Register(tigerConn)
 
@@ -235,6 +276,34 @@ func runSourcesInfo(cmd *cobra.Command, args []string) error {
 		fmt.Println("  Configuration:")
 		fmt.Printf("    Store: %s.myshopify.com\n", cfg.Sources.Shopify.Store)
 		fmt.Printf("    API Key Env: %s\n", cfg.Sources.Shopify.APIKeyEnv)
+	case "matrixify":
+		fmt.Println("  Configuration:")
+		if cfg.Sources.Matrixify.File != "" {
+			fmt.Printf("    File: %s\n", cfg.Sources.Matrixify.File)
+		} else {
+			fmt.Println("    File: none (pass --file to products import)")
+		}
+	case "woocommerce":
+		fmt.Println("  Configuration:")
+		fmt.Printf("    URL: %s\n", cfg.Sources.WooCommerce.URL)
+		fmt.Printf("    Consumer Key Env: %s\n", cfg.Sources.WooCommerce.ConsumerKeyEnv)
+		fmt.Printf("    Consumer Secret Env: %s\n", cfg.Sources.WooCommerce.ConsumerSecretEnv)
+	case "rest":
+		fmt.Println("  Configuration:")
+		fmt.Printf("    URL: %s\n", cfg.Sources.REST.URL)
+		if cfg.Sources.REST.TokenEnv != "" {
+			fmt.Printf("    Token Env: %s\n", cfg.Sources.REST.TokenEnv)
+		}
+		pagination := cfg.Sources.REST.Pagination.Type
+		if pagination == "" {
+			pagination = rest.PaginationNone
+		}
+		fmt.Printf("    Pagination: %s\n", pagination)
+		if err := cfg.Sources.REST.Validate(); err != nil {
+			fmt.Printf("    Mapping: invalid (%v)\n", err)
+		} else {
+			fmt.Printf("    Mapping: sku=%s title=%s\n", cfg.Sources.REST.Mapping.SKUPath, cfg.Sources.REST.Mapping.TitlePath)
+		}
 	case "nobb":
 		fmt.Println("  Configuration:")
 		fmt.Printf("    Username Env: %s\n", cfg.Sources.NOBB.UsernameEnv)
@@ -243,6 +312,14 @@ func runSourcesInfo(cmd *cobra.Command, args []string) error {
 	case "tiger_nl":
 		fmt.Println("  Configuration:")
 		fmt.Printf("    Rate Limit: %dms\n", cfg.Sources.TigerNL.RateLimitMs)
+		if cfg.Sources.TigerNL.MappingsFile != "" {
+			fmt.Printf("    Mappings File: %s\n", cfg.Sources.TigerNL.MappingsFile)
+		}
+		userAgent := cfg.Sources.TigerNL.UserAgent
+		if userAgent == "" {
+			userAgent = matcher.DefaultUserAgent
+		}
+		fmt.Printf("    User-Agent: %s\n", userAgent)
 		fmt.Println("    Base URL: https://tiger.nl")
 	}
 	fmt.Println()
@@ -250,6 +327,132 @@ func runSourcesInfo(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runSourcesStatus(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+
+	header.Println("\n  SOURCE STATUS")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Println()
+
+	if err := initSources(); err != nil {
+		color.Yellow("  Warning: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	connectors := source.List()
+	if len(connectors) == 0 {
+		color.Yellow("  No sources registered.")
+		fmt.Println()
+		return nil
+	}
+
+	testErrs := make(map[string]error, len(connectors))
+	for _, c := range connectors {
+		testErrs[c.Name()] = c.Test(ctx)
+	}
+
+	renderConnectorStats(connectors, testErrs)
+
+	for _, c := range connectors {
+		if err := testErrs[c.Name()]; err != nil {
+			color.Red("  %s: %v", c.Name(), err)
+		}
+	}
+	fmt.Println()
+
+	return nil
+}
+
+// renderConnectorStats prints a table of the connectors' statistics. testErrs
+// holds each connector's test result and may be nil when no test was run.
+func renderConnectorStats(connectors []source.Connector, testErrs map[string]error) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Source", "Status", "Requests", "Data", "Cache", "Errors", "Last Request"})
+	table.SetBorder(false)
+	table.SetHeaderColor(
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+		tablewriter.Colors{tablewriter.Bold, tablewriter.FgCyanColor},
+	)
+
+	for _, c := range connectors {
+		stats := c.Stats()
+
+		status := "-"
+		if testErrs != nil {
+			if testErrs[c.Name()] != nil {
+				status = color.RedString("failed")
+			} else {
+				status = color.GreenString("ok")
+			}
+		}
+
+		cache := "-"
+		if rate := stats.CacheHitRate(); rate >= 0 {
+			cache = fmt.Sprintf("%d/%d (%.0f%%)", stats.CacheHits, stats.CacheHits+stats.CacheMisses, rate*100)
+		}
+		if stats.CacheEntries > 0 {
+			if cache == "-" {
+				cache = fmt.Sprintf("%d cached", stats.CacheEntries)
+			} else {
+				cache += fmt.Sprintf(", %d cached", stats.CacheEntries)
+			}
+		}
+
+		errs := "0"
+		if n := stats.ErrorCount(); n > 0 {
+			kinds := make([]string, 0, len(stats.Errors))
+			for kind := range stats.Errors {
+				kinds = append(kinds, kind)
+			}
+			sort.Strings(kinds)
+			parts := make([]string, 0, len(kinds))
+			for _, kind := range kinds {
+				parts = append(parts, fmt.Sprintf("%s: %d", kind, stats.Errors[kind]))
+			}
+			errs = color.RedString("%d (%s)", n, strings.Join(parts, ", "))
+		}
+
+		last := "-"
+		if !stats.LastRequest.IsZero() {
+			last = stats.LastRequest.Format("15:04:05")
+		}
+
+		table.Append([]string{
+			c.Name(),
+			status,
+			fmt.Sprintf("%d", stats.Requests),
+			formatBytes(stats.Bytes),
+			cache,
+			errs,
+			last,
+		})
+	}
+
+	table.Render()
+	fmt.Println()
+}
+
+// formatBytes formats a byte count, e.g. 1.5 MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/state"
+	"github.com/badno/badops/pkg/models"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var (
+	stateExportGzip    bool
+	stateImportMerge   bool
+	stateImportReplace bool
+	stateImportYes     bool
+	stateMigrateDryRun bool
+)
+
+var stateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Back up and share the JSON state",
+	Long: `Export the product state (output/.badops-state.json) to a file and import it
+again, e.g. to back it up or to move it to another machine without a database.`,
+}
+
+var stateExportCmd = &cobra.Command{
+	Use:   "export <file>",
+	Short: "Write a copy of the current state",
+	Long: `Write a normalized copy of the current state to a file. The file is gzipped
+with --gzip or when its name ends in .gz.`,
+	Example: `  badops state export backup/state.json
+  badops state export state-2024-06-01.json.gz`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runStateExport,
+}
+
+var stateImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Load an exported state",
+	Long: `Load a state written by 'badops state export' (plain or gzipped).
+
+With --merge (the default) products are merged into the current state the
+same way as a products import: existing data is kept and updated with the
+file's values, and history is appended. With --replace the current state is
+swapped for the file wholesale.
+
+Files with an incompatible state version are refused.`,
+	Example: `  badops state import backup/state.json
+  badops state import state-2024-06-01.json.gz --replace`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE:         runStateImport,
+}
+
+var stateMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the state file to the current version",
+	Long: fmt.Sprintf(`Upgrade output/.badops-state.json to state version %s, applying each
+migration in turn and recording it in the history. Commands migrate the state
+automatically when they load it; use this to upgrade explicitly, or with
+--dry-run to see which migrations are pending.`, state.StateVersion),
+	SilenceUsage: true,
+	RunE:         runStateMigrate,
+}
+
+func init() {
+	stateExportCmd.Flags().BoolVar(&stateExportGzip, "gzip", false, "Gzip the export (default when the file ends in .gz)")
+	stateImportCmd.Flags().BoolVar(&stateImportMerge, "merge", false, "Merge into the current state (default)")
+	stateImportCmd.Flags().BoolVar(&stateImportReplace, "replace", false, "Replace the current state")
+	stateImportCmd.Flags().BoolVarP(&stateImportYes, "yes", "y", false, "Replace without asking for confirmation")
+	stateImportCmd.MarkFlagsMutuallyExclusive("merge", "replace")
+	stateMigrateCmd.Flags().BoolVar(&stateMigrateDryRun, "dry-run", false, "Show pending migrations without writing")
+
+	stateCmd.AddCommand(stateExportCmd)
+	stateCmd.AddCommand(stateImportCmd)
+	stateCmd.AddCommand(stateMigrateCmd)
+}
+
+func runStateExport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	store := state.NewStore("")
+	if err := store.Load(); err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	defer store.Close()
+
+	compress := stateExportGzip || strings.HasSuffix(path, ".gz")
+	count, err := store.Export(path, compress)
+	if err != nil {
+		return err
+	}
+
+	color.Green("  ✓ Exported %d products to %s", count, path)
+	return nil
+}
+
+func runStateImport(cmd *cobra.Command, args []string) error {
+	path := args[0]
+
+	file, err := state.ReadStateFile(path)
+	if err != nil {
+		return err
+	}
+
+	store := state.NewStore("")
+	if err := store.LoadForUpdate(); err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	defer store.Close()
+
+	if stateImportReplace && store.Count() > 0 && !stateImportYes {
+		fmt.Printf("This will replace the current state (%d products) with %d products from %s.\n",
+			store.Count(), len(file.Products), path)
+		fmt.Print("Are you sure? [y/N]: ")
+		var confirm string
+		fmt.Scanln(&confirm)
+		if confirm != "y" && confirm != "Y" {
+			fmt.Println("Cancelled")
+			return nil
+		}
+	}
+
+	count := store.ImportState(file, stateImportReplace, path)
+	if err := store.Save(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	if stateImportReplace {
+		color.Green("  ✓ Replaced the state with %d products from %s", count, path)
+	} else {
+		color.Green("  ✓ Merged %d products from %s (%d products in state)", count, path, store.Count())
+	}
+	return nil
+}
+
+func runStateMigrate(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(state.DefaultStateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			color.Yellow("  No state file at %s", state.DefaultStateFile)
+			return nil
+		}
+		return err
+	}
+
+	_, steps, err := state.Migrate(data)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		color.Green("  ✓ State is already at version %s", state.StateVersion)
+		return nil
+	}
+
+	for _, step := range steps {
+		fmt.Printf("  • %s\n", step.Details)
+	}
+	if stateMigrateDryRun {
+		color.Yellow("\n  Dry run: %d migrations pending", len(steps))
+		return nil
+	}
+
+	// Loading applies the migrations and saves the result
+	store := state.NewStore("")
+	if err := store.LoadForUpdate(); err != nil {
+		return fmt.Errorf("failed to migrate state: %w", err)
+	}
+	defer store.Close()
+	color.Green("\n  ✓ Migrated state to version %s (%d products)", state.StateVersion, store.Count())
+	return nil
+}
+
+// stateStore is the product state the product commands work on: the JSON
+// state file, or PostgreSQL when database.use_db is set
+type stateStore interface {
+	state.Backend
+	Close()
+
+	GetImportCursor(key string) (time.Time, bool)
+	SetImportCursor(key string, t time.Time)
+	RecordImageUpload(sku, stem, size, resizedPath, publicURL string) bool
+	RecordJournal(entry state.JournalEntry, keep int)
+	ImportLegacyProducts(products []models.Product, source string) int
+	ExportLegacyProducts() []models.Product
+}
+
+// dbStateStore is a PostgresStore that owns its connection
+type dbStateStore struct {
+	*state.PostgresStore
+	client *postgres.Client
+}
+
+// Close closes the database connection
+func (s *dbStateStore) Close() {
+	s.PostgresStore.Close()
+	s.client.Close()
+}
+
+// useDBState reports whether database.use_db keeps product state in PostgreSQL
+func useDBState() (bool, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return false, fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg.Database.UseDB, nil
+}
+
+// openStore loads the product state from PostgreSQL when database.use_db is
+// set and from the JSON state file otherwise. Commands that save pass write,
+// which takes the state file's exclusive lock up front, so a concurrent
+// writer fails with state.ErrLocked before doing any work. Callers Close the
+// store when done.
+func openStore(ctx context.Context, write bool) (stateStore, error) {
+	useDB, err := useDBState()
+	if err != nil {
+		return nil, err
+	}
+
+	if !useDB {
+		store := state.NewStore("")
+		if err := loadStore(store, write); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("failed to load state: %w", err)
+		}
+		return store, nil
+	}
+
+	client, err := getDBClient()
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	store := &dbStateStore{PostgresStore: state.NewPostgresStore(client), client: client}
+	if err := loadStore(store, write); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to load products from PostgreSQL: %w", err)
+	}
+	return store, nil
+}
+
+// loadStore loads store, for update when write is set
+func loadStore(store state.Backend, write bool) error {
+	if write {
+		return store.LoadForUpdate()
+	}
+	return store.Load()
+}
+
+// storeLocation describes where a store keeps its state, for messages
+func storeLocation(store stateStore) string {
+	if _, ok := store.(*dbStateStore); ok {
+		return "PostgreSQL"
+	}
+	return state.DefaultStateFile
+}
+
+// requireJSONState fails commands that only work on the JSON state file when
+// database.use_db is set
+func requireJSONState(command string) error {
+	useDB, err := useDBState()
+	if err != nil {
+		return err
+	}
+	if useDB {
+		return fmt.Errorf("%s works on the JSON state file and is not available with database.use_db", command)
+	}
+	return nil
+}
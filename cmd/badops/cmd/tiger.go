@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/skurules"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var tigerCmd = &cobra.Command{
+	Use:   "tiger",
+	Short: "Tiger.nl scraper tools",
+	Long:  `Manage Tiger.nl scraper settings such as the category and series keyword mappings.`,
+}
+
+var tigerMappingsCmd = &cobra.Command{
+	Use:   "mappings",
+	Short: "Manage Tiger.nl category/series mappings",
+	Long: `Manage the keyword mappings used to build Tiger.nl search URLs.
+
+Mappings are read from ~/.badops/tiger-mappings.yaml, or from the file set in
+sources.tiger_nl.mappings_file. Without a file the built-in mappings are used.
+
+Example file:
+  categories:
+    - keyword: toalettbørste
+      value: toiletborstel
+  series:
+    - keyword: boston
+      value: productserie-boston`,
+}
+
+var tigerMappingsValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Validate a mappings file",
+	Long:  `Check a mappings file for parse errors, duplicate keywords and keywords that can never match.`,
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  runTigerMappingsValidate,
+}
+
+func init() {
+	tigerCmd.AddCommand(tigerMappingsCmd)
+	tigerMappingsCmd.AddCommand(tigerMappingsValidateCmd)
+}
+
+func runTigerMappingsValidate(cmd *cobra.Command, args []string) error {
+	header := color.New(color.FgCyan, color.Bold)
+	success := color.New(color.FgGreen)
+
+	path, err := tigerMappingsPath(args)
+	if err != nil {
+		return err
+	}
+
+	header.Println("\n  VALIDATE TIGER.NL MAPPINGS")
+	fmt.Println("  " + strings.Repeat("─", 50))
+	fmt.Printf("  File: %s\n\n", path)
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		color.Yellow("  File not found, built-in mappings are in use")
+		fmt.Println()
+		return nil
+	}
+
+	mappings, err := matcher.LoadTigerMappings(path)
+	if err != nil {
+		color.Red("  Error: %v", err)
+		return err
+	}
+
+	fmt.Printf("  Categories: %d\n", len(mappings.Categories))
+	fmt.Printf("  Series:     %d\n\n", len(mappings.Series))
+
+	problems := mappings.Validate()
+	if len(problems) == 0 {
+		success.Println("  ✓ Mappings are valid")
+		fmt.Println()
+		return nil
+	}
+
+	for _, p := range problems {
+		color.Red("  ✗ %s", p)
+	}
+	fmt.Println()
+
+	return fmt.Errorf("%d problems found in mappings file", len(problems))
+}
+
+// tigerMappingsPath resolves the mappings file from args, config, or the default location
+func tigerMappingsPath(args []string) (string, error) {
+	if len(args) > 0 {
+		return args[0], nil
+	}
+
+	cfg, err := config.Load()
+	if err == nil && cfg.Sources.TigerNL.MappingsFile != "" {
+		return cfg.Sources.TigerNL.MappingsFile, nil
+	}
+
+	return matcher.DefaultMappingsPath()
+}
+
+// newTigerMatcher creates a Tiger.nl matcher using the configured cache,
+// cache TTLs, request headers, mappings file and SKU rules file. A mappings
+// file that fails to load is an error.
+func newTigerMatcher(ctx context.Context) (*matcher.TigerMatcher, error) {
+	m := matcher.NewTigerMatcher()
+	mappingsErr := m.GetScraper().MappingsError()
+	if cfg, err := config.Load(); err == nil {
+		if path := cfg.Sources.TigerNL.MappingsFile; path != "" {
+			mappingsErr = m.GetScraper().LoadMappingsFile(path)
+		}
+		if cache := tigerMatchCache(ctx, cfg); cache != nil {
+			m.GetScraper().SetCache(cache)
+		}
+		m.GetScraper().SetCacheTTL(cfg.Sources.TigerNL.CacheDuration(), cfg.Sources.TigerNL.NegativeCacheDuration())
+		m.GetScraper().SetHeaders(cfg.Sources.TigerNL.UserAgent, cfg.Sources.TigerNL.AcceptLanguage)
+		if cfg.Defaults.SKURulesFile != "" {
+			if rules, err := skurules.Load(cfg.Defaults.SKURulesFile); err == nil {
+				m.GetSKUMapper().SetRules(rules)
+			} else {
+				color.Yellow("  Warning: failed to load SKU rules: %v", err)
+			}
+		}
+	}
+	if mappingsErr != nil {
+		return nil, fmt.Errorf("failed to load Tiger.nl mappings: %w", mappingsErr)
+	}
+	return m, nil
+}
+
+// matchCacheDB is the connection opened for the shared match cache. Execute
+// closes it when the command finishes.
+var matchCacheDB *postgres.Client
+
+// tigerMatchCache returns the Postgres match cache when the database backend
+// is enabled, or nil to keep the file cache. An unreachable database falls
+// back to the file cache with a warning.
+func tigerMatchCache(ctx context.Context, cfg *config.Config) matcher.Cache {
+	if !cfg.Database.UseDB {
+		return nil
+	}
+	if matchCacheDB == nil {
+		client, err := getDBClient()
+		if err == nil {
+			err = client.Connect(ctx)
+		}
+		if err != nil {
+			color.Yellow("  Warning: database unavailable, using the file match cache: %v", err)
+			return nil
+		}
+		matchCacheDB = client
+	}
+	return postgres.NewMatchCacheRepo(matchCacheDB)
+}
@@ -0,0 +1,512 @@
+// Package api serves products, competitor prices and price alerts over a
+// JSON HTTP API, and ingests Shopify product webhooks
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/internal/database/clickhouse"
+	"github.com/badno/badops/internal/source/shopify"
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+)
+
+// Pagination and query defaults
+const (
+	DefaultLimit     = 50
+	MaxLimit         = 500
+	DefaultPriceDays = 30   // History returned by /products/{sku}/prices
+	DefaultThreshold = 10.0 // Percent difference that makes a price alert
+)
+
+// shutdownTimeout is how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+// Connection timeouts, so slow or idle clients can't hold connections open.
+// The read timeout leaves room for a maxWebhookBody upload; the write timeout
+// covers the slowest handler, the ClickHouse alert query.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+)
+
+// maxWebhookBody caps a webhook payload; products with hundreds of variants
+// stay well below it
+const maxWebhookBody = 5 << 20
+
+// AlertSource finds products priced away from the market average.
+// *clickhouse.Client implements it.
+type AlertSource interface {
+	GetPriceAlerts(ctx context.Context, thresholdPercent float64, ownPrices map[string]float64) ([]clickhouse.PriceAlert, error)
+}
+
+// Config holds the repositories the server reads from
+type Config struct {
+	Products    database.ProductRepository
+	Competitors database.CompetitorRepository
+	Prices      database.PriceObservationRepository
+
+	// Alerts is optional; without it /analytics/alerts responds 503
+	Alerts AlertSource
+
+	// ShopifyWebhookSecret enables POST /webhooks/shopify/products, which
+	// saves the products Shopify sends. It is not routed when ReadOnly is set.
+	ShopifyWebhookSecret string
+	ReadOnly             bool
+}
+
+// Server handles the API requests
+type Server struct {
+	products    database.ProductRepository
+	competitors database.CompetitorRepository
+	prices      database.PriceObservationRepository
+	alerts      AlertSource
+	logger      *slog.Logger
+
+	webhookSecret string // Empty when webhooks are disabled
+}
+
+// New creates a server reading from the configured repositories
+func New(cfg Config) *Server {
+	s := &Server{
+		products:    cfg.Products,
+		competitors: cfg.Competitors,
+		prices:      cfg.Prices,
+		alerts:      cfg.Alerts,
+		logger:      slog.Default().With("component", "api"),
+	}
+	if !cfg.ReadOnly {
+		s.webhookSecret = cfg.ShopifyWebhookSecret
+	}
+	return s
+}
+
+// WebhooksEnabled reports whether POST /webhooks/shopify/products is routed
+func (s *Server) WebhooksEnabled() bool {
+	return s.webhookSecret != ""
+}
+
+// Handler returns the API routes. Only GET (and HEAD) is routed, plus the
+// Shopify webhook when enabled; other methods get 405 Method Not Allowed.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /products", s.listProducts)
+	mux.HandleFunc("GET /products/{sku}", s.getProduct)
+	mux.HandleFunc("GET /products/{sku}/prices", s.getProductPrices)
+	mux.HandleFunc("GET /competitors", s.listCompetitors)
+	mux.HandleFunc("GET /analytics/alerts", s.listAlerts)
+	if s.WebhooksEnabled() {
+		mux.HandleFunc("POST /webhooks/shopify/products", s.shopifyProductWebhook)
+	}
+	return s.logRequests(mux)
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled, then gives
+// in-flight requests shutdownTimeout to finish. The listener is opened
+// first, so a bad address or a port in use is returned immediately.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.Serve(ln) }()
+	s.logger.Info("serving API", "addr", ln.Addr().String())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down API server: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// CompetitorPage is one page of competitors
+type CompetitorPage struct {
+	Competitors []*database.Competitor `json:"competitors"`
+	TotalCount  int64                  `json:"total_count"`
+	Limit       int                    `json:"limit"`
+	Offset      int                    `json:"offset"`
+	HasMore     bool                   `json:"has_more"`
+}
+
+// AlertPage is one page of price alerts, largest difference first
+type AlertPage struct {
+	Alerts     []clickhouse.PriceAlert `json:"alerts"`
+	Threshold  float64                 `json:"threshold"`
+	TotalCount int64                   `json:"total_count"`
+	Limit      int                     `json:"limit"`
+	Offset     int                     `json:"offset"`
+	HasMore    bool                    `json:"has_more"`
+}
+
+// ProductPrices is a product's competitor prices and market position
+type ProductPrices struct {
+	SKU     string                       `json:"sku"`
+	Price   *models.Price                `json:"price,omitempty"` // Our price
+	Days    int                          `json:"days"`
+	Latest  []*database.PriceObservation `json:"latest"`  // Each competitor's latest price
+	History []*database.PriceObservation `json:"history"` // Every observation in the last Days days
+	Market  *database.MarketStats        `json:"market,omitempty"`
+}
+
+// listProducts handles GET /products?limit=&offset=&vendor=&status=
+func (s *Server) listProducts(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	q := r.URL.Query()
+	opts := database.QueryOptions{
+		Limit:    limit,
+		Offset:   offset,
+		OrderBy:  "sku",
+		OrderDir: "ASC",
+		Vendor:   q.Get("vendor"),
+		Status:   models.ProductStatus(q.Get("status")),
+	}
+
+	page, err := s.products.GetAllPaged(r.Context(), opts)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	if page.Products == nil {
+		page.Products = []*models.EnhancedProduct{}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// getProduct handles GET /products/{sku}
+func (s *Server) getProduct(w http.ResponseWriter, r *http.Request) {
+	product, ok := s.productBySKU(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, product)
+}
+
+// getProductPrices handles GET /products/{sku}/prices?days=
+func (s *Server) getProductPrices(w http.ResponseWriter, r *http.Request) {
+	days, err := intParam(r, "days", DefaultPriceDays)
+	if err != nil || days <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid days %q", r.URL.Query().Get("days")))
+		return
+	}
+	product, ok := s.productBySKU(w, r)
+	if !ok {
+		return
+	}
+	productID, err := uuid.Parse(product.ID)
+	if err != nil {
+		s.serverError(w, r, fmt.Errorf("invalid product ID %q: %w", product.ID, err))
+		return
+	}
+
+	ctx := r.Context()
+	latest, err := s.prices.GetLatestByProduct(ctx, productID)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	history, err := s.prices.GetPriceHistory(ctx, productID, days)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	stats, err := s.prices.GetMarketStats(ctx, productID, days)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ProductPrices{
+		SKU:     product.SKU,
+		Price:   product.Price,
+		Days:    days,
+		Latest:  nonNil(latest),
+		History: nonNil(history),
+		Market:  stats,
+	})
+}
+
+// listCompetitors handles GET /competitors?limit=&offset=
+func (s *Server) listCompetitors(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	competitors, err := s.competitors.GetAll(r.Context())
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+
+	items, total, hasMore := paginate(competitors, limit, offset)
+	writeJSON(w, http.StatusOK, CompetitorPage{
+		Competitors: items,
+		TotalCount:  total,
+		Limit:       limit,
+		Offset:      offset,
+		HasMore:     hasMore,
+	})
+}
+
+// listAlerts handles GET /analytics/alerts?threshold=&vendor=&limit=&offset=
+func (s *Server) listAlerts(w http.ResponseWriter, r *http.Request) {
+	if s.alerts == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("analytics are not available (ClickHouse not connected)"))
+		return
+	}
+	limit, offset, err := pagination(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	threshold := DefaultThreshold
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		threshold, err = strconv.ParseFloat(v, 64)
+		if err != nil || threshold < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid threshold %q", v))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	products, err := s.products.GetAll(ctx, database.QueryOptions{Vendor: r.URL.Query().Get("vendor")})
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	ownPrices := make(map[string]float64)
+	for _, p := range products {
+		if p.Price != nil && p.Price.Amount > 0 {
+			ownPrices[p.SKU] = p.Price.Amount
+		}
+	}
+
+	var alerts []clickhouse.PriceAlert
+	if len(ownPrices) > 0 {
+		alerts, err = s.alerts.GetPriceAlerts(ctx, threshold, ownPrices)
+		if err != nil {
+			s.serverError(w, r, err)
+			return
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].DiffPercent > alerts[j].DiffPercent
+	})
+
+	items, total, hasMore := paginate(alerts, limit, offset)
+	writeJSON(w, http.StatusOK, AlertPage{
+		Alerts:     items,
+		Threshold:  threshold,
+		TotalCount: total,
+		Limit:      limit,
+		Offset:     offset,
+		HasMore:    hasMore,
+	})
+}
+
+// shopifyProductWebhook handles POST /webhooks/shopify/products for the
+// products/create and products/update topics. The product is upserted by SKU
+// like an import: an existing product keeps its status and cost, which
+// Shopify doesn't send. Payloads badops can't use are acknowledged so
+// Shopify doesn't retry them; only a failed save asks for a retry.
+func (s *Server) shopifyProductWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeError(w, status, fmt.Errorf("failed to read payload: %w", err))
+		return
+	}
+	if !shopify.VerifyWebhook(body, r.Header.Get(shopify.HeaderHMAC), s.webhookSecret) {
+		s.logger.Warn("rejected webhook with invalid signature", "remote", r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, errors.New("invalid webhook signature"))
+		return
+	}
+
+	topic := r.Header.Get(shopify.HeaderTopic)
+	if topic != shopify.TopicProductsCreate && topic != shopify.TopicProductsUpdate {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "topic": topic})
+		return
+	}
+	update, err := shopify.ParseProductWebhook(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if update.SKU == "" {
+		s.logger.Warn("skipped webhook product without SKU", "topic", topic, "shopify_id", update.ID)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "skipped", "reason": "product has no SKU"})
+		return
+	}
+
+	ctx := r.Context()
+	existing, err := s.products.GetBySKU(ctx, update.SKU)
+	if err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+	mergeWebhookProduct(existing, &update)
+	if err := s.products.SaveEnhanced(ctx, &update); err != nil {
+		s.serverError(w, r, err)
+		return
+	}
+
+	s.logger.Info("saved webhook product", "topic", topic, "sku", update.SKU, "created", existing == nil)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "saved", "sku": update.SKU})
+}
+
+// mergeWebhookProduct prepares a webhook product for saving over existing
+// (nil for a new product). The Shopify product ID is not a database ID.
+func mergeWebhookProduct(existing, update *models.EnhancedProduct) {
+	update.ID = ""
+	if existing == nil {
+		return
+	}
+	update.ID = existing.ID
+	update.Status = existing.Status
+	if existing.Price != nil && update.Price != nil {
+		update.Price.CostPerItem = existing.Price.CostPerItem
+	}
+}
+
+// productBySKU looks up the {sku} path value, writing a 404 or 500 response
+// when there is no product to return
+func (s *Server) productBySKU(w http.ResponseWriter, r *http.Request) (*models.EnhancedProduct, bool) {
+	sku := r.PathValue("sku")
+	product, err := s.products.GetBySKU(r.Context(), sku)
+	if err != nil {
+		s.serverError(w, r, err)
+		return nil, false
+	}
+	if product == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("product %s not found", sku))
+		return nil, false
+	}
+	return product, true
+}
+
+// pagination parses the limit and offset query parameters
+func pagination(r *http.Request) (limit, offset int, err error) {
+	limit, err = intParam(r, "limit", DefaultLimit)
+	if err != nil || limit <= 0 || limit > MaxLimit {
+		return 0, 0, fmt.Errorf("invalid limit %q (1-%d)", r.URL.Query().Get("limit"), MaxLimit)
+	}
+	offset, err = intParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		return 0, 0, fmt.Errorf("invalid offset %q", r.URL.Query().Get("offset"))
+	}
+	return limit, offset, nil
+}
+
+// intParam returns an integer query parameter, or def when it is not set
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
+// paginate returns one page of items, the total count and whether more
+// pages follow. A page is never nil, so it encodes as [].
+func paginate[T any](items []T, limit, offset int) ([]T, int64, bool) {
+	total := len(items)
+	start := min(offset, total)
+	end := min(start+limit, total)
+	return nonNil(items[start:end]), int64(total), end < total
+}
+
+// nonNil returns items, or an empty slice when items is nil
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// serverError logs err and responds 500 without exposing its details
+func (s *Server) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() != nil {
+		return // Client went away or the server is shutting down
+	}
+	s.logger.Error("request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeError(w, http.StatusInternalServerError, errors.New("internal server error"))
+}
+
+// writeError writes an {"error": ...} response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes v as an indented JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// statusRecorder remembers the status code written through it
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs each request's method, path, status and duration at
+// debug level
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		s.logger.Debug("request", "method", r.Method, "path", r.URL.Path,
+			"status", rec.status, "duration", time.Since(start))
+	})
+}
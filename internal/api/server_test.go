@@ -0,0 +1,85 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/badno/badops/internal/source/shopify"
+)
+
+const testWebhookSecret = "shpss_test"
+
+func signWebhook(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestShopifyProductWebhook(t *testing.T) {
+	// None of these requests reach the repositories
+	handler := New(Config{ShopifyWebhookSecret: testWebhookSecret}).Handler()
+
+	body := []byte(`{"id":1,"title":"Tiger Boston"}`)
+	tooLarge := bytes.Repeat([]byte(" "), maxWebhookBody+1)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		signature  string
+		topic      string
+		wantStatus int
+		wantBody   map[string]string
+	}{
+		{"invalid signature", body, signWebhook([]byte("{}")), shopify.TopicProductsUpdate, http.StatusUnauthorized, nil},
+		{"missing signature", body, "", shopify.TopicProductsUpdate, http.StatusUnauthorized, nil},
+		{"payload too large", tooLarge, signWebhook(tooLarge), shopify.TopicProductsUpdate, http.StatusRequestEntityTooLarge, nil},
+		{"ignored topic", body, signWebhook(body), "products/delete", http.StatusOK,
+			map[string]string{"status": "ignored", "topic": "products/delete"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/shopify/products", bytes.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(shopify.HeaderHMAC, tt.signature)
+			}
+			req.Header.Set(shopify.HeaderTopic, tt.topic)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantBody == nil {
+				return
+			}
+			var got map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON response: %v", err)
+			}
+			for k, v := range tt.wantBody {
+				if got[k] != v {
+					t.Errorf("%s = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotRoutedWhenReadOnly(t *testing.T) {
+	handler := New(Config{ShopifyWebhookSecret: testWebhookSecret, ReadOnly: true}).Handler()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/shopify/products", bytes.NewReader([]byte("{}")))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -4,7 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/badno/badops/internal/source/rest"
+	"github.com/badno/badops/pkg/models"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,6 +24,9 @@ type Config struct {
 	Outputs   OutputsConfig   `yaml:"outputs"`
 	Database  DatabaseConfig  `yaml:"database,omitempty"`
 	Defaults  DefaultsConfig  `yaml:"defaults,omitempty"`
+	Notify    NotifyConfig    `yaml:"notify,omitempty"`
+	Currency  CurrencyConfig  `yaml:"currency,omitempty"`
+	Images    ImagesConfig    `yaml:"images,omitempty"`
 }
 
 // SourcesConfig contains configuration for all source connectors
@@ -26,23 +34,81 @@ type SourcesConfig struct {
 	Shopify  ShopifySourceConfig  `yaml:"shopify"`
 	NOBB     NOBBConfig           `yaml:"nobb"`
 	TigerNL  TigerNLConfig        `yaml:"tiger_nl"`
+	Matrixify MatrixifyConfig     `yaml:"matrixify,omitempty"`
+	WooCommerce WooCommerceConfig `yaml:"woocommerce,omitempty"`
+	REST     rest.Config          `yaml:"rest,omitempty"` // Generic JSON API mapped by field paths
+}
+
+// WooCommerceConfig holds WooCommerce source settings
+type WooCommerceConfig struct {
+	URL               string `yaml:"url,omitempty"`                 // Store URL (e.g., https://shop.example.no)
+	ConsumerKeyEnv    string `yaml:"consumer_key_env,omitempty"`    // Credential reference for the REST API consumer key
+	ConsumerSecretEnv string `yaml:"consumer_secret_env,omitempty"` // Credential reference for the REST API consumer secret
+	Currency          string `yaml:"currency,omitempty"`            // Store currency (default: NOK)
+}
+
+// MatrixifyConfig holds Matrixify export file source settings
+type MatrixifyConfig struct {
+	File string `yaml:"file,omitempty"` // Default export CSV for 'products import --source matrixify'
 }
 
 // ShopifySourceConfig holds Shopify source settings
 type ShopifySourceConfig struct {
 	Store     string `yaml:"store"`       // Store name (e.g., "badno")
 	APIKeyEnv string `yaml:"api_key_env"` // Environment variable for API key
+
+	// WebhookSecretEnv is the credential reference for the app's webhook
+	// signing secret; serve accepts product webhooks only when it is set
+	WebhookSecretEnv string `yaml:"webhook_secret_env,omitempty"`
 }
 
 // NOBBConfig holds NOBB settings
 type NOBBConfig struct {
-	UsernameEnv string `yaml:"username_env"` // Environment variable for username
-	PasswordEnv string `yaml:"password_env"` // Environment variable for password
+	UsernameEnv string `yaml:"username_env"`            // Environment variable for username
+	PasswordEnv string `yaml:"password_env"`            // Environment variable for password
+	RateLimitMs int    `yaml:"rate_limit_ms,omitempty"` // Milliseconds between requests (default: 100)
 }
 
 // TigerNLConfig holds Tiger.nl settings
 type TigerNLConfig struct {
-	RateLimitMs int `yaml:"rate_limit_ms"` // Milliseconds between requests
+	RateLimitMs      int    `yaml:"rate_limit_ms"`                // Milliseconds between requests
+	MappingsFile     string `yaml:"mappings_file,omitempty"`      // Category/series mappings (default: ~/.badops/tiger-mappings.yaml)
+	CacheTTL         string `yaml:"cache_ttl,omitempty"`          // How long lookups stay cached, e.g. 24h or 7d (default: 24h)
+	NegativeCacheTTL string `yaml:"negative_cache_ttl,omitempty"` // How long "not found" results stay cached (default: cache_ttl)
+	UserAgent        string `yaml:"user_agent,omitempty"`         // User-Agent sent to Tiger.nl (default: BadOps-TigerScraper/1.0 (+https://bad.no))
+	AcceptLanguage   string `yaml:"accept_language,omitempty"`    // Accept-Language sent to Tiger.nl (default: nl-NL,nl;q=0.9,en;q=0.5)
+}
+
+// CacheDuration returns the parsed cache_ttl. An unset or invalid value is
+// returned as 0 so the scraper's default applies; config validate reports it.
+func (c TigerNLConfig) CacheDuration() time.Duration {
+	ttl, _ := ParseDuration(c.CacheTTL)
+	return ttl
+}
+
+// NegativeCacheDuration returns the parsed negative_cache_ttl, falling back
+// to CacheDuration
+func (c TigerNLConfig) NegativeCacheDuration() time.Duration {
+	if ttl, err := ParseDuration(c.NegativeCacheTTL); err == nil && ttl > 0 {
+		return ttl
+	}
+	return c.CacheDuration()
+}
+
+// ParseDuration parses a number of days such as "7d", or any Go duration such
+// as "12h". An empty value parses as 0.
+func ParseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid duration %q (use e.g. 7d or 12h)", value)
 }
 
 // OutputsConfig contains configuration for all output adapters
@@ -71,8 +137,10 @@ type ClickHouseConfig struct {
 
 // FileOutputConfig holds file output settings
 type FileOutputConfig struct {
-	OutputDir string `yaml:"output_dir"`
-	Pretty    bool   `yaml:"pretty"`
+	OutputDir     string `yaml:"output_dir"`
+	Pretty        bool   `yaml:"pretty"`
+	StoreURL      string `yaml:"store_url,omitempty"`       // Storefront base URL for product links (e.g., https://badno.no)
+	ColumnMapFile string `yaml:"column_map_file,omitempty"` // YAML column map for Matrixify CSV exports
 }
 
 // DatabaseConfig holds database connection settings
@@ -90,6 +158,27 @@ type PostgresConfig struct {
 	UsernameEnv string `yaml:"username_env"`
 	PasswordEnv string `yaml:"password_env"`
 	SSLMode     string `yaml:"ssl_mode"`
+
+	MaxConns        int    `yaml:"max_conns,omitempty"`          // Pool size (default: 25)
+	MinConns        int    `yaml:"min_conns,omitempty"`          // Connections kept open when idle (default: 5)
+	MaxConnLifetime string `yaml:"max_conn_lifetime,omitempty"`  // Recycle connections after this long, e.g. 1h (default: 1h)
+	MaxConnIdleTime string `yaml:"max_conn_idle_time,omitempty"` // Close idle connections after this long, e.g. 30m (default: 30m)
+	ConnectRetries  int    `yaml:"connect_retries,omitempty"`    // Retries while the server is unreachable at startup (default: 3, -1 disables)
+}
+
+// ConnLifetime returns the parsed max_conn_lifetime. An unset or invalid
+// value is returned as 0 so the client's default applies; config validate
+// reports it.
+func (c PostgresConfig) ConnLifetime() time.Duration {
+	d, _ := ParseDuration(c.MaxConnLifetime)
+	return d
+}
+
+// ConnIdleTime returns the parsed max_conn_idle_time, or 0 when unset or
+// invalid
+func (c PostgresConfig) ConnIdleTime() time.Duration {
+	d, _ := ParseDuration(c.MaxConnIdleTime)
+	return d
 }
 
 // ClickHouseDBConfig holds ClickHouse database settings for analytics
@@ -107,6 +196,74 @@ type DefaultsConfig struct {
 	Vendor          string   `yaml:"vendor,omitempty"`           // Default vendor filter
 	EnhanceSources  []string `yaml:"enhance_sources,omitempty"`  // Default enhancement sources
 	ExportFormat    string   `yaml:"export_format,omitempty"`    // Default export format
+	DimensionUnit   string   `yaml:"dimension_unit,omitempty"`   // Unit for enhanced dimensions (mm, cm, m, in)
+	WeightUnit      string   `yaml:"weight_unit,omitempty"`      // Unit for enhanced weight (g, kg, lb)
+	SourcePriority  []string `yaml:"source_priority,omitempty"`  // Enhancement sources, highest priority first
+	OverwritePolicy string   `yaml:"overwrite_policy,omitempty"` // fill_empty, prefer_source or always
+	JournalRuns     int      `yaml:"journal_runs,omitempty"`     // Enhance runs kept for rollback (default: 5)
+	BrandPrefixes   []string `yaml:"brand_prefixes,omitempty"`   // Brands stripped from the start of titles when matching (default: Tiger)
+	SKURulesFile    string   `yaml:"sku_rules_file,omitempty"`   // SKU → candidate ID rules (default: ~/.badops/sku-rules.yaml)
+}
+
+// DefaultMinDiffPercent is how far above the cheapest competitor our price
+// must be before an undercut alert is sent
+const DefaultMinDiffPercent = 10.0
+
+// NotifyConfig holds price alert notification settings
+type NotifyConfig struct {
+	WebhookURLEnv  string  `yaml:"webhook_url_env,omitempty"`  // Credential reference for the webhook URL (env:, file:, cmd:)
+	MinDiffPercent float64 `yaml:"min_diff_percent,omitempty"` // Alert when our price is this far above the market min (default: 10)
+	Format         string  `yaml:"format,omitempty"`           // slack or json (default: slack for hooks.slack.com, else json)
+}
+
+// MinDiff returns min_diff_percent, or DefaultMinDiffPercent when unset
+func (c NotifyConfig) MinDiff() float64 {
+	if c.MinDiffPercent > 0 {
+		return c.MinDiffPercent
+	}
+	return DefaultMinDiffPercent
+}
+
+// DefaultBaseCurrency is the currency competitor prices are normalized to
+// when currency.base is unset
+const DefaultBaseCurrency = "NOK"
+
+// CurrencyConfig holds competitor price currency normalization settings
+type CurrencyConfig struct {
+	Base     string             `yaml:"base,omitempty"`      // Currency prices are normalized to (default: NOK)
+	Rates    map[string]float64 `yaml:"rates,omitempty"`     // Units of base per unit of each currency, e.g. EUR: 11.7
+	RatesURL string             `yaml:"rates_url,omitempty"` // Rates API for currencies without a static rate, e.g. https://api.frankfurter.app/latest?from={base}
+}
+
+// BaseCurrency returns currency.base, or DefaultBaseCurrency when unset
+func (c CurrencyConfig) BaseCurrency() string {
+	if c.Base != "" {
+		return strings.ToUpper(c.Base)
+	}
+	return DefaultBaseCurrency
+}
+
+// ImagesConfig holds image processing settings
+type ImagesConfig struct {
+	Upload ImageUploadConfig `yaml:"upload,omitempty"`
+}
+
+// ImageUploadConfig holds the S3-compatible bucket 'images upload' publishes
+// resized images to
+type ImageUploadConfig struct {
+	Endpoint     string `yaml:"endpoint,omitempty"`       // e.g. https://s3.eu-north-1.amazonaws.com or https://<account>.r2.cloudflarestorage.com
+	Region       string `yaml:"region,omitempty"`         // Signing region (default: us-east-1; R2 uses auto)
+	Bucket       string `yaml:"bucket,omitempty"`
+	Prefix       string `yaml:"prefix,omitempty"`         // Key prefix, e.g. products/
+	PublicURL    string `yaml:"public_url,omitempty"`     // CDN base URL objects are served from (default: endpoint/bucket)
+	AccessKeyEnv string `yaml:"access_key_env,omitempty"` // Credential reference for the access key ID (env:, file:, cmd:)
+	SecretKeyEnv string `yaml:"secret_key_env,omitempty"` // Credential reference for the secret access key
+	ACL          string `yaml:"acl,omitempty"`            // Canned ACL such as public-read (default: none)
+}
+
+// Configured reports whether an upload bucket is set
+func (c ImageUploadConfig) Configured() bool {
+	return c.Endpoint != "" && c.Bucket != ""
 }
 
 // DefaultConfig returns a config with sensible defaults
@@ -163,9 +320,13 @@ func DefaultConfig() *Config {
 			},
 		},
 		Defaults: DefaultsConfig{
-			Vendor:         "Tiger",
-			EnhanceSources: []string{"tiger_nl", "nobb"},
-			ExportFormat:   "matrixify",
+			Vendor:          "Tiger",
+			EnhanceSources:  []string{"tiger_nl", "nobb"},
+			ExportFormat:    "matrixify",
+			DimensionUnit:   "mm",
+			WeightUnit:      "kg",
+			SourcePriority:  []string{"nobb", "tiger_nl"},
+			OverwritePolicy: string(models.OverwriteFillEmpty),
 		},
 	}
 }
@@ -190,8 +351,14 @@ func Load() (*Config, error) {
 	return LoadFrom(configPath)
 }
 
-// LoadFrom reads the configuration from a specific path
+// LoadFrom reads the configuration from a specific path. Values may reference
+// environment variables as ${VAR} or ${VAR:-default}; a .env file next to the
+// config file is loaded first.
 func LoadFrom(path string) (*Config, error) {
+	if err := loadDotEnv(filepath.Join(filepath.Dir(path), DotEnvFile)); err != nil {
+		return nil, err
+	}
+
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -201,10 +368,19 @@ func LoadFrom(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	// Expand after parsing so a value can never inject YAML structure
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	interpolateNode(&doc)
+
+	var config Config
+	if len(doc.Content) > 0 {
+		if err := doc.Decode(&config); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
 
 	// Apply defaults for missing values
 	applyDefaults(&config)
@@ -222,7 +398,8 @@ func Save(config *Config) error {
 	return SaveTo(config, configPath)
 }
 
-// SaveTo writes the configuration to a specific path
+// SaveTo writes the configuration to a specific path. Environment references
+// in the existing file are kept for values that still expand to the same thing.
 func SaveTo(config *Config, path string) error {
 	// Ensure directory exists
 	dir := filepath.Dir(path)
@@ -230,7 +407,19 @@ func SaveTo(config *Config, path string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := yaml.Marshal(config)
+	var doc yaml.Node
+	if err := doc.Encode(config); err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	if existing, err := os.ReadFile(path); err == nil {
+		var original yaml.Node
+		if yaml.Unmarshal(existing, &original) == nil && len(original.Content) > 0 {
+			restoreEnvRefs(original.Content[0], &doc)
+		}
+	}
+
+	data, err := yaml.Marshal(&doc)
 	if err != nil {
 		return fmt.Errorf("failed to serialize config: %w", err)
 	}
@@ -284,9 +473,24 @@ func applyDefaults(config *Config) {
 	if config.Outputs.File.OutputDir == "" {
 		config.Outputs.File.OutputDir = defaults.Outputs.File.OutputDir
 	}
+
+	// Defaults
+	if config.Defaults.DimensionUnit == "" {
+		config.Defaults.DimensionUnit = defaults.Defaults.DimensionUnit
+	}
+	if config.Defaults.WeightUnit == "" {
+		config.Defaults.WeightUnit = defaults.Defaults.WeightUnit
+	}
+	if len(config.Defaults.SourcePriority) == 0 {
+		config.Defaults.SourcePriority = defaults.Defaults.SourcePriority
+	}
+	if config.Defaults.OverwritePolicy == "" {
+		config.Defaults.OverwritePolicy = defaults.Defaults.OverwritePolicy
+	}
 }
 
-// Set updates a specific config value
+// Set updates a specific config value. The key is the dotted YAML path of
+// the field, e.g. database.clickhouse.port.
 func Set(key, value string) error {
 	config, err := Load()
 	if err != nil {
@@ -294,81 +498,42 @@ func Set(key, value string) error {
 	}
 
 	switch key {
-	case "sources.shopify.store":
-		config.Sources.Shopify.Store = value
-	case "sources.shopify.api_key_env":
-		config.Sources.Shopify.APIKeyEnv = value
-	case "sources.nobb.username_env":
-		config.Sources.NOBB.UsernameEnv = value
-	case "sources.nobb.password_env":
-		config.Sources.NOBB.PasswordEnv = value
-	case "outputs.file.output_dir":
-		config.Outputs.File.OutputDir = value
-	case "defaults.vendor":
-		config.Defaults.Vendor = value
-	case "defaults.export_format":
-		config.Defaults.ExportFormat = value
-	case "database.use_db":
-		config.Database.UseDB = value == "true"
-	case "database.postgres.host":
-		config.Database.Postgres.Host = value
-	case "database.postgres.database":
-		config.Database.Postgres.Database = value
-	case "database.postgres.username_env":
-		config.Database.Postgres.UsernameEnv = value
-	case "database.postgres.password_env":
-		config.Database.Postgres.PasswordEnv = value
-	case "database.clickhouse.host":
-		config.Database.ClickHouse.Host = value
-	case "database.clickhouse.database":
-		config.Database.ClickHouse.Database = value
-	default:
-		return fmt.Errorf("unknown config key: %s", key)
+	case "defaults.dimension_unit":
+		if !models.IsLengthUnit(value) {
+			return fmt.Errorf("unsupported dimension unit: %s (use mm, cm, m or in)", value)
+		}
+	case "defaults.weight_unit":
+		if !models.IsWeightUnit(value) {
+			return fmt.Errorf("unsupported weight unit: %s (use g, kg or lb)", value)
+		}
+	case "defaults.overwrite_policy":
+		if !models.IsOverwritePolicy(value) {
+			return fmt.Errorf("unsupported overwrite policy: %s (use fill_empty, prefer_source or always)", value)
+		}
+	case "notify.format":
+		if value != "" && value != "slack" && value != "json" {
+			return fmt.Errorf("unsupported notify format: %s (use slack or json)", value)
+		}
+	case "currency.base":
+		if len(value) != 3 {
+			return fmt.Errorf("currency.base must be an ISO 4217 code such as NOK, got %q", value)
+		}
+		value = strings.ToUpper(value)
+	}
+
+	if err := setPath(config, key, value); err != nil {
+		return err
 	}
 
 	return Save(config)
 }
 
-// Get retrieves a specific config value
+// Get retrieves a specific config value by its dotted YAML path
 func Get(key string) (string, error) {
 	config, err := Load()
 	if err != nil {
 		return "", err
 	}
 
-	switch key {
-	case "sources.shopify.store":
-		return config.Sources.Shopify.Store, nil
-	case "sources.shopify.api_key_env":
-		return config.Sources.Shopify.APIKeyEnv, nil
-	case "sources.nobb.username_env":
-		return config.Sources.NOBB.UsernameEnv, nil
-	case "sources.nobb.password_env":
-		return config.Sources.NOBB.PasswordEnv, nil
-	case "outputs.file.output_dir":
-		return config.Outputs.File.OutputDir, nil
-	case "defaults.vendor":
-		return config.Defaults.Vendor, nil
-	case "defaults.export_format":
-		return config.Defaults.ExportFormat, nil
-	case "database.use_db":
-		if config.Database.UseDB {
-			return "true", nil
-		}
-		return "false", nil
-	case "database.postgres.host":
-		return config.Database.Postgres.Host, nil
-	case "database.postgres.database":
-		return config.Database.Postgres.Database, nil
-	case "database.postgres.username_env":
-		return config.Database.Postgres.UsernameEnv, nil
-	case "database.postgres.password_env":
-		return config.Database.Postgres.PasswordEnv, nil
-	case "database.clickhouse.host":
-		return config.Database.ClickHouse.Host, nil
-	case "database.clickhouse.database":
-		return config.Database.ClickHouse.Database, nil
-	default:
-		return "", fmt.Errorf("unknown config key: %s", key)
-	}
+	return getPath(config, key)
 }
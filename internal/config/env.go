@@ -0,0 +1,147 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DotEnvFile is loaded from the config directory before interpolation
+const DotEnvFile = ".env"
+
+// loadDotEnv sets the variables from a .env file that are not already set in
+// the process environment. A missing file is not an error.
+//
+// Lines are KEY=VALUE, optionally prefixed with "export". Blank lines and
+// lines starting with # are skipped, and matching quotes around the value are
+// removed.
+func loadDotEnv(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNum)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		// The real environment wins over the file
+		if _, set := os.LookupEnv(key); !set {
+			os.Setenv(key, value)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// expandEnv replaces ${VAR} with the value of VAR and ${VAR:-default} with
+// default when VAR is unset or empty. Unset variables without a default
+// expand to an empty string. $$ is a literal $, and any other $ is kept as is.
+func expandEnv(s string) string {
+	if !strings.Contains(s, "$") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				// Unterminated, keep the rest verbatim
+				b.WriteString(s[i:])
+				return b.String()
+			}
+			expr := s[i+2 : i+2+end]
+			name, def, hasDefault := strings.Cut(expr, ":-")
+			value := os.Getenv(name)
+			if value == "" && hasDefault {
+				value = def
+			}
+			b.WriteString(value)
+			i += end + 2
+		default:
+			b.WriteByte('$')
+		}
+	}
+
+	return b.String()
+}
+
+// interpolateNode expands environment references in every scalar value of a
+// parsed YAML document. Keys are left alone.
+func interpolateNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		expanded := expandEnv(node.Value)
+		if expanded != node.Value {
+			node.Value = expanded
+			// Let an unquoted value resolve to its expanded type (e.g., a port)
+			if node.Style == 0 {
+				node.Tag = ""
+			}
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			interpolateNode(node.Content[i])
+		}
+	default:
+		for _, child := range node.Content {
+			interpolateNode(child)
+		}
+	}
+}
+
+// restoreEnvRefs copies environment references from the original document
+// into updated wherever the original value still expands to the updated one,
+// so saving a loaded config doesn't bake in the current environment
+func restoreEnvRefs(original, updated *yaml.Node) {
+	switch {
+	case original.Kind == yaml.ScalarNode && updated.Kind == yaml.ScalarNode:
+		if strings.Contains(original.Value, "$") && expandEnv(original.Value) == updated.Value {
+			updated.Value = original.Value
+			updated.Tag = original.Tag
+			updated.Style = original.Style
+		}
+	case original.Kind == yaml.MappingNode && updated.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(updated.Content); i += 2 {
+			for j := 0; j+1 < len(original.Content); j += 2 {
+				if original.Content[j].Value == updated.Content[i].Value {
+					restoreEnvRefs(original.Content[j+1], updated.Content[i+1])
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("BADOPS_TEST_HOST", "db.internal")
+	t.Setenv("BADOPS_TEST_EMPTY", "")
+	os.Unsetenv("BADOPS_TEST_MISSING")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "localhost", "localhost"},
+		{"set", "${BADOPS_TEST_HOST}", "db.internal"},
+		{"embedded", "postgres://${BADOPS_TEST_HOST}:5432", "postgres://db.internal:5432"},
+		{"default ignored when set", "${BADOPS_TEST_HOST:-localhost}", "db.internal"},
+		{"default when missing", "${BADOPS_TEST_MISSING:-localhost}", "localhost"},
+		{"default when empty", "${BADOPS_TEST_EMPTY:-localhost}", "localhost"},
+		{"empty default", "${BADOPS_TEST_MISSING:-}", ""},
+		{"missing without default", "host=${BADOPS_TEST_MISSING};", "host=;"},
+		{"escaped dollar", "pa$$word", "pa$word"},
+		{"escaped reference", "$${BADOPS_TEST_HOST}", "${BADOPS_TEST_HOST}"},
+		{"lone dollar", "costs $5", "costs $5"},
+		{"trailing dollar", "100$", "100$"},
+		{"bare name is not expanded", "$BADOPS_TEST_HOST", "$BADOPS_TEST_HOST"},
+		{"unterminated", "x ${BADOPS_TEST_HOST", "x ${BADOPS_TEST_HOST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandEnv(tt.in); got != tt.want {
+				t.Errorf("expandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFromInterpolatesEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+
+	t.Setenv("BADOPS_TEST_DB_HOST", "pg.example.no")
+	os.Unsetenv("BADOPS_TEST_DB_PORT")
+	os.Unsetenv("BADOPS_TEST_FROM_DOTENV")
+	os.Unsetenv("BADOPS_TEST_UNSET_USER")
+	t.Setenv("BADOPS_TEST_SHADOWED", "from-environment")
+
+	config := `database:
+  postgres:
+    host: ${BADOPS_TEST_DB_HOST}
+    port: ${BADOPS_TEST_DB_PORT:-6543}
+    database: "${BADOPS_TEST_FROM_DOTENV}"
+    ssl_mode: ${BADOPS_TEST_SHADOWED}
+    username_env: ${BADOPS_TEST_UNSET_USER}
+    password_env: literal$$dollar
+`
+	dotenv := `# Loaded before interpolation
+export BADOPS_TEST_FROM_DOTENV="badops_dev"
+BADOPS_TEST_SHADOWED=from-dotenv
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DotEnvFile), []byte(dotenv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Unsetenv("BADOPS_TEST_FROM_DOTENV") })
+
+	cfg, err := LoadFrom(path)
+	if err != nil {
+		t.Fatalf("LoadFrom: %v", err)
+	}
+
+	pg := cfg.Database.Postgres
+	checks := []struct {
+		field, got, want string
+	}{
+		{"host", pg.Host, "pg.example.no"},
+		{"database", pg.Database, "badops_dev"},
+		{"ssl_mode", pg.SSLMode, "from-environment"},
+		{"username_env", pg.UsernameEnv, ""},
+		{"password_env", pg.PasswordEnv, "literal$dollar"},
+	}
+	for _, c := range checks {
+		if c.got != c.want {
+			t.Errorf("%s = %q, want %q", c.field, c.got, c.want)
+		}
+	}
+	if pg.Port != 6543 {
+		t.Errorf("port = %d, want 6543 from the default", pg.Port)
+	}
+}
+
+func TestLoadDotEnvInvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DotEnvFile)
+	if err := os.WriteFile(path, []byte("BADOPS_TEST_OK=1\nnot a pair\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Unsetenv("BADOPS_TEST_OK") })
+
+	if err := loadDotEnv(path); err == nil {
+		t.Error("expected an error for a line without =")
+	}
+}
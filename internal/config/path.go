@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/badno/badops/internal/credentials"
+)
+
+// fieldByPath walks the config struct along a dotted path of yaml tag names
+// and returns the addressed field
+func fieldByPath(config *Config, key string) (reflect.Value, error) {
+	v := reflect.ValueOf(config).Elem()
+
+	for _, segment := range strings.Split(key, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown config key: %s", key)
+		}
+
+		field, ok := fieldByTag(v, segment)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown config key: %s", key)
+		}
+		v = field
+	}
+
+	if v.Kind() == reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%s is a section, not a value", key)
+	}
+	return v, nil
+}
+
+// fieldByTag returns the struct field whose yaml tag name is name
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setPath parses value into the field at key. Lists are comma-separated.
+func setPath(config *Config, key, value string) error {
+	field, err := fieldByPath(config, key)
+	if err != nil {
+		return err
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", key, value)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", key, value)
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", key, value)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s cannot be set from the command line", key)
+		}
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s cannot be set from the command line", key)
+	}
+
+	return nil
+}
+
+// getPath formats the field at key. Lists are comma-separated.
+func getPath(config *Config, key string) (string, error) {
+	field, err := fieldByPath(config, key)
+	if err != nil {
+		return "", err
+	}
+
+	switch field.Kind() {
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Sprint(field.Interface()), nil
+		}
+		return strings.Join(field.Interface().([]string), ","), nil
+	default:
+		return fmt.Sprint(field.Interface()), nil
+	}
+}
+
+// CredentialRef is a *_env config field. Its value is a credential reference
+// resolved by credentials.Default: a variable name, env:NAME, file:/path
+// or cmd:command.
+type CredentialRef struct {
+	Key string // Dotted config path, e.g. sources.nobb.password_env
+	Ref string // Reference, e.g. NOBB_PASSWORD or file:/run/secrets/nobb
+}
+
+// CredentialRefs returns every *_env field in the config that is set
+func CredentialRefs(config *Config) []CredentialRef {
+	var refs []CredentialRef
+	walkStrings(reflect.ValueOf(config).Elem(), "", func(key string, v reflect.Value) {
+		if strings.HasSuffix(key, "_env") && v.String() != "" {
+			refs = append(refs, CredentialRef{Key: key, Ref: v.String()})
+		}
+	})
+	return refs
+}
+
+// secretKeyWords mark a config key or URL query parameter as holding a secret
+var secretKeyWords = []string{"password", "secret", "token", "api_key", "apikey", "access_key"}
+
+// redactedValue replaces a secret in printed config
+const redactedValue = "[redacted]"
+
+// Redacted returns a copy of the config safe to print. Values are redacted by
+// key, never by comparing them with secrets: the commands of cmd: credential
+// references are hidden, fields whose key names a secret are replaced, and
+// URL fields lose their password and secret query parameters.
+func Redacted(config *Config) *Config {
+	redacted := *config // Only string fields are rewritten, so a shallow copy is enough
+	walkStrings(reflect.ValueOf(&redacted).Elem(), "", func(key string, v reflect.Value) {
+		if v.String() == "" {
+			return
+		}
+		name := key[strings.LastIndex(key, ".")+1:]
+		switch {
+		case strings.HasSuffix(name, "_env"):
+			v.SetString(credentials.RedactRef(v.String()))
+		case isSecretKey(name):
+			v.SetString(redactedValue)
+		case strings.HasSuffix(name, "url") || name == "endpoint":
+			v.SetString(redactURL(v.String()))
+		}
+	})
+	return &redacted
+}
+
+// isSecretKey reports whether a key or parameter name names a secret
+func isSecretKey(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range secretKeyWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the password and secret query parameters of a URL with
+// xxxxx, as url.URL.Redacted does. Values that do not parse as a URL are
+// returned unchanged.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		changed := false
+		for param := range query {
+			if isSecretKey(param) || param == "key" {
+				query.Set(param, "xxxxx")
+				changed = true
+			}
+		}
+		if changed {
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.Redacted()
+}
+
+// walkStrings calls fn for every string field, with its dotted path
+func walkStrings(v reflect.Value, prefix string, fn func(key string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := tag
+		if prefix != "" {
+			key = prefix + "." + tag
+		}
+
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			walkStrings(field, key, fn)
+		case reflect.String:
+			fn(key, field)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package credentials resolves the credential references used in config
+// files. It has no dependencies on the rest of badops, so both the config
+// and the connectors can use it.
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Provider resolves a credential reference from the configuration to the
+// secret it names
+type Provider interface {
+	// Resolve returns the secret for ref. An unset environment variable
+	// resolves to "" without an error, so callers can report it as missing.
+	Resolve(ref string) (string, error)
+}
+
+// Default resolves the references used in config files:
+//
+//	NAME or env:NAME   environment variable NAME
+//	file:/path         contents of a file, e.g. a mounted secret (~ is expanded)
+//	cmd:command        output of a shell command, e.g. cmd:pass show nobb/password
+//
+// File contents and command output are trimmed of surrounding whitespace.
+var Default Provider = RefProvider{}
+
+// cmdTimeout bounds how long a cmd: reference may run
+const cmdTimeout = 30 * time.Second
+
+// RefProvider implements the env:, file: and cmd: reference schemes.
+// References without a scheme name an environment variable, so existing
+// *_env settings keep working.
+type RefProvider struct{}
+
+// Resolve implements Provider
+func (RefProvider) Resolve(ref string) (string, error) {
+	scheme, value, ok := strings.Cut(ref, ":")
+	if !ok {
+		return os.Getenv(ref), nil
+	}
+
+	switch scheme {
+	case "env":
+		return os.Getenv(value), nil
+	case "file":
+		path := value
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", fmt.Errorf("failed to get home directory: %w", err)
+			}
+			path = filepath.Join(home, rest)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read credential file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case "cmd":
+		ctx, cancel := context.WithTimeout(context.Background(), cmdTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", value)
+		cmd.Stderr = os.Stderr // Let tools like pass prompt or explain failures
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("credential command %q failed: %w", value, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	return "", fmt.Errorf("unknown credential reference scheme %q (use env:, file:, or cmd:)", scheme)
+}
+
+// Resolve resolves ref with p, or with Default when p is nil. An empty ref
+// resolves to "".
+func Resolve(p Provider, ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	if p == nil {
+		p = Default
+	}
+	return p.Resolve(ref)
+}
+
+// EnvName returns the environment variable a reference names, or
+// false when it is a file: or cmd: reference
+func EnvName(ref string) (string, bool) {
+	scheme, value, ok := strings.Cut(ref, ":")
+	if !ok {
+		return ref, ref != ""
+	}
+	if scheme == "env" {
+		return value, value != ""
+	}
+	return "", false
+}
+
+// RunsCommand reports whether resolving ref runs a shell command
+func RunsCommand(ref string) bool {
+	return strings.HasPrefix(ref, "cmd:")
+}
+
+// RedactRef returns ref for display. The command of a cmd:
+// reference is hidden, since it may embed a token or secret path.
+func RedactRef(ref string) string {
+	if RunsCommand(ref) {
+		return "cmd:[redacted]"
+	}
+	return ref
+}
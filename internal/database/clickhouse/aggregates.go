@@ -0,0 +1,127 @@
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// RefreshMarketDaily rebuilds the market_daily aggregate for every day on or
+// after since. Days in the window are deleted and re-aggregated from
+// price_history, so running it repeatedly never double-counts observations.
+// A zero since rebuilds the whole table.
+func (c *Client) RefreshMarketDaily(ctx context.Context, since time.Time) error {
+	since = time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Wait for the delete to finish before inserting the new aggregates
+	syncCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"mutations_sync": 1,
+	}))
+
+	if err := c.conn.Exec(syncCtx, "ALTER TABLE market_daily DELETE WHERE date >= toDate(?)", since); err != nil {
+		return fmt.Errorf("failed to clear market_daily: %w", err)
+	}
+
+	query := `
+		INSERT INTO market_daily
+		SELECT
+			product_sku,
+			toDate(observed_at) as date,
+			min(toFloat64(price)) as min_price,
+			max(toFloat64(price)) as max_price,
+			avgState(toFloat64(price)) as avg_price,
+			uniqExactState(competitor_name) as competitors
+		FROM price_history
+		WHERE observed_at >= ?
+		GROUP BY product_sku, date
+	`
+
+	if err := c.conn.Exec(ctx, query, since); err != nil {
+		return fmt.Errorf("failed to refresh market_daily: %w", err)
+	}
+
+	return nil
+}
+
+// marketDailyFresh reports whether market_daily exists, covers the most
+// recent day in price_history and has every observed day since since. A
+// refresh only rebuilds its own window, so after a short one a longer window
+// can have days that were never aggregated.
+func (c *Client) marketDailyFresh(ctx context.Context, since time.Time) bool {
+	query := `
+		SELECT
+			(SELECT max(date) FROM market_daily) as aggregated,
+			(SELECT max(observed_date) FROM price_history) as observed,
+			(SELECT uniqExact(date) FROM market_daily WHERE date >= toDate(?)) as aggregated_days,
+			(SELECT uniqExact(toDate(observed_at)) FROM price_history WHERE observed_at >= toDate(?)) as observed_days
+	`
+
+	var aggregated, observed time.Time
+	var aggregatedDays, observedDays uint64
+	if err := c.conn.QueryRow(ctx, query, since, since).Scan(&aggregated, &observed, &aggregatedDays, &observedDays); err != nil {
+		return false
+	}
+
+	return !aggregated.IsZero() && !aggregated.Before(observed) && aggregatedDays >= observedDays
+}
+
+// getMarketPositionsDaily reads market positions from the market_daily aggregate
+func (c *Client) getMarketPositionsDaily(ctx context.Context, skus []string, since time.Time) ([]MarketPosition, error) {
+	query := `
+		SELECT
+			product_sku,
+			date,
+			min(min_price) as market_min,
+			max(max_price) as market_max,
+			avgMerge(avg_price) as market_avg,
+			toInt64(uniqExactMerge(competitors)) as competitor_count
+		FROM market_daily
+		WHERE date >= toDate(?)
+		GROUP BY product_sku, date
+		ORDER BY product_sku, date
+	`
+
+	var rows driver.Rows
+	var err error
+
+	if len(skus) > 0 {
+		query = `
+			SELECT
+				product_sku,
+				date,
+				min(min_price) as market_min,
+				max(max_price) as market_max,
+				avgMerge(avg_price) as market_avg,
+				toInt64(uniqExactMerge(competitors)) as competitor_count
+			FROM market_daily
+			WHERE product_sku IN (?)
+			  AND date >= toDate(?)
+			GROUP BY product_sku, date
+			ORDER BY product_sku, date
+		`
+		rows, err = c.conn.Query(ctx, query, skus, since)
+	} else {
+		rows, err = c.conn.Query(ctx, query, since)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market_daily: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []MarketPosition
+	for rows.Next() {
+		var p MarketPosition
+		var competitorCount int64
+		if err := rows.Scan(&p.ProductSKU, &p.Date, &p.MarketMin, &p.MarketMax, &p.MarketAvg, &competitorCount); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		p.CompetitorCount = int(competitorCount)
+		positions = append(positions, p)
+	}
+
+	return positions, rows.Err()
+}
@@ -6,37 +6,59 @@ import (
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/badno/badops/internal/database"
 )
 
-// PriceTrend represents price trend data for a product
-type PriceTrend struct {
-	ProductSKU     string
-	CompetitorName string
-	Date           time.Time
-	MinPrice       float64
-	MaxPrice       float64
-	AvgPrice       float64
-	Count          int64
-}
+// PriceTrend represents price trend data for a product. It is shared with
+// the PostgreSQL trend query so both render the same way.
+type PriceTrend = database.PriceTrend
 
 // MarketPosition represents a product's market position
 type MarketPosition struct {
-	ProductSKU      string
-	Date            time.Time
-	MarketMin       float64
-	MarketMax       float64
-	MarketAvg       float64
-	CompetitorCount int
+	ProductSKU      string    `json:"product_sku"`
+	Date            time.Time `json:"date"`
+	MarketMin       float64   `json:"market_min"`
+	MarketMax       float64   `json:"market_max"`
+	MarketAvg       float64   `json:"market_avg"`
+	CompetitorCount int       `json:"competitor_count"`
 }
 
 // PriceAlert represents a price alert
 type PriceAlert struct {
-	ProductSKU     string
-	CompetitorName string
-	CurrentPrice   float64
-	MarketAvg      float64
-	Difference     float64
-	DiffPercent    float64
+	ProductSKU     string  `json:"product_sku"`
+	CompetitorName string  `json:"competitor_name,omitempty"`
+	CurrentPrice   float64 `json:"current_price"`
+	MarketAvg      float64 `json:"market_avg"`
+	Difference     float64 `json:"difference"`
+	DiffPercent    float64 `json:"diff_percent"`
+}
+
+// VolatilityRow represents price volatility for a product across competitors
+type VolatilityRow struct {
+	ProductSKU   string  `json:"product_sku"`
+	AvgPrice     float64 `json:"avg_price"`
+	StdDev       float64 `json:"stddev"`
+	CoV          float64 `json:"cov"` // Coefficient of variation (stddev / avg)
+	Observations uint64  `json:"observations"`
+}
+
+// PriceChange represents a competitor price drop between two observed days
+type PriceChange struct {
+	ProductSKU     string    `json:"product_sku"`
+	CompetitorName string    `json:"competitor_name"`
+	Date           time.Time `json:"date"`
+	OldPrice       float64   `json:"old_price"`
+	NewPrice       float64   `json:"new_price"`
+	DropPercent    float64   `json:"drop_percent"`
+}
+
+// StockAvailability represents how often a competitor had a product in stock
+type StockAvailability struct {
+	CompetitorName string   `json:"competitor_name"`
+	Observations   uint64   `json:"observations"`
+	InStockCount   uint64   `json:"in_stock_count"`
+	InStockRate    float64  `json:"in_stock_rate"`          // Fraction of observations in stock (0-1)
+	AvgQuantity    *float64 `json:"avg_quantity,omitempty"` // Average reported stock quantity, if any
 }
 
 // GetPriceTrends returns price trends for a product over time
@@ -117,10 +139,17 @@ func (c *Client) GetVendorTrends(ctx context.Context, vendor string, days int) (
 	return trends, rows.Err()
 }
 
-// GetMarketPositions returns market positions for products
+// GetMarketPositions returns market positions for products. Uses the
+// market_daily aggregate when it covers the latest observations and every
+// day in the window, otherwise aggregates raw price_history.
 func (c *Client) GetMarketPositions(ctx context.Context, skus []string, days int) ([]MarketPosition, error) {
 	since := time.Now().AddDate(0, 0, -days)
 
+	// Prefer the pre-aggregated daily table when it is up to date
+	if c.marketDailyFresh(ctx, since) {
+		return c.getMarketPositionsDaily(ctx, skus, since)
+	}
+
 	query := `
 		SELECT
 			product_sku,
@@ -277,6 +306,142 @@ func (c *Client) GetPriceDistribution(ctx context.Context, productSKU string) (m
 	return distribution, rows.Err()
 }
 
+// GetPriceVolatility returns the products whose prices vary the most over the
+// last N days, sorted by coefficient of variation (highest first). Products
+// with fewer than minObservations observations are skipped.
+func (c *Client) GetPriceVolatility(ctx context.Context, days int, minObservations int) ([]VolatilityRow, error) {
+	since := time.Now().AddDate(0, 0, -days)
+
+	query := `
+		SELECT
+			product_sku,
+			avg(toFloat64(price)) as avg_price,
+			stddevPop(toFloat64(price)) as stddev,
+			stddevPop(toFloat64(price)) / avg(toFloat64(price)) as cov,
+			count() as observations
+		FROM price_history
+		WHERE observed_at >= ?
+		GROUP BY product_sku
+		HAVING observations >= ? AND avg_price > 0
+		ORDER BY cov DESC
+	`
+
+	rows, err := c.conn.Query(ctx, query, since, minObservations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query volatility: %w", err)
+	}
+	defer rows.Close()
+
+	var results []VolatilityRow
+	for rows.Next() {
+		var r VolatilityRow
+		if err := rows.Scan(&r.ProductSKU, &r.AvgPrice, &r.StdDev, &r.CoV, &r.Observations); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, r)
+	}
+
+	return results, rows.Err()
+}
+
+// GetPriceChanges returns competitor price drops of at least minDropPercent
+// between consecutive observed days within the last N days, largest drop first.
+// Each day uses the competitor's last observed price that day.
+func (c *Client) GetPriceChanges(ctx context.Context, days int, minDropPercent float64) ([]PriceChange, error) {
+	since := time.Now().AddDate(0, 0, -days)
+
+	// Read one extra day so a drop on the first day of the window has a previous price
+	query := `
+		SELECT
+			product_sku,
+			competitor_name,
+			date,
+			old_price,
+			new_price,
+			(old_price - new_price) / old_price * 100 as drop_percent
+		FROM (
+			SELECT
+				product_sku,
+				competitor_name,
+				date,
+				price as new_price,
+				lagInFrame(price, 1, 0) OVER (
+					PARTITION BY product_sku, competitor_name
+					ORDER BY date
+					ROWS BETWEEN 1 PRECEDING AND CURRENT ROW
+				) as old_price
+			FROM (
+				SELECT
+					product_sku,
+					competitor_name,
+					toDate(observed_at) as date,
+					argMax(toFloat64(price), observed_at) as price
+				FROM price_history
+				WHERE observed_at >= ? - INTERVAL 1 DAY
+				GROUP BY product_sku, competitor_name, date
+			)
+		)
+		WHERE old_price > 0
+		  AND date >= toDate(?)
+		  AND drop_percent >= ?
+		ORDER BY drop_percent DESC
+	`
+
+	rows, err := c.conn.Query(ctx, query, since, since, minDropPercent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []PriceChange
+	for rows.Next() {
+		var ch PriceChange
+		if err := rows.Scan(&ch.ProductSKU, &ch.CompetitorName, &ch.Date, &ch.OldPrice, &ch.NewPrice, &ch.DropPercent); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		changes = append(changes, ch)
+	}
+
+	return changes, rows.Err()
+}
+
+// GetStockAvailability returns the in-stock rate per competitor for a product
+// over the last N days
+func (c *Client) GetStockAvailability(ctx context.Context, productSKU string, days int) ([]StockAvailability, error) {
+	since := time.Now().AddDate(0, 0, -days)
+
+	query := `
+		SELECT
+			competitor_name,
+			count() as observations,
+			countIf(in_stock = 1) as in_stock_count,
+			avg(in_stock) as in_stock_rate,
+			avgOrNull(toFloat64(stock_quantity)) as avg_quantity
+		FROM price_history
+		WHERE product_sku = ?
+		  AND observed_at >= ?
+		GROUP BY competitor_name
+		ORDER BY in_stock_rate, competitor_name
+	`
+
+	rows, err := c.conn.Query(ctx, query, productSKU, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock availability: %w", err)
+	}
+	defer rows.Close()
+
+	var results []StockAvailability
+	for rows.Next() {
+		var a StockAvailability
+		if err := rows.Scan(&a.CompetitorName, &a.Observations, &a.InStockCount, &a.InStockRate, &a.AvgQuantity); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, a)
+	}
+
+	return results, rows.Err()
+}
+
 // PriceHistoryRecord represents a single price history record
 type PriceHistoryRecord struct {
 	ProductSKU     string
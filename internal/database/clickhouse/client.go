@@ -162,6 +162,18 @@ func (c *Client) InitSchema(ctx context.Context) error {
 			count(DISTINCT competitor_name) as competitor_count
 		FROM price_history
 		GROUP BY product_sku, date`,
+
+		// Daily market summary, populated incrementally by RefreshMarketDaily
+		`CREATE TABLE IF NOT EXISTS market_daily (
+			product_sku String,
+			date Date,
+			min_price SimpleAggregateFunction(min, Float64),
+			max_price SimpleAggregateFunction(max, Float64),
+			avg_price AggregateFunction(avg, Float64),
+			competitors AggregateFunction(uniqExact, String)
+		) ENGINE = AggregatingMergeTree()
+		PARTITION BY toYYYYMM(date)
+		ORDER BY (product_sku, date)`,
 	}
 
 	for _, query := range queries {
@@ -3,10 +3,12 @@ package clickhouse
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/badno/badops/internal/database"
 	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/metrics"
 	"github.com/google/uuid"
 )
 
@@ -22,6 +24,7 @@ type SyncResult struct {
 type Syncer struct {
 	pgClient *postgres.Client
 	chClient *Client
+	logger   *slog.Logger
 }
 
 // NewSyncer creates a new syncer
@@ -29,9 +32,15 @@ func NewSyncer(pgClient *postgres.Client, chClient *Client) *Syncer {
 	return &Syncer{
 		pgClient: pgClient,
 		chClient: chClient,
+		logger:   slog.Default().With("component", "clickhouse_sync"),
 	}
 }
 
+// SetLogger replaces the syncer's logger
+func (s *Syncer) SetLogger(logger *slog.Logger) {
+	s.logger = logger.With("component", "clickhouse_sync")
+}
+
 // SyncPriceObservations syncs price observations from PostgreSQL to ClickHouse
 func (s *Syncer) SyncPriceObservations(ctx context.Context, since time.Time) (*SyncResult, error) {
 	result := &SyncResult{
@@ -110,6 +119,7 @@ func (s *Syncer) SyncPriceObservations(ctx context.Context, since time.Time) (*S
 
 		batch := records[i:end]
 		if err := s.chClient.InsertPriceHistory(ctx, batch); err != nil {
+			s.logger.Warn("batch insert failed", "records", len(batch), "error", err)
 			result.Errors = append(result.Errors, fmt.Sprintf("batch insert error: %v", err))
 			continue
 		}
@@ -118,6 +128,12 @@ func (s *Syncer) SyncPriceObservations(ctx context.Context, since time.Time) (*S
 	}
 
 	result.EndTime = time.Now()
+	metrics.SyncRecords("clickhouse_prices", result.RecordsSynced, len(records)-result.RecordsSynced)
+	if len(result.Errors) == 0 {
+		metrics.Success("clickhouse_sync")
+	}
+	s.logger.Info("sync finished", "records", result.RecordsSynced, "errors", len(result.Errors),
+		"duration", result.EndTime.Sub(result.StartTime))
 	return result, nil
 }
 
@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/badno/badops/internal/notify"
+	"github.com/jackc/pgx/v5"
+)
+
+// UndercutAlertRepo remembers the last undercut alert sent for each product
+// in the undercut_alerts table, so repeated checks only alert on changes
+type UndercutAlertRepo struct {
+	client *Client
+}
+
+// NewUndercutAlertRepo creates a new undercut alert repository
+func NewUndercutAlertRepo(client *Client) *UndercutAlertRepo {
+	return &UndercutAlertRepo{client: client}
+}
+
+// Unsent returns the undercuts that differ from the last alert sent for
+// their SKU in our price, market minimum or competitor. Prices compare to
+// the cent, as stored.
+func (r *UndercutAlertRepo) Unsent(ctx context.Context, undercuts []notify.Undercut) ([]notify.Undercut, error) {
+	if len(undercuts) == 0 {
+		return nil, nil
+	}
+	skus := make([]string, len(undercuts))
+	for i, u := range undercuts {
+		skus[i] = u.SKU
+	}
+
+	rows, err := r.client.pool.Query(ctx, `
+		SELECT sku, competitor, our_price::float8, market_min::float8
+		FROM undercut_alerts
+		WHERE sku = ANY($1)
+	`, skus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query undercut alerts: %w", err)
+	}
+	defer rows.Close()
+
+	last := make(map[string]notify.Undercut)
+	for rows.Next() {
+		var u notify.Undercut
+		if err := rows.Scan(&u.SKU, &u.Competitor, &u.OurPrice, &u.MarketMin); err != nil {
+			return nil, fmt.Errorf("failed to scan undercut alert: %w", err)
+		}
+		last[u.SKU] = u
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read undercut alerts: %w", err)
+	}
+
+	var unsent []notify.Undercut
+	for _, u := range undercuts {
+		prev, ok := last[u.SKU]
+		if ok && prev.Competitor == u.Competitor && cents(prev.OurPrice) == cents(u.OurPrice) && cents(prev.MarketMin) == cents(u.MarketMin) {
+			continue
+		}
+		unsent = append(unsent, u)
+	}
+	return unsent, nil
+}
+
+// cents rounds a price to whole cents for comparison
+func cents(price float64) int64 {
+	return int64(math.Round(price * 100))
+}
+
+// Record stores undercuts as the last alert sent for their SKUs
+func (r *UndercutAlertRepo) Record(ctx context.Context, undercuts []notify.Undercut) error {
+	if len(undercuts) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, u := range undercuts {
+		batch.Queue(`
+			INSERT INTO undercut_alerts (sku, competitor, our_price, market_min, sent_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (sku) DO UPDATE SET
+				competitor = EXCLUDED.competitor,
+				our_price = EXCLUDED.our_price,
+				market_min = EXCLUDED.market_min,
+				sent_at = EXCLUDED.sent_at
+		`, u.SKU, u.Competitor, u.OurPrice, u.MarketMin)
+	}
+	if err := r.client.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to record undercut alerts: %w", err)
+	}
+	return nil
+}
+
+// Resolve forgets the alerts of checked SKUs that are no longer undercut, so
+// a later undercut alerts again even at the same prices. current holds the
+// checked products that are undercut now; nil checked means every product
+// was checked. Returns how many alerts were forgotten.
+func (r *UndercutAlertRepo) Resolve(ctx context.Context, checked []string, current []notify.Undercut) (int, error) {
+	undercut := make([]string, len(current))
+	for i, u := range current {
+		undercut[i] = u.SKU
+	}
+
+	query := "DELETE FROM undercut_alerts WHERE NOT (sku = ANY($1))"
+	args := []interface{}{undercut}
+	if checked != nil {
+		query += " AND sku = ANY($2)"
+		args = append(args, checked)
+	}
+	tag, err := r.client.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve undercut alerts: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
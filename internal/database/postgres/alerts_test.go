@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/badno/badops/internal/notify"
+)
+
+func TestUndercutAlertsOnlyOnChange(t *testing.T) {
+	client := testClient(t)
+	repo := NewUndercutAlertRepo(client)
+	ctx := context.Background()
+
+	skus := func(undercuts []notify.Undercut) []string {
+		var out []string
+		for _, u := range undercuts {
+			out = append(out, u.SKU)
+		}
+		return out
+	}
+	unsent := func(undercuts ...notify.Undercut) []string {
+		t.Helper()
+		fresh, err := repo.Unsent(ctx, undercuts)
+		if err != nil {
+			t.Fatalf("Unsent: %v", err)
+		}
+		return skus(fresh)
+	}
+
+	boston := notify.Undercut{SKU: "CO-T309012", OurPrice: 499, MarketMin: 429, Competitor: "Megaflis"}
+	urban := notify.Undercut{SKU: "CO-T317312", OurPrice: 349.5, MarketMin: 299, Competitor: "Bademiljø"}
+
+	if got := unsent(boston, urban); len(got) != 2 {
+		t.Fatalf("first check: unsent = %v, want both", got)
+	}
+	if err := repo.Record(ctx, []notify.Undercut{boston, urban}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	// The same undercuts on the next scheduled run are not sent again
+	if got := unsent(boston, urban); len(got) != 0 {
+		t.Errorf("repeat: unsent = %v, want none", got)
+	}
+
+	// Any change in prices or competitor alerts again
+	changed := []notify.Undercut{
+		{SKU: boston.SKU, OurPrice: 499, MarketMin: 419, Competitor: "Megaflis"},
+		{SKU: boston.SKU, OurPrice: 489, MarketMin: 429, Competitor: "Megaflis"},
+		{SKU: boston.SKU, OurPrice: 499, MarketMin: 429, Competitor: "VVSkupp"},
+	}
+	for _, u := range changed {
+		if got := unsent(u); len(got) != 1 {
+			t.Errorf("changed %+v: unsent = %v, want it", u, got)
+		}
+	}
+	// Float noise below a cent is not a change
+	if got := unsent(notify.Undercut{SKU: urban.SKU, OurPrice: 349.500001, MarketMin: 299, Competitor: urban.Competitor}); len(got) != 0 {
+		t.Errorf("sub-cent difference: unsent = %v, want none", got)
+	}
+
+	// Resolving within the checked SKUs leaves other products alone
+	n, err := repo.Resolve(ctx, []string{boston.SKU}, nil)
+	if err != nil || n != 1 {
+		t.Fatalf("Resolve(checked) = %d, %v, want 1", n, err)
+	}
+	if got := unsent(boston, urban); len(got) != 1 || got[0] != boston.SKU {
+		t.Errorf("after resolving %s: unsent = %v, want only it", boston.SKU, got)
+	}
+
+	// A full check forgets everything not undercut now
+	n, err = repo.Resolve(ctx, nil, []notify.Undercut{boston})
+	if err != nil || n != 1 {
+		t.Fatalf("Resolve(all) = %d, %v, want 1", n, err)
+	}
+	if got := unsent(urban); len(got) != 1 {
+		t.Errorf("after full resolve: unsent = %v, want %s", got, urban.SKU)
+	}
+}
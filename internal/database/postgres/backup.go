@@ -0,0 +1,408 @@
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// backupTables are the tables a logical backup covers, in an order where
+// every table comes after the tables it references
+var backupTables = []string{
+	"products",
+	"competitors",
+	"suppliers",
+	"competitor_products",
+	"price_observations",
+	"product_images",
+	"product_properties",
+	"product_suppliers",
+	"package_info",
+	"enhancement_log",
+	"operation_history",
+}
+
+// logicalBackupHeader starts every logical backup file
+const logicalBackupHeader = "-- badops logical backup"
+
+// pgDumpMagic starts every pg_dump custom-format archive
+var pgDumpMagic = []byte("PGDMP")
+
+// BackupFormat identifies how a backup file was written
+type BackupFormat string
+
+const (
+	BackupFormatPgDump  BackupFormat = "pg_dump"
+	BackupFormatLogical BackupFormat = "logical"
+)
+
+// DetectBackupFormat reads the start of a backup file to tell a pg_dump
+// archive from a logical backup
+func DetectBackupFormat(path string) (BackupFormat, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, len(logicalBackupHeader))
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, pgDumpMagic):
+		return BackupFormatPgDump, nil
+	case string(head) == logicalBackupHeader:
+		return BackupFormatLogical, nil
+	}
+	return "", fmt.Errorf("%s is not a badops backup (expected a pg_dump custom-format archive or a logical backup)", path)
+}
+
+// pgEnv returns the environment for pg_dump and pg_restore. The password is
+// passed in PGPASSWORD so it does not show up in the process list.
+func (c *Client) pgEnv() []string {
+	return append(os.Environ(),
+		"PGHOST="+c.config.Host,
+		"PGPORT="+strconv.Itoa(c.config.Port),
+		"PGDATABASE="+c.config.Database,
+		"PGUSER="+c.config.Username,
+		"PGPASSWORD="+c.config.Password,
+		"PGSSLMODE="+c.config.SSLMode,
+	)
+}
+
+// PgDump writes a custom-format pg_dump archive of the database, including
+// the schema and migration version, to path
+func (c *Client) PgDump(ctx context.Context, pgDump string, path string) error {
+	cmd := exec.CommandContext(ctx, pgDump, "--format=custom", "--no-owner", "--no-privileges", "--file="+path)
+	cmd.Env = c.pgEnv()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_dump failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// PgRestore restores a pg_dump archive in a single transaction. Objects in
+// the archive are dropped before they are recreated, so an initialized but
+// empty schema does not make the restore fail.
+func (c *Client) PgRestore(ctx context.Context, pgRestore string, path string) error {
+	cmd := exec.CommandContext(ctx, pgRestore,
+		"--clean", "--if-exists", "--no-owner", "--no-privileges",
+		"--single-transaction", "--exit-on-error",
+		"--dbname="+c.config.Database, path)
+	cmd.Env = c.pgEnv()
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("pg_restore failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// HasData reports whether any of the backed-up tables contains rows
+func (c *Client) HasData(ctx context.Context) (bool, error) {
+	if c.pool == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+
+	for _, table := range backupTables {
+		var exists bool
+		var found bool
+		err := c.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+		if err != nil {
+			return false, fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if !exists {
+			continue
+		}
+		query := "SELECT EXISTS (SELECT 1 FROM " + pgx.Identifier{table}.Sanitize() + ")"
+		if err := c.pool.QueryRow(ctx, query).Scan(&found); err != nil {
+			return false, fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tableColumns returns the columns of a table in definition order
+func (c *Client) tableColumns(ctx context.Context, table string) ([]string, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT column_name
+		FROM information_schema.columns
+		WHERE table_schema = 'public' AND table_name = $1
+		ORDER BY ordinal_position
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", table, err)
+	}
+	columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s not found (run 'badops db init')", table)
+	}
+	return columns, nil
+}
+
+// ExportTables writes a logical backup of the core tables to w, for when
+// pg_dump is not installed. Each table is written as a COPY ... FROM stdin
+// block in PostgreSQL's text format, preceded by a header recording the
+// migration version. Returns the number of rows written per table.
+func (c *Client) ExportTables(ctx context.Context, w io.Writer) (map[string]int64, error) {
+	if c.pool == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+
+	version, _, err := c.MigrationVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	// A repeatable-read transaction gives every table the same snapshot
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n-- migration: %d\n\n", logicalBackupHeader, version)
+
+	counts := make(map[string]int64, len(backupTables))
+	for _, table := range backupTables {
+		columns, err := c.tableColumns(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		columnList := quoteColumns(columns)
+
+		fmt.Fprintf(bw, "COPY %s (%s) FROM stdin;\n", table, columnList)
+		// COPY (SELECT ...) also works for the partitioned price_observations
+		query := fmt.Sprintf("COPY (SELECT %s FROM %s) TO STDOUT", columnList, pgx.Identifier{table}.Sanitize())
+		tag, err := tx.Conn().PgConn().CopyTo(ctx, bw, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", table, err)
+		}
+		bw.WriteString("\\.\n\n")
+		counts[table] = tag.RowsAffected()
+	}
+
+	if err := bw.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return counts, nil
+}
+
+// ImportTables restores a logical backup written by ExportTables into a
+// migrated schema, in a single transaction. With truncate the core tables
+// are emptied first; otherwise rows that already exist make the restore
+// fail. Serial sequences are advanced past the restored ids. Returns the
+// number of rows restored per table.
+func (c *Client) ImportTables(ctx context.Context, r io.Reader, truncate bool) (map[string]int64, error) {
+	if c.pool == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+
+	br := bufio.NewReader(r)
+	header, _ := br.ReadString('\n')
+	if strings.TrimSpace(header) != logicalBackupHeader {
+		return nil, fmt.Errorf("not a logical backup")
+	}
+
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if truncate {
+		tables := make([]string, len(backupTables))
+		for i, table := range backupTables {
+			tables[i] = pgx.Identifier{table}.Sanitize()
+		}
+		if _, err := tx.Exec(ctx, "TRUNCATE "+strings.Join(tables, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+			return nil, fmt.Errorf("failed to clear tables: %w", err)
+		}
+	}
+
+	counts := make(map[string]int64, len(backupTables))
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		line = strings.TrimSpace(line)
+		if v, ok := strings.CutPrefix(line, "-- migration: "); ok {
+			if err := checkBackupVersion(v); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		table, columns, err := parseCopyLine(line)
+		if err != nil {
+			return nil, err
+		}
+		query := fmt.Sprintf("COPY %s (%s) FROM STDIN", pgx.Identifier{table}.Sanitize(), quoteColumns(columns))
+		tag, err := tx.Conn().PgConn().CopyFrom(ctx, &copyDataReader{r: br}, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+		counts[table] = tag.RowsAffected()
+	}
+
+	if err := resetSequences(ctx, tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit restore: %w", err)
+	}
+	return counts, nil
+}
+
+// resetSequences advances every serial column's sequence past the highest
+// restored id, so new rows do not collide with restored ones
+func resetSequences(ctx context.Context, tx pgx.Tx) error {
+	for _, table := range backupTables {
+		rows, err := tx.Query(ctx, `
+			SELECT column_name
+			FROM information_schema.columns
+			WHERE table_schema = 'public' AND table_name = $1
+				AND pg_get_serial_sequence(quote_ident(table_name), column_name) IS NOT NULL
+		`, table)
+		if err != nil {
+			return fmt.Errorf("failed to find sequences of %s: %w", table, err)
+		}
+		columns, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return fmt.Errorf("failed to find sequences of %s: %w", table, err)
+		}
+
+		for _, column := range columns {
+			query := fmt.Sprintf(
+				"SELECT setval(pg_get_serial_sequence($1, $2), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+				pgx.Identifier{column}.Sanitize(), pgx.Identifier{table}.Sanitize())
+			if _, err := tx.Exec(ctx, query, table, column); err != nil {
+				return fmt.Errorf("failed to reset sequence of %s.%s: %w", table, column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkBackupVersion refuses backups written by a newer schema than the
+// embedded migrations, whose columns may not exist here
+func checkBackupVersion(v string) error {
+	version, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid migration version %q in backup", v)
+	}
+	latest, err := LatestMigrationVersion()
+	if err != nil {
+		return err
+	}
+	if uint(version) > latest {
+		return fmt.Errorf("backup is from migration v%d but this badops only knows v%d; upgrade badops first", version, latest)
+	}
+	return nil
+}
+
+// parseCopyLine parses a "COPY table (col, ...) FROM stdin;" line, accepting
+// only the tables a backup covers
+func parseCopyLine(line string) (string, []string, error) {
+	rest, ok := strings.CutPrefix(line, "COPY ")
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected line in backup: %q", line)
+	}
+	table, rest, ok := strings.Cut(rest, " (")
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected line in backup: %q", line)
+	}
+	columnList, ok := strings.CutSuffix(rest, ") FROM stdin;")
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected line in backup: %q", line)
+	}
+
+	known := false
+	for _, t := range backupTables {
+		if t == table {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return "", nil, fmt.Errorf("backup contains unknown table %q", table)
+	}
+
+	var columns []string
+	for _, column := range strings.Split(columnList, ",") {
+		columns = append(columns, strings.Trim(strings.TrimSpace(column), `"`))
+	}
+	return table, columns, nil
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = pgx.Identifier{column}.Sanitize()
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// copyDataReader reads the rows of one COPY block, stopping at the \.
+// terminator line
+type copyDataReader struct {
+	r       *bufio.Reader
+	pending []byte
+	done    bool
+}
+
+func (d *copyDataReader) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		line, err := d.r.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, fmt.Errorf("backup ends inside a COPY block: %w", err)
+		}
+		if string(bytes.TrimRight(line, "\r\n")) == `\.` {
+			d.done = true
+			continue
+		}
+		d.pending = line
+	}
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
@@ -3,13 +3,18 @@ package postgres
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -29,8 +34,30 @@ type Config struct {
 	MaxConnLife  time.Duration
 	MaxConnIdle  time.Duration
 	HealthCheck  time.Duration
+
+	// ConnectRetries is how often Connect retries when the server is
+	// unreachable, e.g. while a container is still starting (default: 3,
+	// negative disables). RetryBackoff is the first delay, doubled each retry
+	// (default: 1s).
+	ConnectRetries int
+	RetryBackoff   time.Duration
+
+	// ReadOnly opens every session with default_transaction_read_only, so
+	// the server rejects writes from this client
+	ReadOnly bool
 }
 
+// Defaults applied by Connect to unset pool and retry settings
+const (
+	DefaultMaxConns       = 25
+	DefaultMinConns       = 5
+	DefaultMaxConnLife    = time.Hour
+	DefaultMaxConnIdle    = 30 * time.Minute
+	DefaultHealthCheck    = time.Minute
+	DefaultConnectRetries = 3
+	DefaultRetryBackoff   = time.Second
+)
+
 // DefaultConfig returns a configuration with sensible defaults
 func DefaultConfig() *Config {
 	return &Config{
@@ -38,11 +65,11 @@ func DefaultConfig() *Config {
 		Port:        5432,
 		Database:    "badops",
 		SSLMode:     "prefer",
-		MaxConns:    25,
-		MinConns:    5,
-		MaxConnLife: time.Hour,
-		MaxConnIdle: 30 * time.Minute,
-		HealthCheck: time.Minute,
+		MaxConns:    DefaultMaxConns,
+		MinConns:    DefaultMinConns,
+		MaxConnLife: DefaultMaxConnLife,
+		MaxConnIdle: DefaultMaxConnIdle,
+		HealthCheck: DefaultHealthCheck,
 	}
 }
 
@@ -50,6 +77,7 @@ func DefaultConfig() *Config {
 type Client struct {
 	pool   *pgxpool.Pool
 	config *Config
+	logger *slog.Logger
 }
 
 // NewClient creates a new PostgreSQL client
@@ -57,11 +85,25 @@ func NewClient(cfg *Config) *Client {
 	if cfg == nil {
 		cfg = DefaultConfig()
 	}
-	return &Client{config: cfg}
+	return &Client{config: cfg, logger: slog.Default().With("component", "postgres")}
 }
 
-// Connect establishes a connection to the database
+// SetLogger replaces the client's logger, which its repositories share
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.logger = logger.With("component", "postgres")
+}
+
+// SetReadOnly sets Config.ReadOnly; it applies to the next Connect
+func (c *Client) SetReadOnly(readOnly bool) {
+	c.config.ReadOnly = readOnly
+}
+
+// Connect establishes a connection to the database. Unset pool settings
+// take their defaults. When the server can't be reached, Connect retries
+// with exponential backoff; authentication and missing-database errors fail
+// immediately.
 func (c *Client) Connect(ctx context.Context) error {
+	start := time.Now()
 	connString := c.buildConnectionString()
 
 	poolConfig, err := pgxpool.ParseConfig(connString)
@@ -69,25 +111,93 @@ func (c *Client) Connect(ctx context.Context) error {
 		return fmt.Errorf("failed to parse connection string: %w", err)
 	}
 
-	poolConfig.MaxConns = c.config.MaxConns
-	poolConfig.MinConns = c.config.MinConns
-	poolConfig.MaxConnLifetime = c.config.MaxConnLife
-	poolConfig.MaxConnIdleTime = c.config.MaxConnIdle
-	poolConfig.HealthCheckPeriod = c.config.HealthCheck
+	cfg := c.config.withDefaults()
+	if cfg.MinConns > cfg.MaxConns {
+		return fmt.Errorf("min_conns (%d) exceeds max_conns (%d)", cfg.MinConns, cfg.MaxConns)
+	}
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLife
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdle
+	poolConfig.HealthCheckPeriod = cfg.HealthCheck
+	if cfg.ReadOnly {
+		poolConfig.ConnConfig.RuntimeParams["default_transaction_read_only"] = "on"
+	}
+
+	for attempt := 0; ; attempt++ {
+		pool, err := c.connectOnce(ctx, poolConfig)
+		if err == nil {
+			c.pool = pool
+			c.logger.Debug("connected", "host", c.config.Host, "database", c.config.Database,
+				"attempts", attempt+1, "duration", time.Since(start))
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries || !retryableConnectError(err) || ctx.Err() != nil {
+			return err
+		}
 
+		delay := cfg.RetryBackoff << attempt
+		c.logger.Warn("database not reachable, retrying", "host", c.config.Host,
+			"attempt", attempt+1, "delay", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// connectOnce creates the pool and pings the server
+func (c *Client) connectOnce(ctx context.Context, poolConfig *pgxpool.Config) (*pgxpool.Pool, error) {
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create connection pool: %w", err)
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
 	}
 
 	// Verify connection
 	if err := pool.Ping(ctx); err != nil {
 		pool.Close()
-		return fmt.Errorf("failed to ping database: %w", err)
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
+	return pool, nil
+}
 
-	c.pool = pool
-	return nil
+// retryableConnectError reports whether a connection error may clear up on
+// its own. Server errors for bad credentials (class 28) or a missing
+// database (3D000) will not.
+func retryableConnectError(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return !strings.HasPrefix(pgErr.Code, "28") && pgErr.Code != "3D000"
+	}
+	return true
+}
+
+// withDefaults returns a copy of the config with unset pool and retry
+// settings replaced by their defaults
+func (c Config) withDefaults() Config {
+	if c.MaxConns <= 0 {
+		c.MaxConns = DefaultMaxConns
+	}
+	if c.MinConns <= 0 {
+		c.MinConns = min(DefaultMinConns, c.MaxConns)
+	}
+	if c.MaxConnLife <= 0 {
+		c.MaxConnLife = DefaultMaxConnLife
+	}
+	if c.MaxConnIdle <= 0 {
+		c.MaxConnIdle = DefaultMaxConnIdle
+	}
+	if c.HealthCheck <= 0 {
+		c.HealthCheck = DefaultHealthCheck
+	}
+	if c.ConnectRetries == 0 {
+		c.ConnectRetries = DefaultConnectRetries
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = DefaultRetryBackoff
+	}
+	return c
 }
 
 // Close closes the database connection pool
@@ -136,6 +246,9 @@ func (c *Client) RunMigrations() error {
 		return fmt.Errorf("migration failed: %w", err)
 	}
 
+	if version, _, err := m.Version(); err == nil {
+		c.logger.Info("migrations applied", "version", version)
+	}
 	return nil
 }
 
@@ -156,6 +269,28 @@ func (c *Client) MigrationVersion() (uint, bool, error) {
 	return m.Version()
 }
 
+// LatestMigrationVersion returns the version of the newest embedded
+// migration, which a fully migrated database is at
+func LatestMigrationVersion() (uint, error) {
+	d, err := iofs.New(migrationsFS, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to load migrations: %w", err)
+	}
+	defer d.Close()
+
+	version, err := d.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	for {
+		next, err := d.Next(version)
+		if err != nil {
+			return version, nil
+		}
+		version = next
+	}
+}
+
 // RollbackMigration rolls back the last migration
 func (c *Client) RollbackMigration() error {
 	d, err := iofs.New(migrationsFS, "migrations")
@@ -239,6 +374,24 @@ func (c *Client) GetTableStats(ctx context.Context) ([]TableStats, error) {
 	return stats, rows.Err()
 }
 
+// VacuumAnalyze reclaims space from deleted rows and refreshes planner
+// statistics for the given tables
+func (c *Client) VacuumAnalyze(ctx context.Context, tables ...string) error {
+	if c.pool == nil {
+		return fmt.Errorf("database not connected")
+	}
+
+	for _, table := range tables {
+		// VACUUM cannot run inside a transaction block; send it on its own
+		// with the simple protocol
+		_, err := c.pool.Exec(ctx, "VACUUM ANALYZE "+pgx.Identifier{table}.Sanitize(), pgx.QueryExecModeSimpleProtocol)
+		if err != nil {
+			return fmt.Errorf("failed to vacuum %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
 // DatabaseInfo contains information about the database
 type DatabaseInfo struct {
 	Version        string
@@ -454,12 +454,12 @@ func (r *PriceObservationRepo) Create(ctx context.Context, observation *database
 	query := `
 		INSERT INTO price_observations (
 			product_id, competitor_id, price, currency, in_stock, stock_quantity,
-			observed_at, observed_date, source
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			observed_at, observed_date, source, original_price, original_currency
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
 		RETURNING id
 	`
 
-	observedDate := observation.ObservedAt.Truncate(24 * time.Hour)
+	observedDate := observedDay(observation.ObservedAt)
 
 	err := r.client.pool.QueryRow(ctx, query,
 		observation.ProductID.String(),
@@ -471,6 +471,8 @@ func (r *PriceObservationRepo) Create(ctx context.Context, observation *database
 		observation.ObservedAt,
 		observedDate,
 		observation.Source,
+		observation.OriginalPrice,
+		observation.OriginalCurrency,
 	).Scan(&observation.ID)
 
 	if err != nil {
@@ -480,28 +482,49 @@ func (r *PriceObservationRepo) Create(ctx context.Context, observation *database
 	return nil
 }
 
-// BulkCreate inserts multiple price observations
+// BulkCreate upserts multiple price observations and returns the number of
+// rows written. See BulkUpsert.
 func (r *PriceObservationRepo) BulkCreate(ctx context.Context, observations []*database.PriceObservation) (int, error) {
+	inserted, updated, err := r.BulkUpsert(ctx, observations)
+	return inserted + updated, err
+}
+
+// BulkUpsert inserts multiple price observations. An observation for a product,
+// competitor and day that already exists is refreshed with the new price and
+// stock, so re-importing a file is idempotent. Returns the number of rows
+// inserted and updated.
+func (r *PriceObservationRepo) BulkUpsert(ctx context.Context, observations []*database.PriceObservation) (inserted, updated int, err error) {
 	if len(observations) == 0 {
-		return 0, nil
+		return 0, 0, nil
 	}
 
 	tx, err := r.client.pool.Begin(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
+	// xmax is 0 for freshly inserted rows and set for rows updated on conflict
 	query := `
 		INSERT INTO price_observations (
 			product_id, competitor_id, price, currency, in_stock, stock_quantity,
-			observed_at, observed_date, source
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			observed_at, observed_date, source, original_price, original_currency
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+		ON CONFLICT (product_id, competitor_id, observed_date) DO UPDATE SET
+			price = EXCLUDED.price,
+			currency = EXCLUDED.currency,
+			in_stock = EXCLUDED.in_stock,
+			stock_quantity = EXCLUDED.stock_quantity,
+			observed_at = EXCLUDED.observed_at,
+			source = EXCLUDED.source,
+			original_price = EXCLUDED.original_price,
+			original_currency = EXCLUDED.original_currency
+		RETURNING (xmax = 0)
 	`
 
 	batch := &pgx.Batch{}
 	for _, obs := range observations {
-		observedDate := obs.ObservedAt.Truncate(24 * time.Hour)
+		observedDate := observedDay(obs.ObservedAt)
 		batch.Queue(query,
 			obs.ProductID.String(),
 			obs.CompetitorID,
@@ -512,39 +535,51 @@ func (r *PriceObservationRepo) BulkCreate(ctx context.Context, observations []*d
 			obs.ObservedAt,
 			observedDate,
 			obs.Source,
+			obs.OriginalPrice,
+			obs.OriginalCurrency,
 		)
 	}
 
 	br := tx.SendBatch(ctx, batch)
-	defer br.Close()
 
-	count := 0
 	for range observations {
-		_, err := br.Exec()
-		if err != nil {
-			return count, fmt.Errorf("failed to insert price observation: %w", err)
+		var isInsert bool
+		if err := br.QueryRow().Scan(&isInsert); err != nil {
+			br.Close()
+			return inserted, updated, fmt.Errorf("failed to upsert price observation: %w", err)
 		}
-		count++
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+
+	if err := br.Close(); err != nil {
+		return 0, 0, fmt.Errorf("failed to close batch: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+		return 0, 0, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return count, nil
+	return inserted, updated, nil
 }
 
+// latestByProductQuery reads each competitor's newest observation for a
+// product; idx_price_obs_latest serves both the filter and the ordering
+const latestByProductQuery = `
+	SELECT DISTINCT ON (competitor_id)
+		id, product_id, competitor_id, price, currency, in_stock, stock_quantity, observed_at, source,
+		original_price, COALESCE(original_currency, '')
+	FROM price_observations
+	WHERE product_id = $1
+	ORDER BY competitor_id, observed_at DESC
+`
+
 // GetLatestByProduct retrieves the most recent price for each competitor for a product
 func (r *PriceObservationRepo) GetLatestByProduct(ctx context.Context, productID uuid.UUID) ([]*database.PriceObservation, error) {
-	query := `
-		SELECT DISTINCT ON (competitor_id)
-			id, product_id, competitor_id, price, currency, in_stock, stock_quantity, observed_at, source
-		FROM price_observations
-		WHERE product_id = $1
-		ORDER BY competitor_id, observed_at DESC
-	`
-
-	rows, err := r.client.pool.Query(ctx, query, productID.String())
+	rows, err := r.client.pool.Query(ctx, latestByProductQuery, productID.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to query latest prices: %w", err)
 	}
@@ -556,7 +591,8 @@ func (r *PriceObservationRepo) GetLatestByProduct(ctx context.Context, productID
 // GetByProductAndCompetitor retrieves price history for a specific product/competitor pair
 func (r *PriceObservationRepo) GetByProductAndCompetitor(ctx context.Context, productID uuid.UUID, competitorID int, since time.Time) ([]*database.PriceObservation, error) {
 	query := `
-		SELECT id, product_id, competitor_id, price, currency, in_stock, stock_quantity, observed_at, source
+		SELECT id, product_id, competitor_id, price, currency, in_stock, stock_quantity, observed_at, source,
+			original_price, COALESCE(original_currency, '')
 		FROM price_observations
 		WHERE product_id = $1 AND competitor_id = $2 AND observed_at >= $3
 		ORDER BY observed_at DESC
@@ -571,23 +607,127 @@ func (r *PriceObservationRepo) GetByProductAndCompetitor(ctx context.Context, pr
 	return r.scanPriceObservations(rows)
 }
 
+// priceHistoryQuery reads a product's observations since a time, newest
+// first, from idx_price_obs_product_observed
+const priceHistoryQuery = `
+	SELECT id, product_id, competitor_id, price, currency, in_stock, stock_quantity, observed_at, source,
+		original_price, COALESCE(original_currency, '')
+	FROM price_observations
+	WHERE product_id = $1 AND observed_at >= $2
+	ORDER BY observed_at DESC
+`
+
 // GetPriceHistory retrieves all price observations for a product in the last N days
 func (r *PriceObservationRepo) GetPriceHistory(ctx context.Context, productID uuid.UUID, days int) ([]*database.PriceObservation, error) {
 	since := time.Now().AddDate(0, 0, -days)
-	query := `
-		SELECT id, product_id, competitor_id, price, currency, in_stock, stock_quantity, observed_at, source
+	rows, err := r.client.pool.Query(ctx, priceHistoryQuery, productID.String(), since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price history: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanPriceObservations(rows)
+}
+
+// marketStatsQuery aggregates observations per product in a single pass.
+// The window function marks each competitor's latest observation, so price
+// stats reflect the current market while the count covers the whole period.
+const marketStatsQuery = `
+	SELECT
+		product_id,
+		MIN(price) FILTER (WHERE is_latest)::float8,
+		MAX(price) FILTER (WHERE is_latest)::float8,
+		AVG(price) FILTER (WHERE is_latest)::float8,
+		COUNT(*),
+		COUNT(DISTINCT competitor_id),
+		(ARRAY_AGG(competitor_id ORDER BY price) FILTER (WHERE is_latest))[1]
+	FROM (
+		SELECT
+			product_id, competitor_id, price,
+			observed_at = MAX(observed_at) OVER (PARTITION BY product_id, competitor_id) AS is_latest
 		FROM price_observations
-		WHERE product_id = $1 AND observed_at >= $2
-		ORDER BY observed_at DESC
+		WHERE observed_at >= $1 %s
+	) o
+	GROUP BY product_id
+`
+
+// GetMarketStats computes min/max/avg competitor price and observation counts
+// for a product over the last N days. Returns nil if there are no observations.
+func (r *PriceObservationRepo) GetMarketStats(ctx context.Context, productID uuid.UUID, days int) (*database.MarketStats, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	query := fmt.Sprintf(marketStatsQuery, "AND product_id = $2")
+
+	stats, err := r.queryMarketStats(ctx, query, since, productID.String())
+	if err != nil {
+		return nil, err
+	}
+	return stats[productID], nil
+}
+
+// GetAllMarketStats computes market stats for every product observed in the last N days
+func (r *PriceObservationRepo) GetAllMarketStats(ctx context.Context, days int) (map[uuid.UUID]*database.MarketStats, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	return r.queryMarketStats(ctx, fmt.Sprintf(marketStatsQuery, ""), since)
+}
+
+// GetDailyTrends buckets a product's observations in the last N days by day
+// and competitor, like the ClickHouse price_history trends
+func (r *PriceObservationRepo) GetDailyTrends(ctx context.Context, productID uuid.UUID, days int) ([]database.PriceTrend, error) {
+	since := time.Now().AddDate(0, 0, -days)
+	query := `
+		SELECT
+			p.sku,
+			c.name,
+			date_trunc('day', o.observed_at) AS day,
+			MIN(o.price)::float8,
+			MAX(o.price)::float8,
+			AVG(o.price)::float8,
+			COUNT(*)
+		FROM price_observations o
+		JOIN products p ON p.id = o.product_id
+		JOIN competitors c ON c.id = o.competitor_id
+		WHERE o.product_id = $1 AND o.observed_at >= $2
+		GROUP BY p.sku, c.name, day
+		ORDER BY day, c.name
 	`
 
 	rows, err := r.client.pool.Query(ctx, query, productID.String(), since)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query price history: %w", err)
+		return nil, fmt.Errorf("failed to query trends: %w", err)
 	}
 	defer rows.Close()
 
-	return r.scanPriceObservations(rows)
+	var trends []database.PriceTrend
+	for rows.Next() {
+		var t database.PriceTrend
+		if err := rows.Scan(&t.ProductSKU, &t.CompetitorName, &t.Date, &t.MinPrice, &t.MaxPrice, &t.AvgPrice, &t.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan trend: %w", err)
+		}
+		trends = append(trends, t)
+	}
+
+	return trends, rows.Err()
+}
+
+func (r *PriceObservationRepo) queryMarketStats(ctx context.Context, query string, args ...interface{}) (map[uuid.UUID]*database.MarketStats, error) {
+	rows, err := r.client.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make(map[uuid.UUID]*database.MarketStats)
+	for rows.Next() {
+		var s database.MarketStats
+		var productIDStr string
+		if err := rows.Scan(&productIDStr, &s.MinPrice, &s.MaxPrice, &s.AvgPrice, &s.Observations, &s.CompetitorCount, &s.MinCompetitorID); err != nil {
+			return nil, fmt.Errorf("failed to scan market stats: %w", err)
+		}
+		s.ProductID, _ = uuid.Parse(productIDStr)
+		stats[s.ProductID] = &s
+	}
+
+	return stats, rows.Err()
 }
 
 func (r *PriceObservationRepo) scanPriceObservations(rows pgx.Rows) ([]*database.PriceObservation, error) {
@@ -600,6 +740,7 @@ func (r *PriceObservationRepo) scanPriceObservations(rows pgx.Rows) ([]*database
 		err := rows.Scan(
 			&obs.ID, &productIDStr, &obs.CompetitorID, &obs.Price, &obs.Currency,
 			&obs.InStock, &obs.StockQuantity, &obs.ObservedAt, &obs.Source,
+			&obs.OriginalPrice, &obs.OriginalCurrency,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan price observation: %w", err)
@@ -622,6 +763,17 @@ func (r *PriceObservationRepo) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// CountOlderThan returns how many price observations DeleteOlderThan would
+// remove
+func (r *PriceObservationRepo) CountOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	var count int64
+	err := r.client.pool.QueryRow(ctx, "SELECT COUNT(*) FROM price_observations WHERE observed_at < $1", before).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count old observations: %w", err)
+	}
+	return count, nil
+}
+
 // DeleteOlderThan removes price observations older than the specified time
 func (r *PriceObservationRepo) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
 	result, err := r.client.pool.Exec(ctx, "DELETE FROM price_observations WHERE observed_at < $1", before)
@@ -630,3 +782,12 @@ func (r *PriceObservationRepo) DeleteOlderThan(ctx context.Context, before time.
 	}
 	return result.RowsAffected(), nil
 }
+
+// observedDay returns midnight of the calendar day t falls on in its own
+// location, the observed_date of an observation. Truncate would round to the
+// UTC day, moving local observations made just after midnight to the day
+// before.
+func observedDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
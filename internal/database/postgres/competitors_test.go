@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// seedPriceObservations fills price_observations with products × competitors
+// × days rows and refreshes the planner statistics
+func seedPriceObservations(t *testing.T, client *Client, products, competitors, days int) {
+	t.Helper()
+	ctx := context.Background()
+
+	_, err := client.pool.Exec(ctx, `
+		INSERT INTO price_observations (product_id, competitor_id, price, observed_at, observed_date)
+		SELECT md5('product-' || p)::uuid, c, 100 + p + c + d,
+			NOW() - d * INTERVAL '1 day', (NOW() - d * INTERVAL '1 day')::date
+		FROM generate_series(1, $1) p, generate_series(1, $2) c, generate_series(0, $3 - 1) d
+	`, products, competitors, days)
+	if err != nil {
+		t.Fatalf("seed price observations: %v", err)
+	}
+	if _, err := client.pool.Exec(ctx, "ANALYZE price_observations"); err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+}
+
+// indexNames returns the index and the partition indexes attached to it,
+// which are the names a plan over the partitioned table refers to
+func indexNames(t *testing.T, client *Client, index string) map[string]bool {
+	t.Helper()
+	rows, err := client.pool.Query(context.Background(), `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = $1
+	`, index)
+	if err != nil {
+		t.Fatalf("list partition indexes: %v", err)
+	}
+	defer rows.Close()
+
+	names := map[string]bool{index: true}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+// planNode is the part of an EXPLAIN (FORMAT JSON) node the tests look at
+type planNode struct {
+	NodeType  string     `json:"Node Type"`
+	IndexName string     `json:"Index Name"`
+	Plans     []planNode `json:"Plans"`
+}
+
+// explain returns every node of the query's plan
+func explain(t *testing.T, client *Client, query string, args ...interface{}) []planNode {
+	t.Helper()
+	var out []byte
+	if err := client.pool.QueryRow(context.Background(), "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&out); err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	if err := json.Unmarshal(out, &plans); err != nil || len(plans) == 0 {
+		t.Fatalf("parse plan %s: %v", out, err)
+	}
+
+	var nodes []planNode
+	var walk func(n planNode)
+	walk = func(n planNode) {
+		nodes = append(nodes, n)
+		for _, child := range n.Plans {
+			walk(child)
+		}
+	}
+	walk(plans[0].Plan)
+	return nodes
+}
+
+func TestPriceObservationQueriesUseIndexes(t *testing.T) {
+	client := testClient(t)
+	seedPriceObservations(t, client, 200, 20, 30)
+
+	var productID string
+	if err := client.pool.QueryRow(context.Background(),
+		"SELECT md5('product-7')::uuid::text").Scan(&productID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		args  []interface{}
+		index string
+	}{
+		{"latest by product", latestByProductQuery, []interface{}{productID}, "idx_price_obs_latest"},
+		{"price history", priceHistoryQuery, []interface{}{productID, time.Now().AddDate(0, 0, -7)}, "idx_price_obs_product_observed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := indexNames(t, client, tt.index)
+			nodes := explain(t, client, tt.query, tt.args...)
+
+			used := false
+			for _, n := range nodes {
+				if n.NodeType == "Seq Scan" {
+					t.Errorf("plan has a sequential scan: %+v", nodes)
+				}
+				if want[n.IndexName] {
+					used = true
+				}
+			}
+			if !used {
+				t.Errorf("plan does not use %s: %+v", tt.index, nodes)
+			}
+		})
+	}
+}
+
+func TestDedupMigrationHandlesNullObservedAt(t *testing.T) {
+	client := testClient(t)
+	ctx := context.Background()
+
+	up, err := migrationsFS.ReadFile("migrations/002_price_observation_dedup.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down, err := migrationsFS.ReadFile("migrations/002_price_observation_dedup.down.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Return to the schema before 002 and seed the duplicates it has to clean up
+	if _, err := client.pool.Exec(ctx, string(down)); err != nil {
+		t.Fatalf("roll back 002: %v", err)
+	}
+	// Leave the full schema behind whichever step fails
+	t.Cleanup(func() {
+		client.pool.Exec(context.Background(), "DROP INDEX IF EXISTS idx_price_obs_unique")
+		client.pool.Exec(context.Background(), string(up))
+	})
+
+	_, err = client.pool.Exec(ctx, `
+		INSERT INTO price_observations (product_id, competitor_id, price, observed_at, observed_date) VALUES
+			(md5('a')::uuid, 1, 100, NULL, '2026-01-05'),
+			(md5('a')::uuid, 1, 110, '2026-01-05 12:00+00', '2026-01-05'),
+			(md5('b')::uuid, 1, 200, NULL, '2026-01-05'),
+			(md5('b')::uuid, 1, 210, NULL, '2026-01-05'),
+			(md5('c')::uuid, 1, 300, NULL, '2026-01-05')
+	`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	if _, err := client.pool.Exec(ctx, string(up)); err != nil {
+		t.Fatalf("apply 002: %v", err)
+	}
+
+	rows, err := client.pool.Query(ctx, `
+		SELECT product_id::text, price::float8, observed_at IS NULL
+		FROM price_observations ORDER BY product_id
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	got := map[string]float64{}
+	for rows.Next() {
+		var id string
+		var price float64
+		var null bool
+		if err := rows.Scan(&id, &price, &null); err != nil {
+			t.Fatal(err)
+		}
+		if null {
+			t.Errorf("product %s still has a NULL observed_at", id)
+		}
+		if _, dup := got[id]; dup {
+			t.Errorf("product %s still has duplicate observations", id)
+		}
+		got[id] = price
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var a, b, c string
+	client.pool.QueryRow(ctx, "SELECT md5('a')::uuid::text, md5('b')::uuid::text, md5('c')::uuid::text").Scan(&a, &b, &c)
+	want := map[string]float64{a: 110, b: 210, c: 300}
+	for id, price := range want {
+		if got[id] != price {
+			t.Errorf("product %s kept price %v, want %v", id, got[id], price)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// SaveEnhanced persists an enhanced product in a single transaction: the
+// product row, its images, properties, suppliers and package info, and any
+// enhancements not yet in enhancement_log. Either everything is written or nothing is, so a crash
+// mid-enhance cannot leave partial data. The product and image IDs are set
+// to their database values.
+func (r *ProductRepo) SaveEnhanced(ctx context.Context, product *models.EnhancedProduct) error {
+	tx, err := r.client.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// An existing SKU keeps its ID, so read back the one actually stored
+	var productID string
+	err = tx.QueryRow(ctx, upsertProductQuery+" RETURNING id", productUpsertArgs(product, time.Now())...).Scan(&productID)
+	if err != nil {
+		return fmt.Errorf("failed to upsert product %s: %w", product.SKU, err)
+	}
+	product.ID = productID
+
+	for i := range product.Images {
+		if err := saveProductImage(ctx, tx, productID, &product.Images[i]); err != nil {
+			return err
+		}
+	}
+
+	for _, prop := range product.Properties {
+		_, err := tx.Exec(ctx, upsertPropertyQuery, productID, prop.Code, prop.Name, prop.Value, prop.Unit, prop.Source)
+		if err != nil {
+			return fmt.Errorf("failed to upsert property %s: %w", prop.Code, err)
+		}
+	}
+
+	if err := saveProductSuppliers(ctx, tx, productID, product.Suppliers); err != nil {
+		return err
+	}
+	if err := savePackageInfo(ctx, tx, productID, product.PackageInfo); err != nil {
+		return err
+	}
+
+	if _, err := logNewEnhancements(ctx, tx, productID, product.Enhancements); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// saveProductImage updates the product's image with the same source URL, or
+// inserts it if the product has no such image yet
+func saveProductImage(ctx context.Context, tx pgx.Tx, productID string, img *models.ProductImage) error {
+	resizedJSON := []byte("{}")
+	if img.ResizedPaths != nil {
+		resizedJSON, _ = json.Marshal(img.ResizedPaths)
+	}
+
+	var downloadedAt *time.Time
+	if !img.DownloadedAt.IsZero() {
+		downloadedAt = &img.DownloadedAt
+	}
+
+	status := img.Status
+	if status == "" {
+		status = "pending"
+	}
+
+	var id string
+	err := tx.QueryRow(ctx, `
+		UPDATE product_images SET
+			source = $3, local_path = $4, width = $5, height = $6, position = $7,
+			alt_text = $8, status = $9, resized_paths = $10, downloaded_at = $11
+		WHERE product_id = $1 AND source_url = $2
+		RETURNING id
+	`, productID, img.SourceURL, img.Source, img.LocalPath, img.Width, img.Height, img.Position,
+		img.Alt, status, resizedJSON, downloadedAt,
+	).Scan(&id)
+
+	if err == pgx.ErrNoRows {
+		if _, perr := uuid.Parse(img.ID); perr != nil {
+			img.ID = uuid.New().String()
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO product_images (
+				id, product_id, source_url, source, local_path,
+				width, height, position, alt_text, status, resized_paths, downloaded_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, img.ID, productID, img.SourceURL, img.Source, img.LocalPath,
+			img.Width, img.Height, img.Position, img.Alt, status, resizedJSON, downloadedAt,
+		)
+		id = img.ID
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save image %s: %w", img.SourceURL, err)
+	}
+
+	img.ID = id
+	return nil
+}
+
+// logNewEnhancements appends the enhancements recorded after the product's
+// latest enhancement_log entry, so saving a product twice logs each once.
+// Returns the number of entries added.
+func logNewEnhancements(ctx context.Context, tx pgx.Tx, productID string, enhancements []models.Enhancement) (int, error) {
+	var lastLogged *time.Time
+	err := tx.QueryRow(ctx, "SELECT MAX(created_at) FROM enhancement_log WHERE product_id = $1", productID).Scan(&lastLogged)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read enhancement log: %w", err)
+	}
+
+	added := 0
+	for _, e := range enhancements {
+		// created_at keeps microseconds, so compare at that precision
+		if lastLogged != nil && !e.Timestamp.Truncate(time.Microsecond).After(*lastLogged) {
+			continue
+		}
+
+		createdAt := e.Timestamp
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+
+		_, err := tx.Exec(ctx, `
+			INSERT INTO enhancement_log (product_id, source, action, fields_added, success, error, created_at)
+			VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		`, productID, e.Source, e.Action, e.FieldsAdded, e.Success, e.Error, createdAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to log enhancement: %w", err)
+		}
+		added++
+	}
+
+	return added, nil
+}
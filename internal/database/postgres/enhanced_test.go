@@ -0,0 +1,96 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestSaveEnhancedRoundTripsNOBBDetails(t *testing.T) {
+	client := testClient(t)
+	ctx := context.Background()
+	repo := NewProductRepo(client)
+
+	enhancedAt := time.Now().Add(-time.Hour).Truncate(time.Microsecond)
+	product := &models.EnhancedProduct{
+		SKU:    "CO-T309012",
+		Title:  "Boston Toilet Roll Holder",
+		Vendor: "Tiger",
+		Status: models.StatusEnhanced,
+		Suppliers: []models.Supplier{
+			{ID: "12345", Name: "Coram Nordic", GLN: "7080000000001", ArticleNo: "309012", IsPrimary: true},
+			{ID: "67890", Name: "Ahlsell", ArticleNo: "A-1"},
+		},
+		PackageInfo: []models.PackageInfo{
+			{Type: "PIECE", Quantity: 1, GTIN: "8717343309012", Weight: 0.45, WeightUnit: "kg", IsPCU: true, Deliverable: true},
+			{Type: "OUTER", Quantity: 6, Length: 30, Width: 20, Height: 15, DimUnit: "cm", Volume: 9, ConsistsOfCount: 6, ConsistsOfUnit: "PIECE"},
+		},
+		Enhancements: []models.Enhancement{
+			{Source: "nobb", Action: "enhanced", FieldsAdded: []string{"suppliers"}, Timestamp: enhancedAt, Success: true},
+		},
+	}
+	if err := repo.SaveEnhanced(ctx, product); err != nil {
+		t.Fatalf("SaveEnhanced: %v", err)
+	}
+	// A second save must not log the enhancement again or duplicate rows
+	if err := repo.SaveEnhanced(ctx, product); err != nil {
+		t.Fatalf("SaveEnhanced again: %v", err)
+	}
+
+	id := uuid.MustParse(product.ID)
+	ids := []uuid.UUID{id}
+
+	links, err := NewSupplierRepo(client).GetByProducts(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var suppliers []models.Supplier
+	for _, l := range links[id] {
+		suppliers = append(suppliers, models.Supplier{
+			ID: l.SupplierID, Name: l.SupplierName, GLN: l.SupplierGLN, ArticleNo: l.ArticleNumber, IsPrimary: l.IsPrimary,
+		})
+	}
+	if !reflect.DeepEqual(suppliers, product.Suppliers) {
+		t.Errorf("suppliers = %+v, want %+v", suppliers, product.Suppliers)
+	}
+
+	packages, err := NewPackageInfoRepo(client).GetByProducts(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(packages[id], product.PackageInfo) {
+		t.Errorf("package info = %+v, want %+v", packages[id], product.PackageInfo)
+	}
+
+	logs, err := NewEnhancementLogRepo(client).GetByProducts(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs[id]) != 1 {
+		t.Fatalf("enhancement log has %d entries, want 1", len(logs[id]))
+	}
+	if e := logs[id][0]; e.Source != "nobb" || !e.Success || !e.CreatedAt.Equal(enhancedAt) {
+		t.Errorf("enhancement log entry = %+v", e)
+	}
+
+	// Dropping a supplier removes its link; an empty list keeps them
+	product.Suppliers = product.Suppliers[:1]
+	if err := repo.SaveEnhanced(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	product.Suppliers = nil
+	if err := repo.SaveEnhanced(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	links, err = NewSupplierRepo(client).GetByProducts(ctx, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links[id]) != 1 || links[id][0].SupplierID != "12345" {
+		t.Errorf("supplier links after removal = %+v", links[id])
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/pkg/models"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
@@ -127,14 +128,20 @@ func NewEnhancementLogRepo(client *Client) *EnhancementLogRepo {
 	return &EnhancementLogRepo{client: client}
 }
 
-// Add inserts a new enhancement log entry
+// Add inserts a new enhancement log entry. A zero CreatedAt is set to the
+// current time.
 func (r *EnhancementLogRepo) Add(ctx context.Context, entry *database.EnhancementLog) error {
 	query := `
-		INSERT INTO enhancement_log (product_id, source, action, fields_added, success, error)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO enhancement_log (product_id, source, action, fields_added, success, error, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), COALESCE($7, NOW()))
 		RETURNING id, created_at
 	`
 
+	var createdAt *time.Time
+	if !entry.CreatedAt.IsZero() {
+		createdAt = &entry.CreatedAt
+	}
+
 	err := r.client.pool.QueryRow(ctx, query,
 		entry.ProductID.String(),
 		entry.Source,
@@ -142,6 +149,7 @@ func (r *EnhancementLogRepo) Add(ctx context.Context, entry *database.Enhancemen
 		entry.FieldsAdded,
 		entry.Success,
 		entry.Error,
+		createdAt,
 	).Scan(&entry.ID, &entry.CreatedAt)
 
 	if err != nil {
@@ -151,13 +159,44 @@ func (r *EnhancementLogRepo) Add(ctx context.Context, entry *database.Enhancemen
 	return nil
 }
 
-// GetByProduct retrieves enhancement logs for a product
+// ImportEnhancements logs each product's recorded enhancements in one
+// transaction, skipping those already logged (see logNewEnhancements), and
+// returns the number of entries added
+func (r *EnhancementLogRepo) ImportEnhancements(ctx context.Context, enhancements map[uuid.UUID][]models.Enhancement) (int, error) {
+	tx, err := r.client.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	total := 0
+	for productID, list := range enhancements {
+		n, err := logNewEnhancements(ctx, tx, productID.String(), list)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return total, nil
+}
+
+const enhancementLogColumns = `
+	l.id, l.product_id, COALESCE(p.sku, ''), l.source, l.action, l.fields_added,
+	l.success, COALESCE(l.error, ''), l.created_at
+`
+
+// GetByProduct retrieves enhancement logs for a product, newest first
 func (r *EnhancementLogRepo) GetByProduct(ctx context.Context, productID uuid.UUID) ([]*database.EnhancementLog, error) {
 	query := `
-		SELECT id, product_id, source, action, fields_added, success, error, created_at
-		FROM enhancement_log
-		WHERE product_id = $1
-		ORDER BY created_at DESC
+		SELECT ` + enhancementLogColumns + `
+		FROM enhancement_log l
+		LEFT JOIN products p ON p.id = l.product_id
+		WHERE l.product_id = $1
+		ORDER BY l.created_at DESC, l.id DESC
 	`
 
 	rows, err := r.client.pool.Query(ctx, query, productID.String())
@@ -166,33 +205,49 @@ func (r *EnhancementLogRepo) GetByProduct(ctx context.Context, productID uuid.UU
 	}
 	defer rows.Close()
 
-	var entries []*database.EnhancementLog
-	for rows.Next() {
-		var entry database.EnhancementLog
-		var productIDStr string
+	return r.scanEnhancementLogs(rows)
+}
 
-		err := rows.Scan(
-			&entry.ID, &productIDStr, &entry.Source, &entry.Action,
-			&entry.FieldsAdded, &entry.Success, &entry.Error, &entry.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan enhancement log: %w", err)
-		}
+// GetByProducts retrieves the enhancement logs of several products in one
+// query, keyed by product ID, oldest first as product.Enhancements is ordered
+func (r *EnhancementLogRepo) GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]*database.EnhancementLog, error) {
+	byProduct := make(map[uuid.UUID][]*database.EnhancementLog)
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
 
-		entry.ProductID, _ = uuid.Parse(productIDStr)
-		entries = append(entries, &entry)
+	query := `
+		SELECT ` + enhancementLogColumns + `
+		FROM enhancement_log l
+		LEFT JOIN products p ON p.id = l.product_id
+		WHERE l.product_id = ANY($1)
+		ORDER BY l.product_id, l.created_at, l.id
+	`
+
+	rows, err := r.client.pool.Query(ctx, query, uuidStrings(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query enhancement logs: %w", err)
 	}
+	defer rows.Close()
 
-	return entries, rows.Err()
+	entries, err := r.scanEnhancementLogs(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		byProduct[e.ProductID] = append(byProduct[e.ProductID], e)
+	}
+	return byProduct, nil
 }
 
-// GetBySource retrieves enhancement logs by source
+// GetBySource retrieves the latest enhancement logs from a source
 func (r *EnhancementLogRepo) GetBySource(ctx context.Context, source string, limit int) ([]*database.EnhancementLog, error) {
 	query := `
-		SELECT id, product_id, source, action, fields_added, success, error, created_at
-		FROM enhancement_log
-		WHERE source = $1
-		ORDER BY created_at DESC
+		SELECT ` + enhancementLogColumns + `
+		FROM enhancement_log l
+		LEFT JOIN products p ON p.id = l.product_id
+		WHERE l.source = $1
+		ORDER BY l.created_at DESC, l.id DESC
 		LIMIT $2
 	`
 
@@ -202,13 +257,17 @@ func (r *EnhancementLogRepo) GetBySource(ctx context.Context, source string, lim
 	}
 	defer rows.Close()
 
+	return r.scanEnhancementLogs(rows)
+}
+
+func (r *EnhancementLogRepo) scanEnhancementLogs(rows pgx.Rows) ([]*database.EnhancementLog, error) {
 	var entries []*database.EnhancementLog
 	for rows.Next() {
 		var entry database.EnhancementLog
 		var productIDStr string
 
 		err := rows.Scan(
-			&entry.ID, &productIDStr, &entry.Source, &entry.Action,
+			&entry.ID, &productIDStr, &entry.ProductSKU, &entry.Source, &entry.Action,
 			&entry.FieldsAdded, &entry.Success, &entry.Error, &entry.CreatedAt,
 		)
 		if err != nil {
@@ -227,6 +286,8 @@ type ImageRepo struct {
 	client *Client
 }
 
+var _ database.ImageRepository = (*ImageRepo)(nil)
+
 // NewImageRepo creates a new PostgreSQL image repository
 func NewImageRepo(client *Client) *ImageRepo {
 	return &ImageRepo{client: client}
@@ -272,11 +333,14 @@ func (r *ImageRepo) Create(ctx context.Context, image *database.ProductImage) er
 	return nil
 }
 
+// imageColumns are the product_images columns scanImages reads
+const imageColumns = `id, product_id, source_url, source, local_path,
+		       width, height, position, alt_text, status, resized_paths, downloaded_at, created_at`
+
 // GetByProduct retrieves all images for a product
 func (r *ImageRepo) GetByProduct(ctx context.Context, productID uuid.UUID) ([]*database.ProductImage, error) {
 	query := `
-		SELECT id, product_id, source_url, source, local_path,
-		       width, height, position, alt_text, status, resized_paths, downloaded_at, created_at
+		SELECT ` + imageColumns + `
 		FROM product_images
 		WHERE product_id = $1
 		ORDER BY position
@@ -288,6 +352,65 @@ func (r *ImageRepo) GetByProduct(ctx context.Context, productID uuid.UUID) ([]*d
 	}
 	defer rows.Close()
 
+	return scanImages(rows)
+}
+
+// GetByProducts retrieves the images of several products in one query,
+// keyed by product ID and ordered by position. Products without images are
+// absent from the map.
+func (r *ImageRepo) GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]*database.ProductImage, error) {
+	byProduct := make(map[uuid.UUID][]*database.ProductImage)
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+
+	query := `
+		SELECT ` + imageColumns + `
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position
+	`
+
+	rows, err := r.client.pool.Query(ctx, query, uuidStrings(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query images: %w", err)
+	}
+	defer rows.Close()
+
+	images, err := scanImages(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		byProduct[img.ProductID] = append(byProduct[img.ProductID], img)
+	}
+	return byProduct, nil
+}
+
+// CountByProduct returns the number of images of every product that has
+// any, in a single query, for listings that only show counts
+func (r *ImageRepo) CountByProduct(ctx context.Context) (map[uuid.UUID]int, error) {
+	rows, err := r.client.pool.Query(ctx, "SELECT product_id, COUNT(*) FROM product_images GROUP BY product_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to count images: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[uuid.UUID]int)
+	for rows.Next() {
+		var productIDStr string
+		var count int
+		if err := rows.Scan(&productIDStr, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan image count: %w", err)
+		}
+		productID, _ := uuid.Parse(productIDStr)
+		counts[productID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+func scanImages(rows pgx.Rows) ([]*database.ProductImage, error) {
 	var images []*database.ProductImage
 	for rows.Next() {
 		var img database.ProductImage
@@ -316,6 +439,15 @@ func (r *ImageRepo) GetByProduct(ctx context.Context, productID uuid.UUID) ([]*d
 	return images, rows.Err()
 }
 
+// uuidStrings converts IDs for an ANY($1) parameter
+func uuidStrings(ids []uuid.UUID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.String()
+	}
+	return out
+}
+
 // Update updates an existing image
 func (r *ImageRepo) Update(ctx context.Context, image *database.ProductImage) error {
 	query := `
@@ -442,18 +574,19 @@ func NewPropertyRepo(client *Client) *PropertyRepo {
 	return &PropertyRepo{client: client}
 }
 
+// upsertPropertyQuery inserts a property or updates it per product, code and source
+const upsertPropertyQuery = `
+	INSERT INTO product_properties (product_id, code, name, value, unit, source)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (product_id, code, source) DO UPDATE SET
+		name = EXCLUDED.name,
+		value = EXCLUDED.value,
+		unit = EXCLUDED.unit
+`
+
 // Create inserts a new product property
 func (r *PropertyRepo) Create(ctx context.Context, property *database.ProductProperty) error {
-	query := `
-		INSERT INTO product_properties (product_id, code, name, value, unit, source)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (product_id, code, source) DO UPDATE SET
-			name = EXCLUDED.name,
-			value = EXCLUDED.value,
-			unit = EXCLUDED.unit
-	`
-
-	_, err := r.client.pool.Exec(ctx, query,
+	_, err := r.client.pool.Exec(ctx, upsertPropertyQuery,
 		property.ProductID.String(),
 		property.Code,
 		property.Name,
@@ -484,6 +617,41 @@ func (r *PropertyRepo) GetByProduct(ctx context.Context, productID uuid.UUID) ([
 	}
 	defer rows.Close()
 
+	return scanProperties(rows)
+}
+
+// GetByProducts retrieves the properties of several products in one query,
+// keyed by product ID
+func (r *PropertyRepo) GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]*database.ProductProperty, error) {
+	byProduct := make(map[uuid.UUID][]*database.ProductProperty)
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+
+	query := `
+		SELECT product_id, code, name, value, unit, source
+		FROM product_properties
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, source, name
+	`
+
+	rows, err := r.client.pool.Query(ctx, query, uuidStrings(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query properties: %w", err)
+	}
+	defer rows.Close()
+
+	properties, err := scanProperties(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, prop := range properties {
+		byProduct[prop.ProductID] = append(byProduct[prop.ProductID], prop)
+	}
+	return byProduct, nil
+}
+
+func scanProperties(rows pgx.Rows) ([]*database.ProductProperty, error) {
 	var properties []*database.ProductProperty
 	for rows.Next() {
 		var prop database.ProductProperty
@@ -513,18 +681,9 @@ func (r *PropertyRepo) BulkUpsert(ctx context.Context, properties []*database.Pr
 	}
 	defer tx.Rollback(ctx)
 
-	query := `
-		INSERT INTO product_properties (product_id, code, name, value, unit, source)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		ON CONFLICT (product_id, code, source) DO UPDATE SET
-			name = EXCLUDED.name,
-			value = EXCLUDED.value,
-			unit = EXCLUDED.unit
-	`
-
 	batch := &pgx.Batch{}
 	for _, prop := range properties {
-		batch.Queue(query,
+		batch.Queue(upsertPropertyQuery,
 			prop.ProductID.String(),
 			prop.Code,
 			prop.Name,
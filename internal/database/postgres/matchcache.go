@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badno/badops/internal/matcher"
+	"github.com/jackc/pgx/v5"
+)
+
+// matchCacheTimeout bounds each cache query; the matcher's Cache interface
+// has no context, and a slow database should cost a lookup, not hang it
+const matchCacheTimeout = 10 * time.Second
+
+// MatchCacheRepo is a matcher.Cache stored in the match_cache table, so
+// Tiger.nl lookups are shared between machines and survive output/ being
+// wiped
+type MatchCacheRepo struct {
+	client *Client
+}
+
+var _ matcher.Cache = (*MatchCacheRepo)(nil)
+
+// NewMatchCacheRepo creates a new PostgreSQL match cache
+func NewMatchCacheRepo(client *Client) *MatchCacheRepo {
+	return &MatchCacheRepo{client: client}
+}
+
+// Get returns the entry stored under key
+func (r *MatchCacheRepo) Get(key string) (matcher.CacheEntry, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), matchCacheTimeout)
+	defer cancel()
+
+	rows, err := r.client.pool.Query(ctx, `
+		SELECT key, not_found, product, cached_at
+		FROM match_cache
+		WHERE key = $1
+	`, key)
+	if err != nil {
+		return matcher.CacheEntry{}, false, fmt.Errorf("failed to query match cache: %w", err)
+	}
+	entries, err := scanMatchCache(rows)
+	if err != nil || len(entries) == 0 {
+		return matcher.CacheEntry{}, false, err
+	}
+	return entries[0], true, nil
+}
+
+// Set stores an entry under its key, replacing any previous result
+func (r *MatchCacheRepo) Set(entry matcher.CacheEntry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), matchCacheTimeout)
+	defer cancel()
+
+	args, err := matchCacheArgs(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := r.client.pool.Exec(ctx, upsertMatchCacheQuery, args...); err != nil {
+		return fmt.Errorf("failed to store match cache entry: %w", err)
+	}
+	return nil
+}
+
+// upsertMatchCacheQuery stores an entry, replacing any previous result
+const upsertMatchCacheQuery = `
+	INSERT INTO match_cache (key, url, not_found, product, cached_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (key) DO UPDATE SET
+		url = EXCLUDED.url,
+		not_found = EXCLUDED.not_found,
+		product = EXCLUDED.product,
+		cached_at = EXCLUDED.cached_at
+`
+
+// matchCacheArgs returns the upsertMatchCacheQuery arguments for an entry
+func matchCacheArgs(e matcher.CacheEntry) ([]any, error) {
+	var url *string
+	var product []byte
+	if e.Product != nil {
+		url = &e.Product.URL
+		data, err := json.Marshal(e.Product)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode cached product %s: %w", e.SKU, err)
+		}
+		product = data
+	}
+	return []any{e.SKU, url, e.NotFound, product, e.CachedAt}, nil
+}
+
+// Delete removes the entries with the given keys and returns how many were
+// removed
+func (r *MatchCacheRepo) Delete(keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), matchCacheTimeout)
+	defer cancel()
+
+	tag, err := r.client.pool.Exec(ctx, "DELETE FROM match_cache WHERE key = ANY($1)", keys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete match cache entries: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Clear removes every entry and returns how many there were
+func (r *MatchCacheRepo) Clear() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), matchCacheTimeout)
+	defer cancel()
+
+	tag, err := r.client.pool.Exec(ctx, "DELETE FROM match_cache")
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear match cache: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// Entries returns every entry, including expired ones
+func (r *MatchCacheRepo) Entries() ([]matcher.CacheEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), matchCacheTimeout)
+	defer cancel()
+
+	rows, err := r.client.pool.Query(ctx, `
+		SELECT key, not_found, product, cached_at
+		FROM match_cache
+		ORDER BY key
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query match cache: %w", err)
+	}
+	return scanMatchCache(rows)
+}
+
+// Len returns the number of entries, including expired ones
+func (r *MatchCacheRepo) Len() (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), matchCacheTimeout)
+	defer cancel()
+
+	var n int
+	if err := r.client.pool.QueryRow(ctx, "SELECT COUNT(*) FROM match_cache").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count match cache entries: %w", err)
+	}
+	return n, nil
+}
+
+// Import stores file cache entries that are newer than the ones in the
+// table and returns how many were written
+func (r *MatchCacheRepo) Import(ctx context.Context, entries []matcher.CacheEntry) (int, error) {
+	tx, err := r.client.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Entries another machine refreshed since are kept
+	query := upsertMatchCacheQuery + "WHERE match_cache.cached_at < EXCLUDED.cached_at"
+
+	imported := 0
+	for _, e := range entries {
+		args, err := matchCacheArgs(e)
+		if err != nil {
+			return 0, err
+		}
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import match cache entry %s: %w", e.SKU, err)
+		}
+		imported += int(tag.RowsAffected())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit match cache import: %w", err)
+	}
+	return imported, nil
+}
+
+func scanMatchCache(rows pgx.Rows) ([]matcher.CacheEntry, error) {
+	defer rows.Close()
+
+	var entries []matcher.CacheEntry
+	for rows.Next() {
+		var e matcher.CacheEntry
+		var product []byte
+		if err := rows.Scan(&e.SKU, &e.NotFound, &product, &e.CachedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan match cache entry: %w", err)
+		}
+		if len(product) > 0 {
+			e.Product = &matcher.TigerProduct{}
+			if err := json.Unmarshal(product, e.Product); err != nil {
+				return nil, fmt.Errorf("invalid cached product %s: %w", e.SKU, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,5 @@
+-- Rollback migration 002: Price observation dedup
+
+DROP INDEX IF EXISTS idx_price_obs_unique;
+CREATE INDEX idx_price_obs_lookup ON price_observations(product_id, competitor_id, observed_date);
+ALTER TABLE price_observations ALTER COLUMN observed_at DROP NOT NULL;
@@ -0,0 +1,21 @@
+-- Migration 002: One price observation per product, competitor and day
+
+-- Rows without observed_at never compare in the dedup below, so give them the
+-- start of their observed_date; they then lose to any timed observation
+UPDATE price_observations
+SET observed_at = observed_date::timestamptz
+WHERE observed_at IS NULL;
+
+ALTER TABLE price_observations ALTER COLUMN observed_at SET NOT NULL;
+
+-- Remove duplicates left by earlier re-imports, keeping the latest observation
+DELETE FROM price_observations a
+USING price_observations b
+WHERE a.product_id = b.product_id
+  AND a.competitor_id = b.competitor_id
+  AND a.observed_date = b.observed_date
+  AND (a.observed_at, a.id) < (b.observed_at, b.id);
+
+-- Replace the lookup index with a unique one (includes the partition key)
+DROP INDEX IF EXISTS idx_price_obs_lookup;
+CREATE UNIQUE INDEX idx_price_obs_unique ON price_observations(product_id, competitor_id, observed_date);
@@ -0,0 +1,6 @@
+-- Rollback migration 003: Product full-text search
+
+DROP INDEX IF EXISTS idx_products_search;
+DROP TRIGGER IF EXISTS update_products_search_vector ON products;
+DROP FUNCTION IF EXISTS products_search_vector_update();
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
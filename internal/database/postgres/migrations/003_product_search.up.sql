@@ -0,0 +1,28 @@
+-- Migration 003: Full-text search over products
+
+ALTER TABLE products ADD COLUMN search_vector tsvector;
+
+-- The 'simple' configuration skips stemming, which suits mixed
+-- Norwegian/English product titles and brand or series names
+CREATE OR REPLACE FUNCTION products_search_vector_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', COALESCE(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('simple', array_to_string(COALESCE(NEW.tags, '{}'), ' ')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.description, '')), 'C');
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_products_search_vector
+    BEFORE INSERT OR UPDATE OF title, description, tags ON products
+    FOR EACH ROW EXECUTE FUNCTION products_search_vector_update();
+
+-- Backfill existing rows
+UPDATE products SET search_vector =
+    setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+    setweight(to_tsvector('simple', array_to_string(COALESCE(tags, '{}'), ' ')), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(description, '')), 'C');
+
+CREATE INDEX idx_products_search ON products USING GIN(search_vector);
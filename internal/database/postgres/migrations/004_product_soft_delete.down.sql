@@ -0,0 +1,4 @@
+-- Rollback migration 004: Product soft-delete
+
+DROP INDEX IF EXISTS idx_products_deleted_at;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,6 @@
+-- Migration 004: Soft-delete for products
+
+-- Archived products keep their price history and links; Purge removes them for good
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_products_deleted_at ON products(deleted_at) WHERE deleted_at IS NOT NULL;
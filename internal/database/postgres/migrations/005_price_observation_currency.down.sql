@@ -0,0 +1,4 @@
+-- Rollback migration 005: Currency normalization for price observations
+
+ALTER TABLE price_observations DROP COLUMN IF EXISTS original_currency;
+ALTER TABLE price_observations DROP COLUMN IF EXISTS original_price;
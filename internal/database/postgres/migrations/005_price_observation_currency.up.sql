@@ -0,0 +1,6 @@
+-- Migration 005: Currency normalization for price observations
+
+-- price/currency hold the price in the base currency; prices quoted in another
+-- currency keep the quoted amount here (NULL when no conversion was needed)
+ALTER TABLE price_observations ADD COLUMN original_price DECIMAL(12,2);
+ALTER TABLE price_observations ADD COLUMN original_currency VARCHAR(3);
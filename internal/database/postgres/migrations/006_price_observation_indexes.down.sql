@@ -0,0 +1,5 @@
+-- Rollback migration 006: Indexes for the price observation read paths
+
+DROP INDEX IF EXISTS idx_price_obs_latest;
+DROP INDEX IF EXISTS idx_price_obs_product_observed;
+CREATE INDEX idx_price_obs_product ON price_observations(product_id);
@@ -0,0 +1,14 @@
+-- Migration 006: Indexes for the price observation read paths
+
+-- Price history per product (GetPriceHistory) filters on product_id and a
+-- time range and sorts newest first. Supersedes idx_price_obs_product.
+CREATE INDEX idx_price_obs_product_observed ON price_observations(product_id, observed_at DESC);
+DROP INDEX IF EXISTS idx_price_obs_product;
+
+-- Latest price per competitor (GetLatestByProduct, DISTINCT ON competitor_id)
+-- and per-pair history (GetByProductAndCompetitor) read one competitor's
+-- observations for a product newest first
+CREATE INDEX idx_price_obs_latest ON price_observations(product_id, competitor_id, observed_at DESC);
+
+-- Lookups by competitor_id alone use idx_price_obs_competitor and
+-- idx_competitor_products_competitor from migration 001
@@ -0,0 +1,3 @@
+-- Rollback migration 007: profit_margin was never written before it
+
+UPDATE products SET profit_margin = NULL;
@@ -0,0 +1,7 @@
+-- Migration 007: Fill in profit_margin for existing products
+
+-- Writes now store (price - cost) / price * 100 when both are known; compute
+-- it for rows written before that
+UPDATE products
+SET profit_margin = GREATEST(ROUND((price - cost) / price * 100, 2), -999.99)
+WHERE price > 0 AND cost > 0;
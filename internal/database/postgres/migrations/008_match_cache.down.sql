@@ -0,0 +1,3 @@
+-- Rollback migration 008: Shared Tiger.nl match cache
+
+DROP TABLE IF EXISTS match_cache;
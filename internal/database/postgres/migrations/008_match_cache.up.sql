@@ -0,0 +1,14 @@
+-- Migration 008: Shared Tiger.nl match cache
+
+-- Lookup results keyed like the file cache (output/.tiger-cache.json): a SKU,
+-- "gtin:" + a normalized barcode, or "url:" + an overridden product page.
+-- Expiry is decided by the reader's cache_ttl, so stale rows are kept.
+CREATE TABLE match_cache (
+    key TEXT PRIMARY KEY,
+    url TEXT,
+    not_found BOOLEAN NOT NULL DEFAULT FALSE,
+    product JSONB,
+    cached_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_match_cache_cached_at ON match_cache(cached_at);
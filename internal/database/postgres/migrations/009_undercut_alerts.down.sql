@@ -0,0 +1,3 @@
+-- Rollback migration 009: Last undercut alert sent per product
+
+DROP TABLE IF EXISTS undercut_alerts;
@@ -0,0 +1,13 @@
+-- Migration 009: Last undercut alert sent per product
+
+-- An undercut is only alerted again once our price, the market minimum or the
+-- cheapest competitor changes, so scheduled imports and watch runs don't
+-- repeat the same alert. Rows are removed when the product stops being
+-- undercut.
+CREATE TABLE undercut_alerts (
+    sku VARCHAR(100) PRIMARY KEY,
+    competitor VARCHAR(200) NOT NULL DEFAULT '',
+    our_price DECIMAL(12, 2) NOT NULL,
+    market_min DECIMAL(12, 2) NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,13 @@
+-- Rollback migration 010: Package info details
+
+ALTER TABLE package_info
+    DROP COLUMN IF EXISTS volume,
+    DROP COLUMN IF EXISTS is_pcu,
+    DROP COLUMN IF EXISTS min_order_qty,
+    DROP COLUMN IF EXISTS deliverable,
+    DROP COLUMN IF EXISTS stocked,
+    DROP COLUMN IF EXISTS calculated_count,
+    DROP COLUMN IF EXISTS consists_of_count,
+    DROP COLUMN IF EXISTS consists_of_unit,
+    DROP COLUMN IF EXISTS dangerous_goods,
+    DROP COLUMN IF EXISTS dgun_number;
@@ -0,0 +1,15 @@
+-- Migration 010: Keep every NOBB packaging field in package_info
+
+-- The state store writes products' package_info rows on save and reads them
+-- back on load, so the table holds everything models.PackageInfo does
+ALTER TABLE package_info
+    ADD COLUMN volume DECIMAL(12, 3),
+    ADD COLUMN is_pcu BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN min_order_qty INTEGER,
+    ADD COLUMN deliverable BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN stocked BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN calculated_count DECIMAL(12, 3),
+    ADD COLUMN consists_of_count DECIMAL(12, 3),
+    ADD COLUMN consists_of_unit VARCHAR(20),
+    ADD COLUMN dangerous_goods BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN dgun_number VARCHAR(20);
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// PackageInfoRepo reads the NOBB packaging levels of products. They are
+// written with the product by ProductRepo.SaveEnhanced.
+type PackageInfoRepo struct {
+	client *Client
+}
+
+// NewPackageInfoRepo creates a new PostgreSQL package info repository
+func NewPackageInfoRepo(client *Client) *PackageInfoRepo {
+	return &PackageInfoRepo{client: client}
+}
+
+// GetByProducts retrieves the packaging levels of several products in one
+// query, keyed by product ID, in the order they were saved
+func (r *PackageInfoRepo) GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]models.PackageInfo, error) {
+	byProduct := make(map[uuid.UUID][]models.PackageInfo)
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+
+	query := `
+		SELECT product_id, package_type, COALESCE(quantity, 0), COALESCE(gtin, ''),
+			COALESCE(weight, 0)::float8, COALESCE(weight_unit, ''),
+			COALESCE(length, 0)::float8, COALESCE(width, 0)::float8, COALESCE(height, 0)::float8,
+			COALESCE(dim_unit, ''), COALESCE(volume, 0)::float8, is_pcu, COALESCE(min_order_qty, 0),
+			deliverable, stocked, COALESCE(calculated_count, 0)::float8,
+			COALESCE(consists_of_count, 0)::float8, COALESCE(consists_of_unit, ''),
+			dangerous_goods, COALESCE(dgun_number, '')
+		FROM package_info
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, id
+	`
+
+	rows, err := r.client.pool.Query(ctx, query, uuidStrings(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query package info: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var pkg models.PackageInfo
+		var productIDStr string
+
+		err := rows.Scan(&productIDStr, &pkg.Type, &pkg.Quantity, &pkg.GTIN,
+			&pkg.Weight, &pkg.WeightUnit, &pkg.Length, &pkg.Width, &pkg.Height,
+			&pkg.DimUnit, &pkg.Volume, &pkg.IsPCU, &pkg.MinOrderQty,
+			&pkg.Deliverable, &pkg.Stocked, &pkg.CalculatedCount,
+			&pkg.ConsistsOfCount, &pkg.ConsistsOfUnit, &pkg.DangerousGoods, &pkg.DGUNNumber)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan package info: %w", err)
+		}
+
+		productID, _ := uuid.Parse(productIDStr)
+		byProduct[productID] = append(byProduct[productID], pkg)
+	}
+
+	return byProduct, rows.Err()
+}
+
+// savePackageInfo replaces the product's packaging levels. An empty list
+// leaves the stored ones alone, since callers such as the Shopify webhook
+// only carry product fields.
+func savePackageInfo(ctx context.Context, tx pgx.Tx, productID string, packages []models.PackageInfo) error {
+	if len(packages) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM package_info WHERE product_id = $1", productID); err != nil {
+		return fmt.Errorf("failed to replace package info: %w", err)
+	}
+	for _, pkg := range packages {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO package_info (
+				product_id, package_type, quantity, gtin, weight, weight_unit,
+				length, width, height, dim_unit, volume, is_pcu, min_order_qty,
+				deliverable, stocked, calculated_count, consists_of_count,
+				consists_of_unit, dangerous_goods, dgun_number
+			) VALUES ($1, $2, $3, NULLIF($4, ''), $5, NULLIF($6, ''), $7, $8, $9, NULLIF($10, ''),
+				$11, $12, $13, $14, $15, $16, $17, NULLIF($18, ''), $19, NULLIF($20, ''))
+		`, productID, pkg.Type, pkg.Quantity, pkg.GTIN, pkg.Weight, pkg.WeightUnit,
+			pkg.Length, pkg.Width, pkg.Height, pkg.DimUnit, pkg.Volume, pkg.IsPCU, pkg.MinOrderQty,
+			pkg.Deliverable, pkg.Stocked, pkg.CalculatedCount, pkg.ConsistsOfCount,
+			pkg.ConsistsOfUnit, pkg.DangerousGoods, pkg.DGUNNumber,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save %s package info: %w", pkg.Type, err)
+		}
+	}
+	return nil
+}
@@ -3,9 +3,12 @@ package postgres
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/badno/badops/internal/database"
 	"github.com/badno/badops/pkg/models"
@@ -23,8 +26,12 @@ func NewProductRepo(client *Client) *ProductRepo {
 	return &ProductRepo{client: client}
 }
 
-// Create inserts a new product into the database
+// Create inserts a new product into the database. Products that fail
+// EnhancedProduct.Validate are rejected.
 func (r *ProductRepo) Create(ctx context.Context, product *models.EnhancedProduct) error {
+	if errs := product.Validate(); len(errs) > 0 {
+		return fmt.Errorf("invalid product %q: %w", product.SKU, errors.Join(errs...))
+	}
 	if product.ID == "" {
 		product.ID = uuid.New().String()
 	}
@@ -68,9 +75,14 @@ func (r *ProductRepo) Create(ctx context.Context, product *models.EnhancedProduc
 
 	var length, width, height *float64
 	if product.Dimensions != nil {
-		length = &product.Dimensions.Length
-		width = &product.Dimensions.Width
-		height = &product.Dimensions.Height
+		// Dimensions are stored in mm
+		dims := product.Dimensions
+		if mm := dims.ConvertTo("mm"); mm != nil {
+			dims = mm
+		}
+		length = &dims.Length
+		width = &dims.Width
+		height = &dims.Height
 	}
 
 	specsJSON, _ := json.Marshal(product.Specifications)
@@ -84,7 +96,7 @@ func (r *ProductRepo) Create(ctx context.Context, product *models.EnhancedProduc
 	_, err := r.client.pool.Exec(ctx, query,
 		product.ID, product.SKU, product.Handle, product.Barcode, product.NOBBNumber,
 		product.Title, product.Description, product.Vendor, product.ProductType, product.Tags,
-		price, cost, compareAt, currency, nil,
+		price, cost, compareAt, currency, profitMargin(product.Price),
 		weightValue, weightUnit, length, width, height,
 		string(product.Status), specsJSON, product.CreatedAt, product.UpdatedAt,
 		product.LegacyMatchedURL, product.LegacyMatchScore,
@@ -97,22 +109,22 @@ func (r *ProductRepo) Create(ctx context.Context, product *models.EnhancedProduc
 	return nil
 }
 
-// GetByID retrieves a product by its UUID
+// GetByID retrieves a product by its UUID, including soft-deleted products
 func (r *ProductRepo) GetByID(ctx context.Context, id uuid.UUID) (*models.EnhancedProduct, error) {
-	return r.getByField(ctx, "id", id.String())
+	return r.getByField(ctx, "id", id.String(), true)
 }
 
 // GetBySKU retrieves a product by its SKU
 func (r *ProductRepo) GetBySKU(ctx context.Context, sku string) (*models.EnhancedProduct, error) {
-	return r.getByField(ctx, "sku", sku)
+	return r.getByField(ctx, "sku", sku, false)
 }
 
 // GetByBarcode retrieves a product by its barcode
 func (r *ProductRepo) GetByBarcode(ctx context.Context, barcode string) (*models.EnhancedProduct, error) {
-	return r.getByField(ctx, "barcode", barcode)
+	return r.getByField(ctx, "barcode", barcode, false)
 }
 
-func (r *ProductRepo) getByField(ctx context.Context, field, value string) (*models.EnhancedProduct, error) {
+func (r *ProductRepo) getByField(ctx context.Context, field, value string, includeDeleted bool) (*models.EnhancedProduct, error) {
 	query := fmt.Sprintf(`
 		SELECT
 			id, sku, handle, barcode, nobb_number,
@@ -124,6 +136,9 @@ func (r *ProductRepo) getByField(ctx context.Context, field, value string) (*mod
 		FROM products
 		WHERE %s = $1
 	`, field)
+	if !includeDeleted {
+		query += " AND deleted_at IS NULL"
+	}
 
 	row := r.client.pool.QueryRow(ctx, query, value)
 	return r.scanProduct(row)
@@ -209,7 +224,8 @@ func (r *ProductRepo) Update(ctx context.Context, product *models.EnhancedProduc
 			weight_value = $14, weight_unit = $15,
 			length_mm = $16, width_mm = $17, height_mm = $18,
 			status = $19, specifications = $20,
-			legacy_matched_url = $21, legacy_match_score = $22
+			legacy_matched_url = $21, legacy_match_score = $22,
+			profit_margin = $23
 		WHERE id = $1
 	`
 
@@ -234,9 +250,14 @@ func (r *ProductRepo) Update(ctx context.Context, product *models.EnhancedProduc
 
 	var length, width, height *float64
 	if product.Dimensions != nil {
-		length = &product.Dimensions.Length
-		width = &product.Dimensions.Width
-		height = &product.Dimensions.Height
+		// Dimensions are stored in mm
+		dims := product.Dimensions
+		if mm := dims.ConvertTo("mm"); mm != nil {
+			dims = mm
+		}
+		length = &dims.Length
+		width = &dims.Width
+		height = &dims.Height
 	}
 
 	specsJSON, _ := json.Marshal(product.Specifications)
@@ -248,6 +269,7 @@ func (r *ProductRepo) Update(ctx context.Context, product *models.EnhancedProduc
 		weightValue, weightUnit, length, width, height,
 		string(product.Status), specsJSON,
 		product.LegacyMatchedURL, product.LegacyMatchScore,
+		profitMargin(product.Price),
 	)
 
 	if err != nil {
@@ -257,151 +279,326 @@ func (r *ProductRepo) Update(ctx context.Context, product *models.EnhancedProduc
 	return nil
 }
 
-// Delete removes a product from the database
+// Delete soft-deletes a product: it is marked archived and hidden from
+// lookups and listings, but its price history is kept. See Purge.
 func (r *ProductRepo) Delete(ctx context.Context, id uuid.UUID) error {
-	_, err := r.client.pool.Exec(ctx, "DELETE FROM products WHERE id = $1", id.String())
+	query := `
+		UPDATE products
+		SET deleted_at = NOW(), status = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	_, err := r.client.pool.Exec(ctx, query, id.String(), string(models.StatusArchived))
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
 	return nil
 }
 
-// BulkUpsert inserts or updates multiple products
-func (r *ProductRepo) BulkUpsert(ctx context.Context, products []*models.EnhancedProduct) (int, error) {
-	if len(products) == 0 {
+// UpdateTags replaces the tags of the active products keyed by SKU in one
+// transaction, leaving their other columns alone, and returns how many rows
+// were updated
+func (r *ProductRepo) UpdateTags(ctx context.Context, tags map[string][]string) (int, error) {
+	if len(tags) == 0 {
 		return 0, nil
 	}
-
 	tx, err := r.client.pool.Begin(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	query := `
-		INSERT INTO products (
-			id, sku, handle, barcode, nobb_number,
-			title, description, vendor, product_type, tags,
-			price, cost, compare_at_price, currency,
-			weight_value, weight_unit, length_mm, width_mm, height_mm,
-			status, specifications, created_at, updated_at,
-			legacy_matched_url, legacy_match_score
-		) VALUES (
-			$1, $2, $3, $4, $5,
-			$6, $7, $8, $9, $10,
-			$11, $12, $13, $14,
-			$15, $16, $17, $18, $19,
-			$20, $21, $22, $23,
-			$24, $25
-		)
-		ON CONFLICT (sku) DO UPDATE SET
-			handle = EXCLUDED.handle,
-			barcode = COALESCE(NULLIF(EXCLUDED.barcode, ''), products.barcode),
-			nobb_number = COALESCE(NULLIF(EXCLUDED.nobb_number, ''), products.nobb_number),
-			title = EXCLUDED.title,
-			description = COALESCE(NULLIF(EXCLUDED.description, ''), products.description),
-			vendor = EXCLUDED.vendor,
-			product_type = EXCLUDED.product_type,
-			tags = EXCLUDED.tags,
-			price = COALESCE(EXCLUDED.price, products.price),
-			cost = COALESCE(EXCLUDED.cost, products.cost),
-			compare_at_price = COALESCE(EXCLUDED.compare_at_price, products.compare_at_price),
-			currency = EXCLUDED.currency,
-			weight_value = COALESCE(EXCLUDED.weight_value, products.weight_value),
-			weight_unit = COALESCE(NULLIF(EXCLUDED.weight_unit, ''), products.weight_unit),
-			length_mm = COALESCE(EXCLUDED.length_mm, products.length_mm),
-			width_mm = COALESCE(EXCLUDED.width_mm, products.width_mm),
-			height_mm = COALESCE(EXCLUDED.height_mm, products.height_mm),
-			status = EXCLUDED.status,
-			specifications = products.specifications || EXCLUDED.specifications,
-			updated_at = NOW(),
-			legacy_matched_url = COALESCE(NULLIF(EXCLUDED.legacy_matched_url, ''), products.legacy_matched_url),
-			legacy_match_score = COALESCE(EXCLUDED.legacy_match_score, products.legacy_match_score)
-	`
-
 	batch := &pgx.Batch{}
-	now := time.Now()
-
-	for _, p := range products {
-		if p.ID == "" {
-			p.ID = uuid.New().String()
+	for sku, t := range tags {
+		if t == nil {
+			t = []string{}
 		}
+		batch.Queue(`
+			UPDATE products SET tags = $2, updated_at = NOW()
+			WHERE sku = $1 AND deleted_at IS NULL
+		`, sku, t)
+	}
 
-		var price, cost, compareAt *float64
-		var currency string = "NOK"
-		if p.Price != nil {
-			price = &p.Price.Amount
-			cost = &p.Price.CostPerItem
-			compareAt = &p.Price.CompareAt
-			currency = p.Price.Currency
+	updated := 0
+	br := tx.SendBatch(ctx, batch)
+	for range tags {
+		tag, err := br.Exec()
+		if err != nil {
+			br.Close()
+			return 0, fmt.Errorf("failed to update tags: %w", err)
 		}
+		updated += int(tag.RowsAffected())
+	}
+	if err := br.Close(); err != nil {
+		return 0, fmt.Errorf("failed to update tags: %w", err)
+	}
 
-		var weightValue *float64
-		var weightUnit string
-		if p.Weight != nil {
-			weightValue = &p.Weight.Value
-			weightUnit = p.Weight.Unit
-		}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return updated, nil
+}
 
-		var length, width, height *float64
-		if p.Dimensions != nil {
-			length = &p.Dimensions.Length
-			width = &p.Dimensions.Width
-			height = &p.Dimensions.Height
+// Purge permanently removes products soft-deleted before the cutoff,
+// along with their images, properties and links
+func (r *ProductRepo) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.client.pool.Exec(ctx, "DELETE FROM products WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge products: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// upsertProductQuery inserts a product or updates it by SKU. Sparse fields
+// (barcode, description, price, dimensions) never overwrite known values
+// with empty ones, and re-importing an archived product restores it.
+const upsertProductQuery = `
+	INSERT INTO products (
+		id, sku, handle, barcode, nobb_number,
+		title, description, vendor, product_type, tags,
+		price, cost, compare_at_price, currency,
+		weight_value, weight_unit, length_mm, width_mm, height_mm,
+		status, specifications, created_at, updated_at,
+		legacy_matched_url, legacy_match_score, profit_margin
+	) VALUES (
+		$1, $2, $3, $4, $5,
+		$6, $7, $8, $9, $10,
+		$11, $12, $13, $14,
+		$15, $16, $17, $18, $19,
+		$20, $21, $22, $23,
+		$24, $25, $26
+	)
+	ON CONFLICT (sku) DO UPDATE SET
+		handle = EXCLUDED.handle,
+		barcode = COALESCE(NULLIF(EXCLUDED.barcode, ''), products.barcode),
+		nobb_number = COALESCE(NULLIF(EXCLUDED.nobb_number, ''), products.nobb_number),
+		title = EXCLUDED.title,
+		description = COALESCE(NULLIF(EXCLUDED.description, ''), products.description),
+		vendor = EXCLUDED.vendor,
+		product_type = EXCLUDED.product_type,
+		tags = EXCLUDED.tags,
+		price = COALESCE(EXCLUDED.price, products.price),
+		cost = COALESCE(EXCLUDED.cost, products.cost),
+		compare_at_price = COALESCE(EXCLUDED.compare_at_price, products.compare_at_price),
+		-- The margin follows the price and cost sent with it
+		profit_margin = CASE WHEN EXCLUDED.price IS NULL THEN products.profit_margin ELSE EXCLUDED.profit_margin END,
+		currency = EXCLUDED.currency,
+		weight_value = COALESCE(EXCLUDED.weight_value, products.weight_value),
+		weight_unit = COALESCE(NULLIF(EXCLUDED.weight_unit, ''), products.weight_unit),
+		length_mm = COALESCE(EXCLUDED.length_mm, products.length_mm),
+		width_mm = COALESCE(EXCLUDED.width_mm, products.width_mm),
+		height_mm = COALESCE(EXCLUDED.height_mm, products.height_mm),
+		status = EXCLUDED.status,
+		specifications = products.specifications || EXCLUDED.specifications,
+		updated_at = NOW(),
+		deleted_at = NULL,
+		legacy_matched_url = COALESCE(NULLIF(EXCLUDED.legacy_matched_url, ''), products.legacy_matched_url),
+		legacy_match_score = COALESCE(EXCLUDED.legacy_match_score, products.legacy_match_score)
+`
+
+// productUpsertArgs returns the upsertProductQuery arguments for a product,
+// assigning it an ID if it has none
+func productUpsertArgs(p *models.EnhancedProduct, now time.Time) []interface{} {
+	if p.ID == "" {
+		p.ID = uuid.New().String()
+	}
+
+	var price, cost, compareAt *float64
+	var currency string = "NOK"
+	if p.Price != nil {
+		price = &p.Price.Amount
+		cost = &p.Price.CostPerItem
+		compareAt = &p.Price.CompareAt
+		currency = p.Price.Currency
+	}
+
+	var weightValue *float64
+	var weightUnit string
+	if p.Weight != nil {
+		weightValue = &p.Weight.Value
+		weightUnit = p.Weight.Unit
+	}
+
+	var length, width, height *float64
+	if p.Dimensions != nil {
+		// Dimensions are stored in mm
+		dims := p.Dimensions
+		if mm := dims.ConvertTo("mm"); mm != nil {
+			dims = mm
 		}
+		length = &dims.Length
+		width = &dims.Width
+		height = &dims.Height
+	}
 
-		specsJSON, _ := json.Marshal(p.Specifications)
+	specsJSON, _ := json.Marshal(p.Specifications)
 
-		createdAt := p.CreatedAt
-		if createdAt.IsZero() {
-			createdAt = now
+	createdAt := p.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = now
+	}
+
+	return []interface{}{
+		p.ID, p.SKU, p.Handle, p.Barcode, p.NOBBNumber,
+		p.Title, p.Description, p.Vendor, p.ProductType, p.Tags,
+		price, cost, compareAt, currency,
+		weightValue, weightUnit, length, width, height,
+		string(p.Status), specsJSON, createdAt, now,
+		p.LegacyMatchedURL, p.LegacyMatchScore, profitMargin(p.Price),
+	}
+}
+
+// profitMargin returns the value for the profit_margin column: the margin
+// rounded to two decimals, or nil when the price or cost is unknown. It is
+// clamped to what DECIMAL(5,2) holds.
+func profitMargin(price *models.Price) *float64 {
+	if !price.HasMargin() {
+		return nil
+	}
+	margin := math.Round(price.Margin()*100) / 100
+	margin = max(margin, -999.99)
+	return &margin
+}
+
+// BulkUpsert inserts or updates multiple products, committing every
+// database.DefaultBulkBatchSize products
+func (r *ProductRepo) BulkUpsert(ctx context.Context, products []*models.EnhancedProduct) (int, error) {
+	return r.BulkUpsertWithOptions(ctx, products, database.BulkOptions{})
+}
+
+// BulkUpsertWithOptions inserts or updates products in batches of
+// opts.BatchSize, each in its own transaction, so a failing batch only rolls
+// back itself. Products that fail EnhancedProduct.Validate are skipped and
+// logged. Returns the number of products in committed batches.
+func (r *ProductRepo) BulkUpsertWithOptions(ctx context.Context, products []*models.EnhancedProduct, opts database.BulkOptions) (int, error) {
+	valid := make([]*models.EnhancedProduct, 0, len(products))
+	for _, p := range products {
+		if errs := p.Validate(); len(errs) > 0 {
+			r.client.logger.Warn("skipping invalid product", "sku", p.SKU, "error", errors.Join(errs...))
+			continue
 		}
+		valid = append(valid, p)
+	}
+	products = valid
 
-		batch.Queue(query,
-			p.ID, p.SKU, p.Handle, p.Barcode, p.NOBBNumber,
-			p.Title, p.Description, p.Vendor, p.ProductType, p.Tags,
-			price, cost, compareAt, currency,
-			weightValue, weightUnit, length, width, height,
-			string(p.Status), specsJSON, createdAt, now,
-			p.LegacyMatchedURL, p.LegacyMatchScore,
-		)
+	if len(products) == 0 {
+		return 0, nil
 	}
 
-	br := tx.SendBatch(ctx, batch)
-	defer br.Close()
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = database.DefaultBulkBatchSize
+	}
 
+	start := time.Now()
 	count := 0
+	for i := 0; i < len(products); i += batchSize {
+		end := min(i+batchSize, len(products))
+		if err := r.upsertBatch(ctx, products[i:end]); err != nil {
+			return count, fmt.Errorf("products %d-%d: %w", i+1, end, err)
+		}
+		count = end
+		if opts.Progress != nil {
+			opts.Progress(count, len(products))
+		}
+	}
+
+	r.client.logger.Debug("bulk upsert", "products", count, "batch_size", batchSize, "duration", time.Since(start))
+	return count, nil
+}
+
+// upsertBatch upserts products in one transaction
+func (r *ProductRepo) upsertBatch(ctx context.Context, products []*models.EnhancedProduct) error {
+	tx, err := r.client.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	now := time.Now()
+
+	for _, p := range products {
+		batch.Queue(upsertProductQuery, productUpsertArgs(p, now)...)
+	}
+
+	br := tx.SendBatch(ctx, batch)
 	for range products {
-		_, err := br.Exec()
-		if err != nil {
-			return count, fmt.Errorf("failed to upsert product: %w", err)
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return fmt.Errorf("failed to upsert product: %w", err)
 		}
-		count++
+	}
+	if err := br.Close(); err != nil {
+		return fmt.Errorf("failed to upsert products: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-
-	return count, nil
+	return nil
 }
 
 // GetAll retrieves products with optional filtering
 func (r *ProductRepo) GetAll(ctx context.Context, opts database.QueryOptions) ([]*models.EnhancedProduct, error) {
-	var conditions []string
-	var args []interface{}
-	argNum := 1
+	query, args := listProductsQuery(opts, false)
 
-	if opts.Vendor != "" {
-		conditions = append(conditions, fmt.Sprintf("vendor = $%d", argNum))
-		args = append(args, opts.Vendor)
-		argNum++
+	rows, err := r.client.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
 	}
+	defer rows.Close()
 
-	if opts.Status != "" {
-		conditions = append(conditions, fmt.Sprintf("status = $%d", argNum))
-		args = append(args, string(opts.Status))
-		argNum++
+	return r.scanProducts(rows)
+}
+
+// GetAllPaged retrieves one page of products along with the total number of
+// products matching the filters, so callers can paginate
+func (r *ProductRepo) GetAllPaged(ctx context.Context, opts database.QueryOptions) (*database.ProductPage, error) {
+	query, args := listProductsQuery(opts, true)
+
+	rows, err := r.client.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	// The windowed count is repeated on every row
+	var total int64
+	products, err := r.scanProducts(rows, &total)
+	if err != nil {
+		return nil, err
+	}
+
+	// A page past the end has no rows to carry the count
+	if len(products) == 0 && opts.Offset > 0 {
+		conditions, args := productFilters(opts, nil)
+		countQuery := "SELECT COUNT(*) FROM products"
+		if len(conditions) > 0 {
+			countQuery += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		if err := r.client.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("failed to count products: %w", err)
+		}
+	}
+
+	return &database.ProductPage{
+		Products:   products,
+		TotalCount: total,
+		Limit:      opts.Limit,
+		Offset:     opts.Offset,
+		HasMore:    int64(opts.Offset+len(products)) < total,
+	}, nil
+}
+
+// listProductsQuery builds the filtered, ordered and limited product query for
+// GetAll. With withTotal, a count(*) over() column is appended after the
+// product columns.
+func listProductsQuery(opts database.QueryOptions, withTotal bool) (string, []interface{}) {
+	conditions, args := productFilters(opts, nil)
+
+	totalColumn := ""
+	if withTotal {
+		totalColumn = ", COUNT(*) OVER() AS total_count"
 	}
 
 	query := `
@@ -411,7 +608,7 @@ func (r *ProductRepo) GetAll(ctx context.Context, opts database.QueryOptions) ([
 			price, cost, compare_at_price, currency,
 			weight_value, weight_unit, length_mm, width_mm, height_mm,
 			status, specifications, created_at, updated_at,
-			legacy_matched_url, legacy_match_score
+			legacy_matched_url, legacy_match_score` + totalColumn + `
 		FROM products
 	`
 
@@ -436,16 +633,121 @@ func (r *ProductRepo) GetAll(ctx context.Context, opts database.QueryOptions) ([
 		query += fmt.Sprintf(" OFFSET %d", opts.Offset)
 	}
 
-	rows, err := r.client.pool.Query(ctx, query, args...)
+	return query, args
+}
+
+// productFilters translates QueryOptions filters into SQL conditions,
+// numbering placeholders after any args already in use
+func productFilters(opts database.QueryOptions, args []interface{}) ([]string, []interface{}) {
+	var conditions []string
+
+	if !opts.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if opts.Vendor != "" {
+		args = append(args, opts.Vendor)
+		conditions = append(conditions, fmt.Sprintf("vendor = $%d", len(args)))
+	}
+
+	if opts.Status != "" {
+		args = append(args, string(opts.Status))
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	if opts.MissingImages {
+		conditions = append(conditions, "NOT EXISTS (SELECT 1 FROM product_images pi WHERE pi.product_id = products.id)")
+	}
+
+	if opts.MissingDescription {
+		conditions = append(conditions, "(description IS NULL OR description = '')")
+	}
+
+	if opts.MarginBelow != nil {
+		args = append(args, *opts.MarginBelow)
+		conditions = append(conditions, fmt.Sprintf("profit_margin < $%d", len(args)))
+	}
+
+	return conditions, args
+}
+
+// Search finds products matching query in title, tags and description,
+// ranked by relevance. Words match as prefixes, so "bost hook" finds
+// "Boston Hook". SKUs and barcodes containing the query are also matched
+// and ranked first. Vendor, status and limit options apply.
+func (r *ProductRepo) Search(ctx context.Context, query string, opts database.QueryOptions) ([]*models.EnhancedProduct, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+
+	args := []interface{}{prefixTSQuery(query), "%" + escapeLike(query) + "%"}
+	conditions, args := productFilters(opts, args)
+
+	sql := `
+		SELECT
+			id, sku, handle, barcode, nobb_number,
+			title, description, vendor, product_type, tags,
+			price, cost, compare_at_price, currency,
+			weight_value, weight_unit, length_mm, width_mm, height_mm,
+			status, specifications, created_at, updated_at,
+			legacy_matched_url, legacy_match_score
+		FROM products
+		WHERE (search_vector @@ to_tsquery('simple', $1) OR sku ILIKE $2 OR barcode ILIKE $2)
+	`
+
+	for _, c := range conditions {
+		sql += " AND " + c
+	}
+
+	sql += `
+		ORDER BY
+			(CASE WHEN sku ILIKE $2 OR barcode ILIKE $2 THEN 1 ELSE 0 END) DESC,
+			ts_rank(search_vector, to_tsquery('simple', $1)) DESC,
+			title
+	`
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	sql += fmt.Sprintf(" LIMIT %d", limit)
+	if opts.Offset > 0 {
+		sql += fmt.Sprintf(" OFFSET %d", opts.Offset)
+	}
+
+	rows, err := r.client.pool.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query products: %w", err)
+		return nil, fmt.Errorf("failed to search products: %w", err)
 	}
 	defer rows.Close()
 
 	return r.scanProducts(rows)
 }
 
-func (r *ProductRepo) scanProducts(rows pgx.Rows) ([]*models.EnhancedProduct, error) {
+// prefixTSQuery builds a tsquery that requires every word of a search as a
+// prefix, e.g. "boston hook" becomes "boston:* & hook:*". Characters with
+// meaning in tsquery syntax are dropped.
+func prefixTSQuery(search string) string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// escapeLike escapes LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// scanProducts scans product rows. Extra destinations receive any columns
+// selected after the product columns.
+func (r *ProductRepo) scanProducts(rows pgx.Rows, extra ...interface{}) ([]*models.EnhancedProduct, error) {
 	var products []*models.EnhancedProduct
 
 	for rows.Next() {
@@ -458,14 +760,15 @@ func (r *ProductRepo) scanProducts(rows pgx.Rows) ([]*models.EnhancedProduct, er
 		var specs []byte
 		var status string
 
-		err := rows.Scan(
+		dest := []interface{}{
 			&p.ID, &p.SKU, &p.Handle, &p.Barcode, &p.NOBBNumber,
 			&p.Title, &p.Description, &p.Vendor, &p.ProductType, &p.Tags,
 			&price, &cost, &compareAt, &currency,
 			&weightValue, &weightUnit, &length, &width, &height,
 			&status, &specs, &p.CreatedAt, &p.UpdatedAt,
 			&p.LegacyMatchedURL, &p.LegacyMatchScore,
-		)
+		}
+		err := rows.Scan(append(dest, extra...)...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
@@ -528,10 +831,10 @@ func (r *ProductRepo) GetByStatus(ctx context.Context, status models.ProductStat
 	return r.GetAll(ctx, database.QueryOptions{Status: status})
 }
 
-// Count returns the total number of products
+// Count returns the number of products, excluding soft-deleted ones
 func (r *ProductRepo) Count(ctx context.Context) (int64, error) {
 	var count int64
-	err := r.client.pool.QueryRow(ctx, "SELECT COUNT(*) FROM products").Scan(&count)
+	err := r.client.pool.QueryRow(ctx, "SELECT COUNT(*) FROM products WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count products: %w", err)
 	}
@@ -540,7 +843,7 @@ func (r *ProductRepo) Count(ctx context.Context) (int64, error) {
 
 // CountByVendor returns product counts grouped by vendor
 func (r *ProductRepo) CountByVendor(ctx context.Context) (map[string]int64, error) {
-	query := `SELECT vendor, COUNT(*) FROM products GROUP BY vendor ORDER BY COUNT(*) DESC`
+	query := `SELECT vendor, COUNT(*) FROM products WHERE deleted_at IS NULL GROUP BY vendor ORDER BY COUNT(*) DESC`
 	rows, err := r.client.pool.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count by vendor: %w", err)
@@ -559,7 +862,7 @@ func (r *ProductRepo) CountByVendor(ctx context.Context) (map[string]int64, erro
 	return counts, rows.Err()
 }
 
-// CountByStatus returns product counts grouped by status
+// CountByStatus returns product counts grouped by status, including archived products
 func (r *ProductRepo) CountByStatus(ctx context.Context) (map[models.ProductStatus]int64, error) {
 	query := `SELECT status, COUNT(*) FROM products GROUP BY status`
 	rows, err := r.client.pool.Query(ctx, query)
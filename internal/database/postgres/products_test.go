@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+)
+
+func TestListProductsQueryFilters(t *testing.T) {
+	margin := 15.0
+	tests := []struct {
+		name       string
+		opts       database.QueryOptions
+		conditions []string
+		args       []interface{}
+	}{
+		{
+			name:       "no filters",
+			conditions: []string{"deleted_at IS NULL"},
+		},
+		{
+			name:       "vendor and status",
+			opts:       database.QueryOptions{Vendor: "Tiger", Status: models.StatusEnhanced},
+			conditions: []string{"deleted_at IS NULL", "vendor = $1", "status = $2"},
+			args:       []interface{}{"Tiger", "enhanced"},
+		},
+		{
+			name:       "deleted included with margin",
+			opts:       database.QueryOptions{IncludeDeleted: true, MarginBelow: &margin},
+			conditions: []string{"profit_margin < $1"},
+			args:       []interface{}{15.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, args := productFilters(tt.opts, nil)
+			if fmt.Sprint(conditions) != fmt.Sprint(tt.conditions) {
+				t.Errorf("conditions = %q, want %q", conditions, tt.conditions)
+			}
+			if fmt.Sprint(args) != fmt.Sprint(tt.args) {
+				t.Errorf("args = %v, want %v", args, tt.args)
+			}
+
+			// The paged query counts over the same filters it lists
+			query, queryArgs := listProductsQuery(tt.opts, true)
+			if !strings.Contains(query, "COUNT(*) OVER() AS total_count") {
+				t.Errorf("query lacks windowed count:\n%s", query)
+			}
+			if !strings.Contains(query, " WHERE "+strings.Join(tt.conditions, " AND ")) {
+				t.Errorf("query lacks filters %q:\n%s", tt.conditions, query)
+			}
+			if fmt.Sprint(queryArgs) != fmt.Sprint(tt.args) {
+				t.Errorf("query args = %v, want %v", queryArgs, tt.args)
+			}
+		})
+	}
+}
+
+func TestListProductsQueryTotalColumn(t *testing.T) {
+	query, _ := listProductsQuery(database.QueryOptions{Limit: 10, Offset: 20}, false)
+	if strings.Contains(query, "total_count") {
+		t.Errorf("unpaged query has a count column:\n%s", query)
+	}
+	if !strings.HasSuffix(query, " LIMIT 10 OFFSET 20") {
+		t.Errorf("query does not end with limit and offset:\n%s", query)
+	}
+}
+
+func TestGetAllPagedFilteredCounts(t *testing.T) {
+	client := testClient(t)
+	repo := NewProductRepo(client)
+	ctx := context.Background()
+
+	// 5 Tiger products (3 enhanced), 2 Geberit, and 1 deleted Tiger product
+	seed := []struct {
+		sku    string
+		vendor string
+		status models.ProductStatus
+	}{
+		{"T-1", "Tiger", models.StatusEnhanced},
+		{"T-2", "Tiger", models.StatusEnhanced},
+		{"T-3", "Tiger", models.StatusEnhanced},
+		{"T-4", "Tiger", models.StatusPending},
+		{"T-5", "Tiger", models.StatusPending},
+		{"G-1", "Geberit", models.StatusEnhanced},
+		{"G-2", "Geberit", models.StatusPending},
+		{"T-6", "Tiger", models.StatusEnhanced},
+	}
+	var last *models.EnhancedProduct
+	for _, s := range seed {
+		last = &models.EnhancedProduct{SKU: s.sku, Title: s.sku, Vendor: s.vendor, Status: s.status}
+		if err := repo.Create(ctx, last); err != nil {
+			t.Fatalf("create %s: %v", s.sku, err)
+		}
+	}
+	if err := repo.Delete(ctx, uuid.MustParse(last.ID)); err != nil {
+		t.Fatalf("delete %s: %v", last.SKU, err)
+	}
+
+	tests := []struct {
+		name      string
+		opts      database.QueryOptions
+		wantRows  int
+		wantTotal int64
+		wantMore  bool
+	}{
+		{"all first page", database.QueryOptions{Limit: 4}, 4, 7, true},
+		{"all last page", database.QueryOptions{Limit: 4, Offset: 4}, 3, 7, false},
+		{"vendor", database.QueryOptions{Vendor: "Tiger", Limit: 2}, 2, 5, true},
+		{"vendor and status", database.QueryOptions{Vendor: "Tiger", Status: models.StatusEnhanced, Limit: 2}, 2, 3, true},
+		{"vendor and status last page", database.QueryOptions{Vendor: "Tiger", Status: models.StatusEnhanced, Limit: 2, Offset: 2}, 1, 3, false},
+		{"page past the end", database.QueryOptions{Vendor: "Tiger", Status: models.StatusEnhanced, Limit: 2, Offset: 10}, 0, 3, false},
+		{"deleted included", database.QueryOptions{Vendor: "Tiger", IncludeDeleted: true, Limit: 10}, 6, 6, false},
+		{"no matches", database.QueryOptions{Vendor: "Ifö", Limit: 10}, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repo.GetAllPaged(ctx, tt.opts)
+			if err != nil {
+				t.Fatalf("GetAllPaged: %v", err)
+			}
+			if len(page.Products) != tt.wantRows {
+				t.Errorf("rows = %d, want %d", len(page.Products), tt.wantRows)
+			}
+			if page.TotalCount != tt.wantTotal {
+				t.Errorf("TotalCount = %d, want %d", page.TotalCount, tt.wantTotal)
+			}
+			if page.HasMore != tt.wantMore {
+				t.Errorf("HasMore = %v, want %v", page.HasMore, tt.wantMore)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// SupplierRepo implements the SupplierRepository interface
+type SupplierRepo struct {
+	client *Client
+}
+
+// NewSupplierRepo creates a new PostgreSQL supplier repository
+func NewSupplierRepo(client *Client) *SupplierRepo {
+	return &SupplierRepo{client: client}
+}
+
+// upsertSupplierQuery inserts a supplier keyed on its NOBB participant number.
+// An empty GLN never overwrites a known one.
+const upsertSupplierQuery = `
+	INSERT INTO suppliers (id, name, gln)
+	VALUES ($1, $2, NULLIF($3, ''))
+	ON CONFLICT (id) DO UPDATE SET
+		name = EXCLUDED.name,
+		gln = COALESCE(EXCLUDED.gln, suppliers.gln)
+`
+
+// Create inserts or updates a supplier
+func (r *SupplierRepo) Create(ctx context.Context, supplier *database.Supplier) error {
+	_, err := r.client.pool.Exec(ctx, upsertSupplierQuery, supplier.ID, supplier.Name, supplier.GLN)
+	if err != nil {
+		return fmt.Errorf("failed to create supplier: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a supplier by participant number
+func (r *SupplierRepo) GetByID(ctx context.Context, id string) (*database.Supplier, error) {
+	query := `SELECT id, name, COALESCE(gln, '') FROM suppliers WHERE id = $1`
+
+	var s database.Supplier
+	err := r.client.pool.QueryRow(ctx, query, id).Scan(&s.ID, &s.Name, &s.GLN)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplier: %w", err)
+	}
+
+	return &s, nil
+}
+
+// GetAll retrieves all suppliers ordered by name
+func (r *SupplierRepo) GetAll(ctx context.Context) ([]*database.Supplier, error) {
+	query := `SELECT id, name, COALESCE(gln, '') FROM suppliers ORDER BY name`
+
+	rows, err := r.client.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	var suppliers []*database.Supplier
+	for rows.Next() {
+		var s database.Supplier
+		if err := rows.Scan(&s.ID, &s.Name, &s.GLN); err != nil {
+			return nil, fmt.Errorf("failed to scan supplier: %w", err)
+		}
+		suppliers = append(suppliers, &s)
+	}
+
+	return suppliers, rows.Err()
+}
+
+// BulkUpsert inserts or updates multiple suppliers
+func (r *SupplierRepo) BulkUpsert(ctx context.Context, suppliers []*database.Supplier) (int, error) {
+	if len(suppliers) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.client.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, s := range suppliers {
+		batch.Queue(upsertSupplierQuery, s.ID, s.Name, s.GLN)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+
+	count := 0
+	for range suppliers {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return count, fmt.Errorf("failed to upsert supplier: %w", err)
+		}
+		count++
+	}
+
+	if err := br.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return count, nil
+}
+
+// productSupplierColumns are the link columns with the supplier's name and
+// GLN joined in
+const productSupplierColumns = `
+	ps.product_id, ps.supplier_id, COALESCE(ps.article_number, ''), ps.is_primary,
+	COALESCE(s.name, ''), COALESCE(s.gln, '')
+`
+
+// GetByProduct retrieves the supplier links for a product, primary supplier first
+func (r *SupplierRepo) GetByProduct(ctx context.Context, productID uuid.UUID) ([]*database.ProductSupplier, error) {
+	query := `
+		SELECT ` + productSupplierColumns + `
+		FROM product_suppliers ps
+		LEFT JOIN suppliers s ON s.id = ps.supplier_id
+		WHERE ps.product_id = $1
+		ORDER BY ps.is_primary DESC, ps.supplier_id
+	`
+
+	rows, err := r.client.pool.Query(ctx, query, productID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	return scanProductSuppliers(rows)
+}
+
+// GetByProducts retrieves the supplier links of several products in one
+// query, keyed by product ID, primary supplier first
+func (r *SupplierRepo) GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]*database.ProductSupplier, error) {
+	byProduct := make(map[uuid.UUID][]*database.ProductSupplier)
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+
+	query := `
+		SELECT ` + productSupplierColumns + `
+		FROM product_suppliers ps
+		LEFT JOIN suppliers s ON s.id = ps.supplier_id
+		WHERE ps.product_id = ANY($1)
+		ORDER BY ps.product_id, ps.is_primary DESC, ps.supplier_id
+	`
+
+	rows, err := r.client.pool.Query(ctx, query, uuidStrings(productIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product suppliers: %w", err)
+	}
+	defer rows.Close()
+
+	links, err := scanProductSuppliers(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		byProduct[link.ProductID] = append(byProduct[link.ProductID], link)
+	}
+	return byProduct, nil
+}
+
+func scanProductSuppliers(rows pgx.Rows) ([]*database.ProductSupplier, error) {
+	var links []*database.ProductSupplier
+	for rows.Next() {
+		var link database.ProductSupplier
+		var productIDStr string
+
+		err := rows.Scan(&productIDStr, &link.SupplierID, &link.ArticleNumber, &link.IsPrimary,
+			&link.SupplierName, &link.SupplierGLN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product supplier: %w", err)
+		}
+
+		link.ProductID, _ = uuid.Parse(productIDStr)
+		links = append(links, &link)
+	}
+
+	return links, rows.Err()
+}
+
+// upsertProductSupplierQuery links a product to a supplier
+const upsertProductSupplierQuery = `
+	INSERT INTO product_suppliers (product_id, supplier_id, article_number, is_primary)
+	VALUES ($1, $2, NULLIF($3, ''), $4)
+	ON CONFLICT (product_id, supplier_id) DO UPDATE SET
+		article_number = EXCLUDED.article_number,
+		is_primary = EXCLUDED.is_primary
+`
+
+// saveProductSuppliers upserts the product's suppliers and replaces its
+// supplier links. An empty list leaves the stored links alone, since callers
+// such as the Shopify webhook only carry product fields.
+func saveProductSuppliers(ctx context.Context, tx pgx.Tx, productID string, suppliers []models.Supplier) error {
+	if len(suppliers) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(suppliers))
+	for _, s := range suppliers {
+		if s.ID == "" {
+			continue
+		}
+		if _, err := tx.Exec(ctx, upsertSupplierQuery, s.ID, s.Name, s.GLN); err != nil {
+			return fmt.Errorf("failed to upsert supplier %s: %w", s.ID, err)
+		}
+		if _, err := tx.Exec(ctx, upsertProductSupplierQuery, productID, s.ID, s.ArticleNo, s.IsPrimary); err != nil {
+			return fmt.Errorf("failed to link supplier %s: %w", s.ID, err)
+		}
+		ids = append(ids, s.ID)
+	}
+
+	_, err := tx.Exec(ctx, "DELETE FROM product_suppliers WHERE product_id = $1 AND NOT (supplier_id = ANY($2))", productID, ids)
+	if err != nil {
+		return fmt.Errorf("failed to remove old supplier links: %w", err)
+	}
+	return nil
+}
+
+// BulkUpsertProductSuppliers inserts or updates product-supplier links.
+// The suppliers must already exist.
+func (r *SupplierRepo) BulkUpsertProductSuppliers(ctx context.Context, links []*database.ProductSupplier) (int, error) {
+	if len(links) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.client.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		batch.Queue(upsertProductSupplierQuery,
+			link.ProductID.String(),
+			link.SupplierID,
+			link.ArticleNumber,
+			link.IsPrimary,
+		)
+	}
+
+	br := tx.SendBatch(ctx, batch)
+
+	count := 0
+	for range links {
+		if _, err := br.Exec(); err != nil {
+			br.Close()
+			return count, fmt.Errorf("failed to upsert product supplier: %w", err)
+		}
+		count++
+	}
+
+	if err := br.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close batch: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return count, nil
+}
@@ -16,13 +16,19 @@ type ProductRepository interface {
 	GetBySKU(ctx context.Context, sku string) (*models.EnhancedProduct, error)
 	GetByBarcode(ctx context.Context, barcode string) (*models.EnhancedProduct, error)
 	Update(ctx context.Context, product *models.EnhancedProduct) error
+	UpdateTags(ctx context.Context, tags map[string][]string) (int, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 
 	// Bulk operations
 	BulkUpsert(ctx context.Context, products []*models.EnhancedProduct) (int, error)
+	BulkUpsertWithOptions(ctx context.Context, products []*models.EnhancedProduct, opts BulkOptions) (int, error)
+	SaveEnhanced(ctx context.Context, product *models.EnhancedProduct) error
 	GetAll(ctx context.Context, opts QueryOptions) ([]*models.EnhancedProduct, error)
+	GetAllPaged(ctx context.Context, opts QueryOptions) (*ProductPage, error)
 	GetByVendor(ctx context.Context, vendor string) ([]*models.EnhancedProduct, error)
 	GetByStatus(ctx context.Context, status models.ProductStatus) ([]*models.EnhancedProduct, error)
+	Search(ctx context.Context, query string, opts QueryOptions) ([]*models.EnhancedProduct, error)
 
 	// Counts and stats
 	Count(ctx context.Context) (int64, error)
@@ -59,7 +65,11 @@ type PriceObservationRepository interface {
 	GetLatestByProduct(ctx context.Context, productID uuid.UUID) ([]*PriceObservation, error)
 	GetByProductAndCompetitor(ctx context.Context, productID uuid.UUID, competitorID int, since time.Time) ([]*PriceObservation, error)
 	GetPriceHistory(ctx context.Context, productID uuid.UUID, days int) ([]*PriceObservation, error)
+	GetMarketStats(ctx context.Context, productID uuid.UUID, days int) (*MarketStats, error)
+	GetAllMarketStats(ctx context.Context, days int) (map[uuid.UUID]*MarketStats, error)
+	GetDailyTrends(ctx context.Context, productID uuid.UUID, days int) ([]PriceTrend, error)
 	Count(ctx context.Context) (int64, error)
+	CountOlderThan(ctx context.Context, before time.Time) (int64, error)
 	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
@@ -67,6 +77,8 @@ type PriceObservationRepository interface {
 type ImageRepository interface {
 	Create(ctx context.Context, image *ProductImage) error
 	GetByProduct(ctx context.Context, productID uuid.UUID) ([]*ProductImage, error)
+	GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]*ProductImage, error)
+	CountByProduct(ctx context.Context) (map[uuid.UUID]int, error)
 	Update(ctx context.Context, image *ProductImage) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	BulkUpsert(ctx context.Context, images []*ProductImage) (int, error)
@@ -76,6 +88,7 @@ type ImageRepository interface {
 type PropertyRepository interface {
 	Create(ctx context.Context, property *ProductProperty) error
 	GetByProduct(ctx context.Context, productID uuid.UUID) ([]*ProductProperty, error)
+	GetByProducts(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID][]*ProductProperty, error)
 	BulkUpsert(ctx context.Context, properties []*ProductProperty) (int, error)
 	DeleteByProduct(ctx context.Context, productID uuid.UUID) error
 }
@@ -86,6 +99,8 @@ type SupplierRepository interface {
 	GetByID(ctx context.Context, id string) (*Supplier, error)
 	GetAll(ctx context.Context) ([]*Supplier, error)
 	BulkUpsert(ctx context.Context, suppliers []*Supplier) (int, error)
+	GetByProduct(ctx context.Context, productID uuid.UUID) ([]*ProductSupplier, error)
+	BulkUpsertProductSuppliers(ctx context.Context, links []*ProductSupplier) (int, error)
 }
 
 // HistoryRepository defines the interface for operation history
@@ -103,6 +118,38 @@ type QueryOptions struct {
 	OrderDir string // "ASC" or "DESC"
 	Vendor   string
 	Status   models.ProductStatus
+
+	// IncludeDeleted also returns soft-deleted (archived) products
+	IncludeDeleted bool
+
+	// Enhancement worklist filters
+	MissingImages      bool // Products without any image
+	MissingDescription bool // Products with an empty description
+
+	// MarginBelow, if set, selects products with a known profit margin below
+	// this percentage
+	MarginBelow *float64
+}
+
+// DefaultBulkBatchSize is how many rows a bulk write commits at a time
+const DefaultBulkBatchSize = 1000
+
+// BulkOptions controls how a bulk write is split into transactions
+type BulkOptions struct {
+	BatchSize int // Rows per transaction (default: DefaultBulkBatchSize)
+
+	// Progress, if set, is called after each committed batch with the rows
+	// written so far and the total
+	Progress func(done, total int)
+}
+
+// ProductPage is one page of a product listing
+type ProductPage struct {
+	Products   []*models.EnhancedProduct `json:"products"`
+	TotalCount int64                     `json:"total_count"` // Products matching the filters across all pages
+	Limit      int                       `json:"limit"`
+	Offset     int                       `json:"offset"`
+	HasMore    bool                      `json:"has_more"`
 }
 
 // Competitor represents a competitor in the database
@@ -144,6 +191,34 @@ type PriceObservation struct {
 	StockQuantity *int      `json:"stock_quantity,omitempty"`
 	ObservedAt    time.Time `json:"observed_at"`
 	Source        string    `json:"source"` // reprice_csv, scraper, api
+
+	// Price and Currency are in the base currency; a price quoted in another
+	// currency keeps its original amount here
+	OriginalPrice    *float64 `json:"original_price,omitempty"`
+	OriginalCurrency string   `json:"original_currency,omitempty"`
+}
+
+// MarketStats summarizes competitor prices for a product over a period.
+// Min, max and average are taken over each competitor's latest price.
+type MarketStats struct {
+	ProductID       uuid.UUID `json:"product_id"`
+	MinPrice        float64   `json:"min_price"`
+	MaxPrice        float64   `json:"max_price"`
+	AvgPrice        float64   `json:"avg_price"`
+	Observations    int       `json:"observations"`
+	CompetitorCount int       `json:"competitor_count"`
+	MinCompetitorID int       `json:"min_competitor_id"` // Competitor with the lowest latest price
+}
+
+// PriceTrend summarizes one competitor's prices for a product on one day
+type PriceTrend struct {
+	ProductSKU     string    `json:"product_sku"`
+	CompetitorName string    `json:"competitor_name"`
+	Date           time.Time `json:"date"`
+	MinPrice       float64   `json:"min_price"`
+	MaxPrice       float64   `json:"max_price"`
+	AvgPrice       float64   `json:"avg_price"`
+	Count          int64     `json:"count"`
 }
 
 // ProductImage represents a product image in the database
@@ -186,6 +261,8 @@ type ProductSupplier struct {
 	SupplierID    string    `json:"supplier_id"`
 	ArticleNumber string    `json:"article_number,omitempty"`
 	IsPrimary     bool      `json:"is_primary"`
+	SupplierName  string    `json:"supplier_name,omitempty"` // Not stored; joined from suppliers when read
+	SupplierGLN   string    `json:"supplier_gln,omitempty"`  // Not stored; joined from suppliers when read
 }
 
 // OperationHistory represents an operation in the history log
@@ -203,6 +280,7 @@ type OperationHistory struct {
 type EnhancementLog struct {
 	ID          int64     `json:"id,omitempty"`
 	ProductID   uuid.UUID `json:"product_id"`
+	ProductSKU  string    `json:"product_sku,omitempty"` // Not stored; joined from products when read
 	Source      string    `json:"source"`
 	Action      string    `json:"action"`
 	FieldsAdded []string  `json:"fields_added,omitempty"`
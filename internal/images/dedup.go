@@ -0,0 +1,125 @@
+package images
+
+import (
+	"image"
+	"math/bits"
+	"path/filepath"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// DefaultDedupThreshold is the Hamming distance below which two image hashes
+// are considered the same picture
+const DefaultDedupThreshold = 6
+
+// hashedImage holds the perceptual hash of an image on disk
+type hashedImage struct {
+	path   string
+	hash   uint64
+	pixels int
+}
+
+// Dedup collapses near-duplicate images using DefaultDedupThreshold.
+// See DedupWithThreshold.
+func Dedup(paths []string) ([]string, map[string]string) {
+	return DedupWithThreshold(paths, DefaultDedupThreshold)
+}
+
+// DedupWithThreshold computes a difference hash (dHash) for each image and
+// collapses images of the same product whose hashes differ in fewer than
+// threshold bits. Of each duplicate group the highest-resolution image is kept.
+// Returns the kept paths in input order and a map of removed path to kept path.
+// Images that cannot be decoded are always kept.
+func DedupWithThreshold(paths []string, threshold int) ([]string, map[string]string) {
+	removed := make(map[string]string)
+	if threshold <= 0 {
+		return paths, removed
+	}
+
+	// Group kept images by product, so variants of different SKUs never collapse
+	groups := make(map[string][]*hashedImage)
+	keep := make(map[string]bool)
+
+	for _, path := range paths {
+		img, err := imaging.Open(path)
+		if err != nil {
+			keep[path] = true
+			continue
+		}
+
+		current := &hashedImage{
+			path:   path,
+			hash:   dHash(img),
+			pixels: img.Bounds().Dx() * img.Bounds().Dy(),
+		}
+
+		sku := SKUFromFilename(path)
+		var match *hashedImage
+		for _, kept := range groups[sku] {
+			if bits.OnesCount64(kept.hash^current.hash) < threshold {
+				match = kept
+				break
+			}
+		}
+
+		if match == nil {
+			groups[sku] = append(groups[sku], current)
+			keep[path] = true
+			continue
+		}
+
+		if current.pixels > match.pixels {
+			// The new image is larger: it replaces the kept one
+			delete(keep, match.path)
+			keep[current.path] = true
+			for from, to := range removed {
+				if to == match.path {
+					removed[from] = current.path
+				}
+			}
+			removed[match.path] = current.path
+			*match = *current
+		} else {
+			removed[path] = match.path
+		}
+	}
+
+	var kept []string
+	for _, path := range paths {
+		if keep[path] {
+			kept = append(kept, path)
+		}
+	}
+
+	return kept, removed
+}
+
+// dHash computes a 64-bit difference hash: the image is shrunk to 9x8
+// grayscale and each bit records whether a pixel is brighter than its
+// right-hand neighbour
+func dHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// SKUFromFilename returns the product SKU from a downloaded image name
+// (SKU.jpg or SKU_new_N.jpg)
+func SKUFromFilename(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	sku, _, _ := strings.Cut(name, "_new_")
+	return sku
+}
@@ -1,24 +1,68 @@
 package images
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"image"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
+// Default delay between requests to the same host
+const defaultHostRateLimit = 100 * time.Millisecond
+
+// minImageBytes is the smallest response accepted as an image; anything
+// shorter is an error page or a placeholder
+const minImageBytes = 256
+
+// ErrInvalidImage is returned when a download is not an image, e.g. an HTML
+// error page served with status 200
+var ErrInvalidImage = errors.New("not an image")
+
 // ImageURL represents an image URL with metadata
 type ImageURL struct {
 	URL string
 	SKU string
 }
 
+// ImageDownload describes an image to download in a batch
+type ImageDownload struct {
+	URL      string
+	Filename string // File name without extension (e.g. SKU or SKU_new_1)
+	Source   string // Connector the image URL came from (e.g. tiger_nl), recorded in state
+}
+
+// DownloadResult is the outcome of a single download in a batch
+type DownloadResult struct {
+	ImageDownload
+	Path      string
+	Size      string
+	Attempts  int
+	Unchanged bool // The server answered 304 Not Modified; the existing file was kept
+	Error     error
+}
+
 // Fetcher handles downloading images
 type Fetcher struct {
-	client    *http.Client
-	outputDir string
+	client     *http.Client
+	outputDir  string
+	rateLimit  time.Duration
+	nextSlot   map[string]time.Time
+	rateMu     sync.Mutex
+	onProgress func(DownloadResult)
+
+	validators     map[string]validator
+	validatorsMu   sync.Mutex
+	validatorsFile string
 }
 
 // NewFetcher creates a new image fetcher
@@ -26,9 +70,81 @@ func NewFetcher() *Fetcher {
 	return &Fetcher{
 		client:    &http.Client{},
 		outputDir: "output/originals",
+		rateLimit: defaultHostRateLimit,
+		nextSlot:  make(map[string]time.Time),
 	}
 }
 
+// SetRateLimit sets the minimum delay between requests to the same host
+func (f *Fetcher) SetRateLimit(d time.Duration) {
+	f.rateMu.Lock()
+	defer f.rateMu.Unlock()
+	f.rateLimit = d
+}
+
+// SetProgressFunc registers a callback invoked after each batch download
+// completes. It is called from worker goroutines.
+func (f *Fetcher) SetProgressFunc(fn func(DownloadResult)) {
+	f.onProgress = fn
+}
+
+// waitForHost blocks until a request to the URL's host is allowed by the
+// per-host rate limiter
+func (f *Fetcher) waitForHost(rawURL string) {
+	host := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		host = u.Host
+	}
+
+	// Reserve the next free slot for this host
+	f.rateMu.Lock()
+	now := time.Now()
+	slot := f.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	f.nextSlot[host] = slot.Add(f.rateLimit)
+	f.rateMu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
+
+// DownloadBatch downloads images in parallel using at most concurrency
+// workers. Results are returned in the same order as the input. Failed
+// downloads are retried once before being reported as failed.
+func (f *Fetcher) DownloadBatch(downloads []ImageDownload, concurrency int) []DownloadResult {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]DownloadResult, len(downloads))
+
+	var g errgroup.Group
+	g.SetLimit(concurrency)
+
+	for i, d := range downloads {
+		g.Go(func() error {
+			result := DownloadResult{ImageDownload: d}
+			for result.Attempts < 2 {
+				result.Attempts++
+				result.Path, result.Size, result.Unchanged, result.Error = f.download(d.URL, d.Filename)
+				if result.Error == nil || errors.Is(result.Error, ErrInvalidImage) {
+					break // Invalid content will not change on retry
+				}
+			}
+
+			results[i] = result
+			if f.onProgress != nil {
+				f.onProgress(result)
+			}
+			return nil
+		})
+	}
+
+	g.Wait()
+	return results
+}
+
 // GetDemoImageURLs returns real Tiger.nl image URLs for the demo
 func (f *Fetcher) GetDemoImageURLs() []ImageURL {
 	// Real Tiger.nl PIM image URLs (1200px for high quality)
@@ -62,11 +178,20 @@ func (f *Fetcher) GetDemoImageURLs() []ImageURL {
 	}
 }
 
-// Download fetches an image and saves it locally
+// Download fetches an image and saves it locally. If the image was fetched
+// before and the server reports it unchanged, the existing file is kept.
 func (f *Fetcher) Download(url, sku string) (string, string, error) {
+	path, size, _, err := f.download(url, sku)
+	return path, size, err
+}
+
+// download fetches an image with a conditional request when validators from a
+// previous download are known. unchanged is true when the server answered
+// 304 Not Modified and the file on disk was left as is.
+func (f *Fetcher) download(url, sku string) (path, size string, unchanged bool, err error) {
 	// Create output directory
 	if err := os.MkdirAll(f.outputDir, 0755); err != nil {
-		return "", "", err
+		return "", "", false, err
 	}
 
 	// Determine file extension
@@ -80,34 +205,120 @@ func (f *Fetcher) Download(url, sku string) (string, string, error) {
 	filename := fmt.Sprintf("%s%s", sku, ext)
 	destPath := filepath.Join(f.outputDir, filename)
 
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", "", false, err
+	}
+	f.setConditionalHeaders(req, destPath)
+
 	// Download image
-	resp, err := f.client.Get(url)
+	f.waitForHost(url)
+	resp, err := f.client.Do(req)
 	if err != nil {
-		return "", "", err
+		return "", "", false, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified {
+		info, err := os.Stat(destPath)
+		if err != nil {
+			return "", "", false, err
+		}
+		return destPath, formatSize(info.Size()), true, nil
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return "", "", fmt.Errorf("failed to download: HTTP %d", resp.StatusCode)
+		return "", "", false, fmt.Errorf("failed to download: HTTP %d", resp.StatusCode)
+	}
+
+	// Check the content before touching the file on disk
+	body := bufio.NewReaderSize(resp.Body, 512)
+	if err := checkImageContent(body, resp.Header.Get("Content-Type")); err != nil {
+		return "", "", false, err
+	}
+
+	n, err := writeImageFile(destPath, body)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	// Only a complete file may be matched by the validators, or a later 304
+	// would keep a truncated one
+	f.storeValidators(url, destPath, resp.Header)
+
+	return destPath, formatSize(n), false, nil
+}
+
+// writeImageFile copies r to a temporary file next to path and renames it into
+// place once the copy is complete, so a failed download never truncates the
+// existing image. Returns the number of bytes written.
+func writeImageFile(path string, r io.Reader) (int64, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
 	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
 
-	// Create destination file
-	out, err := os.Create(destPath)
+	n, err := io.Copy(tmp, r)
 	if err != nil {
-		return "", "", err
+		tmp.Close()
+		return 0, err
 	}
-	defer out.Close()
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
 
-	// Copy content
-	n, err := io.Copy(out, resp.Body)
+// checkImageContent sniffs the first bytes of a response with
+// http.DetectContentType and returns ErrInvalidImage unless they are an image
+// of at least minImageBytes. serverType is the Content-Type the server sent,
+// used in the error only.
+func checkImageContent(body *bufio.Reader, serverType string) error {
+	head, err := body.Peek(512)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if err == io.EOF && len(head) < minImageBytes {
+		return fmt.Errorf("%w: response is only %d bytes", ErrInvalidImage, len(head))
+	}
+
+	detected := http.DetectContentType(head)
+	if !strings.HasPrefix(detected, "image/") {
+		if serverType == "" {
+			serverType = "none"
+		}
+		return fmt.Errorf("%w: content is %s (server sent %s)", ErrInvalidImage, detected, serverType)
+	}
+	return nil
+}
+
+// ReadImageInfo returns the pixel dimensions and file size of an image on disk
+// without decoding the full image
+func ReadImageInfo(path string) (width, height int, size int64, err error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return "", "", err
+		return 0, 0, 0, err
 	}
+	defer f.Close()
 
-	// Format size
-	size := formatSize(n)
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to read image header: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, 0, err
+	}
 
-	return destPath, size, nil
+	return cfg.Width, cfg.Height, info.Size(), nil
 }
 
 func formatSize(bytes int64) string {
@@ -138,6 +349,7 @@ func (f *Fetcher) ValidateURL(url string) (bool, error) {
 // DownloadWithValidation downloads an image only if it passes validation
 func (f *Fetcher) DownloadWithValidation(url, sku string) (string, string, error) {
 	// First validate the URL
+	f.waitForHost(url)
 	valid, err := f.ValidateURL(url)
 	if err != nil {
 		return "", "", fmt.Errorf("validation failed: %w", err)
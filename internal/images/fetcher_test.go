@@ -0,0 +1,90 @@
+package images
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+// encodePNG returns a PNG of w x h with varying pixels, so it doesn't
+// compress below minImageBytes
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := imaging.New(w, h, productRed)
+	for i := range img.Pix {
+		img.Pix[i] = byte(i * 7 % 251)
+	}
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, imaging.PNG); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDownloadKeepsFileWhenCopyFails(t *testing.T) {
+	original := encodePNG(t, 64, 64)
+	replacement := encodePNG(t, 128, 128)
+
+	// The first request succeeds; later ones promise the replacement but
+	// close the connection halfway through the body
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "image/png")
+		if requests == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Write(original)
+			return
+		}
+		w.Header().Set("ETag", `"v2"`)
+		w.Header().Set("Content-Length", strconv.Itoa(len(replacement)))
+		w.Write(replacement[:len(replacement)/2])
+		w.(http.Flusher).Flush()
+		if hj, ok := w.(http.Hijacker); ok {
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	f := NewFetcher()
+	f.outputDir = t.TempDir()
+	f.SetRateLimit(0)
+	url := server.URL + "/CO-T309012.png"
+
+	path, _, _, err := f.download(url, "CO-T309012")
+	if err != nil {
+		t.Fatalf("first download: %v", err)
+	}
+
+	if _, _, _, err := f.download(url, "CO-T309012"); err == nil {
+		t.Fatal("interrupted download succeeded")
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Errorf("file has %d bytes after the failed download, want the original %d", len(got), len(original))
+	}
+	if v := f.validators[url]; v.ETag != `"v1"` {
+		t.Errorf("validators = %+v, want the original ETag", v)
+	}
+
+	entries, err := os.ReadDir(f.outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() != filepath.Base(path) && e.Name() != validatorsFileName {
+			t.Errorf("left behind %s", e.Name())
+		}
+	}
+}
@@ -3,24 +3,56 @@ package images
 import (
 	"fmt"
 	"image"
+	"image/color"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/disintegration/imaging"
+	"github.com/gen2brain/avif"
+	"github.com/gen2brain/webp"
+)
+
+// Output formats supported by ResizeSquareFormat, with their file extension
+var outputFormats = map[string]string{
+	"jpg":  ".jpg",
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"webp": ".webp",
+	"avif": ".avif",
+}
+
+// IsOutputFormat reports whether format is a supported resize output format
+func IsOutputFormat(format string) bool {
+	_, ok := outputFormats[strings.ToLower(format)]
+	return ok
+}
+
+// FitMode controls how non-square images are made square
+type FitMode string
+
+const (
+	FitCrop FitMode = "crop" // Center-crop to a square, then resize
+	FitPad  FitMode = "pad"  // Scale to fit inside the square and pad with the background color
 )
 
 // Resizer handles image resizing operations
 type Resizer struct {
-	inputDir  string
-	outputDir string
+	inputDir   string
+	outputDir  string
+	quality    int // Encoder quality 1-100 for JPEG/WebP/AVIF (0 = encoder default)
+	fitMode    FitMode
+	background color.Color // Padding color in pad mode
 }
 
 // NewResizer creates a new image resizer
 func NewResizer() *Resizer {
 	return &Resizer{
-		inputDir:  "output/originals",
-		outputDir: "output/resized",
+		inputDir:   "output/originals",
+		outputDir:  "output/resized",
+		fitMode:    FitCrop,
+		background: color.White,
 	}
 }
 
@@ -46,14 +78,98 @@ func (r *Resizer) FindOriginals() ([]string, error) {
 	return images, err
 }
 
-// ResizeSquare resizes an image to a square with center-crop
+// SetQuality sets the encoder quality (1-100) for JPEG, WebP and AVIF output.
+// 0 uses each encoder's default.
+func (r *Resizer) SetQuality(quality int) {
+	r.quality = quality
+}
+
+// SetFitMode sets how non-square images are made square (crop or pad)
+func (r *Resizer) SetFitMode(mode FitMode) error {
+	switch mode {
+	case FitCrop, FitPad:
+		r.fitMode = mode
+		return nil
+	default:
+		return fmt.Errorf("unsupported fit mode: %s (use crop or pad)", mode)
+	}
+}
+
+// SetBackground sets the padding color used in pad mode
+func (r *Resizer) SetBackground(c color.Color) {
+	r.background = c
+}
+
+// ParseHexColor parses a #RRGGBB or #RRGGBBAA color (the # is optional)
+func ParseHexColor(hex string) (color.NRGBA, error) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q (use #RRGGBB or #RRGGBBAA)", hex)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q: %w", hex, err)
+	}
+
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
+
+// ResizeSquare resizes an image to a square using the configured fit mode,
+// keeping the source file format
 func (r *Resizer) ResizeSquare(srcPath string, size int) (string, error) {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(srcPath)), ".")
+	if !IsOutputFormat(format) {
+		format = "jpg"
+	}
+	return r.ResizeSquareFormat(srcPath, size, format)
+}
+
+// ResizeSquareFormat resizes an image to a square using the configured fit
+// mode and saves it in the given format (jpg, png, webp, avif). Transparency is kept for
+// PNG, WebP and AVIF and flattened onto white for JPEG.
+func (r *Resizer) ResizeSquareFormat(srcPath string, size int, format string) (string, error) {
+	format = strings.ToLower(format)
+	ext, ok := outputFormats[format]
+	if !ok {
+		return "", fmt.Errorf("unsupported output format: %s", format)
+	}
+
 	// Open source image
 	src, err := imaging.Open(srcPath)
 	if err != nil {
 		return "", err
 	}
 
+	var resized image.Image
+	if r.fitMode == FitPad {
+		resized = r.padSquare(src, size)
+	} else {
+		resized = cropSquare(src, size)
+	}
+
+	// Create output directory
+	sizeDir := filepath.Join(r.outputDir, fmt.Sprintf("%d", size))
+	if err := os.MkdirAll(sizeDir, 0755); err != nil {
+		return "", err
+	}
+
+	// Save resized image with the format's extension
+	filename := strings.TrimSuffix(filepath.Base(srcPath), filepath.Ext(srcPath)) + ext
+	destPath := filepath.Join(sizeDir, filename)
+
+	if err := r.save(resized, destPath, format); err != nil {
+		return "", err
+	}
+
+	return destPath, nil
+}
+
+// cropSquare center-crops an image to a square and resizes it to size x size
+func cropSquare(src image.Image, size int) image.Image {
 	// Get dimensions
 	bounds := src.Bounds()
 	width := bounds.Dx()
@@ -75,21 +191,61 @@ func (r *Resizer) ResizeSquare(srcPath string, size int) (string, error) {
 	}
 
 	// Resize to target size
-	resized := imaging.Resize(cropped, size, size, imaging.Lanczos)
+	return imaging.Resize(cropped, size, size, imaging.Lanczos)
+}
 
-	// Create output directory
-	sizeDir := filepath.Join(r.outputDir, fmt.Sprintf("%d", size))
-	if err := os.MkdirAll(sizeDir, 0755); err != nil {
-		return "", err
+// padSquare scales an image to fit inside size x size, keeping its aspect
+// ratio, and centers it on a square of the background color
+func (r *Resizer) padSquare(src image.Image, size int) image.Image {
+	// Scale the longest side to size (also upscales small images, like crop mode)
+	var fitted *image.NRGBA
+	if src.Bounds().Dx() >= src.Bounds().Dy() {
+		fitted = imaging.Resize(src, size, 0, imaging.Lanczos)
+	} else {
+		fitted = imaging.Resize(src, 0, size, imaging.Lanczos)
 	}
 
-	// Save resized image
-	filename := filepath.Base(srcPath)
-	destPath := filepath.Join(sizeDir, filename)
+	bg := imaging.New(size, size, r.background)
+	return imaging.OverlayCenter(bg, fitted, 1.0)
+}
 
-	if err := imaging.Save(resized, destPath); err != nil {
-		return "", err
+// save encodes an image to path in the given format
+func (r *Resizer) save(img image.Image, path, format string) error {
+	switch format {
+	case "jpg", "jpeg":
+		// JPEG has no alpha channel: flatten transparent areas onto white
+		bg := imaging.New(img.Bounds().Dx(), img.Bounds().Dy(), color.White)
+		flattened := imaging.Overlay(bg, img, image.Pt(0, 0), 1.0)
+		if r.quality > 0 {
+			return imaging.Save(flattened, path, imaging.JPEGQuality(r.quality))
+		}
+		return imaging.Save(flattened, path)
+	case "png":
+		return imaging.Save(img, path)
 	}
 
-	return destPath, nil
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	switch format {
+	case "webp":
+		err = webp.Encode(out, img, webp.Options{Quality: r.quality, Method: webp.DefaultMethod})
+	case "avif":
+		err = avif.Encode(out, img, avif.Options{
+			Quality:           r.quality,
+			QualityAlpha:      r.quality,
+			Speed:             avif.DefaultSpeed,
+			ChromaSubsampling: image.YCbCrSubsampleRatio420,
+		})
+	default:
+		err = fmt.Errorf("unsupported output format: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return out.Close()
 }
@@ -0,0 +1,156 @@
+package images
+
+import (
+	"image"
+	"image/color"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+)
+
+var productRed = color.NRGBA{R: 200, G: 20, B: 20, A: 255}
+
+// writeSource saves a solid product-colored PNG of w x h and returns its path
+func writeSource(t *testing.T, w, h int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "CO-T309012.png")
+	if err := imaging.Save(imaging.New(w, h, productRed), path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestResizer(t *testing.T, mode FitMode) *Resizer {
+	t.Helper()
+	r := NewResizer()
+	r.outputDir = t.TempDir()
+	if err := r.SetFitMode(mode); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// contentBounds returns the bounding box of the pixels that are not the
+// background color
+func contentBounds(img image.Image, bg color.Color) image.Rectangle {
+	br, bgc, bb, _ := bg.RGBA()
+	var box image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			if r == br && g == bgc && b == bb {
+				continue
+			}
+			box = box.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return box
+}
+
+func TestResizeSquareIsNxN(t *testing.T) {
+	sources := []struct {
+		name string
+		w, h int
+	}{
+		{"tall", 100, 400},
+		{"wide", 300, 120},
+		{"square", 50, 50},
+	}
+	for _, mode := range []FitMode{FitCrop, FitPad} {
+		for _, src := range sources {
+			t.Run(string(mode)+"/"+src.name, func(t *testing.T) {
+				r := newTestResizer(t, mode)
+				out, err := r.ResizeSquareFormat(writeSource(t, src.w, src.h), 200, "png")
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				img, err := imaging.Open(out)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 200 {
+					t.Errorf("output is %dx%d, want 200x200", b.Dx(), b.Dy())
+				}
+				if filepath.Dir(out) != filepath.Join(r.outputDir, "200") {
+					t.Errorf("output written to %s, want the 200 size directory", out)
+				}
+			})
+		}
+	}
+}
+
+func TestResizeSquarePadKeepsAspectRatio(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h         int
+		wantW, wantH int
+	}{
+		{"tall", 100, 400, 50, 200},
+		{"wide", 400, 100, 200, 50},
+	}
+	bg := color.NRGBA{R: 240, G: 240, B: 255, A: 255}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestResizer(t, FitPad)
+			r.SetBackground(bg)
+			out, err := r.ResizeSquareFormat(writeSource(t, tt.w, tt.h), 200, "png")
+			if err != nil {
+				t.Fatal(err)
+			}
+			img, err := imaging.Open(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The whole product is kept, centered, with the background around it
+			box := contentBounds(img, bg)
+			if box.Dx() != tt.wantW || box.Dy() != tt.wantH {
+				t.Errorf("product is %dx%d, want %dx%d", box.Dx(), box.Dy(), tt.wantW, tt.wantH)
+			}
+			if wantMin := image.Pt((200-tt.wantW)/2, (200-tt.wantH)/2); box.Min != wantMin {
+				t.Errorf("product starts at %v, want centered at %v", box.Min, wantMin)
+			}
+			if got := color.NRGBAModel.Convert(img.At(0, 0)); got != bg {
+				t.Errorf("corner is %v, want the background %v", got, bg)
+			}
+		})
+	}
+}
+
+func TestResizeSquareCropFillsSquare(t *testing.T) {
+	r := newTestResizer(t, FitCrop)
+	out, err := r.ResizeSquareFormat(writeSource(t, 100, 400), 200, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := imaging.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if box := contentBounds(img, color.White); box != image.Rect(0, 0, 200, 200) {
+		t.Errorf("product covers %v, want the whole square", box)
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    color.NRGBA
+		wantErr bool
+	}{
+		{"#ffffff", color.NRGBA{255, 255, 255, 255}, false},
+		{"F0F0FF", color.NRGBA{240, 240, 255, 255}, false},
+		{"#00000080", color.NRGBA{0, 0, 0, 128}, false},
+		{"#fff", color.NRGBA{}, true},
+		{"#gggggg", color.NRGBA{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHexColor(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseHexColor(%q) = %v, %v; want %v (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
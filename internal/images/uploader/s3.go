@@ -0,0 +1,207 @@
+package uploader
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheControl lets CDNs and browsers cache uploaded images for a
+// year; a re-uploaded image keeps its key, so purge the CDN when replacing one
+const DefaultCacheControl = "public, max-age=31536000"
+
+// S3Config configures an S3-compatible bucket (AWS S3, Cloudflare R2, MinIO,
+// DigitalOcean Spaces, ...). Objects are addressed path-style:
+// {endpoint}/{bucket}/{key}.
+type S3Config struct {
+	Endpoint     string // e.g. https://s3.eu-north-1.amazonaws.com
+	Region       string // Signing region (default: us-east-1; R2 uses auto)
+	Bucket       string
+	Prefix       string // Key prefix, e.g. products/
+	PublicURL    string // Base URL objects are served from, e.g. a CDN (default: {endpoint}/{bucket})
+	AccessKey    string
+	SecretKey    string
+	ACL          string // Canned ACL such as public-read (default: none, for buckets public by policy)
+	CacheControl string // Cache-Control of uploaded objects (default: DefaultCacheControl)
+	BaseDir      string // Local directory keys are relative to (default: output/resized)
+	Timeout      time.Duration
+}
+
+// S3Uploader uploads files to an S3-compatible bucket with SigV4-signed PUTs
+type S3Uploader struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Uploader creates an uploader for an S3-compatible bucket
+func NewS3Uploader(cfg S3Config) (*S3Uploader, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("image upload needs an endpoint and a bucket")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("image upload needs an access key and a secret key")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return nil, fmt.Errorf("invalid upload endpoint %q (use e.g. https://s3.eu-north-1.amazonaws.com)", cfg.Endpoint)
+	}
+
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.CacheControl == "" {
+		cfg.CacheControl = DefaultCacheControl
+	}
+	if cfg.BaseDir == "" {
+		cfg.BaseDir = "output/resized"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 60 * time.Second
+	}
+
+	return &S3Uploader{
+		config:   cfg,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		now:      time.Now,
+	}, nil
+}
+
+// Key returns the object key for a local file: its path relative to the
+// base directory (so output/resized/800/SKU.jpg becomes 800/SKU.jpg) under
+// the prefix. Files outside the base directory use their file name.
+func (u *S3Uploader) Key(localPath string) string {
+	rel, err := filepath.Rel(u.config.BaseDir, localPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		rel = filepath.Base(localPath)
+	}
+	return u.config.Prefix + filepath.ToSlash(rel)
+}
+
+// PublicURL returns the URL an object key is served from
+func (u *S3Uploader) PublicURL(key string) string {
+	base := strings.TrimSuffix(u.config.PublicURL, "/")
+	if base == "" {
+		base = u.endpoint.String() + "/" + u.config.Bucket
+	}
+	return base + "/" + escapePath(key)
+}
+
+// Upload puts a file in the bucket and returns its public URL. Uploading the
+// same file again overwrites the object.
+func (u *S3Uploader) Upload(localPath string) (string, error) {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return "", err
+	}
+
+	key := u.Key(localPath)
+	objectPath := strings.TrimSuffix(u.endpoint.Path, "/") + "/" + escapePath(u.config.Bucket) + "/" + escapePath(key)
+	req, err := http.NewRequest(http.MethodPut, u.endpoint.Scheme+"://"+u.endpoint.Host+objectPath, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	payloadHash := sha256.Sum256(data)
+	req.Header.Set("Content-Type", contentType(localPath))
+	req.Header.Set("Cache-Control", u.config.CacheControl)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if u.config.ACL != "" {
+		req.Header.Set("X-Amz-Acl", u.config.ACL)
+	}
+	signV4(req, objectPath, hex.EncodeToString(payloadHash[:]), u.config.AccessKey, u.config.SecretKey, u.config.Region, u.now().UTC())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("failed to upload %s: status %d: %s", key, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return u.PublicURL(key), nil
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header for the S3
+// service. escapedPath is the request path as sent; the Host header and
+// every header already set on req are signed.
+func signV4(req *http.Request, escapedPath, payloadHash, accessKey, secretKey, region string, t time.Time) {
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapedPath,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// escapePath percent-encodes everything in a key except unreserved
+// characters and slashes, as SigV4 requires
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,35 @@
+// Package uploader publishes resized product images to object storage so
+// exports can reference hosted copies instead of the scraped source URLs.
+package uploader
+
+import (
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// Uploader publishes a local file and returns the public URL it is served from
+type Uploader interface {
+	Upload(localPath string) (publicURL string, err error)
+}
+
+// contentTypes covers image formats mime does not know on every platform
+var contentTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+	".avif": "image/avif",
+}
+
+// contentType returns the MIME type for a file name
+func contentType(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,84 @@
+package images
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// validatorsFileName is the sidecar in the output directory that records the
+// HTTP cache validators of every downloaded image
+const validatorsFileName = ".http-cache.json"
+
+// validator holds the cache validators a server sent with an image
+type validator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	Path         string `json:"path"`
+}
+
+// loadValidatorsLocked reads the sidecar on first use. The caller holds validatorsMu.
+func (f *Fetcher) loadValidatorsLocked() {
+	if f.validators != nil {
+		return
+	}
+	f.validators = make(map[string]validator)
+	f.validatorsFile = filepath.Join(f.outputDir, validatorsFileName)
+
+	data, err := os.ReadFile(f.validatorsFile)
+	if err != nil {
+		return // Nothing downloaded yet
+	}
+	json.Unmarshal(data, &f.validators)
+}
+
+// setConditionalHeaders adds If-None-Match and If-Modified-Since to req when
+// the URL was downloaded to destPath before and the file is still there
+func (f *Fetcher) setConditionalHeaders(req *http.Request, destPath string) {
+	f.validatorsMu.Lock()
+	f.loadValidatorsLocked()
+	v, ok := f.validators[req.URL.String()]
+	f.validatorsMu.Unlock()
+
+	if !ok || v.Path != destPath {
+		return
+	}
+	if _, err := os.Stat(destPath); err != nil {
+		return // The file was removed; fetch it again
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// storeValidators records the validators of a successful download and writes
+// the sidecar. Responses without validators drop any previous entry.
+func (f *Fetcher) storeValidators(rawURL, destPath string, header http.Header) {
+	f.validatorsMu.Lock()
+	defer f.validatorsMu.Unlock()
+	f.loadValidatorsLocked()
+
+	v := validator{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		Path:         destPath,
+	}
+	if v.ETag == "" && v.LastModified == "" {
+		if _, ok := f.validators[rawURL]; !ok {
+			return
+		}
+		delete(f.validators, rawURL)
+	} else {
+		f.validators[rawURL] = v
+	}
+
+	data, err := json.MarshalIndent(f.validators, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(f.validatorsFile, data, 0644) // Best effort; a lost entry only costs a full download
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatText = "text" // key=value lines (default)
+	FormatJSON = "json" // One JSON object per line, for log shippers
+)
+
+// DefaultLevel keeps interactive runs quiet: only warnings and errors are
+// logged alongside the regular command output
+const DefaultLevel = "warn"
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToLower(s))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q (use debug, info, warn, or error)", s)
+	}
+	return level, nil
+}
+
+// New creates a logger writing to w at the given level and format
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch strings.ToLower(format) {
+	case FormatText, "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q (use text or json)", format)
+}
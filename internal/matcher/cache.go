@@ -0,0 +1,143 @@
+package matcher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultCacheFile is where the file cache keeps lookup results
+const DefaultCacheFile = "output/.tiger-cache.json"
+
+// CacheEntry stores a cached lookup result
+type CacheEntry struct {
+	SKU      string        `json:"sku"` // Cache key: a SKU, a BarcodeCacheKey or "url:" + an overridden page
+	Product  *TigerProduct `json:"product,omitempty"`
+	NotFound bool          `json:"not_found,omitempty"`
+	CachedAt time.Time     `json:"cached_at"`
+}
+
+// Cache persists the scraper's lookup results. Entries are returned whether
+// or not they have expired; the scraper applies its TTLs. Implementations
+// must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (CacheEntry, bool, error)
+	Set(entry CacheEntry) error
+	Delete(keys ...string) (int, error)
+	Clear() (int, error)
+	Entries() ([]CacheEntry, error)
+	Len() (int, error)
+}
+
+// FileCache is a Cache kept in memory and written to a JSON file on every
+// change. It is the default when the database backend is disabled.
+type FileCache struct {
+	path    string
+	entries map[string]*CacheEntry
+	mu      sync.RWMutex
+	saveMu  sync.Mutex // Serializes file writes from concurrent lookups
+}
+
+// NewFileCache loads the cache file at path (default: DefaultCacheFile). A
+// missing or unreadable file starts an empty cache.
+func NewFileCache(path string) *FileCache {
+	if path == "" {
+		path = DefaultCacheFile
+	}
+	c := &FileCache{path: path, entries: make(map[string]*CacheEntry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c // No cache file yet
+	}
+	var entries []CacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return c
+	}
+	for _, e := range entries {
+		entry := e // Copy to avoid pointer issues
+		c.entries[e.SKU] = &entry
+	}
+	return c
+}
+
+// Get returns the entry stored under key
+func (c *FileCache) Get(key string) (CacheEntry, bool, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if entry, ok := c.entries[key]; ok {
+		return *entry, true, nil
+	}
+	return CacheEntry{}, false, nil
+}
+
+// Set stores an entry under its key and saves the file
+func (c *FileCache) Set(entry CacheEntry) error {
+	c.mu.Lock()
+	c.entries[entry.SKU] = &entry
+	c.mu.Unlock()
+	return c.save() // Save synchronously to ensure it completes
+}
+
+// Delete removes the entries with the given keys and returns how many were
+// removed
+func (c *FileCache) Delete(keys ...string) (int, error) {
+	c.mu.Lock()
+	n := 0
+	for _, key := range keys {
+		if _, ok := c.entries[key]; ok {
+			delete(c.entries, key)
+			n++
+		}
+	}
+	c.mu.Unlock()
+	if n == 0 {
+		return 0, nil
+	}
+	return n, c.save()
+}
+
+// Clear removes every entry and returns how many there were
+func (c *FileCache) Clear() (int, error) {
+	c.mu.Lock()
+	n := len(c.entries)
+	c.entries = make(map[string]*CacheEntry)
+	c.mu.Unlock()
+	return n, c.save()
+}
+
+// Entries returns a copy of every entry
+func (c *FileCache) Entries() ([]CacheEntry, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entries := make([]CacheEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// Len returns the number of entries
+func (c *FileCache) Len() (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries), nil
+}
+
+// save writes the cache to disk
+func (c *FileCache) save() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	entries, _ := c.Entries()
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, data, 0644)
+}
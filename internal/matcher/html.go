@@ -0,0 +1,322 @@
+package matcher
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	// Product detail slugs look like 12345-product-name
+	productSlugRe = regexp.MustCompile(`^\d+-.+`)
+	// PIM product images: /pim/528_{uuid}
+	pimPathRe = regexp.MustCompile(`^/pim/528_[a-f0-9-]+$`)
+	// Media images (lifestyle shots): /media/{hash}/{filename}
+	mediaPathRe = regexp.MustCompile(`^/media/[a-z0-9]+/.+\.(jpg|jpeg|png|webp)$`)
+)
+
+// pageImages holds the image references found on a product page
+type pageImages struct {
+	PIMPaths  []string // Product image paths, without query
+	MediaURLs []string // Lifestyle image URLs as referenced by the page
+}
+
+// extractProductLinks returns the paths of product detail pages linked from a
+// listing page, in document order. Links inside product cards (elements with a
+// "product" class) come first, so navigation and teaser links are only used as
+// a fallback.
+func extractProductLinks(r io.Reader, prefix string) ([]string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var carded, other []string
+	seen := make(map[string]bool)
+
+	walkElements(doc, func(n *html.Node) {
+		if n.Data != "a" {
+			return
+		}
+
+		path := urlPath(attr(n, "href"))
+		if seen[path] || !isProductPath(path, prefix) {
+			return
+		}
+		seen[path] = true
+
+		if hasClassContaining(n, "product") || hasAncestorClassContaining(n, "product") {
+			carded = append(carded, path)
+		} else {
+			other = append(other, path)
+		}
+	})
+
+	return append(carded, other...), nil
+}
+
+// structuredProduct holds the product identity a page declares in its
+// schema.org structured data
+type structuredProduct struct {
+	Name  string
+	GTINs []string // As declared, e.g. from gtin13 or gtin
+}
+
+// gtinKeys are the schema.org properties that carry a product's GTIN
+var gtinKeys = []string{"gtin", "gtin8", "gtin12", "gtin13", "gtin14"}
+
+// extractStructuredProduct reads the schema.org Product declared in JSON-LD
+// scripts and microdata (itemprop) attributes
+func extractStructuredProduct(r io.Reader) (*structuredProduct, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &structuredProduct{}
+	walkElements(doc, func(n *html.Node) {
+		if n.Data == "script" && strings.EqualFold(attr(n, "type"), "application/ld+json") && n.FirstChild != nil {
+			var data interface{}
+			if err := json.Unmarshal([]byte(n.FirstChild.Data), &data); err == nil {
+				collectJSONLDProduct(data, product)
+			}
+			return
+		}
+
+		switch prop := attr(n, "itemprop"); {
+		case prop == "name" && product.Name == "" && hasAncestorItemType(n, "Product"):
+			product.Name = strings.TrimSpace(textContent(n))
+		case contains(gtinKeys, prop):
+			value := attr(n, "content")
+			if value == "" {
+				value = textContent(n)
+			}
+			if value = strings.TrimSpace(value); value != "" {
+				product.GTINs = append(product.GTINs, value)
+			}
+		}
+	})
+
+	return product, nil
+}
+
+// collectJSONLDProduct walks a decoded JSON-LD document, including @graph
+// arrays, and adds the name and GTINs of Product nodes to product
+func collectJSONLDProduct(data interface{}, product *structuredProduct) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collectJSONLDProduct(item, product)
+		}
+	case map[string]interface{}:
+		if isJSONLDType(v["@type"], "Product") {
+			if name, ok := v["name"].(string); ok && product.Name == "" {
+				product.Name = strings.TrimSpace(name)
+			}
+			for _, key := range gtinKeys {
+				switch gtin := v[key].(type) {
+				case string:
+					product.GTINs = append(product.GTINs, gtin)
+				case float64:
+					product.GTINs = append(product.GTINs, strconv.FormatFloat(gtin, 'f', -1, 64))
+				}
+			}
+		}
+		for key, value := range v {
+			if key == "@graph" || key == "offers" || key == "hasVariant" {
+				collectJSONLDProduct(value, product)
+			}
+		}
+	}
+}
+
+// isJSONLDType reports whether a JSON-LD @type value (a string or an array of
+// strings) includes typ
+func isJSONLDType(value interface{}, typ string) bool {
+	switch v := value.(type) {
+	case string:
+		return v == typ
+	case []interface{}:
+		for _, t := range v {
+			if s, ok := t.(string); ok && s == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractPageImages collects product and media images from <img> and <source>
+// elements, picking the highest-resolution srcset candidate for each element
+func extractPageImages(r io.Reader) (*pageImages, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	images := &pageImages{}
+	seen := make(map[string]bool)
+
+	walkElements(doc, func(n *html.Node) {
+		if n.Data != "img" && n.Data != "source" {
+			return
+		}
+
+		var candidates []string
+		for _, key := range []string{"srcset", "data-srcset"} {
+			if best := bestSrcsetCandidate(attr(n, key)); best != "" {
+				candidates = append(candidates, best)
+			}
+		}
+		for _, key := range []string{"src", "data-src"} {
+			if src := strings.TrimSpace(attr(n, key)); src != "" {
+				candidates = append(candidates, src)
+			}
+		}
+
+		// Use the first candidate that is a product or media image
+		for _, candidate := range candidates {
+			path := urlPath(candidate)
+			if seen[path] {
+				return
+			}
+
+			if pimPathRe.MatchString(path) {
+				seen[path] = true
+				images.PIMPaths = append(images.PIMPaths, path)
+				return
+			}
+
+			if mediaPathRe.MatchString(strings.ToLower(path)) &&
+				!strings.Contains(path, "icon") && !strings.Contains(path, "logo") {
+				seen[path] = true
+				images.MediaURLs = append(images.MediaURLs, candidate)
+				return
+			}
+		}
+	})
+
+	return images, nil
+}
+
+// bestSrcsetCandidate returns the URL with the largest width (w) or pixel
+// density (x) descriptor in a srcset attribute
+func bestSrcsetCandidate(srcset string) string {
+	var best string
+	bestScore := -1.0
+
+	for _, part := range strings.Split(srcset, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// A candidate without a descriptor is 1x
+		score := 1.0
+		if len(fields) > 1 {
+			desc := fields[1]
+			if v, err := strconv.ParseFloat(desc[:len(desc)-1], 64); err == nil &&
+				(strings.HasSuffix(desc, "w") || strings.HasSuffix(desc, "x")) {
+				score = v
+			}
+		}
+
+		if score > bestScore {
+			best = fields[0]
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+// isProductPath reports whether path is a product detail page under prefix,
+// e.g. /producten/badkameraccessoires/haak/12345-boston-haak/
+func isProductPath(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, "/") {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, prefix), "/"), "/")
+	if len(segments) < 2 {
+		return false
+	}
+	return productSlugRe.MatchString(segments[len(segments)-1])
+}
+
+// urlPath returns the path of an absolute or relative URL
+func urlPath(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	return u.Path
+}
+
+// walkElements calls fn for every element node in the tree
+func walkElements(n *html.Node, fn func(*html.Node)) {
+	if n.Type == html.ElementNode {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, fn)
+	}
+}
+
+// attr returns the value of an attribute, or "" if it is not set
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasClassContaining reports whether any of the element's classes contains substr
+func hasClassContaining(n *html.Node, substr string) bool {
+	for _, class := range strings.Fields(attr(n, "class")) {
+		if strings.Contains(class, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAncestorItemType reports whether the element or an ancestor declares a
+// microdata itemtype ending in typ, e.g. https://schema.org/Product
+func hasAncestorItemType(n *html.Node, typ string) bool {
+	for p := n; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && strings.HasSuffix(attr(p, "itemtype"), "/"+typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// textContent returns the concatenated text of an element and its children
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		sb.WriteString(textContent(c))
+	}
+	return sb.String()
+}
+
+// hasAncestorClassContaining reports whether any ancestor element has a class containing substr
+func hasAncestorClassContaining(n *html.Node, substr string) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && hasClassContaining(p, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExtractProductURL(t *testing.T) {
+	s := &TigerScraper{baseURL: "https://tiger.nl"}
+
+	got := s.extractProductURL(readFixture(t, "search_results.html"), "Boston toiletrolhouder")
+	want := "https://tiger.nl/producten/badkameraccessoires/toiletrolhouder/13140-boston-toiletrolhouder-zonder-klep/"
+	if got != want {
+		t.Errorf("extractProductURL() = %q, want %q", got, want)
+	}
+
+	if got := s.extractProductURL("<html><body><p>Geen resultaten</p></body></html>", "Boston"); got != "" {
+		t.Errorf("extractProductURL() = %q for a page without products, want empty", got)
+	}
+}
+
+func TestExtractProductLinks(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "search_results.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := extractProductLinks(f, "/producten/badkameraccessoires/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Product cards first, deduplicated; the navigation link is a fallback
+	want := []string{
+		"/producten/badkameraccessoires/toiletrolhouder/13140-boston-toiletrolhouder-zonder-klep/",
+		"/producten/badkameraccessoires/haak/13160-boston-handdoekhaak/",
+		"/producten/badkameraccessoires/haak/99999-nieuw-in-de-collectie/",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("extractProductLinks() =\n  %q\nwant\n  %q", got, want)
+	}
+}
+
+func TestExtractPageImages(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "product_page.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	got, err := extractPageImages(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPIM := []string{
+		"/pim/528_2be183e7-9c32-419d-975e-f2b4aad4145a",
+		"/pim/528_7f3c9a10-4d2e-4b8a-9c6f-0e1d2c3b4a59",
+	}
+	if !reflect.DeepEqual(got.PIMPaths, wantPIM) {
+		t.Errorf("PIMPaths =\n  %q\nwant\n  %q", got.PIMPaths, wantPIM)
+	}
+	// The 2x srcset candidate wins; logos and icons are skipped
+	wantMedia := []string{"//tiger.nl/media/9f8e7d/boston-sfeer-badkamer@2x.jpg"}
+	if !reflect.DeepEqual(got.MediaURLs, wantMedia) {
+		t.Errorf("MediaURLs =\n  %q\nwant\n  %q", got.MediaURLs, wantMedia)
+	}
+
+	s := &TigerScraper{baseURL: "https://tiger.nl"}
+	if got := s.absoluteURL(got.MediaURLs[0]); got != "https://tiger.nl/media/9f8e7d/boston-sfeer-badkamer@2x.jpg" {
+		t.Errorf("absoluteURL() = %q", got)
+	}
+}
+
+func TestBestSrcsetCandidate(t *testing.T) {
+	tests := []struct {
+		srcset string
+		want   string
+	}{
+		{"", ""},
+		{"a.jpg", "a.jpg"},
+		{"a.jpg 300w, b.jpg 1200w, c.jpg 800w", "b.jpg"},
+		{"a.jpg 1x, b.jpg 2x", "b.jpg"},
+		{"a.jpg, b.jpg 1.5x", "b.jpg"},
+		{" a.jpg 300w ,\n b.jpg 600w ", "b.jpg"},
+	}
+	for _, tt := range tests {
+		if got := bestSrcsetCandidate(tt.srcset); got != tt.want {
+			t.Errorf("bestSrcsetCandidate(%q) = %q, want %q", tt.srcset, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package matcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMappingsFile is the mappings file name inside the config directory
+const DefaultMappingsFile = "tiger-mappings.yaml"
+
+// KeywordMapping maps a product name keyword to a Tiger.nl filter value
+type KeywordMapping struct {
+	Keyword string `yaml:"keyword"`
+	Value   string `yaml:"value"`
+}
+
+// TigerMappings holds the keyword maps used to build Tiger.nl search URLs.
+// Keywords are matched against the lowercased product name in file order,
+// and the first match in each section wins.
+type TigerMappings struct {
+	Categories []KeywordMapping `yaml:"categories"`
+	Series     []KeywordMapping `yaml:"series"`
+}
+
+// DefaultTigerMappings returns the built-in category and series mappings
+func DefaultTigerMappings() *TigerMappings {
+	return &TigerMappings{
+		Categories: []KeywordMapping{
+			{Keyword: "toalettrullholder", Value: "toiletrolhouder"},
+			{Keyword: "toilet roll", Value: "toiletrolhouder"},
+			{Keyword: "toalettbørste", Value: "toiletborstel"},
+			{Keyword: "toilet brush", Value: "toiletborstel"},
+			{Keyword: "håndklestang", Value: "handdoekhouder"},
+			{Keyword: "towel rail", Value: "handdoekhouder"},
+			{Keyword: "towel bar", Value: "handdoekhouder"},
+			{Keyword: "krok", Value: "haak"},
+			{Keyword: "hook", Value: "haak"},
+			{Keyword: "dusjkurv", Value: "douchekorf"},
+			{Keyword: "shower caddy", Value: "douchekorf"},
+			{Keyword: "speil", Value: "spiegel"},
+			{Keyword: "mirror", Value: "spiegel"},
+		},
+		Series: []KeywordMapping{
+			{Keyword: "boston", Value: "productserie-boston"},
+			{Keyword: "urban", Value: "productserie-urban"},
+			{Keyword: "2-store", Value: "productserie-2-store"},
+			{Keyword: "carv", Value: "productserie-carv"},
+			{Keyword: "tune", Value: "productserie-tune"},
+			{Keyword: "impuls", Value: "productserie-impuls"},
+			{Keyword: "nomad", Value: "productserie-nomad"},
+			{Keyword: "items", Value: "productserie-items"},
+		},
+	}
+}
+
+// DefaultMappingsPath returns ~/.badops/tiger-mappings.yaml
+func DefaultMappingsPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".badops", DefaultMappingsFile), nil
+}
+
+// LoadTigerMappings reads mappings from a YAML file
+func LoadTigerMappings(path string) (*TigerMappings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mappings TigerMappings
+	if err := yaml.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to parse mappings file: %w", err)
+	}
+
+	return &mappings, nil
+}
+
+// matchKeyword returns the value of the first keyword contained in name, or "".
+// name is a NormalizeTitle result; keywords are folded the same way.
+func matchKeyword(mappings []KeywordMapping, name string) string {
+	for _, m := range mappings {
+		if keyword := foldTitle(m.Keyword); keyword != "" && strings.Contains(name, keyword) {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// Validate checks the mappings and returns a list of problems: empty entries,
+// duplicate keywords, and keywords that can never match because they are
+// shadowed by an earlier keyword they contain. Keywords are compared the way
+// they are matched, after NormalizeTitle-style folding.
+func (m *TigerMappings) Validate() []string {
+	var problems []string
+	problems = append(problems, validateSection("categories", m.Categories)...)
+	problems = append(problems, validateSection("series", m.Series)...)
+	return problems
+}
+
+// validateSection validates a single keyword section
+func validateSection(section string, mappings []KeywordMapping) []string {
+	var problems []string
+	seen := make(map[string]int)
+
+	for i, m := range mappings {
+		if m.Keyword == "" || m.Value == "" {
+			problems = append(problems, fmt.Sprintf("%s[%d]: keyword and value are required", section, i))
+			continue
+		}
+
+		keyword := foldTitle(m.Keyword)
+		if first, ok := seen[keyword]; ok {
+			problems = append(problems, fmt.Sprintf("%s[%d]: duplicate keyword %q (first defined at %s[%d])",
+				section, i, m.Keyword, section, first))
+			continue
+		}
+		seen[keyword] = i
+
+		for j := 0; j < i; j++ {
+			earlier := foldTitle(mappings[j].Keyword)
+			if earlier != "" && earlier != keyword && strings.Contains(keyword, earlier) {
+				problems = append(problems, fmt.Sprintf("%s[%d]: keyword %q is unreachable (shadowed by earlier keyword %q)",
+					section, i, m.Keyword, mappings[j].Keyword))
+				break
+			}
+		}
+	}
+
+	return problems
+}
@@ -0,0 +1,85 @@
+package matcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeHomeMappings points HOME at a temporary directory holding data as the
+// default mappings file, or no file when data is empty
+func writeHomeMappings(t *testing.T, data string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if data == "" {
+		return
+	}
+	dir := filepath.Join(home, ".badops")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, DefaultMappingsFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefaultMappingsFile(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		writeHomeMappings(t, "")
+		s := NewTigerScraper()
+		if err := s.MappingsError(); err != nil {
+			t.Errorf("MappingsError() = %v, want nil", err)
+		}
+		if url := s.buildSearchURL("Boston Toilet Brush"); !strings.Contains(url, "productserie-boston") {
+			t.Errorf("built-in mappings not used: %s", url)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		writeHomeMappings(t, "series:\n  - keyword: boston\n    value: productserie-custom\n")
+		s := NewTigerScraper()
+		if err := s.MappingsError(); err != nil {
+			t.Fatalf("MappingsError() = %v, want nil", err)
+		}
+		if url := s.buildSearchURL("Boston Toilet Brush"); !strings.Contains(url, "productserie-custom") {
+			t.Errorf("file mappings not used: %s", url)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		writeHomeMappings(t, "series: [keyword: boston\n")
+		s := NewTigerScraper()
+		err := s.MappingsError()
+		if err == nil || !strings.Contains(err.Error(), DefaultMappingsFile) {
+			t.Fatalf("MappingsError() = %v, want a parse error naming the file", err)
+		}
+		// Until replaced, the built-in mappings stay in use
+		if url := s.buildSearchURL("Boston Toilet Brush"); !strings.Contains(url, "productserie-boston") {
+			t.Errorf("built-in mappings not used: %s", url)
+		}
+
+		s.SetMappings(DefaultTigerMappings())
+		if err := s.MappingsError(); err != nil {
+			t.Errorf("MappingsError() after SetMappings = %v, want nil", err)
+		}
+	})
+}
+
+func TestLoadMappingsFileErrors(t *testing.T) {
+	writeHomeMappings(t, "")
+	s := NewTigerScraper()
+	dir := t.TempDir()
+
+	broken := filepath.Join(dir, "broken.yaml")
+	if err := os.WriteFile(broken, []byte("categories: {keyword"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LoadMappingsFile(broken); err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("LoadMappingsFile(broken) = %v, want a parse error", err)
+	}
+	if err := s.LoadMappingsFile(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadMappingsFile(missing) = nil, want an error")
+	}
+}
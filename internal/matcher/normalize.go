@@ -0,0 +1,79 @@
+package matcher
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultBrandPrefixes are the brand names stripped from the start of titles
+var DefaultBrandPrefixes = []string{"tiger"}
+
+var (
+	brandPrefixes   = normalizeBrands(DefaultBrandPrefixes)
+	brandPrefixesMu sync.RWMutex
+)
+
+// unfoldable maps letters that have no Unicode decomposition to ASCII
+var unfoldable = strings.NewReplacer("ø", "o", "æ", "ae", "ß", "ss", "đ", "d", "ł", "l", "œ", "oe")
+
+// SetBrandPrefixes replaces the brand names NormalizeTitle strips from the
+// start of titles. An empty list disables stripping.
+func SetBrandPrefixes(brands []string) {
+	normalized := normalizeBrands(brands)
+	brandPrefixesMu.Lock()
+	defer brandPrefixesMu.Unlock()
+	brandPrefixes = normalized
+}
+
+func normalizeBrands(brands []string) []string {
+	normalized := make([]string, 0, len(brands))
+	for _, b := range brands {
+		if b = foldTitle(b); b != "" {
+			normalized = append(normalized, b)
+		}
+	}
+	return normalized
+}
+
+// NormalizeTitle returns the form of a product title used for keyword and
+// fuzzy matching: lowercased, without diacritics (ø and æ become o and ae),
+// with punctuation replaced by spaces, whitespace collapsed, and a leading
+// brand name (see SetBrandPrefixes) removed.
+//
+//	NormalizeTitle("Tiger Boston Håndklestang, RVS-gepolijst") == "boston handklestang rvs gepolijst"
+func NormalizeTitle(s string) string {
+	title := foldTitle(s)
+
+	brandPrefixesMu.RLock()
+	defer brandPrefixesMu.RUnlock()
+	for _, brand := range brandPrefixes {
+		if title == brand {
+			return title // Nothing but the brand; keep it
+		}
+		if rest, ok := strings.CutPrefix(title, brand+" "); ok {
+			return rest
+		}
+	}
+	return title
+}
+
+// foldTitle lowercases s, strips diacritics and punctuation and collapses
+// whitespace
+func foldTitle(s string) string {
+	s = unfoldable.Replace(strings.ToLower(s))
+
+	// Decompose accented letters and drop the combining marks
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	if folded, _, err := transform.String(t, s); err == nil {
+		s = folded
+	}
+
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
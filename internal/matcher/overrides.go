@@ -0,0 +1,122 @@
+package matcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// loadOverrides loads the manual SKU to Tiger.nl URL overrides from disk
+func (s *TigerScraper) loadOverrides() {
+	data, err := os.ReadFile(s.overridesFile)
+	if err != nil {
+		return // No overrides yet
+	}
+	var overrides map[string]string
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return
+	}
+	s.overridesMu.Lock()
+	defer s.overridesMu.Unlock()
+	s.overrides = overrides
+}
+
+// saveOverridesLocked writes the overrides to disk. The caller holds overridesMu.
+func (s *TigerScraper) saveOverridesLocked() error {
+	data, err := json.MarshalIndent(s.overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.overridesFile), 0755); err != nil {
+		return fmt.Errorf("failed to create overrides directory: %w", err)
+	}
+	if err := os.WriteFile(s.overridesFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write overrides: %w", err)
+	}
+	return nil
+}
+
+// Override returns the Tiger.nl URL a SKU is pinned to, if any
+func (s *TigerScraper) Override(sku string) (string, bool) {
+	s.overridesMu.RLock()
+	defer s.overridesMu.RUnlock()
+	u, ok := s.overrides[sku]
+	return u, ok
+}
+
+// Overrides returns the SKUs with a manual override, sorted
+func (s *TigerScraper) Overrides() []string {
+	s.overridesMu.RLock()
+	defer s.overridesMu.RUnlock()
+	skus := make([]string, 0, len(s.overrides))
+	for sku := range s.overrides {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+	return skus
+}
+
+// SetOverride pins a SKU to a Tiger.nl product page. Overrides take
+// precedence over every automated lookup and are kept across re-matching.
+// The SKU's cached lookup is dropped so it cannot resurface.
+func (s *TigerScraper) SetOverride(sku, productURL string) error {
+	u, err := url.Parse(strings.TrimSpace(productURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") ||
+		(u.Hostname() != "tiger.nl" && !strings.HasSuffix(u.Hostname(), ".tiger.nl")) {
+		return fmt.Errorf("not a Tiger.nl URL: %s", productURL)
+	}
+
+	s.overridesMu.Lock()
+	defer s.overridesMu.Unlock()
+	if s.overrides == nil {
+		s.overrides = make(map[string]string)
+	}
+	s.overrides[sku] = u.String()
+	if err := s.saveOverridesLocked(); err != nil {
+		return err
+	}
+	s.InvalidateCache(sku)
+	return nil
+}
+
+// ClearOverride removes a SKU's override. It returns false if there was none.
+func (s *TigerScraper) ClearOverride(sku string) (bool, error) {
+	s.overridesMu.Lock()
+	defer s.overridesMu.Unlock()
+	if _, ok := s.overrides[sku]; !ok {
+		return false, nil
+	}
+	delete(s.overrides, sku)
+	if err := s.saveOverridesLocked(); err != nil {
+		return true, err
+	}
+	s.InvalidateCache(sku)
+	return true, nil
+}
+
+// FindProductByURL scrapes the validated images of a known product page, as
+// used for overridden SKUs. Results are cached by URL.
+func (s *TigerScraper) FindProductByURL(ctx context.Context, productURL string) (*TigerProduct, error) {
+	key := "url:" + productURL
+	if cached, found := s.GetCached(key); found && cached != nil {
+		return cached, nil
+	}
+
+	images, err := s.scrapeProductImagesWithValidation(ctx, productURL)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &TigerProduct{
+		Name:      urlPath(productURL),
+		URL:       productURL,
+		ImageURLs: images,
+	}
+	s.SetCached(key, product)
+	return product, nil
+}
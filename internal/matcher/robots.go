@@ -0,0 +1,105 @@
+package matcher
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCrawlDelay caps a robots.txt Crawl-delay so a misconfigured value
+// cannot stall a run indefinitely
+const maxCrawlDelay = 30 * time.Second
+
+// crawlDelay returns Tiger.nl's robots.txt Crawl-delay for our user agent,
+// fetching robots.txt on first use. A missing or unreadable robots.txt means
+// no delay; it is fetched again only if ctx was cancelled before it was read.
+func (s *TigerScraper) crawlDelay(ctx context.Context) time.Duration {
+	s.robotsMu.Lock()
+	defer s.robotsMu.Unlock()
+	if s.robotsLoaded {
+		return s.robotsDelay
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"/robots.txt", nil)
+	if err != nil {
+		return 0
+	}
+	s.setHeaders(req)
+	resp, err := s.client.Do(req)
+	if ctx.Err() != nil {
+		return 0
+	}
+	s.robotsLoaded = true
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		s.robotsDelay = min(parseCrawlDelay(io.LimitReader(resp.Body, 512*1024), s.userAgent), maxCrawlDelay)
+	}
+	return s.robotsDelay
+}
+
+// parseCrawlDelay returns the Crawl-delay robots.txt sets for userAgent. A
+// group naming the agent's product token (e.g. BadOps-TigerScraper) wins over
+// the * group; 0 means no delay.
+func parseCrawlDelay(r io.Reader, userAgent string) time.Duration {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var (
+		agents      []string // User-agent lines of the current group
+		inRules     bool     // Whether the current group's rules have started
+		wildcard    time.Duration
+		specific    time.Duration
+		hasSpecific bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		if field == "user-agent" {
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+			continue
+		}
+		inRules = true
+		if field != "crawl-delay" {
+			continue
+		}
+
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds <= 0 {
+			continue
+		}
+		delay := time.Duration(seconds * float64(time.Second))
+		for _, agent := range agents {
+			switch {
+			case agent == "*":
+				wildcard = delay
+			case agent != "" && strings.Contains(token, agent):
+				specific, hasSpecific = delay, true
+			}
+		}
+	}
+
+	if hasSpecific {
+		return specific
+	}
+	return wildcard
+}
@@ -1,9 +1,11 @@
 package matcher
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"regexp"
@@ -17,135 +19,343 @@ type TigerProduct struct {
 	Name      string   `json:"name"`
 	URL       string   `json:"url"`
 	ImageURLs []string `json:"image_urls"`
+	GTIN      string   `json:"gtin,omitempty"` // Set when the page's structured data confirmed the barcode
 }
 
-// CacheEntry stores a cached lookup result
-type CacheEntry struct {
-	SKU       string        `json:"sku"`
-	Product   *TigerProduct `json:"product,omitempty"`
-	NotFound  bool          `json:"not_found,omitempty"`
-	CachedAt  time.Time     `json:"cached_at"`
-}
+// DefaultUserAgent identifies the scraper to Tiger.nl, with a URL to reach us
+const DefaultUserAgent = "BadOps-TigerScraper/1.0 (+https://bad.no)"
+
+// DefaultAcceptLanguage asks Tiger.nl for its Dutch pages, which the
+// category and series mappings are written against
+const DefaultAcceptLanguage = "nl-NL,nl;q=0.9,en;q=0.5"
+
+// DefaultCacheTTL is how long lookups stay cached unless configured otherwise
+const DefaultCacheTTL = 24 * time.Hour
 
 // TigerScraper scrapes product data from Tiger.nl
 type TigerScraper struct {
 	client       *http.Client
 	baseURL      string
-	cache        map[string]*CacheEntry
-	cacheMu      sync.RWMutex
-	cacheFile    string
+	cache        Cache
+	cacheMu      sync.RWMutex // Guards cache, the TTLs and refreshAfter
+	refreshAfter time.Time    // Entries cached before this are ignored (force refresh)
 	rateLimit    time.Duration
 	lastRequest  time.Time
 	rateLimitMu  sync.Mutex
+	mappings     *TigerMappings
+	mappingsErr  error // Why the default mappings file could not be loaded
+	mappingsMu   sync.RWMutex
+	observer     Observer
+	cacheTTL      time.Duration // How long found products stay cached
+	negativeTTL   time.Duration // How long "not found" results stay cached
+	overrides     map[string]string // SKU -> Tiger.nl URL, pinned by hand
+	overridesMu   sync.RWMutex
+	overridesFile string
+	userAgent      string
+	acceptLanguage string
+	robotsDelay    time.Duration // Crawl-delay from robots.txt
+	robotsLoaded   bool
+	robotsMu       sync.Mutex
+}
+
+// Observer is notified of the scraper's requests and cache lookups, so a
+// connector can keep statistics without the scraper depending on it
+type Observer interface {
+	RecordResponse(req *http.Request, resp *http.Response, err error, elapsed time.Duration)
+	RecordCacheHit()
+	RecordCacheMiss()
 }
 
 // NewTigerScraper creates a new Tiger.nl scraper with caching and rate limiting
 func NewTigerScraper() *TigerScraper {
 	s := &TigerScraper{
-		client:    &http.Client{Timeout: 30 * time.Second},
-		baseURL:   "https://tiger.nl",
-		cache:     make(map[string]*CacheEntry),
-		cacheFile: "output/.tiger-cache.json",
-		rateLimit: 150 * time.Millisecond, // 150ms between requests
-	}
-	s.loadCache()
+		client:        &http.Client{Timeout: 30 * time.Second},
+		baseURL:       "https://tiger.nl",
+		cache:         NewFileCache(DefaultCacheFile),
+		cacheTTL:      DefaultCacheTTL,
+		negativeTTL:   DefaultCacheTTL,
+		overridesFile: "output/.tiger-overrides.json",
+		rateLimit:     150 * time.Millisecond, // 150ms between requests
+		mappings:      DefaultTigerMappings(),
+		userAgent:      DefaultUserAgent,
+		acceptLanguage: DefaultAcceptLanguage,
+	}
+	s.loadOverrides()
+	s.mappingsErr = s.loadDefaultMappings()
 	return s
 }
 
-// loadCache loads the cache from disk
-func (s *TigerScraper) loadCache() {
-	data, err := os.ReadFile(s.cacheFile)
+// loadDefaultMappings loads ~/.badops/tiger-mappings.yaml if it exists,
+// keeping the built-in mappings otherwise. A file that exists but can't be
+// read or parsed is an error.
+func (s *TigerScraper) loadDefaultMappings() error {
+	path, err := DefaultMappingsPath()
 	if err != nil {
-		return // No cache file yet
+		return nil // No home directory, so no default file
+	}
+	mappings, err := LoadTigerMappings(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.SetMappings(mappings)
+	return nil
+}
+
+// MappingsError returns why the default mappings file could not be loaded
+// when the scraper was created, or nil. The built-in mappings are used
+// instead until LoadMappingsFile or SetMappings replaces them.
+func (s *TigerScraper) MappingsError() error {
+	s.mappingsMu.RLock()
+	defer s.mappingsMu.RUnlock()
+	return s.mappingsErr
+}
+
+// LoadMappingsFile replaces the category/series mappings with those in path
+func (s *TigerScraper) LoadMappingsFile(path string) error {
+	mappings, err := LoadTigerMappings(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.SetMappings(mappings)
+	return nil
+}
+
+// SetMappings replaces the category/series mappings used to build search URLs
+func (s *TigerScraper) SetMappings(mappings *TigerMappings) {
+	s.mappingsMu.Lock()
+	defer s.mappingsMu.Unlock()
+	s.mappings = mappings
+	s.mappingsErr = nil
+}
+
+// SetRateLimit sets the minimum delay between requests. The delay is shared by
+// every goroutine using the scraper.
+func (s *TigerScraper) SetRateLimit(d time.Duration) {
+	s.rateLimitMu.Lock()
+	defer s.rateLimitMu.Unlock()
+	s.rateLimit = d
+}
+
+// SetHeaders sets the User-Agent and Accept-Language sent with every
+// request. Empty values keep the defaults. It must be called before the
+// scraper is used.
+func (s *TigerScraper) SetHeaders(userAgent, acceptLanguage string) {
+	if userAgent != "" {
+		s.userAgent = userAgent
 	}
-	var entries []CacheEntry
-	if err := json.Unmarshal(data, &entries); err != nil {
-		return
+	if acceptLanguage != "" {
+		s.acceptLanguage = acceptLanguage
 	}
+}
+
+// setHeaders adds the identifying headers to a request
+func (s *TigerScraper) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", s.userAgent)
+	req.Header.Set("Accept-Language", s.acceptLanguage)
+}
+
+// SetObserver sets the observer notified of requests and cache lookups. It
+// must be called before the scraper is used.
+func (s *TigerScraper) SetObserver(o Observer) {
+	s.observer = o
+}
+
+// SetCache replaces the cache lookups are stored in, e.g. with the shared
+// Postgres cache. It must be called before the scraper is used.
+func (s *TigerScraper) SetCache(c Cache) {
 	s.cacheMu.Lock()
 	defer s.cacheMu.Unlock()
-	for _, e := range entries {
-		entry := e // Copy to avoid pointer issues
-		s.cache[e.SKU] = &entry
+	s.cache = c
+}
+
+// store returns the current cache
+func (s *TigerScraper) store() Cache {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+	return s.cache
+}
+
+// SetForceRefresh makes lookups ignore every entry cached before now, so
+// they are fetched again and the fresh results re-cached. Results cached
+// later in the same run are still used.
+func (s *TigerScraper) SetForceRefresh(force bool) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.refreshAfter = time.Time{}
+	if force {
+		s.refreshAfter = time.Now()
 	}
 }
 
-// saveCache saves the cache to disk
-func (s *TigerScraper) saveCache() {
+// fresh reports whether a cache entry is within its TTL and was not cached
+// before a forced refresh
+func (s *TigerScraper) fresh(entry CacheEntry) bool {
 	s.cacheMu.RLock()
-	entries := make([]CacheEntry, 0, len(s.cache))
-	for _, e := range s.cache {
-		entries = append(entries, *e)
+	defer s.cacheMu.RUnlock()
+	ttl := s.cacheTTL
+	if entry.NotFound {
+		ttl = s.negativeTTL
 	}
-	s.cacheMu.RUnlock()
+	return time.Since(entry.CachedAt) < ttl && !entry.CachedAt.Before(s.refreshAfter)
+}
 
-	data, err := json.MarshalIndent(entries, "", "  ")
-	if err != nil {
-		return
+// SetCacheTTL sets how long found products and "not found" results stay
+// cached. Zero keeps the current value.
+func (s *TigerScraper) SetCacheTTL(ttl, negative time.Duration) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if ttl > 0 {
+		s.cacheTTL = ttl
+	}
+	if negative > 0 {
+		s.negativeTTL = negative
 	}
-	os.MkdirAll("output", 0755)
-	os.WriteFile(s.cacheFile, data, 0644)
 }
 
-// GetCached returns a cached result if available
-func (s *TigerScraper) GetCached(sku string) (*TigerProduct, bool) {
+// CacheTTL returns how long an entry stays cached
+func (s *TigerScraper) CacheTTL(notFound bool) time.Duration {
 	s.cacheMu.RLock()
 	defer s.cacheMu.RUnlock()
-	if entry, ok := s.cache[sku]; ok {
-		// Cache entries valid for 24 hours
-		if time.Since(entry.CachedAt) < 24*time.Hour {
-			if entry.NotFound {
-				return nil, true // Cached as not found
-			}
-			return entry.Product, true
+	if notFound {
+		return s.negativeTTL
+	}
+	return s.cacheTTL
+}
+
+// GetCached returns a cached result if available. A cache that cannot be
+// read counts as a miss.
+func (s *TigerScraper) GetCached(sku string) (*TigerProduct, bool) {
+	entry, ok, err := s.store().Get(sku)
+	if err != nil {
+		slog.Warn("tiger cache read failed", "key", sku, "error", err)
+	}
+	if ok && s.fresh(entry) {
+		if s.observer != nil {
+			s.observer.RecordCacheHit()
+		}
+		if entry.NotFound {
+			return nil, true // Cached as not found
 		}
+		return entry.Product, true
+	}
+	if s.observer != nil {
+		s.observer.RecordCacheMiss()
 	}
 	return nil, false
 }
 
-// SetCached stores a result in the cache
+// CacheSize returns the number of entries in the cache, including expired ones
+func (s *TigerScraper) CacheSize() int {
+	n, err := s.store().Len()
+	if err != nil {
+		slog.Warn("tiger cache read failed", "error", err)
+	}
+	return n
+}
+
+// CacheEntries returns a copy of every cache entry, including expired ones
+func (s *TigerScraper) CacheEntries() []CacheEntry {
+	entries, err := s.store().Entries()
+	if err != nil {
+		slog.Warn("tiger cache read failed", "error", err)
+	}
+	return entries
+}
+
+// ClearCache removes every cache entry and returns how many there were
+func (s *TigerScraper) ClearCache() int {
+	n, err := s.store().Clear()
+	if err != nil {
+		slog.Warn("tiger cache clear failed", "error", err)
+	}
+	return n
+}
+
+// InvalidateCache removes the entries with the given keys (SKUs, or keys
+// such as BarcodeCacheKey) and returns how many were removed
+func (s *TigerScraper) InvalidateCache(keys ...string) int {
+	n, err := s.store().Delete(keys...)
+	if err != nil {
+		slog.Warn("tiger cache delete failed", "keys", keys, "error", err)
+	}
+	return n
+}
+
+// SetCached stores a result in the cache. A failed write only costs a
+// repeated lookup, so it is logged rather than returned.
 func (s *TigerScraper) SetCached(sku string, product *TigerProduct) {
-	s.cacheMu.Lock()
-	s.cache[sku] = &CacheEntry{
+	err := s.store().Set(CacheEntry{
 		SKU:      sku,
 		Product:  product,
 		NotFound: product == nil,
 		CachedAt: time.Now(),
+	})
+	if err != nil {
+		slog.Warn("tiger cache write failed", "key", sku, "error", err)
 	}
-	s.cacheMu.Unlock()
-	s.saveCache() // Save synchronously to ensure it completes
 }
 
-// rateLimitWait waits if needed to respect rate limiting
-func (s *TigerScraper) rateLimitWait() {
+// rateLimitWait waits if needed to respect rate limiting, or until ctx is
+// done. minDelay raises the configured rate limit, e.g. to a robots.txt
+// Crawl-delay.
+func (s *TigerScraper) rateLimitWait(ctx context.Context, minDelay time.Duration) error {
 	s.rateLimitMu.Lock()
 	defer s.rateLimitMu.Unlock()
 
+	delay := max(s.rateLimit, minDelay)
 	elapsed := time.Since(s.lastRequest)
-	if elapsed < s.rateLimit {
-		time.Sleep(s.rateLimit - elapsed)
+	if elapsed < delay {
+		timer := time.NewTimer(delay - elapsed)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
 	}
 	s.lastRequest = time.Now()
+	return nil
 }
 
 // doGet performs a rate-limited GET request
-func (s *TigerScraper) doGet(url string) (*http.Response, error) {
-	s.rateLimitWait()
-	return s.client.Get(url)
+func (s *TigerScraper) doGet(ctx context.Context, url string) (*http.Response, error) {
+	return s.do(ctx, http.MethodGet, url)
 }
 
 // doHead performs a rate-limited HEAD request
-func (s *TigerScraper) doHead(url string) (*http.Response, error) {
-	s.rateLimitWait()
-	return s.client.Head(url)
+func (s *TigerScraper) doHead(ctx context.Context, url string) (*http.Response, error) {
+	return s.do(ctx, http.MethodHead, url)
+}
+
+// do performs a rate-limited request and reports it to the observer. The
+// request is cancelled when ctx is done. Requests are spaced by at least
+// the robots.txt Crawl-delay.
+func (s *TigerScraper) do(ctx context.Context, method, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.setHeaders(req)
+	if err := s.rateLimitWait(ctx, s.crawlDelay(ctx)); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if s.observer != nil {
+		s.observer.RecordResponse(req, resp, err, time.Since(start))
+	}
+	return resp, err
 }
 
 // FindProduct searches for a product on Tiger.nl and returns its images
-func (s *TigerScraper) FindProduct(productName string) (*TigerProduct, error) {
+func (s *TigerScraper) FindProduct(ctx context.Context, productName string) (*TigerProduct, error) {
 	// Map common product types to Tiger.nl category URLs
 	searchURL := s.buildSearchURL(productName)
 
-	resp, err := s.doGet(searchURL)
+	resp, err := s.doGet(ctx, searchURL)
 	if err != nil {
 		return nil, err
 	}
@@ -165,7 +375,7 @@ func (s *TigerScraper) FindProduct(productName string) (*TigerProduct, error) {
 	}
 
 	// Fetch product page and extract images
-	images, err := s.scrapeProductImages(productURL)
+	images, err := s.scrapeProductImages(ctx, productURL)
 	if err != nil {
 		return nil, err
 	}
@@ -177,56 +387,139 @@ func (s *TigerScraper) FindProduct(productName string) (*TigerProduct, error) {
 	}, nil
 }
 
-// buildSearchURL constructs a search URL based on product name keywords
-func (s *TigerScraper) buildSearchURL(productName string) string {
-	nameLower := strings.ToLower(productName)
-
-	// Map product types to Tiger.nl categories
-	categoryMap := map[string]string{
-		"toalettrullholder": "toiletrolhouder",
-		"toilet roll":       "toiletrolhouder",
-		"toalettbørste":     "toiletborstel",
-		"toilet brush":      "toiletborstel",
-		"håndklestang":      "handdoekhouder",
-		"towel rail":        "handdoekhouder",
-		"towel bar":         "handdoekhouder",
-		"krok":              "haak",
-		"hook":              "haak",
-		"dusjkurv":          "douchekorf",
-		"shower caddy":      "douchekorf",
-		"speil":             "spiegel",
-		"mirror":            "spiegel",
-	}
-
-	// Map series names
-	seriesMap := map[string]string{
-		"boston":  "productserie-boston",
-		"urban":   "productserie-urban",
-		"2-store": "productserie-2-store",
-		"carv":    "productserie-carv",
-		"tune":    "productserie-tune",
-		"impuls":  "productserie-impuls",
-		"nomad":   "productserie-nomad",
-		"items":   "productserie-items",
-	}
-
-	// Find matching category
-	var category string
-	for keyword, cat := range categoryMap {
-		if strings.Contains(nameLower, keyword) {
-			category = cat
-			break
+// barcodeCandidates is how many site search results are checked for a
+// matching GTIN before giving up
+const barcodeCandidates = 3
+
+// FindProductByBarcode searches Tiger.nl's site search for a barcode and
+// returns the first result whose structured data declares the same GTIN.
+// Search results that merely mention the number are not accepted.
+func (s *TigerScraper) FindProductByBarcode(ctx context.Context, barcode string) (*TigerProduct, error) {
+	gtin := normalizeGTIN(barcode)
+	if gtin == "" {
+		return nil, fmt.Errorf("invalid barcode: %q", barcode)
+	}
+
+	query := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(barcode))
+	searchURL := fmt.Sprintf("%s/zoeken/?q=%s", s.baseURL, query)
+	resp, err := s.doGet(ctx, searchURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("search returned status %d", resp.StatusCode)
+	}
+
+	links, err := extractProductLinks(resp.Body, "/producten/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse search results: %w", err)
+	}
+	if len(links) > barcodeCandidates {
+		links = links[:barcodeCandidates]
+	}
+
+	for _, link := range links {
+		productURL := s.baseURL + link
+		product, err := s.productWithGTIN(ctx, productURL, gtin)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil || product == nil {
+			continue
 		}
+		return product, nil
+	}
+
+	return nil, fmt.Errorf("product not found for barcode: %s", barcode)
+}
+
+// productWithGTIN fetches a product page and returns it if its structured
+// data declares gtin (normalized), or nil if it declares other GTINs or none
+func (s *TigerScraper) productWithGTIN(ctx context.Context, productURL, gtin string) (*TigerProduct, error) {
+	resp, err := s.doGet(ctx, productURL)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	// Find matching series
-	var series string
-	for keyword, ser := range seriesMap {
-		if strings.Contains(nameLower, keyword) {
-			series = ser
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("page returned status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	declared, err := extractStructuredProduct(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse product page: %w", err)
+	}
+	matched := ""
+	for _, g := range declared.GTINs {
+		if normalizeGTIN(g) == gtin {
+			matched = g
 			break
 		}
 	}
+	if matched == "" {
+		return nil, nil
+	}
+
+	pageImages, err := extractPageImages(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse product page: %w", err)
+	}
+	var images []string
+	for _, path := range pageImages.PIMPaths {
+		images = append(images, s.pimImageURL(path))
+	}
+
+	return &TigerProduct{
+		Name:      declared.Name,
+		URL:       productURL,
+		ImageURLs: images,
+		GTIN:      matched,
+	}, nil
+}
+
+// normalizeGTIN strips separators and left-pads a GTIN-8, -12, -13 or -14 to
+// 14 digits so the different lengths of the same code compare equal. It
+// returns "" for anything else.
+func normalizeGTIN(code string) string {
+	digits := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9':
+			return r
+		case r == ' ' || r == '-':
+			return -1
+		}
+		return 'x'
+	}, strings.TrimSpace(code))
+
+	if strings.Contains(digits, "x") {
+		return ""
+	}
+	switch len(digits) {
+	case 8, 12, 13, 14:
+		return strings.Repeat("0", 14-len(digits)) + digits
+	}
+	return ""
+}
+
+// buildSearchURL constructs a search URL based on product name keywords
+func (s *TigerScraper) buildSearchURL(productName string) string {
+	name := NormalizeTitle(productName)
+
+	s.mappingsMu.RLock()
+	mappings := s.mappings
+	s.mappingsMu.RUnlock()
+
+	// Find matching category and series
+	category := matchKeyword(mappings.Categories, name)
+	series := matchKeyword(mappings.Series, name)
 
 	// Build URL with filters
 	url := fmt.Sprintf("%s/producten/badkameraccessoires/", s.baseURL)
@@ -248,66 +541,62 @@ func (s *TigerScraper) buildSearchURL(productName string) string {
 
 // extractProductURL finds the product detail page URL from search results
 func (s *TigerScraper) extractProductURL(html, productName string) string {
-	// Look for product links in the HTML
-	// Pattern: /producten/badkameraccessoires/[type]/[id]-[name]/
-	re := regexp.MustCompile(`/producten/badkameraccessoires/[^"]+/\d+-[^"]+/`)
-	matches := re.FindAllString(html, -1)
-
-	if len(matches) > 0 {
-		return s.baseURL + matches[0]
+	// Product links look like /producten/badkameraccessoires/[type]/[id]-[name]/
+	links, err := extractProductLinks(strings.NewReader(html), "/producten/badkameraccessoires/")
+	if err != nil || len(links) == 0 {
+		return ""
 	}
-	return ""
+	return s.baseURL + links[0]
 }
 
 // scrapeProductImages extracts all image URLs from a product page
-func (s *TigerScraper) scrapeProductImages(productURL string) ([]string, error) {
-	resp, err := s.doGet(productURL)
+func (s *TigerScraper) scrapeProductImages(ctx context.Context, productURL string) ([]string, error) {
+	resp, err := s.doGet(ctx, productURL)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	pageImages, err := extractPageImages(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to parse product page: %w", err)
 	}
 
-	html := string(body)
 	var images []string
 
-	// Extract PIM image URLs (product images)
-	// Pattern: /pim/528_[uuid]
-	pimRe := regexp.MustCompile(`/pim/528_[a-f0-9-]+`)
-	pimMatches := pimRe.FindAllString(html, -1)
-
-	seen := make(map[string]bool)
-	for _, match := range pimMatches {
-		if !seen[match] {
-			seen[match] = true
-			// Request high-res version
-			fullURL := fmt.Sprintf("%s%s?width=1200&height=1200&format=jpg&quality=90", s.baseURL, match)
-			images = append(images, fullURL)
-		}
+	// PIM product images, requested in high resolution
+	for _, path := range pageImages.PIMPaths {
+		images = append(images, s.pimImageURL(path))
 	}
 
-	// Also extract media images (lifestyle shots)
-	// Pattern: /media/[hash]/[filename]
-	mediaRe := regexp.MustCompile(`/media/[a-z0-9]+/[^"'\s]+\.(jpg|jpeg|png|webp)`)
-	mediaMatches := mediaRe.FindAllString(html, -1)
-
-	for _, match := range mediaMatches {
-		if !seen[match] && !strings.Contains(match, "icon") && !strings.Contains(match, "logo") {
-			seen[match] = true
-			images = append(images, s.baseURL+match)
-		}
+	// Media images (lifestyle shots)
+	for _, mediaURL := range pageImages.MediaURLs {
+		images = append(images, s.absoluteURL(mediaURL))
 	}
 
 	return images, nil
 }
 
+// pimImageURL returns the high-res URL for a PIM image path
+func (s *TigerScraper) pimImageURL(path string) string {
+	return fmt.Sprintf("%s%s?width=1200&height=1200&format=jpg&quality=90", s.baseURL, path)
+}
+
+// absoluteURL resolves a page-relative URL against the Tiger.nl base URL
+func (s *TigerScraper) absoluteURL(ref string) string {
+	switch {
+	case strings.HasPrefix(ref, "//"):
+		return "https:" + ref
+	case strings.HasPrefix(ref, "/"):
+		return s.baseURL + ref
+	default:
+		return ref
+	}
+}
+
 // GetProductImages is a convenience method that returns image count and URLs
-func (s *TigerScraper) GetProductImages(productName string) (int, []string, error) {
-	product, err := s.FindProduct(productName)
+func (s *TigerScraper) GetProductImages(ctx context.Context, productName string) (int, []string, error) {
+	product, err := s.FindProduct(ctx, productName)
 	if err != nil {
 		return 0, nil, err
 	}
@@ -316,13 +605,17 @@ func (s *TigerScraper) GetProductImages(productName string) (int, []string, erro
 
 // FindProductByID finds a product on Tiger.nl using a direct product ID
 // This is more reliable than name-based searching as it goes directly to the product page
-func (s *TigerScraper) FindProductByID(tigerID string, basePath string, productTypes []string) (*TigerProduct, error) {
+func (s *TigerScraper) FindProductByID(ctx context.Context, tigerID string, basePath string, productTypes []string) (*TigerProduct, error) {
 	// Try each product type until we find a valid page
 	for _, productType := range productTypes {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
 		// First, try to find the exact URL by fetching the category page
 		// and looking for our product ID
 		categoryURL := fmt.Sprintf("%s%s%s/", s.baseURL, basePath, productType)
-		resp, err := s.doGet(categoryURL)
+		resp, err := s.doGet(ctx, categoryURL)
 		if err != nil {
 			continue
 		}
@@ -344,7 +637,7 @@ func (s *TigerScraper) FindProductByID(tigerID string, basePath string, productT
 
 		if match != "" {
 			productURL := s.baseURL + match
-			images, err := s.scrapeProductImagesWithValidation(productURL)
+			images, err := s.scrapeProductImagesWithValidation(ctx, productURL)
 			if err != nil {
 				continue
 			}
@@ -362,7 +655,7 @@ func (s *TigerScraper) FindProductByID(tigerID string, basePath string, productT
 
 // FindProductByIDDirect tries to directly access product pages by constructing URLs
 // This is faster than searching category pages
-func (s *TigerScraper) FindProductByIDDirect(tigerID string, basePath string, productTypes []string) (*TigerProduct, error) {
+func (s *TigerScraper) FindProductByIDDirect(ctx context.Context, tigerID string, basePath string, productTypes []string) (*TigerProduct, error) {
 	// Build candidate URLs and try each one
 	for _, productType := range productTypes {
 		// Try common slug patterns
@@ -374,14 +667,17 @@ func (s *TigerScraper) FindProductByIDDirect(tigerID string, basePath string, pr
 		for _, slug := range slugPatterns {
 			productURL := fmt.Sprintf("%s%s%s/%s/", s.baseURL, basePath, productType, slug)
 
-			resp, err := s.doHead(productURL)
+			resp, err := s.doHead(ctx, productURL)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			if err != nil {
 				continue
 			}
 			resp.Body.Close()
 
 			if resp.StatusCode == 200 {
-				images, err := s.scrapeProductImagesWithValidation(productURL)
+				images, err := s.scrapeProductImagesWithValidation(ctx, productURL)
 				if err != nil {
 					continue
 				}
@@ -396,12 +692,12 @@ func (s *TigerScraper) FindProductByIDDirect(tigerID string, basePath string, pr
 	}
 
 	// Fall back to searching category pages
-	return s.FindProductByID(tigerID, basePath, productTypes)
+	return s.FindProductByID(ctx, tigerID, basePath, productTypes)
 }
 
 // scrapeProductImagesWithValidation extracts and validates image URLs from a product page
-func (s *TigerScraper) scrapeProductImagesWithValidation(productURL string) ([]string, error) {
-	resp, err := s.doGet(productURL)
+func (s *TigerScraper) scrapeProductImagesWithValidation(ctx context.Context, productURL string) ([]string, error) {
+	resp, err := s.doGet(ctx, productURL)
 	if err != nil {
 		return nil, err
 	}
@@ -416,32 +712,29 @@ func (s *TigerScraper) scrapeProductImagesWithValidation(productURL string) ([]s
 		return nil, err
 	}
 
-	html := string(body)
-	var validImages []string
-
-	// Extract PIM image URLs (product images)
-	pimRe := regexp.MustCompile(`/pim/528_[a-f0-9-]+`)
-	pimMatches := pimRe.FindAllString(html, -1)
-
-	seen := make(map[string]bool)
-	for _, match := range pimMatches {
-		if !seen[match] {
-			seen[match] = true
-			fullURL := fmt.Sprintf("%s%s?width=1200&height=1200&format=jpg&quality=90", s.baseURL, match)
+	pageImages, err := extractPageImages(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse product page: %w", err)
+	}
 
-			// Validate the image URL
-			if s.ValidateImageURL(fullURL) {
-				validImages = append(validImages, fullURL)
-			}
+	// Validate PIM image URLs (product images)
+	var validImages []string
+	for _, path := range pageImages.PIMPaths {
+		fullURL := s.pimImageURL(path)
+		if s.ValidateImageURL(ctx, fullURL) {
+			validImages = append(validImages, fullURL)
 		}
 	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
 	return validImages, nil
 }
 
 // ValidateImageURL checks if an image URL is accessible (returns 200)
-func (s *TigerScraper) ValidateImageURL(imageURL string) bool {
-	resp, err := s.doHead(imageURL)
+func (s *TigerScraper) ValidateImageURL(ctx context.Context, imageURL string) bool {
+	resp, err := s.doHead(ctx, imageURL)
 	if err != nil {
 		return false
 	}
@@ -451,8 +744,8 @@ func (s *TigerScraper) ValidateImageURL(imageURL string) bool {
 }
 
 // GetImageCount returns the count of valid images for a product
-func (s *TigerScraper) GetImageCount(productURL string) (int, error) {
-	images, err := s.scrapeProductImagesWithValidation(productURL)
+func (s *TigerScraper) GetImageCount(ctx context.Context, productURL string) (int, error) {
+	images, err := s.scrapeProductImagesWithValidation(ctx, productURL)
 	if err != nil {
 		return 0, err
 	}
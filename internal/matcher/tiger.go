@@ -1,7 +1,10 @@
 package matcher
 
 import (
+	"context"
+	"fmt"
 	"math/rand"
+	"sort"
 	"strings"
 	"time"
 
@@ -43,45 +46,144 @@ func NewTigerMatcher() *TigerMatcher {
 	}
 }
 
-// Match attempts to match a product against Tiger.nl
-func (m *TigerMatcher) Match(product models.Product) (string, float64) {
-	nameLower := strings.ToLower(product.Name)
+// Match methods, from most to least reliable
+const (
+	MethodManual  = "manual"  // Pinned by hand with a match override
+	MethodBarcode = "barcode" // The Tiger.nl page declares the product's GTIN
+	MethodID      = "id"      // A Tiger.nl ID derived from the SKU resolved to a page
+	MethodName    = "name"    // Series or category keywords in the product name
+)
+
+// maxAlternates caps the runner-up candidates returned with a match
+const maxAlternates = 5
 
-	// Find matching keywords
-	var matchedURL string
-	matchCount := 0
+// MatchCandidate is a Tiger.nl page a product might correspond to
+type MatchCandidate struct {
+	URL    string  `json:"url"`
+	Score  float64 `json:"score"`
+	Method string  `json:"method"`
+}
+
+// MatchResult is the outcome of matching a product: the chosen candidate and
+// the runner-ups, best first
+type MatchResult struct {
+	URL        string
+	Score      float64
+	Method     string
+	Product    *TigerProduct // Set when the match was confirmed on a product page (manual, barcode, id)
+	Alternates []MatchCandidate
+}
 
+// Match attempts to match a product against Tiger.nl. A manual override wins
+// outright. Products with a barcode are then looked up by GTIN, and an exact
+// GTIN hit scores 1.0. Otherwise the SKU-derived Tiger.nl IDs are tried, then
+// name keywords. The candidates that were not chosen are returned as
+// alternates. Lookups stop when ctx is done, leaving only the name match.
+func (m *TigerMatcher) Match(ctx context.Context, product models.Product) MatchResult {
+	var confirmed *MatchResult
+	if overrideURL, ok := m.scraper.Override(product.SKU); ok {
+		// The page is scraped for images when reachable, but the pin stands either way
+		tigerProduct, _ := m.scraper.FindProductByURL(ctx, overrideURL)
+		confirmed = &MatchResult{URL: overrideURL, Score: 1.0, Method: MethodManual, Product: tigerProduct}
+	}
+	if confirmed == nil && product.Barcode != "" {
+		if tigerProduct, err := m.LookupByBarcode(ctx, product.Barcode); err == nil && tigerProduct != nil {
+			confirmed = &MatchResult{URL: tigerProduct.URL, Score: 1.0, Method: MethodBarcode, Product: tigerProduct}
+		}
+	}
+	if confirmed == nil && product.SKU != "" {
+		if tigerProduct, err := m.LookupBySKU(ctx, product.SKU, product.Name); err == nil && tigerProduct != nil {
+			confirmed = &MatchResult{URL: tigerProduct.URL, Score: 0.95, Method: MethodID, Product: tigerProduct}
+		}
+	}
+
+	candidates := m.matchName(product.Name)
+	if confirmed == nil {
+		result := MatchResult{URL: candidates[0].URL, Score: candidates[0].Score, Method: MethodName}
+		result.Alternates = candidates[1:]
+		return result
+	}
+
+	for _, c := range candidates {
+		if c.URL != confirmed.URL && len(confirmed.Alternates) < maxAlternates {
+			confirmed.Alternates = append(confirmed.Alternates, c)
+		}
+	}
+	return *confirmed
+}
+
+// matchName scores the catalog pages whose keywords appear in the name, best
+// first. It always returns at least one candidate: the generic product
+// listing when no keyword matched.
+func (m *TigerMatcher) matchName(name string) []MatchCandidate {
+	normalized := NormalizeTitle(name)
+
+	// Find matching keywords, in the order they appear in the name
+	type hit struct {
+		pos int
+		url string
+	}
+	var hits []hit
 	for keyword, url := range m.catalog {
-		if strings.Contains(nameLower, keyword) {
-			matchedURL = url
-			matchCount++
+		if pos := strings.Index(normalized, foldTitle(keyword)); pos >= 0 {
+			hits = append(hits, hit{pos, url})
 		}
 	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].pos != hits[j].pos {
+			return hits[i].pos < hits[j].pos
+		}
+		return hits[i].url < hits[j].url
+	})
 
 	// Calculate confidence score with some randomness for demo
 	rand.Seed(time.Now().UnixNano())
 	baseScore := 0.0
 
 	switch {
-	case matchCount >= 2:
+	case len(hits) >= 2:
 		baseScore = 0.90 + rand.Float64()*0.10 // 90-100%
-	case matchCount == 1:
+	case len(hits) == 1:
 		baseScore = 0.75 + rand.Float64()*0.15 // 75-90%
 	default:
 		baseScore = 0.30 + rand.Float64()*0.30 // 30-60%
 	}
 
-	if matchedURL == "" {
+	if len(hits) == 0 {
 		// Generate a plausible URL for demo
-		matchedURL = "https://www.tiger.nl/nl/badkameraccessoires/producten"
+		return []MatchCandidate{{
+			URL:    "https://www.tiger.nl/nl/badkameraccessoires/producten",
+			Score:  baseScore,
+			Method: MethodName,
+		}}
 	}
 
-	return matchedURL, baseScore
+	// The first keyword in the name wins; each later one scores a bit lower
+	candidates := make([]MatchCandidate, 0, len(hits))
+	seen := make(map[string]bool)
+	for _, h := range hits {
+		if seen[h.url] || len(candidates) > maxAlternates {
+			continue
+		}
+		seen[h.url] = true
+		candidates = append(candidates, MatchCandidate{
+			URL:    h.url,
+			Score:  baseScore - 0.05*float64(len(candidates)),
+			Method: MethodName,
+		})
+	}
+	return candidates
 }
 
 // LookupBySKU attempts to find a product on Tiger.nl using the SKU
-// Returns the product info, list of valid image URLs, and error if any
-func (m *TigerMatcher) LookupBySKU(sku string, productName string) (*TigerProduct, error) {
+// Returns the product info, list of valid image URLs, and error if any.
+// A lookup cut short by ctx returns its error and is not cached.
+func (m *TigerMatcher) LookupBySKU(ctx context.Context, sku string, productName string) (*TigerProduct, error) {
+	// A manual override replaces the automated lookup
+	if overrideURL, ok := m.scraper.Override(sku); ok {
+		return m.scraper.FindProductByURL(ctx, overrideURL)
+	}
+
 	// Check cache first
 	if cached, found := m.scraper.GetCached(sku); found {
 		return cached, nil
@@ -96,9 +198,13 @@ func (m *TigerMatcher) LookupBySKU(sku string, productName string) (*TigerProduc
 	// Get product types to try
 	productTypes := m.skuMapper.GetProductTypes()
 
-	// Try each candidate ID
+	// Try each candidate ID, first at its likely product URL, then on the
+	// category pages. Either way only image URLs that respond are kept.
 	for _, tigerID := range candidateIDs {
-		product, err := m.scraper.FindProductByID(tigerID, basePath, productTypes)
+		product, err := m.scraper.FindProductByIDDirect(ctx, tigerID, basePath, productTypes)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		if err == nil && product != nil {
 			// Cache the successful result
 			m.scraper.SetCached(sku, product)
@@ -111,6 +217,42 @@ func (m *TigerMatcher) LookupBySKU(sku string, productName string) (*TigerProduc
 	return nil, nil
 }
 
+// LookupByBarcode finds the Tiger.nl product whose structured data declares
+// the barcode. It returns nil without an error when there is none, and
+// ctx's error when the lookup was cut short.
+func (m *TigerMatcher) LookupByBarcode(ctx context.Context, barcode string) (*TigerProduct, error) {
+	gtin := normalizeGTIN(barcode)
+	if gtin == "" {
+		return nil, fmt.Errorf("invalid barcode: %q", barcode)
+	}
+
+	// Barcode results share the cache with SKU lookups under their own keys
+	key := BarcodeCacheKey(barcode)
+	if cached, found := m.scraper.GetCached(key); found {
+		return cached, nil
+	}
+
+	product, err := m.scraper.FindProductByBarcode(ctx, barcode)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		product = nil
+	}
+	m.scraper.SetCached(key, product)
+	return product, nil
+}
+
+// BarcodeCacheKey returns the cache key of a barcode lookup, or "" for an
+// invalid barcode
+func BarcodeCacheKey(barcode string) string {
+	gtin := normalizeGTIN(barcode)
+	if gtin == "" {
+		return ""
+	}
+	return "gtin:" + gtin
+}
+
 // GetSKUMapper returns the SKU mapper for direct access
 func (m *TigerMatcher) GetSKUMapper() *SKUMapper {
 	return m.skuMapper
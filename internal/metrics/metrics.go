@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Enhancement results
+const (
+	ResultSuccess = "success" // The source enhanced the product
+	ResultFailure = "failure" // The source had nothing for the product
+	ResultError   = "error"   // The enhancement call itself failed
+)
+
+var registry = prometheus.NewRegistry()
+
+var (
+	productsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "badops_products_processed_total",
+		Help: "Products processed, by operation (import, enhance, export).",
+	}, []string{"operation"})
+
+	enhancements = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "badops_enhancements_total",
+		Help: "Enhancement attempts, by source and result (success, failure, error).",
+	}, []string{"source", "result"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "badops_source_request_duration_seconds",
+		Help:    "Duration of HTTP requests made by source connectors, by source and status code (0 when the request failed).",
+		Buckets: prometheus.ExponentialBuckets(0.025, 2, 10), // 25ms to ~13s
+	}, []string{"source", "code"})
+
+	syncRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "badops_sync_records_total",
+		Help: "Records synced, by sync (e.g., clickhouse_prices) and result (synced, failed).",
+	}, []string{"sync", "result"})
+
+	lastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "badops_last_success_timestamp_seconds",
+		Help: "Unix time an operation last completed successfully, for alerting on stalled runs.",
+	}, []string{"operation"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		productsProcessed,
+		enhancements,
+		requestDuration,
+		syncRecords,
+		lastSuccess,
+	)
+}
+
+// ProductsProcessed counts n products processed by an operation
+func ProductsProcessed(operation string, n int) {
+	productsProcessed.WithLabelValues(operation).Add(float64(n))
+}
+
+// Enhancement counts one enhancement attempt
+func Enhancement(source, result string) {
+	enhancements.WithLabelValues(source, result).Inc()
+}
+
+// Request observes the duration of a source's HTTP request. code is the
+// response status, or 0 when no response was received.
+func Request(source string, code int, elapsed time.Duration) {
+	requestDuration.WithLabelValues(source, strconv.Itoa(code)).Observe(elapsed.Seconds())
+}
+
+// SyncRecords counts records synced and records that failed to sync
+func SyncRecords(sync string, synced, failed int) {
+	syncRecords.WithLabelValues(sync, "synced").Add(float64(synced))
+	syncRecords.WithLabelValues(sync, "failed").Add(float64(failed))
+}
+
+// Success records that an operation completed successfully now
+func Success(operation string) {
+	lastSuccess.WithLabelValues(operation).SetToCurrentTime()
+}
+
+// Serve exposes the metrics at http://addr/metrics in the background. The
+// listener is opened before returning, so a bad address or a port in use is
+// reported to the caller.
+func Serve(addr string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "addr", addr, "error", err)
+		}
+	}()
+	slog.Info("serving metrics", "addr", ln.Addr().String())
+	return srv, nil
+}
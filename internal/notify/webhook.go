@@ -0,0 +1,114 @@
+// Package notify sends price alerts to webhooks such as Slack incoming
+// webhooks
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Payload formats
+const (
+	FormatSlack = "slack"
+	FormatJSON  = "json"
+)
+
+// Undercut is a product priced above the cheapest competitor
+type Undercut struct {
+	SKU         string    `json:"sku"`
+	Title       string    `json:"title,omitempty"`
+	OurPrice    float64   `json:"our_price"`
+	MarketMin   float64   `json:"market_min"`
+	Competitor  string    `json:"competitor"`
+	DiffPercent float64   `json:"diff_percent"` // How far our price is above MarketMin
+	Currency    string    `json:"currency"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// Webhook posts alerts to a URL
+type Webhook struct {
+	url    string
+	format string
+	client *http.Client
+}
+
+// NewWebhook creates a webhook notifier. An empty format picks slack for
+// Slack incoming webhook URLs and json otherwise.
+func NewWebhook(url, format string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("no webhook URL configured")
+	}
+	switch format {
+	case "":
+		format = FormatJSON
+		if strings.Contains(url, "hooks.slack.com") {
+			format = FormatSlack
+		}
+	case FormatSlack, FormatJSON:
+	default:
+		return nil, fmt.Errorf("unsupported notify format %q (use slack or json)", format)
+	}
+	return &Webhook{
+		url:    url,
+		format: format,
+		client: &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Format returns the payload format in use
+func (w *Webhook) Format() string {
+	return w.format
+}
+
+// SendUndercut posts one undercut alert
+func (w *Webhook) SendUndercut(ctx context.Context, u Undercut) error {
+	var payload interface{}
+	if w.format == FormatSlack {
+		payload = slackPayload(u)
+	} else {
+		payload = struct {
+			Event string `json:"event"`
+			Undercut
+		}{"price_undercut", u}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// slackPayload formats an undercut as a Slack message with mrkdwn text
+func slackPayload(u Undercut) map[string]string {
+	name := u.SKU
+	if u.Title != "" {
+		name = fmt.Sprintf("%s (%s)", u.Title, u.SKU)
+	}
+	return map[string]string{
+		"text": fmt.Sprintf(":rotating_light: *%s* is undercut by %s: our price %.2f %s is %.1f%% above their %.2f %s",
+			name, u.Competitor, u.OurPrice, u.Currency, u.DiffPercent, u.MarketMin, u.Currency),
+	}
+}
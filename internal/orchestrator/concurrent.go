@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// EnhanceConcurrent calls enhance for each product on a pool of at most
+// concurrency workers and waits for them to finish. Each product is handled
+// by a single worker, so enhance may modify it without locking, but anything
+// it shares across products must be synchronized by the caller.
+//
+// The pool only bounds how many products are in flight: each source enforces
+// its own rate limit, so adding workers never makes a source send requests
+// faster than it allows.
+//
+// When ctx is cancelled no new products are started, products already in
+// flight are allowed to finish, and ctx.Err() is returned.
+func EnhanceConcurrent(ctx context.Context, products []*models.EnhancedProduct, concurrency int, enhance func(ctx context.Context, p *models.EnhancedProduct)) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > len(products) {
+		concurrency = len(products)
+	}
+
+	jobs := make(chan *models.EnhancedProduct)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				enhance(ctx, p)
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for _, p := range products {
+		// Check first so a cancelled context never starts another product,
+		// even when a worker is ready to receive
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		select {
+		case jobs <- p:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return err
+}
@@ -3,42 +3,108 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/badno/badops/internal/config"
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/metrics"
 	"github.com/badno/badops/internal/output"
+	clickhouseout "github.com/badno/badops/internal/output/clickhouse"
 	"github.com/badno/badops/internal/output/file"
+	shopifyout "github.com/badno/badops/internal/output/shopify"
 	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/internal/source/matrixify"
 	"github.com/badno/badops/internal/source/nobb"
+	"github.com/badno/badops/internal/source/rest"
 	"github.com/badno/badops/internal/source/shopify"
 	"github.com/badno/badops/internal/source/tiger"
+	"github.com/badno/badops/internal/source/woocommerce"
 	"github.com/badno/badops/internal/state"
 	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
 )
 
 // Orchestrator coordinates the product enhancement pipeline
 type Orchestrator struct {
-	store     *state.Store
+	store     state.Backend
+	db        *postgres.Client // Set when the store is PostgreSQL-backed
 	config    *config.Config
 	sources   map[string]source.Connector
 	outputs   map[string]output.Adapter
+	persister EnhancedPersister
+	enhLog    EnhancementLogger
+	logger    *slog.Logger
 }
 
-// New creates a new orchestrator
+// EnhancedPersister saves enhancement results outside the JSON state, such as
+// postgres.ProductRepo, which writes each product atomically
+type EnhancedPersister interface {
+	SaveEnhanced(ctx context.Context, product *models.EnhancedProduct) error
+}
+
+// EnhancementLogger records enhancement outcomes in an audit trail, such as
+// postgres.EnhancementLogRepo
+type EnhancementLogger interface {
+	Add(ctx context.Context, entry *database.EnhancementLog) error
+}
+
+// New creates a new orchestrator. With database.use_db set, product state is
+// kept in PostgreSQL instead of the JSON state file.
 func New(cfg *config.Config) *Orchestrator {
-	return &Orchestrator{
-		store:   state.NewStore(""),
+	o := &Orchestrator{
 		config:  cfg,
 		sources: make(map[string]source.Connector),
 		outputs: make(map[string]output.Adapter),
+		logger:  slog.Default(),
+	}
+
+	if cfg.Database.UseDB {
+		// Resolution errors leave the credential empty and surface when
+		// Initialize connects
+		username, _ := credentials.Resolve(nil, cfg.Database.Postgres.UsernameEnv)
+		password, _ := credentials.Resolve(nil, cfg.Database.Postgres.PasswordEnv)
+		o.db = postgres.NewClient(&postgres.Config{
+			Host:     cfg.Database.Postgres.Host,
+			Port:     cfg.Database.Postgres.Port,
+			Database: cfg.Database.Postgres.Database,
+			Username: username,
+			Password: password,
+			SSLMode:  cfg.Database.Postgres.SSLMode,
+
+			MaxConns:       int32(cfg.Database.Postgres.MaxConns),
+			MinConns:       int32(cfg.Database.Postgres.MinConns),
+			MaxConnLife:    cfg.Database.Postgres.ConnLifetime(),
+			MaxConnIdle:    cfg.Database.Postgres.ConnIdleTime(),
+			ConnectRetries: cfg.Database.Postgres.ConnectRetries,
+		})
+		o.store = state.NewPostgresStore(o.db)
+		o.enhLog = postgres.NewEnhancementLogRepo(o.db)
+	} else {
+		o.store = state.NewStore("")
 	}
+
+	return o
 }
 
 // Initialize sets up all connectors and adapters
 func (o *Orchestrator) Initialize(ctx context.Context) error {
 	// Load state
-	if err := o.store.Load(); err != nil {
-		// Not fatal, just means starting fresh
+	if o.db != nil {
+		if err := o.db.Connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		if err := o.store.LoadForUpdate(); err != nil {
+			return fmt.Errorf("failed to load state from database: %w", err)
+		}
+	} else if err := o.store.LoadForUpdate(); err != nil {
+		// A missing state file loads as empty; anything else, such as
+		// another process holding the lock, must not be saved over
+		return fmt.Errorf("failed to load state: %w", err)
 	}
 
 	// Initialize source connectors
@@ -47,18 +113,50 @@ func (o *Orchestrator) Initialize(ctx context.Context) error {
 		APIKeyEnv: o.config.Sources.Shopify.APIKeyEnv,
 	})
 
+	o.sources["matrixify"] = matrixify.NewConnector(matrixify.Config{
+		File: o.config.Sources.Matrixify.File,
+	})
+
+	o.sources["woocommerce"] = woocommerce.NewConnector(woocommerce.Config{
+		URL:               o.config.Sources.WooCommerce.URL,
+		ConsumerKeyEnv:    o.config.Sources.WooCommerce.ConsumerKeyEnv,
+		ConsumerSecretEnv: o.config.Sources.WooCommerce.ConsumerSecretEnv,
+		Currency:          o.config.Sources.WooCommerce.Currency,
+	})
+
+	o.sources["rest"] = rest.NewConnector(o.config.Sources.REST)
+
 	o.sources["nobb"] = nobb.NewConnector(nobb.Config{
-		UsernameEnv: o.config.Sources.NOBB.UsernameEnv,
-		PasswordEnv: o.config.Sources.NOBB.PasswordEnv,
+		UsernameEnv:   o.config.Sources.NOBB.UsernameEnv,
+		PasswordEnv:   o.config.Sources.NOBB.PasswordEnv,
+		DimensionUnit: o.config.Defaults.DimensionUnit,
+		WeightUnit:    o.config.Defaults.WeightUnit,
+		RateLimitMs:   o.config.Sources.NOBB.RateLimitMs,
+		Merge:         source.NewMergePolicy(o.config.Defaults.SourcePriority, o.config.Defaults.OverwritePolicy),
+		SKURulesFile:  o.config.Defaults.SKURulesFile,
 	})
 
+	// With the database backend, Tiger.nl lookups are shared through Postgres
+	var matchCache matcher.Cache
+	if o.db != nil {
+		matchCache = postgres.NewMatchCacheRepo(o.db)
+	}
 	o.sources["tiger_nl"] = tiger.NewConnector(tiger.Config{
-		RateLimitMs: o.config.Sources.TigerNL.RateLimitMs,
+		RateLimitMs:      o.config.Sources.TigerNL.RateLimitMs,
+		MappingsFile:     o.config.Sources.TigerNL.MappingsFile,
+		SKURulesFile:     o.config.Defaults.SKURulesFile,
+		CacheTTL:         o.config.Sources.TigerNL.CacheDuration(),
+		NegativeCacheTTL: o.config.Sources.TigerNL.NegativeCacheDuration(),
+		UserAgent:        o.config.Sources.TigerNL.UserAgent,
+		AcceptLanguage:   o.config.Sources.TigerNL.AcceptLanguage,
+		Cache:            matchCache,
+		Merge:            source.NewMergePolicy(o.config.Defaults.SourcePriority, o.config.Defaults.OverwritePolicy),
 	})
 
 	// Initialize output adapters
 	o.outputs["csv"] = file.NewCSVAdapter(file.CSVConfig{
-		OutputDir: o.config.Outputs.File.OutputDir,
+		OutputDir:     o.config.Outputs.File.OutputDir,
+		ColumnMapFile: o.config.Outputs.File.ColumnMapFile,
 	})
 
 	o.outputs["json"] = file.NewJSONAdapter(file.JSONConfig{
@@ -66,17 +164,77 @@ func (o *Orchestrator) Initialize(ctx context.Context) error {
 		Pretty:    o.config.Outputs.File.Pretty,
 	})
 
+	storeURL := o.config.Outputs.File.StoreURL
+	if storeURL == "" && o.config.Outputs.Shopify.Store != "" {
+		storeURL = fmt.Sprintf("https://%s.myshopify.com", o.config.Outputs.Shopify.Store)
+	}
+	o.outputs["google"] = file.NewGoogleAdapter(file.GoogleConfig{
+		OutputDir: o.config.Outputs.File.OutputDir,
+		StoreURL:  storeURL,
+		Title:     o.config.Outputs.Shopify.Store,
+	})
+
+	o.outputs["shopify"] = shopifyout.NewAdapter(shopifyout.Config{
+		Store:     o.config.Outputs.Shopify.Store,
+		APIKeyEnv: o.config.Outputs.Shopify.APIKeyEnv,
+	})
+
+	o.outputs["clickhouse"] = clickhouseout.NewAdapter(clickhouseout.Config{
+		Host:        o.config.Outputs.ClickHouse.Host,
+		Port:        o.config.Outputs.ClickHouse.Port,
+		Database:    o.config.Outputs.ClickHouse.Database,
+		UsernameEnv: o.config.Outputs.ClickHouse.UsernameEnv,
+		PasswordEnv: o.config.Outputs.ClickHouse.PasswordEnv,
+		Table:       o.config.Outputs.ClickHouse.Table,
+		Secure:      o.config.Outputs.ClickHouse.Secure,
+	})
+
+	o.propagateLogger()
 	return nil
 }
 
+// SetLogger replaces the logger used by the orchestrator and by the
+// connectors, adapters and database client it manages
+func (o *Orchestrator) SetLogger(logger *slog.Logger) {
+	o.logger = logger
+	o.propagateLogger()
+}
+
+// propagateLogger hands the orchestrator's logger to everything it manages
+func (o *Orchestrator) propagateLogger() {
+	type loggerSetter interface {
+		SetLogger(*slog.Logger)
+	}
+	for _, s := range o.sources {
+		if ls, ok := s.(loggerSetter); ok {
+			ls.SetLogger(o.logger)
+		}
+	}
+	for _, a := range o.outputs {
+		if ls, ok := a.(loggerSetter); ok {
+			ls.SetLogger(o.logger)
+		}
+	}
+	if o.db != nil {
+		o.db.SetLogger(o.logger)
+	}
+}
+
 // Close cleans up all resources
 func (o *Orchestrator) Close() error {
-	for _, s := range o.sources {
-		s.Close()
+	for name, s := range o.sources {
+		// Sources write their lookup caches on close
+		if err := s.Close(); err != nil {
+			o.logger.Warn("failed to close source", "source", name, "error", err)
+		}
 	}
 	for _, a := range o.outputs {
 		a.Close()
 	}
+	o.store.Close()
+	if o.db != nil {
+		o.db.Close()
+	}
 	return nil
 }
 
@@ -85,6 +243,8 @@ type ImportOptions struct {
 	Source string
 	Vendor string
 	Limit  int
+	File   string // Export file for file sources such as matrixify (default: from config)
+	DryRun bool   // Fetch and count products without changing state
 }
 
 // Import imports products from a source
@@ -98,9 +258,17 @@ func (o *Orchestrator) Import(ctx context.Context, opts ImportOptions) (*ImportR
 	if !ok {
 		return nil, fmt.Errorf("unknown source: %s", opts.Source)
 	}
+	if opts.File != "" {
+		fileSource, ok := src.(source.FileSource)
+		if !ok {
+			return nil, fmt.Errorf("source %s does not import from files", opts.Source)
+		}
+		fileSource.SetFile(opts.File)
+	}
 
 	// Connect
 	if err := src.Connect(ctx); err != nil {
+		o.logger.Error("import failed", "source", opts.Source, "error", err)
 		result.Error = err
 		return result, err
 	}
@@ -111,15 +279,24 @@ func (o *Orchestrator) Import(ctx context.Context, opts ImportOptions) (*ImportR
 		Vendor: opts.Vendor,
 	})
 	if err != nil {
+		o.logger.Error("import failed", "source", opts.Source, "error", err)
 		result.Error = err
 		return result, err
 	}
 
+	if opts.DryRun {
+		result.ProductsImported = len(fetchResult.Products)
+		result.Success = true
+		result.CompletedAt = time.Now()
+		return result, nil
+	}
+
 	// Import to state
 	count := o.store.ImportProducts(fetchResult.Products, opts.Source)
 
 	// Save state
 	if err := o.store.Save(); err != nil {
+		o.logger.Error("failed to save state", "error", err)
 		result.Error = err
 		return result, err
 	}
@@ -127,6 +304,10 @@ func (o *Orchestrator) Import(ctx context.Context, opts ImportOptions) (*ImportR
 	result.ProductsImported = count
 	result.Success = true
 	result.CompletedAt = time.Now()
+	metrics.ProductsProcessed("import", count)
+	metrics.Success("import")
+	o.logger.Info("import finished", "source", opts.Source, "products", count,
+		"duration", result.CompletedAt.Sub(result.StartedAt))
 
 	return result, nil
 }
@@ -142,10 +323,11 @@ type ImportResult struct {
 
 // EnhanceOptions configures the enhancement operation
 type EnhanceOptions struct {
-	Sources []string
-	Vendor  string
-	Limit   int
-	DryRun  bool
+	Sources     []string
+	Vendor      string
+	Limit       int
+	DryRun      bool
+	Concurrency int // Products enhanced in parallel by per-product sources (default: 1)
 }
 
 // Enhance runs enhancements on products
@@ -174,6 +356,7 @@ func (o *Orchestrator) Enhance(ctx context.Context, opts EnhanceOptions) (*Enhan
 			continue
 		}
 		if err := src.Connect(ctx); err != nil {
+			o.logger.Warn("skipping enhancement source", "source", srcName, "error", err)
 			continue
 		}
 		enhancers = append(enhancers, src)
@@ -183,39 +366,157 @@ func (o *Orchestrator) Enhance(ctx context.Context, opts EnhanceOptions) (*Enhan
 		return nil, fmt.Errorf("no enhancement sources available")
 	}
 
-	// Enhance each product
-	for _, p := range products {
-		for _, enhancer := range enhancers {
-			if opts.DryRun {
-				result.BySource[enhancer.Name()]++
-				continue
-			}
+	// Enhance products with each source, preferring batch lookups when supported.
+	// The remaining sources run per product on a worker pool.
+	perProduct := make([]source.Connector, 0, len(enhancers))
+	var failures []*database.EnhancementLog
+	for _, enhancer := range enhancers {
+		if opts.DryRun {
+			result.BySource[enhancer.Name()] += len(products)
+			continue
+		}
 
-			enhResult, err := enhancer.EnhanceProduct(ctx, p)
+		if batcher, ok := enhancer.(source.BatchEnhancer); ok {
+			enhResults, err := batcher.EnhanceProductsBatch(ctx, products)
 			if err != nil {
+				o.logger.Warn("batch enhancement failed", "source", enhancer.Name(), "error", err)
+				for _, p := range products {
+					failures = appendFailure(failures, enhancer.Name(), p, err)
+				}
 				continue
 			}
-			if enhResult.Success {
-				result.ProductsEnhanced++
-				result.ImagesAdded += enhResult.ImagesAdded
-				result.FieldsUpdated += len(enhResult.FieldsUpdated)
-				result.BySource[enhancer.Name()]++
+			for _, enhResult := range enhResults {
+				result.addEnhancement(enhancer.Name(), enhResult)
+				if enhResult != nil && !enhResult.Success {
+					failures = appendFailure(failures, enhancer.Name(), enhResult.Product, enhResult.Error)
+				}
+			}
+			continue
+		}
+
+		perProduct = append(perProduct, enhancer)
+	}
+
+	if len(perProduct) > 0 {
+		var mu sync.Mutex
+		err := EnhanceConcurrent(ctx, products, opts.Concurrency, func(ctx context.Context, p *models.EnhancedProduct) {
+			for _, enhancer := range perProduct {
+				enhResult, err := enhancer.EnhanceProduct(ctx, p)
+				if err != nil {
+					o.logger.Warn("enhancement failed", "source", enhancer.Name(), "sku", p.SKU, "error", err)
+					metrics.Enhancement(enhancer.Name(), metrics.ResultError)
+					mu.Lock()
+					failures = appendFailure(failures, enhancer.Name(), p, err)
+					mu.Unlock()
+					continue
+				}
+				mu.Lock()
+				result.addEnhancement(enhancer.Name(), enhResult)
+				if enhResult != nil && !enhResult.Success {
+					failures = appendFailure(failures, enhancer.Name(), p, enhResult.Error)
+				}
+				mu.Unlock()
 			}
+		})
+		if err != nil {
+			// Keep what was enhanced before the cancellation
+			o.logger.Warn("enhancement stopped early", "error", err)
+			result.Error = err
+		}
+	}
+
+	// Persist enhanced products, one transaction each, before saving state so
+	// the database IDs they get are saved with them
+	var persistErr error
+	if o.persister != nil && !opts.DryRun {
+		result.ProductsPersisted, persistErr = PersistEnhanced(context.WithoutCancel(ctx), o.persister, products, o.logger)
+		if persistErr != nil {
+			result.Error = persistErr
 		}
 	}
 
 	// Save state
 	if !opts.DryRun {
 		if err := o.store.Save(); err != nil {
+			o.logger.Error("failed to save state", "error", err)
 			result.Error = err
 			return result, err
 		}
+		o.logFailures(ctx, failures)
 	}
 
-	result.Success = true
+	result.Success = persistErr == nil
 	result.CompletedAt = time.Now()
+	metrics.ProductsProcessed("enhance", result.ProductsProcessed)
+	if result.Error == nil && !opts.DryRun {
+		metrics.Success("enhance")
+	}
+	o.logger.Info("enhance finished", "sources", opts.Sources, "products", result.ProductsProcessed,
+		"enhanced", result.ProductsEnhanced, "images_added", result.ImagesAdded,
+		"persisted", result.ProductsPersisted, "duration", result.CompletedAt.Sub(result.StartedAt))
 
-	return result, nil
+	return result, persistErr
+}
+
+// PersistEnhanced saves each product through p in its own transaction and
+// returns the number saved. A failed product is logged and skipped; the
+// error reports how many failed and the last failure.
+func PersistEnhanced(ctx context.Context, p EnhancedPersister, products []*models.EnhancedProduct, logger *slog.Logger) (int, error) {
+	saved, failed := 0, 0
+	var lastErr error
+	for _, product := range products {
+		if err := p.SaveEnhanced(ctx, product); err != nil {
+			logger.Warn("failed to persist product", "sku", product.SKU, "error", err)
+			failed++
+			lastErr = err
+			continue
+		}
+		saved++
+	}
+	if failed > 0 {
+		return saved, fmt.Errorf("failed to persist %d of %d products, last: %w", failed, len(products), lastErr)
+	}
+	return saved, nil
+}
+
+// appendFailure adds a log entry for a failed enhancement. Successful ones are
+// recorded in product.Enhancements and logged when the store saves the
+// product. Products without a database ID have nothing to log against.
+func appendFailure(failures []*database.EnhancementLog, sourceName string, p *models.EnhancedProduct, err error) []*database.EnhancementLog {
+	if p == nil {
+		return failures
+	}
+	productID, perr := uuid.Parse(p.ID)
+	if perr != nil {
+		return failures
+	}
+	entry := &database.EnhancementLog{
+		ProductID: productID,
+		Source:    sourceName,
+		Action:    "enhance_failed",
+		Success:   false,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return append(failures, entry)
+}
+
+// logFailures writes failed enhancements to the enhancement log. The run's
+// context may already be cancelled, but its failures are still recorded.
+func (o *Orchestrator) logFailures(ctx context.Context, failures []*database.EnhancementLog) {
+	if o.enhLog == nil || len(failures) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+
+	for _, entry := range failures {
+		if err := o.enhLog.Add(ctx, entry); err != nil {
+			o.logger.Warn("failed to log enhancement failure", "source", entry.Source, "error", err)
+			return
+		}
+	}
 }
 
 // EnhanceResult contains the results of an enhancement operation
@@ -224,6 +525,7 @@ type EnhanceResult struct {
 	ProductsEnhanced  int
 	ImagesAdded       int
 	FieldsUpdated     int
+	ProductsPersisted int
 	BySource          map[string]int
 	Success           bool
 	Error             error
@@ -231,12 +533,28 @@ type EnhanceResult struct {
 	CompletedAt       time.Time
 }
 
+// addEnhancement tallies a single enhancement result
+func (r *EnhanceResult) addEnhancement(sourceName string, enhResult *source.EnhancementResult) {
+	if enhResult == nil || !enhResult.Success {
+		metrics.Enhancement(sourceName, metrics.ResultFailure)
+		return
+	}
+	metrics.Enhancement(sourceName, metrics.ResultSuccess)
+	r.ProductsEnhanced++
+	r.ImagesAdded += enhResult.ImagesAdded
+	r.FieldsUpdated += len(enhResult.FieldsUpdated)
+	r.BySource[sourceName]++
+}
+
 // ExportOptions configures the export operation
 type ExportOptions struct {
 	Destination   string
 	Format        output.Format
 	OutputPath    string
 	OnlyEnhanced  bool
+	SKUs          []string
+	Status        models.ProductStatus
+	Vendor        string
 	IncludeImages bool
 	DryRun        bool
 }
@@ -266,6 +584,9 @@ func (o *Orchestrator) Export(ctx context.Context, opts ExportOptions) (*output.
 		Format:        opts.Format,
 		OutputPath:    opts.OutputPath,
 		OnlyEnhanced:  opts.OnlyEnhanced,
+		SKUs:          opts.SKUs,
+		Status:        opts.Status,
+		Vendor:        opts.Vendor,
 		IncludeImages: opts.IncludeImages,
 		DryRun:        opts.DryRun,
 	})
@@ -276,7 +597,9 @@ type PipelineOptions struct {
 	ImportSource    string
 	ImportVendor    string
 	ImportLimit     int
+	ImportFile      string // Export file for file sources such as matrixify
 	EnhanceSources  []string
+	Concurrency     int // Products enhanced in parallel (default: 1)
 	ExportDest      string
 	ExportFormat    output.Format
 	ExportPath      string
@@ -295,7 +618,10 @@ type PipelineResult struct {
 	CompletedAt time.Time
 }
 
-// RunPipeline executes a full import → enhance → export pipeline
+// RunPipeline executes a full import → enhance → export pipeline. ImportVendor
+// and ImportLimit also scope the enhance step, and ImportVendor the export.
+// Steps without a source or destination are skipped, and the run stops after
+// a step that fails or is cancelled.
 func (o *Orchestrator) RunPipeline(ctx context.Context, opts PipelineOptions) (*PipelineResult, error) {
 	result := &PipelineResult{
 		StartedAt: time.Now(),
@@ -307,6 +633,8 @@ func (o *Orchestrator) RunPipeline(ctx context.Context, opts PipelineOptions) (*
 			Source: opts.ImportSource,
 			Vendor: opts.ImportVendor,
 			Limit:  opts.ImportLimit,
+			File:   opts.ImportFile,
+			DryRun: opts.DryRun,
 		})
 		result.Import = importResult
 		if err != nil {
@@ -318,14 +646,22 @@ func (o *Orchestrator) RunPipeline(ctx context.Context, opts PipelineOptions) (*
 	// Step 2: Enhance
 	if len(opts.EnhanceSources) > 0 {
 		enhanceResult, err := o.Enhance(ctx, EnhanceOptions{
-			Sources: opts.EnhanceSources,
-			DryRun:  opts.DryRun,
+			Sources:     opts.EnhanceSources,
+			Vendor:      opts.ImportVendor,
+			Limit:       opts.ImportLimit,
+			DryRun:      opts.DryRun,
+			Concurrency: opts.Concurrency,
 		})
 		result.Enhance = enhanceResult
 		if err != nil {
 			result.Error = fmt.Errorf("enhance failed: %w", err)
 			return result, result.Error
 		}
+		if err := ctx.Err(); err != nil {
+			// Enhance saves what it finished before a cancellation
+			result.Error = fmt.Errorf("enhance stopped: %w", err)
+			return result, result.Error
+		}
 	}
 
 	// Step 3: Export
@@ -334,6 +670,7 @@ func (o *Orchestrator) RunPipeline(ctx context.Context, opts PipelineOptions) (*
 			Destination:   opts.ExportDest,
 			Format:        opts.ExportFormat,
 			OutputPath:    opts.ExportPath,
+			Vendor:        opts.ImportVendor,
 			IncludeImages: opts.IncludeImages,
 			DryRun:        opts.DryRun,
 		})
@@ -351,10 +688,23 @@ func (o *Orchestrator) RunPipeline(ctx context.Context, opts PipelineOptions) (*
 }
 
 // GetStore returns the state store
-func (o *Orchestrator) GetStore() *state.Store {
+func (o *Orchestrator) GetStore() state.Backend {
 	return o.store
 }
 
+// SetPersister makes Enhance also save each enhanced product through p, such
+// as a postgres.ProductRepo when the JSON state is used. Enhance fails if any
+// product could not be saved.
+func (o *Orchestrator) SetPersister(p EnhancedPersister) {
+	o.persister = p
+}
+
+// SetEnhancementLogger sets where failed enhancements are logged. It is set
+// automatically when the store is PostgreSQL-backed.
+func (o *Orchestrator) SetEnhancementLogger(l EnhancementLogger) {
+	o.enhLog = l
+}
+
 // GetSource returns a source connector by name
 func (o *Orchestrator) GetSource(name string) (source.Connector, bool) {
 	src, ok := o.sources[name]
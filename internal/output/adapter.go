@@ -2,6 +2,9 @@ package output
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/badno/badops/pkg/models"
@@ -11,10 +14,12 @@ import (
 type Format string
 
 const (
-	FormatMatrixify Format = "matrixify" // Shopify Matrixify compatible CSV
-	FormatShopify   Format = "shopify"   // Standard Shopify CSV
-	FormatJSON      Format = "json"      // JSON format
-	FormatJSONL     Format = "jsonl"     // JSON Lines format
+	FormatMatrixify      Format = "matrixify"  // Shopify Matrixify compatible CSV
+	FormatShopify        Format = "shopify"    // Standard Shopify CSV
+	FormatJSON           Format = "json"       // JSON format
+	FormatJSONL          Format = "jsonl"      // JSON Lines format
+	FormatGoogleMerchant Format = "google"     // Google Merchant Center RSS product feed
+	FormatMetafields     Format = "metafields" // Matrixify metafields CSV from product properties
 )
 
 // ExportOptions configures export behavior
@@ -24,15 +29,99 @@ type ExportOptions struct {
 	IncludeImages bool             // Include image URLs
 	OnlyEnhanced bool              // Only export enhanced products
 	SKUs         []string          // Specific SKUs to export
+	Status       models.ProductStatus // Only export products with this status
+	Vendor       string            // Only export products from this vendor (case-insensitive)
 	Filters      map[string]string // Additional filters
 	DryRun       bool              // Preview without actually exporting
+	ImageRowsOnly bool             // Matrixify: put every image on its own row, never on the product row
+}
+
+// FilterProducts returns the products selected by the OnlyEnhanced, SKUs,
+// Status and Vendor options, in their original order
+func FilterProducts(products []models.EnhancedProduct, opts ExportOptions) []models.EnhancedProduct {
+	var skuSet map[string]bool
+	if len(opts.SKUs) > 0 {
+		skuSet = make(map[string]bool, len(opts.SKUs))
+		for _, sku := range opts.SKUs {
+			skuSet[sku] = true
+		}
+	}
+
+	filtered := make([]models.EnhancedProduct, 0, len(products))
+	for _, p := range products {
+		if opts.OnlyEnhanced && len(p.Enhancements) == 0 {
+			continue
+		}
+		if skuSet != nil && !skuSet[p.SKU] {
+			continue
+		}
+		if opts.Status != "" && p.Status != opts.Status {
+			continue
+		}
+		if opts.Vendor != "" && !strings.EqualFold(p.Vendor, opts.Vendor) {
+			continue
+		}
+		filtered = append(filtered, p)
+	}
+	return filtered
+}
+
+// maxInvalidListed caps how many invalid SKUs InvalidDetails lists
+const maxInvalidListed = 10
+
+// ValidateProducts splits products into those that pass
+// EnhancedProduct.Validate and descriptions of the others, such as
+// "CO-1 (price.amount: is negative (-5.00))", so adapters can skip and report
+// invalid products instead of exporting them
+func ValidateProducts(products []models.EnhancedProduct) ([]models.EnhancedProduct, []string) {
+	valid := make([]models.EnhancedProduct, 0, len(products))
+	var invalid []string
+	for i := range products {
+		errs := products[i].Validate()
+		if len(errs) == 0 {
+			valid = append(valid, products[i])
+			continue
+		}
+		problems := make([]string, len(errs))
+		for j, err := range errs {
+			problems[j] = err.Error()
+		}
+		sku := products[i].SKU
+		if strings.TrimSpace(sku) == "" {
+			sku = "(no SKU)"
+		}
+		invalid = append(invalid, fmt.Sprintf("%s (%s)", sku, strings.Join(problems, "; ")))
+	}
+	return valid, invalid
+}
+
+// InvalidDetails summarizes products skipped by ValidateProducts for
+// ExportResult.Details
+func InvalidDetails(invalid []string) string {
+	if len(invalid) == 0 {
+		return ""
+	}
+
+	listed := invalid
+	if len(listed) > maxInvalidListed {
+		listed = listed[:maxInvalidListed]
+	}
+
+	details := fmt.Sprintf("; skipped %d invalid products: %s", len(invalid), strings.Join(listed, ", "))
+	if len(invalid) > len(listed) {
+		details += fmt.Sprintf("; and %d more", len(invalid)-len(listed))
+	}
+	return details
 }
 
 // ExportResult represents the result of an export operation
 type ExportResult struct {
 	Destination     string    // Where data was exported
 	ProductsExported int      // Number of products exported
+	ProductsCreated int       // Products newly created at the destination (API adapters)
+	ProductsUpdated int       // Existing products updated at the destination (API adapters)
 	ImagesExported  int       // Number of images exported
+	ProductsInvalid int       // Products skipped because they failed validation
 	Success         bool
 	Error           error
 	StartedAt       time.Time
@@ -66,6 +155,7 @@ type BaseAdapter struct {
 	name      string
 	connected bool
 	formats   []Format
+	logger    *slog.Logger
 }
 
 // NewBaseAdapter creates a new base adapter
@@ -88,6 +178,20 @@ func (b *BaseAdapter) SetConnected(connected bool) {
 	b.connected = connected
 }
 
+// Logger returns the adapter's logger, which tags records with the output
+// name. It defaults to the slog default logger.
+func (b *BaseAdapter) Logger() *slog.Logger {
+	if b.logger == nil {
+		return slog.Default().With("output", b.name)
+	}
+	return b.logger
+}
+
+// SetLogger replaces the adapter's logger
+func (b *BaseAdapter) SetLogger(logger *slog.Logger) {
+	b.logger = logger.With("output", b.name)
+}
+
 func (b *BaseAdapter) SupportsFormat(format Format) bool {
 	for _, f := range b.formats {
 		if f == format {
@@ -2,19 +2,22 @@ package clickhouse
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
-	"os"
-	"strings"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/output"
 	"github.com/badno/badops/pkg/models"
 )
 
 const AdapterName = "clickhouse"
 
+// insertBatchSize is the number of products sent per insert block
+const insertBatchSize = 1000
+
 // Config holds ClickHouse connection configuration
 type Config struct {
 	Host        string // ClickHouse host
@@ -22,8 +25,8 @@ type Config struct {
 	Database    string // Database name
 	Username    string // Username
 	Password    string // Password
-	UsernameEnv string // Environment variable for username
-	PasswordEnv string // Environment variable for password
+	UsernameEnv string // Credential reference for username (e.g., CLICKHOUSE_USERNAME, file:/path)
+	PasswordEnv string // Credential reference for password
 	Table       string // Target table name
 	Secure      bool   // Use TLS
 }
@@ -32,7 +35,7 @@ type Config struct {
 type Adapter struct {
 	*output.BaseAdapter
 	config Config
-	db     *sql.DB
+	conn   driver.Conn
 }
 
 // NewAdapter creates a new ClickHouse output adapter
@@ -44,7 +47,7 @@ func NewAdapter(cfg Config) *Adapter {
 		cfg.Database = "default"
 	}
 	if cfg.Table == "" {
-		cfg.Table = "products"
+		cfg.Table = "enhanced_products"
 	}
 
 	return &Adapter{
@@ -63,46 +66,54 @@ func (a *Adapter) SupportsFormat(format output.Format) bool {
 
 // Connect establishes connection to ClickHouse
 func (a *Adapter) Connect(ctx context.Context) error {
-	// Resolve credentials from environment
+	// Resolve credentials from their references
 	username := a.config.Username
-	if username == "" && a.config.UsernameEnv != "" {
-		username = os.Getenv(a.config.UsernameEnv)
+	if username == "" {
+		value, err := credentials.Resolve(nil, a.config.UsernameEnv)
+		if err != nil {
+			return fmt.Errorf("failed to resolve ClickHouse username: %w", err)
+		}
+		username = value
 	}
 	password := a.config.Password
-	if password == "" && a.config.PasswordEnv != "" {
-		password = os.Getenv(a.config.PasswordEnv)
+	if password == "" {
+		value, err := credentials.Resolve(nil, a.config.PasswordEnv)
+		if err != nil {
+			return fmt.Errorf("failed to resolve ClickHouse password: %w", err)
+		}
+		password = value
 	}
 
-	// Build connection string
-	protocol := "tcp"
+	protocol := clickhouse.Native
 	if a.config.Secure {
-		protocol = "tls"
-	}
-
-	dsn := fmt.Sprintf("%s://%s:%d?database=%s",
-		protocol, a.config.Host, a.config.Port, a.config.Database)
-
-	if username != "" {
-		dsn += fmt.Sprintf("&username=%s", username)
-	}
-	if password != "" {
-		dsn += fmt.Sprintf("&password=%s", password)
+		protocol = clickhouse.HTTP
 	}
 
-	// Open connection
-	db, err := sql.Open("clickhouse", dsn)
+	conn, err := clickhouse.Open(&clickhouse.Options{
+		Addr: []string{fmt.Sprintf("%s:%d", a.config.Host, a.config.Port)},
+		Auth: clickhouse.Auth{
+			Database: a.config.Database,
+			Username: username,
+			Password: password,
+		},
+		Protocol: protocol,
+		Compression: &clickhouse.Compression{
+			Method: clickhouse.CompressionLZ4,
+		},
+		DialTimeout: 10 * time.Second,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to open ClickHouse connection: %w", err)
 	}
 
-	a.db = db
+	a.conn = conn
 	return a.Test(ctx)
 }
 
 // Close cleans up resources
 func (a *Adapter) Close() error {
-	if a.db != nil {
-		a.db.Close()
+	if a.conn != nil {
+		a.conn.Close()
 	}
 	a.SetConnected(false)
 	return nil
@@ -110,11 +121,11 @@ func (a *Adapter) Close() error {
 
 // Test verifies connectivity to ClickHouse
 func (a *Adapter) Test(ctx context.Context) error {
-	if a.db == nil {
+	if a.conn == nil {
 		return fmt.Errorf("not connected to ClickHouse")
 	}
 
-	if err := a.db.PingContext(ctx); err != nil {
+	if err := a.conn.Ping(ctx); err != nil {
 		return fmt.Errorf("ClickHouse ping failed: %w", err)
 	}
 
@@ -136,28 +147,7 @@ func (a *Adapter) ExportProducts(ctx context.Context, products []models.Enhanced
 	}
 
 	// Filter products
-	filteredProducts := products
-	if opts.OnlyEnhanced {
-		filteredProducts = make([]models.EnhancedProduct, 0)
-		for _, p := range products {
-			if len(p.Enhancements) > 0 {
-				filteredProducts = append(filteredProducts, p)
-			}
-		}
-	}
-	if len(opts.SKUs) > 0 {
-		skuSet := make(map[string]bool)
-		for _, sku := range opts.SKUs {
-			skuSet[sku] = true
-		}
-		temp := make([]models.EnhancedProduct, 0)
-		for _, p := range filteredProducts {
-			if skuSet[p.SKU] {
-				temp = append(temp, p)
-			}
-		}
-		filteredProducts = temp
-	}
+	filteredProducts := output.FilterProducts(products, opts)
 
 	if opts.DryRun {
 		result.ProductsExported = len(filteredProducts)
@@ -169,8 +159,8 @@ func (a *Adapter) ExportProducts(ctx context.Context, products []models.Enhanced
 
 	// Ensure table exists
 	if err := a.ensureTable(ctx); err != nil {
-		result.Error = err
-		return result, err
+		result.Error = fmt.Errorf("failed to create table %s: %w", a.config.Table, err)
+		return result, result.Error
 	}
 
 	// Insert products
@@ -211,6 +201,7 @@ func (a *Adapter) ensureTable(ctx context.Context) error {
 			dimensions_height Float64,
 			dimensions_unit String,
 			images Array(String),
+			image_count UInt32,
 			specifications String,
 			properties String,
 			suppliers String,
@@ -223,52 +214,52 @@ func (a *Adapter) ensureTable(ctx context.Context) error {
 		ORDER BY (sku, exported_at)
 	`, a.config.Table)
 
-	_, err := a.db.ExecContext(ctx, createSQL)
-	return err
+	return a.conn.Exec(ctx, createSQL)
 }
 
-// insertProducts inserts products into ClickHouse
+// insertProducts inserts products in blocks of insertBatchSize. ClickHouse
+// buffers the rows server-side (async_insert) and acknowledges each block once
+// it is written. Returns the number of products and images inserted.
 func (a *Adapter) insertProducts(ctx context.Context, products []models.EnhancedProduct) (int, int, error) {
-	if len(products) == 0 {
-		return 0, 0, nil
-	}
+	insertCtx := clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+		"async_insert":          1,
+		"wait_for_async_insert": 1,
+	}))
 
-	// Build batch insert
-	columns := []string{
-		"sku", "handle", "barcode", "nobb_number",
-		"title", "description", "vendor", "product_type", "tags",
-		"price_amount", "price_currency",
-		"weight_value", "weight_unit",
-		"dimensions_length", "dimensions_width", "dimensions_height", "dimensions_unit",
-		"images", "specifications", "properties", "suppliers", "enhancements",
-		"status", "created_at", "updated_at",
-	}
-
-	placeholders := make([]string, len(columns))
-	for i := range placeholders {
-		placeholders[i] = "?"
-	}
+	inserted := 0
+	imagesExported := 0
 
-	insertSQL := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s)",
-		a.config.Table,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "),
-	)
+	for start := 0; start < len(products); start += insertBatchSize {
+		end := min(start+insertBatchSize, len(products))
 
-	tx, err := a.db.BeginTx(ctx, nil)
-	if err != nil {
-		return 0, 0, err
+		images, err := a.insertBatch(insertCtx, products[start:end])
+		if err != nil {
+			return inserted, imagesExported, err
+		}
+		inserted += end - start
+		imagesExported += images
 	}
 
-	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	return inserted, imagesExported, nil
+}
+
+// insertBatch sends one block of products and returns its image count
+func (a *Adapter) insertBatch(ctx context.Context, products []models.EnhancedProduct) (int, error) {
+	batch, err := a.conn.PrepareBatch(ctx, fmt.Sprintf(`
+		INSERT INTO %s (
+			sku, handle, barcode, nobb_number,
+			title, description, vendor, product_type, tags,
+			price_amount, price_currency,
+			weight_value, weight_unit,
+			dimensions_length, dimensions_width, dimensions_height, dimensions_unit,
+			images, image_count, specifications, properties, suppliers, enhancements,
+			status, created_at, updated_at
+		)
+	`, a.config.Table))
 	if err != nil {
-		tx.Rollback()
-		return 0, 0, err
+		return 0, fmt.Errorf("failed to prepare batch: %w", err)
 	}
-	defer stmt.Close()
 
-	inserted := 0
 	imagesExported := 0
 
 	for _, p := range products {
@@ -299,10 +290,15 @@ func (a *Adapter) insertProducts(ctx context.Context, products []models.Enhanced
 		}
 
 		// Collect image URLs
-		var imageURLs []string
+		imageURLs := make([]string, 0, len(p.Images))
 		for _, img := range p.Images {
 			imageURLs = append(imageURLs, img.SourceURL)
-			imagesExported++
+		}
+		imagesExported += len(imageURLs)
+
+		tags := p.Tags
+		if tags == nil {
+			tags = []string{}
 		}
 
 		// Serialize complex fields to JSON
@@ -311,26 +307,24 @@ func (a *Adapter) insertProducts(ctx context.Context, products []models.Enhanced
 		suppliersJSON, _ := json.Marshal(p.Suppliers)
 		enhancementsJSON, _ := json.Marshal(p.Enhancements)
 
-		_, err := stmt.ExecContext(ctx,
+		err := batch.Append(
 			p.SKU, p.Handle, p.Barcode, p.NOBBNumber,
-			p.Title, p.Description, p.Vendor, p.ProductType, p.Tags,
+			p.Title, p.Description, p.Vendor, p.ProductType, tags,
 			priceAmount, priceCurrency,
 			weightValue, weightUnit,
 			dimLength, dimWidth, dimHeight, dimUnit,
-			imageURLs, string(specsJSON), string(propsJSON), string(suppliersJSON), string(enhancementsJSON),
+			imageURLs, uint32(len(imageURLs)), string(specsJSON), string(propsJSON), string(suppliersJSON), string(enhancementsJSON),
 			string(p.Status), p.CreatedAt, p.UpdatedAt,
 		)
-
 		if err != nil {
-			// Log error but continue with other products
-			continue
+			batch.Abort()
+			return 0, fmt.Errorf("failed to append product %s: %w", p.SKU, err)
 		}
-		inserted++
 	}
 
-	if err := tx.Commit(); err != nil {
-		return inserted, imagesExported, err
+	if err := batch.Send(); err != nil {
+		return 0, fmt.Errorf("failed to insert products: %w", err)
 	}
 
-	return inserted, imagesExported, nil
+	return imagesExported, nil
 }
@@ -0,0 +1,171 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Logical fields a Matrixify CSV column can be mapped to
+const (
+	FieldHandle             = "handle"
+	FieldTitle              = "title"
+	FieldBodyHTML           = "body_html"
+	FieldVendor             = "vendor"
+	FieldProductCategory    = "product_category"
+	FieldType               = "type"
+	FieldTags               = "tags"
+	FieldPublished          = "published"
+	FieldOption1Name        = "option1_name"
+	FieldOption1Value       = "option1_value"
+	FieldSKU                = "sku"
+	FieldGrams              = "grams"
+	FieldInventoryTracker   = "inventory_tracker"
+	FieldInventoryQty       = "inventory_qty"
+	FieldInventoryPolicy    = "inventory_policy"
+	FieldFulfillmentService = "fulfillment_service"
+	FieldPrice              = "price"
+	FieldCompareAtPrice     = "compare_at_price"
+	FieldRequiresShipping   = "requires_shipping"
+	FieldTaxable            = "taxable"
+	FieldBarcode            = "barcode"
+	FieldImageSrc           = "image_src"
+	FieldImagePosition      = "image_position"
+	FieldImageAlt           = "image_alt"
+	FieldSEOTitle           = "seo_title"
+	FieldSEODescription     = "seo_description"
+	FieldWeightUnit         = "weight_unit"
+)
+
+// requiredFields must be present in every column map: Matrixify matches rows
+// to products by handle and variants by SKU
+var requiredFields = []string{FieldHandle, FieldTitle, FieldSKU}
+
+// Column maps a logical field to an output column header
+type Column struct {
+	Field  string `yaml:"field"`
+	Header string `yaml:"header"`
+}
+
+// ColumnMap is the ordered list of columns written by the Matrixify exporter
+type ColumnMap struct {
+	Columns []Column `yaml:"columns"`
+}
+
+// DefaultColumnMap returns the built-in Matrixify layout
+func DefaultColumnMap() *ColumnMap {
+	return &ColumnMap{Columns: []Column{
+		{FieldHandle, "Handle"},
+		{FieldTitle, "Title"},
+		{FieldBodyHTML, "Body (HTML)"},
+		{FieldVendor, "Vendor"},
+		{FieldProductCategory, "Product Category"},
+		{FieldType, "Type"},
+		{FieldTags, "Tags"},
+		{FieldPublished, "Published"},
+		{FieldOption1Name, "Option1 Name"},
+		{FieldOption1Value, "Option1 Value"},
+		{FieldSKU, "Variant SKU"},
+		{FieldGrams, "Variant Grams"},
+		{FieldInventoryTracker, "Variant Inventory Tracker"},
+		{FieldInventoryQty, "Variant Inventory Qty"},
+		{FieldInventoryPolicy, "Variant Inventory Policy"},
+		{FieldFulfillmentService, "Variant Fulfillment Service"},
+		{FieldPrice, "Variant Price"},
+		{FieldCompareAtPrice, "Variant Compare At Price"},
+		{FieldRequiresShipping, "Variant Requires Shipping"},
+		{FieldTaxable, "Variant Taxable"},
+		{FieldBarcode, "Variant Barcode"},
+		{FieldImageSrc, "Image Src"},
+		{FieldImagePosition, "Image Position"},
+		{FieldImageAlt, "Image Alt Text"},
+		{FieldSEOTitle, "SEO Title"},
+		{FieldSEODescription, "SEO Description"},
+		{FieldWeightUnit, "Variant Weight Unit"},
+	}}
+}
+
+// LoadColumnMap reads and validates a YAML column map:
+//
+//	columns:
+//	  - field: handle
+//	    header: Handle
+//	  - field: sku
+//	    header: Variant SKU
+func LoadColumnMap(path string) (*ColumnMap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column map: %w", err)
+	}
+
+	var m ColumnMap
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse column map %s: %w", path, err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid column map %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// Validate checks that every field is known, no field or header repeats, and
+// all required fields are mapped
+func (m *ColumnMap) Validate() error {
+	known := make(map[string]bool)
+	for _, c := range DefaultColumnMap().Columns {
+		known[c.Field] = true
+	}
+
+	fields := make(map[string]bool)
+	headers := make(map[string]bool)
+	for _, c := range m.Columns {
+		if !known[c.Field] {
+			return fmt.Errorf("unknown field %q", c.Field)
+		}
+		if c.Header == "" {
+			return fmt.Errorf("field %q has no header", c.Field)
+		}
+		if fields[c.Field] {
+			return fmt.Errorf("field %q is mapped more than once", c.Field)
+		}
+		if headers[c.Header] {
+			return fmt.Errorf("header %q is used more than once", c.Header)
+		}
+		fields[c.Field] = true
+		headers[c.Header] = true
+	}
+
+	var missing []string
+	for _, f := range requiredFields {
+		if !fields[f] {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required fields: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// Headers returns the column headers in output order
+func (m *ColumnMap) Headers() []string {
+	headers := make([]string, len(m.Columns))
+	for i, c := range m.Columns {
+		headers[i] = c.Header
+	}
+	return headers
+}
+
+// Row projects logical field values onto the mapped columns
+func (m *ColumnMap) Row(values map[string]string) []string {
+	row := make([]string, len(m.Columns))
+	for i, c := range m.Columns {
+		row[i] = values[c.Field]
+	}
+	return row
+}
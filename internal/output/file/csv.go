@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -17,13 +18,15 @@ const CSVAdapterName = "csv"
 
 // CSVConfig holds CSV file output configuration
 type CSVConfig struct {
-	OutputDir string // Directory for output files
+	OutputDir     string // Directory for output files
+	ColumnMapFile string // Optional YAML column map for the Matrixify format
 }
 
 // CSVAdapter implements the output.Adapter interface for CSV files
 type CSVAdapter struct {
 	*output.BaseAdapter
-	config CSVConfig
+	config  CSVConfig
+	columns *ColumnMap // Loaded on Connect, nil means the built-in layout
 }
 
 // NewCSVAdapter creates a new CSV file adapter
@@ -35,17 +38,24 @@ func NewCSVAdapter(cfg CSVConfig) *CSVAdapter {
 	return &CSVAdapter{
 		BaseAdapter: output.NewBaseAdapter(
 			CSVAdapterName,
-			[]output.Format{output.FormatMatrixify, output.FormatShopify},
+			[]output.Format{output.FormatMatrixify, output.FormatShopify, output.FormatMetafields},
 		),
 		config: cfg,
 	}
 }
 
-// Connect creates the output directory
+// Connect creates the output directory and loads the column map, if any
 func (a *CSVAdapter) Connect(ctx context.Context) error {
 	if err := os.MkdirAll(a.config.OutputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
+	if a.config.ColumnMapFile != "" {
+		columns, err := LoadColumnMap(a.config.ColumnMapFile)
+		if err != nil {
+			return err
+		}
+		a.columns = columns
+	}
 	a.SetConnected(true)
 	return nil
 }
@@ -81,34 +91,14 @@ func (a *CSVAdapter) ExportProducts(ctx context.Context, products []models.Enhan
 		}
 	}
 
-	// Filter products if needed
-	filteredProducts := products
-	if opts.OnlyEnhanced {
-		filteredProducts = make([]models.EnhancedProduct, 0)
-		for _, p := range products {
-			if len(p.Enhancements) > 0 {
-				filteredProducts = append(filteredProducts, p)
-			}
-		}
-	}
-	if len(opts.SKUs) > 0 {
-		skuSet := make(map[string]bool)
-		for _, sku := range opts.SKUs {
-			skuSet[sku] = true
-		}
-		temp := make([]models.EnhancedProduct, 0)
-		for _, p := range filteredProducts {
-			if skuSet[p.SKU] {
-				temp = append(temp, p)
-			}
-		}
-		filteredProducts = temp
-	}
+	// Filter products if needed, skipping invalid ones
+	filteredProducts, invalid := output.ValidateProducts(output.FilterProducts(products, opts))
+	result.ProductsInvalid = len(invalid)
 
 	if opts.DryRun {
 		result.ProductsExported = len(filteredProducts)
 		result.Success = true
-		result.Details = fmt.Sprintf("Dry run: would export %d products", len(filteredProducts))
+		result.Details = fmt.Sprintf("Dry run: would export %d products", len(filteredProducts)) + output.InvalidDetails(invalid)
 		result.CompletedAt = time.Now()
 		return result, nil
 	}
@@ -117,7 +107,11 @@ func (a *CSVAdapter) ExportProducts(ctx context.Context, products []models.Enhan
 	filename := opts.OutputPath
 	if filename == "" {
 		timestamp := time.Now().Format("2006-01-02_150405")
-		filename = filepath.Join(a.config.OutputDir, fmt.Sprintf("products_%s.csv", timestamp))
+		prefix := "products"
+		if opts.Format == output.FormatMetafields {
+			prefix = "metafields"
+		}
+		filename = filepath.Join(a.config.OutputDir, fmt.Sprintf("%s_%s.csv", prefix, timestamp))
 	}
 
 	// Create file
@@ -132,10 +126,12 @@ func (a *CSVAdapter) ExportProducts(ctx context.Context, products []models.Enhan
 	defer writer.Flush()
 
 	// Write based on format
-	var imagesExported int
+	var imagesExported, metafieldsExported int
 	switch opts.Format {
 	case output.FormatMatrixify:
 		imagesExported, err = a.writeMatrixifyFormat(writer, filteredProducts, opts)
+	case output.FormatMetafields:
+		metafieldsExported, err = a.writeMetafieldsFormat(writer, filteredProducts)
 	default:
 		imagesExported, err = a.writeShopifyFormat(writer, filteredProducts, opts)
 	}
@@ -149,46 +145,24 @@ func (a *CSVAdapter) ExportProducts(ctx context.Context, products []models.Enhan
 	result.ProductsExported = len(filteredProducts)
 	result.ImagesExported = imagesExported
 	result.Success = true
-	result.Details = fmt.Sprintf("Exported %d products to %s", len(filteredProducts), filename)
+	result.Details = fmt.Sprintf("Exported %d products to %s", len(filteredProducts), filename) + output.InvalidDetails(invalid)
+	if opts.Format == output.FormatMetafields {
+		result.Details = fmt.Sprintf("Exported %d metafields for %d products to %s", metafieldsExported, len(filteredProducts), filename) + output.InvalidDetails(invalid)
+	}
 	result.CompletedAt = time.Now()
 
 	return result, nil
 }
 
-// writeMatrixifyFormat writes products in Matrixify-compatible format
+// writeMatrixifyFormat writes products in Matrixify-compatible format, with
+// the columns of the configured column map
 func (a *CSVAdapter) writeMatrixifyFormat(w *csv.Writer, products []models.EnhancedProduct, opts output.ExportOptions) (int, error) {
-	// Matrixify headers
-	headers := []string{
-		"Handle",
-		"Title",
-		"Body (HTML)",
-		"Vendor",
-		"Product Category",
-		"Type",
-		"Tags",
-		"Published",
-		"Option1 Name",
-		"Option1 Value",
-		"Variant SKU",
-		"Variant Grams",
-		"Variant Inventory Tracker",
-		"Variant Inventory Qty",
-		"Variant Inventory Policy",
-		"Variant Fulfillment Service",
-		"Variant Price",
-		"Variant Compare At Price",
-		"Variant Requires Shipping",
-		"Variant Taxable",
-		"Variant Barcode",
-		"Image Src",
-		"Image Position",
-		"Image Alt Text",
-		"SEO Title",
-		"SEO Description",
-		"Variant Weight Unit",
+	columns := a.columns
+	if columns == nil {
+		columns = DefaultColumnMap()
 	}
 
-	if err := w.Write(headers); err != nil {
+	if err := w.Write(columns.Headers()); err != nil {
 		return 0, err
 	}
 
@@ -202,85 +176,113 @@ func (a *CSVAdapter) writeMatrixifyFormat(w *csv.Writer, products []models.Enhan
 		}
 
 		// Base product row
-		row := make([]string, len(headers))
-		row[0] = handle                              // Handle
-		row[1] = p.Title                             // Title
-		row[2] = p.Description                       // Body (HTML)
-		row[3] = p.Vendor                            // Vendor
-		row[4] = ""                                  // Product Category
-		row[5] = p.ProductType                       // Type
-		row[6] = strings.Join(p.Tags, ", ")          // Tags
-		row[7] = "TRUE"                              // Published
-		row[8] = "Title"                             // Option1 Name
-		row[9] = "Default Title"                     // Option1 Value
-		row[10] = p.SKU                              // Variant SKU
+		row := map[string]string{
+			FieldHandle:             handle,
+			FieldTitle:              p.Title,
+			FieldBodyHTML:           p.Description,
+			FieldVendor:             p.Vendor,
+			FieldType:               p.ProductType,
+			FieldTags:               strings.Join(p.Tags, ", "),
+			FieldPublished:          "TRUE",
+			FieldOption1Name:        "Title",
+			FieldOption1Value:       "Default Title",
+			FieldSKU:                p.SKU,
+			FieldInventoryTracker:   "shopify",
+			FieldInventoryPolicy:    "deny",
+			FieldFulfillmentService: "manual",
+			FieldRequiresShipping:   "TRUE",
+			FieldTaxable:            "TRUE",
+			FieldBarcode:            p.Barcode,
+		}
 
 		// Weight
 		if p.Weight != nil {
 			grams := p.Weight.Value
-			if p.Weight.Unit == "kg" {
-				grams = p.Weight.Value * 1000
+			if converted := p.Weight.ConvertTo("g"); converted != nil {
+				grams = converted.Value
 			}
-			row[11] = fmt.Sprintf("%.0f", grams)     // Variant Grams
+			row[FieldGrams] = fmt.Sprintf("%.0f", grams)
+			row[FieldWeightUnit] = "g"
 		}
 
-		row[12] = "shopify"                          // Variant Inventory Tracker
-		row[13] = ""                                 // Variant Inventory Qty
-		row[14] = "deny"                             // Variant Inventory Policy
-		row[15] = "manual"                           // Variant Fulfillment Service
-
 		// Price
 		if p.Price != nil {
-			row[16] = fmt.Sprintf("%.2f", p.Price.Amount)     // Variant Price
+			row[FieldPrice] = fmt.Sprintf("%.2f", p.Price.Amount)
 			if p.Price.CompareAt > 0 {
-				row[17] = fmt.Sprintf("%.2f", p.Price.CompareAt) // Variant Compare At Price
+				row[FieldCompareAtPrice] = fmt.Sprintf("%.2f", p.Price.CompareAt)
 			}
 		}
 
-		row[18] = "TRUE"                             // Variant Requires Shipping
-		row[19] = "TRUE"                             // Variant Taxable
-		row[20] = p.Barcode                          // Variant Barcode
-
-		// First image in main row
-		if len(p.Images) > 0 && opts.IncludeImages {
-			row[21] = p.Images[0].SourceURL          // Image Src
-			row[22] = "1"                            // Image Position
-			row[23] = p.Images[0].Alt                // Image Alt Text
-			imagesExported++
+		var images []models.ProductImage
+		if opts.IncludeImages {
+			images = orderedImages(p.Images)
 		}
 
-		row[24] = ""                                 // SEO Title
-		row[25] = ""                                 // SEO Description
-
-		if p.Weight != nil {
-			row[26] = "g"                            // Variant Weight Unit
+		// First image in main row, unless images get rows of their own
+		imageRows := images
+		position := 0
+		if len(images) > 0 && !opts.ImageRowsOnly {
+			position = 1
+			row[FieldImageSrc] = images[0].SourceURL
+			row[FieldImagePosition] = "1"
+			row[FieldImageAlt] = imageAlt(images[0], p.Title)
+			imagesExported++
+			imageRows = images[1:]
 		}
 
-		if err := w.Write(row); err != nil {
+		if err := w.Write(columns.Row(row)); err != nil {
 			return imagesExported, err
 		}
 
 		// Additional image rows (Matrixify format uses separate rows for each image)
-		if opts.IncludeImages && len(p.Images) > 1 {
-			for i := 1; i < len(p.Images); i++ {
-				img := p.Images[i]
-				imgRow := make([]string, len(headers))
-				imgRow[0] = handle                   // Handle (same as product)
-				imgRow[21] = img.SourceURL           // Image Src
-				imgRow[22] = fmt.Sprintf("%d", i+1)  // Image Position
-				imgRow[23] = img.Alt                 // Image Alt Text
-
-				if err := w.Write(imgRow); err != nil {
-					return imagesExported, err
-				}
-				imagesExported++
+		for _, img := range imageRows {
+			position++
+			imgRow := map[string]string{
+				FieldHandle:        handle, // Same as product
+				FieldImageSrc:      img.SourceURL,
+				FieldImagePosition: fmt.Sprintf("%d", position),
+				FieldImageAlt:      imageAlt(img, p.Title),
+			}
+
+			if err := w.Write(columns.Row(imgRow)); err != nil {
+				return imagesExported, err
 			}
+			imagesExported++
 		}
 	}
 
 	return imagesExported, nil
 }
 
+// orderedImages returns the images with a source URL sorted by Position, so
+// they can be numbered 1..N. Images without a position keep their order after
+// the positioned ones.
+func orderedImages(images []models.ProductImage) []models.ProductImage {
+	ordered := make([]models.ProductImage, 0, len(images))
+	for _, img := range images {
+		if img.SourceURL != "" {
+			ordered = append(ordered, img)
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, pj := ordered[i].Position, ordered[j].Position
+		if pi <= 0 || pj <= 0 {
+			return pi > 0 && pj <= 0
+		}
+		return pi < pj
+	})
+	return ordered
+}
+
+// imageAlt returns the image's alt text, falling back to the product title
+func imageAlt(img models.ProductImage, title string) string {
+	if img.Alt != "" {
+		return img.Alt
+	}
+	return title
+}
+
 // writeShopifyFormat writes products in standard Shopify CSV format
 func (a *CSVAdapter) writeShopifyFormat(w *csv.Writer, products []models.EnhancedProduct, opts output.ExportOptions) (int, error) {
 	// Simplified Shopify headers
@@ -0,0 +1,114 @@
+package file
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/badno/badops/internal/output"
+	"github.com/badno/badops/pkg/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// goldenProducts covers the cases the Matrixify layout cares about: images
+// out of position order, an image without a position or alt text, a product
+// without images, a kg weight and a compare-at price
+func goldenProducts() []models.EnhancedProduct {
+	return []models.EnhancedProduct{
+		{
+			SKU:         "CO-T309012",
+			Handle:      "boston-toilet-roll-holder",
+			Barcode:     "8717343309012",
+			Title:       "Boston Toilet Roll Holder",
+			Description: "<p>Toilet roll holder in brushed stainless steel.</p>",
+			Vendor:      "Tiger",
+			ProductType: "Toilet Roll Holder",
+			Tags:        []string{"Boston", "bathroom accessories"},
+			Price:       &models.Price{Amount: 499, CompareAt: 599, Currency: "NOK"},
+			Weight:      &models.Weight{Value: 0.45, Unit: "kg"},
+			Images: []models.ProductImage{
+				{SourceURL: "https://cdn.example.com/boston-side.jpg", Position: 3, Alt: "Side view"},
+				{SourceURL: "https://cdn.example.com/boston-front.jpg", Position: 1, Alt: "Front view"},
+				{SourceURL: "https://cdn.example.com/boston-extra.jpg"},
+				{SourceURL: "https://cdn.example.com/boston-detail.jpg", Position: 2, Alt: "Detail"},
+				{LocalPath: "images/not-uploaded.jpg", Position: 4},
+			},
+		},
+		{
+			SKU:    "CO-T317312",
+			Title:  "Urban Toilet Brush",
+			Vendor: "Tiger",
+			Price:  &models.Price{Amount: 349.5, Currency: "NOK"},
+			Weight: &models.Weight{Value: 820, Unit: "g"},
+		},
+	}
+}
+
+func TestMatrixifyGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   output.ExportOptions
+		images int
+		golden string
+	}{
+		{
+			name:   "images",
+			opts:   output.ExportOptions{Format: output.FormatMatrixify, IncludeImages: true},
+			images: 4,
+			golden: "matrixify.golden.csv",
+		},
+		{
+			name:   "image rows only",
+			opts:   output.ExportOptions{Format: output.FormatMatrixify, IncludeImages: true, ImageRowsOnly: true},
+			images: 4,
+			golden: "matrixify_image_rows.golden.csv",
+		},
+		{
+			name:   "without images",
+			opts:   output.ExportOptions{Format: output.FormatMatrixify},
+			images: 0,
+			golden: "matrixify_no_images.golden.csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			adapter := NewCSVAdapter(CSVConfig{OutputDir: dir})
+			tt.opts.OutputPath = filepath.Join(dir, "products.csv")
+
+			result, err := adapter.ExportProducts(context.Background(), goldenProducts(), tt.opts)
+			if err != nil {
+				t.Fatalf("ExportProducts: %v", err)
+			}
+			if result.ProductsExported != 2 {
+				t.Errorf("ProductsExported = %d, want 2", result.ProductsExported)
+			}
+			if result.ImagesExported != tt.images {
+				t.Errorf("ImagesExported = %d, want %d", result.ImagesExported, tt.images)
+			}
+
+			got, err := os.ReadFile(tt.opts.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("output differs from %s:\n got:\n%s\nwant:\n%s", golden, got, want)
+			}
+		})
+	}
+}
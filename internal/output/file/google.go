@@ -0,0 +1,269 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/output"
+	"github.com/badno/badops/pkg/models"
+)
+
+const GoogleAdapterName = "google"
+
+// googleNamespace is the Google Merchant Center product attribute namespace
+const googleNamespace = "http://base.google.com/ns/1.0"
+
+// maxSkippedListed caps how many skipped SKUs are listed in the result details
+const maxSkippedListed = 10
+
+// GoogleConfig holds Google Merchant feed output configuration
+type GoogleConfig struct {
+	OutputDir string // Directory for output files
+	StoreURL  string // Storefront base URL, products link to StoreURL/products/<handle>
+	Title     string // Feed channel title
+}
+
+// GoogleAdapter writes products as a Google Merchant Center RSS 2.0 feed
+type GoogleAdapter struct {
+	*output.BaseAdapter
+	config GoogleConfig
+}
+
+// NewGoogleAdapter creates a new Google Merchant feed adapter
+func NewGoogleAdapter(cfg GoogleConfig) *GoogleAdapter {
+	if cfg.OutputDir == "" {
+		cfg.OutputDir = "output"
+	}
+	if cfg.Title == "" {
+		cfg.Title = "Products"
+	}
+	cfg.StoreURL = strings.TrimRight(cfg.StoreURL, "/")
+
+	return &GoogleAdapter{
+		BaseAdapter: output.NewBaseAdapter(
+			GoogleAdapterName,
+			[]output.Format{output.FormatGoogleMerchant},
+		),
+		config: cfg,
+	}
+}
+
+// Connect creates the output directory
+func (a *GoogleAdapter) Connect(ctx context.Context) error {
+	if err := os.MkdirAll(a.config.OutputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	a.SetConnected(true)
+	return nil
+}
+
+// Close cleans up resources
+func (a *GoogleAdapter) Close() error {
+	a.SetConnected(false)
+	return nil
+}
+
+// Test verifies the output directory is writable
+func (a *GoogleAdapter) Test(ctx context.Context) error {
+	testFile := filepath.Join(a.config.OutputDir, ".test")
+	f, err := os.Create(testFile)
+	if err != nil {
+		return fmt.Errorf("output directory not writable: %w", err)
+	}
+	f.Close()
+	os.Remove(testFile)
+	return nil
+}
+
+// googleFeed is the RSS 2.0 document
+type googleFeed struct {
+	XMLName xml.Name      `xml:"rss"`
+	Version string        `xml:"version,attr"`
+	NS      string        `xml:"xmlns:g,attr"`
+	Channel googleChannel `xml:"channel"`
+}
+
+type googleChannel struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link"`
+	Description string       `xml:"description"`
+	Items       []googleItem `xml:"item"`
+}
+
+// googleItem is one product in the feed
+type googleItem struct {
+	ID          string `xml:"g:id"`
+	Title       string `xml:"g:title"`
+	Description string `xml:"g:description"`
+	Link        string `xml:"g:link"`
+	ImageLink   string `xml:"g:image_link"`
+	Price       string `xml:"g:price"`
+	GTIN        string `xml:"g:gtin,omitempty"`
+	Brand       string `xml:"g:brand,omitempty"`
+	Condition   string `xml:"g:condition"`
+}
+
+// ExportProducts writes products with all required feed fields to an XML feed.
+// Products missing a required field are skipped and listed in the details.
+func (a *GoogleAdapter) ExportProducts(ctx context.Context, products []models.EnhancedProduct, opts output.ExportOptions) (*output.ExportResult, error) {
+	result := &output.ExportResult{
+		StartedAt: time.Now(),
+	}
+
+	if !a.IsConnected() {
+		if err := a.Connect(ctx); err != nil {
+			result.Error = err
+			return result, err
+		}
+	}
+
+	// Filter products if needed
+	filteredProducts := output.FilterProducts(products, opts)
+
+	items := make([]googleItem, 0, len(filteredProducts))
+	var skipped []string
+	for i := range filteredProducts {
+		item, missing := a.feedItem(&filteredProducts[i])
+		if len(missing) > 0 {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", filteredProducts[i].SKU, strings.Join(missing, ", ")))
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if opts.DryRun {
+		result.ProductsExported = len(items)
+		result.Success = true
+		result.Details = fmt.Sprintf("Dry run: would export %d products", len(items)) + skippedDetails(skipped)
+		result.CompletedAt = time.Now()
+		return result, nil
+	}
+
+	// Determine filename
+	filename := opts.OutputPath
+	if filename == "" {
+		timestamp := time.Now().Format("2006-01-02_150405")
+		filename = filepath.Join(a.config.OutputDir, fmt.Sprintf("google_feed_%s.xml", timestamp))
+	}
+
+	if err := a.writeFeed(filename, items); err != nil {
+		result.Error = err
+		return result, err
+	}
+
+	result.Destination = filename
+	result.ProductsExported = len(items)
+	result.ImagesExported = len(items)
+	result.Success = true
+	result.Details = fmt.Sprintf("Exported %d products to %s", len(items), filename) + skippedDetails(skipped)
+	result.CompletedAt = time.Now()
+
+	return result, nil
+}
+
+// feedItem builds the feed entry for a product and returns the names of any
+// required fields it is missing
+func (a *GoogleAdapter) feedItem(p *models.EnhancedProduct) (googleItem, []string) {
+	item := googleItem{
+		ID:          p.SKU,
+		Title:       p.Title,
+		Description: p.Description,
+		GTIN:        p.Barcode,
+		Brand:       p.Vendor,
+		Condition:   "new",
+	}
+
+	var missing []string
+	if item.Title == "" {
+		missing = append(missing, "title")
+	}
+	if item.Description == "" {
+		missing = append(missing, "description")
+	}
+
+	if p.Handle != "" && a.config.StoreURL != "" {
+		item.Link = a.config.StoreURL + "/products/" + p.Handle
+	} else {
+		missing = append(missing, "link")
+	}
+
+	// The main image is the one with the lowest position, not the first stored
+	if images := orderedImages(p.Images); len(images) > 0 {
+		item.ImageLink = images[0].SourceURL
+	}
+	if item.ImageLink == "" {
+		missing = append(missing, "image")
+	}
+
+	if p.Price != nil && p.Price.Amount > 0 {
+		currency := p.Price.Currency
+		if currency == "" {
+			currency = "NOK"
+		}
+		item.Price = fmt.Sprintf("%.2f %s", p.Price.Amount, currency)
+	} else {
+		missing = append(missing, "price")
+	}
+
+	return item, missing
+}
+
+// writeFeed writes the feed document
+func (a *GoogleAdapter) writeFeed(filename string, items []googleItem) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := bufio.NewWriter(f)
+	if _, err := writer.WriteString(xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(writer)
+	encoder.Indent("", "  ")
+
+	feed := googleFeed{
+		Version: "2.0",
+		NS:      googleNamespace,
+		Channel: googleChannel{
+			Title:       a.config.Title,
+			Link:        a.config.StoreURL,
+			Description: fmt.Sprintf("%s product feed", a.config.Title),
+			Items:       items,
+		},
+	}
+	if err := encoder.Encode(feed); err != nil {
+		return fmt.Errorf("failed to encode feed: %w", err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// skippedDetails summarizes skipped products for ExportResult.Details
+func skippedDetails(skipped []string) string {
+	if len(skipped) == 0 {
+		return ""
+	}
+
+	listed := skipped
+	if len(listed) > maxSkippedListed {
+		listed = listed[:maxSkippedListed]
+	}
+
+	details := fmt.Sprintf("; skipped %d products missing required fields: %s", len(skipped), strings.Join(listed, "; "))
+	if len(skipped) > len(listed) {
+		details += fmt.Sprintf("; and %d more", len(skipped)-len(listed))
+	}
+	return details
+}
@@ -0,0 +1,62 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+func TestGoogleFeedItemImageLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		images []models.ProductImage
+		want   string
+	}{
+		{
+			name: "lowest position",
+			images: []models.ProductImage{
+				{SourceURL: "https://cdn.example.com/side.jpg", Position: 3},
+				{SourceURL: "https://cdn.example.com/front.jpg", Position: 1},
+				{SourceURL: "https://cdn.example.com/detail.jpg", Position: 2},
+			},
+			want: "https://cdn.example.com/front.jpg",
+		},
+		{
+			name: "unpositioned after positioned",
+			images: []models.ProductImage{
+				{SourceURL: "https://cdn.example.com/extra.jpg"},
+				{SourceURL: "https://cdn.example.com/detail.jpg", Position: 2},
+			},
+			want: "https://cdn.example.com/detail.jpg",
+		},
+		{
+			name: "skips images without a URL",
+			images: []models.ProductImage{
+				{LocalPath: "images/not-uploaded.jpg", Position: 1},
+				{SourceURL: "https://cdn.example.com/front.jpg", Position: 2},
+			},
+			want: "https://cdn.example.com/front.jpg",
+		},
+		{
+			name:   "no images",
+			images: nil,
+			want:   "",
+		},
+	}
+
+	a := NewGoogleAdapter(GoogleConfig{StoreURL: "https://badno.no"})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := goldenProducts()[0]
+			p.Images = tt.images
+
+			item, missing := a.feedItem(&p)
+			if item.ImageLink != tt.want {
+				t.Errorf("ImageLink = %q, want %q", item.ImageLink, tt.want)
+			}
+			if wantMissing := tt.want == ""; wantMissing != (len(missing) > 0) {
+				t.Errorf("missing = %v", missing)
+			}
+		})
+	}
+}
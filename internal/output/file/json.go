@@ -83,28 +83,7 @@ func (a *JSONAdapter) ExportProducts(ctx context.Context, products []models.Enha
 	}
 
 	// Filter products if needed
-	filteredProducts := products
-	if opts.OnlyEnhanced {
-		filteredProducts = make([]models.EnhancedProduct, 0)
-		for _, p := range products {
-			if len(p.Enhancements) > 0 {
-				filteredProducts = append(filteredProducts, p)
-			}
-		}
-	}
-	if len(opts.SKUs) > 0 {
-		skuSet := make(map[string]bool)
-		for _, sku := range opts.SKUs {
-			skuSet[sku] = true
-		}
-		temp := make([]models.EnhancedProduct, 0)
-		for _, p := range filteredProducts {
-			if skuSet[p.SKU] {
-				temp = append(temp, p)
-			}
-		}
-		filteredProducts = temp
-	}
+	filteredProducts := output.FilterProducts(products, opts)
 
 	if opts.DryRun {
 		result.ProductsExported = len(filteredProducts)
@@ -188,7 +167,9 @@ func (a *JSONAdapter) writeJSON(filename string, products []models.EnhancedProdu
 	return encoder.Encode(export)
 }
 
-// writeJSONL writes products as JSON Lines (one object per line)
+// writeJSONL writes products as JSON Lines (one object per line, no
+// surrounding array). Lines are streamed through a buffered writer, so large
+// catalogs are never held in memory as a single document.
 func (a *JSONAdapter) writeJSONL(filename string, products []models.EnhancedProduct) error {
 	f, err := os.Create(filename)
 	if err != nil {
@@ -197,20 +178,16 @@ func (a *JSONAdapter) writeJSONL(filename string, products []models.EnhancedProd
 	defer f.Close()
 
 	writer := bufio.NewWriter(f)
-	defer writer.Flush()
+	encoder := json.NewEncoder(writer) // Encode appends the newline
 
-	for _, p := range products {
-		data, err := json.Marshal(p)
-		if err != nil {
-			return err
-		}
-		if _, err := writer.Write(data); err != nil {
-			return err
-		}
-		if _, err := writer.WriteString("\n"); err != nil {
-			return err
+	for i := range products {
+		if err := encoder.Encode(&products[i]); err != nil {
+			return fmt.Errorf("failed to write product %s: %w", products[i].SKU, err)
 		}
 	}
 
-	return nil
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
 }
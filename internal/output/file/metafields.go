@@ -0,0 +1,269 @@
+package file
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// Shopify metafield key limits
+const (
+	metafieldNamespaceMin = 3
+	metafieldKeyMax       = 64
+)
+
+// metafieldNamespaceDefault is used for properties without a usable source
+const metafieldNamespaceDefault = "custom"
+
+// metafieldUnits maps property units to the Shopify measurement metafield
+// type and the unit that type expects in its JSON value
+var metafieldUnits = map[string]struct{ kind, unit string }{
+	"mm":    {"dimension", "mm"},
+	"cm":    {"dimension", "cm"},
+	"m":     {"dimension", "m"},
+	"in":    {"dimension", "in"},
+	"ft":    {"dimension", "ft"},
+	"yd":    {"dimension", "yd"},
+	"g":     {"weight", "g"},
+	"gram":  {"weight", "g"},
+	"kg":    {"weight", "kg"},
+	"lb":    {"weight", "lb"},
+	"oz":    {"weight", "oz"},
+	"ml":    {"volume", "ml"},
+	"cl":    {"volume", "cl"},
+	"l":     {"volume", "l"},
+	"ltr":   {"volume", "l"},
+	"liter": {"volume", "l"},
+	"m3":    {"volume", "m3"},
+	"m³":    {"volume", "m3"},
+}
+
+// metafieldBooleans maps yes/no property values, English and Norwegian
+var metafieldBooleans = map[string]string{
+	"true": "true", "yes": "true", "ja": "true",
+	"false": "false", "no": "false", "nei": "false",
+}
+
+// metafieldRow is one product's value for a metafield
+type metafieldRow struct {
+	handle    string
+	namespace string
+	key       string
+	value     metafieldValue
+}
+
+// metafieldValue is a property value classified for typing
+type metafieldValue struct {
+	text    string  // Value as written, with its unit
+	kind    string  // Measurement type (dimension, weight, volume), "number", "boolean" or "text"
+	number  float64 // Parsed number of number and measurement values
+	integer bool    // Number without a fractional part or decimal separator
+	unit    string  // Shopify unit of measurement values
+}
+
+// writeMetafieldsFormat writes product properties as a Matrixify metafields
+// CSV, one row per property. The namespace is the property source and the key
+// its code (or name when it has none); properties sharing a key on a product
+// are written once. Shopify metafield definitions have one type per key, so
+// the type is chosen per namespace and key across the export: a number or
+// measurement type only when every value has that type, text otherwise.
+func (a *CSVAdapter) writeMetafieldsFormat(w *csv.Writer, products []models.EnhancedProduct) (int, error) {
+	headers := []string{"Handle", "Metafield Namespace", "Metafield Key", "Metafield Value", "Metafield Type"}
+	if err := w.Write(headers); err != nil {
+		return 0, err
+	}
+
+	var rows []metafieldRow
+	values := make(map[string][]metafieldValue)
+	for _, p := range products {
+		handle := p.Handle
+		if handle == "" {
+			handle = strings.ToLower(strings.ReplaceAll(p.Title, " ", "-"))
+		}
+
+		seen := make(map[string]bool)
+		for _, prop := range p.Properties {
+			value, ok := parseMetafieldValue(prop)
+			if !ok {
+				continue
+			}
+			namespace := metafieldNamespace(prop.Source)
+			key := metafieldKey(prop.Code)
+			if key == "" {
+				key = metafieldKey(prop.Name)
+			}
+			id := namespace + "." + key
+			if key == "" || seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			rows = append(rows, metafieldRow{handle: handle, namespace: namespace, key: key, value: value})
+			values[id] = append(values[id], value)
+		}
+	}
+
+	types := make(map[string]string, len(values))
+	for id, vs := range values {
+		types[id] = metafieldType(vs)
+	}
+
+	for i, row := range rows {
+		fieldType := types[row.namespace+"."+row.key]
+		record := []string{row.handle, row.namespace, row.key, row.value.format(fieldType), fieldType}
+		if err := w.Write(record); err != nil {
+			return i, err
+		}
+	}
+
+	return len(rows), nil
+}
+
+// parseMetafieldValue classifies a property value. Numbers with a length,
+// weight or volume unit are measurements; numbers with any other unit, and
+// numbers with leading zeros such as article numbers, are text.
+func parseMetafieldValue(prop models.Property) (metafieldValue, bool) {
+	value := strings.TrimSpace(prop.Value)
+	unit := strings.TrimSpace(prop.Unit)
+	if value == "" {
+		return metafieldValue{}, false
+	}
+
+	// Values like "12 mm" carry their unit inline
+	number := value
+	text := value
+	if unit == "" {
+		if fields := strings.Fields(value); len(fields) == 2 {
+			if _, ok := parseMetafieldNumber(fields[0]); ok {
+				number, unit = fields[0], fields[1]
+			}
+		}
+	} else {
+		text += " " + unit
+	}
+
+	// Numbers past float64 precision, such as long codes, stay text too
+	if n, ok := parseMetafieldNumber(number); ok && !hasLeadingZero(number) && math.Abs(n) < 1<<53 {
+		if unit == "" {
+			integer := n == float64(int64(n)) && !strings.ContainsAny(number, ".,")
+			return metafieldValue{text: text, kind: "number", number: n, integer: integer}, true
+		}
+		if m, ok := metafieldUnits[strings.ToLower(unit)]; ok {
+			return metafieldValue{text: text, kind: m.kind, number: n, unit: m.unit}, true
+		}
+		return metafieldValue{text: text, kind: "text"}, true
+	}
+
+	if _, ok := metafieldBooleans[strings.ToLower(value)]; ok && unit == "" {
+		return metafieldValue{text: text, kind: "boolean"}, true
+	}
+	return metafieldValue{text: text, kind: "text"}, true
+}
+
+// metafieldType returns the Shopify type for a key's values: their common
+// measurement, number or boolean type, or text when they disagree
+func metafieldType(values []metafieldValue) string {
+	kind := values[0].kind
+	integer, multiline := true, false
+	for _, v := range values {
+		if v.kind != kind {
+			kind = "text"
+		}
+		integer = integer && v.integer
+		multiline = multiline || strings.Contains(v.text, "\n")
+	}
+
+	switch kind {
+	case "dimension", "weight", "volume", "boolean":
+		return kind
+	case "number":
+		if integer {
+			return "number_integer"
+		}
+		return "number_decimal"
+	}
+	if multiline {
+		return "multi_line_text_field"
+	}
+	return "single_line_text_field"
+}
+
+// format returns the value as written for a metafield of the given type
+func (v metafieldValue) format(fieldType string) string {
+	switch fieldType {
+	case "dimension", "weight", "volume":
+		data, _ := json.Marshal(struct {
+			Value float64 `json:"value"`
+			Unit  string  `json:"unit"`
+		}{v.number, v.unit})
+		return string(data)
+	case "number_integer":
+		return strconv.FormatInt(int64(v.number), 10)
+	case "number_decimal":
+		return strconv.FormatFloat(v.number, 'f', -1, 64)
+	case "boolean":
+		return metafieldBooleans[strings.ToLower(v.text)]
+	}
+	return v.text
+}
+
+// hasLeadingZero reports whether a number is written with a leading zero,
+// like "0042", which a number metafield would drop
+func hasLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9'
+}
+
+// parseMetafieldNumber parses a number, accepting a decimal comma
+func parseMetafieldNumber(s string) (float64, bool) {
+	if strings.Count(s, ",") == 1 && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, false
+	}
+	return n, true
+}
+
+// metafieldNamespace returns the namespace for a property source, e.g. nobb_etim
+func metafieldNamespace(source string) string {
+	namespace := metafieldSlug(source)
+	if len(namespace) < metafieldNamespaceMin {
+		return metafieldNamespaceDefault
+	}
+	return namespace
+}
+
+// metafieldKey returns a Shopify-safe key for a property name or code
+func metafieldKey(name string) string {
+	key := metafieldSlug(name)
+	if len(key) > metafieldKeyMax {
+		key = strings.TrimRight(key[:metafieldKeyMax], "_")
+	}
+	return key
+}
+
+// metafieldSlug lowercases s and replaces runs of anything but letters,
+// digits and dashes with an underscore, transliterating Norwegian letters
+func metafieldSlug(s string) string {
+	s = strings.NewReplacer("æ", "ae", "ø", "o", "å", "a").Replace(strings.ToLower(s))
+
+	var b strings.Builder
+	underscore := false
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			b.WriteRune(r)
+			underscore = false
+		case !underscore && b.Len() > 0:
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
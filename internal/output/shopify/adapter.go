@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/output"
 	"github.com/badno/badops/pkg/models"
 )
@@ -17,21 +21,28 @@ import (
 const (
 	AdapterName = "shopify"
 	apiVersion  = "2024-01"
+
+	// requestInterval keeps requests within Shopify's 2 req/s leaky bucket
+	requestInterval = 500 * time.Millisecond
+	// maxRetries is the number of retries after a 429 response
+	maxRetries = 3
 )
 
 // Config holds Shopify output configuration
 type Config struct {
 	Store     string // Store name (e.g., "badno" for badno.myshopify.com)
 	APIKey    string // API access token
-	APIKeyEnv string // Environment variable name for API key
+	APIKeyEnv string // Credential reference for the API key (e.g., SHOPIFY_API_KEY, file:/path)
 }
 
 // Adapter implements the output.Adapter interface for Shopify
 type Adapter struct {
 	*output.BaseAdapter
-	config  Config
-	client  *http.Client
-	baseURL string
+	config      Config
+	client      *http.Client
+	baseURL     string
+	lastRequest time.Time
+	rateLimitMu sync.Mutex
 }
 
 // NewAdapter creates a new Shopify output adapter
@@ -55,10 +66,14 @@ func (a *Adapter) SupportsFormat(format output.Format) bool {
 
 // Connect establishes connection to Shopify API
 func (a *Adapter) Connect(ctx context.Context) error {
-	// Resolve API key from environment if needed
+	// Resolve API key from its credential reference if needed
 	apiKey := a.config.APIKey
-	if apiKey == "" && a.config.APIKeyEnv != "" {
-		apiKey = os.Getenv(a.config.APIKeyEnv)
+	if apiKey == "" {
+		key, err := credentials.Resolve(nil, a.config.APIKeyEnv)
+		if err != nil {
+			return fmt.Errorf("failed to resolve shopify API key: %w", err)
+		}
+		apiKey = key
 	}
 	if apiKey == "" {
 		return fmt.Errorf("shopify API key not configured")
@@ -106,7 +121,9 @@ func (a *Adapter) Test(ctx context.Context) error {
 	return nil
 }
 
-// ExportProducts updates products in Shopify
+// ExportProducts upserts products in Shopify by SKU. Products with a known
+// Shopify ID (or whose SKU matches an existing variant) are updated; others
+// are created. New images are appended via the product images endpoint.
 func (a *Adapter) ExportProducts(ctx context.Context, products []models.EnhancedProduct, opts output.ExportOptions) (*output.ExportResult, error) {
 	result := &output.ExportResult{
 		StartedAt: time.Now(),
@@ -119,52 +136,49 @@ func (a *Adapter) ExportProducts(ctx context.Context, products []models.Enhanced
 		}
 	}
 
-	// Filter products
-	filteredProducts := products
-	if opts.OnlyEnhanced {
-		filteredProducts = make([]models.EnhancedProduct, 0)
-		for _, p := range products {
-			if len(p.Enhancements) > 0 {
-				filteredProducts = append(filteredProducts, p)
-			}
-		}
-	}
-	if len(opts.SKUs) > 0 {
-		skuSet := make(map[string]bool)
-		for _, sku := range opts.SKUs {
-			skuSet[sku] = true
-		}
-		temp := make([]models.EnhancedProduct, 0)
-		for _, p := range filteredProducts {
-			if skuSet[p.SKU] {
-				temp = append(temp, p)
-			}
-		}
-		filteredProducts = temp
-	}
+	// Filter products, skipping invalid ones
+	filteredProducts, invalid := output.ValidateProducts(output.FilterProducts(products, opts))
+	result.ProductsInvalid = len(invalid)
 
 	if opts.DryRun {
 		result.ProductsExported = len(filteredProducts)
 		result.Success = true
-		result.Details = fmt.Sprintf("Dry run: would update %d products in Shopify", len(filteredProducts))
+		result.Details = fmt.Sprintf("Dry run: would upsert %d products in Shopify", len(filteredProducts)) + output.InvalidDetails(invalid)
 		result.CompletedAt = time.Now()
 		return result, nil
 	}
 
-	// Update each product
+	// Upsert each product
+	created := 0
 	updated := 0
 	imagesAdded := 0
 	var lastError error
 
 	for _, p := range filteredProducts {
-		if p.ID == "" {
-			// Skip products without Shopify ID
+		productID := shopifyProductID(p)
+		if productID == "" && p.SKU != "" {
+			id, err := a.findProductIDBySKU(ctx, p.SKU)
+			if err != nil {
+				lastError = fmt.Errorf("lookup %s: %w", p.SKU, err)
+				continue
+			}
+			productID = id
+		}
+
+		if productID == "" {
+			newImages, err := a.createProduct(ctx, p, opts)
+			if err != nil {
+				lastError = fmt.Errorf("create %s: %w", p.SKU, err)
+				continue
+			}
+			created++
+			imagesAdded += newImages
 			continue
 		}
 
-		newImages, err := a.updateProduct(ctx, p, opts)
+		newImages, err := a.updateProduct(ctx, productID, p, opts)
 		if err != nil {
-			lastError = err
+			lastError = fmt.Errorf("update %s: %w", p.SKU, err)
 			continue
 		}
 		updated++
@@ -172,72 +186,249 @@ func (a *Adapter) ExportProducts(ctx context.Context, products []models.Enhanced
 	}
 
 	result.Destination = a.config.Store + ".myshopify.com"
-	result.ProductsExported = updated
+	result.ProductsExported = created + updated
+	result.ProductsCreated = created
+	result.ProductsUpdated = updated
 	result.ImagesExported = imagesAdded
 	result.Success = lastError == nil
 	result.Error = lastError
-	result.Details = fmt.Sprintf("Updated %d/%d products in Shopify", updated, len(filteredProducts))
+	result.Details = fmt.Sprintf("Created %d and updated %d of %d products in Shopify (%d images added)",
+		created, updated, len(filteredProducts), imagesAdded) + output.InvalidDetails(invalid)
 	result.CompletedAt = time.Now()
 
 	return result, nil
 }
 
-// updateProduct updates a single product in Shopify
-func (a *Adapter) updateProduct(ctx context.Context, product models.EnhancedProduct, opts output.ExportOptions) (int, error) {
+// shopifyProductID returns the product's Shopify ID, or "" if the product's
+// ID is not a Shopify ID (e.g., a database UUID or a product parsed from CSV)
+func shopifyProductID(product models.EnhancedProduct) string {
+	if product.ID == "" {
+		return ""
+	}
+	for _, c := range product.ID {
+		if c < '0' || c > '9' {
+			return ""
+		}
+	}
+	return product.ID
+}
+
+// newImages returns the images that have not yet been uploaded to Shopify
+func newImages(product models.EnhancedProduct) []shopifyImage {
+	var images []shopifyImage
+	for _, img := range product.Images {
+		// Only add new images (those with source other than shopify)
+		if img.Source != "shopify" && img.Status != "existing" {
+			images = append(images, shopifyImage{
+				Src:      img.SourceURL,
+				Position: img.Position,
+				Alt:      img.Alt,
+			})
+		}
+	}
+	return images
+}
+
+// createProduct creates a new product with a single variant for the SKU
+func (a *Adapter) createProduct(ctx context.Context, product models.EnhancedProduct, opts output.ExportOptions) (int, error) {
+	variant := shopifyVariant{
+		SKU:     product.SKU,
+		Barcode: product.Barcode,
+	}
+	if product.Price != nil {
+		variant.Price = fmt.Sprintf("%.2f", product.Price.Amount)
+	}
+	if grams := product.Weight.ConvertTo("g"); grams != nil {
+		variant.Grams = int(grams.Value)
+	}
+
+	payload := shopifyProductUpdate{
+		Product: shopifyProduct{
+			Title:       product.Title,
+			BodyHTML:    product.Description,
+			Vendor:      product.Vendor,
+			ProductType: product.ProductType,
+			Tags:        strings.Join(product.Tags, ", "),
+			Variants:    []shopifyVariant{variant},
+		},
+	}
+
+	imagesAdded := 0
+	if opts.IncludeImages {
+		payload.Product.Images = newImages(product)
+		imagesAdded = len(payload.Product.Images)
+	}
+
+	if err := a.doRequest(ctx, "POST", "/products.json", payload, nil); err != nil {
+		return 0, err
+	}
+
+	return imagesAdded, nil
+}
+
+// updateProduct updates an existing product and appends new images
+func (a *Adapter) updateProduct(ctx context.Context, productID string, product models.EnhancedProduct, opts output.ExportOptions) (int, error) {
 	// Prepare update payload
 	updateData := shopifyProductUpdate{
 		Product: shopifyProduct{
-			ID:          product.ID,
+			ID:          productID,
 			Title:       product.Title,
 			BodyHTML:    product.Description,
 			Vendor:      product.Vendor,
 			ProductType: product.ProductType,
+			Tags:        strings.Join(product.Tags, ", "),
 		},
 	}
 
-	// Add new images if requested
+	if err := a.doRequest(ctx, "PUT", fmt.Sprintf("/products/%s.json", productID), updateData, nil); err != nil {
+		return 0, err
+	}
+
+	// Append new images one at a time so existing images are kept, skipping
+	// any the product already has so repeated exports don't duplicate them
 	imagesAdded := 0
 	if opts.IncludeImages {
-		for _, img := range product.Images {
-			// Only add new images (those with source other than shopify)
-			if img.Source != "shopify" && img.Status != "existing" {
-				updateData.Product.Images = append(updateData.Product.Images, shopifyImage{
-					Src:      img.SourceURL,
-					Position: img.Position,
-					Alt:      img.Alt,
-				})
-				imagesAdded++
+		imagesPath := fmt.Sprintf("/products/%s/images.json", productID)
+		var current shopifyImageList
+		if err := a.doRequest(ctx, "GET", imagesPath, nil, &current); err != nil {
+			return 0, fmt.Errorf("failed to list images: %w", err)
+		}
+		existing := make(map[string]bool, 2*len(current.Images))
+		for _, img := range current.Images {
+			existing[img.Src] = true
+			existing[imageFilename(img.Src)] = true
+		}
+
+		for _, img := range newImages(product) {
+			if existing[img.Src] || existing[imageFilename(img.Src)] {
+				continue
+			}
+			if err := a.doRequest(ctx, "POST", imagesPath, shopifyImageCreate{Image: img}, nil); err != nil {
+				return imagesAdded, fmt.Errorf("failed to add image %s: %w", img.Src, err)
 			}
+			existing[img.Src] = true
+			existing[imageFilename(img.Src)] = true
+			imagesAdded++
 		}
 	}
 
-	// Make API request
-	body, err := json.Marshal(updateData)
-	if err != nil {
-		return 0, err
+	return imagesAdded, nil
+}
+
+// imageFilename returns the file name of an image URL without its query.
+// Shopify rehosts uploaded images on its CDN under their original file name,
+// so this matches an image to the source URL it was created from.
+func imageFilename(src string) string {
+	if i := strings.IndexAny(src, "?#"); i >= 0 {
+		src = src[:i]
 	}
+	return strings.ToLower(path.Base(src))
+}
 
-	url := fmt.Sprintf("%s/products/%s.json", a.baseURL, product.ID)
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
-	if err != nil {
-		return 0, err
+// findProductIDBySKU looks up the product owning a variant SKU via GraphQL.
+// Returns "" if no variant has the SKU.
+func (a *Adapter) findProductIDBySKU(ctx context.Context, sku string) (string, error) {
+	query := shopifyGraphQLRequest{
+		Query: `query($q: String!) { productVariants(first: 1, query: $q) { edges { node { product { id } } } } }`,
+		Variables: map[string]string{
+			"q": fmt.Sprintf("sku:%q", sku),
+		},
 	}
 
-	req.Header.Set("X-Shopify-Access-Token", a.config.APIKey)
-	req.Header.Set("Content-Type", "application/json")
+	var resp shopifyVariantLookupResponse
+	if err := a.doRequest(ctx, "POST", "/graphql.json", query, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Errors) > 0 {
+		return "", fmt.Errorf("shopify GraphQL error: %s", resp.Errors[0].Message)
+	}
+	if len(resp.Data.ProductVariants.Edges) == 0 {
+		return "", nil
+	}
 
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return 0, err
+	// GraphQL IDs look like gid://shopify/Product/123456789
+	gid := resp.Data.ProductVariants.Edges[0].Node.Product.ID
+	return gid[strings.LastIndex(gid, "/")+1:], nil
+}
+
+// rateLimitWait waits if needed to stay within Shopify's 2 req/s leaky bucket
+func (a *Adapter) rateLimitWait(ctx context.Context) error {
+	a.rateLimitMu.Lock()
+	defer a.rateLimitMu.Unlock()
+
+	if wait := requestInterval - time.Since(a.lastRequest); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
 	}
-	defer resp.Body.Close()
+	a.lastRequest = time.Now()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("shopify API error (status %d): %s", resp.StatusCode, string(respBody))
+// doRequest performs a rate-limited JSON request against the Admin API,
+// retrying when Shopify responds with 429 Too Many Requests
+func (a *Adapter) doRequest(ctx context.Context, method, path string, payload, out interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return err
+		}
 	}
 
-	return imagesAdded, nil
+	for attempt := 0; ; attempt++ {
+		if err := a.rateLimitWait(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("X-Shopify-Access-Token", a.config.APIKey)
+		req.Header.Set("Content-Type", "application/json")
+
+		start := time.Now()
+		resp, err := a.client.Do(req)
+		if err != nil {
+			a.Logger().Warn("request failed", "method", method, "path", path, "error", err)
+			return err
+		}
+		a.Logger().Debug("request", "method", method, "path", path, "status", resp.StatusCode, "duration", time.Since(start))
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < maxRetries {
+			resp.Body.Close()
+			retryAfter := 2 * time.Second
+			if secs, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil && secs > 0 {
+				retryAfter = time.Duration(secs * float64(time.Second))
+			}
+			a.Logger().Warn("rate limited, retrying", "method", method, "path", path, "attempt", attempt+1, "retry_after", retryAfter)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryAfter):
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			respBody, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("shopify API error (status %d): %s", resp.StatusCode, string(respBody))
+		}
+
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("failed to decode response: %w", err)
+			}
+		}
+		return nil
+	}
 }
 
 // Shopify API types
@@ -246,12 +437,21 @@ type shopifyProductUpdate struct {
 }
 
 type shopifyProduct struct {
-	ID          string         `json:"id,omitempty"`
-	Title       string         `json:"title,omitempty"`
-	BodyHTML    string         `json:"body_html,omitempty"`
-	Vendor      string         `json:"vendor,omitempty"`
-	ProductType string         `json:"product_type,omitempty"`
-	Images      []shopifyImage `json:"images,omitempty"`
+	ID          string           `json:"id,omitempty"`
+	Title       string           `json:"title,omitempty"`
+	BodyHTML    string           `json:"body_html,omitempty"`
+	Vendor      string           `json:"vendor,omitempty"`
+	ProductType string           `json:"product_type,omitempty"`
+	Tags        string           `json:"tags,omitempty"`
+	Variants    []shopifyVariant `json:"variants,omitempty"`
+	Images      []shopifyImage   `json:"images,omitempty"`
+}
+
+type shopifyVariant struct {
+	SKU     string `json:"sku,omitempty"`
+	Barcode string `json:"barcode,omitempty"`
+	Price   string `json:"price,omitempty"`
+	Grams   int    `json:"grams,omitempty"`
 }
 
 type shopifyImage struct {
@@ -259,3 +459,33 @@ type shopifyImage struct {
 	Position int    `json:"position,omitempty"`
 	Alt      string `json:"alt,omitempty"`
 }
+
+type shopifyImageCreate struct {
+	Image shopifyImage `json:"image"`
+}
+
+type shopifyImageList struct {
+	Images []shopifyImage `json:"images"`
+}
+
+type shopifyGraphQLRequest struct {
+	Query     string            `json:"query"`
+	Variables map[string]string `json:"variables,omitempty"`
+}
+
+type shopifyVariantLookupResponse struct {
+	Data struct {
+		ProductVariants struct {
+			Edges []struct {
+				Node struct {
+					Product struct {
+						ID string `json:"id"`
+					} `json:"product"`
+				} `json:"node"`
+			} `json:"edges"`
+		} `json:"productVariants"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
@@ -3,13 +3,50 @@ package parser
 import (
 	"encoding/csv"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/badno/badops/pkg/models"
 )
 
-// ParseMatrixifyCSV parses a Matrixify or Shopify export CSV file
+// ParseMatrixifyCSV parses a Matrixify or Shopify export CSV file and returns
+// its Tiger products
 func ParseMatrixifyCSV(filepath string) ([]models.Product, error) {
+	enhanced, err := ParseMatrixifyProducts(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	products := []models.Product{}
+	for _, p := range enhanced {
+		// Only include Tiger products
+		if !strings.EqualFold(p.Vendor, "Tiger") {
+			continue
+		}
+
+		var images []string
+		for _, img := range p.Images {
+			images = append(images, img.SourceURL)
+		}
+		products = append(products, models.Product{
+			SKU:            p.SKU,
+			Name:           p.Title,
+			Brand:          p.Vendor,
+			Barcode:        p.Barcode,
+			ExistingImages: images,
+		})
+	}
+
+	return products, nil
+}
+
+// ParseMatrixifyProducts parses a Matrixify or Shopify export CSV file into
+// one product per variant SKU. Product fields (title, body, vendor, type,
+// tags) come from the handle's first row and images from every row of the
+// handle, since exports put additional images and variants on rows that only
+// repeat the handle.
+func ParseMatrixifyProducts(filepath string) ([]models.EnhancedProduct, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
 		return nil, err
@@ -18,6 +55,7 @@ func ParseMatrixifyCSV(filepath string) ([]models.Product, error) {
 
 	reader := csv.NewReader(file)
 	reader.LazyQuotes = true // Handle Shopify's sometimes malformed CSV
+	reader.FieldsPerRecord = -1
 
 	records, err := reader.ReadAll()
 	if err != nil {
@@ -25,7 +63,7 @@ func ParseMatrixifyCSV(filepath string) ([]models.Product, error) {
 	}
 
 	if len(records) < 2 {
-		return []models.Product{}, nil
+		return []models.EnhancedProduct{}, nil
 	}
 
 	// Find column indices - support both Matrixify and Shopify formats
@@ -35,77 +73,138 @@ func ParseMatrixifyCSV(filepath string) ([]models.Product, error) {
 		header[0] = strings.TrimPrefix(header[0], "\ufeff")
 	}
 
-	handleIdx := findColumn(header, "Handle")
-	titleIdx := findColumn(header, "Title")
-	vendorIdx := findColumn(header, "Vendor")
-	imageIdx := findColumn(header, "Image Src")
+	col := func(names ...string) int {
+		for _, name := range names {
+			if i := findColumn(header, name); i >= 0 {
+				return i
+			}
+		}
+		return -1
+	}
+	idIdx := col("ID")
+	handleIdx := col("Handle")
+	titleIdx := col("Title")
+	bodyIdx := col("Body HTML", "Body (HTML)")
+	vendorIdx := col("Vendor")
+	typeIdx := col("Type")
+	tagsIdx := col("Tags")
+	updatedIdx := col("Updated At")
+	imageIdx := col("Image Src")
+	imageAltIdx := col("Image Alt Text")
+	barcodeIdx := col("Variant Barcode")
+	priceIdx := col("Variant Price")
+	compareAtIdx := col("Variant Compare At Price")
+	costIdx := col("Variant Cost", "Cost per item")
 
 	// Shopify-specific columns
-	skuIdx := findColumn(header, "Variant SKU")
+	skuIdx := col("Variant SKU")
 	if skuIdx < 0 {
 		skuIdx = handleIdx // Fallback to Handle if no Variant SKU
 	}
 
-	// Track seen SKUs to avoid duplicates (Shopify has multiple rows per variant)
-	seen := make(map[string]bool)
-	var products []models.Product
-
-	for _, row := range records[1:] {
-		if len(row) <= titleIdx {
-			continue
+	field := func(row []string, i int) string {
+		if i < 0 || i >= len(row) {
+			return ""
 		}
+		return strings.TrimSpace(row[i])
+	}
 
-		// Get SKU
-		sku := ""
-		if skuIdx >= 0 && len(row) > skuIdx {
-			sku = strings.TrimSpace(row[skuIdx])
-		}
-		if sku == "" {
-			continue
-		}
+	// Product rows by handle, so image rows can be attached to their variants
+	parents := make(map[string]*models.EnhancedProduct)
+	images := make(map[string][]models.ProductImage)
+	var products []*models.EnhancedProduct
+	seen := make(map[string]bool) // Shopify has multiple rows per variant
 
-		// Skip duplicates
-		if seen[sku] {
-			continue
+	for _, row := range records[1:] {
+		handle := field(row, handleIdx)
+		sku := field(row, skuIdx)
+		imageKey := handle
+		if imageKey == "" {
+			imageKey = sku
 		}
-		seen[sku] = true
-
-		// Check if it's a Tiger product
-		vendor := ""
-		if vendorIdx >= 0 && len(row) > vendorIdx {
-			vendor = strings.TrimSpace(row[vendorIdx])
+		parent := parents[handle]
+		if parent == nil || handle == "" {
+			parent = &models.EnhancedProduct{
+				ID:          field(row, idIdx),
+				Handle:      handle,
+				Title:       field(row, titleIdx),
+				Description: field(row, bodyIdx),
+				Vendor:      field(row, vendorIdx),
+				ProductType: field(row, typeIdx),
+				Status:      models.StatusPending,
+			}
+			if tags := field(row, tagsIdx); tags != "" {
+				for _, tag := range strings.Split(tags, ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						parent.Tags = append(parent.Tags, tag)
+					}
+				}
+			}
+			if t, err := time.Parse(time.RFC3339, field(row, updatedIdx)); err == nil {
+				parent.UpdatedAt = t
+			} else if t, err := time.Parse("2006-01-02 15:04:05 -0700", field(row, updatedIdx)); err == nil {
+				parent.UpdatedAt = t
+			}
+			if handle != "" {
+				parents[handle] = parent
+			}
 		}
 
-		// Only include Tiger products
-		if !strings.EqualFold(vendor, "Tiger") {
-			continue
+		// Image Src may list several comma-separated URLs
+		if src := field(row, imageIdx); src != "" {
+			for _, url := range strings.Split(src, ",") {
+				if url = strings.TrimSpace(url); url == "" {
+					continue
+				}
+				images[imageKey] = append(images[imageKey], models.ProductImage{
+					SourceURL: url,
+					Position:  len(images[imageKey]) + 1,
+					Alt:       field(row, imageAltIdx),
+					Status:    "existing",
+					Source:    "matrixify",
+				})
+			}
 		}
 
-		// Get title
-		title := ""
-		if titleIdx >= 0 && len(row) > titleIdx {
-			title = strings.TrimSpace(row[titleIdx])
+		if sku == "" || seen[sku] {
+			continue
 		}
+		seen[sku] = true
 
-		// Get images
-		var images []string
-		if imageIdx >= 0 && len(row) > imageIdx && row[imageIdx] != "" {
-			images = strings.Split(row[imageIdx], ",")
-			for i := range images {
-				images[i] = strings.TrimSpace(images[i])
+		product := *parent
+		product.SKU = sku
+		product.Barcode = field(row, barcodeIdx)
+		product.Tags = append([]string(nil), parent.Tags...)
+		if price := parseAmount(field(row, priceIdx)); price > 0 {
+			product.Price = &models.Price{
+				Amount:      price,
+				Currency:    "NOK", // Default to NOK for bad.no
+				CompareAt:   parseAmount(field(row, compareAtIdx)),
+				CostPerItem: parseAmount(field(row, costIdx)),
 			}
 		}
+		products = append(products, &product)
+	}
 
-		product := models.Product{
-			SKU:            sku,
-			Name:           title,
-			Brand:          vendor,
-			ExistingImages: images,
+	result := make([]models.EnhancedProduct, 0, len(products))
+	for _, p := range products {
+		key := p.Handle
+		if key == "" {
+			key = p.SKU
 		}
-		products = append(products, product)
+		p.Images = append([]models.ProductImage(nil), images[key]...)
+		result = append(result, *p)
 	}
+	return result, nil
+}
 
-	return products, nil
+// parseAmount parses a price cell, returning 0 when it is empty or invalid
+func parseAmount(s string) float64 {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	return f
 }
 
 func findColumn(header []string, name string) int {
@@ -0,0 +1,237 @@
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCurrency is the base currency prices are normalized to when none
+// is configured
+const DefaultCurrency = "NOK"
+
+// currencySymbols maps symbols and ISO codes found in price cells to ISO
+// codes. "kr" is shared by NOK, SEK and DKK, so it is left to the column or
+// base currency.
+var currencySymbols = []struct {
+	token string
+	code  string
+}{
+	{"NOK", "NOK"},
+	{"SEK", "SEK"},
+	{"DKK", "DKK"},
+	{"EUR", "EUR"},
+	{"USD", "USD"},
+	{"GBP", "GBP"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"$", "USD"},
+}
+
+// DetectCurrency returns the ISO currency code written in a price such as
+// "€ 129,00" or "1 299 SEK", or "" if it names none
+func DetectCurrency(s string) string {
+	upper := strings.ToUpper(s)
+	for _, c := range currencySymbols {
+		if strings.Contains(upper, c.token) {
+			return c.code
+		}
+	}
+	return ""
+}
+
+// normalizeCurrencyCode upper-cases a currency column value and maps
+// symbols to ISO codes. Returns "" for values it does not recognise.
+func normalizeCurrencyCode(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	if code := DetectCurrency(s); code != "" {
+		return code
+	}
+	upper := strings.ToUpper(s)
+	if len(upper) == 3 && strings.IndexFunc(upper, func(r rune) bool { return r < 'A' || r > 'Z' }) < 0 {
+		return upper
+	}
+	return ""
+}
+
+// RateProvider returns exchange rates into a base currency
+type RateProvider interface {
+	// Rate returns how many units of base one unit of currency is worth
+	Rate(ctx context.Context, currency, base string) (float64, error)
+}
+
+// StaticRates are fixed exchange rates, in units of the base currency per
+// unit of each currency (e.g. EUR: 11.7 for a NOK base)
+type StaticRates map[string]float64
+
+// Rate returns the configured rate for currency
+func (r StaticRates) Rate(_ context.Context, currency, base string) (float64, error) {
+	rate, ok := r[strings.ToUpper(currency)]
+	if !ok || rate <= 0 {
+		return 0, fmt.Errorf("no exchange rate configured for %s to %s", currency, base)
+	}
+	return rate, nil
+}
+
+// HTTPRates fetches rates from a URL returning {"base": "NOK", "rates":
+// {"EUR": 0.085, ...}} as served by frankfurter.app and similar APIs, where
+// rates are units of each currency per unit of base. {base} in the URL is
+// replaced with the base currency. Responses are cached per base.
+type HTTPRates struct {
+	URL    string
+	client *http.Client
+	cache  map[string]map[string]float64
+}
+
+// NewHTTPRates creates a rates provider backed by an HTTP API
+func NewHTTPRates(url string) *HTTPRates {
+	return &HTTPRates{
+		URL:    url,
+		client: &http.Client{Timeout: 15 * time.Second},
+		cache:  make(map[string]map[string]float64),
+	}
+}
+
+// Rate returns the rate for currency, fetching the base's rates on first use
+func (h *HTTPRates) Rate(ctx context.Context, currency, base string) (float64, error) {
+	rates, ok := h.cache[base]
+	if !ok {
+		var err error
+		if rates, err = h.fetch(ctx, base); err != nil {
+			return 0, err
+		}
+		h.cache[base] = rates
+	}
+	perBase, ok := rates[strings.ToUpper(currency)]
+	if !ok || perBase <= 0 {
+		return 0, fmt.Errorf("rates provider has no rate for %s to %s", currency, base)
+	}
+	return 1 / perBase, nil
+}
+
+func (h *HTTPRates) fetch(ctx context.Context, base string) (map[string]float64, error) {
+	url := strings.ReplaceAll(h.URL, "{base}", base)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rates request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rates provider returned %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange rates: %w", err)
+	}
+	if body.Base != "" && !strings.EqualFold(body.Base, base) {
+		return nil, fmt.Errorf("rates provider returned rates for %s, expected %s", body.Base, base)
+	}
+
+	rates := make(map[string]float64, len(body.Rates))
+	for code, rate := range body.Rates {
+		rates[strings.ToUpper(code)] = rate
+	}
+	return rates, nil
+}
+
+// Converter normalizes prices to a base currency. Static rates win over
+// the provider, which may be nil.
+type Converter struct {
+	Base     string
+	Static   StaticRates
+	Provider RateProvider
+}
+
+// NewConverter creates a converter to base (default NOK)
+func NewConverter(base string, static StaticRates, provider RateProvider) *Converter {
+	base = strings.ToUpper(strings.TrimSpace(base))
+	if base == "" {
+		base = DefaultCurrency
+	}
+	rates := make(StaticRates, len(static))
+	for code, rate := range static {
+		rates[strings.ToUpper(code)] = rate
+	}
+	return &Converter{Base: base, Static: rates, Provider: provider}
+}
+
+// Convert returns amount in the base currency. An empty currency is taken
+// to be the base.
+func (c *Converter) Convert(ctx context.Context, amount float64, currency string) (float64, error) {
+	currency = strings.ToUpper(currency)
+	if currency == "" || currency == c.Base {
+		return amount, nil
+	}
+
+	rate, err := c.Static.Rate(ctx, currency, c.Base)
+	if err != nil && c.Provider != nil {
+		rate, err = c.Provider.Rate(ctx, currency, c.Base)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return math.Round(amount*rate*100) / 100, nil
+}
+
+// Normalize converts records quoted in another currency to the base
+// currency, keeping the quoted price in OriginalPrice/OriginalCurrency.
+// Records without a rate are dropped and reported, one message per currency.
+func (c *Converter) Normalize(ctx context.Context, records []CSVRecord) ([]CSVRecord, []string) {
+	kept := records[:0]
+	skipped := make(map[string]int)
+	firstErr := make(map[string]error)
+
+	for _, rec := range records {
+		currency := strings.ToUpper(rec.Currency)
+		if currency == "" || currency == c.Base {
+			rec.Currency = c.Base
+			kept = append(kept, rec)
+			continue
+		}
+
+		price, err := c.Convert(ctx, rec.CompetitorPrice, currency)
+		if err != nil {
+			skipped[currency]++
+			if _, ok := firstErr[currency]; !ok {
+				firstErr[currency] = err
+			}
+			continue
+		}
+		rec.OriginalPrice = rec.CompetitorPrice
+		rec.OriginalCurrency = currency
+		rec.CompetitorPrice = price
+		rec.Currency = c.Base
+		kept = append(kept, rec)
+	}
+
+	currencies := make([]string, 0, len(skipped))
+	for currency := range skipped {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var errs []string
+	for _, currency := range currencies {
+		errs = append(errs, fmt.Sprintf("skipped %d %s prices: %v", skipped[currency], currency, firstErr[currency]))
+	}
+	return kept, errs
+}
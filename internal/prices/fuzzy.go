@@ -0,0 +1,200 @@
+package prices
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/badno/badops/internal/matcher"
+	"github.com/google/uuid"
+)
+
+// DefaultFuzzyThreshold is the minimum token-set similarity for a title match
+const DefaultFuzzyThreshold = 0.85
+
+// maxFuzzyConfidence caps title match confidence, so fuzzy matches always rank
+// below exact SKU/barcode matches
+const maxFuzzyConfidence = 0.99
+
+// ambiguityMargin is how far the best title match must score above the next
+const ambiguityMargin = 0.02
+
+// FuzzyMatch is a product matched to a CSV record by title
+type FuzzyMatch struct {
+	ProductID  uuid.UUID
+	Title      string
+	Confidence float64
+}
+
+// TitleIndex finds catalog products by approximate title
+type TitleIndex struct {
+	titles    map[uuid.UUID]string
+	tokens    map[uuid.UUID][]string
+	byToken   map[string][]uuid.UUID
+	threshold float64
+}
+
+// NewTitleIndex builds an index over product titles. Matches scoring below
+// threshold are rejected.
+func NewTitleIndex(titles map[uuid.UUID]string, threshold float64) *TitleIndex {
+	idx := &TitleIndex{
+		titles:    titles,
+		tokens:    make(map[uuid.UUID][]string, len(titles)),
+		byToken:   make(map[string][]uuid.UUID),
+		threshold: threshold,
+	}
+
+	for id, title := range titles {
+		tokens := titleTokens(title)
+		idx.tokens[id] = tokens
+		for _, t := range tokens {
+			idx.byToken[t] = append(idx.byToken[t], id)
+		}
+	}
+
+	return idx
+}
+
+// Match returns the product whose title is most similar to title, if any
+// scores at or above the index threshold
+func (idx *TitleIndex) Match(title string) (FuzzyMatch, bool) {
+	tokens := titleTokens(title)
+	if len(tokens) == 0 {
+		return FuzzyMatch{}, false
+	}
+
+	// Only score products that share at least one token
+	candidates := make(map[uuid.UUID]bool)
+	for _, t := range tokens {
+		for _, id := range idx.byToken[t] {
+			candidates[id] = true
+		}
+	}
+
+	var best FuzzyMatch
+	runnerUp := 0.0
+	for id := range candidates {
+		score := tokenSetRatio(tokens, idx.tokens[id])
+		if score > best.Confidence {
+			runnerUp = best.Confidence
+			best = FuzzyMatch{ProductID: id, Title: idx.titles[id], Confidence: score}
+		} else if score > runnerUp {
+			runnerUp = score
+		}
+	}
+
+	// Reject ambiguous titles that fit several products equally well
+	if best.Confidence < idx.threshold || best.Confidence-runnerUp < ambiguityMargin {
+		return FuzzyMatch{}, false
+	}
+	best.Confidence = min(best.Confidence, maxFuzzyConfidence)
+	return best, true
+}
+
+// MatchRecords fuzzy-matches records whose SKU and barcode are not in
+// productMap. Returns matches keyed by record SKU and the SKUs left unmatched.
+func (idx *TitleIndex) MatchRecords(records []CSVRecord, productMap map[string]uuid.UUID) (map[string]FuzzyMatch, []string) {
+	matches := make(map[string]FuzzyMatch)
+	var unmatched []string
+	seen := make(map[string]bool)
+
+	for _, rec := range records {
+		if seen[rec.SKU] || exactProductID(rec, productMap) != uuid.Nil {
+			continue
+		}
+		seen[rec.SKU] = true
+
+		if m, ok := idx.Match(rec.ProductTitle); ok {
+			matches[rec.SKU] = m
+		} else {
+			unmatched = append(unmatched, rec.SKU)
+		}
+	}
+
+	return matches, unmatched
+}
+
+// titleTokens normalizes a title with matcher.NormalizeTitle and splits it
+// into unique tokens
+func titleTokens(title string) []string {
+	fields := strings.Fields(matcher.NormalizeTitle(title))
+
+	seen := make(map[string]bool, len(fields))
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			tokens = append(tokens, f)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// tokenSetRatio compares two sorted token sets the way fuzzywuzzy's
+// token_set_ratio does: the shared tokens are compared with each side's full
+// token list, so extra words on one side (colour, size) cost little
+func tokenSetRatio(a, b []string) float64 {
+	var common, onlyA, onlyB []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			common = append(common, a[i])
+			i++
+			j++
+		case a[i] < b[j]:
+			onlyA = append(onlyA, a[i])
+			i++
+		default:
+			onlyB = append(onlyB, b[j])
+			j++
+		}
+	}
+	onlyA = append(onlyA, a[i:]...)
+	onlyB = append(onlyB, b[j:]...)
+
+	sect := strings.Join(common, " ")
+	withA := strings.TrimSpace(sect + " " + strings.Join(onlyA, " "))
+	withB := strings.TrimSpace(sect + " " + strings.Join(onlyB, " "))
+
+	best := ratio(withA, withB)
+	// A single shared word is not enough to call one title a subset of the other
+	if len(common) > 1 {
+		best = max(best, ratio(sect, withA), ratio(sect, withB))
+	}
+	return best
+}
+
+// ratio returns the normalized edit similarity of two strings (0-1)
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	total := len(ra) + len(rb)
+	if total == 0 {
+		return 1
+	}
+	return float64(total-levenshtein(ra, rb)) / float64(total)
+}
+
+// levenshtein returns the edit distance between two rune slices, counting a
+// substitution as a deletion plus an insertion
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 2
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
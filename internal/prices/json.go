@@ -0,0 +1,157 @@
+package prices
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// jsonRecord is one competitor price in a JSON price feed
+type jsonRecord struct {
+	SKU           string    `json:"sku"`
+	Barcode       string    `json:"barcode"`
+	Title         string    `json:"title"`
+	Vendor        string    `json:"vendor"`
+	Competitor    string    `json:"competitor"`
+	Price         jsonPrice `json:"price"`
+	Currency      string    `json:"currency"`
+	InStock       *bool     `json:"in_stock"`
+	StockQuantity *int      `json:"stock_quantity"`
+	URL           string    `json:"url"`
+	ObservedAt    string    `json:"observed_at"`
+}
+
+// jsonPrice accepts a price as a JSON number or a string such as "1 299,00 kr"
+// or "€ 129,00", keeping any currency the string names
+type jsonPrice struct {
+	value    float64
+	currency string
+}
+
+func (p *jsonPrice) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		p.value = (&Parser{}).parseFloat(strings.TrimSuffix(strings.TrimSpace(s), "kr"))
+		p.currency = DetectCurrency(s)
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("price must be a number or string: %s", data)
+	}
+	p.value = f
+	return nil
+}
+
+// JSONParser handles parsing of JSON price feeds: an array of
+// {sku, barcode, competitor, price, currency, in_stock, url, observed_at} objects.
+// The records have the same shape as a Reprice CSV export, so they go
+// through the same product matching and conversion.
+type JSONParser struct{}
+
+// NewJSONParser creates a new JSON price feed parser
+func NewJSONParser() *JSONParser {
+	return &JSONParser{}
+}
+
+// ParseFile parses a JSON price feed file
+func (p *JSONParser) ParseFile(filePath string) (*ParseResult, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return p.Parse(file)
+}
+
+// Parse parses a JSON price feed from a reader. Objects without a SKU,
+// competitor or positive price are skipped and reported in Errors.
+func (p *JSONParser) Parse(r io.Reader) (*ParseResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	data, encoding, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON price feed (expected an array of objects): %w", err)
+	}
+
+	result := &ParseResult{
+		Records:         make([]CSVRecord, 0, len(items)),
+		Competitors:     make(map[string]bool),
+		ObservationTime: time.Now(),
+		Encoding:        encoding,
+	}
+
+	seenProducts := make(map[string]bool)
+	for i, raw := range items {
+		var item jsonRecord
+		if err := json.Unmarshal(raw, &item); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: %v", i, err))
+			continue
+		}
+
+		sku := strings.TrimSpace(item.SKU)
+		competitor := strings.TrimSpace(item.Competitor)
+		switch {
+		case sku == "":
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: missing sku", i))
+			continue
+		case competitor == "":
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d (%s): missing competitor", i, sku))
+			continue
+		case item.Price.value <= 0:
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d (%s): missing or invalid price", i, sku))
+			continue
+		}
+
+		observedAt := result.ObservationTime
+		if item.ObservedAt != "" {
+			t, err := parseTime(item.ObservedAt)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("item %d (%s): invalid observation date %q, using import time", i, sku, item.ObservedAt))
+			} else {
+				observedAt = t
+			}
+		}
+
+		// Like a CSV without a stock column, a missing in_stock means in stock
+		// unless the quantity says otherwise
+		stock := true
+		if item.InStock != nil {
+			stock = *item.InStock
+		} else if item.StockQuantity != nil {
+			stock = *item.StockQuantity > 0
+		}
+
+		seenProducts[sku] = true
+		result.Competitors[competitor] = true
+		result.Records = append(result.Records, CSVRecord{
+			SKU:                     sku,
+			Barcode:                 BarcodeKey(item.Barcode),
+			ProductTitle:            strings.TrimSpace(item.Title),
+			Vendor:                  strings.TrimSpace(item.Vendor),
+			CompetitorName:          competitor,
+			CompetitorPrice:         item.Price.value,
+			CompetitorStock:         stock,
+			CompetitorStockQuantity: item.StockQuantity,
+			CompetitorURL:           strings.TrimSpace(item.URL),
+			ObservedAt:              observedAt,
+			Source:                  SourceJSON,
+			Currency:                firstNonEmpty(normalizeCurrencyCode(item.Currency), item.Price.currency),
+		})
+	}
+
+	result.ProductCount = len(seenProducts)
+	return result, nil
+}
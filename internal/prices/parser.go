@@ -1,6 +1,7 @@
 package prices
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -8,25 +9,43 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/pkg/models"
 	"github.com/google/uuid"
+	"golang.org/x/text/encoding/charmap"
 )
 
-// CSVRecord represents a single row from the Reprice CSV export
+// Observation sources recorded on imported prices
+const (
+	SourceRepriceCSV = "reprice_csv"
+	SourceJSON       = "api"
+)
+
+// CSVRecord represents a single row from the Reprice CSV export, or one
+// object from a JSON price feed
 type CSVRecord struct {
-	SKU              string
-	Barcode          string
-	ProductTitle     string
-	Vendor           string
-	OwnPrice         float64
-	OwnStock         bool
-	OwnStockQuantity *int
-	CompetitorName   string
-	CompetitorPrice  float64
-	CompetitorStock  bool
-	CompetitorURL    string
-	ObservedAt       time.Time
+	SKU                     string
+	Barcode                 string
+	ProductTitle            string
+	Vendor                  string
+	OwnPrice                float64
+	OwnStock                bool
+	OwnStockQuantity        *int
+	CompetitorName          string
+	CompetitorPrice         float64
+	CompetitorStock         bool
+	CompetitorStockQuantity *int
+	CompetitorURL           string
+	ObservedAt              time.Time
+	Source                  string // Observation source; empty means SourceRepriceCSV
+	Currency                string // ISO code of CompetitorPrice; empty means the base currency
+
+	// Set by Converter.Normalize when CompetitorPrice was converted
+	OriginalPrice    float64
+	OriginalCurrency string
 }
 
 // ParseResult contains the results of parsing a Reprice CSV file
@@ -35,6 +54,8 @@ type ParseResult struct {
 	Competitors     map[string]bool
 	ProductCount    int
 	ObservationTime time.Time
+	Delimiter       rune
+	Encoding        string
 	Errors          []string
 }
 
@@ -47,7 +68,13 @@ type Parser struct {
 	colVendor           int
 	colOwnPrice         int
 	colOwnStock         int
+	colOwnStockQty      int
+	colObservedAt       int
+	colCurrency         int
 	colCompetitorPrefix string
+
+	// Field delimiter; 0 means detect from the header line
+	delimiter rune
 }
 
 // NewParser creates a new Reprice CSV parser
@@ -59,10 +86,18 @@ func NewParser() *Parser {
 		colVendor:           -1,
 		colOwnPrice:         -1,
 		colOwnStock:         -1,
+		colOwnStockQty:      -1,
+		colObservedAt:       -1,
+		colCurrency:         -1,
 		colCompetitorPrefix: "competitor_",
 	}
 }
 
+// SetDelimiter overrides delimiter detection. Pass 0 to detect automatically.
+func (p *Parser) SetDelimiter(delimiter rune) {
+	p.delimiter = delimiter
+}
+
 // ParseFile parses a Reprice CSV file and returns the records
 func (p *Parser) ParseFile(filePath string) (*ParseResult, error) {
 	file, err := os.Open(filePath)
@@ -76,7 +111,23 @@ func (p *Parser) ParseFile(filePath string) (*ParseResult, error) {
 
 // Parse parses Reprice CSV data from a reader
 func (p *Parser) Parse(r io.Reader) (*ParseResult, error) {
-	reader := csv.NewReader(r)
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data: %w", err)
+	}
+
+	data, encoding, err := decodeText(data)
+	if err != nil {
+		return nil, err
+	}
+
+	delimiter := p.delimiter
+	if delimiter == 0 {
+		delimiter = detectDelimiter(data)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
 	reader.FieldsPerRecord = -1 // Allow variable number of fields
 	reader.TrimLeadingSpace = true
 
@@ -93,6 +144,8 @@ func (p *Parser) Parse(r io.Reader) (*ParseResult, error) {
 		Records:         make([]CSVRecord, 0),
 		Competitors:     make(map[string]bool),
 		ObservationTime: time.Now(),
+		Delimiter:       delimiter,
+		Encoding:        encoding,
 	}
 
 	// Track unique products
@@ -123,11 +176,24 @@ func (p *Parser) Parse(r io.Reader) (*ParseResult, error) {
 			continue // Skip rows without SKU
 		}
 
-		barcode := p.getField(row, p.colBarcode)
+		barcode := BarcodeKey(p.getField(row, p.colBarcode))
 		title := p.getField(row, p.colTitle)
 		vendor := p.getField(row, p.colVendor)
 		ownPrice := p.parseFloat(p.getField(row, p.colOwnPrice))
 		ownStock := p.parseBool(p.getField(row, p.colOwnStock))
+		ownStockQty := p.parseInt(p.getField(row, p.colOwnStockQty))
+		rowCurrency := normalizeCurrencyCode(p.getField(row, p.colCurrency))
+
+		// Use the row's own observation time when the export has one
+		observedAt := result.ObservationTime
+		if raw := p.getField(row, p.colObservedAt); raw != "" {
+			t, err := parseTime(raw)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("line %d: invalid observation date %q, using import time", lineNum, raw))
+			} else {
+				observedAt = t
+			}
+		}
 
 		// Track unique products
 		seenProducts[sku] = true
@@ -144,9 +210,16 @@ func (p *Parser) Parse(r io.Reader) (*ParseResult, error) {
 				continue
 			}
 
+			var stockQty *int
+			if cols.qtyCol >= 0 {
+				stockQty = p.parseInt(p.getField(row, cols.qtyCol))
+			}
+
 			stock := true
 			if cols.stockCol >= 0 {
 				stock = p.parseBool(p.getField(row, cols.stockCol))
+			} else if stockQty != nil {
+				stock = *stockQty > 0
 			}
 
 			url := ""
@@ -154,18 +227,30 @@ func (p *Parser) Parse(r io.Reader) (*ParseResult, error) {
 				url = p.getField(row, cols.urlCol)
 			}
 
+			// A currency in the price cell wins over the currency columns
+			currency := DetectCurrency(priceStr)
+			if currency == "" && cols.currencyCol >= 0 {
+				currency = normalizeCurrencyCode(p.getField(row, cols.currencyCol))
+			}
+			if currency == "" {
+				currency = rowCurrency
+			}
+
 			record := CSVRecord{
-				SKU:             sku,
-				Barcode:         barcode,
-				ProductTitle:    title,
-				Vendor:          vendor,
-				OwnPrice:        ownPrice,
-				OwnStock:        ownStock,
-				CompetitorName:  competitorName,
-				CompetitorPrice: price,
-				CompetitorStock: stock,
-				CompetitorURL:   url,
-				ObservedAt:      result.ObservationTime,
+				SKU:                     sku,
+				Barcode:                 barcode,
+				ProductTitle:            title,
+				Vendor:                  vendor,
+				OwnPrice:                ownPrice,
+				OwnStock:                ownStock,
+				OwnStockQuantity:        ownStockQty,
+				CompetitorName:          competitorName,
+				CompetitorPrice:         price,
+				CompetitorStock:         stock,
+				CompetitorStockQuantity: stockQty,
+				CompetitorURL:           url,
+				ObservedAt:              observedAt,
+				Currency:                currency,
 			}
 
 			result.Records = append(result.Records, record)
@@ -177,10 +262,44 @@ func (p *Parser) Parse(r io.Reader) (*ParseResult, error) {
 	return result, nil
 }
 
+// decodeText strips a UTF-8 byte order mark and transcodes Windows-1252
+// (as written by Excel on Windows) to UTF-8 when the data is not valid UTF-8.
+// Returns the UTF-8 data and the name of the detected encoding.
+func decodeText(data []byte) ([]byte, string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data, "utf-8", nil
+	}
+
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode Windows-1252 data: %w", err)
+	}
+	return decoded, "windows-1252", nil
+}
+
+// detectDelimiter picks the most frequent of comma, semicolon and tab in the
+// header line, defaulting to comma
+func detectDelimiter(data []byte) rune {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+
+	delimiter := ','
+	best := bytes.Count(line, []byte(","))
+	for _, candidate := range []rune{';', '\t'} {
+		if n := bytes.Count(line, []byte(string(candidate))); n > best {
+			delimiter = candidate
+			best = n
+		}
+	}
+	return delimiter
+}
+
 type competitorColumns struct {
-	priceCol int
-	stockCol int
-	urlCol   int
+	priceCol    int
+	stockCol    int
+	qtyCol      int
+	urlCol      int
+	currencyCol int
 }
 
 // mapHeader maps column indices from the header row
@@ -203,6 +322,12 @@ func (p *Parser) mapHeader(header []string) map[string]competitorColumns {
 			p.colOwnPrice = i
 		case colLower == "stock" || colLower == "in_stock" || colLower == "our_stock":
 			p.colOwnStock = i
+		case colLower == "qty" || colLower == "quantity" || colLower == "inventory" || colLower == "stock_quantity" || colLower == "our_qty":
+			p.colOwnStockQty = i
+		case colLower == "date" || colLower == "observed_at" || colLower == "scraped_at" || colLower == "timestamp":
+			p.colObservedAt = i
+		case colLower == "currency":
+			p.colCurrency = i
 		default:
 			// Check for competitor columns
 			// Format: "Competitor Name" or "competitor_name_price" etc.
@@ -210,11 +335,15 @@ func (p *Parser) mapHeader(header []string) map[string]competitorColumns {
 			if competitorName != "" {
 				cols, exists := competitors[competitorName]
 				if !exists {
-					cols = competitorColumns{priceCol: -1, stockCol: -1, urlCol: -1}
+					cols = competitorColumns{priceCol: -1, stockCol: -1, qtyCol: -1, urlCol: -1, currencyCol: -1}
 				}
 
-				if strings.Contains(colLower, "price") {
+				if strings.Contains(colLower, "currency") {
+					cols.currencyCol = i
+				} else if strings.Contains(colLower, "price") {
 					cols.priceCol = i
+				} else if isQuantityColumn(colLower) {
+					cols.qtyCol = i
 				} else if strings.Contains(colLower, "stock") || strings.Contains(colLower, "availability") {
 					cols.stockCol = i
 				} else if strings.Contains(colLower, "url") || strings.Contains(colLower, "link") {
@@ -238,7 +367,8 @@ func (p *Parser) extractCompetitorName(col string) string {
 	colLower := strings.ToLower(col)
 
 	// Skip standard columns
-	standardCols := []string{"sku", "barcode", "ean", "title", "name", "vendor", "brand", "price", "stock", "id", "handle"}
+	standardCols := []string{"sku", "barcode", "ean", "title", "name", "vendor", "brand", "price", "stock", "id", "handle", "qty", "quantity", "inventory",
+		"date", "observed_at", "scraped_at", "timestamp", "currency"}
 	for _, std := range standardCols {
 		if colLower == std {
 			return ""
@@ -246,7 +376,8 @@ func (p *Parser) extractCompetitorName(col string) string {
 	}
 
 	// Remove common suffixes
-	suffixes := []string{"_price", "_stock", "_url", "_link", " price", " stock", " url", " link", " availability"}
+	suffixes := []string{"_price", "_stock", "_qty", "_quantity", "_inventory", "_url", "_link", "_currency",
+		" price", " stock", " qty", " quantity", " inventory", " url", " link", " availability", " currency"}
 	name := col
 	for _, suffix := range suffixes {
 		if strings.HasSuffix(strings.ToLower(name), suffix) {
@@ -264,6 +395,12 @@ func (p *Parser) extractCompetitorName(col string) string {
 	return name
 }
 
+// isQuantityColumn reports whether a competitor column holds a stock quantity
+// (e.g. "X_qty", "X_inventory", "X stock quantity")
+func isQuantityColumn(colLower string) bool {
+	return strings.Contains(colLower, "qty") || strings.Contains(colLower, "quantity") || strings.Contains(colLower, "inventory")
+}
+
 // getField safely gets a field from a row
 func (p *Parser) getField(row []string, index int) string {
 	if index < 0 || index >= len(row) {
@@ -282,31 +419,67 @@ func (p *Parser) parseFloat(s string) float64 {
 	s = strings.TrimPrefix(s, "$")
 	s = strings.TrimPrefix(s, "€")
 	s = strings.TrimSpace(s)
+	s = strings.TrimFunc(s, func(r rune) bool { return !unicode.IsDigit(r) && r != '.' && r != '-' })
 
 	f, _ := strconv.ParseFloat(s, 64)
 	return f
 }
 
+// parseInt parses a stock quantity, returning nil if the field is empty or not a number
+func (p *Parser) parseInt(s string) *int {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if s == "" {
+		return nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		// Some exports write quantities as decimals (e.g. "12.0")
+		f, ferr := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+		if ferr != nil {
+			return nil
+		}
+		n = int(f)
+	}
+	return &n
+}
+
+// timeLayouts are the observation date formats accepted in Reprice exports
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// parseTime parses an observation timestamp in any of the supported layouts
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date format: %s", s)
+}
+
 // parseBool parses a string to bool
 func (p *Parser) parseBool(s string) bool {
 	s = strings.ToLower(strings.TrimSpace(s))
 	return s == "true" || s == "yes" || s == "1" || s == "in stock" || s == "available"
 }
 
-// ConvertToPriceObservations converts parsed records to database price observations
-func ConvertToPriceObservations(records []CSVRecord, productMap map[string]uuid.UUID, competitorMap map[string]int) []*database.PriceObservation {
+// ConvertToPriceObservations converts parsed records to database price observations.
+// Records without an exact SKU/barcode match fall back to fuzzy, which may be nil.
+func ConvertToPriceObservations(records []CSVRecord, productMap map[string]uuid.UUID, fuzzy map[string]FuzzyMatch, competitorMap map[string]int) []*database.PriceObservation {
 	observations := make([]*database.PriceObservation, 0, len(records))
 
 	for _, rec := range records {
-		productID, ok := productMap[rec.SKU]
-		if !ok {
-			// Try barcode lookup
-			if rec.Barcode != "" {
-				productID, ok = productMap[rec.Barcode]
-			}
-			if !ok {
-				continue // Product not found
-			}
+		productID, _, _ := resolveProduct(rec, productMap, fuzzy)
+		if productID == uuid.Nil {
+			continue // Product not found
 		}
 
 		competitorID, ok := competitorMap[rec.CompetitorName]
@@ -314,14 +487,30 @@ func ConvertToPriceObservations(records []CSVRecord, productMap map[string]uuid.
 			continue // Competitor not found
 		}
 
+		source := rec.Source
+		if source == "" {
+			source = SourceRepriceCSV
+		}
+
+		currency := rec.Currency
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+
 		obs := &database.PriceObservation{
-			ProductID:    productID,
-			CompetitorID: competitorID,
-			Price:        rec.CompetitorPrice,
-			Currency:     "NOK",
-			InStock:      rec.CompetitorStock,
-			ObservedAt:   rec.ObservedAt,
-			Source:       "reprice_csv",
+			ProductID:     productID,
+			CompetitorID:  competitorID,
+			Price:         rec.CompetitorPrice,
+			Currency:      currency,
+			InStock:       rec.CompetitorStock,
+			StockQuantity: rec.CompetitorStockQuantity,
+			ObservedAt:    rec.ObservedAt,
+			Source:        source,
+		}
+		if rec.OriginalCurrency != "" {
+			originalPrice := rec.OriginalPrice
+			obs.OriginalPrice = &originalPrice
+			obs.OriginalCurrency = rec.OriginalCurrency
 		}
 
 		observations = append(observations, obs)
@@ -330,20 +519,16 @@ func ConvertToPriceObservations(records []CSVRecord, productMap map[string]uuid.
 	return observations
 }
 
-// ConvertToCompetitorProducts creates competitor product links from records
-func ConvertToCompetitorProducts(records []CSVRecord, productMap map[string]uuid.UUID, competitorMap map[string]int) []*database.CompetitorProduct {
+// ConvertToCompetitorProducts creates competitor product links from records.
+// Links made through fuzzy are recorded with the title_fuzzy match method.
+func ConvertToCompetitorProducts(records []CSVRecord, productMap map[string]uuid.UUID, fuzzy map[string]FuzzyMatch, competitorMap map[string]int) []*database.CompetitorProduct {
 	// Use map to deduplicate
 	links := make(map[string]*database.CompetitorProduct)
 
 	for _, rec := range records {
-		productID, ok := productMap[rec.SKU]
-		if !ok {
-			if rec.Barcode != "" {
-				productID, ok = productMap[rec.Barcode]
-			}
-			if !ok {
-				continue
-			}
+		productID, method, confidence := resolveProduct(rec, productMap, fuzzy)
+		if productID == uuid.Nil {
+			continue
 		}
 
 		competitorID, ok := competitorMap[rec.CompetitorName]
@@ -359,8 +544,8 @@ func ConvertToCompetitorProducts(records []CSVRecord, productMap map[string]uuid
 				URL:             rec.CompetitorURL,
 				CompetitorTitle: rec.ProductTitle,
 				IsActive:        true,
-				MatchMethod:     "csv_import",
-				MatchConfidence: 1.0,
+				MatchMethod:     method,
+				MatchConfidence: confidence,
 			}
 		}
 	}
@@ -372,3 +557,38 @@ func ConvertToCompetitorProducts(records []CSVRecord, productMap map[string]uuid
 
 	return result
 }
+
+// resolveProduct finds the product for a record by SKU, then barcode, then
+// fuzzy title match. Returns uuid.Nil if the record matches no product.
+func resolveProduct(rec CSVRecord, productMap map[string]uuid.UUID, fuzzy map[string]FuzzyMatch) (uuid.UUID, string, float64) {
+	if id := exactProductID(rec, productMap); id != uuid.Nil {
+		return id, "csv_import", 1.0
+	}
+	if m, ok := fuzzy[rec.SKU]; ok {
+		return m.ProductID, "title_fuzzy", m.Confidence
+	}
+	return uuid.Nil, "", 0
+}
+
+// BarcodeKey returns the form of a barcode used as a product map key: the
+// canonical GTIN when the check digit is valid, so zero-padded and unpadded
+// forms match, and the trimmed input otherwise
+func BarcodeKey(barcode string) string {
+	if gtin, ok := models.NormalizeBarcode(barcode); ok {
+		return gtin
+	}
+	return strings.TrimSpace(barcode)
+}
+
+// exactProductID looks up a record by SKU, then barcode
+func exactProductID(rec CSVRecord, productMap map[string]uuid.UUID) uuid.UUID {
+	if id, ok := productMap[rec.SKU]; ok {
+		return id
+	}
+	if rec.Barcode != "" {
+		if id, ok := productMap[rec.Barcode]; ok {
+			return id
+		}
+	}
+	return uuid.Nil
+}
@@ -0,0 +1,107 @@
+package prices
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func parseFixture(t *testing.T, name string) *ParseResult {
+	t.Helper()
+	result, err := NewParser().ParseFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("ParseFile(%s): %v", name, err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("ParseFile(%s) errors: %v", name, result.Errors)
+	}
+	return result
+}
+
+func competitorNames(result *ParseResult) []string {
+	names := make([]string, 0, len(result.Competitors))
+	for name := range result.Competitors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findRecord returns the record of a SKU and competitor
+func findRecord(t *testing.T, result *ParseResult, sku, competitor string) CSVRecord {
+	t.Helper()
+	for _, r := range result.Records {
+		if r.SKU == sku && r.CompetitorName == competitor {
+			return r
+		}
+	}
+	t.Fatalf("no record for %s from %s", sku, competitor)
+	return CSVRecord{}
+}
+
+func TestParseDetectsDelimiterAndEncoding(t *testing.T) {
+	tests := []struct {
+		fixture       string
+		wantDelimiter rune
+		wantEncoding  string
+	}{
+		{"semicolon.csv", ';', "utf-8"},
+		{"bom.csv", ',', "utf-8"},
+		{"windows1252.csv", ';', "windows-1252"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			result := parseFixture(t, tt.fixture)
+
+			if result.Delimiter != tt.wantDelimiter {
+				t.Errorf("delimiter = %q, want %q", result.Delimiter, tt.wantDelimiter)
+			}
+			if result.Encoding != tt.wantEncoding {
+				t.Errorf("encoding = %s, want %s", result.Encoding, tt.wantEncoding)
+			}
+
+			// Competitor names keep their Norwegian letters
+			want := []string{"Bademiljø", "Byggmakker Øst"}
+			got := competitorNames(result)
+			if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+				t.Errorf("competitors = %q, want %q", got, want)
+			}
+
+			// A BOM must not end up in the first header, or no SKU is found
+			r := findRecord(t, result, "CO-T309012", "Bademiljø")
+			if r.ProductTitle != "Tiger Boston toalettrullholder" || r.OwnPrice != 1299 || r.CompetitorPrice != 1249.5 {
+				t.Errorf("record = %+v", r)
+			}
+			if r := findRecord(t, result, "CO-T309012", "Byggmakker Øst"); r.CompetitorPrice != 1310 {
+				t.Errorf("Byggmakker Øst price = %v, want 1310", r.CompetitorPrice)
+			}
+		})
+	}
+}
+
+func TestParseSemicolonStock(t *testing.T) {
+	result := parseFixture(t, "semicolon.csv")
+
+	if result.ProductCount != 2 {
+		t.Errorf("product count = %d, want 2", result.ProductCount)
+	}
+	r := findRecord(t, result, "CO-T309013", "Bademiljø")
+	if r.CompetitorStock || r.CompetitorPrice != 329 {
+		t.Errorf("record = %+v, want 329 out of stock", r)
+	}
+	if r.ProductTitle != "Tiger Boston håndklekrok" {
+		t.Errorf("title = %q", r.ProductTitle)
+	}
+}
+
+func TestDelimiterOverride(t *testing.T) {
+	p := NewParser()
+	p.SetDelimiter(',')
+	result, err := p.ParseFile(filepath.Join("testdata", "semicolon.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Delimiter != ',' || len(result.Records) != 0 {
+		t.Errorf("got %q-separated with %d records, want the override and no records", result.Delimiter, len(result.Records))
+	}
+}
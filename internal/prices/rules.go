@@ -0,0 +1,183 @@
+package prices
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Price rule bases
+const (
+	BaseMarketMin = "market_min"
+	BaseMarketAvg = "market_avg"
+	BaseMarketMax = "market_max"
+	BaseCost      = "cost"
+	BasePrice     = "price"
+)
+
+var ruleBases = map[string]bool{
+	BaseMarketMin: true,
+	BaseMarketAvg: true,
+	BaseMarketMax: true,
+	BaseCost:      true,
+	BasePrice:     true,
+}
+
+// PriceInputs are the values a price rule can refer to. Zero means unknown.
+type PriceInputs struct {
+	MarketMin float64 // Cheapest competitor's latest price
+	MarketAvg float64
+	MarketMax float64
+	Cost      float64
+	Price     float64 // Our current price
+}
+
+func (in PriceInputs) value(base string) float64 {
+	switch base {
+	case BaseMarketMin:
+		return in.MarketMin
+	case BaseMarketAvg:
+		return in.MarketAvg
+	case BaseMarketMax:
+		return in.MarketMax
+	case BaseCost:
+		return in.Cost
+	case BasePrice:
+		return in.Price
+	}
+	return 0
+}
+
+// priceOp is one adjustment applied to a rule's base price
+type priceOp struct {
+	op      byte // '+', '-', '*' or '/'
+	value   float64
+	percent bool // value is a percentage of the running price (+ and - only)
+}
+
+// PriceRule is a repricing expression such as "market_min+2%" (market
+// minimum plus 2%), "market_min-1" (undercut the cheapest competitor by 1)
+// or "cost*1.1". It starts from a base (market_min, market_avg, market_max,
+// cost, price or a fixed number) and applies adjustments left to right.
+type PriceRule struct {
+	base     string
+	constant float64 // Base value when base is ""
+	ops      []priceOp
+	raw      string
+}
+
+// ParsePriceRule parses a price rule. Spaces are ignored and bases are
+// case-insensitive.
+func ParsePriceRule(s string) (*PriceRule, error) {
+	expr := strings.ToLower(strings.Join(strings.Fields(s), ""))
+	if expr == "" {
+		return nil, fmt.Errorf("empty price rule")
+	}
+	rule := &PriceRule{raw: strings.TrimSpace(s)}
+
+	i := 0
+	for i < len(expr) && (expr[i] >= 'a' && expr[i] <= 'z' || expr[i] == '_') {
+		i++
+	}
+	if i > 0 {
+		rule.base = expr[:i]
+		if !ruleBases[rule.base] {
+			return nil, fmt.Errorf("price rule %q: unknown base %q (use market_min, market_avg, market_max, cost, price or a number)", s, rule.base)
+		}
+	} else {
+		n, rest, err := parseRuleNumber(expr)
+		if err != nil {
+			return nil, fmt.Errorf("price rule %q: %w", s, err)
+		}
+		rule.constant = n
+		i = len(expr) - len(rest)
+	}
+
+	for rest := expr[i:]; rest != ""; {
+		op := rest[0]
+		if !strings.ContainsRune("+-*/", rune(op)) {
+			return nil, fmt.Errorf("price rule %q: expected +, -, * or / at %q", s, rest)
+		}
+		n, after, err := parseRuleNumber(rest[1:])
+		if err != nil {
+			return nil, fmt.Errorf("price rule %q: %w", s, err)
+		}
+		o := priceOp{op: op, value: n}
+		if strings.HasPrefix(after, "%") {
+			if op != '+' && op != '-' {
+				return nil, fmt.Errorf("price rule %q: %% only applies to + and -", s)
+			}
+			o.percent = true
+			after = after[1:]
+		}
+		if op == '/' && n == 0 {
+			return nil, fmt.Errorf("price rule %q: division by zero", s)
+		}
+		rule.ops = append(rule.ops, o)
+		rest = after
+	}
+
+	return rule, nil
+}
+
+// parseRuleNumber reads a non-negative decimal number from the start of s
+func parseRuleNumber(s string) (float64, string, error) {
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	if i == 0 {
+		if s == "" {
+			return 0, "", fmt.Errorf("expected a number at the end")
+		}
+		return 0, "", fmt.Errorf("expected a number at %q", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid number %q", s[:i])
+	}
+	return n, s[i:], nil
+}
+
+// Base returns the value the rule starts from, or "" for a fixed number
+func (r *PriceRule) Base() string {
+	return r.base
+}
+
+// String returns the rule as written
+func (r *PriceRule) String() string {
+	return r.raw
+}
+
+// Eval computes the rule's price, rounded to 2 decimals. It returns false
+// when the base value is unknown (zero), e.g. a product without a cost or
+// competitor prices.
+func (r *PriceRule) Eval(in PriceInputs) (float64, bool) {
+	v := r.constant
+	if r.base != "" {
+		v = in.value(r.base)
+		if v <= 0 {
+			return 0, false
+		}
+	}
+
+	for _, o := range r.ops {
+		n := o.value
+		if o.percent {
+			n = v * o.value / 100
+		}
+		switch o.op {
+		case '+':
+			v += n
+		case '-':
+			v -= n
+		case '*':
+			v *= n
+		case '/':
+			v /= n
+		}
+	}
+
+	return math.Round(v*100) / 100, true
+}
@@ -0,0 +1,294 @@
+package prices
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// SourceScraper is the observation source recorded on scraped prices
+const SourceScraper = "scraper"
+
+// DefaultScrapeRateLimit is the delay between requests to one competitor
+// when its scrape config sets no rate_limit_ms
+const DefaultScrapeRateLimit = time.Second
+
+// ScrapeConfig is a competitor's scrape_config, read from the keys:
+//
+//	base_url        Site root, used for {base_url} and relative URLs (default: the competitor website)
+//	product_url     URL template for products without a stored URL, e.g. {base_url}/search?q={sku}
+//	                ({sku}, {barcode} and {competitor_sku} are substituted)
+//	price_selector  CSS selector of the price element; a content attribute wins over its text
+//	stock_selector  CSS selector of the stock element (optional; in stock when it exists)
+//	in_stock_text   Text the stock element contains when in stock (optional, case-insensitive)
+//	currency        Price currency, converted to the base currency on save (default NOK)
+//	rate_limit_ms   Milliseconds between requests (default 1000)
+type ScrapeConfig struct {
+	BaseURL       string
+	ProductURL    string
+	PriceSelector string
+	StockSelector string
+	InStockText   string
+	Currency      string
+	RateLimit     time.Duration
+
+	price, stock selector
+}
+
+// ParseScrapeConfig validates a competitor's scrape_config. website is used
+// when base_url is not set.
+func ParseScrapeConfig(config map[string]string, website string) (*ScrapeConfig, error) {
+	cfg := &ScrapeConfig{
+		BaseURL:       strings.TrimRight(firstNonEmpty(config["base_url"], website), "/"),
+		ProductURL:    strings.TrimSpace(config["product_url"]),
+		PriceSelector: strings.TrimSpace(config["price_selector"]),
+		StockSelector: strings.TrimSpace(config["stock_selector"]),
+		InStockText:   strings.TrimSpace(config["in_stock_text"]),
+		Currency:      strings.ToUpper(firstNonEmpty(strings.TrimSpace(config["currency"]), DefaultCurrency)),
+		RateLimit:     DefaultScrapeRateLimit,
+	}
+
+	if cfg.PriceSelector == "" {
+		return nil, fmt.Errorf("scrape_config has no price_selector")
+	}
+	var err error
+	if cfg.price, err = compileSelector(cfg.PriceSelector); err != nil {
+		return nil, fmt.Errorf("price_selector: %w", err)
+	}
+	if cfg.StockSelector != "" {
+		if cfg.stock, err = compileSelector(cfg.StockSelector); err != nil {
+			return nil, fmt.Errorf("stock_selector: %w", err)
+		}
+	}
+	if raw := strings.TrimSpace(config["rate_limit_ms"]); raw != "" {
+		ms, err := strconv.Atoi(raw)
+		if err != nil || ms < 0 {
+			return nil, fmt.Errorf("invalid rate_limit_ms %q", raw)
+		}
+		cfg.RateLimit = time.Duration(ms) * time.Millisecond
+	}
+	return cfg, nil
+}
+
+// ProductPageURL returns the page to scrape for a product: the stored link
+// URL (resolved against base_url), else the product_url template. Returns ""
+// when neither is available.
+func (c *ScrapeConfig) ProductPageURL(linkURL, sku, barcode, competitorSKU string) string {
+	if linkURL != "" {
+		return c.resolve(linkURL)
+	}
+	if c.ProductURL == "" {
+		return ""
+	}
+	if strings.Contains(c.ProductURL, "{barcode}") && barcode == "" ||
+		strings.Contains(c.ProductURL, "{competitor_sku}") && competitorSKU == "" {
+		return ""
+	}
+	r := strings.NewReplacer(
+		"{base_url}", c.BaseURL,
+		"{sku}", url.QueryEscape(sku),
+		"{barcode}", url.QueryEscape(barcode),
+		"{competitor_sku}", url.QueryEscape(competitorSKU),
+	)
+	return c.resolve(r.Replace(c.ProductURL))
+}
+
+// resolve makes a relative URL absolute against base_url
+func (c *ScrapeConfig) resolve(ref string) string {
+	u, err := url.Parse(ref)
+	if err != nil || u.IsAbs() || c.BaseURL == "" {
+		return ref
+	}
+	base, err := url.Parse(c.BaseURL + "/")
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// ScrapedPrice is the price and stock read from a competitor page
+type ScrapedPrice struct {
+	URL     string
+	Price   float64
+	InStock bool
+}
+
+// Scraper fetches competitor product pages, waiting RateLimit between
+// requests to the same competitor
+type Scraper struct {
+	client    *http.Client
+	userAgent string
+
+	mu          sync.Mutex
+	lastRequest map[string]time.Time // By base URL
+}
+
+// NewScraper creates a competitor price scraper
+func NewScraper() *Scraper {
+	return &Scraper{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		userAgent:   "BadOps-PriceScraper/1.0 (https://bad.no)",
+		lastRequest: make(map[string]time.Time),
+	}
+}
+
+// Scrape fetches a product page and extracts its price and stock
+func (s *Scraper) Scrape(ctx context.Context, cfg *ScrapeConfig, pageURL string) (*ScrapedPrice, error) {
+	if err := s.wait(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", s.userAgent)
+	req.Header.Set("Accept", "text/html")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, pageURL)
+	}
+
+	result, err := ExtractPrice(io.LimitReader(resp.Body, 10<<20), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pageURL, err)
+	}
+	result.URL = pageURL
+	return result, nil
+}
+
+// wait sleeps until the competitor's rate limit allows another request
+func (s *Scraper) wait(ctx context.Context, cfg *ScrapeConfig) error {
+	s.mu.Lock()
+	next := s.lastRequest[cfg.BaseURL].Add(cfg.RateLimit)
+	now := time.Now()
+	if next.Before(now) {
+		next = now
+	}
+	s.lastRequest[cfg.BaseURL] = next
+	s.mu.Unlock()
+
+	select {
+	case <-time.After(time.Until(next)):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ExtractPrice reads the price and stock from a product page using the
+// config's selectors
+func ExtractPrice(r io.Reader, cfg *ScrapeConfig) (*ScrapedPrice, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse page: %w", err)
+	}
+
+	el := cfg.price.first(doc)
+	if el == nil {
+		return nil, fmt.Errorf("no element matches price_selector %q", cfg.PriceSelector)
+	}
+	raw := attr(el, "content")
+	if raw == "" {
+		raw = textContent(el)
+	}
+	price, ok := parseScrapedPrice(raw)
+	if !ok {
+		return nil, fmt.Errorf("could not read a price from %q", raw)
+	}
+
+	result := &ScrapedPrice{Price: price, InStock: true}
+	if cfg.stock != nil {
+		stockEl := cfg.stock.first(doc)
+		result.InStock = stockEl != nil
+		if stockEl != nil && cfg.InStockText != "" {
+			text := attr(stockEl, "content") + " " + textContent(stockEl)
+			result.InStock = strings.Contains(strings.ToLower(text), strings.ToLower(cfg.InStockText))
+		}
+	}
+	return result, nil
+}
+
+// parseScrapedPrice reads the first number in a price text such as
+// "kr 1 299,-", "1.299,00 kr" or "1299.00". The last separator is the decimal
+// point when one or two digits follow it; other separators group thousands.
+func parseScrapedPrice(s string) (float64, bool) {
+	runes := []rune(s)
+	start := 0
+	for start < len(runes) && !unicode.IsDigit(runes[start]) {
+		start++
+	}
+
+	var number []rune
+scan:
+	for i := start; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsDigit(r), r == '.', r == ',':
+			number = append(number, r)
+			continue
+		case unicode.IsSpace(r) || r == '\'':
+			// A space groups thousands only when three digits follow
+			if isDigitGroup(runes[i+1:]) {
+				continue
+			}
+		}
+		break scan
+	}
+
+	text := strings.TrimRight(string(number), ".,")
+	if text == "" {
+		return 0, false
+	}
+	intPart, frac := text, ""
+	if i := strings.LastIndexAny(text, ".,"); i >= 0 && len(text)-i-1 <= 2 {
+		intPart, frac = text[:i], text[i+1:]
+	}
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+	if frac != "" {
+		intPart += "." + frac
+	}
+
+	price, err := strconv.ParseFloat(intPart, 64)
+	if err != nil || price <= 0 {
+		return 0, false
+	}
+	return price, true
+}
+
+// isDigitGroup reports whether runes start with exactly three digits
+func isDigitGroup(runes []rune) bool {
+	if len(runes) < 3 {
+		return false
+	}
+	for _, r := range runes[:3] {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return len(runes) == 3 || !unicode.IsDigit(runes[3])
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,252 @@
+package prices
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// selector is the subset of CSS selectors used in scrape configs:
+// comma-separated alternatives of compound selectors (tag, #id, .class,
+// [attr], [attr=value]) joined by descendant whitespace, e.g.
+// ".product-info span.price, meta[itemprop=price]"
+type selector [][]compound
+
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrMatch
+}
+
+type attrMatch struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// compileSelector parses a selector, rejecting combinators and
+// pseudo-classes it does not support
+func compileSelector(s string) (selector, error) {
+	var sel selector
+	for _, alt := range splitOutside(s, ',') {
+		var chain []compound
+		for _, part := range splitOutside(alt, ' ') {
+			c, err := parseCompound(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid selector %q: %w", s, err)
+			}
+			chain = append(chain, c)
+		}
+		if len(chain) == 0 {
+			return nil, fmt.Errorf("invalid selector %q: empty selector", s)
+		}
+		sel = append(sel, chain)
+	}
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return sel, nil
+}
+
+// splitOutside splits s on sep outside [brackets] and quotes, dropping empty
+// parts. Whitespace is a separator when sep is ' '.
+func splitOutside(s string, sep rune) []string {
+	var parts []string
+	var b strings.Builder
+	depth, quote := 0, rune(0)
+	flush := func() {
+		if part := strings.TrimSpace(b.String()); part != "" {
+			parts = append(parts, part)
+		}
+		b.Reset()
+	}
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[':
+			depth++
+		case r == ']':
+			depth--
+		case depth == 0 && (r == sep || sep == ' ' && (r == '\t' || r == '\n')):
+			flush()
+			continue
+		}
+		b.WriteRune(r)
+	}
+	flush()
+	return parts
+}
+
+// parseCompound parses one compound selector such as span.price[data-x=1]
+func parseCompound(s string) (compound, error) {
+	var c compound
+
+	// name reads an identifier starting at i
+	name := func(i int) (string, int) {
+		j := i
+		for j < len(s) && !strings.ContainsRune(".#[:>+~", rune(s[j])) {
+			j++
+		}
+		return s[i:j], j
+	}
+
+	tag, i := name(0)
+	if tag != "*" {
+		c.tag = strings.ToLower(tag)
+	}
+	for i < len(s) {
+		switch s[i] {
+		case '.':
+			var class string
+			class, i = name(i + 1)
+			if class == "" {
+				return c, fmt.Errorf("empty class name")
+			}
+			c.classes = append(c.classes, class)
+		case '#':
+			c.id, i = name(i + 1)
+			if c.id == "" {
+				return c, fmt.Errorf("empty id")
+			}
+		case '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return c, fmt.Errorf("unclosed [")
+			}
+			body := s[i+1 : i+end]
+			i += end + 1
+
+			var a attrMatch
+			if key, value, ok := strings.Cut(body, "="); ok {
+				a = attrMatch{key: strings.TrimSpace(key), value: strings.Trim(strings.TrimSpace(value), `"'`), hasValue: true}
+			} else {
+				a.key = strings.TrimSpace(body)
+			}
+			if a.key == "" || strings.ContainsAny(a.key, "~|^$*") {
+				return c, fmt.Errorf("unsupported attribute selector [%s]", body)
+			}
+			a.key = strings.ToLower(a.key)
+			c.attrs = append(c.attrs, a)
+		case ':', '>', '+', '~':
+			return c, fmt.Errorf("combinators and pseudo-classes are not supported")
+		default:
+			return c, fmt.Errorf("unexpected %q", s[i])
+		}
+	}
+	return c, nil
+}
+
+// first returns the first element in document order matching any
+// alternative, or nil
+func (sel selector) first(root *html.Node) *html.Node {
+	var found *html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if found != nil {
+			return
+		}
+		if n.Type == html.ElementNode && sel.matches(n) {
+			found = n
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	return found
+}
+
+// matches reports whether n is matched by any alternative
+func (sel selector) matches(n *html.Node) bool {
+	for _, chain := range sel {
+		if matchChain(n, chain) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchChain matches the last compound against n and the preceding ones
+// against its ancestors
+func matchChain(n *html.Node, chain []compound) bool {
+	last := len(chain) - 1
+	if !chain[last].matches(n) {
+		return false
+	}
+	i := last - 1
+	for p := n.Parent; p != nil && i >= 0; p = p.Parent {
+		if p.Type == html.ElementNode && chain[i].matches(p) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+func (c compound) matches(n *html.Node) bool {
+	if c.tag != "" && n.Data != c.tag {
+		return false
+	}
+	if c.id != "" && attr(n, "id") != c.id {
+		return false
+	}
+	classes := strings.Fields(attr(n, "class"))
+	for _, want := range c.classes {
+		found := false
+		for _, class := range classes {
+			if class == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		value, ok := attrValue(n, a.key)
+		if !ok || a.hasValue && value != a.value {
+			return false
+		}
+	}
+	return true
+}
+
+// attr returns the value of an attribute, or "" if it is not set
+func attr(n *html.Node, key string) string {
+	value, _ := attrValue(n, key)
+	return value
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// textContent returns the element's text with whitespace collapsed
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
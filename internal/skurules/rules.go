@@ -0,0 +1,295 @@
+package skurules
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRulesFile is the rules file name inside the config directory
+const DefaultRulesFile = "sku-rules.yaml"
+
+// Rule is one transform applied to every candidate ID produced so far. A
+// rule only applies to candidates matching Match (all when empty); the
+// others pass through unchanged. The transforms run in field order: prefix
+// strip, regex replace, zero-pad, filter.
+type Rule struct {
+	Name        string   `yaml:"name,omitempty"`
+	Match       string   `yaml:"match,omitempty"`        // Regex a candidate must match for the rule to apply
+	StripPrefix []string `yaml:"strip_prefix,omitempty"` // Remove the first of these prefixes the candidate starts with
+	Replace     string   `yaml:"replace,omitempty"`      // Regex to replace in the candidate
+	With        []string `yaml:"with,omitempty"`         // Replacements for Replace ($1 etc.); each yields a candidate
+	Pad         int      `yaml:"pad,omitempty"`          // Left-pad numeric candidates with zeros to this length
+	Filter      string   `yaml:"filter,omitempty"`       // Drop candidates that do not match this regex
+	Keep        bool     `yaml:"keep,omitempty"`         // Keep the input candidate as well as the transformed ones
+
+	match, replace, filter *regexp.Regexp
+}
+
+// RuleSet holds the ordered rules that turn a bad.no SKU into candidate IDs
+// for each source
+type RuleSet struct {
+	Tiger []Rule `yaml:"tiger"` // Tiger.nl product IDs
+	NOBB  []Rule `yaml:"nobb"`  // 8-digit NOBB numbers
+}
+
+// colorSuffixes are the Tiger.nl color codes tried for T-prefixed SKUs:
+// black, chrome, brushed stainless and white
+var colorSuffixes = []string{"746", "346", "946", "146"}
+
+// DefaultRules returns the built-in rules
+func DefaultRules() *RuleSet {
+	// T-prefixed SKUs (e.g., CO-T309012 → 309030346): Tiger.nl IDs are
+	// base(4 digits) + "30" + color suffix. Some products keep the 5th digit
+	// (T309512 → 309530746) and the Urban series adds a leading 1
+	// (T317312 → 1317330746). The raw number is tried last.
+	var with []string
+	for _, pattern := range []string{"${1}30", "${1}${2}30", "1${1}${2}30"} {
+		for _, suffix := range colorSuffixes {
+			with = append(with, pattern+suffix)
+		}
+	}
+	with = append(with, "${1}${2}${3}")
+
+	rules := &RuleSet{
+		Tiger: []Rule{
+			{Name: "strip store prefix", StripPrefix: []string{"CO-"}},
+			{Name: "Cooper 800 series is numeric", Match: `^T800`, StripPrefix: []string{"T"}},
+			{Name: "short T-prefixed SKUs are used as is", Match: `^T.{0,5}$`, StripPrefix: []string{"T"}},
+			{Name: "T-prefixed SKUs map to color variants", Replace: `^T(.{4})(.)(.+)$`, With: with},
+		},
+		NOBB: []Rule{
+			{Name: "strip supplier prefix", StripPrefix: []string{"CO-T", "CO-", "T"}, Keep: true},
+			{Name: "pad to a NOBB number", Match: `^\d{6,7}$`, Pad: 8},
+			{Name: "NOBB numbers have 8 digits", Filter: `^\d{8}$`},
+		},
+	}
+	if err := rules.compile(); err != nil {
+		panic(err) // The built-in rules are valid
+	}
+	return rules
+}
+
+// DefaultPath returns ~/.badops/sku-rules.yaml
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".badops", DefaultRulesFile), nil
+}
+
+// Load reads rules from a YAML file. Sections missing from the file keep the
+// built-in rules.
+func Load(path string) (*RuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules RuleSet
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse SKU rules file: %w", err)
+	}
+
+	defaults := DefaultRules()
+	if rules.Tiger == nil {
+		rules.Tiger = defaults.Tiger
+	}
+	if rules.NOBB == nil {
+		rules.NOBB = defaults.NOBB
+	}
+	if err := rules.compile(); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// LoadOrDefault loads path, or ~/.badops/sku-rules.yaml when path is empty.
+// A missing default file yields the built-in rules; a missing explicit file
+// is an error.
+func LoadOrDefault(path string) (*RuleSet, error) {
+	if path != "" {
+		return Load(path)
+	}
+	if defaultPath, err := DefaultPath(); err == nil {
+		if rules, err := Load(defaultPath); err == nil || !os.IsNotExist(err) {
+			return rules, err
+		}
+	}
+	return DefaultRules(), nil
+}
+
+// compile compiles the regexes of every rule
+func (rs *RuleSet) compile() error {
+	for _, section := range rs.sections() {
+		for i := range section.rules {
+			if err := section.rules[i].compile(); err != nil {
+				return fmt.Errorf("%s[%d]: %w", section.name, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// sections returns the rule lists with their names in file order
+func (rs *RuleSet) sections() []struct {
+	name  string
+	rules []Rule
+} {
+	return []struct {
+		name  string
+		rules []Rule
+	}{{"tiger", rs.Tiger}, {"nobb", rs.NOBB}}
+}
+
+func (r *Rule) compile() error {
+	var err error
+	for _, re := range []struct {
+		field string
+		expr  string
+		dst   **regexp.Regexp
+	}{
+		{"match", r.Match, &r.match},
+		{"replace", r.Replace, &r.replace},
+		{"filter", r.Filter, &r.filter},
+	} {
+		*re.dst = nil
+		if re.expr == "" {
+			continue
+		}
+		if *re.dst, err = regexp.Compile(re.expr); err != nil {
+			return fmt.Errorf("invalid %s regex %q: %w", re.field, re.expr, err)
+		}
+	}
+	if r.Replace != "" && len(r.With) == 0 {
+		return fmt.Errorf("replace %q needs at least one with value", r.Replace)
+	}
+	return nil
+}
+
+// Validate returns the problems with the rules: invalid regexes, a replace
+// without replacements, and rules that do nothing
+func (rs *RuleSet) Validate() []string {
+	var problems []string
+	for _, section := range rs.sections() {
+		for i := range section.rules {
+			r := section.rules[i]
+			if err := r.compile(); err != nil {
+				problems = append(problems, fmt.Sprintf("%s[%d]: %v", section.name, i, err))
+				continue
+			}
+			if len(r.StripPrefix) == 0 && r.Replace == "" && r.Pad == 0 && r.Filter == "" {
+				problems = append(problems, fmt.Sprintf("%s[%d]: rule has no transform (strip_prefix, replace, pad or filter)", section.name, i))
+			}
+		}
+	}
+	return problems
+}
+
+// TigerCandidates returns the Tiger.nl product IDs to try for a SKU
+func (rs *RuleSet) TigerCandidates(sku string) []string {
+	return Apply(rs.Tiger, sku)
+}
+
+// NOBBCandidates returns the NOBB numbers to try for a SKU
+func (rs *RuleSet) NOBBCandidates(sku string) []string {
+	return Apply(rs.NOBB, sku)
+}
+
+// Apply runs the rules over a SKU and returns the distinct candidates in
+// the order they were produced
+func Apply(rules []Rule, sku string) []string {
+	candidates := []string{strings.TrimSpace(sku)}
+	for i := range rules {
+		var next []string
+		for _, c := range candidates {
+			next = append(next, rules[i].apply(c)...)
+		}
+		candidates = dedupe(next)
+	}
+	return candidates
+}
+
+// apply transforms a single candidate
+func (r *Rule) apply(candidate string) []string {
+	if r.match != nil && !r.match.MatchString(candidate) {
+		return []string{candidate}
+	}
+
+	outputs := []string{candidate}
+	if len(r.StripPrefix) > 0 {
+		for _, prefix := range r.StripPrefix {
+			if len(candidate) > len(prefix) && strings.HasPrefix(candidate, prefix) {
+				outputs = []string{candidate[len(prefix):]}
+				break
+			}
+		}
+	}
+
+	if r.replace != nil {
+		var replaced []string
+		for _, out := range outputs {
+			if !r.replace.MatchString(out) {
+				replaced = append(replaced, out)
+				continue
+			}
+			for _, with := range r.With {
+				replaced = append(replaced, r.replace.ReplaceAllString(out, with))
+			}
+		}
+		outputs = replaced
+	}
+
+	if r.Pad > 0 {
+		for i, out := range outputs {
+			if isNumeric(out) && len(out) < r.Pad {
+				outputs[i] = strings.Repeat("0", r.Pad-len(out)) + out
+			}
+		}
+	}
+
+	if r.Keep {
+		outputs = append([]string{candidate}, outputs...)
+	}
+
+	if r.filter != nil {
+		kept := outputs[:0]
+		for _, out := range outputs {
+			if r.filter.MatchString(out) {
+				kept = append(kept, out)
+			}
+		}
+		outputs = kept
+	}
+	return outputs
+}
+
+// dedupe removes repeated and empty candidates, keeping the first of each
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
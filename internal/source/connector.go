@@ -2,6 +2,8 @@ package source
 
 import (
 	"context"
+	"log/slog"
+	"time"
 
 	"github.com/badno/badops/pkg/models"
 )
@@ -33,7 +35,7 @@ type FetchOptions struct {
 	Offset      int               // Starting offset for pagination
 	Vendor      string            // Filter by vendor (e.g., "Tiger")
 	SKUs        []string          // Specific SKUs to fetch
-	UpdatedSince *int64           // Only fetch products updated after this Unix timestamp
+	UpdatedSince time.Time        // Only fetch products updated at or after this time (zero = all)
 	IncludeImages bool            // Include image data
 	Filters     map[string]string // Additional filters
 }
@@ -45,6 +47,7 @@ type EnhancementResult struct {
 	ImagesAdded   int
 	Success       bool
 	Error         error
+	DryRun        bool // Result is a preview; the product was not modified
 }
 
 // FetchResult represents the result of fetching products
@@ -83,6 +86,32 @@ type Connector interface {
 
 	// Test performs a connectivity and credentials test
 	Test(ctx context.Context) error
+
+	// Stats returns the connector's request, cache, and error counters
+	Stats() ConnectorStats
+}
+
+// BatchEnhancer is implemented by enhancement connectors that can enrich
+// many products with fewer API calls than one EnhanceProduct call per product
+type BatchEnhancer interface {
+	// EnhanceProductsBatch enriches the given products and returns one result
+	// per product, in the same order as the input slice
+	EnhanceProductsBatch(ctx context.Context, products []*models.EnhancedProduct) ([]*EnhancementResult, error)
+}
+
+// Previewer is implemented by enhancement connectors that can report what an
+// enhancement would change without modifying the product
+type Previewer interface {
+	// PreviewEnhancement computes FieldsUpdated and ImagesAdded for a product
+	// without writing to it. The returned result has DryRun set.
+	PreviewEnhancement(ctx context.Context, product *models.EnhancedProduct) (*EnhancementResult, error)
+}
+
+// FileSource is implemented by source connectors that read an export file
+// rather than an API, so callers can point them at a file per run
+type FileSource interface {
+	// SetFile replaces the configured file path
+	SetFile(path string)
 }
 
 // HasCapability checks if a connector supports a specific capability
@@ -101,6 +130,8 @@ type BaseConnector struct {
 	connectorType ConnectorType
 	capabilities []Capability
 	connected    bool
+	counter      statsCounter
+	logger       *slog.Logger
 }
 
 // NewBaseConnector creates a new base connector with common fields
@@ -131,3 +162,17 @@ func (b *BaseConnector) IsConnected() bool {
 func (b *BaseConnector) SetConnected(connected bool) {
 	b.connected = connected
 }
+
+// Logger returns the connector's logger, which tags records with the source
+// name. It defaults to the slog default logger.
+func (b *BaseConnector) Logger() *slog.Logger {
+	if b.logger == nil {
+		return slog.Default().With("source", b.name)
+	}
+	return b.logger
+}
+
+// SetLogger replaces the connector's logger
+func (b *BaseConnector) SetLogger(logger *slog.Logger) {
+	b.logger = logger.With("source", b.name)
+}
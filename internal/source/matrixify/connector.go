@@ -0,0 +1,131 @@
+// Package matrixify reads products from Matrixify or Shopify export CSV files
+package matrixify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/badno/badops/internal/parser"
+	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/pkg/models"
+)
+
+const ConnectorName = "matrixify"
+
+// Config holds Matrixify connector configuration
+type Config struct {
+	File string // Path to the export CSV
+}
+
+// Connector implements the source.Connector interface for Matrixify export
+// files, so they go through the same import and state merge as Shopify
+type Connector struct {
+	*source.BaseConnector
+	config Config
+}
+
+// NewConnector creates a new Matrixify connector
+func NewConnector(cfg Config) *Connector {
+	return &Connector{
+		BaseConnector: source.NewBaseConnector(
+			ConnectorName,
+			source.TypeSource,
+			[]source.Capability{
+				source.CapabilityFetchProducts,
+				source.CapabilityFetchImages,
+			},
+		),
+		config: cfg,
+	}
+}
+
+// SetFile replaces the export file path
+func (c *Connector) SetFile(path string) {
+	c.config.File = path
+	c.SetConnected(false)
+}
+
+// Connect checks that the export file exists
+func (c *Connector) Connect(ctx context.Context) error {
+	return c.Test(ctx)
+}
+
+// Close cleans up resources
+func (c *Connector) Close() error {
+	c.SetConnected(false)
+	return nil
+}
+
+// Test verifies the export file is readable
+func (c *Connector) Test(ctx context.Context) error {
+	if c.config.File == "" {
+		return fmt.Errorf("matrixify file not configured (use --file or set sources.matrixify.file)")
+	}
+	info, err := os.Stat(c.config.File)
+	if err != nil {
+		return fmt.Errorf("failed to open matrixify file: %w", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("matrixify file %s is a directory", c.config.File)
+	}
+	c.SetConnected(true)
+	return nil
+}
+
+// FetchProducts parses the export file, applying the vendor, SKU,
+// updated-since, offset and limit options
+func (c *Connector) FetchProducts(ctx context.Context, opts source.FetchOptions) (*source.FetchResult, error) {
+	if !c.IsConnected() {
+		if err := c.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	all, err := parser.ParseMatrixifyProducts(c.config.File)
+	if err != nil {
+		c.RecordError("parse")
+		return nil, fmt.Errorf("failed to parse %s: %w", c.config.File, err)
+	}
+
+	skus := make(map[string]bool, len(opts.SKUs))
+	for _, sku := range opts.SKUs {
+		skus[sku] = true
+	}
+
+	var matched []models.EnhancedProduct
+	for _, p := range all {
+		if opts.Vendor != "" && !strings.EqualFold(p.Vendor, opts.Vendor) {
+			continue
+		}
+		if len(skus) > 0 && !skus[p.SKU] {
+			continue
+		}
+		if !opts.UpdatedSince.IsZero() && !p.UpdatedAt.IsZero() && p.UpdatedAt.Before(opts.UpdatedSince) {
+			continue
+		}
+		matched = append(matched, p)
+	}
+
+	total := len(matched)
+	if opts.Offset > 0 {
+		matched = matched[min(opts.Offset, len(matched)):]
+	}
+	hasMore := false
+	if opts.Limit > 0 && len(matched) > opts.Limit {
+		matched = matched[:opts.Limit]
+		hasMore = true
+	}
+
+	return &source.FetchResult{
+		Products:   matched,
+		TotalCount: total,
+		HasMore:    hasMore,
+	}, nil
+}
+
+// EnhanceProduct is not supported by the Matrixify connector
+func (c *Connector) EnhanceProduct(ctx context.Context, product *models.EnhancedProduct) (*source.EnhancementResult, error) {
+	return nil, fmt.Errorf("matrixify connector does not support product enhancement")
+}
@@ -0,0 +1,220 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// MergePolicy decides how values from enhancement sources are merged into a
+// product. The zero value only fills empty fields.
+type MergePolicy struct {
+	Priority  []string               // Source names, highest priority first
+	Overwrite models.OverwritePolicy // Default: fill_empty
+}
+
+// NewMergePolicy creates a policy from the defaults.source_priority and
+// defaults.overwrite_policy settings
+func NewMergePolicy(priority []string, overwrite string) MergePolicy {
+	return MergePolicy{
+		Priority:  priority,
+		Overwrite: models.OverwritePolicy(overwrite),
+	}
+}
+
+// rank returns the position of a source in the priority list. Unlisted
+// sources rank below all listed ones.
+func (p MergePolicy) rank(source string) int {
+	for i, s := range p.Priority {
+		if s == source {
+			return i
+		}
+	}
+	return len(p.Priority)
+}
+
+// canReplace reports whether source may replace a value that owner set.
+// owner is "" when the value did not come from an enhancement, such as data
+// imported from Shopify, which prefer_source never replaces.
+func (p MergePolicy) canReplace(source, owner string) bool {
+	switch p.Overwrite {
+	case models.OverwriteAlways:
+		return true
+	case models.OverwritePreferSource:
+		return owner != "" && (owner == source || p.rank(source) < p.rank(owner))
+	}
+	return false
+}
+
+// Merger applies the values from one enhancement source to a product. It is
+// the single place connectors decide whether a field may be set, and it
+// records which fields were set and which existing values were replaced.
+type Merger struct {
+	policy  MergePolicy
+	source  string
+	product *models.EnhancedProduct
+
+	updated   []string
+	overrides []string
+}
+
+// NewMerger creates a merger for source's values on product
+func (p MergePolicy) NewMerger(source string, product *models.EnhancedProduct) *Merger {
+	return &Merger{
+		policy:  p,
+		source:  source,
+		product: product,
+	}
+}
+
+// allow reports whether field may be set, given whether it is currently empty
+func (m *Merger) allow(field string, empty bool) bool {
+	if !empty {
+		owner := m.product.FieldSource(field)
+		if !m.policy.canReplace(m.source, owner) {
+			return false
+		}
+		if owner == "" {
+			owner = "import"
+		}
+		if owner != m.source {
+			m.overrides = append(m.overrides, fmt.Sprintf("%s (was %s)", field, owner))
+		}
+	}
+	m.updated = append(m.updated, field)
+	return true
+}
+
+// SetString sets a string field, e.g. m.SetString("description", &p.Description, v)
+func (m *Merger) SetString(field string, dst *string, value string) bool {
+	if value == "" || *dst == value {
+		return false
+	}
+	if !m.allow(field, *dst == "") {
+		return false
+	}
+	*dst = value
+	return true
+}
+
+// SetDimensions sets the product dimensions
+func (m *Merger) SetDimensions(value *models.Dimensions) bool {
+	if value == nil || (m.product.Dimensions != nil && *m.product.Dimensions == *value) {
+		return false
+	}
+	if !m.allow("dimensions", m.product.Dimensions == nil) {
+		return false
+	}
+	m.product.Dimensions = value
+	return true
+}
+
+// SetWeight sets the product weight
+func (m *Merger) SetWeight(value *models.Weight) bool {
+	if value == nil || (m.product.Weight != nil && *m.product.Weight == *value) {
+		return false
+	}
+	if !m.allow("weight", m.product.Weight == nil) {
+		return false
+	}
+	m.product.Weight = value
+	return true
+}
+
+// AddImages appends the images whose URL the product doesn't have yet and
+// returns how many were added. Images are never removed. Unless the policy is
+// fill_empty, the source's images are then placed ahead of images from
+// lower-priority sources; imported images always stay first.
+func (m *Merger) AddImages(images []models.ProductImage) int {
+	seen := make(map[string]bool, len(m.product.Images))
+	for _, img := range m.product.Images {
+		seen[img.SourceURL] = true
+		if img.OriginalURL != "" {
+			seen[img.OriginalURL] = true
+		}
+	}
+
+	added := 0
+	for _, img := range images {
+		if img.SourceURL == "" || seen[img.SourceURL] {
+			continue
+		}
+		seen[img.SourceURL] = true
+		img.Source = m.source
+		m.product.Images = append(m.product.Images, img)
+		added++
+	}
+	if added == 0 {
+		return 0
+	}
+	m.updated = append(m.updated, "images")
+
+	if m.policy.Overwrite == models.OverwritePreferSource || m.policy.Overwrite == models.OverwriteAlways {
+		m.prioritizeImages()
+	}
+	return added
+}
+
+// prioritizeImages orders enhancement images by source priority and
+// renumbers positions, recording the sources the merger's images now precede
+func (m *Merger) prioritizeImages() {
+	imageRank := func(img models.ProductImage) int {
+		if isImportedImage(img) {
+			return -1
+		}
+		return m.policy.rank(img.Source)
+	}
+
+	own := m.policy.rank(m.source)
+	passed := make(map[string]bool)
+	for _, img := range m.product.Images {
+		if !isImportedImage(img) && img.Source != m.source && imageRank(img) > own {
+			passed[img.Source] = true
+		}
+	}
+	if len(passed) == 0 {
+		return
+	}
+
+	sort.SliceStable(m.product.Images, func(i, j int) bool {
+		return imageRank(m.product.Images[i]) < imageRank(m.product.Images[j])
+	})
+	for i := range m.product.Images {
+		m.product.Images[i].Position = i + 1
+	}
+
+	sources := make([]string, 0, len(passed))
+	for s := range passed {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	m.overrides = append(m.overrides, fmt.Sprintf("images (ahead of %s)", strings.Join(sources, ", ")))
+}
+
+// isImportedImage reports whether an image came with the product rather than
+// from an enhancement
+func isImportedImage(img models.ProductImage) bool {
+	return img.Source == "" || img.Source == "shopify" || img.Status == "existing"
+}
+
+// Updated returns the fields the merger set, in the order they were set
+func (m *Merger) Updated() []string {
+	return m.updated
+}
+
+// Overrides returns the existing values the merger replaced, e.g.
+// "description (was import)"
+func (m *Merger) Overrides() []string {
+	return m.overrides
+}
+
+// OverrideDetails formats the overrides for an enhancement's details, or
+// returns "" when nothing was replaced
+func (m *Merger) OverrideDetails() string {
+	if len(m.overrides) == 0 {
+		return ""
+	}
+	return "; replaced " + strings.Join(m.overrides, ", ")
+}
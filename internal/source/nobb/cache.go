@@ -0,0 +1,190 @@
+package nobb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/badno/badops/internal/source"
+)
+
+const (
+	defaultCacheFile = "output/.nobb-cache.json"
+	defaultCacheTTL  = 24 * time.Hour
+
+	// cacheFlushEvery is how many changed entries are kept in memory before
+	// the cache file is rewritten; FlushCache (and Close) write the rest
+	cacheFlushEvery = 100
+)
+
+// cacheEntry stores a cached NOBB item lookup
+type cacheEntry struct {
+	NOBBNumber string    `json:"nobb_number"`
+	Item       *nobbItem `json:"item,omitempty"`
+	NotFound   bool      `json:"not_found,omitempty"`
+	CachedAt   time.Time `json:"cached_at"`
+}
+
+// loadCache loads the cache from disk
+func (c *Connector) loadCache() {
+	data, err := os.ReadFile(c.config.CacheFile)
+	if err != nil {
+		return // No cache file yet
+	}
+	var entries []cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	for _, e := range entries {
+		entry := e // Copy to avoid pointer issues
+		c.cache[e.NOBBNumber] = &entry
+	}
+}
+
+// saveCache writes the whole cache to disk. Entries changed since the last
+// write stay pending if it fails, so a later flush retries them.
+func (c *Connector) saveCache() error {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.cacheMu.Lock()
+	entries := make([]cacheEntry, 0, len(c.cache))
+	for _, e := range c.cache {
+		entries = append(entries, *e)
+	}
+	pending := c.dirty
+	c.dirty = 0
+	c.cacheMu.Unlock()
+
+	if err := writeCacheFile(c.config.CacheFile, entries); err != nil {
+		c.cacheMu.Lock()
+		c.dirty += pending
+		c.cacheMu.Unlock()
+		return fmt.Errorf("failed to save NOBB cache: %w", err)
+	}
+	return nil
+}
+
+func writeCacheFile(path string, entries []cacheEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// FlushCache writes the cache file if entries changed since the last write
+func (c *Connector) FlushCache() error {
+	c.cacheMu.RLock()
+	dirty := c.dirty > 0
+	c.cacheMu.RUnlock()
+	if !dirty {
+		return nil
+	}
+	return c.saveCache()
+}
+
+// flushIfDue writes the cache file once cacheFlushEvery entries changed
+func (c *Connector) flushIfDue() error {
+	c.cacheMu.RLock()
+	due := c.dirty >= cacheFlushEvery
+	c.cacheMu.RUnlock()
+	if !due {
+		return nil
+	}
+	return c.saveCache()
+}
+
+// GetCached returns a cached item if available. A nil item with ok=true
+// means the NOBB number is cached as not found. With ForceRefresh, items
+// cached before the connector was created count as misses.
+func (c *Connector) GetCached(nobbNumber string) (*nobbItem, bool) {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+	if entry, ok := c.cache[normalizeNOBBNumber(nobbNumber)]; ok {
+		if time.Since(entry.CachedAt) < c.config.CacheTTL && !entry.CachedAt.Before(c.refreshAfter) {
+			c.RecordCacheHit()
+			if entry.NotFound {
+				return nil, true // Cached as not found
+			}
+			return entry.Item, true
+		}
+	}
+	c.RecordCacheMiss()
+	return nil, false
+}
+
+// CacheSize returns the number of entries in the cache, including expired ones
+func (c *Connector) CacheSize() int {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+	return len(c.cache)
+}
+
+// CacheSummary describes the cache entries, including expired ones
+func (c *Connector) CacheSummary() source.CacheSummary {
+	c.cacheMu.RLock()
+	defer c.cacheMu.RUnlock()
+	var summary source.CacheSummary
+	for _, e := range c.cache {
+		summary.Add(e.CachedAt, e.NotFound, c.config.CacheTTL)
+	}
+	return summary
+}
+
+// ClearCache removes every cache entry and returns how many there were. The
+// cache file is written by FlushCache or Close.
+func (c *Connector) ClearCache() int {
+	c.cacheMu.Lock()
+	n := len(c.cache)
+	c.cache = make(map[string]*cacheEntry)
+	c.dirty += n
+	c.cacheMu.Unlock()
+	return n
+}
+
+// InvalidateCache removes the entries for the given NOBB numbers and returns
+// how many were removed. The cache file is written by FlushCache or Close.
+func (c *Connector) InvalidateCache(nobbNumbers ...string) int {
+	c.cacheMu.Lock()
+	n := 0
+	for _, num := range nobbNumbers {
+		key := normalizeNOBBNumber(num)
+		if _, ok := c.cache[key]; ok {
+			delete(c.cache, key)
+			n++
+		}
+	}
+	c.dirty += n
+	c.cacheMu.Unlock()
+	return n
+}
+
+// SetCached stores an item in the cache. A nil item is cached as not found.
+// The cache file is rewritten every cacheFlushEvery changes; the error is
+// that write's.
+func (c *Connector) SetCached(nobbNumber string, item *nobbItem) error {
+	c.setCachedEntry(nobbNumber, item)
+	return c.flushIfDue()
+}
+
+// setCachedEntry stores an item in memory and marks the cache file stale
+func (c *Connector) setCachedEntry(nobbNumber string, item *nobbItem) {
+	key := normalizeNOBBNumber(nobbNumber)
+	c.cacheMu.Lock()
+	c.cache[key] = &cacheEntry{
+		NOBBNumber: key,
+		Item:       item,
+		NotFound:   item == nil,
+		CachedAt:   time.Now(),
+	}
+	c.dirty++
+	c.cacheMu.Unlock()
+}
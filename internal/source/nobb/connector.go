@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/skurules"
 	"github.com/badno/badops/internal/source"
 	"github.com/badno/badops/pkg/models"
 )
@@ -17,14 +22,29 @@ import (
 const (
 	ConnectorName = "nobb"
 	baseURL       = "https://export.byggtjeneste.no/api/v1"
+
+	// batchSize is the number of NOBB numbers requested per batch lookup
+	batchSize = 50
+
+	// defaultRateLimit is the minimum delay between API requests
+	defaultRateLimit = 100 * time.Millisecond
 )
 
 // Config holds NOBB connection configuration
 type Config struct {
-	Username    string // NOBB username
-	Password    string // NOBB password
-	UsernameEnv string // Environment variable for username
-	PasswordEnv string // Environment variable for password
+	Username      string               // NOBB username
+	Password      string               // NOBB password
+	UsernameEnv   string               // Credential reference for username (e.g., NOBB_USERNAME, file:/path)
+	PasswordEnv   string               // Credential reference for password (e.g., NOBB_PASSWORD, cmd:pass show nobb)
+	Credentials   credentials.Provider // Resolves UsernameEnv/PasswordEnv (default: credentials.Default)
+	CacheFile     string               // Path to the item cache (default: output/.nobb-cache.json)
+	CacheTTL      time.Duration        // How long cached items stay valid (default: 24h)
+	DimensionUnit string               // Unit to store product dimensions in (default: mm)
+	WeightUnit    string               // Unit to store product weight in (default: kg)
+	RateLimitMs   int                  // Milliseconds between requests (default: 100)
+	Merge         source.MergePolicy   // When NOBB values may replace existing ones
+	SKURulesFile  string               // SKU → NOBB number rules YAML (default: ~/.badops/sku-rules.yaml)
+	ForceRefresh  bool                 // Ignore items cached before the connector was created; fresh results are re-cached
 }
 
 // Connector implements the source.Connector interface for NOBB
@@ -33,11 +53,39 @@ type Connector struct {
 	config    Config
 	client    *http.Client
 	authToken string
+	cache     map[string]*cacheEntry
+	cacheMu   sync.RWMutex
+	dirty     int                          // Cache entries changed since the file was written, guarded by cacheMu
+	saveMu    sync.Mutex                   // Serializes cache file writes
+	etimNames map[string]map[string]string // ETIM class -> feature code -> name
+	etimMu    sync.RWMutex
+
+	rateLimit   time.Duration
+	lastRequest time.Time
+	rateLimitMu sync.Mutex
+
+	skuRules    *skurules.RuleSet // SKU → NOBB number candidates
+	skuRulesErr error             // Reported by Connect
+
+	refreshAfter time.Time // Items cached before this are ignored (Config.ForceRefresh)
 }
 
 // NewConnector creates a new NOBB connector
 func NewConnector(cfg Config) *Connector {
-	return &Connector{
+	if cfg.CacheFile == "" {
+		cfg.CacheFile = defaultCacheFile
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = defaultCacheTTL
+	}
+	if cfg.DimensionUnit == "" {
+		cfg.DimensionUnit = "mm"
+	}
+	if cfg.WeightUnit == "" {
+		cfg.WeightUnit = "kg"
+	}
+
+	c := &Connector{
 		BaseConnector: source.NewBaseConnector(
 			ConnectorName,
 			source.TypeEnhancement,
@@ -52,23 +100,33 @@ func NewConnector(cfg Config) *Connector {
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
+		cache:     make(map[string]*cacheEntry),
+		etimNames: make(map[string]map[string]string),
+		rateLimit: defaultRateLimit,
+	}
+	if cfg.RateLimitMs > 0 {
+		c.rateLimit = time.Duration(cfg.RateLimitMs) * time.Millisecond
+	}
+	if cfg.ForceRefresh {
+		c.refreshAfter = time.Now()
 	}
+	c.loadCache()
+	c.skuRules, c.skuRulesErr = skurules.LoadOrDefault(cfg.SKURulesFile)
+	return c
 }
 
 type Capability = source.Capability
 
 // Connect establishes connection to NOBB API
 func (c *Connector) Connect(ctx context.Context) error {
-	// Resolve credentials from environment
-	username := c.config.Username
-	if username == "" && c.config.UsernameEnv != "" {
-		username = os.Getenv(c.config.UsernameEnv)
-	}
-	password := c.config.Password
-	if password == "" && c.config.PasswordEnv != "" {
-		password = os.Getenv(c.config.PasswordEnv)
+	if c.skuRulesErr != nil {
+		return fmt.Errorf("failed to load SKU rules: %w", c.skuRulesErr)
 	}
 
+	username, password, err := c.resolveCredentials()
+	if err != nil {
+		return err
+	}
 	if username == "" || password == "" {
 		return fmt.Errorf("NOBB credentials not configured")
 	}
@@ -84,10 +142,32 @@ func (c *Connector) Connect(ctx context.Context) error {
 	return c.Test(ctx)
 }
 
-// Close cleans up resources
+// resolveCredentials returns the configured username and password, resolving
+// their credential references when they aren't set directly
+func (c *Connector) resolveCredentials() (string, string, error) {
+	username := c.config.Username
+	if username == "" {
+		value, err := credentials.Resolve(c.config.Credentials, c.config.UsernameEnv)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to resolve NOBB username: %w", err)
+		}
+		username = value
+	}
+	password := c.config.Password
+	if password == "" {
+		value, err := credentials.Resolve(c.config.Credentials, c.config.PasswordEnv)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to resolve NOBB password: %w", err)
+		}
+		password = value
+	}
+	return username, password, nil
+}
+
+// Close writes pending cache entries and cleans up resources
 func (c *Connector) Close() error {
 	c.SetConnected(false)
-	return nil
+	return c.FlushCache()
 }
 
 // Test verifies connectivity to NOBB API
@@ -95,17 +175,12 @@ func (c *Connector) Test(ctx context.Context) error {
 	// Ensure credentials are set
 	if c.authToken == "" {
 		// Try to resolve credentials
-		username := c.config.Username
-		if username == "" && c.config.UsernameEnv != "" {
-			username = os.Getenv(c.config.UsernameEnv)
-		}
-		password := c.config.Password
-		if password == "" && c.config.PasswordEnv != "" {
-			password = os.Getenv(c.config.PasswordEnv)
+		username, password, err := c.resolveCredentials()
+		if err != nil {
+			return err
 		}
-
 		if username == "" || password == "" {
-			return fmt.Errorf("NOBB credentials not configured (set %s and %s environment variables)",
+			return fmt.Errorf("NOBB credentials not configured (set %s and %s)",
 				c.config.UsernameEnv, c.config.PasswordEnv)
 		}
 
@@ -123,7 +198,7 @@ func (c *Connector) Test(ctx context.Context) error {
 	req.Header.Set("Authorization", "Basic "+c.authToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("failed to connect to NOBB: %w", err)
 	}
@@ -141,6 +216,41 @@ func (c *Connector) Test(ctx context.Context) error {
 	return nil
 }
 
+// do sends a request once the rate limit allows it. The limit is shared by
+// every goroutine using the connector.
+func (c *Connector) do(req *http.Request) (*http.Response, error) {
+	if err := c.rateLimitWait(req.Context()); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	c.RecordResponse(req, resp, err, time.Since(start))
+	return resp, err
+}
+
+// Stats returns the connector's counters along with the cache size
+func (c *Connector) Stats() source.ConnectorStats {
+	stats := c.BaseConnector.Stats()
+	stats.CacheEntries = c.CacheSize()
+	return stats
+}
+
+// rateLimitWait waits until rateLimit has passed since the previous request
+func (c *Connector) rateLimitWait(ctx context.Context) error {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
+
+	if wait := c.rateLimit - time.Since(c.lastRequest); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
+}
+
 // FetchProducts is not the primary use case for NOBB (it's an enhancement source)
 func (c *Connector) FetchProducts(ctx context.Context, opts source.FetchOptions) (*source.FetchResult, error) {
 	return nil, fmt.Errorf("NOBB connector is an enhancement source, use EnhanceProduct instead")
@@ -160,40 +270,153 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 	}
 
 	// Try to find by NOBB number if available
-	var nobbItem *nobbItem
+	var item *nobbItem
 	var err error
 	var searchMethod string
 
 	if product.NOBBNumber != "" {
 		searchMethod = "nobb_number"
-		nobbItem, err = c.fetchItemByNOBBNumber(ctx, product.NOBBNumber)
+		item, err = c.fetchItemByNOBBNumber(ctx, product.NOBBNumber)
 		if err != nil {
 			result.Error = fmt.Errorf("NOBB search by number failed: %w", err)
 			return result, nil
 		}
 	}
 
+	c.enhanceFromItem(ctx, result, item, searchMethod)
+	return result, nil
+}
+
+// PreviewEnhancement reports which fields NOBB would update without modifying
+// the product. The enhancement is applied to a deep copy and then discarded.
+func (c *Connector) PreviewEnhancement(ctx context.Context, product *models.EnhancedProduct) (*source.EnhancementResult, error) {
+	preview, err := c.EnhanceProduct(ctx, product.Clone())
+	if err != nil {
+		return nil, err
+	}
+
+	// Diff images against the original product
+	imagesAdded := 0
+	for _, img := range preview.Product.Images {
+		if img.Source == "nobb" && !hasImageURL(product, img.SourceURL) {
+			imagesAdded++
+		}
+	}
+
+	preview.Product = product
+	preview.ImagesAdded = imagesAdded
+	preview.DryRun = true
+	return preview, nil
+}
+
+// hasImageURL checks whether a product already has an image with the given URL
+func hasImageURL(product *models.EnhancedProduct, url string) bool {
+	for _, img := range product.Images {
+		if img.SourceURL == url {
+			return true
+		}
+	}
+	return false
+}
+
+// EnhanceProductsBatch enriches products using one NOBB request per batch of
+// known NOBB numbers. Products without a NOBB number, or whose number is not
+// returned by NOBB, fall back to the per-product EAN/SKU lookup.
+func (c *Connector) EnhanceProductsBatch(ctx context.Context, products []*models.EnhancedProduct) ([]*source.EnhancementResult, error) {
+	if !c.IsConnected() {
+		if err := c.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]*source.EnhancementResult, len(products))
+	for i, p := range products {
+		results[i] = &source.EnhancementResult{
+			Product: p,
+			Success: false,
+		}
+	}
+
+	// Group products with known NOBB numbers
+	var numbered []int
+	for i, p := range products {
+		if p.NOBBNumber != "" {
+			numbered = append(numbered, i)
+		}
+	}
+
+	// Fetch each batch with a single request
+	items := make(map[string]*nobbItem)
+	for start := 0; start < len(numbered); start += batchSize {
+		end := start + batchSize
+		if end > len(numbered) {
+			end = len(numbered)
+		}
+
+		numbers := make([]string, 0, end-start)
+		for _, idx := range numbered[start:end] {
+			numbers = append(numbers, products[idx].NOBBNumber)
+		}
+
+		batch, err := c.fetchItemsByNOBBNumbers(ctx, numbers)
+		if err != nil {
+			c.Logger().Warn("NOBB batch lookup failed", "products", len(numbers), "error", err)
+			for _, idx := range numbered[start:end] {
+				results[idx].Error = fmt.Errorf("NOBB search by number failed: %w", err)
+			}
+			continue
+		}
+		for num, item := range batch {
+			items[num] = item
+		}
+	}
+
+	// Apply the batch results, falling back to per-product lookups
+	for i, p := range products {
+		if results[i].Error != nil {
+			continue
+		}
+
+		searchMethod := ""
+		var item *nobbItem
+		if p.NOBBNumber != "" {
+			searchMethod = "nobb_number"
+			item = items[normalizeNOBBNumber(p.NOBBNumber)]
+		}
+
+		c.enhanceFromItem(ctx, results[i], item, searchMethod)
+	}
+
+	return results, nil
+}
+
+// enhanceFromItem completes an enhancement once the NOBB number lookup has run.
+// If item is nil the product is searched by EAN and SKU before giving up.
+func (c *Connector) enhanceFromItem(ctx context.Context, result *source.EnhancementResult, item *nobbItem, searchMethod string) {
+	product := result.Product
+	var err error
+
 	// Fall back to searching by EAN/barcode
-	if nobbItem == nil && product.Barcode != "" {
+	if item == nil && product.Barcode != "" {
 		searchMethod = "barcode"
-		nobbItem, err = c.searchItemByEAN(ctx, product.Barcode)
+		item, err = c.searchItemByEAN(ctx, product.Barcode)
 		if err != nil {
 			result.Error = fmt.Errorf("NOBB search by EAN failed: %w", err)
-			return result, nil
+			return
 		}
 	}
 
 	// Fall back to searching by SKU
-	if nobbItem == nil && product.SKU != "" {
+	if item == nil && product.SKU != "" {
 		searchMethod = "sku"
-		nobbItem, err = c.searchItemBySKU(ctx, product.SKU)
+		item, err = c.searchItemBySKU(ctx, product.SKU)
 		if err != nil {
 			result.Error = fmt.Errorf("NOBB search by SKU failed: %w", err)
-			return result, nil
+			return
 		}
 	}
 
-	if nobbItem == nil {
+	if item == nil {
 		details := fmt.Sprintf("product not found in NOBB database (searched by: %s", searchMethod)
 		if product.NOBBNumber != "" {
 			details += fmt.Sprintf(", nobb=%s", product.NOBBNumber)
@@ -205,29 +428,33 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 			details += fmt.Sprintf(", sku=%s", product.SKU)
 		}
 		details += ")"
-		result.Error = fmt.Errorf(details)
-		return result, nil
+		result.Error = errors.New(details)
+		return
 	}
 
 	// Fetch additional properties if not included in main response
-	nobbNumStr := fmt.Sprintf("%d", nobbItem.NobbNumber)
-	if nobbItem.Properties == nil || nobbItem.Properties.IsEmpty() {
+	nobbNumStr := fmt.Sprintf("%d", item.NobbNumber)
+	if item.Properties == nil || item.Properties.IsEmpty() {
 		props, propErr := c.fetchPropertiesSeparate(ctx, nobbNumStr)
 		if propErr == nil && len(props) > 0 {
-			if nobbItem.Properties == nil {
-				nobbItem.Properties = &nobbPropertiesGroup{}
+			if item.Properties == nil {
+				item.Properties = &nobbPropertiesGroup{}
 			}
-			nobbItem.Properties.Other = append(nobbItem.Properties.Other, props...)
+			item.Properties.Other = append(item.Properties.Other, props...)
 		}
 	}
 
+	// Resolve raw ETIM feature codes to human-readable names
+	etimNames := c.resolveItemETIMNames(ctx, item)
+
 	// Apply enhancements (suppliers, packages, media, and properties)
-	fieldsUpdated := c.applyNobbData(product, nobbItem)
+	merger := c.config.Merge.NewMerger(ConnectorName, product)
+	fieldsUpdated := c.applyNobbData(merger, product, item, etimNames)
 
 	// Build enhancement details
 	var details []string
 	details = append(details, fmt.Sprintf("NOBB#: %s", nobbNumStr))
-	propCount := nobbItem.Properties.TotalCount()
+	propCount := item.Properties.TotalCount()
 	if propCount > 0 {
 		details = append(details, fmt.Sprintf("%d properties", propCount))
 	}
@@ -248,7 +475,7 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 	product.Enhancements = append(product.Enhancements, models.Enhancement{
 		Source:      "nobb",
 		Action:      "data_enriched",
-		Details:     fmt.Sprintf("Enhanced with NOBB data (%s)", joinDetails(details)),
+		Details:     fmt.Sprintf("Enhanced with NOBB data (%s)", joinDetails(details)) + merger.OverrideDetails(),
 		FieldsAdded: fieldsUpdated,
 		Timestamp:   time.Now(),
 		Success:     true,
@@ -257,8 +484,6 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 	product.UpdatedAt = time.Now()
 	result.FieldsUpdated = fieldsUpdated
 	result.Success = true
-
-	return result, nil
 }
 
 // joinDetails joins details with comma separator
@@ -374,8 +599,12 @@ type nobbProperty struct {
 
 // fetchItemByNOBBNumber fetches a single item by NOBB number using GET with nobbnos param
 func (c *Connector) fetchItemByNOBBNumber(ctx context.Context, nobbNumber string) (*nobbItem, error) {
-	url := fmt.Sprintf("%s/items?nobbnos=%s", baseURL, nobbNumber)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if item, ok := c.GetCached(nobbNumber); ok {
+		return item, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/items?nobbnos=%s", baseURL, url.QueryEscape(nobbNumber))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -383,14 +612,14 @@ func (c *Connector) fetchItemByNOBBNumber(ctx context.Context, nobbNumber string
 	req.Header.Set("Authorization", "Basic "+c.authToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, nil
+		return nil, c.SetCached(nobbNumber, nil)
 	}
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
@@ -404,17 +633,104 @@ func (c *Connector) fetchItemByNOBBNumber(ctx context.Context, nobbNumber string
 	}
 
 	if len(items) == 0 {
-		return nil, nil
+		return nil, c.SetCached(nobbNumber, nil)
 	}
 
+	if err := c.SetCached(nobbNumber, &items[0]); err != nil {
+		return nil, err
+	}
 	return &items[0], nil
 }
 
+// fetchItemsByNOBBNumbers fetches several items in one request using the
+// comma-separated nobbnos param. Results are keyed by normalized NOBB number.
+// Cached items are served from the cache and only misses are requested.
+func (c *Connector) fetchItemsByNOBBNumbers(ctx context.Context, nobbNumbers []string) (map[string]*nobbItem, error) {
+	items := make(map[string]*nobbItem)
+	var uncached []string
+	for _, num := range nobbNumbers {
+		if item, ok := c.GetCached(num); ok {
+			if item != nil {
+				items[normalizeNOBBNumber(num)] = item
+			}
+			continue
+		}
+		uncached = append(uncached, num)
+	}
+	if len(uncached) == 0 {
+		return items, nil
+	}
+
+	escaped := make([]string, len(uncached))
+	for i, num := range uncached {
+		escaped[i] = url.QueryEscape(num)
+	}
+	endpoint := fmt.Sprintf("%s/items?nobbnos=%s", baseURL, strings.Join(escaped, ","))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Basic "+c.authToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		for _, num := range uncached {
+			c.setCachedEntry(num, nil)
+		}
+		if err := c.flushIfDue(); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("NOBB API error: status %d - %s", resp.StatusCode, string(respBody))
+	}
+
+	// API returns array directly
+	var list []nobbItem
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("JSON decode error: %w", err)
+	}
+
+	fetched := make(map[string]*nobbItem)
+	for i := range list {
+		fetched[fmt.Sprintf("%d", list[i].NobbNumber)] = &list[i]
+	}
+
+	// Cache hits and misses alike so repeated lookups skip the API
+	for _, num := range uncached {
+		key := normalizeNOBBNumber(num)
+		item := fetched[key]
+		c.setCachedEntry(num, item)
+		if item != nil {
+			items[key] = item
+		}
+	}
+	if err := c.flushIfDue(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// normalizeNOBBNumber strips leading zeros so stored numbers match API numbers
+func normalizeNOBBNumber(nobbNumber string) string {
+	return strings.TrimLeft(strings.TrimSpace(nobbNumber), "0")
+}
+
 // searchItemByEAN searches for an item by EAN/GTIN code
 func (c *Connector) searchItemByEAN(ctx context.Context, ean string) (*nobbItem, error) {
 	// Use GET /items?gtins=XXXXX
-	url := fmt.Sprintf("%s/items?gtins=%s", baseURL, ean)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	endpoint := fmt.Sprintf("%s/items?gtins=%s", baseURL, url.QueryEscape(ean))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -422,7 +738,7 @@ func (c *Connector) searchItemByEAN(ctx context.Context, ean string) (*nobbItem,
 	req.Header.Set("Authorization", "Basic "+c.authToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -448,52 +764,26 @@ func (c *Connector) searchItemByEAN(ctx context.Context, ean string) (*nobbItem,
 
 // searchItemBySKU searches for an item by supplier article number (SKU)
 // Note: NOBB API doesn't directly support searching by supplier article number.
-// This function attempts to search by NOBB number if the SKU follows NOBB format.
+// This function tries the NOBB numbers the SKU rules generate from the SKU.
 func (c *Connector) searchItemBySKU(ctx context.Context, sku string) (*nobbItem, error) {
-	// If SKU looks like a NOBB number (8 digits), try to fetch it directly
-	if len(sku) == 8 && isNumeric(sku) {
-		return c.fetchItemByNOBBNumber(ctx, sku)
-	}
-
-	// Strip common prefixes and try again
-	// e.g., CO-T309012 -> T309012 -> 309012
-	cleanSKU := sku
-	for _, prefix := range []string{"CO-T", "CO-", "T"} {
-		if len(cleanSKU) > len(prefix) && cleanSKU[:len(prefix)] == prefix {
-			cleanSKU = cleanSKU[len(prefix):]
-			break
-		}
-	}
-
-	// If cleaned SKU looks like a NOBB number, try that
-	if len(cleanSKU) >= 6 && len(cleanSKU) <= 8 && isNumeric(cleanSKU) {
-		// Pad to 8 digits if needed
-		for len(cleanSKU) < 8 {
-			cleanSKU = "0" + cleanSKU
+	// The SKU rules turn e.g. CO-T309012 into the NOBB number 00309012
+	for _, nobbNumber := range c.skuRules.NOBBCandidates(sku) {
+		item, err := c.fetchItemByNOBBNumber(ctx, nobbNumber)
+		if err != nil || item != nil {
+			return item, err
 		}
-		return c.fetchItemByNOBBNumber(ctx, cleanSKU)
 	}
 
 	// NOBB API doesn't support direct supplier article number search
 	return nil, nil
 }
 
-// isNumeric checks if a string contains only digits
-func isNumeric(s string) bool {
-	for _, c := range s {
-		if c < '0' || c > '9' {
-			return false
-		}
-	}
-	return true
-}
-
 // fetchProperties fetches properties for a NOBB item
 // fetchPropertiesSeparate fetches properties from the separate /properties endpoint
 // This endpoint returns a flat list of properties, different from the main items endpoint
 func (c *Connector) fetchPropertiesSeparate(ctx context.Context, nobbNumber string) ([]nobbProperty, error) {
-	url := fmt.Sprintf("%s/items/%s/properties", baseURL, nobbNumber)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	endpoint := fmt.Sprintf("%s/items/%s/properties", baseURL, url.PathEscape(nobbNumber))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -501,7 +791,7 @@ func (c *Connector) fetchPropertiesSeparate(ctx context.Context, nobbNumber stri
 	req.Header.Set("Authorization", "Basic "+c.authToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -521,8 +811,8 @@ func (c *Connector) fetchPropertiesSeparate(ctx context.Context, nobbNumber stri
 
 // fetchSuppliers fetches suppliers for a NOBB item
 func (c *Connector) fetchSuppliers(ctx context.Context, nobbNumber string) ([]nobbSupplier, error) {
-	url := fmt.Sprintf("%s/items/%s/suppliers", baseURL, nobbNumber)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	endpoint := fmt.Sprintf("%s/items/%s/suppliers", baseURL, url.PathEscape(nobbNumber))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -530,7 +820,7 @@ func (c *Connector) fetchSuppliers(ctx context.Context, nobbNumber string) ([]no
 	req.Header.Set("Authorization", "Basic "+c.authToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -550,8 +840,8 @@ func (c *Connector) fetchSuppliers(ctx context.Context, nobbNumber string) ([]no
 
 // fetchPackages fetches package info for a NOBB item
 func (c *Connector) fetchPackages(ctx context.Context, nobbNumber string) ([]nobbPackage, error) {
-	url := fmt.Sprintf("%s/items/%s/packages", baseURL, nobbNumber)
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	endpoint := fmt.Sprintf("%s/items/%s/packages", baseURL, url.PathEscape(nobbNumber))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -559,7 +849,7 @@ func (c *Connector) fetchPackages(ctx context.Context, nobbNumber string) ([]nob
 	req.Header.Set("Authorization", "Basic "+c.authToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -577,63 +867,63 @@ func (c *Connector) fetchPackages(ctx context.Context, nobbNumber string) ([]nob
 	return packages, nil
 }
 
-// applyNobbData applies NOBB data to an EnhancedProduct
-func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbItem) []string {
+// applyNobbData applies NOBB data to an EnhancedProduct. Core fields and
+// images go through merger, which applies the configured overwrite policy.
+// etimNames maps raw ETIM feature codes to names and may be nil.
+func (c *Connector) applyNobbData(merger *source.Merger, product *models.EnhancedProduct, item *nobbItem, etimNames map[string]string) []string {
 	var fieldsUpdated []string
 
 	// Set NOBB number
-	nobbNumStr := fmt.Sprintf("%d", item.NobbNumber)
-	if product.NOBBNumber == "" && item.NobbNumber > 0 {
-		product.NOBBNumber = nobbNumStr
-		fieldsUpdated = append(fieldsUpdated, "nobb_number")
+	if item.NobbNumber > 0 {
+		merger.SetString("nobb_number", &product.NOBBNumber, fmt.Sprintf("%d", item.NobbNumber))
 	}
 
-	// Update description if not set
-	if product.Description == "" && item.Description != "" {
-		product.Description = item.Description
-		fieldsUpdated = append(fieldsUpdated, "description")
-	}
-
-	// Use digital channel text for description if available
-	if product.Description == "" && item.DigitalChannelText != "" {
-		product.Description = item.DigitalChannelText
-		fieldsUpdated = append(fieldsUpdated, "description")
+	// Set description, falling back to the digital channel text
+	description := item.Description
+	if description == "" {
+		description = item.DigitalChannelText
 	}
+	merger.SetString("description", &product.Description, description)
 
 	// Set product type from NOBB category
-	if product.ProductType == "" && item.ProductGroupName != "" {
-		product.ProductType = item.ProductGroupName
-		fieldsUpdated = append(fieldsUpdated, "product_type")
-	}
+	merger.SetString("product_type", &product.ProductType, item.ProductGroupName)
 
-	// Extract dimensions and weight from first supplier's package info
-	if len(item.Suppliers) > 0 && len(item.Suppliers[0].Packages) > 0 {
-		pkg := item.Suppliers[0].Packages[0]
+	// Extract dimensions and weight from the main supplier's base package
+	if supplier := preferredSupplier(item.Suppliers); supplier != nil && len(supplier.Packages) > 0 {
+		pkg := preferredPackage(*supplier)
 
-		// Set dimensions (values are in mm)
-		if product.Dimensions == nil && (pkg.Length > 0 || pkg.Width > 0 || pkg.Height > 0) {
-			product.Dimensions = &models.Dimensions{
+		// Set dimensions (values are in mm, normalized to the configured unit)
+		if pkg.Length > 0 || pkg.Width > 0 || pkg.Height > 0 {
+			dims := &models.Dimensions{
 				Length: float64(pkg.Length),
 				Width:  float64(pkg.Width),
 				Height: float64(pkg.Height),
 				Unit:   "mm",
 			}
-			fieldsUpdated = append(fieldsUpdated, "dimensions")
+			if converted := dims.ConvertTo(c.config.DimensionUnit); converted != nil {
+				dims = converted
+			}
+			merger.SetDimensions(dims)
 		}
 
-		// Set weight (value is in kg)
-		if product.Weight == nil && pkg.Weight > 0 {
-			product.Weight = &models.Weight{
+		// Set weight (value is in kg, normalized to the configured unit)
+		if pkg.Weight > 0 {
+			weight := &models.Weight{
 				Value: pkg.Weight,
 				Unit:  "kg",
 			}
-			fieldsUpdated = append(fieldsUpdated, "weight")
+			if converted := weight.ConvertTo(c.config.WeightUnit); converted != nil {
+				weight = converted
+			}
+			merger.SetWeight(weight)
 		}
 
-		// Set barcode from GTIN if not already set
-		if product.Barcode == "" && pkg.GTIN != "" {
-			product.Barcode = pkg.GTIN
-			fieldsUpdated = append(fieldsUpdated, "barcode")
+		// Set barcode from GTIN, in canonical form; GTINs with a wrong check
+		// digit are not copied
+		if gtin, ok := models.NormalizeBarcode(pkg.GTIN); ok {
+			merger.SetString("barcode", &product.Barcode, gtin)
+		} else if pkg.GTIN != "" {
+			c.Logger().Debug("ignoring invalid GTIN", "sku", product.SKU, "gtin", pkg.GTIN)
 		}
 	}
 
@@ -686,13 +976,18 @@ func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbIte
 	if item.Properties != nil {
 		// Extract ETIM properties (technical specifications)
 		for _, prop := range item.Properties.ETIM {
-			product.Properties = append(product.Properties, models.Property{
+			property := models.Property{
 				Code:   prop.PropertyGUID,
 				Name:   prop.PropertyName,
 				Value:  prop.Value,
 				Unit:   prop.Unit,
 				Source: "nobb_etim",
-			})
+			}
+			if name, ok := etimNames[prop.PropertyName]; ok && isETIMCode(prop.PropertyName) {
+				property.Name = name
+				property.RawName = prop.PropertyName
+			}
+			product.Properties = append(product.Properties, property)
 		}
 		// Extract environment properties
 		for _, prop := range item.Properties.Environment {
@@ -740,6 +1035,7 @@ func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbIte
 	}
 
 	// Extract supplier info with media
+	var images []models.ProductImage
 	for _, sup := range item.Suppliers {
 		product.Suppliers = append(product.Suppliers, models.Supplier{
 			ID:        sup.ParticipantNumber,
@@ -752,7 +1048,7 @@ func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbIte
 		for _, media := range sup.Media {
 			if media.URL != "" {
 				// Determine position - primary images come first
-				position := len(product.Images) + 1
+				position := len(product.Images) + len(images) + 1
 				if media.IsPrimary {
 					position = 1
 				}
@@ -774,7 +1070,7 @@ func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbIte
 					altText = product.Title + " - " + media.MediaType
 				}
 
-				product.Images = append(product.Images, models.ProductImage{
+				images = append(images, models.ProductImage{
 					ID:        media.GUID,
 					SourceURL: media.URL,
 					Position:  position,
@@ -789,17 +1085,8 @@ func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbIte
 		fieldsUpdated = append(fieldsUpdated, "suppliers")
 	}
 
-	// Check if images were added
-	imagesAdded := false
-	for _, img := range product.Images {
-		if img.Source == "nobb" {
-			imagesAdded = true
-			break
-		}
-	}
-	if imagesAdded {
-		fieldsUpdated = append(fieldsUpdated, "images")
-	}
+	// Add images not already on the product
+	merger.AddImages(images)
 
 	// Extract comprehensive package info from all suppliers
 	for _, sup := range item.Suppliers {
@@ -830,5 +1117,35 @@ func (c *Connector) applyNobbData(product *models.EnhancedProduct, item *nobbIte
 		fieldsUpdated = append(fieldsUpdated, "package_info")
 	}
 
-	return fieldsUpdated
+	return append(merger.Updated(), fieldsUpdated...)
+}
+
+// preferredSupplier returns the supplier flagged as main supplier, falling back
+// to the first supplier if none is flagged
+func preferredSupplier(suppliers []nobbSupplier) *nobbSupplier {
+	if len(suppliers) == 0 {
+		return nil
+	}
+	for i := range suppliers {
+		if suppliers[i].IsMainSupplier {
+			return &suppliers[i]
+		}
+	}
+	return &suppliers[0]
+}
+
+// preferredPackage returns the single-piece package of a supplier, which holds
+// the base product dimensions. Packages are often listed pallet-first, so the
+// first package is only used when no piece package exists.
+func preferredPackage(supplier nobbSupplier) *nobbPackage {
+	if len(supplier.Packages) == 0 {
+		return nil
+	}
+	for i := range supplier.Packages {
+		switch supplier.Packages[i].Class {
+		case "PIECE", "F-PAK": // F-PAK is the consumer (single unit) package
+			return &supplier.Packages[i]
+		}
+	}
+	return &supplier.Packages[0]
 }
@@ -0,0 +1,128 @@
+package nobb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+func TestPreferredPackage(t *testing.T) {
+	tests := []struct {
+		name      string
+		classes   []string
+		wantClass string
+		wantIndex int // Index of the expected package; -1 for nil
+	}{
+		{"no packages", nil, "", -1},
+		{"piece only", []string{"PIECE"}, "PIECE", 0},
+		{"pallet first", []string{"PALLET", "OUTER", "PIECE"}, "PIECE", 2},
+		{"piece between", []string{"OUTER", "PIECE", "PALLET"}, "PIECE", 1},
+		{"consumer package", []string{"PALLET", "F-PAK"}, "F-PAK", 1},
+		{"first piece wins", []string{"PALLET", "F-PAK", "PIECE"}, "F-PAK", 1},
+		{"no piece falls back to first", []string{"PALLET", "OUTER"}, "PALLET", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var supplier nobbSupplier
+			for i, class := range tt.classes {
+				supplier.Packages = append(supplier.Packages, nobbPackage{Class: class, Length: i + 1})
+			}
+
+			got := preferredPackage(supplier)
+			if tt.wantIndex < 0 {
+				if got != nil {
+					t.Fatalf("got package %+v, want nil", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil package")
+			}
+			if got.Class != tt.wantClass || got != &supplier.Packages[tt.wantIndex] {
+				t.Errorf("got %s package %d, want %s package %d", got.Class, got.Length-1, tt.wantClass, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestPreferredSupplier(t *testing.T) {
+	if got := preferredSupplier(nil); got != nil {
+		t.Errorf("got %+v for no suppliers, want nil", *got)
+	}
+
+	suppliers := []nobbSupplier{{Name: "first"}, {Name: "second"}}
+	if got := preferredSupplier(suppliers); got.Name != "first" {
+		t.Errorf("got %s without a main supplier, want first", got.Name)
+	}
+
+	suppliers[1].IsMainSupplier = true
+	if got := preferredSupplier(suppliers); got.Name != "second" {
+		t.Errorf("got %s, want the main supplier", got.Name)
+	}
+}
+
+func TestApplyNobbDataUsesMainSupplierPiece(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "item_mixed_packages.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var item nobbItem
+	if err := json.Unmarshal(data, &item); err != nil {
+		t.Fatalf("invalid fixture: %v", err)
+	}
+
+	c := NewConnector(Config{CacheFile: filepath.Join(t.TempDir(), "cache.json")})
+	product := &models.EnhancedProduct{SKU: "CO-T309012"}
+	c.applyNobbData(c.config.Merge.NewMerger(ConnectorName, product), product, &item, nil)
+
+	if product.Dimensions == nil {
+		t.Fatal("no dimensions set")
+	}
+	if d := product.Dimensions; d.Length != 150 || d.Width != 80 || d.Height != 60 || d.Unit != "mm" {
+		t.Errorf("dimensions = %+v, want the main supplier's piece 150x80x60 mm", *d)
+	}
+	if product.Weight == nil || product.Weight.Value != 0.35 || product.Weight.Unit != "kg" {
+		t.Errorf("weight = %+v, want 0.35 kg", product.Weight)
+	}
+	if product.Barcode != "4006381333931" {
+		t.Errorf("barcode = %q, want the piece GTIN", product.Barcode)
+	}
+}
+
+// roundTripFunc records requests instead of sending them
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestResolveETIMNamesEscapesClass(t *testing.T) {
+	c := NewConnector(Config{CacheFile: filepath.Join(t.TempDir(), "cache.json"), RateLimitMs: 1})
+
+	var got *url.URL
+	c.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.URL
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`[]`)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	})
+
+	if _, err := c.ResolveETIMNames(context.Background(), "EC000/../items?x=1"); err != nil {
+		t.Fatalf("ResolveETIMNames: %v", err)
+	}
+	if want := "/api/v1/etim/classes/EC000%2F..%2Fitems%3Fx=1/features"; got.EscapedPath() != want {
+		t.Errorf("path = %s, want %s", got.EscapedPath(), want)
+	}
+	if got.RawQuery != "" {
+		t.Errorf("class leaked into the query: %q", got.RawQuery)
+	}
+}
@@ -0,0 +1,105 @@
+package nobb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// etimCodePattern matches raw ETIM identifiers such as EF000008 (feature),
+// EC002112 (class), EV000123 (value) or EG000017 (group)
+var etimCodePattern = regexp.MustCompile(`^E[CFGV]\d{6}$`)
+
+// etimFeature is a feature definition from the NOBB ETIM classification endpoint
+type etimFeature struct {
+	FeatureCode string `json:"featureCode"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+}
+
+// isETIMCode reports whether a property name is a raw ETIM code rather than a label
+func isETIMCode(name string) bool {
+	return etimCodePattern.MatchString(name)
+}
+
+// ResolveETIMNames returns a map of ETIM feature codes to localized names for
+// the given ETIM class. Dictionaries are cached per class for the session.
+func (c *Connector) ResolveETIMNames(ctx context.Context, class string) (map[string]string, error) {
+	c.etimMu.RLock()
+	names, ok := c.etimNames[class]
+	c.etimMu.RUnlock()
+	if ok {
+		return names, nil
+	}
+
+	endpoint := fmt.Sprintf("%s/etim/classes/%s/features", baseURL, url.PathEscape(class))
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Basic "+c.authToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("NOBB API error: status %d - %s", resp.StatusCode, string(respBody))
+	}
+
+	var features []etimFeature
+	if err := json.NewDecoder(resp.Body).Decode(&features); err != nil {
+		return nil, fmt.Errorf("JSON decode error: %w", err)
+	}
+
+	names = make(map[string]string, len(features))
+	for _, f := range features {
+		if f.FeatureCode != "" && f.Description != "" {
+			names[f.FeatureCode] = f.Description
+		}
+	}
+
+	c.etimMu.Lock()
+	c.etimNames[class] = names
+	c.etimMu.Unlock()
+
+	return names, nil
+}
+
+// resolveItemETIMNames fetches the feature dictionary for an item's ETIM class
+// when any of its ETIM properties are labelled with raw codes
+func (c *Connector) resolveItemETIMNames(ctx context.Context, item *nobbItem) map[string]string {
+	if item.EtimClass == "" || item.Properties == nil {
+		return nil
+	}
+
+	needsResolve := false
+	for _, prop := range item.Properties.ETIM {
+		if isETIMCode(prop.PropertyName) {
+			needsResolve = true
+			break
+		}
+	}
+	if !needsResolve {
+		return nil
+	}
+
+	names, err := c.ResolveETIMNames(ctx, item.EtimClass)
+	if err != nil {
+		// Keep raw codes and don't retry this class for the rest of the session
+		c.etimMu.Lock()
+		c.etimNames[item.EtimClass] = map[string]string{}
+		c.etimMu.Unlock()
+		return nil
+	}
+	return names
+}
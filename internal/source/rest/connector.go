@@ -0,0 +1,503 @@
+// Package rest imports products from any paginated JSON API, mapping
+// response fields to products with JSONPath-style paths from config
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/pkg/models"
+)
+
+const ConnectorName = "rest"
+
+// Defaults for unset config values
+const (
+	DefaultPageSize    = 100
+	DefaultRateLimitMs = 500
+)
+
+// Pagination types
+const (
+	PaginationNone    = "none"     // A single request returns every product
+	PaginationPage    = "page"     // page=1,2,... until a short page
+	PaginationOffset  = "offset"   // offset=0,100,... until a short page
+	PaginationCursor  = "cursor"   // Cursor read from next_path, sent as cursor_param
+	PaginationNextURL = "next_url" // Absolute or relative next page URL read from next_path
+)
+
+// Config holds REST connector configuration. It is read directly from
+// sources.rest in the config file.
+type Config struct {
+	URL               string            `yaml:"url"`                           // Products endpoint
+	Headers           map[string]string `yaml:"headers,omitempty"`             // Request headers; {token} is replaced with the token_env credential
+	TokenEnv          string            `yaml:"token_env,omitempty"`           // Credential reference for {token} (env:, file:, cmd:)
+	ItemsPath         string            `yaml:"items_path,omitempty"`          // Path to the product array (default: the response itself)
+	UpdatedSinceParam string            `yaml:"updated_since_param,omitempty"` // Query param for --since/--incremental (RFC3339)
+	Currency          string            `yaml:"currency,omitempty"`            // Price currency when currency_path is unset (default: NOK)
+	RateLimitMs       int               `yaml:"rate_limit_ms,omitempty"`       // Delay between requests (default: 500)
+	MaxRetries        int               `yaml:"max_retries,omitempty"`         // Retries on network errors, 429 and 5xx (default: 3)
+	Pagination        Pagination        `yaml:"pagination,omitempty"`
+	Mapping           Mapping           `yaml:"mapping"`
+
+	Credentials credentials.Provider `yaml:"-"` // Resolves TokenEnv (default: credentials.Default)
+}
+
+// Pagination describes how the endpoint pages through products
+type Pagination struct {
+	Type        string `yaml:"type,omitempty"`         // none, page, offset, cursor or next_url (default: none)
+	PageParam   string `yaml:"page_param,omitempty"`   // Page number or offset param (default: page or offset)
+	SizeParam   string `yaml:"size_param,omitempty"`   // Page size param (default: limit)
+	PageSize    int    `yaml:"page_size,omitempty"`    // Products per page (default: 100)
+	CursorParam string `yaml:"cursor_param,omitempty"` // Param the cursor is sent in (default: cursor)
+	NextPath    string `yaml:"next_path,omitempty"`    // Path to the next cursor or URL in the response
+}
+
+// Mapping maps fields of one product object to EnhancedProduct fields
+type Mapping struct {
+	IDPath          string `yaml:"id_path,omitempty"`
+	SKUPath         string `yaml:"sku_path"`   // Required
+	TitlePath       string `yaml:"title_path"` // Required
+	DescriptionPath string `yaml:"description_path,omitempty"`
+	VendorPath      string `yaml:"vendor_path,omitempty"`
+	BarcodePath     string `yaml:"barcode_path,omitempty"`
+	ProductTypePath string `yaml:"product_type_path,omitempty"`
+	TagsPath        string `yaml:"tags_path,omitempty"`  // A string array, comma-separated string or e.g. tags[*].name
+	PricePath       string `yaml:"price_path,omitempty"` // Number or numeric string
+	CompareAtPath   string `yaml:"compare_at_path,omitempty"`
+	CurrencyPath    string `yaml:"currency_path,omitempty"`
+	ImagesPath      string `yaml:"images_path,omitempty"` // Image URLs, e.g. images[*].src
+	UpdatedAtPath   string `yaml:"updated_at_path,omitempty"`
+}
+
+// compiledMapping holds the mapping's compiled paths
+type compiledMapping struct {
+	id, sku, title, description, vendor, barcode, productType path
+	tags, price, compareAt, currency, images, updatedAt       path
+}
+
+// Connector implements the source.Connector interface for generic JSON APIs
+type Connector struct {
+	*source.BaseConnector
+	config  Config
+	client  *http.Client
+	limiter *source.RateLimiter
+	retry   source.RetryPolicy
+	headers map[string]string
+
+	items   path
+	next    path
+	mapping compiledMapping
+}
+
+// NewConnector creates a new REST connector
+func NewConnector(cfg Config) *Connector {
+	rateLimit := cfg.RateLimitMs
+	if rateLimit <= 0 {
+		rateLimit = DefaultRateLimitMs
+	}
+	retry := source.DefaultRetryPolicy
+	if cfg.MaxRetries > 0 {
+		retry.MaxRetries = cfg.MaxRetries
+	}
+	return &Connector{
+		BaseConnector: source.NewBaseConnector(
+			ConnectorName,
+			source.TypeSource,
+			[]source.Capability{
+				source.CapabilityFetchProducts,
+				source.CapabilityFetchImages,
+			},
+		),
+		config: cfg,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: source.NewRateLimiter(time.Duration(rateLimit) * time.Millisecond),
+		retry:   retry,
+	}
+}
+
+// Validate checks the endpoint, pagination and field mapping without
+// making any requests
+func (c Config) Validate() error {
+	_, _, _, err := c.compile()
+	return err
+}
+
+// compile validates the config and compiles its paths
+func (c Config) compile() (items, next path, m compiledMapping, err error) {
+	if c.URL == "" {
+		return nil, nil, m, fmt.Errorf("rest source url not configured")
+	}
+	if u, perr := url.Parse(c.URL); perr != nil || u.Scheme == "" || u.Host == "" {
+		return nil, nil, m, fmt.Errorf("invalid rest source url %q", c.URL)
+	}
+	if c.Mapping.SKUPath == "" || c.Mapping.TitlePath == "" {
+		return nil, nil, m, fmt.Errorf("rest mapping needs sku_path and title_path")
+	}
+	for name, value := range c.Headers {
+		if strings.Contains(value, "{token}") && c.TokenEnv == "" {
+			return nil, nil, m, fmt.Errorf("header %s uses {token} but token_env is not set", name)
+		}
+	}
+
+	switch c.Pagination.Type {
+	case "", PaginationNone, PaginationPage, PaginationOffset:
+	case PaginationCursor, PaginationNextURL:
+		if c.Pagination.NextPath == "" {
+			return nil, nil, m, fmt.Errorf("%s pagination needs next_path", c.Pagination.Type)
+		}
+	default:
+		return nil, nil, m, fmt.Errorf("unsupported pagination type %q (use none, page, offset, cursor or next_url)", c.Pagination.Type)
+	}
+
+	compile := func(name, s string) path {
+		if err != nil {
+			return nil
+		}
+		var p path
+		if p, err = compilePath(s); err != nil {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		return p
+	}
+	items = compile("items_path", c.ItemsPath)
+	next = compile("pagination.next_path", c.Pagination.NextPath)
+	m = compiledMapping{
+		id:          compile("id_path", c.Mapping.IDPath),
+		sku:         compile("sku_path", c.Mapping.SKUPath),
+		title:       compile("title_path", c.Mapping.TitlePath),
+		description: compile("description_path", c.Mapping.DescriptionPath),
+		vendor:      compile("vendor_path", c.Mapping.VendorPath),
+		barcode:     compile("barcode_path", c.Mapping.BarcodePath),
+		productType: compile("product_type_path", c.Mapping.ProductTypePath),
+		tags:        compile("tags_path", c.Mapping.TagsPath),
+		price:       compile("price_path", c.Mapping.PricePath),
+		compareAt:   compile("compare_at_path", c.Mapping.CompareAtPath),
+		currency:    compile("currency_path", c.Mapping.CurrencyPath),
+		images:      compile("images_path", c.Mapping.ImagesPath),
+		updatedAt:   compile("updated_at_path", c.Mapping.UpdatedAtPath),
+	}
+	return items, next, m, err
+}
+
+// Connect validates the config, resolves the token and fetches the first
+// page to check the items path
+func (c *Connector) Connect(ctx context.Context) error {
+	items, next, mapping, err := c.config.compile()
+	if err != nil {
+		return err
+	}
+	c.items, c.next, c.mapping = items, next, mapping
+
+	token := ""
+	if c.config.TokenEnv != "" {
+		token, err = credentials.Resolve(c.config.Credentials, c.config.TokenEnv)
+		if err != nil {
+			return fmt.Errorf("failed to resolve rest source token: %w", err)
+		}
+		if token == "" {
+			return fmt.Errorf("rest source token %s is not set", c.config.TokenEnv)
+		}
+	}
+	c.headers = make(map[string]string, len(c.config.Headers))
+	for name, value := range c.config.Headers {
+		c.headers[name] = strings.ReplaceAll(value, "{token}", token)
+	}
+
+	return c.Test(ctx)
+}
+
+// Close cleans up resources
+func (c *Connector) Close() error {
+	c.SetConnected(false)
+	return nil
+}
+
+// Test fetches the first page and checks that items_path selects an array
+func (c *Connector) Test(ctx context.Context) error {
+	_, body, err := c.fetchPage(ctx, c.pageURL(url.Values{}, 1, 0, ""))
+	if err != nil {
+		return err
+	}
+	if c.items != nil && c.items.first(body) == nil {
+		return fmt.Errorf("items_path %q not found in the response", c.config.ItemsPath)
+	}
+	c.SetConnected(true)
+	return nil
+}
+
+// FetchProducts pages through the endpoint until opts.Limit products are
+// loaded or the last page is read. Vendor, SKUs and UpdatedSince are also
+// applied to the mapped products, since the endpoint may not filter.
+func (c *Connector) FetchProducts(ctx context.Context, opts source.FetchOptions) (*source.FetchResult, error) {
+	if !c.IsConnected() {
+		if err := c.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{}
+	if !opts.UpdatedSince.IsZero() && c.config.UpdatedSinceParam != "" {
+		query.Set(c.config.UpdatedSinceParam, opts.UpdatedSince.UTC().Format(time.RFC3339))
+	}
+	skus := make(map[string]bool, len(opts.SKUs))
+	for _, sku := range opts.SKUs {
+		skus[sku] = true
+	}
+
+	products := make([]models.EnhancedProduct, 0)
+	page, offset, cursor := 1, 0, ""
+	pageURL := c.pageURL(query, page, offset, cursor)
+	seen := make(map[string]bool) // Page URLs, to stop on APIs that repeat the last page
+
+	for pageURL != "" && !seen[pageURL] {
+		seen[pageURL] = true
+		items, body, err := c.fetchPage(ctx, pageURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			p := c.convertProduct(item)
+			switch {
+			case p.SKU == "":
+				continue // State is keyed by SKU
+			case opts.Vendor != "" && !strings.EqualFold(p.Vendor, opts.Vendor):
+				continue
+			case len(skus) > 0 && !skus[p.SKU]:
+				continue
+			case !opts.UpdatedSince.IsZero() && !p.UpdatedAt.IsZero() && p.UpdatedAt.Before(opts.UpdatedSince):
+				continue
+			}
+			if opts.Limit > 0 && len(products) >= opts.Limit {
+				return &source.FetchResult{Products: products, TotalCount: len(products), HasMore: true}, nil
+			}
+			products = append(products, p)
+		}
+
+		pageSize := c.pageSize()
+		switch c.config.Pagination.Type {
+		case PaginationPage:
+			if len(items) < pageSize {
+				pageURL = ""
+				break
+			}
+			page++
+			pageURL = c.pageURL(query, page, offset, cursor)
+		case PaginationOffset:
+			if len(items) < pageSize {
+				pageURL = ""
+				break
+			}
+			offset += len(items)
+			pageURL = c.pageURL(query, page, offset, cursor)
+		case PaginationCursor:
+			if cursor = c.next.str(body); cursor == "" {
+				pageURL = ""
+				break
+			}
+			pageURL = c.pageURL(query, page, offset, cursor)
+		case PaginationNextURL:
+			pageURL = c.resolveNext(pageURL, c.next.str(body))
+		default:
+			pageURL = ""
+		}
+	}
+
+	return &source.FetchResult{
+		Products:   products,
+		TotalCount: len(products),
+	}, nil
+}
+
+func (c *Connector) pageSize() int {
+	if c.config.Pagination.PageSize > 0 {
+		return c.config.Pagination.PageSize
+	}
+	return DefaultPageSize
+}
+
+// pageURL builds the URL of a page from the endpoint and pagination params
+func (c *Connector) pageURL(query url.Values, page, offset int, cursor string) string {
+	u, err := url.Parse(c.config.URL)
+	if err != nil {
+		return c.config.URL
+	}
+	q := u.Query()
+	for key, values := range query {
+		q[key] = values
+	}
+
+	p := c.config.Pagination
+	sizeParam := p.SizeParam
+	if sizeParam == "" {
+		sizeParam = "limit"
+	}
+	switch p.Type {
+	case PaginationPage:
+		q.Set(firstNonEmpty(p.PageParam, "page"), strconv.Itoa(page))
+		q.Set(sizeParam, strconv.Itoa(c.pageSize()))
+	case PaginationOffset:
+		q.Set(firstNonEmpty(p.PageParam, "offset"), strconv.Itoa(offset))
+		q.Set(sizeParam, strconv.Itoa(c.pageSize()))
+	case PaginationCursor:
+		if cursor != "" {
+			q.Set(firstNonEmpty(p.CursorParam, "cursor"), cursor)
+		}
+		if p.PageSize > 0 {
+			q.Set(sizeParam, strconv.Itoa(p.PageSize))
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// resolveNext resolves a next page link against the current page URL
+func (c *Connector) resolveNext(current, next string) string {
+	if next == "" {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return next
+	}
+	ref, err := url.Parse(next)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// fetchPage fetches one page and returns the product objects selected by
+// items_path along with the decoded body
+func (c *Connector) fetchPage(ctx context.Context, pageURL string) ([]interface{}, interface{}, error) {
+	resp, err := c.DoWithRetry(ctx, c.client, c.limiter, c.retry, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		for name, value := range c.headers {
+			req.Header.Set(name, value)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("rest source error (status %d): %s", resp.StatusCode, string(bytes.TrimSpace(body)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	var body interface{}
+	if err := decoder.Decode(&body); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	list := body
+	if c.items != nil {
+		list = c.items.first(body)
+	}
+	items, ok := list.([]interface{})
+	if !ok {
+		if list == nil && c.items != nil {
+			// An empty last page may omit the array altogether
+			return nil, body, nil
+		}
+		return nil, nil, fmt.Errorf("items_path %q does not select an array in the response", c.config.ItemsPath)
+	}
+	return items, body, nil
+}
+
+// convertProduct maps one product object to an EnhancedProduct
+func (c *Connector) convertProduct(item interface{}) models.EnhancedProduct {
+	m := c.mapping
+	ep := models.EnhancedProduct{
+		ID:             m.id.str(item),
+		SKU:            m.sku.str(item),
+		Title:          m.title.str(item),
+		Description:    m.description.str(item),
+		Vendor:         m.vendor.str(item),
+		Barcode:        m.barcode.str(item),
+		ProductType:    m.productType.str(item),
+		Status:         models.StatusPending,
+		Specifications: make(map[string]string),
+	}
+
+	for _, tag := range m.tags.strs(item) {
+		for _, t := range strings.Split(tag, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				ep.Tags = append(ep.Tags, t)
+			}
+		}
+	}
+
+	if price := parsePrice(m.price.str(item)); price > 0 {
+		currency := firstNonEmpty(strings.ToUpper(m.currency.str(item)), strings.ToUpper(c.config.Currency), "NOK")
+		ep.Price = &models.Price{
+			Amount:    price,
+			Currency:  currency,
+			CompareAt: parsePrice(m.compareAt.str(item)),
+		}
+	}
+
+	for i, src := range m.images.strs(item) {
+		ep.Images = append(ep.Images, models.ProductImage{
+			SourceURL: src,
+			Position:  i + 1,
+			Status:    "existing",
+			Source:    ConnectorName,
+		})
+	}
+
+	if raw := m.updatedAt.str(item); raw != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, raw); err == nil {
+				ep.UpdatedAt = t
+				break
+			}
+		}
+	}
+
+	return ep
+}
+
+// EnhanceProduct is not supported for the REST source connector
+func (c *Connector) EnhanceProduct(ctx context.Context, product *models.EnhancedProduct) (*source.EnhancementResult, error) {
+	return nil, fmt.Errorf("rest connector does not support product enhancement")
+}
+
+// parsePrice parses "199", "199.00" or "199,00", returning 0 when invalid
+func parsePrice(s string) float64 {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// path is a compiled JSONPath-style field path: dot-separated keys with
+// optional [n] indexes and [*] wildcards, e.g. "$.data.items",
+// "images[*].url" or "variants[0].sku". A leading "$." is optional.
+type path []step
+
+type step struct {
+	key      string // Object key; empty for a bare index step
+	index    int    // Array index when hasIndex
+	hasIndex bool
+	all      bool // [*]: every array element
+}
+
+// compilePath parses a field path. An empty string compiles to nil, which
+// matches nothing.
+func compilePath(s string) (path, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "$"), ".")
+	if s == "" {
+		return nil, nil
+	}
+
+	var p path
+	for _, part := range strings.Split(s, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key == "" && rest == "" {
+			return nil, fmt.Errorf("invalid path %q: empty segment", s)
+		}
+		if key != "" {
+			p = append(p, step{key: key})
+		}
+		for rest != "" {
+			inner, after, ok := strings.Cut(rest, "]")
+			if !ok {
+				return nil, fmt.Errorf("invalid path %q: unclosed [", s)
+			}
+			if inner == "*" {
+				p = append(p, step{all: true})
+			} else {
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("invalid path %q: bad index [%s]", s, inner)
+				}
+				p = append(p, step{index: n, hasIndex: true})
+			}
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("invalid path %q: unexpected %q", s, after)
+			}
+			rest = after[1:]
+		}
+	}
+	return p, nil
+}
+
+// get returns every value the path selects in v, a value decoded by
+// encoding/json with UseNumber. Missing keys and out-of-range indexes
+// select nothing.
+func (p path) get(v interface{}) []interface{} {
+	if p == nil {
+		return nil
+	}
+	values := []interface{}{v}
+	for _, s := range p {
+		var next []interface{}
+		for _, value := range values {
+			switch {
+			case s.key != "":
+				if obj, ok := value.(map[string]interface{}); ok {
+					if child, ok := obj[s.key]; ok && child != nil {
+						next = append(next, child)
+					}
+				}
+			case s.all:
+				if arr, ok := value.([]interface{}); ok {
+					next = append(next, arr...)
+				}
+			case s.hasIndex:
+				if arr, ok := value.([]interface{}); ok && s.index < len(arr) {
+					next = append(next, arr[s.index])
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// first returns the first selected value, or nil
+func (p path) first(v interface{}) interface{} {
+	values := p.get(v)
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// str returns the first selected value as a string. Numbers are formatted
+// without trailing zeros; objects and arrays give "".
+func (p path) str(v interface{}) string {
+	return toString(p.first(v))
+}
+
+// strs returns every selected value as a string, flattening arrays and
+// skipping empty values
+func (p path) strs(v interface{}) []string {
+	var out []string
+	for _, value := range p.get(v) {
+		if arr, ok := value.([]interface{}); ok {
+			for _, item := range arr {
+				if s := toString(item); s != "" {
+					out = append(out, s)
+				}
+			}
+			continue
+		}
+		if s := toString(value); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
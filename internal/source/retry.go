@@ -0,0 +1,98 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces requests at least Interval apart. The zero value does
+// not wait.
+type RateLimiter struct {
+	Interval time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewRateLimiter creates a limiter allowing one request per interval
+func NewRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{Interval: interval}
+}
+
+// Wait blocks until the next request may be sent or ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if wait := l.Interval - time.Since(l.last); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	l.last = time.Now()
+	return nil
+}
+
+// RetryPolicy decides how often a failed request is retried. Network
+// errors, 429 and 5xx responses are retried; a Retry-After header overrides
+// the backoff.
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first attempt (0 = no retries)
+	Backoff    time.Duration // Delay before the first retry, doubled each time (default: 1s)
+}
+
+// DefaultRetryPolicy retries three times starting at one second
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, Backoff: time.Second}
+
+// DoWithRetry sends the request built by newRequest, waiting on limiter
+// (which may be nil) before each attempt and retrying per policy. Every
+// attempt is recorded in the connector's stats. newRequest is called once
+// per attempt so request bodies can be re-read.
+func (b *BaseConnector) DoWithRetry(ctx context.Context, client *http.Client, limiter *RateLimiter, policy RetryPolicy, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		b.RecordResponse(req, resp, err, time.Since(start))
+
+		retryable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= policy.MaxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay := backoff << attempt
+		if resp != nil {
+			if secs, perr := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); perr == nil && secs > 0 {
+				delay = time.Duration(secs * float64(time.Second))
+			}
+			resp.Body.Close()
+		}
+		b.Logger().Warn("retrying request", "url", req.URL.Redacted(), "attempt", attempt+1, "delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
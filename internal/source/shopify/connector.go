@@ -7,10 +7,11 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/badno/badops/internal/credentials"
 	"github.com/badno/badops/internal/source"
 	"github.com/badno/badops/pkg/models"
 )
@@ -18,21 +19,29 @@ import (
 const (
 	ConnectorName = "shopify"
 	apiVersion    = "2024-01"
+
+	// pageSize is the maximum products per page allowed by Shopify
+	pageSize = 250
+	// requestInterval keeps requests within Shopify's 2 req/s leaky bucket
+	requestInterval = 500 * time.Millisecond
 )
 
 // Config holds Shopify connection configuration
 type Config struct {
-	Store      string // Store name (e.g., "badno" for badno.myshopify.com)
-	APIKey     string // API access token
-	APIKeyEnv  string // Environment variable name for API key
+	Store       string               // Store name (e.g., "badno" for badno.myshopify.com)
+	APIKey      string               // API access token
+	APIKeyEnv   string               // Credential reference for the API key (e.g., SHOPIFY_API_KEY, file:/path)
+	Credentials credentials.Provider // Resolves APIKeyEnv (default: credentials.Default)
 }
 
 // Connector implements the source.Connector interface for Shopify
 type Connector struct {
 	*source.BaseConnector
-	config  Config
-	client  *http.Client
-	baseURL string
+	config      Config
+	client      *http.Client
+	baseURL     string
+	lastRequest time.Time
+	rateLimitMu sync.Mutex
 }
 
 // NewConnector creates a new Shopify connector
@@ -59,10 +68,14 @@ type Capability = source.Capability
 
 // Connect establishes connection to Shopify API
 func (c *Connector) Connect(ctx context.Context) error {
-	// Resolve API key from environment if needed
+	// Resolve API key from its credential reference if needed
 	apiKey := c.config.APIKey
-	if apiKey == "" && c.config.APIKeyEnv != "" {
-		apiKey = os.Getenv(c.config.APIKeyEnv)
+	if apiKey == "" {
+		key, err := credentials.Resolve(c.config.Credentials, c.config.APIKeyEnv)
+		if err != nil {
+			return fmt.Errorf("failed to resolve shopify API key: %w", err)
+		}
+		apiKey = key
 	}
 	if apiKey == "" {
 		return fmt.Errorf("shopify API key not configured")
@@ -96,7 +109,9 @@ func (c *Connector) Test(ctx context.Context) error {
 	req.Header.Set("X-Shopify-Access-Token", c.config.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	c.RecordResponse(req, resp, err, time.Since(start))
 	if err != nil {
 		return fmt.Errorf("failed to connect to Shopify: %w", err)
 	}
@@ -111,68 +126,142 @@ func (c *Connector) Test(ctx context.Context) error {
 	return nil
 }
 
-// FetchProducts retrieves products from Shopify
+// FetchProducts retrieves products from Shopify, following pagination until
+// opts.Limit products are loaded or all pages are read
 func (c *Connector) FetchProducts(ctx context.Context, opts source.FetchOptions) (*source.FetchResult, error) {
+	products := make([]models.EnhancedProduct, 0)
+	hasMore, nextCursor, err := c.walkProducts(ctx, opts, func(p models.EnhancedProduct) error {
+		products = append(products, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &source.FetchResult{
+		Products:   products,
+		TotalCount: len(products),
+		HasMore:    hasMore,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// FetchProductsStream walks all product pages and invokes fn for each product,
+// so callers can persist incrementally instead of holding the whole catalog in
+// memory. An error returned by fn aborts the walk and is returned.
+func (c *Connector) FetchProductsStream(ctx context.Context, opts source.FetchOptions, fn func(models.EnhancedProduct) error) error {
+	_, _, err := c.walkProducts(ctx, opts, fn)
+	return err
+}
+
+// walkProducts pages through /products.json using the Link header cursor.
+// It returns whether more products remain and the cursor to continue from.
+func (c *Connector) walkProducts(ctx context.Context, opts source.FetchOptions, fn func(models.EnhancedProduct) error) (bool, string, error) {
 	if !c.IsConnected() {
 		if err := c.Connect(ctx); err != nil {
-			return nil, err
+			return false, "", err
 		}
 	}
 
-	// Build query parameters
+	// Build query parameters for the first page
 	params := url.Values{}
 	if opts.Limit > 0 {
-		params.Set("limit", fmt.Sprintf("%d", min(opts.Limit, 250))) // Shopify max is 250
+		params.Set("limit", fmt.Sprintf("%d", min(opts.Limit, pageSize)))
 	} else {
-		params.Set("limit", "250")
+		params.Set("limit", fmt.Sprintf("%d", pageSize))
 	}
 	if opts.Vendor != "" {
 		params.Set("vendor", opts.Vendor)
 	}
+	if !opts.UpdatedSince.IsZero() {
+		params.Set("updated_at_min", opts.UpdatedSince.Format(time.RFC3339))
+	}
+
+	fetched := 0
+	for {
+		page, nextCursor, err := c.fetchProductPage(ctx, params)
+		if err != nil {
+			return false, "", err
+		}
+
+		for _, sp := range page {
+			if opts.Limit > 0 && fetched >= opts.Limit {
+				return true, "", nil
+			}
+			if err := fn(convertShopifyProduct(sp)); err != nil {
+				return false, "", err
+			}
+			fetched++
+		}
+
+		if nextCursor == "" {
+			return false, "", nil
+		}
+		if opts.Limit > 0 && fetched >= opts.Limit {
+			return true, nextCursor, nil
+		}
+
+		// Subsequent pages only accept limit and page_info
+		remaining := pageSize
+		if opts.Limit > 0 {
+			remaining = min(opts.Limit-fetched, pageSize)
+		}
+		params = url.Values{}
+		params.Set("limit", fmt.Sprintf("%d", remaining))
+		params.Set("page_info", nextCursor)
+	}
+}
+
+// fetchProductPage fetches a single page of products and returns the next cursor
+func (c *Connector) fetchProductPage(ctx context.Context, params url.Values) ([]shopifyProduct, string, error) {
+	if err := c.rateLimitWait(ctx); err != nil {
+		return nil, "", err
+	}
 
-	// Fetch products
 	endpoint := c.baseURL + "/products.json?" + params.Encode()
 	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	req.Header.Set("X-Shopify-Access-Token", c.config.APIKey)
 	req.Header.Set("Content-Type", "application/json")
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	c.RecordResponse(req, resp, err, time.Since(start))
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch products: %w", err)
+		return nil, "", fmt.Errorf("failed to fetch products: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("shopify API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, "", fmt.Errorf("shopify API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var shopifyResp shopifyProductsResponse
 	if err := json.NewDecoder(resp.Body).Decode(&shopifyResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, "", fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Check for pagination
-	linkHeader := resp.Header.Get("Link")
-	hasMore := strings.Contains(linkHeader, `rel="next"`)
-	nextCursor := extractNextCursor(linkHeader)
+	return shopifyResp.Products, extractNextCursor(resp.Header.Get("Link")), nil
+}
 
-	// Convert to EnhancedProduct
-	products := make([]models.EnhancedProduct, 0, len(shopifyResp.Products))
-	for _, sp := range shopifyResp.Products {
-		products = append(products, convertShopifyProduct(sp))
-	}
+// rateLimitWait waits if needed to stay within Shopify's 2 req/s leaky bucket
+func (c *Connector) rateLimitWait(ctx context.Context) error {
+	c.rateLimitMu.Lock()
+	defer c.rateLimitMu.Unlock()
 
-	return &source.FetchResult{
-		Products:   products,
-		TotalCount: len(products),
-		HasMore:    hasMore,
-		NextCursor: nextCursor,
-	}, nil
+	if wait := requestInterval - time.Since(c.lastRequest); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	c.lastRequest = time.Now()
+	return nil
 }
 
 // EnhanceProduct is not supported for Shopify source connector
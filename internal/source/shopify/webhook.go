@@ -0,0 +1,52 @@
+package shopify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// Webhook request headers
+const (
+	HeaderHMAC  = "X-Shopify-Hmac-Sha256" // Base64 HMAC-SHA256 of the raw body
+	HeaderTopic = "X-Shopify-Topic"       // e.g. products/update
+)
+
+// Product webhook topics
+const (
+	TopicProductsCreate = "products/create"
+	TopicProductsUpdate = "products/update"
+)
+
+// VerifyWebhook reports whether signature, the X-Shopify-Hmac-Sha256 header,
+// is the HMAC-SHA256 of body under the app's webhook secret. The comparison
+// is constant-time; an empty secret or signature never verifies.
+func VerifyWebhook(body []byte, signature, secret string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// ParseProductWebhook converts a products/create or products/update payload
+// the same way an import converts a product. ID is the Shopify product ID.
+func ParseProductWebhook(body []byte) (models.EnhancedProduct, error) {
+	var sp shopifyProduct
+	if err := json.Unmarshal(body, &sp); err != nil {
+		return models.EnhancedProduct{}, fmt.Errorf("invalid product payload: %w", err)
+	}
+	if sp.ID == 0 {
+		return models.EnhancedProduct{}, fmt.Errorf("invalid product payload: no product id")
+	}
+	return convertShopifyProduct(sp), nil
+}
@@ -0,0 +1,41 @@
+package shopify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+)
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"id":1,"title":"Tiger Boston"}`)
+	const secret = "shpss_test"
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		secret    string
+		want      bool
+	}{
+		{"valid signature", body, sign(body, secret), secret, true},
+		{"tampered body", []byte(`{"id":1,"title":"Tiger Bostn"}`), sign(body, secret), secret, false},
+		{"wrong secret", body, sign(body, "other"), secret, false},
+		{"non-base64 header", body, "not base64!", secret, false},
+		{"empty signature", body, "", secret, false},
+		{"empty secret", body, sign(body, ""), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhook(tt.body, tt.signature, tt.secret); got != tt.want {
+				t.Errorf("VerifyWebhook() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package source
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/badno/badops/internal/metrics"
+)
+
+// Error kinds counted in ConnectorStats.Errors
+const (
+	ErrorNetwork     = "network"      // Request failed without a response
+	ErrorRateLimited = "rate_limited" // HTTP 429
+	ErrorClient      = "http_4xx"     // Other HTTP 4xx except 404
+	ErrorServer      = "http_5xx"     // HTTP 5xx
+)
+
+// ConnectorStats is a snapshot of a connector's activity in this process
+type ConnectorStats struct {
+	Requests     int64
+	Bytes        int64 // Response body bytes read
+	CacheHits    int64
+	CacheMisses  int64
+	CacheEntries int              // Entries in the connector's persistent cache, if it has one
+	Errors       map[string]int64 // By kind, e.g. ErrorNetwork
+	LastRequest  time.Time
+}
+
+// ErrorCount returns the total number of errors of all kinds
+func (s ConnectorStats) ErrorCount() int64 {
+	var n int64
+	for _, count := range s.Errors {
+		n += count
+	}
+	return n
+}
+
+// CacheHitRate returns the fraction of cache lookups that were hits, or -1
+// when there were no lookups
+func (s ConnectorStats) CacheHitRate() float64 {
+	lookups := s.CacheHits + s.CacheMisses
+	if lookups == 0 {
+		return -1
+	}
+	return float64(s.CacheHits) / float64(lookups)
+}
+
+// CacheSummary describes the entries in a connector's persistent cache
+type CacheSummary struct {
+	Entries  int
+	NotFound int             // Entries caching a "not found" result
+	Expired  int             // Entries past their TTL, ignored until refreshed or cleared
+	Ages     []time.Duration // Age of every entry
+}
+
+// Add counts an entry cached at cachedAt that expires after ttl
+func (c *CacheSummary) Add(cachedAt time.Time, notFound bool, ttl time.Duration) {
+	age := time.Since(cachedAt)
+	c.Entries++
+	c.Ages = append(c.Ages, age)
+	if notFound {
+		c.NotFound++
+	}
+	if age >= ttl {
+		c.Expired++
+	}
+}
+
+// statsCounter collects ConnectorStats for a BaseConnector
+type statsCounter struct {
+	mu    sync.Mutex
+	stats ConnectorStats
+}
+
+// Stats returns a snapshot of the connector's activity
+func (b *BaseConnector) Stats() ConnectorStats {
+	b.counter.mu.Lock()
+	defer b.counter.mu.Unlock()
+
+	stats := b.counter.stats
+	stats.Errors = make(map[string]int64, len(b.counter.stats.Errors))
+	for kind, n := range b.counter.stats.Errors {
+		stats.Errors[kind] = n
+	}
+	return stats
+}
+
+// RecordResponse counts an HTTP request, classifies its error, if any, and
+// logs it. Call it with the request and the result of http.Client.Do; the
+// response body is wrapped so the bytes read from it are counted too. 404s
+// are not counted as errors because connectors use them to mean "not found".
+func (b *BaseConnector) RecordResponse(req *http.Request, resp *http.Response, err error, elapsed time.Duration) {
+	kind := ""
+	switch {
+	case err != nil || resp == nil:
+		kind = ErrorNetwork
+	case resp.StatusCode == http.StatusTooManyRequests:
+		kind = ErrorRateLimited
+	case resp.StatusCode >= 500:
+		kind = ErrorServer
+	case resp.StatusCode >= 400 && resp.StatusCode != http.StatusNotFound:
+		kind = ErrorClient
+	}
+
+	b.counter.mu.Lock()
+	b.counter.stats.Requests++
+	b.counter.stats.LastRequest = time.Now()
+	if kind != "" {
+		b.recordErrorLocked(kind)
+	}
+	b.counter.mu.Unlock()
+
+	code := 0
+	if resp != nil {
+		code = resp.StatusCode
+	}
+	metrics.Request(b.name, code, elapsed)
+
+	attrs := []any{"method", req.Method, "url", req.URL.Redacted(), "duration", elapsed}
+	if err != nil || resp == nil {
+		b.Logger().Warn("request failed", append(attrs, "error", err)...)
+		return
+	}
+	attrs = append(attrs, "status", resp.StatusCode)
+	if kind != "" {
+		b.Logger().Warn("request error", append(attrs, "error_kind", kind)...)
+	} else {
+		b.Logger().Debug("request", attrs...)
+	}
+
+	resp.Body = &countingBody{ReadCloser: resp.Body, counter: &b.counter}
+}
+
+// RecordError counts an error of the given kind
+func (b *BaseConnector) RecordError(kind string) {
+	b.counter.mu.Lock()
+	defer b.counter.mu.Unlock()
+	b.recordErrorLocked(kind)
+}
+
+func (b *BaseConnector) recordErrorLocked(kind string) {
+	if b.counter.stats.Errors == nil {
+		b.counter.stats.Errors = make(map[string]int64)
+	}
+	b.counter.stats.Errors[kind]++
+}
+
+// RecordCacheHit counts a lookup answered from the connector's cache
+func (b *BaseConnector) RecordCacheHit() {
+	b.counter.mu.Lock()
+	defer b.counter.mu.Unlock()
+	b.counter.stats.CacheHits++
+}
+
+// RecordCacheMiss counts a lookup the connector's cache could not answer
+func (b *BaseConnector) RecordCacheMiss() {
+	b.counter.mu.Lock()
+	defer b.counter.mu.Unlock()
+	b.counter.stats.CacheMisses++
+}
+
+// countingBody adds the bytes read from a response body to the stats
+type countingBody struct {
+	io.ReadCloser
+	counter *statsCounter
+}
+
+func (c *countingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 {
+		c.counter.mu.Lock()
+		c.counter.stats.Bytes += int64(n)
+		c.counter.mu.Unlock()
+	}
+	return n, err
+}
@@ -3,9 +3,12 @@ package tiger
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/badno/badops/internal/matcher"
+	"github.com/badno/badops/internal/skurules"
 	"github.com/badno/badops/internal/source"
 	"github.com/badno/badops/pkg/models"
 )
@@ -14,7 +17,16 @@ const ConnectorName = "tiger_nl"
 
 // Config holds Tiger.nl connection configuration
 type Config struct {
-	RateLimitMs int // Milliseconds between requests (default: 150)
+	RateLimitMs      int                // Milliseconds between requests (default: 150)
+	MappingsFile     string             // Category/series mappings YAML (default: ~/.badops/tiger-mappings.yaml)
+	SKURulesFile     string             // SKU → candidate ID rules YAML (default: ~/.badops/sku-rules.yaml)
+	CacheTTL         time.Duration      // How long lookups stay cached (default: 24h)
+	NegativeCacheTTL time.Duration      // How long "not found" results stay cached (default: CacheTTL)
+	UserAgent        string             // User-Agent header (default: matcher.DefaultUserAgent)
+	AcceptLanguage   string             // Accept-Language header (default: matcher.DefaultAcceptLanguage)
+	Cache            matcher.Cache      // Lookup cache (default: the file cache in output/)
+	ForceRefresh     bool               // Ignore lookups cached before Connect; fresh results are re-cached
+	Merge            source.MergePolicy // When Tiger.nl values may replace existing ones
 }
 
 // Connector implements the source.Connector interface for Tiger.nl
@@ -47,11 +59,48 @@ type Capability = source.Capability
 
 // Connect initializes the Tiger.nl matcher
 func (c *Connector) Connect(ctx context.Context) error {
-	c.matcher = matcher.NewTigerMatcher()
+	m, err := c.newMatcher()
+	if err != nil {
+		return err
+	}
+	c.matcher = m
 	c.SetConnected(true)
 	return nil
 }
 
+// newMatcher creates a matcher, applying the configured cache, rate limit,
+// request headers, mappings file and SKU rules file
+func (c *Connector) newMatcher() (*matcher.TigerMatcher, error) {
+	m := matcher.NewTigerMatcher()
+	if c.config.Cache != nil {
+		m.GetScraper().SetCache(c.config.Cache)
+	}
+	m.GetScraper().SetForceRefresh(c.config.ForceRefresh)
+	m.GetScraper().SetRateLimit(time.Duration(c.config.RateLimitMs) * time.Millisecond)
+	m.GetScraper().SetHeaders(c.config.UserAgent, c.config.AcceptLanguage)
+	m.GetScraper().SetObserver(c.BaseConnector)
+	negativeTTL := c.config.NegativeCacheTTL
+	if negativeTTL <= 0 {
+		negativeTTL = c.config.CacheTTL
+	}
+	m.GetScraper().SetCacheTTL(c.config.CacheTTL, negativeTTL)
+	if c.config.MappingsFile != "" {
+		if err := m.GetScraper().LoadMappingsFile(c.config.MappingsFile); err != nil {
+			return nil, fmt.Errorf("failed to load Tiger.nl mappings: %w", err)
+		}
+	} else if err := m.GetScraper().MappingsError(); err != nil {
+		return nil, fmt.Errorf("failed to load Tiger.nl mappings: %w", err)
+	}
+	if c.config.SKURulesFile != "" {
+		rules, err := skurules.Load(c.config.SKURulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SKU rules: %w", err)
+		}
+		m.GetSKUMapper().SetRules(rules)
+	}
+	return m, nil
+}
+
 // Close cleans up resources
 func (c *Connector) Close() error {
 	c.SetConnected(false)
@@ -61,7 +110,11 @@ func (c *Connector) Close() error {
 // Test verifies connectivity to Tiger.nl
 func (c *Connector) Test(ctx context.Context) error {
 	if c.matcher == nil {
-		c.matcher = matcher.NewTigerMatcher()
+		m, err := c.newMatcher()
+		if err != nil {
+			return err
+		}
+		c.matcher = m
 	}
 
 	// Test by making a simple lookup
@@ -74,12 +127,55 @@ func (c *Connector) Test(ctx context.Context) error {
 	return nil
 }
 
+// Stats returns the connector's counters along with the scraper's cache size
+func (c *Connector) Stats() source.ConnectorStats {
+	stats := c.BaseConnector.Stats()
+	if c.matcher != nil {
+		stats.CacheEntries = c.matcher.GetScraper().CacheSize()
+	}
+	return stats
+}
+
+// CacheSummary describes the scraper's cache entries, including expired ones.
+// The connector must be connected.
+func (c *Connector) CacheSummary() source.CacheSummary {
+	var summary source.CacheSummary
+	if c.matcher == nil {
+		return summary
+	}
+	scraper := c.matcher.GetScraper()
+	for _, e := range scraper.CacheEntries() {
+		summary.Add(e.CachedAt, e.NotFound, scraper.CacheTTL(e.NotFound))
+	}
+	return summary
+}
+
+// ClearCache removes every scraper cache entry and returns how many there were
+func (c *Connector) ClearCache() int {
+	if c.matcher == nil {
+		return 0
+	}
+	return c.matcher.GetScraper().ClearCache()
+}
+
+// InvalidateCache removes the scraper cache entries with the given keys: SKUs,
+// or barcode keys from matcher.BarcodeCacheKey
+func (c *Connector) InvalidateCache(keys ...string) int {
+	if c.matcher == nil {
+		return 0
+	}
+	return c.matcher.GetScraper().InvalidateCache(keys...)
+}
+
 // FetchProducts is not supported for Tiger.nl (it's an enhancement source)
 func (c *Connector) FetchProducts(ctx context.Context, opts source.FetchOptions) (*source.FetchResult, error) {
 	return nil, fmt.Errorf("tiger_nl connector is an enhancement source, use EnhanceProduct instead")
 }
 
-// EnhanceProduct enriches a product with Tiger.nl images
+// EnhanceProduct enriches a product with Tiger.nl images. The product is
+// looked up by SKU (or its manual override URL), and only image URLs that
+// responded when the page was scraped are added, as pending images with
+// source tiger_nl.
 func (c *Connector) EnhanceProduct(ctx context.Context, product *models.EnhancedProduct) (*source.EnhancementResult, error) {
 	if !c.IsConnected() {
 		if err := c.Connect(ctx); err != nil {
@@ -93,7 +189,7 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 	}
 
 	// Look up the product on Tiger.nl
-	tigerProduct, err := c.matcher.LookupBySKU(product.SKU, product.Title)
+	tigerProduct, err := c.matcher.LookupBySKU(ctx, product.SKU, product.Title)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to lookup product: %w", err)
 		return result, nil
@@ -104,45 +200,20 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 		return result, nil
 	}
 
-	// Count existing images from Tiger.nl
-	existingTigerImages := 0
-	for _, img := range product.Images {
-		if img.Source == "tiger_nl" {
-			existingTigerImages++
-		}
-	}
-
 	// Add new images
-	newImagesAdded := 0
+	newImages := newImageURLs(product, tigerProduct)
 	existingCount := len(product.Images)
-
-	for i, imgURL := range tigerProduct.ImageURLs {
-		// Skip if we already have this many images from the source
-		if i < existingTigerImages {
-			continue
-		}
-
-		// Check if this URL is already in the product
-		alreadyExists := false
-		for _, existing := range product.Images {
-			if existing.SourceURL == imgURL {
-				alreadyExists = true
-				break
-			}
-		}
-		if alreadyExists {
-			continue
-		}
-
-		// Add the new image
-		product.Images = append(product.Images, models.ProductImage{
+	images := make([]models.ProductImage, 0, len(newImages))
+	for i, imgURL := range newImages {
+		images = append(images, models.ProductImage{
 			SourceURL: imgURL,
-			Position:  existingCount + newImagesAdded + 1,
+			Position:  existingCount + i + 1,
 			Status:    "pending",
 			Source:    "tiger_nl",
 		})
-		newImagesAdded++
 	}
+	merger := c.config.Merge.NewMerger(ConnectorName, product)
+	newImagesAdded := merger.AddImages(images)
 
 	// Update legacy fields for backward compatibility
 	product.LegacyMatchedURL = tigerProduct.URL
@@ -153,7 +224,7 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 		product.Enhancements = append(product.Enhancements, models.Enhancement{
 			Source:      "tiger_nl",
 			Action:      "images_added",
-			Details:     fmt.Sprintf("Added %d new images from Tiger.nl (%s)", newImagesAdded, tigerProduct.URL),
+			Details:     fmt.Sprintf("Added %d new images from Tiger.nl (%s)", newImagesAdded, tigerProduct.URL) + merger.OverrideDetails(),
 			FieldsAdded: []string{"images"},
 			Timestamp:   time.Now(),
 			Success:     true,
@@ -170,13 +241,85 @@ func (c *Connector) EnhanceProduct(ctx context.Context, product *models.Enhanced
 
 	product.UpdatedAt = time.Now()
 
-	result.FieldsUpdated = []string{"images"}
+	if newImagesAdded > 0 {
+		result.FieldsUpdated = []string{"images"}
+	}
 	result.ImagesAdded = newImagesAdded
 	result.Success = true
 
 	return result, nil
 }
 
+// PreviewEnhancement reports how many Tiger.nl images would be added without
+// modifying the product
+func (c *Connector) PreviewEnhancement(ctx context.Context, product *models.EnhancedProduct) (*source.EnhancementResult, error) {
+	if !c.IsConnected() {
+		if err := c.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &source.EnhancementResult{
+		Product: product,
+		Success: false,
+		DryRun:  true,
+	}
+
+	tigerProduct, err := c.matcher.LookupBySKU(ctx, product.SKU, product.Title)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to lookup product: %w", err)
+		return result, nil
+	}
+
+	if tigerProduct == nil {
+		result.Error = fmt.Errorf("product not found on Tiger.nl")
+		return result, nil
+	}
+
+	result.ImagesAdded = len(newImageURLs(product, tigerProduct))
+	if result.ImagesAdded > 0 {
+		result.FieldsUpdated = []string{"images"}
+	}
+	result.Success = true
+
+	return result, nil
+}
+
+// newImageURLs returns the Tiger.nl image URLs not yet present on the
+// product, whatever source added them and including images since uploaded
+// to a CDN. URLs are compared without their query
+// string, so the same image requested at another size is not added twice.
+func newImageURLs(product *models.EnhancedProduct, tigerProduct *matcher.TigerProduct) []string {
+	seen := make(map[string]bool, len(product.Images)+len(tigerProduct.ImageURLs))
+	for _, img := range product.Images {
+		seen[imageKey(img.SourceURL)] = true
+		if img.OriginalURL != "" {
+			seen[imageKey(img.OriginalURL)] = true
+		}
+	}
+
+	var urls []string
+	for _, imgURL := range tigerProduct.ImageURLs {
+		key := imageKey(imgURL)
+		if imgURL == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		urls = append(urls, imgURL)
+	}
+
+	return urls
+}
+
+// imageKey identifies an image URL by host and path
+func imageKey(imgURL string) string {
+	u, err := url.Parse(imgURL)
+	if err != nil || u.Host == "" {
+		return imgURL
+	}
+	return strings.ToLower(u.Host) + u.Path
+}
+
 // GetMatcher returns the underlying Tiger.nl matcher for direct access
 func (c *Connector) GetMatcher() *matcher.TigerMatcher {
 	return c.matcher
@@ -0,0 +1,322 @@
+// Package woocommerce imports products from a WooCommerce store through the
+// WooCommerce REST API (v3)
+package woocommerce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/internal/credentials"
+	"github.com/badno/badops/internal/source"
+	"github.com/badno/badops/pkg/models"
+)
+
+const (
+	ConnectorName = "woocommerce"
+
+	// pageSize is the maximum products per page allowed by the REST API
+	pageSize = 100
+	// requestInterval keeps requests polite on shared WordPress hosting
+	requestInterval = 250 * time.Millisecond
+)
+
+// Config holds WooCommerce connection configuration
+type Config struct {
+	URL               string               // Store URL (e.g., https://shop.example.no)
+	ConsumerKeyEnv    string               // Credential reference for the consumer key (ck_...)
+	ConsumerSecretEnv string               // Credential reference for the consumer secret (cs_...)
+	Currency          string               // Store currency (default: NOK)
+	Credentials       credentials.Provider // Resolves the references (default: credentials.Default)
+}
+
+// Connector implements the source.Connector interface for WooCommerce
+type Connector struct {
+	*source.BaseConnector
+	config  Config
+	client  *http.Client
+	baseURL string
+	key     string
+	secret  string
+	limiter *source.RateLimiter
+}
+
+// NewConnector creates a new WooCommerce connector
+func NewConnector(cfg Config) *Connector {
+	return &Connector{
+		BaseConnector: source.NewBaseConnector(
+			ConnectorName,
+			source.TypeSource,
+			[]source.Capability{
+				source.CapabilityFetchProducts,
+				source.CapabilityFetchImages,
+			},
+		),
+		config: cfg,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: source.NewRateLimiter(requestInterval),
+	}
+}
+
+// Connect resolves the API credentials and verifies them
+func (c *Connector) Connect(ctx context.Context) error {
+	if c.config.URL == "" {
+		return fmt.Errorf("woocommerce store URL not configured")
+	}
+	key, err := credentials.Resolve(c.config.Credentials, c.config.ConsumerKeyEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve woocommerce consumer key: %w", err)
+	}
+	secret, err := credentials.Resolve(c.config.Credentials, c.config.ConsumerSecretEnv)
+	if err != nil {
+		return fmt.Errorf("failed to resolve woocommerce consumer secret: %w", err)
+	}
+	if key == "" || secret == "" {
+		return fmt.Errorf("woocommerce consumer key and secret not configured")
+	}
+	c.key, c.secret = key, secret
+	c.baseURL = strings.TrimRight(c.config.URL, "/") + "/wp-json/wc/v3"
+
+	return c.Test(ctx)
+}
+
+// Close cleans up resources
+func (c *Connector) Close() error {
+	c.SetConnected(false)
+	return nil
+}
+
+// Test verifies connectivity by reading one product
+func (c *Connector) Test(ctx context.Context) error {
+	if _, _, err := c.fetchProductPage(ctx, url.Values{"per_page": {"1"}}); err != nil {
+		return fmt.Errorf("failed to connect to WooCommerce: %w", err)
+	}
+	c.SetConnected(true)
+	return nil
+}
+
+// FetchProducts retrieves published products, following page numbers until
+// opts.Limit products are loaded or all pages are read. The REST API has no
+// brand filter, so opts.Vendor is matched against each product's brand.
+func (c *Connector) FetchProducts(ctx context.Context, opts source.FetchOptions) (*source.FetchResult, error) {
+	if !c.IsConnected() {
+		if err := c.Connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	params := url.Values{}
+	params.Set("per_page", strconv.Itoa(pageSize))
+	params.Set("status", "publish")
+	params.Set("orderby", "id")
+	params.Set("order", "asc")
+	if len(opts.SKUs) > 0 {
+		params.Set("sku", strings.Join(opts.SKUs, ","))
+	}
+	if !opts.UpdatedSince.IsZero() {
+		params.Set("modified_after", opts.UpdatedSince.UTC().Format("2006-01-02T15:04:05"))
+		params.Set("dates_are_gmt", "true")
+	}
+
+	products := make([]models.EnhancedProduct, 0)
+	for page := 1; ; page++ {
+		params.Set("page", strconv.Itoa(page))
+		items, totalPages, err := c.fetchProductPage(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, wp := range items {
+			p := c.convertProduct(wp)
+			if opts.Vendor != "" && !strings.EqualFold(p.Vendor, opts.Vendor) {
+				continue
+			}
+			if p.SKU == "" {
+				continue // State is keyed by SKU
+			}
+			if opts.Limit > 0 && len(products) >= opts.Limit {
+				return &source.FetchResult{Products: products, TotalCount: len(products), HasMore: true}, nil
+			}
+			products = append(products, p)
+		}
+
+		if len(items) == 0 || page >= totalPages {
+			break
+		}
+	}
+
+	return &source.FetchResult{
+		Products:   products,
+		TotalCount: len(products),
+	}, nil
+}
+
+// fetchProductPage fetches one page of products and returns the total page
+// count from the X-WP-TotalPages header
+func (c *Connector) fetchProductPage(ctx context.Context, params url.Values) ([]wooProduct, int, error) {
+	resp, err := c.DoWithRetry(ctx, c.client, c.limiter, source.DefaultRetryPolicy, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/products?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.SetBasicAuth(c.key, c.secret)
+		req.Header.Set("Accept", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, 0, fmt.Errorf("woocommerce API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var products []wooProduct
+	if err := json.NewDecoder(resp.Body).Decode(&products); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	totalPages, _ := strconv.Atoi(resp.Header.Get("X-WP-TotalPages"))
+	return products, totalPages, nil
+}
+
+// EnhanceProduct is not supported for the WooCommerce source connector
+func (c *Connector) EnhanceProduct(ctx context.Context, product *models.EnhancedProduct) (*source.EnhancementResult, error) {
+	return nil, fmt.Errorf("woocommerce connector does not support product enhancement")
+}
+
+// WooCommerce API response types
+type wooProduct struct {
+	ID               int64          `json:"id"`
+	Name             string         `json:"name"`
+	Slug             string         `json:"slug"`
+	SKU              string         `json:"sku"`
+	Description      string         `json:"description"`
+	ShortDescription string         `json:"short_description"`
+	Price            string         `json:"price"`
+	RegularPrice     string         `json:"regular_price"`
+	DateCreatedGMT   string         `json:"date_created_gmt"`
+	DateModifiedGMT  string         `json:"date_modified_gmt"`
+	Categories       []wooTerm      `json:"categories"`
+	Tags             []wooTerm      `json:"tags"`
+	Brands           []wooTerm      `json:"brands"` // WooCommerce 9.6+ and the Brands extension
+	Images           []wooImage     `json:"images"`
+	Attributes       []wooAttribute `json:"attributes"`
+	MetaData         []wooMeta      `json:"meta_data"`
+}
+
+type wooTerm struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type wooImage struct {
+	ID   int64  `json:"id"`
+	Src  string `json:"src"`
+	Alt  string `json:"alt"`
+	Name string `json:"name"`
+}
+
+type wooAttribute struct {
+	Name    string   `json:"name"`
+	Slug    string   `json:"slug"`
+	Options []string `json:"options"`
+}
+
+type wooMeta struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// convertProduct converts a WooCommerce product to EnhancedProduct. The
+// first category becomes the product type and the brand becomes the vendor.
+func (c *Connector) convertProduct(wp wooProduct) models.EnhancedProduct {
+	ep := models.EnhancedProduct{
+		ID:             strconv.FormatInt(wp.ID, 10),
+		SKU:            strings.TrimSpace(wp.SKU),
+		Handle:         wp.Slug,
+		Title:          wp.Name,
+		Description:    wp.Description,
+		Vendor:         productBrand(wp),
+		Status:         models.StatusPending,
+		Specifications: make(map[string]string),
+	}
+	if ep.Description == "" {
+		ep.Description = wp.ShortDescription
+	}
+	if len(wp.Categories) > 0 {
+		ep.ProductType = wp.Categories[0].Name
+	}
+	for _, tag := range wp.Tags {
+		ep.Tags = append(ep.Tags, tag.Name)
+	}
+	for _, meta := range wp.MetaData {
+		// GTIN plugins store the barcode in product meta
+		if meta.Key == "_global_unique_id" || meta.Key == "_wpm_gtin_code" || meta.Key == "_alg_ean" {
+			var barcode string
+			if json.Unmarshal(meta.Value, &barcode) == nil && barcode != "" {
+				ep.Barcode = barcode
+				break
+			}
+		}
+	}
+
+	// price is the active price; regular_price is the compare-at price while on sale
+	price, _ := strconv.ParseFloat(wp.Price, 64)
+	if price > 0 {
+		currency := c.config.Currency
+		if currency == "" {
+			currency = "NOK"
+		}
+		ep.Price = &models.Price{Amount: price, Currency: currency}
+		if regular, _ := strconv.ParseFloat(wp.RegularPrice, 64); regular > price {
+			ep.Price.CompareAt = regular
+		}
+	}
+
+	for i, img := range wp.Images {
+		ep.Images = append(ep.Images, models.ProductImage{
+			ID:        strconv.FormatInt(img.ID, 10),
+			SourceURL: img.Src,
+			Position:  i + 1,
+			Alt:       img.Alt,
+			Status:    "existing",
+			Source:    ConnectorName,
+		})
+	}
+
+	if t, err := time.Parse("2006-01-02T15:04:05", wp.DateCreatedGMT); err == nil {
+		ep.CreatedAt = t
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05", wp.DateModifiedGMT); err == nil {
+		ep.UpdatedAt = t
+	}
+
+	return ep
+}
+
+// productBrand returns the product's brand from the brands taxonomy, else a
+// Brand attribute
+func productBrand(wp wooProduct) string {
+	if len(wp.Brands) > 0 {
+		return wp.Brands[0].Name
+	}
+	for _, attr := range wp.Attributes {
+		name := strings.ToLower(attr.Name)
+		if (name == "brand" || name == "merke" || attr.Slug == "pa_brand") && len(attr.Options) > 0 {
+			return attr.Options[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package state
+
+import (
+	"github.com/badno/badops/pkg/models"
+)
+
+// Backend is the product state used by the orchestrator. Store keeps it in
+// the JSON state file, PostgresStore in the database.
+type Backend interface {
+	Load() error
+	LoadForUpdate() error
+	Save() error
+	Close()
+
+	GetProduct(sku string) (*models.EnhancedProduct, bool)
+	SetProduct(product *models.EnhancedProduct)
+	GetAllProducts() []*models.EnhancedProduct
+	GetProductsBySKUs(skus []string) []*models.EnhancedProduct
+	GetProductsByStatus(status models.ProductStatus) []*models.EnhancedProduct
+	GetProductsByVendor(vendor string) []*models.EnhancedProduct
+	Count() int
+
+	ImportProducts(products []models.EnhancedProduct, source string) int
+	RecordImageDownload(sku string, img models.ProductImage) bool
+
+	AddHistory(action, source string, count int, details string)
+	GetHistory() []HistoryEntry
+	GetRecentHistory(n int) []HistoryEntry
+}
@@ -0,0 +1,203 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// DuplicateGroup is a set of products that are the same item under
+// different SKUs
+type DuplicateGroup struct {
+	Key        string   // Normalized SKU or barcode the products share
+	Keep       string   // SKU of the richest record, which the others merge into
+	Duplicates []string // SKUs merged into Keep and removed
+}
+
+// NormalizeSKU returns the form of a SKU used to detect duplicates: trimmed
+// and upper-cased
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+// normalizeBarcode strips everything but digits and leading zeros, so EAN-13
+// and GTIN-14 forms of the same code compare equal
+func normalizeBarcode(barcode string) string {
+	var b strings.Builder
+	for _, r := range barcode {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(b.String(), "0")
+}
+
+// FindDuplicates groups products whose normalized SKU or barcode match.
+// Groups are sorted by key, and the SKUs within a group by richness.
+func (s *Store) FindDuplicates() []DuplicateGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return findDuplicates(s.state.Products)
+}
+
+func findDuplicates(products map[string]*models.EnhancedProduct) []DuplicateGroup {
+	skus := make([]string, 0, len(products))
+	for sku := range products {
+		skus = append(skus, sku)
+	}
+	sort.Strings(skus)
+
+	// Union products that share a normalized SKU or barcode
+	parent := make(map[string]string, len(skus))
+	var find func(string) string
+	find = func(sku string) string {
+		if parent[sku] == sku {
+			return sku
+		}
+		parent[sku] = find(parent[sku])
+		return parent[sku]
+	}
+	firstByKey := make(map[string]string)
+	keyOf := make(map[string]string)
+	union := func(sku, key string) {
+		if first, ok := firstByKey[key]; ok {
+			parent[find(sku)] = find(first)
+			return
+		}
+		firstByKey[key] = sku
+	}
+	for _, sku := range skus {
+		parent[sku] = sku
+		key := NormalizeSKU(sku)
+		keyOf[sku] = key
+		union(sku, "sku:"+key)
+		if barcode := normalizeBarcode(products[sku].Barcode); barcode != "" {
+			union(sku, "barcode:"+barcode)
+		}
+	}
+
+	members := make(map[string][]string)
+	for _, sku := range skus {
+		root := find(sku)
+		members[root] = append(members[root], sku)
+	}
+
+	var groups []DuplicateGroup
+	for _, group := range members {
+		if len(group) < 2 {
+			continue
+		}
+		sort.SliceStable(group, func(i, j int) bool {
+			ri, rj := richness(products[group[i]]), richness(products[group[j]])
+			if ri != rj {
+				return ri > rj
+			}
+			// Prefer the SKU that is already clean
+			return group[i] == strings.TrimSpace(group[i]) && group[j] != strings.TrimSpace(group[j])
+		})
+
+		key := keyOf[group[0]]
+		for _, sku := range group[1:] {
+			if keyOf[sku] != key {
+				key = "barcode " + products[group[0]].Barcode
+				break
+			}
+		}
+		groups = append(groups, DuplicateGroup{Key: key, Keep: group[0], Duplicates: group[1:]})
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Key < groups[j].Key })
+	return groups
+}
+
+// richness scores how complete a product record is
+func richness(p *models.EnhancedProduct) int {
+	score := len(p.Images) + len(p.Properties) + len(p.Specifications) + len(p.Enhancements)
+	for _, field := range []string{p.Title, p.Description, p.Handle, p.Barcode, p.NOBBNumber, p.Vendor, p.LegacyMatchedURL} {
+		if field != "" {
+			score++
+		}
+	}
+	if p.Price != nil {
+		score++
+	}
+	return score
+}
+
+// MergeDuplicates merges each group's duplicates into its kept record with
+// mergeProducts and removes them. The kept record's fields win; images,
+// properties, specifications and enhancements are combined. The kept SKU is
+// trimmed of whitespace. Returns the number of products removed.
+func (s *Store) MergeDuplicates(groups []DuplicateGroup) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := 0
+	for _, g := range groups {
+		keep, ok := s.state.Products[g.Keep]
+		if !ok {
+			continue
+		}
+		for _, sku := range g.Duplicates {
+			dup, ok := s.state.Products[sku]
+			if !ok {
+				continue
+			}
+			keep = mergeDuplicate(keep, dup)
+			delete(s.state.Products, sku)
+			removed++
+		}
+
+		delete(s.state.Products, g.Keep)
+		keep.SKU = strings.TrimSpace(keep.SKU)
+		keep.UpdatedAt = time.Now()
+		s.state.Products[keep.SKU] = keep
+	}
+
+	s.state.History = append(s.state.History, HistoryEntry{
+		Timestamp: time.Now(),
+		Action:    "dedupe",
+		Source:    "state",
+		Count:     removed,
+		Details:   fmt.Sprintf("Merged %d duplicate products into %d", removed, len(groups)),
+	})
+
+	return removed
+}
+
+// mergeDuplicate merges dup into keep. mergeProducts prefers the new
+// record's fields, so keep's are restored where it has them.
+func mergeDuplicate(keep, dup *models.EnhancedProduct) *models.EnhancedProduct {
+	// mergeProducts updates keep's specifications map in place
+	specs := make(map[string]string, len(keep.Specifications))
+	for k, v := range keep.Specifications {
+		specs[k] = v
+	}
+
+	merged := mergeProducts(keep, dup)
+
+	restore := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+	restore(&merged.Title, keep.Title)
+	restore(&merged.Description, keep.Description)
+	restore(&merged.Handle, keep.Handle)
+	restore(&merged.Barcode, keep.Barcode)
+	restore(&merged.NOBBNumber, keep.NOBBNumber)
+	if keep.LegacyMatchedURL != "" {
+		merged.LegacyMatchedURL = keep.LegacyMatchedURL
+		merged.LegacyMatchScore = keep.LegacyMatchScore
+		merged.LegacyMatchMethod = keep.LegacyMatchMethod
+	}
+
+	// Specifications from the kept record win too
+	for k, v := range specs {
+		merged.Specifications[k] = v
+	}
+	return merged
+}
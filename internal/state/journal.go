@@ -0,0 +1,230 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// DefaultJournalRuns is how many enhance runs are kept for rollback
+const DefaultJournalRuns = 5
+
+// JournalEntry records the products an enhance run changed, with enough of
+// their previous state to roll the run back
+type JournalEntry struct {
+	ID        string            `json:"id"` // Run start time, e.g. 20260101-120000
+	Timestamp time.Time         `json:"timestamp"`
+	Sources   []string          `json:"sources"`
+	Products  []ProductSnapshot `json:"products"`
+}
+
+// ProductSnapshot holds the previous values of the fields a run changed on
+// one product. Fields maps JSON field names to their previous JSON value,
+// null when the field was unset. The run's enhancement records are
+// Enhancements[EnhancementsBefore : EnhancementsBefore+EnhancementsAdded].
+type ProductSnapshot struct {
+	SKU                string                     `json:"sku"`
+	Fields             map[string]json.RawMessage `json:"fields"`
+	EnhancementsBefore int                        `json:"enhancements_before"`
+	EnhancementsAdded  int                        `json:"enhancements_added"`
+}
+
+// NewJournalEntry starts a journal entry for a run beginning now
+func NewJournalEntry(sources []string) *JournalEntry {
+	now := time.Now()
+	return &JournalEntry{
+		ID:        now.Format("20060102-150405"),
+		Timestamp: now,
+		Sources:   sources,
+		Products:  []ProductSnapshot{},
+	}
+}
+
+// Snapshot compares a product before and after an enhancement and returns
+// what is needed to restore it, or nil if nothing changed
+func Snapshot(before, after *models.EnhancedProduct) (*ProductSnapshot, error) {
+	beforeFields, err := productFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := productFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &ProductSnapshot{
+		SKU:                before.SKU,
+		Fields:             make(map[string]json.RawMessage),
+		EnhancementsBefore: len(before.Enhancements),
+		EnhancementsAdded:  len(after.Enhancements) - len(before.Enhancements),
+	}
+
+	for name, value := range afterFields {
+		if name == "enhancements" {
+			continue
+		}
+		if prev, ok := beforeFields[name]; !ok {
+			snap.Fields[name] = json.RawMessage("null")
+		} else if string(prev) != string(value) {
+			snap.Fields[name] = prev
+		}
+	}
+	for name, prev := range beforeFields {
+		if _, ok := afterFields[name]; !ok && name != "enhancements" {
+			snap.Fields[name] = prev
+		}
+	}
+
+	if len(snap.Fields) == 0 && snap.EnhancementsAdded <= 0 {
+		return nil, nil
+	}
+	return snap, nil
+}
+
+// restore applies the snapshot to a product, putting back the previous field
+// values and removing the run's enhancement records
+func (snap *ProductSnapshot) restore(p *models.EnhancedProduct) error {
+	fields, err := productFields(p)
+	if err != nil {
+		return err
+	}
+	for name, prev := range snap.Fields {
+		if string(prev) == "null" {
+			delete(fields, name)
+		} else {
+			fields[name] = prev
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var restored models.EnhancedProduct
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", snap.SKU, err)
+	}
+
+	start := snap.EnhancementsBefore
+	end := start + snap.EnhancementsAdded
+	if snap.EnhancementsAdded > 0 && end <= len(restored.Enhancements) {
+		restored.Enhancements = append(restored.Enhancements[:start], restored.Enhancements[end:]...)
+	}
+
+	*p = restored
+	return nil
+}
+
+// productFields splits a product into its top-level JSON fields
+func productFields(p *models.EnhancedProduct) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// RecordJournal adds or updates the journal entry with the same ID and keeps
+// only the most recent keep entries
+func (s *Store) RecordJournal(entry JournalEntry, keep int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keep <= 0 {
+		keep = DefaultJournalRuns
+	}
+
+	replaced := false
+	for i := range s.state.Journal {
+		if s.state.Journal[i].ID == entry.ID {
+			s.state.Journal[i] = entry
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.state.Journal = append(s.state.Journal, entry)
+	}
+
+	if len(s.state.Journal) > keep {
+		s.state.Journal = s.state.Journal[len(s.state.Journal)-keep:]
+	}
+}
+
+// GetJournal returns the journaled runs, oldest first
+func (s *Store) GetJournal() []JournalEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]JournalEntry, len(s.state.Journal))
+	copy(result, s.state.Journal)
+	return result
+}
+
+// Rollback restores the products changed by a journaled run and removes the
+// run from the journal. An empty id rolls back the most recent run. Products
+// removed from the state since the run are skipped.
+func (s *Store) Rollback(id string) (*JournalEntry, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.state.Journal) == 0 {
+		return nil, 0, fmt.Errorf("no enhance runs to roll back")
+	}
+
+	idx := len(s.state.Journal) - 1
+	if id != "" {
+		idx = -1
+		for i, e := range s.state.Journal {
+			if e.ID == id {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, 0, fmt.Errorf("enhance run not found: %s", id)
+		}
+	}
+	entry := s.state.Journal[idx]
+
+	restored := 0
+	for _, snap := range entry.Products {
+		p, ok := s.state.Products[snap.SKU]
+		if !ok {
+			continue
+		}
+		if err := snap.restore(p); err != nil {
+			return nil, restored, err
+		}
+		restored++
+
+		// Later runs snapshotted this product with this run applied. Their
+		// enhancement positions shift down past the removed records, and
+		// rolling them back should restore the values from before this run.
+		for i := idx + 1; i < len(s.state.Journal); i++ {
+			for j := range s.state.Journal[i].Products {
+				later := &s.state.Journal[i].Products[j]
+				if later.SKU != snap.SKU {
+					continue
+				}
+				if later.EnhancementsBefore >= snap.EnhancementsBefore+snap.EnhancementsAdded {
+					later.EnhancementsBefore -= snap.EnhancementsAdded
+				}
+				for name := range later.Fields {
+					if prev, ok := snap.Fields[name]; ok {
+						later.Fields[name] = prev
+					}
+				}
+			}
+		}
+	}
+
+	s.state.Journal = append(s.state.Journal[:idx], s.state.Journal[idx+1:]...)
+	return &entry, restored, nil
+}
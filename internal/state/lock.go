@@ -0,0 +1,154 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrLocked is returned by Load and Save when another process holds the
+// state lock for longer than LockTimeout
+var ErrLocked = errors.New("state is locked")
+
+// LockTimeout is how long Load and Save wait for another process to release
+// the state lock before failing with ErrLocked. Zero fails immediately.
+var LockTimeout = 5 * time.Second
+
+// lockPollInterval is how often a blocked lock is retried
+const lockPollInterval = 100 * time.Millisecond
+
+// fileLock is an advisory lock on a state file's ".lock" sidecar, shared by
+// every Store of the same file in this process. Loading takes a shared lock
+// so readers can run side by side; LoadForUpdate takes it exclusively, so
+// writers queue up before they start working, and saving upgrades a shared
+// lock to an exclusive one, so a process can only write the state while no
+// other process has it loaded. The lock is released when the last Store
+// using it is closed, or when the process exits.
+type fileLock struct {
+	path      string
+	file      *os.File
+	exclusive bool
+	refs      int
+}
+
+var (
+	locksMu sync.Mutex
+	locks   = make(map[string]*fileLock)
+)
+
+// acquireLock returns this process's lock on the state file at statePath,
+// opening and locking it on first use. The lock is shared unless exclusive is
+// set; an exclusive request upgrades a shared lock the process already holds.
+func acquireLock(statePath string, exclusive bool) (*fileLock, error) {
+	path, err := filepath.Abs(statePath + ".lock")
+	if err != nil {
+		return nil, err
+	}
+
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	if l, ok := locks[path]; ok {
+		if exclusive {
+			if err := l.upgradeLocked(); err != nil {
+				return nil, err
+			}
+		}
+		l.refs++
+		return l, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock: %w", err)
+	}
+
+	l := &fileLock{path: path, file: f, refs: 1}
+	if err := l.wait(exclusive); err != nil {
+		f.Close()
+		return nil, err
+	}
+	// No other process holds the exclusive lock, so any PID left by one
+	// that exited is stale
+	f.Truncate(0)
+	if exclusive {
+		l.exclusive = true
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	locks[path] = l
+	return l, nil
+}
+
+// upgrade makes the lock exclusive, waiting for other processes to release
+// their shared locks, and records this process's PID in the lock file
+func (l *fileLock) upgrade() error {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	return l.upgradeLocked()
+}
+
+// upgradeLocked is upgrade for callers holding locksMu
+func (l *fileLock) upgradeLocked() error {
+	if l.exclusive {
+		return nil
+	}
+	if err := l.wait(true); err != nil {
+		return err
+	}
+	l.exclusive = true
+
+	l.file.Truncate(0)
+	l.file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	return nil
+}
+
+// release drops one reference, unlocking the file when it was the last
+func (l *fileLock) release() {
+	locksMu.Lock()
+	defer locksMu.Unlock()
+	l.refs--
+	if l.refs > 0 {
+		return
+	}
+	if l.exclusive {
+		l.file.Truncate(0)
+	}
+	unlockFile(l.file)
+	l.file.Close()
+	delete(locks, l.path)
+}
+
+// wait takes the lock, retrying until LockTimeout
+func (l *fileLock) wait(exclusive bool) error {
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		ok, err := tryLockFile(l.file, exclusive)
+		if err != nil {
+			return fmt.Errorf("failed to lock state: %w", err)
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return l.lockedError()
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// lockedError describes who holds the lock. Only a process that saved the
+// state records its PID; readers are anonymous.
+func (l *fileLock) lockedError() error {
+	data, _ := os.ReadFile(l.path)
+	if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && pid != os.Getpid() {
+		return fmt.Errorf("%w by PID %d; wait for it to finish or raise --lock-timeout", ErrLocked, pid)
+	}
+	return fmt.Errorf("%w: it is in use by another badops process; wait for it to finish or raise --lock-timeout", ErrLocked)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package state
+
+import "os"
+
+// tryLockFile is a no-op where flock is not available; concurrent runs are
+// not serialized there
+func tryLockFile(f *os.File, exclusive bool) (bool, error) {
+	return true, nil
+}
+
+// unlockFile is a no-op where flock is not available
+func unlockFile(f *os.File) error {
+	return nil
+}
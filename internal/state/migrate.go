@@ -0,0 +1,183 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// legacyVersion is the version of v1 state files, which are a plain array of
+// legacy products without a version field
+const legacyVersion = "1.0"
+
+// migration upgrades a raw state file to the version To
+type migration struct {
+	To    string
+	Apply func(raw json.RawMessage) (json.RawMessage, error)
+}
+
+// migrations upgrade a state file one version at a time, keyed by the version
+// they migrate from. To change the on-disk format, bump StateVersion and
+// register a migration from the previous version, e.g.
+//
+//	"3.0": {To: "4.0", Apply: migrateV3ToV4},
+var migrations = map[string]migration{
+	legacyVersion: {To: "2.0", Apply: migrateV1ToV2},
+	"2.0":         {To: "3.0", Apply: migrateV2ToV3},
+}
+
+// Migrate applies migrations to a raw state file until it reaches
+// StateVersion. It returns the migrated file and a history entry for each
+// step, counting the products migrated; both are unchanged when the file is
+// already current. Files with a
+// version that has no migration path, such as one written by a newer badops,
+// are refused.
+func Migrate(data []byte) ([]byte, []HistoryEntry, error) {
+	var steps []HistoryEntry
+	for {
+		version, err := detectVersion(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		if version == StateVersion {
+			return data, steps, nil
+		}
+
+		m, ok := migrations[version]
+		if !ok {
+			return nil, nil, fmt.Errorf("incompatible state version %s (expected %s)", version, StateVersion)
+		}
+		if data, err = m.Apply(data); err != nil {
+			return nil, nil, fmt.Errorf("failed to migrate state from v%s to v%s: %w", version, m.To, err)
+		}
+
+		steps = append(steps, HistoryEntry{
+			Timestamp: time.Now(),
+			Action:    "migrate",
+			Source:    "v" + version,
+			Count:     countProducts(data),
+			Details:   fmt.Sprintf("Migrated state file from v%s to v%s", version, m.To),
+		})
+	}
+}
+
+// detectVersion returns the version of a raw state file
+func detectVersion(data []byte) (string, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return legacyVersion, nil
+	}
+
+	var versionCheck struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &versionCheck); err != nil || versionCheck.Version == "" {
+		return "", fmt.Errorf("not a badops state file")
+	}
+	return versionCheck.Version, nil
+}
+
+// countProducts returns the number of products in a v2 or later state file
+func countProducts(data []byte) int {
+	var file struct {
+		Products map[string]json.RawMessage `json:"products"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0
+	}
+	return len(file.Products)
+}
+
+// migrateV1ToV2 converts the v1 array of legacy products to a v2 state file
+func migrateV1ToV2(raw json.RawMessage) (json.RawMessage, error) {
+	var legacyProducts []models.Product
+	if err := json.Unmarshal(raw, &legacyProducts); err != nil {
+		return nil, fmt.Errorf("failed to parse legacy state file: %w", err)
+	}
+
+	state := &StateFile{
+		Version:     "2.0",
+		Products:    make(map[string]*models.EnhancedProduct),
+		History:     []HistoryEntry{},
+		LastUpdated: time.Now(),
+	}
+	for _, lp := range legacyProducts {
+		ep := lp.ToEnhancedProduct()
+		state.Products[ep.SKU] = ep
+	}
+
+	return json.Marshal(state)
+}
+
+// migrateV2ToV3 stores each product's images in position order, numbered from
+// 1. v2 files could hold images without a position (0), such as the new
+// images of migrated v1 products, and those now follow the positioned ones
+// instead of sorting first. The file is migrated as raw JSON, so every other
+// field is kept as written.
+func migrateV2ToV3(raw json.RawMessage) (json.RawMessage, error) {
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, err
+	}
+
+	var products map[string]map[string]json.RawMessage
+	if data, ok := file["products"]; ok {
+		if err := json.Unmarshal(data, &products); err != nil {
+			return nil, fmt.Errorf("failed to parse products: %w", err)
+		}
+	}
+	for sku, product := range products {
+		data, ok := product["images"]
+		if !ok {
+			continue
+		}
+		var images []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &images); err != nil {
+			return nil, fmt.Errorf("failed to parse images of %s: %w", sku, err)
+		}
+		if len(images) == 0 {
+			continue
+		}
+
+		positions := make([]int, len(images))
+		for i, img := range images {
+			json.Unmarshal(img["position"], &positions[i]) // Missing or invalid is unpositioned
+		}
+		order := make([]int, len(images))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			pa, pb := positions[order[a]], positions[order[b]]
+			if pa <= 0 || pb <= 0 {
+				return pa > 0 && pb <= 0
+			}
+			return pa < pb
+		})
+
+		sorted := make([]map[string]json.RawMessage, len(images))
+		for i, idx := range order {
+			sorted[i] = images[idx]
+			sorted[i]["position"] = json.RawMessage(strconv.Itoa(i + 1))
+		}
+		data, err := json.Marshal(sorted)
+		if err != nil {
+			return nil, err
+		}
+		product["images"] = data
+	}
+
+	if products != nil {
+		data, err := json.Marshal(products)
+		if err != nil {
+			return nil, err
+		}
+		file["products"] = data
+	}
+	file["version"] = json.RawMessage(`"3.0"`)
+	return json.Marshal(file)
+}
@@ -0,0 +1,159 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// v1 state files are a plain array of legacy products
+const v1State = `[
+  {
+    "sku": "CO-T309012",
+    "name": "Tiger Boston Toalettrullholder",
+    "brand": "Tiger",
+    "existing_images": ["https://cdn.shopify.com/a.jpg", "https://cdn.shopify.com/b.jpg"],
+    "new_images": [{"source_url": "https://tiger.nl/pim/c.jpg", "status": "downloaded"}]
+  },
+  {"sku": "CO-T309013", "name": "Tiger Boston Haak", "brand": "Tiger", "existing_images": []}
+]`
+
+func TestMigrateChainsV1ToCurrent(t *testing.T) {
+	data, steps, err := Migrate([]byte(v1State))
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	wantSteps := []string{"v1.0", "v2.0"}
+	if len(steps) != len(wantSteps) {
+		t.Fatalf("got %d migration steps, want %d", len(steps), len(wantSteps))
+	}
+	for i, step := range steps {
+		if step.Action != "migrate" || step.Source != wantSteps[i] {
+			t.Errorf("step %d = %s from %s, want migrate from %s", i, step.Action, step.Source, wantSteps[i])
+		}
+		if step.Count != 2 {
+			t.Errorf("step %d count = %d, want 2", i, step.Count)
+		}
+	}
+
+	var file StateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("migrated file does not parse: %v", err)
+	}
+	if file.Version != StateVersion {
+		t.Errorf("version = %s, want %s", file.Version, StateVersion)
+	}
+	if len(file.Products) != 2 {
+		t.Fatalf("got %d products, want 2", len(file.Products))
+	}
+
+	// The v1 new image had no position; it now follows the existing ones
+	images := file.Products["CO-T309012"].Images
+	wantURLs := []string{"https://cdn.shopify.com/a.jpg", "https://cdn.shopify.com/b.jpg", "https://tiger.nl/pim/c.jpg"}
+	if len(images) != len(wantURLs) {
+		t.Fatalf("got %d images, want %d", len(images), len(wantURLs))
+	}
+	for i, img := range images {
+		if img.SourceURL != wantURLs[i] || img.Position != i+1 {
+			t.Errorf("image %d = %s at %d, want %s at %d", i, img.SourceURL, img.Position, wantURLs[i], i+1)
+		}
+	}
+}
+
+func TestMigrateV2ToV3OrdersImages(t *testing.T) {
+	v2 := `{
+  "version": "2.0",
+  "products": {
+    "CO-T309012": {
+      "sku": "CO-T309012",
+      "title": "Tiger Boston",
+      "images": [
+        {"source_url": "new.jpg", "position": 0, "status": "pending", "source": "tiger_nl"},
+        {"source_url": "second.jpg", "position": 2, "status": "uploaded", "source": "shopify"},
+        {"source_url": "first.jpg", "position": 1, "status": "uploaded", "source": "shopify", "future_field": "kept"}
+      ]
+    },
+    "CO-T309013": {"sku": "CO-T309013", "title": "No images"}
+  },
+  "history": [{"timestamp": "2026-01-01T00:00:00Z", "action": "import", "source": "shopify", "count": 2}],
+  "last_updated": "2026-01-01T00:00:00Z"
+}`
+
+	data, steps, err := Migrate([]byte(v2))
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(steps) != 1 || steps[0].Source != "v2.0" || steps[0].Count != 2 {
+		t.Fatalf("steps = %+v, want one v2.0 step counting 2 products", steps)
+	}
+	if !strings.Contains(string(data), `"future_field":"kept"`) {
+		t.Error("migration dropped a field it does not know")
+	}
+
+	var file StateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("migrated file does not parse: %v", err)
+	}
+	if len(file.History) != 1 || file.History[0].Count != 2 {
+		t.Errorf("history = %+v, want the import entry kept", file.History)
+	}
+
+	want := []string{"first.jpg", "second.jpg", "new.jpg"}
+	images := file.Products["CO-T309012"].Images
+	for i, img := range images {
+		if img.SourceURL != want[i] || img.Position != i+1 {
+			t.Errorf("image %d = %s at %d, want %s at %d", i, img.SourceURL, img.Position, want[i], i+1)
+		}
+	}
+}
+
+func TestMigrateCurrentAndNewerVersions(t *testing.T) {
+	current := `{"version": "` + StateVersion + `", "products": {}, "history": []}`
+	data, steps, err := Migrate([]byte(current))
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if len(steps) != 0 || string(data) != current {
+		t.Errorf("a current file was changed: %d steps", len(steps))
+	}
+
+	if _, _, err := Migrate([]byte(`{"version": "99.0", "products": {}}`)); err == nil {
+		t.Error("a newer state version was accepted")
+	}
+	if _, _, err := Migrate([]byte(`{"products": {}}`)); err == nil {
+		t.Error("a file without a version was accepted")
+	}
+}
+
+func TestLoadSavesMigratedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(v1State), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(path)
+	if err := store.LoadForUpdate(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	store.Close()
+
+	if store.Count() != 2 {
+		t.Errorf("got %d products, want 2", store.Count())
+	}
+	history := store.GetHistory()
+	if len(history) != 2 || history[1].Details != "Migrated state file from v2.0 to v3.0" {
+		t.Errorf("history = %+v, want both migration steps", history)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := detectVersion(data)
+	if err != nil || version != StateVersion {
+		t.Errorf("saved version = %q (%v), want %s", version, err, StateVersion)
+	}
+}
@@ -0,0 +1,262 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/badno/badops/internal/database"
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/pkg/models"
+	"github.com/google/uuid"
+)
+
+// recentHistoryLimit is how many operation_history entries Load reads back
+const recentHistoryLimit = 100
+
+// PostgresStore keeps product state in PostgreSQL. Products are loaded into
+// memory on Load and written back on Save, so it behaves like the JSON Store
+// between the two. Import cursors are kept in memory only.
+type PostgresStore struct {
+	*Store
+
+	products     *postgres.ProductRepo
+	images       *postgres.ImageRepo
+	properties   *postgres.PropertyRepo
+	suppliers    *postgres.SupplierRepo
+	packages     *postgres.PackageInfoRepo
+	enhancements *postgres.EnhancementLogRepo
+	history      *postgres.HistoryRepo
+
+	// saved holds the JSON of each product as last loaded or saved, so Save
+	// only writes products that changed
+	saved map[string][]byte
+	// historySaved is the number of history entries already in the database
+	historySaved int
+}
+
+// NewPostgresStore creates a state store backed by a connected PostgreSQL client
+func NewPostgresStore(client *postgres.Client) *PostgresStore {
+	return &PostgresStore{
+		Store:        NewStore(""),
+		products:     postgres.NewProductRepo(client),
+		images:       postgres.NewImageRepo(client),
+		properties:   postgres.NewPropertyRepo(client),
+		suppliers:    postgres.NewSupplierRepo(client),
+		packages:     postgres.NewPackageInfoRepo(client),
+		enhancements: postgres.NewEnhancementLogRepo(client),
+		history:      postgres.NewHistoryRepo(client),
+		saved:        make(map[string][]byte),
+	}
+}
+
+// Load reads all active products with their images, properties, suppliers,
+// package info and enhancement log, and the recent history from the database
+func (s *PostgresStore) Load() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	products, err := s.products.GetAll(ctx, database.QueryOptions{OrderBy: "sku", OrderDir: "ASC"})
+	if err != nil {
+		return err
+	}
+
+	state := &StateFile{
+		Version:  StateVersion,
+		Products: make(map[string]*models.EnhancedProduct, len(products)),
+		History:  []HistoryEntry{},
+	}
+	saved := make(map[string][]byte, len(products))
+
+	if err := s.loadDetails(ctx, products); err != nil {
+		return err
+	}
+	for _, p := range products {
+		state.Products[p.SKU] = p
+		saved[p.SKU], _ = json.Marshal(p)
+	}
+
+	entries, err := s.history.GetRecent(ctx, recentHistoryLimit)
+	if err != nil {
+		return err
+	}
+	// GetRecent is newest first, the state history oldest first
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		state.History = append(state.History, HistoryEntry{
+			Timestamp: e.StartedAt,
+			Action:    e.Action,
+			Source:    e.Source,
+			Count:     e.Count,
+			Details:   e.Details,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cursors := s.state.ImportCursors
+	s.state = state
+	s.state.ImportCursors = cursors
+	s.saved = saved
+	s.historySaved = len(state.History)
+	return nil
+}
+
+// LoadForUpdate is Load; the database needs no state file lock
+func (s *PostgresStore) LoadForUpdate() error {
+	return s.Load()
+}
+
+// LoadProduct reads one active product with its details (see loadDetails),
+// without loading the rest of the catalog. It returns nil when the SKU is not
+// in the database.
+func (s *PostgresStore) LoadProduct(ctx context.Context, sku string) (*models.EnhancedProduct, error) {
+	product, err := s.products.GetBySKU(ctx, sku)
+	if err != nil || product == nil {
+		return nil, err
+	}
+	if err := s.loadDetails(ctx, []*models.EnhancedProduct{product}); err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+// loadDetails fills in the products' images, properties, suppliers, package
+// info and enhancements, with one query for each rather than one per product.
+// Enhancements come from enhancement_log, failures included, so SaveEnhanced
+// does not log them again.
+func (s *PostgresStore) loadDetails(ctx context.Context, products []*models.EnhancedProduct) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		id, err := uuid.Parse(p.ID)
+		if err != nil {
+			return fmt.Errorf("invalid product id %q: %w", p.ID, err)
+		}
+		ids = append(ids, id)
+	}
+
+	images, err := s.images.GetByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	props, err := s.properties.GetByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	suppliers, err := s.suppliers.GetByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	packages, err := s.packages.GetByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	logs, err := s.enhancements.GetByProducts(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range products {
+		for _, img := range images[ids[i]] {
+			image := models.ProductImage{
+				ID:           img.ID.String(),
+				SourceURL:    img.SourceURL,
+				LocalPath:    img.LocalPath,
+				Position:     img.Position,
+				Alt:          img.AltText,
+				Width:        img.Width,
+				Height:       img.Height,
+				Status:       img.Status,
+				Source:       img.Source,
+				ResizedPaths: img.ResizedPaths,
+			}
+			if img.DownloadedAt != nil {
+				image.DownloadedAt = *img.DownloadedAt
+			}
+			p.Images = append(p.Images, image)
+		}
+
+		for _, prop := range props[ids[i]] {
+			p.Properties = append(p.Properties, models.Property{
+				Code:   prop.Code,
+				Name:   prop.Name,
+				Value:  prop.Value,
+				Unit:   prop.Unit,
+				Source: prop.Source,
+			})
+		}
+
+		for _, link := range suppliers[ids[i]] {
+			p.Suppliers = append(p.Suppliers, models.Supplier{
+				ID:        link.SupplierID,
+				Name:      link.SupplierName,
+				GLN:       link.SupplierGLN,
+				ArticleNo: link.ArticleNumber,
+				IsPrimary: link.IsPrimary,
+			})
+		}
+
+		p.PackageInfo = packages[ids[i]]
+
+		for _, e := range logs[ids[i]] {
+			p.Enhancements = append(p.Enhancements, models.Enhancement{
+				Source:      e.Source,
+				Action:      e.Action,
+				FieldsAdded: e.FieldsAdded,
+				Timestamp:   e.CreatedAt,
+				Success:     e.Success,
+				Error:       e.Error,
+			})
+		}
+	}
+
+	return nil
+}
+
+// Save writes new and changed products, each in its own transaction, and
+// appends history entries added since the last Load or Save
+func (s *PostgresStore) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	for sku, p := range s.state.Products {
+		data, _ := json.Marshal(p)
+		if prev, ok := s.saved[sku]; ok && string(prev) == string(data) {
+			continue
+		}
+
+		if err := s.products.SaveEnhanced(ctx, p); err != nil {
+			return err
+		}
+		// SaveEnhanced sets the database IDs
+		s.saved[sku], _ = json.Marshal(p)
+	}
+
+	// Clear drops history along with the products
+	if s.historySaved > len(s.state.History) {
+		s.historySaved = len(s.state.History)
+	}
+	for _, e := range s.state.History[s.historySaved:] {
+		completedAt := e.Timestamp
+		err := s.history.Add(ctx, &database.OperationHistory{
+			Action:      e.Action,
+			Source:      e.Source,
+			Count:       e.Count,
+			Details:     e.Details,
+			StartedAt:   e.Timestamp,
+			CompletedAt: &completedAt,
+		})
+		if err != nil {
+			return err
+		}
+		s.historySaved++
+	}
+
+	s.state.LastUpdated = time.Now()
+	return nil
+}
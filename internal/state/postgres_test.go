@@ -0,0 +1,119 @@
+package state
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/badno/badops/internal/database/postgres"
+	"github.com/badno/badops/pkg/models"
+)
+
+// testDatabaseEnv names a disposable database, as in the postgres package
+// tests. Its product tables are truncated.
+const testDatabaseEnv = "BADOPS_TEST_DATABASE_URL"
+
+// testPostgresClient connects to the test database, applies migrations and
+// empties the product tables. The test is skipped when none is configured.
+func testPostgresClient(t *testing.T) *postgres.Client {
+	t.Helper()
+
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDatabaseEnv)
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse %s: %v", testDatabaseEnv, err)
+	}
+	cfg := postgres.DefaultConfig()
+	cfg.Host = u.Hostname()
+	if p := u.Port(); p != "" {
+		if cfg.Port, err = strconv.Atoi(p); err != nil {
+			t.Fatalf("parse %s port: %v", testDatabaseEnv, err)
+		}
+	}
+	cfg.Database = u.Path[1:]
+	cfg.Username = u.User.Username()
+	cfg.Password, _ = u.User.Password()
+	if mode := u.Query().Get("sslmode"); mode != "" {
+		cfg.SSLMode = mode
+	}
+	cfg.ConnectRetries = -1
+
+	client := postgres.NewClient(cfg)
+	ctx := context.Background()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.RunMigrations(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if _, err := client.Pool().Exec(ctx, "TRUNCATE products, suppliers, operation_history CASCADE"); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	return client
+}
+
+// Enhancements are read back from enhancement_log, so --skip-fresh sees
+// products enhanced by earlier runs
+func TestPostgresStoreLoadsEnhancements(t *testing.T) {
+	client := testPostgresClient(t)
+
+	store := NewPostgresStore(client)
+	if err := store.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	store.SetProduct(&models.EnhancedProduct{
+		SKU:    "CO-T309012",
+		Title:  "Boston Toilet Roll Holder",
+		Status: models.StatusEnhanced,
+		Suppliers: []models.Supplier{
+			{ID: "12345", Name: "Coram Nordic", ArticleNo: "309012", IsPrimary: true},
+		},
+		Enhancements: []models.Enhancement{
+			{Source: "nobb", Action: "enhanced", Timestamp: time.Now().Add(-time.Hour), Success: true},
+			{Source: "tiger_nl", Action: "enhance_failed", Timestamp: time.Now().Add(-time.Minute), Error: "no match"},
+		},
+	})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewPostgresStore(client)
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	p, ok := reloaded.GetProduct("CO-T309012")
+	if !ok {
+		t.Fatal("product not loaded")
+	}
+
+	since := time.Now().Add(-2 * time.Hour)
+	if !p.EnhancedSince("nobb", since) {
+		t.Errorf("nobb enhancement not loaded: %+v", p.Enhancements)
+	}
+	if p.EnhancedSince("tiger_nl", since) {
+		t.Error("a failed enhancement counts as fresh")
+	}
+	if len(p.Suppliers) != 1 || p.Suppliers[0].Name != "Coram Nordic" {
+		t.Errorf("suppliers = %+v", p.Suppliers)
+	}
+
+	// Saving the unchanged product logs nothing again
+	if err := reloaded.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	var logged int
+	if err := client.Pool().QueryRow(context.Background(), "SELECT COUNT(*) FROM enhancement_log").Scan(&logged); err != nil {
+		t.Fatal(err)
+	}
+	if logged != 2 {
+		t.Errorf("enhancement_log has %d rows, want 2", logged)
+	}
+}
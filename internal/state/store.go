@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,7 +13,7 @@ import (
 )
 
 const (
-	StateVersion = "2.0"
+	StateVersion = "3.0"
 	DefaultStateFile = "output/.badops-state.json"
 )
 
@@ -25,12 +26,14 @@ type HistoryEntry struct {
 	Details     string    `json:"details"`     // Human-readable description
 }
 
-// StateFile represents the v2 state file structure
+// StateFile represents the v3 state file structure
 type StateFile struct {
 	Version     string                            `json:"version"`
 	Products    map[string]*models.EnhancedProduct `json:"products"` // Keyed by SKU
 	History     []HistoryEntry                    `json:"history"`
 	LastUpdated time.Time                         `json:"last_updated"`
+	ImportCursors map[string]time.Time            `json:"import_cursors,omitempty"` // Max source updated_at per import key
+	Journal     []JournalEntry                    `json:"journal,omitempty"`        // Recent enhance runs, for rollback
 }
 
 // Store manages product state persistence
@@ -38,6 +41,7 @@ type Store struct {
 	mu        sync.RWMutex
 	filePath  string
 	state     *StateFile
+	lock      *fileLock // Held from the first Load or Save until Close
 }
 
 // NewStore creates a new state store
@@ -61,6 +65,10 @@ func (s *Store) Load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if err := s.lockLocked(); err != nil {
+		return err
+	}
+
 	data, err := os.ReadFile(s.filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -75,56 +83,73 @@ func (s *Store) Load() error {
 		return err
 	}
 
-	// Try to detect version
-	var versionCheck struct {
-		Version string `json:"version"`
+	// Bring older formats up to StateVersion
+	data, steps, err := Migrate(data)
+	if err != nil {
+		return err
 	}
-	if err := json.Unmarshal(data, &versionCheck); err == nil && versionCheck.Version != "" {
-		// v2 format
-		var state StateFile
-		if err := json.Unmarshal(data, &state); err != nil {
-			return fmt.Errorf("failed to parse state file: %w", err)
-		}
-		s.state = &state
-	} else {
-		// v1 format (array of legacy products)
-		return s.migrateFromV1(data)
+
+	var state StateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to parse state file: %w", err)
+	}
+	if state.Products == nil {
+		state.Products = make(map[string]*models.EnhancedProduct)
 	}
+	s.state = &state
 
-	return nil
+	if len(steps) == 0 {
+		return nil
+	}
+
+	// Record the migration in history and save the migrated state
+	s.state.History = append(s.state.History, steps...)
+	return s.saveInternal()
 }
 
-// migrateFromV1 converts v1 state file to v2 format
-func (s *Store) migrateFromV1(data []byte) error {
-	var legacyProducts []models.Product
-	if err := json.Unmarshal(data, &legacyProducts); err != nil {
-		return fmt.Errorf("failed to parse legacy state file: %w", err)
+// LoadForUpdate is Load for callers that will Save. It takes the exclusive
+// lock before reading, so a second writer waits (or fails with ErrLocked)
+// before doing any work instead of at Save, and two writers can't both hold
+// a shared lock and wait for each other to upgrade.
+func (s *Store) LoadForUpdate() error {
+	s.mu.Lock()
+	var err error
+	if s.lock == nil {
+		s.lock, err = acquireLock(s.filePath, true)
+	} else {
+		err = s.lock.upgrade()
 	}
-
-	s.state = &StateFile{
-		Version:     StateVersion,
-		Products:    make(map[string]*models.EnhancedProduct),
-		History:     []HistoryEntry{},
-		LastUpdated: time.Now(),
+	s.mu.Unlock()
+	if err != nil {
+		return err
 	}
+	return s.Load()
+}
 
-	// Convert each legacy product
-	for _, lp := range legacyProducts {
-		ep := lp.ToEnhancedProduct()
-		s.state.Products[ep.SKU] = ep
+// lockLocked takes the process's shared lock on the state file if this store
+// does not hold it yet. The caller holds mu.
+func (s *Store) lockLocked() error {
+	if s.lock != nil {
+		return nil
 	}
+	lock, err := acquireLock(s.filePath, false)
+	if err != nil {
+		return err
+	}
+	s.lock = lock
+	return nil
+}
 
-	// Record migration in history
-	s.state.History = append(s.state.History, HistoryEntry{
-		Timestamp: time.Now(),
-		Action:    "migrate",
-		Source:    "v1",
-		Count:     len(legacyProducts),
-		Details:   fmt.Sprintf("Migrated %d products from v1 state format", len(legacyProducts)),
-	})
-
-	// Save the migrated state
-	return s.saveInternal()
+// Close releases the store's lock on the state file. Other processes can
+// load and save the state once every store in this process is closed or the
+// process exits. The store can be loaded again afterwards.
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lock != nil {
+		s.lock.release()
+		s.lock = nil
+	}
 }
 
 // Save writes the state to disk
@@ -136,6 +161,13 @@ func (s *Store) Save() error {
 
 // saveInternal saves without acquiring lock (for internal use)
 func (s *Store) saveInternal() error {
+	if err := s.lockLocked(); err != nil {
+		return err
+	}
+	if err := s.lock.upgrade(); err != nil {
+		return err
+	}
+
 	s.state.LastUpdated = time.Now()
 
 	// Ensure directory exists
@@ -149,7 +181,52 @@ func (s *Store) saveInternal() error {
 		return err
 	}
 
-	return os.WriteFile(s.filePath, data, 0644)
+	return writeFileAtomic(s.filePath, data, 0644)
+}
+
+// writeFileAtomic replaces path with data so that a crash or a full disk never
+// leaves a truncated file: data is written and synced to a temporary file in
+// the same directory, the previous version is kept as path+".bak", and the
+// temporary file is renamed into place.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	// Hard-link the previous version as the backup; the link keeps it after
+	// the rename replaces path. Best effort, e.g. on the first save.
+	backup := path + ".bak"
+	os.Remove(backup)
+	os.Link(path, backup)
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	// Persist the rename itself
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
 }
 
 // GetProduct retrieves a product by SKU
@@ -280,6 +357,101 @@ func (s *Store) GetRecentHistory(n int) []HistoryEntry {
 	return history
 }
 
+// GetImportCursor returns the stored updated_at cursor for an incremental import
+func (s *Store) GetImportCursor(key string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, ok := s.state.ImportCursors[key]
+	return t, ok
+}
+
+// SetImportCursor stores the updated_at cursor for an incremental import
+func (s *Store) SetImportCursor(key string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state.ImportCursors == nil {
+		s.state.ImportCursors = make(map[string]time.Time)
+	}
+	s.state.ImportCursors[key] = t
+}
+
+// RecordImageDownload stores metadata for a downloaded image on the product
+// with the given SKU. The image is matched by SourceURL (or OriginalURL once
+// uploaded), then by the name of its downloaded file, and appended only if
+// the product has neither. Returns false if the product is not in the store.
+func (s *Store) RecordImageDownload(sku string, img models.ProductImage) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, ok := s.state.Products[sku]
+	if !ok {
+		return false
+	}
+
+	var existing *models.ProductImage
+	for i := range product.Images {
+		if product.Images[i].SourceURL == img.SourceURL || product.Images[i].OriginalURL == img.SourceURL {
+			existing = &product.Images[i]
+			break
+		}
+	}
+	if existing == nil && img.LocalPath != "" {
+		existing = product.ImageByFile(strings.TrimSuffix(filepath.Base(img.LocalPath), filepath.Ext(img.LocalPath)))
+	}
+	if existing == nil {
+		img.Position = len(product.Images) + 1
+		product.Images = append(product.Images, img)
+		product.UpdatedAt = time.Now()
+		return true
+	}
+
+	existing.LocalPath = img.LocalPath
+	existing.Width = img.Width
+	existing.Height = img.Height
+	existing.Bytes = img.Bytes
+	existing.Status = img.Status
+	existing.DownloadedAt = img.DownloadedAt
+	if existing.Source == "" {
+		existing.Source = img.Source
+	}
+	product.UpdatedAt = time.Now()
+	return true
+}
+
+// RecordImageUpload marks the image of the product with the given SKU whose
+// downloaded file is named stem (without extension) as uploaded: SourceURL
+// becomes publicURL, the URL it was fetched from moves to OriginalURL and the
+// uploaded file is recorded in ResizedPaths under size. Returns false if no
+// image matches.
+func (s *Store) RecordImageUpload(sku, stem, size, resizedPath, publicURL string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	product, ok := s.state.Products[sku]
+	if !ok {
+		return false
+	}
+
+	img := product.ImageByFile(stem)
+	if img == nil {
+		return false
+	}
+
+	if img.OriginalURL == "" && img.SourceURL != publicURL {
+		img.OriginalURL = img.SourceURL
+	}
+	img.SourceURL = publicURL
+	img.Status = "uploaded"
+	if img.ResizedPaths == nil {
+		img.ResizedPaths = make(map[string]string)
+	}
+	img.ResizedPaths[size] = resizedPath
+	product.UpdatedAt = time.Now()
+	return true
+}
+
 // ImportProducts imports products, updating existing ones
 func (s *Store) ImportProducts(products []models.EnhancedProduct, source string) int {
 	s.mu.Lock()
@@ -350,6 +522,11 @@ func mergeProducts(existing, new *models.EnhancedProduct) *models.EnhancedProduc
 	if new.NOBBNumber != "" {
 		result.NOBBNumber = new.NOBBNumber
 	}
+	if new.LegacyMatchedURL != "" {
+		result.LegacyMatchedURL = new.LegacyMatchedURL
+		result.LegacyMatchScore = new.LegacyMatchScore
+		result.LegacyMatchMethod = new.LegacyMatchMethod
+	}
 
 	// Merge images (avoid duplicates)
 	existingURLs := make(map[string]bool)
@@ -0,0 +1,133 @@
+package state
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/badno/badops/pkg/models"
+)
+
+// gzipMagic is the header every gzip stream starts with
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Export writes a normalized copy of the state to path, gzipped when
+// compress is set. Returns the number of products written.
+func (s *Store) Export(path string, compress bool) (int, error) {
+	s.mu.RLock()
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	count := len(s.state.Products)
+	s.mu.RUnlock()
+	if err != nil {
+		return 0, err
+	}
+
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Name = strings.TrimSuffix(filepath.Base(path), ".gz")
+		if _, err := zw.Write(data); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		data = buf.Bytes()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write state export: %w", err)
+	}
+	return count, nil
+}
+
+// ReadStateFile reads a state export, gzipped or plain, and migrates it to
+// StateVersion. Versions without a migration path are refused.
+func ReadStateFile(path string) (*StateFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(data, gzipMagic) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzipped state: %w", err)
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("failed to decompress state: %w", err)
+		}
+	}
+
+	data, steps, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var file StateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse state file: %w", err)
+	}
+	file.History = append(file.History, steps...)
+	if file.Products == nil {
+		file.Products = make(map[string]*models.EnhancedProduct)
+	}
+	if file.History == nil {
+		file.History = []HistoryEntry{}
+	}
+	return &file, nil
+}
+
+// ImportState loads an exported state into the store. With replace the
+// store's state is swapped for the file wholesale; otherwise products are
+// merged like ImportProducts, history is appended and import cursors keep
+// the later time. Returns the number of products imported.
+func (s *Store) ImportState(file *StateFile, replace bool, source string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(file.Products)
+	action := "state-merge"
+	if replace {
+		action = "state-replace"
+		file.Version = StateVersion
+		s.state = file
+	} else {
+		for sku, p := range file.Products {
+			if existing, exists := s.state.Products[sku]; exists {
+				p = mergeProducts(existing, p)
+			}
+			s.state.Products[sku] = p
+		}
+
+		s.state.History = append(s.state.History, file.History...)
+		for key, t := range file.ImportCursors {
+			if s.state.ImportCursors == nil {
+				s.state.ImportCursors = make(map[string]time.Time)
+			}
+			if t.After(s.state.ImportCursors[key]) {
+				s.state.ImportCursors[key] = t
+			}
+		}
+	}
+
+	s.state.History = append(s.state.History, HistoryEntry{
+		Timestamp: time.Now(),
+		Action:    action,
+		Source:    source,
+		Count:     count,
+		Details:   fmt.Sprintf("Imported %d products from %s", count, source),
+	})
+
+	return count
+}
@@ -0,0 +1,45 @@
+package models
+
+import "strings"
+
+// NormalizeBarcode strips everything but digits from a GTIN-8, UPC-A
+// (GTIN-12), EAN-13 or GTIN-14 barcode and checks its check digit. Valid
+// barcodes are returned in a canonical form: EAN-13, or GTIN-14 when the
+// indicator digit is not zero, so "7012345678908", "07012345678908" and
+// " 701-2345678908" all give "7012345678908" and GTIN-8 and UPC-A codes are
+// zero-padded to 13 digits. Barcodes of another length or with a wrong check
+// digit return their digits and false.
+func NormalizeBarcode(s string) (string, bool) {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if !gtinLengths[len(digits)] || !validCheckDigit(digits) {
+		return digits, false
+	}
+
+	trimmed := strings.TrimLeft(digits, "0")
+	if len(trimmed) > 13 {
+		return trimmed, true
+	}
+	return strings.Repeat("0", 13-len(trimmed)) + trimmed, true
+}
+
+// validCheckDigit applies the GS1 mod-10 check: from the right, excluding
+// the check digit, digits are weighted 3, 1, 3, ... Leading zeros do not
+// change the sum, so every GTIN length uses the same rule.
+func validCheckDigit(digits string) bool {
+	sum := 0
+	for i := len(digits) - 2; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	check := (10 - sum%10) % 10
+	return check == int(digits[len(digits)-1]-'0')
+}
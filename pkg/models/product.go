@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
 
 // ProductStatus represents the current state of a product in the enhancement pipeline
 type ProductStatus string
@@ -13,6 +17,7 @@ const (
 	StatusApproved   ProductStatus = "approved"
 	StatusExported   ProductStatus = "exported"
 	StatusFailed     ProductStatus = "failed"
+	StatusArchived   ProductStatus = "archived" // Dropped from the catalog, kept for history
 )
 
 // Product represents a product from the Matrixify export (legacy, for backward compatibility)
@@ -20,9 +25,11 @@ type Product struct {
 	SKU            string   `json:"sku"`
 	Name           string   `json:"name"`
 	Brand          string   `json:"brand"`
+	Barcode        string   `json:"barcode,omitempty"`
 	ExistingImages []string `json:"existing_images"`
 	MatchedURL     string   `json:"matched_url,omitempty"`
 	MatchScore     float64  `json:"match_score,omitempty"`
+	MatchMethod    string   `json:"match_method,omitempty"`
 	NewImages      []Image  `json:"new_images,omitempty"`
 }
 
@@ -71,8 +78,9 @@ type EnhancedProduct struct {
 	UpdatedAt time.Time `json:"updated_at"`
 
 	// Legacy fields for backward compatibility with existing state file
-	LegacyMatchedURL string  `json:"matched_url,omitempty"`
-	LegacyMatchScore float64 `json:"match_score,omitempty"`
+	LegacyMatchedURL  string  `json:"matched_url,omitempty"`
+	LegacyMatchScore  float64 `json:"match_score,omitempty"`
+	LegacyMatchMethod string  `json:"match_method,omitempty"` // barcode, id or name
 }
 
 // Price represents product pricing information
@@ -85,6 +93,22 @@ type Price struct {
 	LastUpdated  time.Time `json:"last_updated,omitempty"`
 }
 
+// Margin returns the profit margin as a percentage of the price,
+// (Amount-CostPerItem)/Amount*100. It is negative when the cost exceeds the
+// price and 0 when the price is not positive.
+func (p *Price) Margin() float64 {
+	if p == nil || p.Amount <= 0 {
+		return 0
+	}
+	return (p.Amount - p.CostPerItem) / p.Amount * 100
+}
+
+// HasMargin reports whether both the price and the cost are known, so
+// Margin is meaningful
+func (p *Price) HasMargin() bool {
+	return p != nil && p.Amount > 0 && p.CostPerItem > 0
+}
+
 // Dimensions represents physical dimensions
 type Dimensions struct {
 	Length float64 `json:"length"` // in millimeters
@@ -103,11 +127,13 @@ type Weight struct {
 type ProductImage struct {
 	ID          string    `json:"id,omitempty"`
 	SourceURL   string    `json:"source_url"`
+	OriginalURL string    `json:"original_url,omitempty"` // URL the image was fetched from, once SourceURL points at the uploaded copy
 	LocalPath   string    `json:"local_path,omitempty"`
 	Position    int       `json:"position"`
 	Alt         string    `json:"alt,omitempty"`
 	Width       int       `json:"width,omitempty"`
 	Height      int       `json:"height,omitempty"`
+	Bytes       int64     `json:"bytes,omitempty"` // Size of the downloaded file
 	Status      string    `json:"status"` // pending, downloaded, resized, uploaded, failed
 	Source      string    `json:"source"` // shopify, tiger_nl, nobb
 	ResizedPaths map[string]string `json:"resized_paths,omitempty"`
@@ -121,6 +147,7 @@ type Property struct {
 	Value       string `json:"value"`
 	Unit        string `json:"unit,omitempty"`
 	Source      string `json:"source"` // nobb, tiger_nl, shopify
+	RawName     string `json:"raw_name,omitempty"` // Original name when resolved from a raw code (e.g., ETIM EF000008)
 }
 
 // Supplier represents supplier information from NOBB
@@ -183,18 +210,41 @@ type Report struct {
 	Products        []Product `json:"products"`
 }
 
+// ImageCompareReport is the result of an image comparison between bad.no and
+// Tiger.nl, written by images compare and read by images fetch --from-compare
+type ImageCompareReport struct {
+	GeneratedAt     time.Time         `json:"generated_at"`
+	TotalProducts   int               `json:"total_products"`
+	ProductsWithNew int               `json:"products_with_new"`
+	TotalNew        int               `json:"total_new"`
+	Products        []ImageComparison `json:"products"`
+}
+
+// ImageComparison is one product's image counts in an ImageCompareReport
+type ImageComparison struct {
+	SKU        string   `json:"sku"`
+	Name       string   `json:"name"`
+	BadnoCount int      `json:"badno_count"`
+	TigerCount int      `json:"tiger_count"`
+	NewCount   int      `json:"new_count"`
+	TigerURL   string   `json:"tiger_url,omitempty"`
+	NewImages  []string `json:"new_images,omitempty"` // Tiger.nl image URLs not yet on bad.no, in page order
+}
+
 // ToEnhancedProduct converts a legacy Product to an EnhancedProduct
 func (p *Product) ToEnhancedProduct() *EnhancedProduct {
 	ep := &EnhancedProduct{
-		SKU:              p.SKU,
-		Title:            p.Name,
-		Vendor:           p.Brand,
-		Status:           StatusPending,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
-		LegacyMatchedURL: p.MatchedURL,
-		LegacyMatchScore: p.MatchScore,
-		Specifications:   make(map[string]string),
+		SKU:               p.SKU,
+		Title:             p.Name,
+		Vendor:            p.Brand,
+		Barcode:           p.Barcode,
+		Status:            StatusPending,
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+		LegacyMatchedURL:  p.MatchedURL,
+		LegacyMatchScore:  p.MatchScore,
+		LegacyMatchMethod: p.MatchMethod,
+		Specifications:    make(map[string]string),
 	}
 
 	// Convert existing images
@@ -224,11 +274,13 @@ func (p *Product) ToEnhancedProduct() *EnhancedProduct {
 // ToLegacyProduct converts an EnhancedProduct back to a legacy Product
 func (ep *EnhancedProduct) ToLegacyProduct() *Product {
 	p := &Product{
-		SKU:        ep.SKU,
-		Name:       ep.Title,
-		Brand:      ep.Vendor,
-		MatchedURL: ep.LegacyMatchedURL,
-		MatchScore: ep.LegacyMatchScore,
+		SKU:         ep.SKU,
+		Name:        ep.Title,
+		Brand:       ep.Vendor,
+		Barcode:     ep.Barcode,
+		MatchedURL:  ep.LegacyMatchedURL,
+		MatchScore:  ep.LegacyMatchScore,
+		MatchMethod: ep.LegacyMatchMethod,
 	}
 
 	// Extract existing images (from Shopify)
@@ -252,3 +304,123 @@ func (ep *EnhancedProduct) ToLegacyProduct() *Product {
 
 	return p
 }
+
+// Clone returns a deep copy of the product so it can be modified without
+// affecting the original (e.g., to preview enhancements)
+func (ep *EnhancedProduct) Clone() *EnhancedProduct {
+	c := *ep
+
+	if ep.Tags != nil {
+		c.Tags = append([]string(nil), ep.Tags...)
+	}
+	if ep.Price != nil {
+		price := *ep.Price
+		c.Price = &price
+	}
+	if ep.Dimensions != nil {
+		dims := *ep.Dimensions
+		c.Dimensions = &dims
+	}
+	if ep.Weight != nil {
+		weight := *ep.Weight
+		c.Weight = &weight
+	}
+	if ep.Images != nil {
+		c.Images = make([]ProductImage, len(ep.Images))
+		for i, img := range ep.Images {
+			if img.ResizedPaths != nil {
+				paths := make(map[string]string, len(img.ResizedPaths))
+				for k, v := range img.ResizedPaths {
+					paths[k] = v
+				}
+				img.ResizedPaths = paths
+			}
+			c.Images[i] = img
+		}
+	}
+	if ep.Specifications != nil {
+		c.Specifications = make(map[string]string, len(ep.Specifications))
+		for k, v := range ep.Specifications {
+			c.Specifications[k] = v
+		}
+	}
+	if ep.Properties != nil {
+		c.Properties = append([]Property(nil), ep.Properties...)
+	}
+	if ep.Suppliers != nil {
+		c.Suppliers = append([]Supplier(nil), ep.Suppliers...)
+	}
+	if ep.PackageInfo != nil {
+		c.PackageInfo = append([]PackageInfo(nil), ep.PackageInfo...)
+	}
+	if ep.Enhancements != nil {
+		c.Enhancements = make([]Enhancement, len(ep.Enhancements))
+		for i, e := range ep.Enhancements {
+			if e.FieldsAdded != nil {
+				e.FieldsAdded = append([]string(nil), e.FieldsAdded...)
+			}
+			c.Enhancements[i] = e
+		}
+	}
+
+	return &c
+}
+
+// EnhancedSince reports whether the product has a successful enhancement from
+// source recorded at or after t
+func (ep *EnhancedProduct) EnhancedSince(source string, t time.Time) bool {
+	for _, e := range ep.Enhancements {
+		if e.Source == source && e.Success && !e.Timestamp.Before(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// FieldSource returns the source of the latest successful enhancement that set
+// field, or "" when no enhancement set it (e.g., the value was imported)
+func (ep *EnhancedProduct) FieldSource(field string) string {
+	for i := len(ep.Enhancements) - 1; i >= 0; i-- {
+		e := ep.Enhancements[i]
+		if !e.Success {
+			continue
+		}
+		for _, f := range e.FieldsAdded {
+			if f == field {
+				return e.Source
+			}
+		}
+	}
+	return ""
+}
+
+// ImageByFile returns the image downloaded to a file named stem (without
+// directory or extension), or nil if there is none
+func (ep *EnhancedProduct) ImageByFile(stem string) *ProductImage {
+	for i := range ep.Images {
+		local := ep.Images[i].LocalPath
+		if local != "" && strings.TrimSuffix(filepath.Base(local), filepath.Ext(local)) == stem {
+			return &ep.Images[i]
+		}
+	}
+	return nil
+}
+
+// OverwritePolicy controls when an enhancement may replace a field that
+// already has a value
+type OverwritePolicy string
+
+const (
+	OverwriteFillEmpty    OverwritePolicy = "fill_empty"    // Only set empty fields (default)
+	OverwritePreferSource OverwritePolicy = "prefer_source" // Replace values set by the same or a lower-priority source
+	OverwriteAlways       OverwritePolicy = "always"        // Replace any existing value
+)
+
+// IsOverwritePolicy reports whether s names a supported overwrite policy
+func IsOverwritePolicy(s string) bool {
+	switch OverwritePolicy(s) {
+	case OverwriteFillEmpty, OverwritePreferSource, OverwriteAlways:
+		return true
+	}
+	return false
+}
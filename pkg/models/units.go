@@ -0,0 +1,99 @@
+package models
+
+import (
+	"math"
+	"strings"
+)
+
+// Length units in millimeters per unit
+var lengthUnits = map[string]float64{
+	"mm": 1,
+	"cm": 10,
+	"m":  1000,
+	"in": 25.4,
+}
+
+// Weight units in grams per unit
+var weightUnits = map[string]float64{
+	"g":  1,
+	"kg": 1000,
+	"lb": 453.59237,
+	"oz": 28.349523125,
+}
+
+// Decimal places kept after conversion, so values stay at the unit's natural precision
+var unitPrecision = map[string]int{
+	"mm": 0,
+	"cm": 1,
+	"m":  3,
+	"in": 2,
+	"g":  0,
+	"kg": 3,
+	"lb": 2,
+	"oz": 1,
+}
+
+// IsLengthUnit reports whether unit is a supported dimension unit (mm, cm, m, in)
+func IsLengthUnit(unit string) bool {
+	_, ok := lengthUnits[strings.ToLower(unit)]
+	return ok
+}
+
+// IsWeightUnit reports whether unit is a supported weight unit (g, kg, lb, oz)
+func IsWeightUnit(unit string) bool {
+	_, ok := weightUnits[strings.ToLower(unit)]
+	return ok
+}
+
+// ConvertTo returns a copy of the dimensions in the given unit (mm, cm, m, in).
+// Returns nil if either unit is missing or unsupported.
+func (d *Dimensions) ConvertTo(unit string) *Dimensions {
+	if d == nil {
+		return nil
+	}
+	unit = strings.ToLower(unit)
+	fromFactor, ok := lengthUnits[strings.ToLower(d.Unit)]
+	if !ok {
+		return nil
+	}
+	toFactor, ok := lengthUnits[unit]
+	if !ok {
+		return nil
+	}
+
+	ratio := fromFactor / toFactor
+	return &Dimensions{
+		Length: roundTo(d.Length*ratio, unitPrecision[unit]),
+		Width:  roundTo(d.Width*ratio, unitPrecision[unit]),
+		Height: roundTo(d.Height*ratio, unitPrecision[unit]),
+		Unit:   unit,
+	}
+}
+
+// ConvertTo returns a copy of the weight in the given unit (g, kg, lb, oz).
+// Returns nil if either unit is missing or unsupported.
+func (w *Weight) ConvertTo(unit string) *Weight {
+	if w == nil {
+		return nil
+	}
+	unit = strings.ToLower(unit)
+	fromFactor, ok := weightUnits[strings.ToLower(w.Unit)]
+	if !ok {
+		return nil
+	}
+	toFactor, ok := weightUnits[unit]
+	if !ok {
+		return nil
+	}
+
+	return &Weight{
+		Value: roundTo(w.Value*fromFactor/toFactor, unitPrecision[unit]),
+		Unit:  unit,
+	}
+}
+
+// roundTo rounds a value to the given number of decimal places
+func roundTo(value float64, decimals int) float64 {
+	pow := math.Pow(10, float64(decimals))
+	return math.Round(value*pow) / pow
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// gtinLengths are the digit counts of GTIN-8, UPC-A (GTIN-12), EAN-13 and GTIN-14
+var gtinLengths = map[int]bool{8: true, 12: true, 13: true, 14: true}
+
+// ValidationError is a problem with one field of a product
+type ValidationError struct {
+	Field   string // e.g. "sku", "price.currency", "images.source_url"
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate checks the fields exports and the database rely on: a non-empty
+// SKU, non-negative prices, a 3-letter currency code, a numeric GTIN-length
+// barcode and parseable image URLs. It returns every problem found, or nil
+// when the product is valid. Check digits are reported by Warnings instead.
+func (ep *EnhancedProduct) Validate() []error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.TrimSpace(ep.SKU) == "" {
+		invalid("sku", "is empty")
+	}
+
+	if p := ep.Price; p != nil {
+		if p.Amount < 0 {
+			invalid("price.amount", "is negative (%.2f)", p.Amount)
+		}
+		if p.CompareAt < 0 {
+			invalid("price.compare_at", "is negative (%.2f)", p.CompareAt)
+		}
+		if p.CostPerItem < 0 {
+			invalid("price.cost_per_item", "is negative (%.2f)", p.CostPerItem)
+		}
+		if p.Currency != "" && !isCurrencyCode(p.Currency) {
+			invalid("price.currency", "%q is not a 3-letter currency code", p.Currency)
+		}
+	}
+
+	if b := ep.Barcode; b != "" {
+		switch {
+		case !isDigits(b):
+			invalid("barcode", "%q is not numeric", b)
+		case !gtinLengths[len(b)]:
+			invalid("barcode", "%q has %d digits, expected 8, 12, 13 or 14", b, len(b))
+		}
+	}
+
+	for i, img := range ep.Images {
+		if img.SourceURL == "" {
+			continue // Local-only images have no URL yet
+		}
+		if err := checkImageURL(img.SourceURL); err != nil {
+			invalid("images.source_url", "image %d: %v", i+1, err)
+		}
+	}
+
+	return errs
+}
+
+// Warnings returns problems that don't stop a product from being exported or
+// stored but are likely data errors: a GTIN barcode with a wrong check digit.
+// Barcodes Validate rejects are not checked again.
+func (ep *EnhancedProduct) Warnings() []error {
+	var warnings []error
+	if b := ep.Barcode; isDigits(b) && gtinLengths[len(b)] && !validCheckDigit(b) {
+		warnings = append(warnings, &ValidationError{Field: "barcode", Message: fmt.Sprintf("%q has an invalid check digit", b)})
+	}
+	return warnings
+}
+
+// checkImageURL accepts absolute http(s) URLs
+func checkImageURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%q is not a URL", raw)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	return nil
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}